@@ -18,6 +18,7 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/BurntSushi/toml"
@@ -100,6 +101,9 @@ type SubTaskConfig struct {
 	EnableHeartbeat  bool   `toml:"enable-heartbeat" json:"enable-heartbeat"`
 	Meta             *Meta  `toml:"meta" json:"meta"`
 	Timezone         string `toml:"timezone" josn:"timezone"`
+	// generate sqls without executing them in downstream, sqls are written into DryRunFile or log if it's empty
+	DryRun     bool   `toml:"dry-run" json:"dry-run"`
+	DryRunFile string `toml:"dry-run-file" json:"dry-run-file"`
 
 	BinlogType string `toml:"binlog-type" json:"binlog-type"`
 	// RelayDir get value from dm-worker config
@@ -150,12 +154,31 @@ func (c *SubTaskConfig) SetupFlags(name CmdName) {
 	fs.StringVar(&c.ConfigFile, "config", "", "config file")
 
 	fs.BoolVar(&c.printVersion, "V", false, "prints version and exit")
+	fs.BoolVar(&c.DryRun, "dry-run", false, "generate sqls without executing them in downstream")
+	fs.StringVar(&c.DryRunFile, "dry-run-file", "", "file to write sqls generated in dry-run mode, write into log if not specified")
 
 	switch name {
 	case CmdLoader:
 		// Loader configuration
 		fs.IntVar(&c.PoolSize, "t", 16, "Number of threads restoring concurrently for worker pool. Each worker restore one file at a time, increase this as TiKV nodes increase")
 		fs.StringVar(&c.Dir, "d", "./dumped_data", "Directory of the dump to import")
+		fs.IntVar(&c.TableConcurrency, "table-concurrency", 0, "Max number of data files of a table restoring concurrently, 0 means no limit except the worker pool size")
+		fs.StringVar(&c.CheckpointFile, "checkpoint-file", "", "Local file to save checkpoint, checkpoint is saved in the downstream database if not specified")
+		fs.StringVar(&c.CheckpointImport, "checkpoint-import", "", "File of checkpoints exported by another loader with the same dump, imported before restoring if no checkpoints recorded")
+		fs.Int64Var(&c.RateLimit, "rate-limit", 0, "Max bytes of data files restored per second, 0 means no limit")
+		fs.StringVar(&c.Validation, "validation", "", "compare tables between source and target after all data restored, \"count\" or \"checksum\"")
+		fs.IntVar(&c.ValidationSampleSize, "validation-sample-size", defaultValidationSampleSize, "max count of rows sampled from a table in checksum validation")
+		fs.IntVar(&c.CheckpointBatch, "checkpoint-batch", 0, "Max count of data files whose checkpoints are saved in one transaction, 0 means saving checkpoints together with data")
+		fs.IntVar(&c.JobQueueSize, "job-queue-size", defaultJobQueueSize, "Max count of statements read but not executed of each worker")
+		fs.Int64Var(&c.JobQueueBytes, "job-queue-bytes", 0, "Max bytes of statements read but not executed of each worker, 0 means no limit except job-queue-size")
+		fs.BoolVar(&c.SameServerCopy, "same-server-copy", false, "Copy rows of tables by INSERT ... SELECT in the target if the source and target are the same server")
+		fs.IntVar(&c.CommitStatements, "commit-statements", 0, "Max count of statements committed in one transaction along with the checkpoint, 0 or 1 means one statement per transaction")
+		fs.StringVar(&c.CommitInterval, "commit-interval", "", "Max duration statements wait to be committed since the first of them read, like 500ms, empty means no limit except commit-statements")
+		fs.IntVar(&c.MaxConnections, "max-connections", 0, "Max count of connections to the downstream database opened by all workers, 0 means no limit")
+		fs.IntVar(&c.ThrottleThreadsRunning, "throttle-threads-running", 0, "Slow down restoring while Threads_running of the downstream database is not below it, 0 means no throttle, it requires rate-limit")
+		fs.BoolVar(&c.DisableKeyChecks, "disable-key-checks", false, "Restore with foreign_key_checks=0 and unique_checks=0 in sessions for speed, rows violating foreign keys or unique keys may be restored without errors")
+		fs.IntVar(&c.RetryLimit, "retry-limit", 0, "Max times to retry operations failed with transient errors, like transactions and loading checkpoints, 9 if not specified")
+		fs.StringVar(&c.RetryMaxInterval, "retry-max-interval", "", "Max backoff between retries, like 16s, the backoff doubles from 1s every retry up to it, 16s if not specified")
 		fs.StringVar(&c.PprofAddr, "pprof-addr", ":8272", "Loader pprof addr")
 	case CmdSyncer:
 		// Syncer configuration
@@ -164,9 +187,24 @@ func (c *SubTaskConfig) SetupFlags(name CmdName) {
 		fs.StringVar(&c.Flavor, "flavor", mysql.MySQLFlavor, "use flavor for different MySQL source versions; support \"mysql\", \"mariadb\" now; if you replicate from mariadb, please set it to \"mariadb\"")
 		fs.IntVar(&c.WorkerCount, "count", 16, "parallel worker count")
 		fs.IntVar(&c.Batch, "b", 10, "batch commit count")
+		fs.IntVar(&c.InsertBatch, "insert-batch", 1, "max rows coalesced into one INSERT statement")
+		fs.IntVar(&c.MaxStatementSize, "max-statement-size", 0, "max estimated bytes of a batched statement, 0 means 3/4 of max_allowed_packet of the target")
 		fs.IntVar(&c.MaxRetry, "max-retry", 100, "maxinum retry when network interruption")
 		fs.BoolVar(&c.EnableGTID, "enable-gtid", false, "enable gtid mode")
 		fs.BoolVar(&c.SafeMode, "safe-mode", false, "enable safe mode to make syncer reentrant")
+		fs.StringVar(&c.SafeModeDuration, "safe-mode-duration", "", "enable safe mode for events happening in the duration after resumed, 5m if not specified")
+		fs.BoolVar(&c.UpdateAllDuplicates, "update-all-duplicates", false, "update all duplicate rows rather than one of them for tables without usable index")
+		fs.IntVar(&c.RowLimit, "row-limit", 1, "max rows changed by an UPDATE or DELETE statement for a row of tables without usable index, -1 means no limit")
+		fs.BoolVar(&c.FillMissingColumns, "fill-missing-columns", false, "fill trailing columns missing in inserted rows with their default values")
+		fs.BoolVar(&c.ZeroDateToNull, "zero-date-to-null", false, "convert zero dates of nullable date and time columns to NULL")
+		fs.StringVar(&c.IdentifierCase, "identifier-case", "", "case of schema, table and column names in DML statements, \"preserve\" (default) or \"lower\"")
+		fs.BoolVar(&c.StrictNotNull, "strict-not-null", false, "reject inserted rows with NULL values for NOT NULL columns")
+		fs.BoolVar(&c.KeepTransaction, "keep-transaction", false, "execute DMLs of a source transaction in one transaction")
+		fs.BoolVar(&c.DiagnoseBatchFailure, "diagnose-batch-failure", false, "find the failing statement of a failed batch by executing statements one at a time")
+		fs.BoolVar(&c.UpsertOnMissing, "upsert-on-missing", false, "replace the changed row if an UPDATE matches no row in the target, and count DELETEs matching no row")
+		fs.BoolVar(&c.DeleteOnKeyConflict, "delete-on-key-conflict", false, "delete the row conflicting on another unique key if an INSERT ... ON DUPLICATE KEY UPDATE fails with a duplicate entry, and execute it again")
+		fs.StringVar(&c.SlowStatementThreshold, "slow-statement-threshold", "", "log DML statements taking longer than the threshold to execute, like 500ms, not logged if not specified")
+		fs.BoolVar(&c.ExplainSlowStatements, "explain-slow-statements", false, "run EXPLAIN for slow UPDATE and DELETE statements and log the plans, it requires slow-statement-threshold")
 		fs.StringVar(&c.StatusAddr, "status-addr", ":8271", "Syncer status addr")
 		fs.BoolVar(&c.DisableHeartbeat, "disable-heartbeat", true, "deprecated!!! disable heartbeat between mysql and syncer")
 		fs.BoolVar(&c.EnableHeartbeat, "enable-heartbeat", false, "enable heartbeat between mysql and syncer")
@@ -236,6 +274,145 @@ func (c *SubTaskConfig) adjust() error {
 		c.MetaSchema = defaultMetaSchema
 	}
 
+	if c.ConflictStrategy == "" {
+		c.ConflictStrategy = ConflictReplace
+	} else if !isConflictStrategy(c.ConflictStrategy) {
+		return errors.NotSupportedf("conflict strategy %s", c.ConflictStrategy)
+	}
+	for _, rule := range c.TableConflictStrategies {
+		if rule == nil || rule.SchemaPattern == "" {
+			return errors.NotValidf("table conflict strategy %+v without schema-pattern", rule)
+		}
+		if !isConflictStrategy(rule.Strategy) {
+			return errors.NotSupportedf("conflict strategy %s of tables %s.%s", rule.Strategy, rule.SchemaPattern, rule.TablePattern)
+		}
+	}
+
+	if c.RowLimit == 0 {
+		c.RowLimit = 1
+	} else if c.RowLimit < NoRowLimit {
+		return errors.NotValidf("row-limit %d", c.RowLimit)
+	}
+	for _, rule := range c.TableRowLimits {
+		if rule == nil || rule.SchemaPattern == "" {
+			return errors.NotValidf("table row limit %+v without schema-pattern", rule)
+		}
+		if rule.Limit == 0 || rule.Limit < NoRowLimit {
+			return errors.NotValidf("row limit %d of tables %s.%s", rule.Limit, rule.SchemaPattern, rule.TablePattern)
+		}
+	}
+	for _, rule := range c.TablePreferredIndexes {
+		if rule == nil || rule.SchemaPattern == "" {
+			return errors.NotValidf("table preferred index %+v without schema-pattern", rule)
+		}
+		if rule.Index == "" {
+			return errors.NotValidf("empty preferred index of tables %s.%s", rule.SchemaPattern, rule.TablePattern)
+		}
+	}
+	for _, rule := range c.TableChangeIgnoredColumns {
+		if rule == nil || rule.SchemaPattern == "" {
+			return errors.NotValidf("table change ignored columns %+v without schema-pattern", rule)
+		}
+		if len(rule.Columns) == 0 {
+			return errors.NotValidf("empty change ignored columns of tables %s.%s", rule.SchemaPattern, rule.TablePattern)
+		}
+	}
+
+	if c.KeyStrategy == "" {
+		c.KeyStrategy = KeyStrategyJoin
+	} else if c.KeyStrategy != KeyStrategyJoin && c.KeyStrategy != KeyStrategyHash {
+		return errors.NotSupportedf("key strategy %s", c.KeyStrategy)
+	}
+
+	if c.IdentifierCase == "" {
+		c.IdentifierCase = IdentifierCasePreserve
+	} else if c.IdentifierCase != IdentifierCasePreserve && c.IdentifierCase != IdentifierCaseLower {
+		return errors.NotSupportedf("identifier case %s", c.IdentifierCase)
+	}
+
+	if _, err := ParseSafeModeDuration(c.SafeModeDuration); err != nil {
+		return errors.Trace(err)
+	}
+	if threshold, err := ParseSlowStatementThreshold(c.SlowStatementThreshold); err != nil {
+		return errors.Trace(err)
+	} else if c.ExplainSlowStatements && threshold == 0 {
+		return errors.NotValidf("explain-slow-statements without slow-statement-threshold")
+	}
+
+	for _, table := range c.MetricsTables {
+		if i := strings.Index(table, "."); i <= 0 || i == len(table)-1 {
+			return errors.NotValidf("metrics-tables %s, it should be like `schema.table`", table)
+		}
+	}
+
+	if c.MaxStatementSize < 0 {
+		return errors.NotValidf("max-statement-size %d", c.MaxStatementSize)
+	}
+
+	if c.TableConcurrency < 0 {
+		return errors.NotValidf("table-concurrency %d", c.TableConcurrency)
+	}
+
+	if c.RateLimit < 0 {
+		return errors.NotValidf("rate-limit %d", c.RateLimit)
+	}
+
+	if c.Validation != "" && c.Validation != ValidationCount && c.Validation != ValidationChecksum {
+		return errors.NotSupportedf("validation %s", c.Validation)
+	}
+	if c.ValidationSampleSize == 0 {
+		c.ValidationSampleSize = defaultValidationSampleSize
+	} else if c.ValidationSampleSize < 0 {
+		return errors.NotValidf("validation-sample-size %d", c.ValidationSampleSize)
+	}
+
+	if c.CheckpointBatch < 0 {
+		return errors.NotValidf("checkpoint-batch %d", c.CheckpointBatch)
+	}
+
+	if c.JobQueueSize == 0 {
+		c.JobQueueSize = defaultJobQueueSize
+	} else if c.JobQueueSize < 0 {
+		return errors.NotValidf("job-queue-size %d", c.JobQueueSize)
+	}
+	if c.JobQueueBytes < 0 {
+		return errors.NotValidf("job-queue-bytes %d", c.JobQueueBytes)
+	}
+
+	if c.CommitStatements < 0 {
+		return errors.NotValidf("commit-statements %d", c.CommitStatements)
+	}
+	if c.MaxConnections < 0 {
+		return errors.NotValidf("max-connections %d", c.MaxConnections)
+	}
+	if c.ThrottleThreadsRunning < 0 {
+		return errors.NotValidf("throttle-threads-running %d", c.ThrottleThreadsRunning)
+	}
+	if c.ThrottleThreadsRunning > 0 && c.RateLimit <= 0 {
+		return errors.NotValidf("throttle-threads-running %d without rate-limit", c.ThrottleThreadsRunning)
+	}
+	if c.CommitInterval != "" {
+		if interval, err := time.ParseDuration(c.CommitInterval); err != nil || interval <= 0 {
+			return errors.NotValidf("commit-interval %s", c.CommitInterval)
+		}
+	}
+	if c.RetryLimit < 0 {
+		return errors.NotValidf("retry-limit %d", c.RetryLimit)
+	}
+	if c.RetryMaxInterval != "" {
+		if interval, err := time.ParseDuration(c.RetryMaxInterval); err != nil || interval <= 0 {
+			return errors.NotValidf("retry-max-interval %s", c.RetryMaxInterval)
+		}
+	}
+	for _, rule := range c.RowFilters {
+		if rule == nil || rule.SchemaPattern == "" {
+			return errors.NotValidf("row filter %+v without schema-pattern", rule)
+		}
+		if strings.TrimSpace(rule.Where) == "" {
+			return errors.NotValidf("empty condition of row filter of tables %s.%s", rule.SchemaPattern, rule.TablePattern)
+		}
+	}
+
 	if c.MaxRetry == 0 {
 		c.MaxRetry = 1
 	}
@@ -254,6 +431,34 @@ func (c *SubTaskConfig) adjust() error {
 	return nil
 }
 
+func isConflictStrategy(strategy string) bool {
+	return strategy == ConflictReplace || strategy == ConflictOnDuplicate || strategy == ConflictIgnore
+}
+
+// ParseSafeModeDuration parses the duration of safe-mode after the syncer resumed, the default duration is used if it's empty
+func ParseSafeModeDuration(s string) (time.Duration, error) {
+	if s == "" {
+		return defaultSafeModeDuration, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, errors.NotValidf("safe-mode-duration %s", s)
+	}
+	return d, nil
+}
+
+// ParseSlowStatementThreshold parses the threshold of slow DML statements, 0 means slow statements are not logged
+func ParseSlowStatementThreshold(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, errors.NotValidf("slow-statement-threshold %s", s)
+	}
+	return d, nil
+}
+
 // Parse parses flag definitions from the argument list.
 func (c *SubTaskConfig) Parse(arguments []string) error {
 	// Parse first to get config file.
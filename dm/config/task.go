@@ -33,6 +33,36 @@ const (
 	PT    = "pt"
 )
 
+// Conflict strategies used by syncer when inserting rows
+const (
+	ConflictReplace     = "replace"
+	ConflictOnDuplicate = "on-duplicate"
+	// ConflictIgnore drops the rows conflicting with existing rows silently, it's suitable for append-only tables
+	ConflictIgnore = "ignore"
+)
+
+// Validation modes used by loader to compare tables between source and target after all data restored
+const (
+	ValidationCount    = "count"
+	ValidationChecksum = "checksum"
+)
+
+// Key strategies used by syncer to generate keys of rows for conflict detection
+const (
+	KeyStrategyJoin = "join"
+	KeyStrategyHash = "hash"
+)
+
+// Identifier cases used by syncer for schema, table and column names in generated DML statements
+const (
+	IdentifierCasePreserve = "preserve"
+	// IdentifierCaseLower lower-cases identifiers, it's suitable for a target with `lower_case_table_names` set
+	IdentifierCaseLower = "lower"
+)
+
+// NoRowLimit is the row limit of UPDATE and DELETE statements without LIMIT clause, see SyncerConfig.RowLimit
+const NoRowLimit = -1
+
 // default config item values
 var (
 	// TaskConfig
@@ -45,12 +75,16 @@ var (
 	defaultChunkFilesize int64 = 64
 	defaultSkipTzUTC           = true
 	// LoaderConfig
-	defaultPoolSize = 16
-	defaultDir      = "./dumped_data"
+	defaultPoolSize             = 16
+	defaultDir                  = "./dumped_data"
+	defaultValidationSampleSize = 1000
+	defaultJobQueueSize         = 1000
 	// SyncerConfig
 	defaultWorkerCount = 16
 	defaultBatch       = 100
 	defaultMaxRetry    = 100
+
+	defaultSafeModeDuration = 5 * time.Minute
 )
 
 // Meta represents binlog's meta pos
@@ -152,6 +186,60 @@ func (m *MydumperConfig) UnmarshalYAML(unmarshal func(interface{}) error) error
 type LoaderConfig struct {
 	PoolSize int    `yaml:"pool-size" toml:"pool-size" json:"pool-size"`
 	Dir      string `yaml:"dir" toml:"dir" json:"dir"`
+	// max count of data files of a table restoring concurrently, 0 means no limit except pool-size
+	TableConcurrency int `yaml:"table-concurrency" toml:"table-concurrency" json:"table-concurrency"`
+	// path of the local file saving checkpoint, checkpoint is saved in the downstream database if it's empty
+	CheckpointFile string `yaml:"checkpoint-file" toml:"checkpoint-file" json:"checkpoint-file"`
+	// path of the checkpoints exported by another loader with the same dump, like a file of checkpoint-file,
+	// they are imported before restoring if no checkpoints are recorded, so the task moved from another DM-worker continues from them
+	CheckpointImport string `yaml:"checkpoint-import" toml:"checkpoint-import" json:"checkpoint-import"`
+	// max bytes of data files restored per second by all workers, 0 means no limit
+	RateLimit int64 `yaml:"rate-limit" toml:"rate-limit" json:"rate-limit"`
+	// max count of data files whose checkpoints are saved into the downstream database in one transaction,
+	// 0 means checkpoints are saved in the same transaction with data
+	CheckpointBatch int `yaml:"checkpoint-batch" toml:"checkpoint-batch" json:"checkpoint-batch"`
+	// compare tables between source and target after all data restored, `count` or `checksum`, empty means not validate.
+	// `checksum` compares CRC32 of primary keys sampled from the beginning of tables, it's cheaper than `count` for very large tables
+	Validation string `yaml:"validation" toml:"validation" json:"validation"`
+	// max count of rows sampled from a table in `checksum` validation, 1000 if not specified
+	ValidationSampleSize int `yaml:"validation-sample-size" toml:"validation-sample-size" json:"validation-sample-size"`
+	// MySQL error codes of statements which are logged and skipped rather than stopping the loader,
+	// like 1062 for duplicate entries when restoring again, and 1146 for tables not created intentionally
+	SkipErrorCodes []uint16 `yaml:"skip-error-codes" toml:"skip-error-codes" json:"skip-error-codes"`
+	// max count of statements read from data files but not executed yet of each worker, 1000 if not specified
+	JobQueueSize int `yaml:"job-queue-size" toml:"job-queue-size" json:"job-queue-size"`
+	// max bytes of statements read from data files but not executed yet of each worker, 0 means no limit except job-queue-size
+	JobQueueBytes int64 `yaml:"job-queue-bytes" toml:"job-queue-bytes" json:"job-queue-bytes"`
+	// copy rows of a table by `INSERT INTO target SELECT * FROM source` executed in the target rather than restoring rows in its data file,
+	// if the source and target are the same server (the same host and port), like copying a schema to another one.
+	// it's not used with column mapping rules or row filters, for tables dumped into multiple data files, or for data files restored partially
+	SameServerCopy bool `yaml:"same-server-copy" toml:"same-server-copy" json:"same-server-copy"`
+	// max count of statements committed in one transaction along with the checkpoint, 0 or 1 means one statement per transaction
+	CommitStatements int `yaml:"commit-statements" toml:"commit-statements" json:"commit-statements"`
+	// max duration statements wait to be committed since the first of them read, like `500ms`, empty means no limit except commit-statements.
+	// statements of a data file are always committed when the file is read to the end
+	CommitInterval string `yaml:"commit-interval" toml:"commit-interval" json:"commit-interval"`
+	// max count of connections to the downstream database opened by all workers and restoring schemas, 0 means no limit.
+	// workers wait for a connection released when all of them are in use, so it's usually not less than pool-size
+	MaxConnections int `yaml:"max-connections" toml:"max-connections" json:"max-connections"`
+	// halve the rate-limit every second while Threads_running of the downstream database is not below it, down to paused,
+	// and increase it back every second while Threads_running is below 80% of it. 0 means no throttle, it requires rate-limit
+	ThrottleThreadsRunning int `yaml:"throttle-threads-running" toml:"throttle-threads-running" json:"throttle-threads-running"`
+	// restore data and schemas with `foreign_key_checks=0` and `unique_checks=0` in sessions for speed, they are reset after every transaction.
+	// it's unsafe, rows violating foreign keys or unique keys may be restored without errors
+	DisableKeyChecks bool `yaml:"disable-key-checks" toml:"disable-key-checks" json:"disable-key-checks"`
+	// max times to retry operations failed with transient errors, like transactions (retried as a whole) and loading checkpoints, 9 if not specified
+	RetryLimit int `yaml:"retry-limit" toml:"retry-limit" json:"retry-limit"`
+	// max backoff between retries, like `16s`, the backoff doubles from 1s every retry up to it. 16s if not specified
+	RetryMaxInterval string `yaml:"retry-max-interval" toml:"retry-max-interval" json:"retry-max-interval"`
+	// tables restored are the ones matched by restore-allow-tables (all if it's empty) and not matched by restore-block-tables,
+	// like excluding tables in a dump shared by tasks. they apply to the loader only, after black-white-list.
+	// tables not restored are not checkpointed either, their schemas are created unless blocked by schema level rules
+	RestoreAllowTables []*TablePattern `yaml:"restore-allow-tables" toml:"restore-allow-tables" json:"restore-allow-tables"`
+	RestoreBlockTables []*TablePattern `yaml:"restore-block-tables" toml:"restore-block-tables" json:"restore-block-tables"`
+	// rows of source tables matched by the rules are restored only if they satisfy the conditions, like restoring rows of one tenant.
+	// conditions are evaluated against rows as they are dumped (before column mapping), rows dropped still count in the progress
+	RowFilters []*RowFilterRule `yaml:"row-filters" toml:"row-filters" json:"row-filters"`
 }
 
 func defaultLoaderConfig() LoaderConfig {
@@ -180,6 +268,73 @@ type SyncerConfig struct {
 	WorkerCount int    `yaml:"worker-count" toml:"worker-count" json:"worker-count"`
 	Batch       int    `yaml:"batch" toml:"batch" json:"batch"`
 	MaxRetry    int    `yaml:"max-retry" toml:"max-retry" json:"max-retry"`
+	// max rows coalesced into one multi-row INSERT statement, 0 or 1 means one statement per row
+	InsertBatch int `yaml:"insert-batch" toml:"insert-batch" json:"insert-batch"`
+	// estimated size limit in bytes of a batched INSERT or DELETE statement, batches exceeding it are split into multiple statements.
+	// 0 (default) means 3/4 of `max_allowed_packet` of the target, which is at most 4MB
+	MaxStatementSize int `yaml:"max-statement-size" toml:"max-statement-size" json:"max-statement-size"`
+	// how to resolve conflicts when inserting rows, `replace` (default), `on-duplicate` or `ignore`.
+	// `ignore` drops the rows conflicting with existing rows silently, use it only for append-only tables
+	ConflictStrategy string `yaml:"conflict-strategy" toml:"conflict-strategy" json:"conflict-strategy"`
+	// conflict strategies of tables matched by patterns, which override conflict-strategy for the tables
+	TableConflictStrategies []*TableConflictStrategy `yaml:"table-conflict-strategies" toml:"table-conflict-strategies" json:"table-conflict-strategies"`
+	// how to generate keys of rows for conflict detection, `join` (default) or `hash`, `hash` uses less memory for wide keys
+	KeyStrategy string `yaml:"key-strategy" toml:"key-strategy" json:"key-strategy"`
+	// case of schema, table and column names of the target in generated DML statements, `preserve` (default) or `lower`.
+	// set it to `lower` if the upstream and downstream have different `lower_case_table_names`, so `Orders` is replicated to `orders`
+	IdentifierCase string `yaml:"identifier-case" toml:"identifier-case" json:"identifier-case"`
+	// update all rows matched rather than one of them (`LIMIT 1`) when no usable index identifies the row,
+	// so duplicate rows of a table without primary key are updated consistently
+	UpdateAllDuplicates bool `yaml:"update-all-duplicates" toml:"update-all-duplicates" json:"update-all-duplicates"`
+	// max rows changed by an UPDATE or DELETE statement for a row when no usable unique index identifies the row,
+	// rows with the same values can't be told apart in that case. 1 (default) changes one of them like the source, -1 means no limit.
+	// update-all-duplicates means no limit for UPDATE statements
+	RowLimit int `yaml:"row-limit" toml:"row-limit" json:"row-limit"`
+	// row limits of tables matched by patterns, which override row-limit for the tables
+	TableRowLimits []*TableRowLimit `yaml:"table-row-limits" toml:"table-row-limits" json:"table-row-limits"`
+	// unique indexes (by name) preferred to identify rows of UPDATE and DELETE events of tables matched by patterns,
+	// like a covering secondary unique index. the primary key or another unique index is used instead if the index doesn't exist,
+	// or it has NULL values in a row
+	TablePreferredIndexes []*TablePreferredIndex `yaml:"table-preferred-indexes" toml:"table-preferred-indexes" json:"table-preferred-indexes"`
+	// columns ignored when detecting changes of rows of UPDATE events of tables matched by patterns, like auto-managed
+	// `ON UPDATE CURRENT_TIMESTAMP` columns of heartbeat-like tables. rows changed only in these columns are not updated in the target
+	TableChangeIgnoredColumns []*TableChangeIgnoredColumns `yaml:"table-change-ignored-columns" toml:"table-change-ignored-columns" json:"table-change-ignored-columns"`
+	// safe-mode is enabled for events happening in the duration after the syncer resumed, like `5m` (default).
+	// events before the last saved checkpoint may be replicated again, the duration should be longer than the interval of saving checkpoints
+	SafeModeDuration string `yaml:"safe-mode-duration" toml:"safe-mode-duration" json:"safe-mode-duration"`
+	// tables like `schema.table` whose generated DML statements are counted separately in metrics,
+	// statements of other tables are counted together, the schema and table are target ones after routed
+	MetricsTables []string `yaml:"metrics-tables" toml:"metrics-tables" json:"metrics-tables"`
+	// fill the trailing columns missing in rows of INSERT events with their DEFAULT values (NULL if no DEFAULT),
+	// rows of events happening before columns added upstream are shorter than the table if the downstream has the columns already.
+	// rows mismatching columns in length are rejected if it's not set
+	FillMissingColumns bool `yaml:"fill-missing-columns" toml:"fill-missing-columns" json:"fill-missing-columns"`
+	// convert zero dates like `0000-00-00` and dates with zero parts like `2020-00-05` of nullable DATE, DATETIME and TIMESTAMP columns to NULL,
+	// set it if the target rejects them in strict mode with NO_ZERO_DATE or NO_ZERO_IN_DATE. they are kept as they are if it's not set
+	ZeroDateToNull bool `yaml:"zero-date-to-null" toml:"zero-date-to-null" json:"zero-date-to-null"`
+	// reject rows of INSERT events with NULL values for NOT NULL columns of the target table with an error naming the column,
+	// rather than executing them and leaving the target to reject them. it's not checked if it's not set
+	StrictNotNull bool `yaml:"strict-not-null" toml:"strict-not-null" json:"strict-not-null"`
+	// execute DMLs of a source transaction (ended by a XID event) in one transaction of the target, so the target never sees a part of it.
+	// DMLs of a transaction are kept in memory until it ends, and executed by one worker, large transactions use more memory
+	KeepTransaction bool `yaml:"keep-transaction" toml:"keep-transaction" json:"keep-transaction"`
+	// when a batch of DML statements fails, execute them again one at a time in transactions rolled back to find the failing one,
+	// and report its SQL with values and keys of its rows in the error. it's slow for large batches, so it's not done if it's not set
+	DiagnoseBatchFailure bool `yaml:"diagnose-batch-failure" toml:"diagnose-batch-failure" json:"diagnose-batch-failure"`
+	// when an UPDATE statement matches no row in the target, like the row is missing for an INSERT skipped before,
+	// execute the REPLACE statement of the changed row instead in the same transaction, and log and count DELETE statements matching no row.
+	// rows of partial images (`binlog_row_image=MINIMAL`) are not replaced. such statements are ignored silently if it's not set
+	UpsertOnMissing bool `yaml:"upsert-on-missing" toml:"upsert-on-missing" json:"upsert-on-missing"`
+	// when an INSERT ... ON DUPLICATE KEY UPDATE statement of the on-duplicate conflict strategy fails with a duplicate entry,
+	// as the row updated conflicts with another row on another unique key, delete the other row and execute the statement again
+	// in the same transaction, like REPLACE does. statements of multiple rows (insert-batch > 1) fail as before
+	DeleteOnKeyConflict bool `yaml:"delete-on-key-conflict" toml:"delete-on-key-conflict" json:"delete-on-key-conflict"`
+	// log a warning with the rendered SQL of DML statements taking longer than the threshold to execute, like `500ms`, empty means not logged
+	SlowStatementThreshold string `yaml:"slow-statement-threshold" toml:"slow-statement-threshold" json:"slow-statement-threshold"`
+	// run EXPLAIN for slow UPDATE and DELETE statements after their transactions committed, and log the plans,
+	// which reveal WHERE clauses not using any index, like the ones of all columns for rows with NULL values in unique keys.
+	// it requires slow-statement-threshold
+	ExplainSlowStatements bool `yaml:"explain-slow-statements" toml:"explain-slow-statements" json:"explain-slow-statements"`
 
 	// refine following configs to top level configs?
 	AutoFixGTID      bool `yaml:"auto-fix-gtid" toml:"auto-fix-gtid" json:"auto-fix-gtid"`
@@ -189,6 +344,53 @@ type SyncerConfig struct {
 	EnableANSIQuotes bool `yaml:"enable-ansi-quotes" toml:"enable-ansi-quotes" json:"enable-ansi-quotes"`
 }
 
+// TableConflictStrategy specifies the conflict strategy of target tables matched by patterns, patterns are like route rules.
+// a rule with table-pattern takes precedence over a rule for the whole schema.
+type TableConflictStrategy struct {
+	SchemaPattern string `yaml:"schema-pattern" toml:"schema-pattern" json:"schema-pattern"`
+	TablePattern  string `yaml:"table-pattern" toml:"table-pattern" json:"table-pattern"`
+	Strategy      string `yaml:"strategy" toml:"strategy" json:"strategy"`
+}
+
+// TablePattern matches tables by patterns like route rules, a pattern without table-pattern matches the whole schema.
+type TablePattern struct {
+	SchemaPattern string `yaml:"schema-pattern" toml:"schema-pattern" json:"schema-pattern"`
+	TablePattern  string `yaml:"table-pattern" toml:"table-pattern" json:"table-pattern"`
+}
+
+// RowFilterRule specifies the condition of rows restored of source tables matched by patterns, patterns are like route rules.
+// a rule with table-pattern takes precedence over a rule for the whole schema.
+type RowFilterRule struct {
+	SchemaPattern string `yaml:"schema-pattern" toml:"schema-pattern" json:"schema-pattern"`
+	TablePattern  string `yaml:"table-pattern" toml:"table-pattern" json:"table-pattern"`
+	// condition like the WHERE clause, like `tenant_id = 42 AND deleted_at IS NULL`
+	Where string `yaml:"where" toml:"where" json:"where"`
+}
+
+// TableRowLimit specifies the row limit of target tables matched by patterns, patterns are like route rules.
+// a rule with table-pattern takes precedence over a rule for the whole schema.
+type TableRowLimit struct {
+	SchemaPattern string `yaml:"schema-pattern" toml:"schema-pattern" json:"schema-pattern"`
+	TablePattern  string `yaml:"table-pattern" toml:"table-pattern" json:"table-pattern"`
+	Limit         int    `yaml:"limit" toml:"limit" json:"limit"`
+}
+
+// TablePreferredIndex specifies the unique index preferred to identify rows of target tables matched by patterns, patterns are like route rules.
+// a rule with table-pattern takes precedence over a rule for the whole schema.
+type TablePreferredIndex struct {
+	SchemaPattern string `yaml:"schema-pattern" toml:"schema-pattern" json:"schema-pattern"`
+	TablePattern  string `yaml:"table-pattern" toml:"table-pattern" json:"table-pattern"`
+	Index         string `yaml:"index" toml:"index" json:"index"`
+}
+
+// TableChangeIgnoredColumns specifies the columns ignored when detecting changes of rows of target tables matched by patterns,
+// patterns are like route rules. a rule with table-pattern takes precedence over a rule for the whole schema.
+type TableChangeIgnoredColumns struct {
+	SchemaPattern string   `yaml:"schema-pattern" toml:"schema-pattern" json:"schema-pattern"`
+	TablePattern  string   `yaml:"table-pattern" toml:"table-pattern" json:"table-pattern"`
+	Columns       []string `yaml:"columns" toml:"columns" json:"columns"`
+}
+
 func defaultSyncerConfig() SyncerConfig {
 	return SyncerConfig{
 		WorkerCount: defaultWorkerCount,
@@ -226,6 +428,9 @@ type TaskConfig struct {
 	DisableHeartbeat bool   `yaml:"disable-heartbeat"` //  deprecated, use !enable-heartbeat instead
 	EnableHeartbeat  bool   `yaml:"enable-heartbeat"`
 	Timezone         string `yaml:"timezone"`
+	// generate sqls without executing them in downstream, sqls are written into dry-run-file or log
+	DryRun     bool   `yaml:"dry-run"`
+	DryRunFile string `yaml:"dry-run-file"`
 
 	// handle schema/table name mode, and only for schema/table name
 	// if case insensitive, we would convert schema/table name to lower case
@@ -440,6 +645,8 @@ func (c *TaskConfig) SubTaskConfigs(sources map[string]DBConfig) ([]*SubTaskConf
 		cfg.DisableHeartbeat = c.DisableHeartbeat
 		cfg.EnableHeartbeat = c.EnableHeartbeat || !c.DisableHeartbeat
 		cfg.Timezone = c.Timezone
+		cfg.DryRun = c.DryRun
+		cfg.DryRunFile = c.DryRunFile
 		cfg.Meta = inst.Meta
 
 		cfg.From = dbCfg
@@ -87,24 +87,33 @@ func (SQLOp) EnumDescriptor() ([]byte, []int) {
 // a (sub) task should be always in one stage of the following stages
 // (sub) task can transfer from on stage to some special other stages
 // New: initial stage when a sub task is created
-//      can not transfered from other stages
-//      transfer to Running when initialize with no error
+//
+//	can not transfered from other stages
+//	transfer to Running when initialize with no error
+//
 // Running: indicates the sub task is processing
-//          transfered from New when created successfully
-//          transfered from Paused when resuming is requested
-//          transfer to Paused when error occured or requested from external
-//          transfer to Stopped when requested from external
-//          transfer to Finished when sub task processing completed (no Syncer used)
+//
+//	transfered from New when created successfully
+//	transfered from Paused when resuming is requested
+//	transfer to Paused when error occured or requested from external
+//	transfer to Stopped when requested from external
+//	transfer to Finished when sub task processing completed (no Syncer used)
+//
 // Paused: indicates the processing is paused, and can be resume from external request
-//         transfered from Running when error occured or requested from external
-//         transfer to Running when resuming is requested from external
-//         transfer to Stopped when requested from external
+//
+//	transfered from Running when error occured or requested from external
+//	transfer to Running when resuming is requested from external
+//	transfer to Stopped when requested from external
+//
 // Stopped: indicates the processing is stopped, and can not be resume (or re-run) again
-//          transfered from Running / Paused when requested from external
-//          can not transfer to any stages
+//
+//	transfered from Running / Paused when requested from external
+//	can not transfer to any stages
+//
 // Finished: indicates the processing is finished, and no need to re-run
-//           transfered from Running when processing completed
-//           should not transfer to any stages
+//
+//	transfered from Running when processing completed
+//	should not transfer to any stages
 type Stage int32
 
 const (
@@ -1052,10 +1061,19 @@ var xxx_messageInfo_DumpStatus proto.InternalMessageInfo
 
 // LoadStatus represents status for load unit
 type LoadStatus struct {
-	FinishedBytes int64  `protobuf:"varint,1,opt,name=finishedBytes,proto3" json:"finishedBytes,omitempty"`
-	TotalBytes    int64  `protobuf:"varint,2,opt,name=totalBytes,proto3" json:"totalBytes,omitempty"`
-	Progress      string `protobuf:"bytes,3,opt,name=progress,proto3" json:"progress,omitempty"`
-	MetaBinlog    string `protobuf:"bytes,4,opt,name=metaBinlog,proto3" json:"metaBinlog,omitempty"`
+	FinishedBytes  int64              `protobuf:"varint,1,opt,name=finishedBytes,proto3" json:"finishedBytes,omitempty"`
+	TotalBytes     int64              `protobuf:"varint,2,opt,name=totalBytes,proto3" json:"totalBytes,omitempty"`
+	Progress       string             `protobuf:"bytes,3,opt,name=progress,proto3" json:"progress,omitempty"`
+	MetaBinlog     string             `protobuf:"bytes,4,opt,name=metaBinlog,proto3" json:"metaBinlog,omitempty"`
+	Tables         []*TableLoadStatus `protobuf:"bytes,5,rep,name=tables,proto3" json:"tables,omitempty"`
+	EtaSeconds     int64              `protobuf:"varint,6,opt,name=etaSeconds,proto3" json:"etaSeconds,omitempty"`
+	MetaBinlogName string             `protobuf:"bytes,7,opt,name=metaBinlogName,proto3" json:"metaBinlogName,omitempty"`
+	MetaBinlogPos  uint32             `protobuf:"varint,8,opt,name=metaBinlogPos,proto3" json:"metaBinlogPos,omitempty"`
+	Validations    []*TableValidation `protobuf:"bytes,9,rep,name=validations" json:"validations,omitempty"`
+	FinishedRows   int64              `protobuf:"varint,10,opt,name=finishedRows,proto3" json:"finishedRows,omitempty"`
+	TotalRows      int64              `protobuf:"varint,11,opt,name=totalRows,proto3" json:"totalRows,omitempty"`
+	Paused         bool               `protobuf:"varint,12,opt,name=paused,proto3" json:"paused,omitempty"`
+	MetaBinlogGTID string             `protobuf:"bytes,13,opt,name=metaBinlogGTID,proto3" json:"metaBinlogGTID,omitempty"`
 }
 
 func (m *LoadStatus) Reset()         { *m = LoadStatus{} }
@@ -1119,6 +1137,247 @@ func (m *LoadStatus) GetMetaBinlog() string {
 	return ""
 }
 
+func (m *LoadStatus) GetTables() []*TableLoadStatus {
+	if m != nil {
+		return m.Tables
+	}
+	return nil
+}
+
+func (m *LoadStatus) GetEtaSeconds() int64 {
+	if m != nil {
+		return m.EtaSeconds
+	}
+	return 0
+}
+
+func (m *LoadStatus) GetMetaBinlogName() string {
+	if m != nil {
+		return m.MetaBinlogName
+	}
+	return ""
+}
+
+func (m *LoadStatus) GetMetaBinlogPos() uint32 {
+	if m != nil {
+		return m.MetaBinlogPos
+	}
+	return 0
+}
+
+func (m *LoadStatus) GetValidations() []*TableValidation {
+	if m != nil {
+		return m.Validations
+	}
+	return nil
+}
+
+func (m *LoadStatus) GetFinishedRows() int64 {
+	if m != nil {
+		return m.FinishedRows
+	}
+	return 0
+}
+
+func (m *LoadStatus) GetTotalRows() int64 {
+	if m != nil {
+		return m.TotalRows
+	}
+	return 0
+}
+
+func (m *LoadStatus) GetPaused() bool {
+	if m != nil {
+		return m.Paused
+	}
+	return false
+}
+
+func (m *LoadStatus) GetMetaBinlogGTID() string {
+	if m != nil {
+		return m.MetaBinlogGTID
+	}
+	return ""
+}
+
+// TableLoadStatus represents the restoring progress of a source table in load unit
+// table: source table name, like `db`.`table`
+// remainingFiles: count of data files not finished yet
+// finishedRows, totalRows: rows of executed INSERT statements and all INSERT statements in data files
+type TableLoadStatus struct {
+	Table          string `protobuf:"bytes,1,opt,name=table,proto3" json:"table,omitempty"`
+	FinishedBytes  int64  `protobuf:"varint,2,opt,name=finishedBytes,proto3" json:"finishedBytes,omitempty"`
+	TotalBytes     int64  `protobuf:"varint,3,opt,name=totalBytes,proto3" json:"totalBytes,omitempty"`
+	RemainingFiles int32  `protobuf:"varint,4,opt,name=remainingFiles,proto3" json:"remainingFiles,omitempty"`
+	FinishedRows   int64  `protobuf:"varint,5,opt,name=finishedRows,proto3" json:"finishedRows,omitempty"`
+	TotalRows      int64  `protobuf:"varint,6,opt,name=totalRows,proto3" json:"totalRows,omitempty"`
+}
+
+func (m *TableLoadStatus) Reset()         { *m = TableLoadStatus{} }
+func (m *TableLoadStatus) String() string { return proto.CompactTextString(m) }
+func (*TableLoadStatus) ProtoMessage()    {}
+func (*TableLoadStatus) Descriptor() ([]byte, []int) {
+	return fileDescriptor_51a1b9e17fd67b10, []int{15}
+}
+func (m *TableLoadStatus) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *TableLoadStatus) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_TableLoadStatus.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalTo(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *TableLoadStatus) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TableLoadStatus.Merge(m, src)
+}
+func (m *TableLoadStatus) XXX_Size() int {
+	return m.Size()
+}
+func (m *TableLoadStatus) XXX_DiscardUnknown() {
+	xxx_messageInfo_TableLoadStatus.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TableLoadStatus proto.InternalMessageInfo
+
+func (m *TableLoadStatus) GetTable() string {
+	if m != nil {
+		return m.Table
+	}
+	return ""
+}
+
+func (m *TableLoadStatus) GetFinishedBytes() int64 {
+	if m != nil {
+		return m.FinishedBytes
+	}
+	return 0
+}
+
+func (m *TableLoadStatus) GetTotalBytes() int64 {
+	if m != nil {
+		return m.TotalBytes
+	}
+	return 0
+}
+
+func (m *TableLoadStatus) GetRemainingFiles() int32 {
+	if m != nil {
+		return m.RemainingFiles
+	}
+	return 0
+}
+
+func (m *TableLoadStatus) GetFinishedRows() int64 {
+	if m != nil {
+		return m.FinishedRows
+	}
+	return 0
+}
+
+func (m *TableLoadStatus) GetTotalRows() int64 {
+	if m != nil {
+		return m.TotalRows
+	}
+	return 0
+}
+
+// TableValidation represents the result of comparing a table between source and target after restored in load unit
+// table: source table name, like `db`.`table`
+// targetTable: target table name
+// mode: `count` compares row counts, `checksum` compares CRC32 of sampled primary keys
+// sourceValue, targetValue: row counts or checksums of the source and target tables
+// matched: whether sourceValue equals targetValue
+type TableValidation struct {
+	Table       string `protobuf:"bytes,1,opt,name=table,proto3" json:"table,omitempty"`
+	TargetTable string `protobuf:"bytes,2,opt,name=targetTable,proto3" json:"targetTable,omitempty"`
+	Mode        string `protobuf:"bytes,3,opt,name=mode,proto3" json:"mode,omitempty"`
+	SourceValue uint64 `protobuf:"varint,4,opt,name=sourceValue,proto3" json:"sourceValue,omitempty"`
+	TargetValue uint64 `protobuf:"varint,5,opt,name=targetValue,proto3" json:"targetValue,omitempty"`
+	Matched     bool   `protobuf:"varint,6,opt,name=matched,proto3" json:"matched,omitempty"`
+}
+
+func (m *TableValidation) Reset()         { *m = TableValidation{} }
+func (m *TableValidation) String() string { return proto.CompactTextString(m) }
+func (*TableValidation) ProtoMessage()    {}
+func (*TableValidation) Descriptor() ([]byte, []int) {
+	return fileDescriptor_51a1b9e17fd67b10, []int{16}
+}
+func (m *TableValidation) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *TableValidation) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_TableValidation.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalTo(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *TableValidation) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TableValidation.Merge(m, src)
+}
+func (m *TableValidation) XXX_Size() int {
+	return m.Size()
+}
+func (m *TableValidation) XXX_DiscardUnknown() {
+	xxx_messageInfo_TableValidation.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TableValidation proto.InternalMessageInfo
+
+func (m *TableValidation) GetTable() string {
+	if m != nil {
+		return m.Table
+	}
+	return ""
+}
+
+func (m *TableValidation) GetTargetTable() string {
+	if m != nil {
+		return m.TargetTable
+	}
+	return ""
+}
+
+func (m *TableValidation) GetMode() string {
+	if m != nil {
+		return m.Mode
+	}
+	return ""
+}
+
+func (m *TableValidation) GetSourceValue() uint64 {
+	if m != nil {
+		return m.SourceValue
+	}
+	return 0
+}
+
+func (m *TableValidation) GetTargetValue() uint64 {
+	if m != nil {
+		return m.TargetValue
+	}
+	return 0
+}
+
+func (m *TableValidation) GetMatched() bool {
+	if m != nil {
+		return m.Matched
+	}
+	return false
+}
+
 // ShardingGroup represents a DDL sharding group, this is used by SyncStatus, and is differ from ShardingGroup in syncer pkg
 // target: target table name
 // DDL: in syncing DDL
@@ -1137,7 +1396,7 @@ func (m *ShardingGroup) Reset()         { *m = ShardingGroup{} }
 func (m *ShardingGroup) String() string { return proto.CompactTextString(m) }
 func (*ShardingGroup) ProtoMessage()    {}
 func (*ShardingGroup) Descriptor() ([]byte, []int) {
-	return fileDescriptor_51a1b9e17fd67b10, []int{15}
+	return fileDescriptor_51a1b9e17fd67b10, []int{17}
 }
 func (m *ShardingGroup) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -1219,7 +1478,7 @@ func (m *SyncStatus) Reset()         { *m = SyncStatus{} }
 func (m *SyncStatus) String() string { return proto.CompactTextString(m) }
 func (*SyncStatus) ProtoMessage()    {}
 func (*SyncStatus) Descriptor() ([]byte, []int) {
-	return fileDescriptor_51a1b9e17fd67b10, []int{16}
+	return fileDescriptor_51a1b9e17fd67b10, []int{18}
 }
 func (m *SyncStatus) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -1334,7 +1593,7 @@ func (m *RelayStatus) Reset()         { *m = RelayStatus{} }
 func (m *RelayStatus) String() string { return proto.CompactTextString(m) }
 func (*RelayStatus) ProtoMessage()    {}
 func (*RelayStatus) Descriptor() ([]byte, []int) {
-	return fileDescriptor_51a1b9e17fd67b10, []int{17}
+	return fileDescriptor_51a1b9e17fd67b10, []int{19}
 }
 func (m *RelayStatus) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -1425,10 +1684,13 @@ func (m *RelayStatus) GetResult() *ProcessResult {
 // unit: sub task's current dm unit's UnitType
 // result: current unit's process result, when the stage is Running, no result
 // unresolvedDDLLockID: un-resolved sharding DDL lock ID (ref DDLLockInfo)
-//                      if needed, we can put this to SyncStatus
+//
+//	if needed, we can put this to SyncStatus
+//
 // status: current unit's statistics
-//         for Load, includes total bytes, progress, etc.
-//         for Sync, includes TPS, binlog meta, etc.
+//
+//	for Load, includes total bytes, progress, etc.
+//	for Sync, includes TPS, binlog meta, etc.
 type SubTaskStatus struct {
 	Name                string         `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
 	Stage               Stage          `protobuf:"varint,2,opt,name=stage,proto3,enum=pb.Stage" json:"stage,omitempty"`
@@ -1448,7 +1710,7 @@ func (m *SubTaskStatus) Reset()         { *m = SubTaskStatus{} }
 func (m *SubTaskStatus) String() string { return proto.CompactTextString(m) }
 func (*SubTaskStatus) ProtoMessage()    {}
 func (*SubTaskStatus) Descriptor() ([]byte, []int) {
-	return fileDescriptor_51a1b9e17fd67b10, []int{18}
+	return fileDescriptor_51a1b9e17fd67b10, []int{20}
 }
 func (m *SubTaskStatus) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -1718,7 +1980,7 @@ func (m *SubTaskStatusList) Reset()         { *m = SubTaskStatusList{} }
 func (m *SubTaskStatusList) String() string { return proto.CompactTextString(m) }
 func (*SubTaskStatusList) ProtoMessage()    {}
 func (*SubTaskStatusList) Descriptor() ([]byte, []int) {
-	return fileDescriptor_51a1b9e17fd67b10, []int{19}
+	return fileDescriptor_51a1b9e17fd67b10, []int{21}
 }
 func (m *SubTaskStatusList) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -1764,7 +2026,7 @@ func (m *CheckError) Reset()         { *m = CheckError{} }
 func (m *CheckError) String() string { return proto.CompactTextString(m) }
 func (*CheckError) ProtoMessage()    {}
 func (*CheckError) Descriptor() ([]byte, []int) {
-	return fileDescriptor_51a1b9e17fd67b10, []int{20}
+	return fileDescriptor_51a1b9e17fd67b10, []int{22}
 }
 func (m *CheckError) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -1810,7 +2072,7 @@ func (m *DumpError) Reset()         { *m = DumpError{} }
 func (m *DumpError) String() string { return proto.CompactTextString(m) }
 func (*DumpError) ProtoMessage()    {}
 func (*DumpError) Descriptor() ([]byte, []int) {
-	return fileDescriptor_51a1b9e17fd67b10, []int{21}
+	return fileDescriptor_51a1b9e17fd67b10, []int{23}
 }
 func (m *DumpError) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -1855,7 +2117,7 @@ func (m *LoadError) Reset()         { *m = LoadError{} }
 func (m *LoadError) String() string { return proto.CompactTextString(m) }
 func (*LoadError) ProtoMessage()    {}
 func (*LoadError) Descriptor() ([]byte, []int) {
-	return fileDescriptor_51a1b9e17fd67b10, []int{22}
+	return fileDescriptor_51a1b9e17fd67b10, []int{24}
 }
 func (m *LoadError) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -1902,7 +2164,7 @@ func (m *SyncSQLError) Reset()         { *m = SyncSQLError{} }
 func (m *SyncSQLError) String() string { return proto.CompactTextString(m) }
 func (*SyncSQLError) ProtoMessage()    {}
 func (*SyncSQLError) Descriptor() ([]byte, []int) {
-	return fileDescriptor_51a1b9e17fd67b10, []int{23}
+	return fileDescriptor_51a1b9e17fd67b10, []int{25}
 }
 func (m *SyncSQLError) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -1961,7 +2223,7 @@ func (m *SyncError) Reset()         { *m = SyncError{} }
 func (m *SyncError) String() string { return proto.CompactTextString(m) }
 func (*SyncError) ProtoMessage()    {}
 func (*SyncError) Descriptor() ([]byte, []int) {
-	return fileDescriptor_51a1b9e17fd67b10, []int{24}
+	return fileDescriptor_51a1b9e17fd67b10, []int{26}
 }
 func (m *SyncError) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -2006,7 +2268,7 @@ func (m *RelayError) Reset()         { *m = RelayError{} }
 func (m *RelayError) String() string { return proto.CompactTextString(m) }
 func (*RelayError) ProtoMessage()    {}
 func (*RelayError) Descriptor() ([]byte, []int) {
-	return fileDescriptor_51a1b9e17fd67b10, []int{25}
+	return fileDescriptor_51a1b9e17fd67b10, []int{27}
 }
 func (m *RelayError) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -2047,7 +2309,8 @@ func (m *RelayError) GetMsg() string {
 // stage: sub task's current stage
 // unit: sub task's current dm unit's UnitType
 // error: current unit's error information
-//         for Sync, includes failed sql, failed sql pos in binlog, etc.
+//
+//	for Sync, includes failed sql, failed sql pos in binlog, etc.
 type SubTaskError struct {
 	Name  string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
 	Stage Stage    `protobuf:"varint,2,opt,name=stage,proto3,enum=pb.Stage" json:"stage,omitempty"`
@@ -2065,7 +2328,7 @@ func (m *SubTaskError) Reset()         { *m = SubTaskError{} }
 func (m *SubTaskError) String() string { return proto.CompactTextString(m) }
 func (*SubTaskError) ProtoMessage()    {}
 func (*SubTaskError) Descriptor() ([]byte, []int) {
-	return fileDescriptor_51a1b9e17fd67b10, []int{26}
+	return fileDescriptor_51a1b9e17fd67b10, []int{28}
 }
 func (m *SubTaskError) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -2321,7 +2584,7 @@ func (m *SubTaskErrorList) Reset()         { *m = SubTaskErrorList{} }
 func (m *SubTaskErrorList) String() string { return proto.CompactTextString(m) }
 func (*SubTaskErrorList) ProtoMessage()    {}
 func (*SubTaskErrorList) Descriptor() ([]byte, []int) {
-	return fileDescriptor_51a1b9e17fd67b10, []int{27}
+	return fileDescriptor_51a1b9e17fd67b10, []int{29}
 }
 func (m *SubTaskErrorList) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -2359,7 +2622,9 @@ func (m *SubTaskErrorList) GetError() []*SubTaskError {
 
 // ProcessResult represents results produced by a dm unit
 // isCanceled: indicates whether the process is canceled from external
-//             when Stop or Pause is requested from external, isCanceled will be true
+//
+//	when Stop or Pause is requested from external, isCanceled will be true
+//
 // errors: includes all (potential) errors occured when processing
 type ProcessResult struct {
 	IsCanceled bool            `protobuf:"varint,1,opt,name=isCanceled,proto3" json:"isCanceled,omitempty"`
@@ -2371,7 +2636,7 @@ func (m *ProcessResult) Reset()         { *m = ProcessResult{} }
 func (m *ProcessResult) String() string { return proto.CompactTextString(m) }
 func (*ProcessResult) ProtoMessage()    {}
 func (*ProcessResult) Descriptor() ([]byte, []int) {
-	return fileDescriptor_51a1b9e17fd67b10, []int{28}
+	return fileDescriptor_51a1b9e17fd67b10, []int{30}
 }
 func (m *ProcessResult) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -2432,7 +2697,7 @@ func (m *ProcessError) Reset()         { *m = ProcessError{} }
 func (m *ProcessError) String() string { return proto.CompactTextString(m) }
 func (*ProcessError) ProtoMessage()    {}
 func (*ProcessError) Descriptor() ([]byte, []int) {
-	return fileDescriptor_51a1b9e17fd67b10, []int{29}
+	return fileDescriptor_51a1b9e17fd67b10, []int{31}
 }
 func (m *ProcessError) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -2490,7 +2755,7 @@ func (m *DDLInfo) Reset()         { *m = DDLInfo{} }
 func (m *DDLInfo) String() string { return proto.CompactTextString(m) }
 func (*DDLInfo) ProtoMessage()    {}
 func (*DDLInfo) Descriptor() ([]byte, []int) {
-	return fileDescriptor_51a1b9e17fd67b10, []int{30}
+	return fileDescriptor_51a1b9e17fd67b10, []int{32}
 }
 func (m *DDLInfo) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -2559,7 +2824,7 @@ func (m *DDLLockInfo) Reset()         { *m = DDLLockInfo{} }
 func (m *DDLLockInfo) String() string { return proto.CompactTextString(m) }
 func (*DDLLockInfo) ProtoMessage()    {}
 func (*DDLLockInfo) Descriptor() ([]byte, []int) {
-	return fileDescriptor_51a1b9e17fd67b10, []int{31}
+	return fileDescriptor_51a1b9e17fd67b10, []int{33}
 }
 func (m *DDLLockInfo) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -2613,7 +2878,7 @@ func (m *ExecDDLRequest) Reset()         { *m = ExecDDLRequest{} }
 func (m *ExecDDLRequest) String() string { return proto.CompactTextString(m) }
 func (*ExecDDLRequest) ProtoMessage()    {}
 func (*ExecDDLRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_51a1b9e17fd67b10, []int{32}
+	return fileDescriptor_51a1b9e17fd67b10, []int{34}
 }
 func (m *ExecDDLRequest) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -2680,7 +2945,7 @@ func (m *BreakDDLLockRequest) Reset()         { *m = BreakDDLLockRequest{} }
 func (m *BreakDDLLockRequest) String() string { return proto.CompactTextString(m) }
 func (*BreakDDLLockRequest) ProtoMessage()    {}
 func (*BreakDDLLockRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_51a1b9e17fd67b10, []int{33}
+	return fileDescriptor_51a1b9e17fd67b10, []int{35}
 }
 func (m *BreakDDLLockRequest) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -2745,7 +3010,7 @@ func (m *SwitchRelayMasterRequest) Reset()         { *m = SwitchRelayMasterReque
 func (m *SwitchRelayMasterRequest) String() string { return proto.CompactTextString(m) }
 func (*SwitchRelayMasterRequest) ProtoMessage()    {}
 func (*SwitchRelayMasterRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_51a1b9e17fd67b10, []int{34}
+	return fileDescriptor_51a1b9e17fd67b10, []int{36}
 }
 func (m *SwitchRelayMasterRequest) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -2783,7 +3048,7 @@ func (m *OperateRelayRequest) Reset()         { *m = OperateRelayRequest{} }
 func (m *OperateRelayRequest) String() string { return proto.CompactTextString(m) }
 func (*OperateRelayRequest) ProtoMessage()    {}
 func (*OperateRelayRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_51a1b9e17fd67b10, []int{35}
+	return fileDescriptor_51a1b9e17fd67b10, []int{37}
 }
 func (m *OperateRelayRequest) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -2830,7 +3095,7 @@ func (m *OperateRelayResponse) Reset()         { *m = OperateRelayResponse{} }
 func (m *OperateRelayResponse) String() string { return proto.CompactTextString(m) }
 func (*OperateRelayResponse) ProtoMessage()    {}
 func (*OperateRelayResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_51a1b9e17fd67b10, []int{36}
+	return fileDescriptor_51a1b9e17fd67b10, []int{38}
 }
 func (m *OperateRelayResponse) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -2903,7 +3168,7 @@ func (m *PurgeRelayRequest) Reset()         { *m = PurgeRelayRequest{} }
 func (m *PurgeRelayRequest) String() string { return proto.CompactTextString(m) }
 func (*PurgeRelayRequest) ProtoMessage()    {}
 func (*PurgeRelayRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_51a1b9e17fd67b10, []int{37}
+	return fileDescriptor_51a1b9e17fd67b10, []int{39}
 }
 func (m *PurgeRelayRequest) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -2967,7 +3232,7 @@ func (m *QueryWorkerConfigRequest) Reset()         { *m = QueryWorkerConfigReque
 func (m *QueryWorkerConfigRequest) String() string { return proto.CompactTextString(m) }
 func (*QueryWorkerConfigRequest) ProtoMessage()    {}
 func (*QueryWorkerConfigRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_51a1b9e17fd67b10, []int{38}
+	return fileDescriptor_51a1b9e17fd67b10, []int{40}
 }
 func (m *QueryWorkerConfigRequest) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -3008,7 +3273,7 @@ func (m *QueryWorkerConfigResponse) Reset()         { *m = QueryWorkerConfigResp
 func (m *QueryWorkerConfigResponse) String() string { return proto.CompactTextString(m) }
 func (*QueryWorkerConfigResponse) ProtoMessage()    {}
 func (*QueryWorkerConfigResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_51a1b9e17fd67b10, []int{39}
+	return fileDescriptor_51a1b9e17fd67b10, []int{41}
 }
 func (m *QueryWorkerConfigResponse) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -3094,6 +3359,8 @@ func init() {
 	proto.RegisterType((*CheckStatus)(nil), "pb.CheckStatus")
 	proto.RegisterType((*DumpStatus)(nil), "pb.DumpStatus")
 	proto.RegisterType((*LoadStatus)(nil), "pb.LoadStatus")
+	proto.RegisterType((*TableLoadStatus)(nil), "pb.TableLoadStatus")
+	proto.RegisterType((*TableValidation)(nil), "pb.TableValidation")
 	proto.RegisterType((*ShardingGroup)(nil), "pb.ShardingGroup")
 	proto.RegisterType((*SyncStatus)(nil), "pb.SyncStatus")
 	proto.RegisterType((*RelayStatus)(nil), "pb.RelayStatus")
@@ -3124,138 +3391,151 @@ func init() {
 func init() { proto.RegisterFile("dmworker.proto", fileDescriptor_51a1b9e17fd67b10) }
 
 var fileDescriptor_51a1b9e17fd67b10 = []byte{
-	// 2083 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xb4, 0x58, 0xcd, 0x6f, 0x23, 0x49,
-	0x15, 0x77, 0xb7, 0x3f, 0x62, 0x3f, 0x3b, 0xde, 0x4e, 0x25, 0x9b, 0xf5, 0x98, 0xdd, 0x10, 0x7a,
-	0x56, 0xbb, 0x59, 0x1f, 0xa2, 0xdd, 0x00, 0x02, 0x01, 0xcb, 0x47, 0xec, 0xcc, 0x8c, 0xc1, 0x33,
-	0x93, 0xb4, 0x67, 0x80, 0x1b, 0xea, 0xb4, 0x2b, 0x4e, 0x2b, 0xed, 0xee, 0x9e, 0xfe, 0x48, 0x36,
-	0x47, 0xc4, 0x11, 0x09, 0x21, 0x21, 0x21, 0x21, 0xce, 0xfc, 0x17, 0xdc, 0x38, 0xc0, 0x71, 0x8f,
-	0x1c, 0xd1, 0xcc, 0xbf, 0xc1, 0x01, 0xbd, 0x57, 0xd5, 0xdd, 0xd5, 0x89, 0xed, 0xdd, 0xc3, 0x70,
-	0xb1, 0xfc, 0x3e, 0xea, 0xd5, 0xab, 0x5f, 0xbd, 0xfe, 0xbd, 0xaa, 0x82, 0xee, 0x6c, 0x71, 0x13,
-	0x44, 0x57, 0x3c, 0x3a, 0x0c, 0xa3, 0x20, 0x09, 0x98, 0x1e, 0x9e, 0x9b, 0x9f, 0xc0, 0xf6, 0x34,
-	0xb1, 0xa3, 0x64, 0x9a, 0x9e, 0xbf, 0xb0, 0xe3, 0x2b, 0x8b, 0xbf, 0x4a, 0x79, 0x9c, 0x30, 0x06,
-	0xb5, 0xc4, 0x8e, 0xaf, 0x7a, 0xda, 0xbe, 0x76, 0xd0, 0xb2, 0xe8, 0xbf, 0x79, 0x08, 0xec, 0x65,
-	0x38, 0xb3, 0x13, 0x6e, 0x71, 0xcf, 0xbe, 0xcd, 0x3c, 0x7b, 0xb0, 0xe1, 0x04, 0x7e, 0xc2, 0xfd,
-	0x44, 0x3a, 0x67, 0xa2, 0x39, 0x85, 0xed, 0xa7, 0xee, 0x3c, 0xba, 0x3b, 0x60, 0x0f, 0xe0, 0xd8,
-	0xf5, 0xbd, 0x60, 0xfe, 0xcc, 0x5e, 0x70, 0x39, 0x46, 0xd1, 0xb0, 0xf7, 0xa1, 0x25, 0xa4, 0xd3,
-	0x20, 0xee, 0xe9, 0xfb, 0xda, 0xc1, 0xa6, 0x55, 0x28, 0xcc, 0xc7, 0xf0, 0xee, 0xf3, 0x90, 0x63,
-	0xd0, 0x3b, 0x19, 0xf7, 0x41, 0x0f, 0x42, 0x0a, 0xd7, 0x3d, 0x82, 0xc3, 0xf0, 0xfc, 0x10, 0x8d,
-	0xcf, 0x43, 0x4b, 0x0f, 0x42, 0x5c, 0x8d, 0x8f, 0x93, 0xe9, 0x62, 0x35, 0xf8, 0xdf, 0xbc, 0x86,
-	0xdd, 0xbb, 0x81, 0xe2, 0x30, 0xf0, 0x63, 0xbe, 0x36, 0xd2, 0x2e, 0x34, 0x22, 0x1e, 0xa7, 0x5e,
-	0x42, 0xb1, 0x9a, 0x96, 0x94, 0x50, 0x2f, 0xa0, 0xed, 0x55, 0x69, 0x0e, 0x29, 0x31, 0x03, 0xaa,
-	0x8b, 0x78, 0xde, 0xab, 0x91, 0x12, 0xff, 0x9a, 0x03, 0xd8, 0x11, 0x28, 0x7e, 0x0d, 0xc4, 0x0f,
-	0x80, 0x9d, 0xa5, 0x3c, 0xba, 0x9d, 0x26, 0x76, 0x92, 0xc6, 0x8a, 0xa7, 0x5f, 0x40, 0x27, 0x56,
-	0xf3, 0x31, 0x6c, 0x91, 0xe7, 0x49, 0x14, 0x05, 0xd1, 0x3a, 0xc7, 0xbf, 0x6a, 0xd0, 0x7b, 0x62,
-	0xfb, 0x33, 0x2f, 0x9b, 0x7f, 0x7a, 0x36, 0x59, 0x17, 0x99, 0x3d, 0x20, 0x34, 0x74, 0x42, 0xa3,
-	0x85, 0x68, 0x4c, 0xcf, 0x26, 0x05, 0xac, 0x76, 0x34, 0x8f, 0x7b, 0xd5, 0xfd, 0x2a, 0xba, 0xe3,
-	0x7f, 0xdc, 0xbd, 0xf3, 0x7c, 0xf7, 0xc4, 0xb2, 0x0b, 0x05, 0xee, 0x7d, 0xfc, 0xca, 0x3b, 0xb5,
-	0x93, 0x84, 0x47, 0x7e, 0xaf, 0x2e, 0xf6, 0xbe, 0xd0, 0x98, 0xbf, 0x86, 0x9d, 0x61, 0xb0, 0x58,
-	0x04, 0xfe, 0xaf, 0x08, 0xbe, 0x7c, 0x4b, 0x0a, 0xd8, 0xb5, 0x15, 0xb0, 0xeb, 0xcb, 0x60, 0xaf,
-	0x16, 0xb0, 0xff, 0x43, 0x83, 0xed, 0x12, 0x96, 0x6f, 0x2b, 0x32, 0xfb, 0x1e, 0x6c, 0xc6, 0x12,
-	0x4a, 0x0a, 0xdd, 0xab, 0xed, 0x57, 0x0f, 0xda, 0x47, 0x5b, 0x84, 0x95, 0x6a, 0xb0, 0xca, 0x7e,
-	0xec, 0x33, 0x68, 0x47, 0xf8, 0x61, 0xc8, 0x61, 0x88, 0x46, 0xfb, 0xe8, 0x1d, 0x1c, 0x66, 0x15,
-	0x6a, 0x4b, 0xf5, 0x31, 0xff, 0xae, 0xc9, 0x8a, 0x90, 0xfb, 0xfc, 0xd6, 0x16, 0xf1, 0x1d, 0xe8,
-	0xc8, 0xe4, 0x28, 0xb2, 0x5c, 0x83, 0xa1, 0xac, 0x41, 0xcc, 0x58, 0xf2, 0x62, 0x87, 0x00, 0x94,
-	0xaa, 0x18, 0x23, 0x16, 0xd0, 0xcd, 0x17, 0x20, 0x46, 0x28, 0x1e, 0xe6, 0xdf, 0x34, 0x68, 0x0f,
-	0x2f, 0xb9, 0x93, 0x21, 0xb0, 0x0b, 0x8d, 0xd0, 0x8e, 0x63, 0x3e, 0xcb, 0xf2, 0x16, 0x12, 0xdb,
-	0x81, 0x7a, 0x12, 0x24, 0xb6, 0x47, 0x69, 0xd7, 0x2d, 0x21, 0x50, 0xf1, 0xa4, 0x8e, 0xc3, 0xe3,
-	0xf8, 0x22, 0xf5, 0x28, 0xf9, 0xba, 0xa5, 0x68, 0x30, 0xda, 0x85, 0xed, 0x7a, 0x7c, 0x46, 0x75,
-	0x57, 0xb7, 0xa4, 0x84, 0x0c, 0x75, 0x63, 0x47, 0xbe, 0xeb, 0xcf, 0x29, 0xc5, 0xba, 0x95, 0x89,
-	0x38, 0x62, 0xc6, 0x13, 0xdb, 0xf5, 0x7a, 0x8d, 0x7d, 0xed, 0xa0, 0x63, 0x49, 0xc9, 0xec, 0x00,
-	0x8c, 0xd2, 0x45, 0x28, 0x41, 0xff, 0x83, 0x06, 0x30, 0x09, 0xec, 0x99, 0x4c, 0xfa, 0x43, 0xd8,
-	0xbc, 0x70, 0x7d, 0x37, 0xbe, 0xe4, 0xb3, 0xe3, 0xdb, 0x84, 0xc7, 0x94, 0x7b, 0xd5, 0x2a, 0x2b,
-	0x31, 0x59, 0xca, 0x5a, 0xb8, 0xe8, 0xe4, 0xa2, 0x68, 0x58, 0x1f, 0x9a, 0x61, 0x14, 0xcc, 0x23,
-	0x1e, 0xc7, 0x72, 0x1f, 0x72, 0x19, 0xc7, 0x2e, 0x78, 0x62, 0x0b, 0xd2, 0x93, 0x1f, 0x91, 0xa2,
-	0x31, 0x7f, 0xaf, 0xc1, 0xe6, 0xf4, 0xd2, 0x8e, 0x66, 0xae, 0x3f, 0x7f, 0x1c, 0x05, 0x29, 0xd1,
-	0x52, 0x62, 0x47, 0x73, 0x9e, 0x71, 0xb0, 0x94, 0xf0, 0x0b, 0x1d, 0x8d, 0x26, 0x38, 0x3f, 0x7d,
-	0xa1, 0xf8, 0x1f, 0x67, 0xbe, 0x70, 0xa3, 0x38, 0xc1, 0x0f, 0x54, 0xce, 0x9c, 0xc9, 0x18, 0x27,
-	0xbe, 0xf5, 0x1d, 0x82, 0x10, 0x47, 0x48, 0x09, 0xc7, 0xa4, 0xbe, 0xb4, 0xd4, 0xc9, 0x92, 0xcb,
-	0xe6, 0xef, 0xaa, 0x00, 0xd3, 0x5b, 0xdf, 0x91, 0xf0, 0xec, 0x43, 0x9b, 0x96, 0x79, 0x72, 0xcd,
-	0xfd, 0x24, 0x03, 0x47, 0x55, 0x61, 0x30, 0x12, 0x5f, 0x84, 0x19, 0x30, 0xb9, 0x8c, 0xf4, 0x11,
-	0x71, 0x87, 0xfb, 0x09, 0x1a, 0xab, 0x64, 0x2c, 0x14, 0xcc, 0x84, 0xce, 0xc2, 0x8e, 0x13, 0x1e,
-	0x95, 0xa0, 0x29, 0xe9, 0xd8, 0x00, 0x0c, 0x55, 0x7e, 0x9c, 0xb8, 0x33, 0x49, 0x34, 0xf7, 0xf4,
-	0x18, 0x8f, 0x16, 0x91, 0xc5, 0x6b, 0x88, 0x78, 0xaa, 0x0e, 0xe3, 0xa9, 0x32, 0xc5, 0xdb, 0x10,
-	0xf1, 0xee, 0xea, 0x31, 0xde, 0xb9, 0x17, 0x38, 0x57, 0xae, 0x3f, 0x27, 0xd8, 0x9b, 0x04, 0x55,
-	0x49, 0xc7, 0x3e, 0x07, 0x23, 0xf5, 0x23, 0x1e, 0x07, 0xde, 0x35, 0x9f, 0xd1, 0xee, 0xc5, 0xbd,
-	0x96, 0xc2, 0x18, 0xea, 0xbe, 0x5a, 0xf7, 0x5c, 0x95, 0x1d, 0x02, 0xf1, 0xc9, 0xc8, 0x5d, 0xf8,
-	0xa7, 0x0e, 0x6d, 0x85, 0x36, 0xee, 0x41, 0xa5, 0x7d, 0x4d, 0xa8, 0xf4, 0x15, 0x50, 0xed, 0x67,
-	0x64, 0x95, 0x9e, 0x8f, 0xdc, 0xac, 0xcb, 0xa9, 0xaa, 0xdc, 0xa3, 0xb4, 0x37, 0xaa, 0x8a, 0x1d,
-	0xc0, 0x3b, 0x8a, 0xa8, 0xec, 0xcc, 0x5d, 0x35, 0x3b, 0x04, 0x46, 0xaa, 0xa1, 0x9d, 0x38, 0x97,
-	0x2f, 0xc3, 0xa7, 0x94, 0x0d, 0x6d, 0x4f, 0xd3, 0x5a, 0x62, 0x61, 0xdf, 0x84, 0x7a, 0x9c, 0xd8,
-	0x73, 0x4e, 0x3b, 0x93, 0xf5, 0x29, 0x54, 0x58, 0x42, 0xcf, 0x3e, 0xc9, 0x19, 0xb2, 0x49, 0x2c,
-	0x45, 0x58, 0x9f, 0x46, 0x01, 0x72, 0x87, 0x45, 0x86, 0x8c, 0x34, 0xcd, 0xff, 0xea, 0xb0, 0x59,
-	0xe2, 0xed, 0xa5, 0x6d, 0x31, 0x9f, 0x51, 0x5f, 0x31, 0xe3, 0x3e, 0xd4, 0x52, 0xdf, 0x4d, 0x08,
-	0xa9, 0xee, 0x51, 0x07, 0xed, 0x2f, 0x7d, 0x37, 0x79, 0x71, 0x1b, 0x72, 0x8b, 0x2c, 0x4a, 0x4e,
-	0xb5, 0xaf, 0xc8, 0x89, 0x7d, 0x0a, 0xdb, 0x45, 0x25, 0x8c, 0x46, 0x93, 0x49, 0xe0, 0x5c, 0x8d,
-	0x47, 0x12, 0xbd, 0x65, 0x26, 0xc6, 0x04, 0xc5, 0x53, 0x45, 0x3f, 0xa9, 0x08, 0x92, 0xff, 0x18,
-	0xea, 0x0e, 0xb2, 0x2f, 0xa1, 0x24, 0x5b, 0x8d, 0x42, 0xc7, 0x4f, 0x2a, 0x96, 0xb0, 0xb3, 0x0f,
-	0xa1, 0x36, 0x4b, 0x17, 0xa1, 0xc4, 0x8a, 0x18, 0xbd, 0xe0, 0xc3, 0x27, 0x15, 0x8b, 0xac, 0xe8,
-	0xe5, 0x05, 0xf6, 0xac, 0xd7, 0x2a, 0xbc, 0x0a, 0x9a, 0x44, 0x2f, 0xb4, 0xa2, 0x17, 0x96, 0x28,
-	0x95, 0xab, 0xf4, 0x2a, 0xd8, 0x02, 0xbd, 0xd0, 0x7a, 0xdc, 0x84, 0x46, 0x2c, 0xd8, 0xf6, 0xc7,
-	0xb0, 0x55, 0x42, 0x7f, 0xe2, 0xc6, 0x04, 0x95, 0x30, 0xf7, 0xb4, 0x55, 0xcd, 0x35, 0x1b, 0xbf,
-	0x07, 0x40, 0x6b, 0x12, 0x1d, 0x4a, 0x76, 0x3a, 0xad, 0x38, 0x08, 0x7c, 0x00, 0x2d, 0x5c, 0xcb,
-	0x1a, 0x33, 0x2e, 0x62, 0x95, 0x39, 0x84, 0x0e, 0x65, 0x7f, 0x36, 0x59, 0xe1, 0xc1, 0x8e, 0x60,
-	0x47, 0xf4, 0x9d, 0xfc, 0xcc, 0xea, 0x26, 0x6e, 0xe0, 0xcb, 0x0f, 0x6b, 0xa9, 0x0d, 0x19, 0x91,
-	0x63, 0xb8, 0xe9, 0xd9, 0x24, 0xa3, 0xe4, 0x4c, 0x36, 0xbf, 0x0b, 0x2d, 0x9c, 0x51, 0x4c, 0x77,
-	0x00, 0x0d, 0x32, 0x64, 0x38, 0x18, 0x39, 0x9c, 0x32, 0x21, 0x4b, 0xda, 0x11, 0x86, 0xa2, 0xf1,
-	0x2e, 0x59, 0xc8, 0x5f, 0x74, 0xe8, 0xa8, 0x9d, 0xfd, 0xff, 0x55, 0xe4, 0x4c, 0x39, 0x00, 0x67,
-	0x75, 0xf8, 0x51, 0x56, 0x87, 0xca, 0x89, 0xa1, 0xd8, 0xb3, 0xa2, 0x0c, 0x1f, 0xca, 0x32, 0x6c,
-	0x90, 0xdb, 0x66, 0x56, 0x86, 0x99, 0x97, 0xa8, 0xc2, 0x87, 0xb2, 0x0a, 0x37, 0x0a, 0xa7, 0x7c,
-	0x03, 0xf3, 0x22, 0x7c, 0x28, 0x8b, 0xb0, 0x59, 0x38, 0xe5, 0xa0, 0xe6, 0x35, 0xb8, 0x01, 0x75,
-	0x02, 0xcf, 0xfc, 0x01, 0x18, 0x2a, 0x34, 0x54, 0x81, 0x1f, 0x49, 0x63, 0x09, 0x78, 0xf5, 0x64,
-	0x24, 0xc7, 0xbe, 0x82, 0xcd, 0xd2, 0x27, 0x8c, 0xcd, 0xdc, 0x8d, 0x87, 0xb6, 0xef, 0x70, 0x2f,
-	0x3f, 0xe7, 0x28, 0x1a, 0x65, 0x4b, 0xf5, 0x22, 0xb2, 0x0c, 0x51, 0xda, 0x52, 0xe5, 0xb4, 0x52,
-	0x2d, 0x9d, 0x56, 0x86, 0xd0, 0x51, 0xfd, 0xd9, 0xb7, 0xa0, 0x86, 0x1b, 0x20, 0x6f, 0x30, 0xb4,
-	0x58, 0x32, 0x88, 0x5d, 0xc1, 0xdf, 0xac, 0x1e, 0xf4, 0xa2, 0x1e, 0x7e, 0x03, 0x1b, 0xa3, 0xd1,
-	0x64, 0xec, 0x5f, 0x04, 0xcb, 0x6e, 0x22, 0xd4, 0x76, 0x9c, 0x4b, 0xbe, 0xb0, 0xb3, 0x93, 0xa4,
-	0x90, 0xe8, 0xa4, 0x66, 0x9f, 0x7b, 0x5c, 0x96, 0xad, 0x10, 0xf2, 0x63, 0x47, 0xad, 0x38, 0x76,
-	0x98, 0x9f, 0x41, 0x3b, 0x63, 0xa7, 0x55, 0x93, 0x74, 0x41, 0x1f, 0x8f, 0xe4, 0x04, 0xfa, 0x78,
-	0x64, 0x9e, 0x42, 0xf7, 0xe4, 0x0b, 0xee, 0x8c, 0x46, 0x93, 0x35, 0x97, 0x24, 0x4c, 0xcd, 0x13,
-	0x74, 0x28, 0x53, 0xf3, 0x32, 0x06, 0xac, 0xf1, 0x2f, 0xb8, 0x43, 0x99, 0x35, 0x2d, 0xfa, 0x6f,
-	0xfe, 0x56, 0x83, 0xed, 0xe3, 0x88, 0xdb, 0x57, 0x32, 0x95, 0x75, 0x71, 0x4d, 0xe8, 0x44, 0x7c,
-	0x11, 0x5c, 0xf3, 0x89, 0x1a, 0xbd, 0xa4, 0xc3, 0xa3, 0x25, 0x17, 0x19, 0xca, 0x69, 0x32, 0x11,
-	0x2d, 0xf1, 0x95, 0x1b, 0xa2, 0xa5, 0x26, 0x2c, 0x52, 0x34, 0xfb, 0xd0, 0x9b, 0xde, 0xb8, 0x89,
-	0x73, 0x49, 0xdf, 0xa7, 0x68, 0x60, 0x32, 0x0f, 0xf3, 0x08, 0xb6, 0xe5, 0xa5, 0xb4, 0x74, 0x65,
-	0xfe, 0x86, 0x72, 0x23, 0x6d, 0xe7, 0xe7, 0x6b, 0x71, 0x0b, 0x33, 0x53, 0xd8, 0x29, 0x8f, 0x91,
-	0x97, 0x82, 0x75, 0x83, 0xde, 0xc2, 0x3d, 0xf6, 0x06, 0xb6, 0x4e, 0xd3, 0x68, 0x5e, 0x4e, 0xb4,
-	0x0f, 0x4d, 0xd7, 0xb7, 0x9d, 0xc4, 0xbd, 0xe6, 0xb2, 0xd4, 0x73, 0x99, 0x30, 0x76, 0xe5, 0x25,
-	0xbc, 0x6a, 0xd1, 0x7f, 0x71, 0x16, 0xf5, 0x38, 0x11, 0x4f, 0x7e, 0x16, 0x15, 0x32, 0x95, 0x9c,
-	0x38, 0x6c, 0xd4, 0x64, 0xc9, 0x91, 0x84, 0xf8, 0xd1, 0x15, 0x48, 0x5c, 0x11, 0x87, 0x81, 0x7f,
-	0xe1, 0xce, 0x33, 0xfc, 0xfe, 0xa4, 0xc1, 0x83, 0x25, 0xc6, 0xb7, 0x76, 0x4d, 0xea, 0x43, 0x33,
-	0x0e, 0xd2, 0xc8, 0xe1, 0xe3, 0x91, 0xcc, 0x2a, 0x97, 0xd5, 0x87, 0x90, 0x7a, 0xe9, 0x21, 0x64,
-	0xf0, 0x7d, 0x68, 0x88, 0x27, 0x04, 0xb6, 0x09, 0xad, 0xb1, 0x7f, 0x6d, 0x7b, 0xee, 0xec, 0x79,
-	0x68, 0x54, 0x58, 0x13, 0x6a, 0xd3, 0x24, 0x08, 0x0d, 0x8d, 0xb5, 0xa0, 0x7e, 0x6a, 0xa7, 0x31,
-	0x37, 0x74, 0x06, 0xd0, 0x40, 0xea, 0x58, 0x70, 0xa3, 0x3a, 0x18, 0x40, 0x9d, 0xae, 0xdb, 0xe4,
-	0xf9, 0x8b, 0xf1, 0xa9, 0x51, 0x61, 0x6d, 0xd8, 0xb0, 0x4e, 0x4e, 0x27, 0x3f, 0x1b, 0x9e, 0x18,
-	0x1a, 0xfa, 0x8e, 0x9f, 0xfd, 0xfc, 0x64, 0xf8, 0xc2, 0xd0, 0x07, 0xbf, 0x84, 0x3a, 0x71, 0x33,
-	0x33, 0xa0, 0x23, 0x27, 0x21, 0xd9, 0xa8, 0xb0, 0x0d, 0xa8, 0x3e, 0xe3, 0x37, 0x86, 0x46, 0x83,
-	0x53, 0x1f, 0xef, 0x3e, 0x62, 0x22, 0x9a, 0x73, 0x66, 0x54, 0xd1, 0x80, 0x99, 0x84, 0x7c, 0x66,
-	0xd4, 0x58, 0x07, 0x9a, 0x8f, 0xe4, 0x65, 0xc6, 0xa8, 0x0f, 0x9e, 0x43, 0x33, 0xe3, 0x74, 0xf6,
-	0x0e, 0xb4, 0x65, 0x68, 0x54, 0x19, 0x15, 0xcc, 0x9b, 0x98, 0xdb, 0xd0, 0x30, 0x45, 0x64, 0x67,
-	0x43, 0xc7, 0x7f, 0x48, 0xc1, 0x46, 0x95, 0xd2, 0xbe, 0xf5, 0x1d, 0xa3, 0x86, 0x8e, 0x54, 0x29,
-	0xc6, 0x6c, 0xf0, 0x43, 0x68, 0xe5, 0x7c, 0x84, 0xc9, 0xbe, 0xf4, 0xaf, 0xfc, 0xe0, 0xc6, 0x27,
-	0x9d, 0x58, 0x20, 0x7e, 0xf5, 0xd3, 0xb3, 0x89, 0xa1, 0xe1, 0x84, 0x14, 0xff, 0x11, 0xb5, 0x4d,
-	0x43, 0x1f, 0x3c, 0x85, 0x0d, 0x59, 0xc7, 0x8c, 0x41, 0x57, 0x26, 0x23, 0x35, 0x46, 0x05, 0x01,
-	0xc6, 0x75, 0x88, 0xa9, 0x34, 0xd6, 0x05, 0xa0, 0x25, 0x0a, 0x59, 0xc7, 0x70, 0x02, 0x5b, 0xa1,
-	0xa8, 0x1e, 0xfd, 0xb9, 0x09, 0x0d, 0x51, 0x2b, 0x6c, 0x08, 0x1d, 0xf5, 0x25, 0x8c, 0xbd, 0x27,
-	0xbb, 0xdd, 0xdd, 0xb7, 0xb1, 0x7e, 0x8f, 0xfa, 0xd5, 0x92, 0x67, 0x0a, 0xb3, 0xc2, 0xc6, 0xd0,
-	0x2d, 0xbf, 0x2a, 0xb1, 0x07, 0xe8, 0xbd, 0xf4, 0xc9, 0xaa, 0xdf, 0x5f, 0x66, 0xca, 0x43, 0x9d,
-	0xc0, 0x66, 0xe9, 0xa1, 0x88, 0xd1, 0xbc, 0xcb, 0xde, 0x8e, 0xd6, 0x66, 0xf4, 0x53, 0x68, 0x2b,
-	0xef, 0x1e, 0x6c, 0x17, 0x5d, 0xef, 0x3f, 0x2a, 0xf5, 0xdf, 0xbb, 0xa7, 0xcf, 0x23, 0x7c, 0x0e,
-	0x50, 0xbc, 0x39, 0xb0, 0x77, 0x73, 0x47, 0xf5, 0xad, 0xa9, 0xbf, 0x7b, 0x57, 0x9d, 0x0f, 0x7f,
-	0x04, 0x20, 0x1f, 0x9c, 0xce, 0x26, 0x31, 0x7b, 0x1f, 0xfd, 0x56, 0x3d, 0x40, 0xad, 0x5d, 0xc8,
-	0x11, 0x74, 0x1e, 0xf1, 0xc4, 0xb9, 0xcc, 0xda, 0x14, 0x1d, 0x5f, 0x95, 0x96, 0xd2, 0x6f, 0x4b,
-	0x05, 0x0a, 0x66, 0xe5, 0x40, 0xfb, 0x54, 0x63, 0x3f, 0x02, 0xc0, 0x5a, 0x4a, 0x13, 0x8e, 0x9c,
-	0xcc, 0xa8, 0x15, 0x96, 0x3a, 0xca, 0xda, 0x19, 0x87, 0xd0, 0x51, 0x9b, 0x85, 0xa8, 0x88, 0x25,
-	0xed, 0x63, 0x6d, 0x90, 0xa7, 0xb0, 0x75, 0x8f, 0xee, 0x05, 0x0a, 0xab, 0xba, 0xc0, 0x57, 0xe5,
-	0xa4, 0xb2, 0xbd, 0xc8, 0x69, 0x49, 0xcf, 0x10, 0x41, 0x96, 0x35, 0x06, 0xb3, 0xc2, 0x7e, 0x02,
-	0x50, 0x70, 0xb7, 0xd8, 0xd1, 0x7b, 0x5c, 0xbe, 0x36, 0x8b, 0xc7, 0xb0, 0xa5, 0x3c, 0x05, 0x0b,
-	0x9a, 0x15, 0xa5, 0x75, 0xff, 0x85, 0x78, 0x6d, 0x20, 0x4b, 0xbe, 0x5b, 0xaa, 0x7c, 0x2d, 0xd0,
-	0x59, 0xc5, 0xf1, 0xfd, 0x0f, 0x56, 0x58, 0x55, 0x88, 0xd4, 0x77, 0x67, 0x01, 0xd1, 0x92, 0x97,
-	0xe8, 0x75, 0x89, 0x1d, 0xf7, 0xfe, 0xf5, 0x7a, 0x4f, 0xfb, 0xf2, 0xf5, 0x9e, 0xf6, 0x9f, 0xd7,
-	0x7b, 0xda, 0x1f, 0xdf, 0xec, 0x55, 0xbe, 0x7c, 0xb3, 0x57, 0xf9, 0xf7, 0x9b, 0xbd, 0xca, 0x79,
-	0x83, 0x1e, 0xcf, 0xbf, 0xfd, 0xbf, 0x00, 0x00, 0x00, 0xff, 0xff, 0x94, 0x79, 0x3d, 0x5b, 0x4e,
-	0x17, 0x00, 0x00,
+	// 2295 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xb4, 0x59, 0xcd, 0x6f, 0xe4, 0x58,
+	0x11, 0x6f, 0xbb, 0x3f, 0xd2, 0xa9, 0xee, 0xf4, 0x38, 0x2f, 0xb3, 0xb3, 0x9e, 0x66, 0x37, 0x04,
+	0xef, 0x6a, 0x36, 0x1b, 0xa4, 0x68, 0x37, 0xb0, 0x02, 0x01, 0xcb, 0xc7, 0xa4, 0x33, 0x99, 0x40,
+	0xcf, 0x4c, 0xe2, 0xce, 0x2c, 0xdc, 0x90, 0x63, 0xbf, 0x74, 0xac, 0x74, 0xdb, 0x1e, 0x7f, 0x24,
+	0x9b, 0x23, 0xe2, 0xc8, 0x05, 0x09, 0x09, 0x09, 0x71, 0xe6, 0xaf, 0x80, 0x1b, 0x07, 0x38, 0x72,
+	0xe3, 0x8a, 0x86, 0x7f, 0x83, 0x03, 0xaa, 0x7a, 0xcf, 0xf6, 0x73, 0x7f, 0xcd, 0x1e, 0x66, 0x2f,
+	0x2d, 0xd7, 0xc7, 0xab, 0x57, 0xef, 0x57, 0xe5, 0x7a, 0xe5, 0x6a, 0xe8, 0x79, 0xd3, 0xdb, 0x30,
+	0xbe, 0xe6, 0xf1, 0x7e, 0x14, 0x87, 0x69, 0xc8, 0xf4, 0xe8, 0xc2, 0xfa, 0x18, 0xb6, 0x46, 0xa9,
+	0x13, 0xa7, 0xa3, 0xec, 0xe2, 0xdc, 0x49, 0xae, 0x6d, 0xfe, 0x2a, 0xe3, 0x49, 0xca, 0x18, 0x34,
+	0x52, 0x27, 0xb9, 0x36, 0xb5, 0x1d, 0x6d, 0x77, 0xdd, 0xa6, 0x67, 0x6b, 0x1f, 0xd8, 0xcb, 0xc8,
+	0x73, 0x52, 0x6e, 0xf3, 0x89, 0x73, 0x97, 0x6b, 0x9a, 0xb0, 0xe6, 0x86, 0x41, 0xca, 0x83, 0x54,
+	0x2a, 0xe7, 0xa4, 0x35, 0x82, 0xad, 0x67, 0xfe, 0x38, 0x9e, 0x5d, 0xb0, 0x0d, 0xf0, 0xd8, 0x0f,
+	0x26, 0xe1, 0xf8, 0xb9, 0x33, 0xe5, 0x72, 0x8d, 0xc2, 0x61, 0xef, 0xc1, 0xba, 0xa0, 0x4e, 0xc3,
+	0xc4, 0xd4, 0x77, 0xb4, 0xdd, 0x0d, 0xbb, 0x64, 0x58, 0xc7, 0xf0, 0xce, 0x8b, 0x88, 0xa3, 0xd1,
+	0x19, 0x8f, 0xfb, 0xa0, 0x87, 0x11, 0x99, 0xeb, 0x1d, 0xc0, 0x7e, 0x74, 0xb1, 0x8f, 0xc2, 0x17,
+	0x91, 0xad, 0x87, 0x11, 0x9e, 0x26, 0xc0, 0xcd, 0x74, 0x71, 0x1a, 0x7c, 0xb6, 0x6e, 0xe0, 0xc1,
+	0xac, 0xa1, 0x24, 0x0a, 0x83, 0x84, 0xaf, 0xb4, 0xf4, 0x00, 0x5a, 0x31, 0x4f, 0xb2, 0x49, 0x4a,
+	0xb6, 0xda, 0xb6, 0xa4, 0x90, 0x2f, 0xa0, 0x35, 0xeb, 0xb4, 0x87, 0xa4, 0x98, 0x01, 0xf5, 0x69,
+	0x32, 0x36, 0x1b, 0xc4, 0xc4, 0x47, 0x6b, 0x0f, 0xee, 0x0b, 0x14, 0xbf, 0x02, 0xe2, 0xbb, 0xc0,
+	0xce, 0x32, 0x1e, 0xdf, 0x8d, 0x52, 0x27, 0xcd, 0x12, 0x45, 0x33, 0x28, 0xa1, 0x13, 0xa7, 0xf9,
+	0x08, 0x36, 0x49, 0xf3, 0x28, 0x8e, 0xc3, 0x78, 0x95, 0xe2, 0x9f, 0x35, 0x30, 0x9f, 0x3a, 0x81,
+	0x37, 0xc9, 0xf7, 0x1f, 0x9d, 0x0d, 0x57, 0x59, 0x66, 0x0f, 0x09, 0x0d, 0x9d, 0xd0, 0x58, 0x47,
+	0x34, 0x46, 0x67, 0xc3, 0x12, 0x56, 0x27, 0x1e, 0x27, 0x66, 0x7d, 0xa7, 0x8e, 0xea, 0xf8, 0x8c,
+	0xd1, 0xbb, 0x28, 0xa2, 0x27, 0x8e, 0x5d, 0x32, 0x30, 0xf6, 0xc9, 0xab, 0xc9, 0xa9, 0x93, 0xa6,
+	0x3c, 0x0e, 0xcc, 0xa6, 0x88, 0x7d, 0xc9, 0xb1, 0x7e, 0x05, 0xf7, 0x0f, 0xc3, 0xe9, 0x34, 0x0c,
+	0x7e, 0x49, 0xf0, 0x15, 0x21, 0x29, 0x61, 0xd7, 0x96, 0xc0, 0xae, 0x2f, 0x82, 0xbd, 0x5e, 0xc2,
+	0xfe, 0x77, 0x0d, 0xb6, 0x2a, 0x58, 0xbe, 0x2d, 0xcb, 0xec, 0x7b, 0xb0, 0x91, 0x48, 0x28, 0xc9,
+	0xb4, 0xd9, 0xd8, 0xa9, 0xef, 0x76, 0x0e, 0x36, 0x09, 0x2b, 0x55, 0x60, 0x57, 0xf5, 0xd8, 0xa7,
+	0xd0, 0x89, 0xf1, 0xc5, 0x90, 0xcb, 0x10, 0x8d, 0xce, 0xc1, 0x3d, 0x5c, 0x66, 0x97, 0x6c, 0x5b,
+	0xd5, 0xb1, 0xfe, 0xa6, 0x01, 0x53, 0xe3, 0xfc, 0xd6, 0x0e, 0xf1, 0x5d, 0xe8, 0x4a, 0xe7, 0xc8,
+	0xb2, 0x3c, 0x83, 0xa1, 0x9c, 0x41, 0xec, 0x58, 0xd1, 0x62, 0xfb, 0x00, 0xe4, 0xaa, 0x58, 0x23,
+	0x0e, 0xd0, 0x2b, 0x0e, 0x20, 0x56, 0x28, 0x1a, 0xd6, 0x5f, 0x34, 0xe8, 0x1c, 0x5e, 0x71, 0x37,
+	0x47, 0xe0, 0x01, 0xb4, 0x22, 0x27, 0x49, 0xb8, 0x97, 0xfb, 0x2d, 0x28, 0x76, 0x1f, 0x9a, 0x69,
+	0x98, 0x3a, 0x13, 0x72, 0xbb, 0x69, 0x0b, 0x82, 0x92, 0x27, 0x73, 0x5d, 0x9e, 0x24, 0x97, 0xd9,
+	0x84, 0x9c, 0x6f, 0xda, 0x0a, 0x07, 0xad, 0x5d, 0x3a, 0xfe, 0x84, 0x7b, 0x94, 0x77, 0x4d, 0x5b,
+	0x52, 0x58, 0xa1, 0x6e, 0x9d, 0x38, 0xf0, 0x83, 0x31, 0xb9, 0xd8, 0xb4, 0x73, 0x12, 0x57, 0x78,
+	0x3c, 0x75, 0xfc, 0x89, 0xd9, 0xda, 0xd1, 0x76, 0xbb, 0xb6, 0xa4, 0xac, 0x2e, 0xc0, 0x20, 0x9b,
+	0x46, 0x12, 0xf4, 0xd7, 0x75, 0x80, 0x61, 0xe8, 0x78, 0xd2, 0xe9, 0x0f, 0x61, 0xe3, 0xd2, 0x0f,
+	0xfc, 0xe4, 0x8a, 0x7b, 0x8f, 0xef, 0x52, 0x9e, 0x90, 0xef, 0x75, 0xbb, 0xca, 0x44, 0x67, 0xc9,
+	0x6b, 0xa1, 0xa2, 0x93, 0x8a, 0xc2, 0x61, 0x7d, 0x68, 0x47, 0x71, 0x38, 0x8e, 0x79, 0x92, 0xc8,
+	0x38, 0x14, 0x34, 0xae, 0x9d, 0xf2, 0xd4, 0x11, 0x45, 0x4f, 0xbe, 0x44, 0x0a, 0x87, 0x7d, 0x1b,
+	0x5a, 0xa9, 0x73, 0x31, 0xe1, 0x98, 0x33, 0x18, 0xa6, 0x2d, 0x51, 0xa4, 0x2e, 0x26, 0xbc, 0x74,
+	0xd3, 0x96, 0x2a, 0x68, 0x8c, 0xa7, 0xce, 0x88, 0xbb, 0x61, 0xe0, 0x25, 0x74, 0xce, 0xba, 0xad,
+	0x70, 0xd8, 0x23, 0xe8, 0x95, 0xa6, 0xa9, 0x24, 0xaf, 0xd1, 0x86, 0x33, 0x5c, 0x3c, 0x76, 0xc9,
+	0xc1, 0x97, 0xbb, 0x4d, 0xa5, 0xb9, 0xca, 0x64, 0x9f, 0x41, 0xe7, 0xc6, 0x99, 0xf8, 0x9e, 0x93,
+	0xfa, 0x61, 0x90, 0x98, 0xeb, 0x33, 0xfe, 0x7d, 0x51, 0xc8, 0x6c, 0x55, 0x8f, 0x59, 0xd0, 0xcd,
+	0xe1, 0xb3, 0xc3, 0xdb, 0xc4, 0x04, 0x72, 0xb3, 0xc2, 0xc3, 0xca, 0x42, 0xf8, 0x91, 0x42, 0x87,
+	0x14, 0x4a, 0x86, 0x48, 0xa5, 0x0c, 0x53, 0xa9, 0x9b, 0xa7, 0x12, 0x52, 0xd5, 0xe3, 0x1d, 0x9f,
+	0x9f, 0x0c, 0xcc, 0x8d, 0xd9, 0xe3, 0x21, 0xd7, 0xfa, 0xb7, 0x06, 0xf7, 0x66, 0x20, 0xa4, 0x34,
+	0x44, 0x96, 0xac, 0x87, 0x82, 0x98, 0x8f, 0xbf, 0xfe, 0xe6, 0xf8, 0xd7, 0xe7, 0xe2, 0xff, 0x08,
+	0x7a, 0x31, 0x9f, 0x3a, 0x3e, 0xe6, 0xe1, 0x13, 0x1f, 0x63, 0x29, 0x92, 0x76, 0x86, 0x3b, 0x87,
+	0x4c, 0xf3, 0x4d, 0xc8, 0xb4, 0x66, 0x90, 0xb1, 0xfe, 0x9a, 0x9f, 0xac, 0x04, 0x7f, 0xc9, 0xc9,
+	0x76, 0xa0, 0x93, 0x3a, 0xf1, 0x98, 0xa7, 0xa4, 0x2e, 0x6b, 0x86, 0xca, 0xc2, 0x8a, 0x3f, 0x0d,
+	0x3d, 0x2e, 0x33, 0x96, 0x9e, 0x71, 0x55, 0x12, 0x66, 0xb1, 0x8b, 0xf6, 0x33, 0x4e, 0xc7, 0x68,
+	0xd8, 0x2a, 0xab, 0xb4, 0x2b, 0x34, 0x9a, 0x42, 0x43, 0x61, 0xe1, 0x2b, 0x3a, 0x75, 0x52, 0xf7,
+	0x8a, 0x7b, 0xe4, 0x7f, 0xdb, 0xce, 0x49, 0xeb, 0x77, 0x1a, 0x6c, 0x8c, 0xae, 0x9c, 0xd8, 0xf3,
+	0x83, 0xf1, 0x71, 0x1c, 0x66, 0x74, 0x05, 0x8b, 0xa5, 0xd2, 0x79, 0x49, 0xa1, 0x6f, 0x83, 0xc1,
+	0x10, 0xc3, 0x41, 0xb7, 0x11, 0x3e, 0xe3, 0x5b, 0x76, 0xe9, 0xc7, 0x49, 0x8a, 0xf9, 0x2a, 0xdf,
+	0xb2, 0x9c, 0x46, 0x3b, 0xc9, 0x5d, 0xe0, 0x52, 0xb9, 0xc0, 0x15, 0x92, 0xc2, 0x35, 0x59, 0x20,
+	0x25, 0x4d, 0x92, 0x14, 0xb4, 0xf5, 0xdb, 0x3a, 0xc0, 0xe8, 0x2e, 0x70, 0x65, 0x82, 0xe0, 0xc1,
+	0x10, 0xe7, 0xa3, 0x1b, 0x1e, 0xa4, 0x79, 0x21, 0x50, 0x59, 0x68, 0x8c, 0xc8, 0xf3, 0x28, 0xcf,
+	0x93, 0x82, 0xc6, 0xb0, 0xc5, 0xdc, 0xe5, 0x41, 0x7a, 0x1e, 0x09, 0xef, 0xea, 0x76, 0xc9, 0xc0,
+	0xc0, 0x4f, 0x9d, 0x24, 0xe5, 0x71, 0xa5, 0x0c, 0x54, 0x78, 0x6c, 0x0f, 0x0c, 0x95, 0x3e, 0x4e,
+	0x7d, 0x4f, 0x5e, 0xaa, 0x73, 0x7c, 0xb4, 0x47, 0x87, 0xc8, 0xed, 0xb5, 0x84, 0x3d, 0x95, 0x87,
+	0xf6, 0x54, 0x9a, 0xec, 0x89, 0x6a, 0x30, 0xc7, 0x47, 0x7b, 0x17, 0x93, 0xd0, 0xbd, 0xf6, 0x83,
+	0x31, 0xc1, 0xde, 0x26, 0xa8, 0x2a, 0x3c, 0xf6, 0x39, 0x18, 0x59, 0x10, 0xf3, 0x24, 0x9c, 0xdc,
+	0x70, 0x8f, 0xa2, 0x97, 0x97, 0x04, 0x71, 0x3b, 0xaa, 0x71, 0xb5, 0xe7, 0x54, 0x95, 0x08, 0x81,
+	0x78, 0xa7, 0x65, 0x14, 0xfe, 0xa1, 0x43, 0x47, 0xb9, 0x22, 0xe7, 0xa0, 0xd2, 0xbe, 0x22, 0x54,
+	0xfa, 0x12, 0xa8, 0x76, 0xf2, 0x8b, 0x39, 0xbb, 0x18, 0xf8, 0x79, 0x47, 0xa7, 0xb2, 0x0a, 0x8d,
+	0x4a, 0x6c, 0x54, 0x16, 0xdb, 0x85, 0x7b, 0x0a, 0xa9, 0x44, 0x66, 0x96, 0xcd, 0xf6, 0x81, 0x11,
+	0xeb, 0x10, 0x33, 0xfe, 0x65, 0xf4, 0x8c, 0xbc, 0x91, 0xaf, 0xc1, 0x02, 0x09, 0xfb, 0x26, 0x34,
+	0x93, 0xd4, 0x19, 0x8b, 0x3a, 0x9d, 0xf7, 0x64, 0xc8, 0xb0, 0x05, 0x9f, 0x7d, 0x5c, 0x74, 0x03,
+	0xed, 0x1d, 0x2d, 0xc7, 0xfa, 0x34, 0x0e, 0xf1, 0x9e, 0xb4, 0x49, 0x90, 0x37, 0x08, 0xd6, 0xff,
+	0x74, 0xd8, 0xa8, 0xf4, 0x28, 0x0b, 0x5b, 0xc0, 0x62, 0x47, 0x7d, 0xc9, 0x8e, 0x3b, 0xd0, 0xc8,
+	0x02, 0x3f, 0x25, 0xa4, 0x7a, 0x07, 0x5d, 0x94, 0xbf, 0x0c, 0xfc, 0xf4, 0xfc, 0x2e, 0xe2, 0x36,
+	0x49, 0x14, 0x9f, 0x1a, 0x6f, 0xf0, 0x89, 0x7d, 0x02, 0x5b, 0x65, 0x26, 0x0c, 0x06, 0xc3, 0x61,
+	0xe8, 0x5e, 0x9f, 0x0c, 0x24, 0x7a, 0x8b, 0x44, 0x8c, 0x89, 0x76, 0x86, 0x32, 0xfa, 0x69, 0x4d,
+	0x34, 0x34, 0x1f, 0x41, 0xd3, 0xc5, 0x4e, 0xc3, 0x5c, 0x2b, 0xdb, 0x2a, 0xa5, 0xf5, 0x78, 0x5a,
+	0xb3, 0x85, 0x9c, 0x7d, 0x08, 0x0d, 0x2f, 0x9b, 0x46, 0x66, 0xbb, 0xec, 0x5e, 0xca, 0xbb, 0xff,
+	0x69, 0xcd, 0x26, 0x29, 0x6a, 0x4d, 0x42, 0xc7, 0x33, 0xd7, 0x4b, 0xad, 0xf2, 0xa2, 0x40, 0x2d,
+	0x94, 0xa2, 0x16, 0xa6, 0xa8, 0x09, 0xa5, 0x56, 0x59, 0x2d, 0x50, 0x0b, 0xa5, 0x8f, 0xdb, 0xd0,
+	0x4a, 0x88, 0x63, 0xfd, 0x18, 0x36, 0x2b, 0xe8, 0x0f, 0xfd, 0x84, 0xa0, 0x12, 0x62, 0x53, 0x5b,
+	0xd6, 0x48, 0xe6, 0xeb, 0xb7, 0x01, 0xe8, 0x4c, 0xa2, 0x1b, 0x93, 0x5d, 0x9d, 0x56, 0x36, 0xbd,
+	0xef, 0xc3, 0x3a, 0x9e, 0x65, 0x85, 0x18, 0x0f, 0xb1, 0x4c, 0x1c, 0x41, 0x97, 0xbc, 0x3f, 0x1b,
+	0x2e, 0xd1, 0x60, 0x07, 0x70, 0x5f, 0xf4, 0x58, 0x45, 0x03, 0xe0, 0xe3, 0xf5, 0x22, 0x5f, 0xac,
+	0x85, 0x32, 0xac, 0x88, 0x1c, 0xcd, 0x8d, 0xce, 0x86, 0x79, 0x49, 0xce, 0x69, 0xeb, 0x33, 0x58,
+	0xc7, 0x1d, 0xc5, 0x76, 0xbb, 0xd0, 0x22, 0x41, 0x8e, 0x83, 0x51, 0xc0, 0x29, 0x1d, 0xb2, 0xa5,
+	0x1c, 0x61, 0x28, 0x9b, 0xcc, 0x05, 0x07, 0xf9, 0x93, 0x0e, 0x5d, 0xb5, 0x8b, 0xfd, 0xba, 0x92,
+	0x9c, 0x29, 0x1f, 0x7b, 0x79, 0x1e, 0x3e, 0xca, 0xf3, 0x50, 0xe9, 0x8e, 0xcb, 0x98, 0x95, 0x69,
+	0xf8, 0x81, 0x4c, 0xc3, 0x16, 0xa9, 0x6d, 0xe4, 0x69, 0x98, 0x6b, 0x91, 0x10, 0x95, 0x28, 0x0b,
+	0xd7, 0x4a, 0xa5, 0x22, 0x80, 0x45, 0x12, 0x7e, 0x20, 0x93, 0xb0, 0x5d, 0x2a, 0x15, 0xa0, 0x16,
+	0x39, 0xb8, 0x06, 0x4d, 0x02, 0xcf, 0xfa, 0x01, 0x18, 0x2a, 0x34, 0x94, 0x81, 0x8f, 0xa4, 0xb0,
+	0x02, 0xbc, 0xa2, 0x64, 0xcb, 0xb5, 0xaf, 0x60, 0xa3, 0xf2, 0x0a, 0x63, 0xd3, 0xe3, 0x27, 0x87,
+	0x4e, 0xe0, 0xf2, 0x49, 0xd1, 0xd3, 0x2b, 0x1c, 0x25, 0xa4, 0x7a, 0x69, 0x59, 0x9a, 0xa8, 0x84,
+	0x54, 0xe9, 0xcc, 0xeb, 0x95, 0xce, 0xfc, 0x10, 0xba, 0xaa, 0x3e, 0xfb, 0x16, 0x34, 0x30, 0x00,
+	0xf2, 0x6b, 0x9d, 0x0e, 0x4b, 0x02, 0x11, 0x15, 0xfc, 0xcd, 0xf3, 0x41, 0x2f, 0xf3, 0xe1, 0xd7,
+	0xb0, 0x36, 0x18, 0x0c, 0x4f, 0x82, 0xcb, 0x70, 0xd1, 0x57, 0x37, 0xee, 0x9d, 0xb8, 0x57, 0x7c,
+	0xea, 0xe4, 0x5f, 0x4d, 0x82, 0x2a, 0x9b, 0xa6, 0xba, 0xda, 0x34, 0xe5, 0x6d, 0x47, 0xa3, 0x6c,
+	0x3b, 0xac, 0x4f, 0xa1, 0x93, 0x57, 0xa7, 0x65, 0x9b, 0xf4, 0x40, 0x3f, 0x19, 0xc8, 0x0d, 0xf4,
+	0x93, 0x81, 0x75, 0x0a, 0xbd, 0xa3, 0x2f, 0xb9, 0x3b, 0x18, 0x0c, 0x57, 0x0c, 0x04, 0xd0, 0xb5,
+	0x89, 0x28, 0x87, 0xd2, 0xb5, 0x49, 0x5e, 0x01, 0x1b, 0xfc, 0x4b, 0xee, 0x92, 0x67, 0x6d, 0x9b,
+	0x9e, 0xad, 0xdf, 0x68, 0xb0, 0xf5, 0x38, 0xe6, 0xce, 0xb5, 0x74, 0x65, 0x95, 0x5d, 0x0b, 0xba,
+	0x31, 0x9f, 0x86, 0x37, 0x7c, 0xa8, 0x5a, 0xaf, 0xf0, 0xb0, 0x47, 0xe3, 0xc2, 0x43, 0xb9, 0x4d,
+	0x4e, 0xa2, 0x24, 0xb9, 0xf6, 0x23, 0x94, 0x34, 0x84, 0x44, 0x92, 0x56, 0x1f, 0xcc, 0xd1, 0xad,
+	0x9f, 0xba, 0x57, 0xf4, 0x7e, 0x8a, 0x0b, 0x4c, 0xfa, 0x61, 0x1d, 0xc0, 0x96, 0x1c, 0xc0, 0x54,
+	0xc6, 0x43, 0xdf, 0x50, 0xa6, 0x2f, 0x9d, 0xe2, 0x5b, 0x52, 0x4c, 0x1c, 0xac, 0x0c, 0xee, 0x57,
+	0xd7, 0xc8, 0x0f, 0xe0, 0x55, 0x8b, 0xde, 0xc2, 0xcc, 0xe6, 0x16, 0x36, 0x4f, 0xb3, 0x78, 0x5c,
+	0x75, 0xb4, 0x0f, 0x6d, 0x3f, 0x70, 0xdc, 0xd4, 0xbf, 0xe1, 0x32, 0xd5, 0x0b, 0x9a, 0x30, 0xf6,
+	0xe5, 0xc0, 0xa9, 0x6e, 0xd3, 0xb3, 0xe8, 0x45, 0x27, 0x9c, 0x0a, 0x4f, 0xd1, 0x8b, 0x0a, 0x9a,
+	0x52, 0x4e, 0x34, 0x1b, 0x0d, 0x99, 0x72, 0x44, 0x21, 0x7e, 0xf4, 0xb9, 0x2f, 0xc6, 0x21, 0x87,
+	0x61, 0x70, 0xe9, 0x8f, 0x73, 0xfc, 0xfe, 0xa0, 0xc1, 0xc3, 0x05, 0xc2, 0xb7, 0x36, 0x12, 0xe8,
+	0x43, 0x5b, 0x34, 0xf1, 0x27, 0x03, 0xe9, 0x55, 0x41, 0xab, 0x43, 0xbf, 0x66, 0x65, 0xe8, 0xb7,
+	0xf7, 0x7d, 0x68, 0x89, 0x71, 0x19, 0xdb, 0x80, 0xf5, 0x93, 0x80, 0x3e, 0xf2, 0x5e, 0x44, 0x46,
+	0x8d, 0xb5, 0xa1, 0x31, 0x4a, 0xc3, 0xc8, 0xd0, 0xd8, 0x3a, 0x34, 0x4f, 0xf1, 0xe3, 0xcc, 0xd0,
+	0x19, 0x40, 0x0b, 0x4b, 0xc7, 0x94, 0x1b, 0xf5, 0xbd, 0x3d, 0x68, 0xd2, 0x68, 0x89, 0x34, 0x7f,
+	0x71, 0x72, 0x6a, 0xd4, 0x58, 0x07, 0xd6, 0xec, 0xa3, 0xd3, 0xe1, 0xcf, 0x0e, 0x8f, 0x0c, 0x0d,
+	0x75, 0x4f, 0x9e, 0xff, 0xfc, 0xe8, 0xf0, 0xdc, 0xd0, 0xf7, 0xbe, 0x80, 0x26, 0xd5, 0x66, 0x66,
+	0x40, 0x57, 0x6e, 0x42, 0xb4, 0x51, 0x63, 0x6b, 0x50, 0x7f, 0xce, 0x6f, 0x0d, 0x8d, 0x16, 0x67,
+	0x01, 0x7e, 0x49, 0x89, 0x8d, 0x68, 0x4f, 0xcf, 0xa8, 0xa3, 0x00, 0x3d, 0x89, 0xb8, 0x67, 0x34,
+	0x58, 0x17, 0xda, 0x4f, 0xe4, 0xb7, 0x94, 0xd1, 0xdc, 0x7b, 0x01, 0xed, 0xbc, 0xa6, 0xb3, 0x7b,
+	0xd0, 0x91, 0xa6, 0x91, 0x65, 0xd4, 0xd0, 0x6f, 0xaa, 0xdc, 0x86, 0x86, 0x2e, 0x62, 0x75, 0x36,
+	0x74, 0x7c, 0xc2, 0x12, 0x6c, 0xd4, 0xc9, 0xed, 0xbb, 0xc0, 0x35, 0x1a, 0xa8, 0x48, 0x99, 0x62,
+	0x78, 0x7b, 0x3f, 0x84, 0xf5, 0xa2, 0x1e, 0xa1, 0xb3, 0x2f, 0x83, 0xeb, 0x20, 0xbc, 0x0d, 0x88,
+	0x27, 0x0e, 0x88, 0x6f, 0xfd, 0xe8, 0x6c, 0x68, 0x68, 0xb8, 0x21, 0xd9, 0x7f, 0x42, 0xd7, 0xa6,
+	0xa1, 0xef, 0x3d, 0x83, 0x35, 0x99, 0xc7, 0x8c, 0x41, 0x4f, 0x3a, 0x23, 0x39, 0x46, 0x0d, 0x01,
+	0xc6, 0x73, 0x88, 0xad, 0x34, 0xd6, 0x03, 0xa0, 0x23, 0x0a, 0x5a, 0x47, 0x73, 0x02, 0x5b, 0xc1,
+	0xa8, 0x1f, 0xfc, 0xb1, 0x0d, 0x2d, 0x91, 0x2b, 0xec, 0x10, 0xba, 0xea, 0xd4, 0x97, 0xbd, 0x2b,
+	0x6f, 0xbb, 0xd9, 0x39, 0x70, 0xdf, 0xa4, 0xfb, 0x6a, 0xc1, 0x48, 0xce, 0xaa, 0xb1, 0x13, 0xe8,
+	0x55, 0x27, 0xa8, 0xec, 0x21, 0x6a, 0x2f, 0x1c, 0xcf, 0xf6, 0xfb, 0x8b, 0x44, 0x85, 0xa9, 0x23,
+	0xd8, 0xa8, 0x0c, 0x45, 0x19, 0xed, 0xbb, 0x68, 0x4e, 0xba, 0xd2, 0xa3, 0x9f, 0x42, 0x47, 0x99,
+	0xf1, 0xb1, 0x07, 0xa8, 0x3a, 0x3f, 0x40, 0xed, 0xbf, 0x3b, 0xc7, 0x2f, 0x2c, 0x7c, 0x0e, 0x50,
+	0xce, 0xd7, 0xd8, 0x3b, 0x85, 0xa2, 0x3a, 0x57, 0xed, 0x3f, 0x98, 0x65, 0x17, 0xcb, 0x9f, 0x00,
+	0xc8, 0xe1, 0xea, 0xd9, 0x30, 0x61, 0xef, 0xa1, 0xde, 0xb2, 0x61, 0xeb, 0xca, 0x83, 0x1c, 0x40,
+	0xf7, 0x09, 0x4f, 0xdd, 0xab, 0xfc, 0x9a, 0xa2, 0xf6, 0x55, 0xb9, 0x52, 0xfa, 0x1d, 0xc9, 0x40,
+	0xc2, 0xaa, 0xed, 0x6a, 0x9f, 0x68, 0xec, 0x47, 0x00, 0x98, 0x4b, 0x59, 0xca, 0xb1, 0x26, 0x33,
+	0xba, 0x0a, 0x2b, 0x37, 0xca, 0xca, 0x1d, 0x0f, 0xa1, 0xab, 0x5e, 0x16, 0x22, 0x23, 0x16, 0x5c,
+	0x1f, 0x2b, 0x8d, 0x3c, 0x83, 0xcd, 0xb9, 0x72, 0x2f, 0x50, 0x58, 0x76, 0x0b, 0xbc, 0xc9, 0x27,
+	0xb5, 0xda, 0x0b, 0x9f, 0x16, 0xdc, 0x19, 0x7d, 0x73, 0x5e, 0x50, 0x18, 0xf9, 0x09, 0x40, 0x59,
+	0xbb, 0x45, 0x44, 0xe7, 0x6a, 0xf9, 0x4a, 0x2f, 0x8e, 0x61, 0x53, 0xf9, 0xdb, 0x43, 0x94, 0x59,
+	0x91, 0x5a, 0xf3, 0xff, 0x86, 0xac, 0x34, 0x64, 0xcb, 0x19, 0xbd, 0x5a, 0xaf, 0x05, 0x3a, 0xcb,
+	0x6a, 0x7c, 0xff, 0xfd, 0x25, 0x52, 0x15, 0x22, 0xf5, 0x3f, 0x16, 0x01, 0xd1, 0x82, 0x7f, 0x5d,
+	0x56, 0x39, 0xf6, 0xd8, 0xf8, 0xe7, 0xeb, 0x6d, 0xed, 0x5f, 0xaf, 0xb7, 0xb5, 0xff, 0xbc, 0xde,
+	0xd6, 0x7e, 0xff, 0xdf, 0xed, 0xda, 0x45, 0x8b, 0xfe, 0x20, 0xfa, 0xce, 0xff, 0x07, 0x00, 0xf2,
+	0x9c, 0x49, 0xfb, 0x32, 0x1a, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -4397,46 +4677,217 @@ func (m *LoadStatus) MarshalTo(dAtA []byte) (int, error) {
 		i = encodeVarintDmworker(dAtA, i, uint64(len(m.MetaBinlog)))
 		i += copy(dAtA[i:], m.MetaBinlog)
 	}
-	return i, nil
-}
-
-func (m *ShardingGroup) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalTo(dAtA)
-	if err != nil {
-		return nil, err
-	}
-	return dAtA[:n], nil
-}
-
-func (m *ShardingGroup) MarshalTo(dAtA []byte) (int, error) {
-	var i int
-	_ = i
-	var l int
-	_ = l
-	if len(m.Target) > 0 {
-		dAtA[i] = 0xa
-		i++
-		i = encodeVarintDmworker(dAtA, i, uint64(len(m.Target)))
-		i += copy(dAtA[i:], m.Target)
-	}
-	if len(m.DDLs) > 0 {
-		for _, s := range m.DDLs {
-			dAtA[i] = 0x12
+	if len(m.Tables) > 0 {
+		for _, msg := range m.Tables {
+			dAtA[i] = 0x2a
 			i++
-			l = len(s)
-			for l >= 1<<7 {
-				dAtA[i] = uint8(uint64(l)&0x7f | 0x80)
-				l >>= 7
-				i++
+			i = encodeVarintDmworker(dAtA, i, uint64(msg.Size()))
+			n, err := msg.MarshalTo(dAtA[i:])
+			if err != nil {
+				return 0, err
 			}
-			dAtA[i] = uint8(l)
-			i++
-			i += copy(dAtA[i:], s)
+			i += n
 		}
 	}
-	if len(m.FirstPos) > 0 {
+	if m.EtaSeconds != 0 {
+		dAtA[i] = 0x30
+		i++
+		i = encodeVarintDmworker(dAtA, i, uint64(m.EtaSeconds))
+	}
+	if len(m.MetaBinlogName) > 0 {
+		dAtA[i] = 0x3a
+		i++
+		i = encodeVarintDmworker(dAtA, i, uint64(len(m.MetaBinlogName)))
+		i += copy(dAtA[i:], m.MetaBinlogName)
+	}
+	if m.MetaBinlogPos != 0 {
+		dAtA[i] = 0x40
+		i++
+		i = encodeVarintDmworker(dAtA, i, uint64(m.MetaBinlogPos))
+	}
+	if len(m.Validations) > 0 {
+		for _, msg := range m.Validations {
+			dAtA[i] = 0x4a
+			i++
+			i = encodeVarintDmworker(dAtA, i, uint64(msg.Size()))
+			n, err := msg.MarshalTo(dAtA[i:])
+			if err != nil {
+				return 0, err
+			}
+			i += n
+		}
+	}
+	if m.FinishedRows != 0 {
+		dAtA[i] = 0x50
+		i++
+		i = encodeVarintDmworker(dAtA, i, uint64(m.FinishedRows))
+	}
+	if m.TotalRows != 0 {
+		dAtA[i] = 0x58
+		i++
+		i = encodeVarintDmworker(dAtA, i, uint64(m.TotalRows))
+	}
+	if m.Paused {
+		dAtA[i] = 0x60
+		i++
+		if m.Paused {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i++
+	}
+	if len(m.MetaBinlogGTID) > 0 {
+		dAtA[i] = 0x6a
+		i++
+		i = encodeVarintDmworker(dAtA, i, uint64(len(m.MetaBinlogGTID)))
+		i += copy(dAtA[i:], m.MetaBinlogGTID)
+	}
+	return i, nil
+}
+
+func (m *TableLoadStatus) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *TableLoadStatus) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if len(m.Table) > 0 {
+		dAtA[i] = 0xa
+		i++
+		i = encodeVarintDmworker(dAtA, i, uint64(len(m.Table)))
+		i += copy(dAtA[i:], m.Table)
+	}
+	if m.FinishedBytes != 0 {
+		dAtA[i] = 0x10
+		i++
+		i = encodeVarintDmworker(dAtA, i, uint64(m.FinishedBytes))
+	}
+	if m.TotalBytes != 0 {
+		dAtA[i] = 0x18
+		i++
+		i = encodeVarintDmworker(dAtA, i, uint64(m.TotalBytes))
+	}
+	if m.RemainingFiles != 0 {
+		dAtA[i] = 0x20
+		i++
+		i = encodeVarintDmworker(dAtA, i, uint64(m.RemainingFiles))
+	}
+	if m.FinishedRows != 0 {
+		dAtA[i] = 0x28
+		i++
+		i = encodeVarintDmworker(dAtA, i, uint64(m.FinishedRows))
+	}
+	if m.TotalRows != 0 {
+		dAtA[i] = 0x30
+		i++
+		i = encodeVarintDmworker(dAtA, i, uint64(m.TotalRows))
+	}
+	return i, nil
+}
+
+func (m *TableValidation) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *TableValidation) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if len(m.Table) > 0 {
+		dAtA[i] = 0xa
+		i++
+		i = encodeVarintDmworker(dAtA, i, uint64(len(m.Table)))
+		i += copy(dAtA[i:], m.Table)
+	}
+	if len(m.TargetTable) > 0 {
+		dAtA[i] = 0x12
+		i++
+		i = encodeVarintDmworker(dAtA, i, uint64(len(m.TargetTable)))
+		i += copy(dAtA[i:], m.TargetTable)
+	}
+	if len(m.Mode) > 0 {
+		dAtA[i] = 0x1a
+		i++
+		i = encodeVarintDmworker(dAtA, i, uint64(len(m.Mode)))
+		i += copy(dAtA[i:], m.Mode)
+	}
+	if m.SourceValue != 0 {
+		dAtA[i] = 0x20
+		i++
+		i = encodeVarintDmworker(dAtA, i, uint64(m.SourceValue))
+	}
+	if m.TargetValue != 0 {
+		dAtA[i] = 0x28
+		i++
+		i = encodeVarintDmworker(dAtA, i, uint64(m.TargetValue))
+	}
+	if m.Matched {
+		dAtA[i] = 0x30
+		i++
+		if m.Matched {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i++
+	}
+	return i, nil
+}
+
+func (m *ShardingGroup) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *ShardingGroup) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if len(m.Target) > 0 {
+		dAtA[i] = 0xa
+		i++
+		i = encodeVarintDmworker(dAtA, i, uint64(len(m.Target)))
+		i += copy(dAtA[i:], m.Target)
+	}
+	if len(m.DDLs) > 0 {
+		for _, s := range m.DDLs {
+			dAtA[i] = 0x12
+			i++
+			l = len(s)
+			for l >= 1<<7 {
+				dAtA[i] = uint8(uint64(l)&0x7f | 0x80)
+				l >>= 7
+				i++
+			}
+			dAtA[i] = uint8(l)
+			i++
+			i += copy(dAtA[i:], s)
+		}
+	}
+	if len(m.FirstPos) > 0 {
 		dAtA[i] = 0x1a
 		i++
 		i = encodeVarintDmworker(dAtA, i, uint64(len(m.FirstPos)))
@@ -5833,6 +6284,96 @@ func (m *LoadStatus) Size() (n int) {
 	if l > 0 {
 		n += 1 + l + sovDmworker(uint64(l))
 	}
+	if len(m.Tables) > 0 {
+		for _, e := range m.Tables {
+			l = e.Size()
+			n += 1 + l + sovDmworker(uint64(l))
+		}
+	}
+	if m.EtaSeconds != 0 {
+		n += 1 + sovDmworker(uint64(m.EtaSeconds))
+	}
+	l = len(m.MetaBinlogName)
+	if l > 0 {
+		n += 1 + l + sovDmworker(uint64(l))
+	}
+	if m.MetaBinlogPos != 0 {
+		n += 1 + sovDmworker(uint64(m.MetaBinlogPos))
+	}
+	if len(m.Validations) > 0 {
+		for _, e := range m.Validations {
+			l = e.Size()
+			n += 1 + l + sovDmworker(uint64(l))
+		}
+	}
+	if m.FinishedRows != 0 {
+		n += 1 + sovDmworker(uint64(m.FinishedRows))
+	}
+	if m.TotalRows != 0 {
+		n += 1 + sovDmworker(uint64(m.TotalRows))
+	}
+	if m.Paused {
+		n += 2
+	}
+	l = len(m.MetaBinlogGTID)
+	if l > 0 {
+		n += 1 + l + sovDmworker(uint64(l))
+	}
+	return n
+}
+
+func (m *TableLoadStatus) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Table)
+	if l > 0 {
+		n += 1 + l + sovDmworker(uint64(l))
+	}
+	if m.FinishedBytes != 0 {
+		n += 1 + sovDmworker(uint64(m.FinishedBytes))
+	}
+	if m.TotalBytes != 0 {
+		n += 1 + sovDmworker(uint64(m.TotalBytes))
+	}
+	if m.RemainingFiles != 0 {
+		n += 1 + sovDmworker(uint64(m.RemainingFiles))
+	}
+	if m.FinishedRows != 0 {
+		n += 1 + sovDmworker(uint64(m.FinishedRows))
+	}
+	if m.TotalRows != 0 {
+		n += 1 + sovDmworker(uint64(m.TotalRows))
+	}
+	return n
+}
+
+func (m *TableValidation) Size() (n int) {
+	var l int
+	_ = l
+	l = len(m.Table)
+	if l > 0 {
+		n += 1 + l + sovDmworker(uint64(l))
+	}
+	l = len(m.TargetTable)
+	if l > 0 {
+		n += 1 + l + sovDmworker(uint64(l))
+	}
+	l = len(m.Mode)
+	if l > 0 {
+		n += 1 + l + sovDmworker(uint64(l))
+	}
+	if m.SourceValue != 0 {
+		n += 1 + sovDmworker(uint64(m.SourceValue))
+	}
+	if m.TargetValue != 0 {
+		n += 1 + sovDmworker(uint64(m.TargetValue))
+	}
+	if m.Matched {
+		n += 2
+	}
 	return n
 }
 
@@ -8274,6 +8815,591 @@ func (m *LoadStatus) Unmarshal(dAtA []byte) error {
 			}
 			m.MetaBinlog = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
+		case 5:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Tables", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowDmworker
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthDmworker
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Tables = append(m.Tables, &TableLoadStatus{})
+			if err := m.Tables[len(m.Tables)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field EtaSeconds", wireType)
+			}
+			m.EtaSeconds = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowDmworker
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.EtaSeconds |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 7:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MetaBinlogName", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowDmworker
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthDmworker
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.MetaBinlogName = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 8:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MetaBinlogPos", wireType)
+			}
+			m.MetaBinlogPos = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowDmworker
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.MetaBinlogPos |= (uint32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 9:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Validations", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowDmworker
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthDmworker
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Validations = append(m.Validations, &TableValidation{})
+			if err := m.Validations[len(m.Validations)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 10:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field FinishedRows", wireType)
+			}
+			m.FinishedRows = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowDmworker
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.FinishedRows |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 11:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field TotalRows", wireType)
+			}
+			m.TotalRows = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowDmworker
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.TotalRows |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 12:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Paused", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowDmworker
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Paused = bool(v != 0)
+		case 13:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MetaBinlogGTID", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowDmworker
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthDmworker
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.MetaBinlogGTID = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipDmworker(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthDmworker
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *TableLoadStatus) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowDmworker
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: TableLoadStatus: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: TableLoadStatus: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Table", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowDmworker
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthDmworker
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Table = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field FinishedBytes", wireType)
+			}
+			m.FinishedBytes = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowDmworker
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.FinishedBytes |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field TotalBytes", wireType)
+			}
+			m.TotalBytes = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowDmworker
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.TotalBytes |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field RemainingFiles", wireType)
+			}
+			m.RemainingFiles = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowDmworker
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.RemainingFiles |= (int32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field FinishedRows", wireType)
+			}
+			m.FinishedRows = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowDmworker
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.FinishedRows |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field TotalRows", wireType)
+			}
+			m.TotalRows = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowDmworker
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.TotalRows |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipDmworker(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthDmworker
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *TableValidation) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowDmworker
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: TableValidation: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: TableValidation: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Table", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowDmworker
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthDmworker
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Table = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field TargetTable", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowDmworker
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthDmworker
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.TargetTable = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Mode", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowDmworker
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthDmworker
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Mode = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SourceValue", wireType)
+			}
+			m.SourceValue = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowDmworker
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.SourceValue |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field TargetValue", wireType)
+			}
+			m.TargetValue = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowDmworker
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.TargetValue |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Matched", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowDmworker
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Matched = bool(v != 0)
 		default:
 			iNdEx = preIndex
 			skippy, err := skipDmworker(dAtA[iNdEx:])
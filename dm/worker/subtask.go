@@ -22,6 +22,7 @@ import (
 	"github.com/pingcap/dm/dm/unit"
 	"github.com/pingcap/dm/loader"
 	"github.com/pingcap/dm/mydumper"
+	"github.com/pingcap/dm/pkg/gtid"
 	"github.com/pingcap/dm/pkg/log"
 	"github.com/pingcap/dm/pkg/utils"
 	"github.com/pingcap/dm/syncer"
@@ -31,6 +32,7 @@ import (
 	_ "github.com/pingcap/tidb-tools/pkg/check"
 	_ "github.com/pingcap/tidb-tools/pkg/dbutil"
 	_ "github.com/pingcap/tidb-tools/pkg/utils"
+	"github.com/siddontang/go-mysql/mysql"
 	"golang.org/x/net/context"
 )
 
@@ -583,6 +585,35 @@ func (st *SubTask) ClearDDLInfo() {
 	st.cacheDDLInfo = nil
 }
 
+// waitRelayCatchupGTID waits for the GTID set of relay log containing the one of load unit,
+// when only the GTID set is available in the dump rather than the binlog position.
+func (st *SubTask) waitRelayCatchupGTID(ctx context.Context, loadGTID string) error {
+	gs1, err := gtid.ParserGTID(st.cfg.Flavor, loadGTID)
+	if err != nil {
+		return errors.Annotatef(err, "GTID set of load unit")
+	}
+	hub := GetConditionHub()
+	for {
+		relayStatus := hub.w.relayHolder.Status()
+		gs2, err := gtid.ParserGTID(st.cfg.Flavor, relayStatus.RelayBinlogGtid)
+		if err != nil {
+			return errors.Annotatef(err, "GTID set of relay log")
+		}
+		if gs2.Contain(gs1) {
+			break
+		}
+		log.Debugf("loader end GTID set: %s, relay GTID set: %s, wait for catchup", gs1, gs2)
+
+		select {
+		case <-ctx.Done():
+			return errors.Errorf("wait relay catchup timeout, loader end GTID set: %s, relay GTID set: %s", gs1, gs2)
+		case <-time.After(time.Millisecond * 50):
+		}
+	}
+	log.Info("relay GTID set catchup loader end GTID set")
+	return nil
+}
+
 // unitTransWaitCondition waits when transferring from current unit to next unit.
 // Currently there is only one wait condition
 // from Load unit to Sync unit, wait for relay-log catched up with mydumper binlog position.
@@ -596,10 +627,13 @@ func (st *SubTask) unitTransWaitCondition() error {
 		defer cancel()
 
 		loadStatus := pu.Status().(*pb.LoadStatus)
-		pos1, err := utils.DecodeBinlogPosition(loadStatus.MetaBinlog)
-		if err != nil {
-			return errors.Trace(err)
+		if loadStatus.MetaBinlogName == "" {
+			if loadStatus.MetaBinlogGTID == "" {
+				return errors.NotValidf("binlog position of load unit")
+			}
+			return errors.Trace(st.waitRelayCatchupGTID(ctx, loadStatus.MetaBinlogGTID))
 		}
+		pos1 := &mysql.Position{Name: loadStatus.MetaBinlogName, Pos: loadStatus.MetaBinlogPos}
 		for {
 			relayStatus := hub.w.relayHolder.Status()
 			pos2, err := utils.DecodeBinlogPosition(relayStatus.RelayBinlog)
@@ -0,0 +1,213 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loader
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pingcap/errors"
+)
+
+// suffixes of a dump archived as a gzip-compressed tarball, like `dump.tar.gz`
+var archiveSuffixes = []string{".tar.gz", ".tgz"}
+
+/* Loading from a dump archive
+ * the dump may be shipped as a gzip-compressed tarball, whose path is specified as the dump directory.
+ * files in the archive are identified by their base names, no matter which directory they are in,
+ * so checkpoints are keyed on the base names of data files, the same as the ones in a directory.
+ * the metadata and schema files, which are small, are extracted to a temporary directory when the loader starts,
+ * while data files are streamed from the archive when they are restored, rather than extracted to disk.
+ * a member of a tarball can't be read randomly, it's read by decompressing the archive from the start.
+ * so resuming a data file replays the member from its start, and the data before the offset in checkpoint are discarded.
+ * positions of data files are counted in the uncompressed bytes of members, like compressed data files.
+ */
+
+// isDumpArchive checks whether the dump directory is an archive by its suffix
+func isDumpArchive(dir string) bool {
+	for _, suffix := range archiveSuffixes {
+		if strings.HasSuffix(dir, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// isArchivedDataFile returns whether the file in the archive is streamed when restored rather than extracted
+func isArchivedDataFile(name string) bool {
+	return name != "metadata" && !strings.Contains(name, "-schema")
+}
+
+// dumpArchive is a dump archived as a gzip-compressed tarball
+type dumpArchive struct {
+	path  string
+	dir   string              // temporary directory of the metadata and schema files extracted
+	files map[string]struct{} // base names of all files in the archive
+}
+
+// openDumpArchive scans the archive, and extracts the metadata and schema files into a new temporary directory
+func openDumpArchive(archivePath string) (*dumpArchive, error) {
+	dir, err := ioutil.TempDir("", "dm-loader-archive")
+	if err != nil {
+		return nil, errors.Annotatef(err, "create directory to extract archive %s", archivePath)
+	}
+	a := &dumpArchive{path: archivePath, dir: dir, files: make(map[string]struct{})}
+
+	err = a.walk(func(name string, r io.Reader) (bool, error) {
+		if _, ok := a.files[name]; ok {
+			return false, errors.Errorf("duplicate file %s in archive %s", name, archivePath)
+		}
+		a.files[name] = struct{}{}
+		if isArchivedDataFile(name) {
+			return false, nil
+		}
+		return false, errors.Trace(extractFile(filepath.Join(dir, name), r))
+	})
+	if err != nil {
+		a.close()
+		return nil, errors.Trace(err)
+	}
+	return a, nil
+}
+
+func extractFile(file string, r io.Reader) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	_, err = io.Copy(f, r)
+	if err2 := f.Close(); err == nil {
+		err = err2
+	}
+	return errors.Annotatef(err, "extract file %s", file)
+}
+
+// walk calls fn with the base name and content of every regular file in the archive in order, until fn returns true or an error
+func (a *dumpArchive) walk(fn func(name string, r io.Reader) (bool, error)) error {
+	f, err := os.Open(a.path)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer f.Close()
+
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		return errors.Annotatef(err, "open archive %s", a.path)
+	}
+	defer gr.Close()
+
+	tr := tar.NewReader(gr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return errors.Annotatef(err, "read archive %s", a.path)
+		}
+		if !hdr.FileInfo().Mode().IsRegular() {
+			continue
+		}
+		done, err := fn(path.Base(hdr.Name), tr)
+		if err != nil || done {
+			return errors.Trace(err)
+		}
+	}
+}
+
+// open returns the reader of the data file in the archive, the archive is decompressed until the file
+func (a *dumpArchive) open(name string) (io.ReadCloser, error) {
+	f, err := os.Open(a.path)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, errors.Annotatef(err, "open archive %s", a.path)
+	}
+
+	tr := tar.NewReader(gr)
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			gr.Close()
+			f.Close()
+			if err == io.EOF {
+				return nil, errors.NotFoundf("file %s in archive %s", name, a.path)
+			}
+			return nil, errors.Annotatef(err, "read archive %s", a.path)
+		}
+		if hdr.FileInfo().Mode().IsRegular() && path.Base(hdr.Name) == name {
+			return &archivedFile{Reader: tr, gr: gr, f: f}, nil
+		}
+	}
+}
+
+// close removes the temporary directory of files extracted
+func (a *dumpArchive) close() error {
+	return errors.Trace(os.RemoveAll(a.dir))
+}
+
+// archivedFile is the reader of a file in the archive
+type archivedFile struct {
+	io.Reader
+	gr *gzip.Reader
+	f  *os.File
+}
+
+func (r *archivedFile) Close() error {
+	r.gr.Close()
+	return errors.Trace(r.f.Close())
+}
+
+// decompressReadCloser closes the underlying file after the decompress reader closed
+type decompressReadCloser struct {
+	io.ReadCloser
+	underlying io.Closer
+}
+
+func (r *decompressReadCloser) Close() error {
+	r.ReadCloser.Close()
+	return errors.Trace(r.underlying.Close())
+}
+
+// openDataFile returns the reader of the decompressed data of the data file,
+// it's read from the archive if archive is not nil, otherwise from the local file.
+func openDataFile(archive *dumpArchive, file string) (io.ReadCloser, error) {
+	var (
+		f   io.ReadCloser
+		err error
+	)
+	if archive != nil {
+		f, err = archive.open(filepath.Base(file))
+	} else {
+		f, err = os.Open(file)
+	}
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	r, err := newDecompressReader(file, f)
+	if err != nil {
+		f.Close()
+		return nil, errors.Trace(err)
+	}
+	return &decompressReadCloser{ReadCloser: r, underlying: f}, nil
+}
@@ -16,12 +16,15 @@ package loader
 import (
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/pingcap/dm/dm/config"
 	"github.com/pingcap/dm/pkg/log"
 	"github.com/pingcap/errors"
+	"golang.org/x/net/context"
 )
 
 // CheckPoint represents checkpoint status
@@ -55,18 +58,200 @@ type CheckPoint interface {
 	// Count returns recorded checkpoints' count
 	Count() (int, error)
 
-	// GenSQL generates sql to update checkpoint to DB
+	// GenSQL generates sql to update checkpoint to DB,
+	// it returns an empty string if the checkpoint is not saved in DB
 	GenSQL(filename string, offset int64) string
+
+	// UpdateOffset updates the offset of the file after the data job executed,
+	// it's for the checkpoint not updated by sql generated by GenSQL
+	UpdateOffset(filename string, offset int64) error
+
+	// Flush saves checkpoints updated by UpdateOffset but not saved yet
+	Flush() error
+
+	// Prune removes checkpoints of data files not in existingFiles, like files of a previous dump with another layout,
+	// checkpoints of existing files are kept whether they are finished or not.
+	// it should be called after Load, and it's safe to be called repeatedly
+	Prune(existingFiles []string) error
+
+	// SaveChecksum saves the checksum of all rows in the data file after they are restored, see rowsChecksum
+	SaveChecksum(filename string, checksum uint64) error
+
+	// GetTableChecksum returns the checksum of rows in the data files of table whose checksums are saved,
+	// it's the checksum of all rows of the table after the table finished
+	GetTableChecksum(db, table string) uint64
+
+	// Export returns all checkpoints in a portable format, including the ones not saved yet,
+	// it's the same format as the file of FileCheckPoint, and can be imported by Import of any CheckPoint
+	Export() ([]byte, error)
+
+	// Import replaces all checkpoints with the ones exported by Export, like the ones of a task moved from another DM-worker.
+	// it doesn't check the data files of the checkpoints, see Loader.ImportCheckpoint
+	Import(data []byte) error
 }
 
-// RemoteCheckPoint implements CheckPoint by saving status in remote database system, mostly in TiDB.
-type RemoteCheckPoint struct {
-	conn           *Conn // NOTE: use dbutil in tidb-tools later
-	id             string
-	schema         string
-	table          string
+// newCheckPoint creates a CheckPoint, it's saved in the local file if cfg.CheckpointFile specified,
+// and only kept in memory in dry-run mode.
+func newCheckPoint(cfg *config.SubTaskConfig, id string) (CheckPoint, error) {
+	if cfg.DryRun {
+		return newFileCheckPoint("", id)
+	}
+	if cfg.CheckpointFile != "" {
+		return newFileCheckPoint(cfg.CheckpointFile, id)
+	}
+	return newRemoteCheckPoint(cfg, id)
+}
+
+// restoringState records restoring files loaded from checkpoint and tables finished
+type restoringState struct {
 	restoringFiles map[string]map[string]FilePosSet
 	finishedTables map[string]struct{}
+	checksums      map[string]uint64 // data file name -> checksum of rows saved after the file restored
+}
+
+func newRestoringState() restoringState {
+	return restoringState{
+		restoringFiles: make(map[string]map[string]FilePosSet),
+		finishedTables: make(map[string]struct{}),
+		checksums:      make(map[string]uint64),
+	}
+}
+
+// addRestoringFile adds a restoring file loaded from checkpoint
+func (cp *restoringState) addRestoringFile(schema, table, filename string, offset, endPos int64) {
+	if _, ok := cp.restoringFiles[schema]; !ok {
+		cp.restoringFiles[schema] = make(map[string]FilePosSet)
+	}
+	tables := cp.restoringFiles[schema]
+	if _, ok := tables[table]; !ok {
+		tables[table] = make(map[string][]int64)
+	}
+	restoringFiles := tables[table]
+	restoringFiles[filename] = []int64{offset, endPos}
+}
+
+// tableChecksum returns the sum of checksums of data files of the table
+func (cp *restoringState) tableChecksum(db, table string) uint64 {
+	var checksum uint64
+	for filename, fileChecksum := range cp.checksums {
+		if fileDB, fileTable, err := parseDataFileName(filename); err == nil && fileDB == db && fileTable == table {
+			checksum += fileChecksum
+		}
+	}
+	return checksum
+}
+
+// resetRestoringFiles resets restoring files and checksums to the checkpoints of data files
+func (cp *restoringState) resetRestoringFiles(points map[string]*filePoint) {
+	cp.restoringFiles = make(map[string]map[string]FilePosSet)
+	cp.checksums = make(map[string]uint64)
+	for filename, point := range points {
+		cp.addRestoringFile(point.Schema, point.Table, filename, point.Offset, point.EndPos)
+		if point.Checksum != 0 {
+			cp.checksums[filename] = point.Checksum
+		}
+	}
+}
+
+// exportPoints encodes the checkpoints of data files, see CheckPoint.Export
+func exportPoints(id string, points map[string]*filePoint) ([]byte, error) {
+	data, err := json.Marshal(&fileCheckPointData{ID: id, Files: points})
+	return data, errors.Annotatef(err, "export checkpoints")
+}
+
+// importPoints decodes the checkpoints of data files exported by exportPoints.
+// the ID is ignored, it's different if the dump is in another directory, see Loader.checkpointID
+func importPoints(data []byte) (map[string]*filePoint, error) {
+	var content fileCheckPointData
+	if err := json.Unmarshal(data, &content); err != nil {
+		return nil, errors.Annotatef(err, "decode exported checkpoints")
+	}
+	for filename, point := range content.Files {
+		if point == nil {
+			return nil, errors.NotValidf("exported checkpoint of file %s", filename)
+		}
+		db, table, err := parseDataFileName(filename)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if point.Schema != db || point.Table != table || point.Offset < 0 || point.Offset > point.EndPos {
+			return nil, errors.NotValidf("exported checkpoint %+v of file %s", point, filename)
+		}
+	}
+	if content.Files == nil {
+		content.Files = make(map[string]*filePoint)
+	}
+	return content.Files, nil
+}
+
+// pruneRestoringFiles removes restoring files not in existingFiles
+func (cp *restoringState) pruneRestoringFiles(existingFiles map[string]struct{}) {
+	for file := range cp.checksums {
+		if _, ok := existingFiles[file]; !ok {
+			delete(cp.checksums, file)
+		}
+	}
+	for schema, tables := range cp.restoringFiles {
+		for table, files := range tables {
+			for file := range files {
+				if _, ok := existingFiles[file]; !ok {
+					delete(files, file)
+				}
+			}
+			if len(files) == 0 {
+				delete(tables, table)
+			}
+		}
+		if len(tables) == 0 {
+			delete(cp.restoringFiles, schema)
+		}
+	}
+}
+
+// toFileSet converts names of files to a set
+func toFileSet(files []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(files))
+	for _, file := range files {
+		set[file] = struct{}{}
+	}
+	return set
+}
+
+// checkpointSchemaVersion is the version of checkpoint table schema, it's saved as the default value of `schema_version` column.
+// tables created before `schema_version` added are version 1.
+const checkpointSchemaVersion = 3
+
+// checkpointMigrations[i] upgrades checkpoint table from version i+1 to version i+2.
+// the last sql of a migration sets the default value of `schema_version`, so the version is bumped only after all sqls executed,
+// and sqls are executed again if interrupted, adding an existing column is ignored.
+var checkpointMigrations = [][]string{
+	{"ALTER TABLE %s ADD COLUMN `schema_version` int NOT NULL DEFAULT 2"},
+	{
+		"ALTER TABLE %s ADD COLUMN `checksum` bigint unsigned NOT NULL DEFAULT 0",
+		"ALTER TABLE %s ALTER COLUMN `schema_version` SET DEFAULT 3",
+	},
+}
+
+// maxCreateRetryCount is the max times to execute the statement creating the checkpoint schema or table, see createIfNotExists
+var maxCreateRetryCount = 3
+
+// RemoteCheckPoint implements CheckPoint by saving status in remote database system, mostly in TiDB.
+type RemoteCheckPoint struct {
+	restoringState
+
+	conn   *Conn       // used to query checkpoints, NOTE: use dbutil in tidb-tools later
+	exec   Executor    // used to save checkpoints, it's conn except in tests
+	retry  RetryPolicy // retries loading checkpoints, saving them is retried by exec
+	id     string
+	schema string
+	table  string
+
+	// if batch > 0, checkpoints are saved by SaveBatch rather than in the same transaction with data,
+	// and at most batch dirty checkpoints are kept in memory before saved in one transaction.
+	batch     int
+	batchLock sync.Mutex
+	points    map[string]*filePoint // data file name -> latest checkpoint initialized or updated since loaded
+	dirty     map[string]struct{}   // data files whose checkpoints are not saved yet
 }
 
 func newRemoteCheckPoint(cfg *config.SubTaskConfig, id string) (CheckPoint, error) {
@@ -76,12 +261,16 @@ func newRemoteCheckPoint(cfg *config.SubTaskConfig, id string) (CheckPoint, erro
 	}
 
 	cp := &RemoteCheckPoint{
+		restoringState: newRestoringState(),
 		conn:           conn,
+		exec:           conn,
+		retry:          newRetryPolicy(cfg),
 		id:             id,
-		restoringFiles: make(map[string]map[string]FilePosSet),
-		finishedTables: make(map[string]struct{}),
 		schema:         cfg.MetaSchema,
 		table:          fmt.Sprintf("%s_loader_checkpoint", cfg.Name),
+		batch:          cfg.CheckpointBatch,
+		points:         make(map[string]*filePoint),
+		dirty:          make(map[string]struct{}),
 	}
 
 	err = cp.prepare()
@@ -101,13 +290,13 @@ func (cp *RemoteCheckPoint) prepare() error {
 	if err := cp.createTable(); err != nil {
 		return errors.Trace(err)
 	}
-	return nil
+	// upgrade table created by previous versions
+	return errors.Trace(cp.migrate())
 }
 
 func (cp *RemoteCheckPoint) createSchema() error {
 	sql2 := fmt.Sprintf("CREATE SCHEMA IF NOT EXISTS `%s`", cp.schema)
-	err := cp.conn.executeSQL([]string{sql2}, true)
-	return errors.Trace(err)
+	return errors.Trace(cp.createIfNotExists(sql2))
 }
 
 func (cp *RemoteCheckPoint) createTable() error {
@@ -119,16 +308,81 @@ func (cp *RemoteCheckPoint) createTable() error {
 		cp_table varchar(128) NOT NULL,
 		offset bigint NOT NULL,
 		end_pos bigint NOT NULL,
+		checksum bigint unsigned NOT NULL DEFAULT 0,
+		schema_version int NOT NULL DEFAULT %d,
 		create_time timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
 		update_time timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
 		UNIQUE KEY uk_id_f (id,filename)
 	);
 `
-	sql2 := fmt.Sprintf(createTable, tableName)
-	err := cp.conn.executeSQL([]string{sql2}, true)
+	sql2 := fmt.Sprintf(createTable, tableName, checkpointSchemaVersion)
+	return errors.Trace(cp.createIfNotExists(sql2))
+}
+
+// createIfNotExists executes the `CREATE ... IF NOT EXISTS` statement of the checkpoint schema or table.
+// loaders of subtasks started at the same time may create them concurrently, then the statement may fail with
+// ErrDBCreateExists or ErrTableExists (like in TiDB) rather than do nothing, and it's executed again,
+// which succeeds as the schema or table exists now.
+func (cp *RemoteCheckPoint) createIfNotExists(sql string) error {
+	policy := RetryPolicy{
+		MaxRetries: maxCreateRetryCount - 1,
+		Backoff:    retryInterval,
+		RetryableFunc: func(err error) bool {
+			return isErrDBExists(err) || isErrTableExists(err)
+		},
+	}
+	err := policy.Do(context.Background(), "[checkpoint] "+sql, func() error {
+		return cp.exec.Exec(context.Background(), []string{sql}, nil)
+	})
 	return errors.Trace(err)
 }
 
+// schemaVersion returns the version of checkpoint table schema
+func (cp *RemoteCheckPoint) schemaVersion() (int, error) {
+	query := "SELECT `COLUMN_DEFAULT` FROM `information_schema`.`COLUMNS` WHERE `TABLE_SCHEMA` = ? AND `TABLE_NAME` = ? AND `COLUMN_NAME` = 'schema_version'"
+	rows, err := cp.conn.querySQL(query, cp.schema, cp.table)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	defer rows.Close()
+
+	version := 1 // no `schema_version` column
+	for rows.Next() {
+		var def string
+		if err = rows.Scan(&def); err != nil {
+			return 0, errors.Trace(err)
+		}
+		version, err = strconv.Atoi(def)
+		if err != nil {
+			return 0, errors.Annotatef(err, "parse schema version %s of checkpoint table", def)
+		}
+	}
+	return version, errors.Trace(rows.Err())
+}
+
+// migrate upgrades checkpoint table to checkpointSchemaVersion, it does nothing if the table is up to date
+func (cp *RemoteCheckPoint) migrate() error {
+	version, err := cp.schemaVersion()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if version > checkpointSchemaVersion {
+		return errors.NotSupportedf("checkpoint table `%s`.`%s` of schema version %d newer than %d", cp.schema, cp.table, version, checkpointSchemaVersion)
+	}
+
+	tableName := fmt.Sprintf("`%s`.`%s`", cp.schema, cp.table)
+	for ; version < checkpointSchemaVersion; version++ {
+		log.Infof("[checkpoint] upgrade checkpoint table %s from schema version %d to %d", tableName, version, version+1)
+		for _, sql := range checkpointMigrations[version-1] {
+			err = cp.exec.Exec(context.Background(), []string{fmt.Sprintf(sql, tableName)}, nil)
+			if err != nil && !isErrDupFieldName(err) {
+				return errors.Annotatef(err, "upgrade checkpoint table %s to schema version %d", tableName, version+1)
+			}
+		}
+	}
+	return nil
+}
+
 // Load implements CheckPoint.Load
 func (cp *RemoteCheckPoint) Load() error {
 	begin := time.Now()
@@ -136,7 +390,23 @@ func (cp *RemoteCheckPoint) Load() error {
 		log.Infof("[checkpoint] load checkpoint takes %f seconds", time.Since(begin).Seconds())
 	}()
 
-	query := fmt.Sprintf("SELECT `filename`,`cp_schema`,`cp_table`,`offset`,`end_pos` from `%s`.`%s` where `id`='%s'", cp.schema, cp.table, cp.id)
+	// checkpoints not saved yet are newer than the ones in DB
+	cp.batchLock.Lock()
+	defer cp.batchLock.Unlock()
+	if err := cp.flush(); err != nil {
+		return errors.Trace(err)
+	}
+	cp.points = make(map[string]*filePoint)
+
+	query := fmt.Sprintf("SELECT `filename`,`cp_schema`,`cp_table`,`offset`,`end_pos`,`checksum` from `%s`.`%s` where `id`='%s'", cp.schema, cp.table, cp.id)
+	err := cp.retry.Do(context.Background(), "[checkpoint] "+query, func() error {
+		return cp.load(query)
+	})
+	return errors.Trace(err)
+}
+
+// load queries checkpoints by query, the checkpoints loaded partially are dropped if failed
+func (cp *RemoteCheckPoint) load(query string) error {
 	rows, err := cp.conn.querySQL(query)
 	if err != nil {
 		return errors.Trace(err)
@@ -149,31 +419,28 @@ func (cp *RemoteCheckPoint) Load() error {
 		table    string
 		offset   int64
 		endPos   int64
+		checksum uint64
 	)
 
 	cp.restoringFiles = make(map[string]map[string]FilePosSet) // reset to empty
+	cp.checksums = make(map[string]uint64)
 	for rows.Next() {
-		err := rows.Scan(&filename, &schema, &table, &offset, &endPos)
+		err := rows.Scan(&filename, &schema, &table, &offset, &endPos, &checksum)
 		if err != nil {
 			return errors.Trace(err)
 		}
 
-		if _, ok := cp.restoringFiles[schema]; !ok {
-			cp.restoringFiles[schema] = make(map[string]FilePosSet)
+		cp.addRestoringFile(schema, table, filename, offset, endPos)
+		if checksum != 0 {
+			cp.checksums[filename] = checksum
 		}
-		tables := cp.restoringFiles[schema]
-		if _, ok := tables[table]; !ok {
-			tables[table] = make(map[string][]int64)
-		}
-		restoringFiles := tables[table]
-		restoringFiles[filename] = []int64{offset, endPos}
 	}
 
 	return errors.Trace(rows.Err())
 }
 
 // GetRestoringFileInfo implements CheckPoint.GetRestoringFileInfo
-func (cp *RemoteCheckPoint) GetRestoringFileInfo(db, table string) map[string][]int64 {
+func (cp *restoringState) GetRestoringFileInfo(db, table string) map[string][]int64 {
 	if tables, ok := cp.restoringFiles[db]; ok {
 		if restoringFiles, ok := tables[table]; ok {
 			return restoringFiles
@@ -183,7 +450,7 @@ func (cp *RemoteCheckPoint) GetRestoringFileInfo(db, table string) map[string][]
 }
 
 // GetAllRestoringFileInfo implements CheckPoint.GetAllRestoringFileInfo
-func (cp *RemoteCheckPoint) GetAllRestoringFileInfo() map[string][]int64 {
+func (cp *restoringState) GetAllRestoringFileInfo() map[string][]int64 {
 	results := make(map[string][]int64)
 	for _, tables := range cp.restoringFiles {
 		for _, files := range tables {
@@ -196,7 +463,7 @@ func (cp *RemoteCheckPoint) GetAllRestoringFileInfo() map[string][]int64 {
 }
 
 // IsTableFinished implements CheckPoint.IsTableFinished
-func (cp *RemoteCheckPoint) IsTableFinished(db, table string) bool {
+func (cp *restoringState) IsTableFinished(db, table string) bool {
 	key := strings.Join([]string{db, table}, ".")
 	if _, ok := cp.finishedTables[key]; ok {
 		return true
@@ -205,7 +472,7 @@ func (cp *RemoteCheckPoint) IsTableFinished(db, table string) bool {
 }
 
 // CalcProgress implements CheckPoint.CalcProgress
-func (cp *RemoteCheckPoint) CalcProgress(allFiles map[string]Tables2DataFiles) error {
+func (cp *restoringState) CalcProgress(allFiles map[string]Tables2DataFiles) error {
 	cp.finishedTables = make(map[string]struct{}) // reset to empty
 	for db, tables := range cp.restoringFiles {
 		dbTables, ok := allFiles[db]
@@ -238,7 +505,7 @@ func (cp *RemoteCheckPoint) CalcProgress(allFiles map[string]Tables2DataFiles) e
 	return nil
 }
 
-func (cp *RemoteCheckPoint) allFilesFinished(files map[string][]int64) bool {
+func (cp *restoringState) allFilesFinished(files map[string][]int64) bool {
 	for file, pos := range files {
 		if len(pos) != 2 {
 			log.Errorf("[checkpoint] unexpected position data: %s %v", file, pos)
@@ -251,22 +518,32 @@ func (cp *RemoteCheckPoint) allFilesFinished(files map[string][]int64) bool {
 	return true
 }
 
-// Init implements CheckPoint.Init
-func (cp *RemoteCheckPoint) Init(filename string, endPos int64) error {
+// parseDataFileName parses the db and table name from the data file name
+func parseDataFileName(filename string) (db, table string, err error) {
 	idx := strings.Index(filename, ".sql")
 	if idx < 0 {
-		return errors.Errorf("invalid db table sql file - %s", filename)
+		return "", "", errors.Errorf("invalid db table sql file - %s", filename)
 	}
 	fname := filename[:idx]
 	fields := strings.Split(fname, ".")
 	if len(fields) != 2 && len(fields) != 3 {
-		return errors.Errorf("invalid db table sql file - %s", filename)
+		return "", "", errors.Errorf("invalid db table sql file - %s", filename)
 	}
 
 	// fields[0] -> db name, fields[1] -> table name
+	return fields[0], fields[1], nil
+}
+
+// Init implements CheckPoint.Init
+func (cp *RemoteCheckPoint) Init(filename string, endPos int64) error {
+	db, table, err := parseDataFileName(filename)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
 	sql2 := fmt.Sprintf("INSERT INTO `%s`.`%s` (`id`, `filename`, `cp_schema`, `cp_table`, `offset`, `end_pos`) VALUES(?,?,?,?,?,?)", cp.schema, cp.table)
-	log.Debugf("[checkpoint] sql:%s, id:%s, filename:%s, cp_schema:%s, cp_table:%s, offset:%d, end_pos:%d", sql2, cp.id, filename, fields[0], fields[1], 0, endPos)
-	_, err := cp.conn.db.Exec(sql2, cp.id, filename, fields[0], fields[1], 0, endPos)
+	log.Debugf("[checkpoint] sql:%s, id:%s, filename:%s, cp_schema:%s, cp_table:%s, offset:%d, end_pos:%d", sql2, cp.id, filename, db, table, 0, endPos)
+	err = cp.exec.Exec(context.Background(), []string{sql2}, [][]interface{}{{cp.id, filename, db, table, 0, endPos}})
 	if err != nil {
 		if isErrDupEntry(err) {
 			log.Infof("[checkpoint] id:%s filename %s already exists, skip it.", cp.id, filename)
@@ -275,26 +552,255 @@ func (cp *RemoteCheckPoint) Init(filename string, endPos int64) error {
 		return errors.Annotatef(err, "initialize checkpoint")
 	}
 
+	if cp.batch > 0 {
+		cp.batchLock.Lock()
+		cp.points[filename] = &filePoint{Schema: db, Table: table, EndPos: endPos}
+		cp.batchLock.Unlock()
+	}
 	return errors.Trace(err)
 }
 
 // Close implements CheckPoint.Close
 func (cp *RemoteCheckPoint) Close() {
-	closeConn(cp.conn)
+	if err := cp.exec.Close(); err != nil {
+		log.Errorf("[checkpoint] close executor error %v", err)
+	}
 }
 
 // GenSQL implements CheckPoint.GenSQL
 func (cp *RemoteCheckPoint) GenSQL(filename string, offset int64) string {
+	if cp.batch > 0 {
+		return "" // saved by SaveBatch
+	}
+	return cp.genUpdateSQL(filename, offset)
+}
+
+func (cp *RemoteCheckPoint) genUpdateSQL(filename string, offset int64) string {
 	sql := fmt.Sprintf("UPDATE `%s`.`%s` SET `offset`=%d WHERE `id` ='%s' AND `filename`='%s';",
 		cp.schema, cp.table, offset, cp.id, filename)
 	return sql
 }
 
+// UpdateOffset implements CheckPoint.UpdateOffset
+func (cp *RemoteCheckPoint) UpdateOffset(filename string, offset int64) error {
+	if cp.batch > 0 {
+		return errors.Trace(cp.SaveBatch(filename, offset))
+	}
+	// updated by sql generated by GenSQL in the same transaction with the data job
+	return nil
+}
+
+// SaveBatch marks the checkpoint of the file dirty with the offset, it's reflected in GetRestoringFileInfo and GetAllRestoringFileInfo at once.
+// dirty checkpoints are saved in one transaction when the count of them reaches batch, or by Flush.
+func (cp *RemoteCheckPoint) SaveBatch(filename string, offset int64) error {
+	cp.batchLock.Lock()
+	defer cp.batchLock.Unlock()
+
+	point, ok := cp.points[filename]
+	if !ok {
+		db, table, err := parseDataFileName(filename)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		pos, ok := cp.restoringState.GetRestoringFileInfo(db, table)[filename]
+		if !ok || len(pos) != 2 {
+			return errors.NotFoundf("checkpoint of file %s", filename)
+		}
+		point = &filePoint{Schema: db, Table: table, Offset: pos[0], EndPos: pos[1]}
+		cp.points[filename] = point
+	}
+	// data jobs of a file may be finished out of order
+	if offset > point.Offset {
+		point.Offset = offset
+	}
+	cp.dirty[filename] = struct{}{}
+
+	if len(cp.dirty) >= cp.batch {
+		return errors.Trace(cp.flush())
+	}
+	return nil
+}
+
+// Flush implements CheckPoint.Flush
+func (cp *RemoteCheckPoint) Flush() error {
+	cp.batchLock.Lock()
+	defer cp.batchLock.Unlock()
+	return errors.Trace(cp.flush())
+}
+
+// flush saves all dirty checkpoints in one transaction, it should be called with batchLock held
+func (cp *RemoteCheckPoint) flush() error {
+	if len(cp.dirty) == 0 {
+		return nil
+	}
+	sqls := make([]string, 0, len(cp.dirty))
+	for filename := range cp.dirty {
+		sqls = append(sqls, cp.genUpdateSQL(filename, cp.points[filename].Offset))
+	}
+	if err := cp.exec.Exec(context.Background(), sqls, nil); err != nil {
+		return errors.Annotatef(err, "save %d checkpoints", len(sqls))
+	}
+	cp.dirty = make(map[string]struct{})
+	return nil
+}
+
+// GetRestoringFileInfo implements CheckPoint.GetRestoringFileInfo, checkpoints not saved yet are included.
+func (cp *RemoteCheckPoint) GetRestoringFileInfo(db, table string) map[string][]int64 {
+	results := cp.restoringState.GetRestoringFileInfo(db, table)
+	cp.batchLock.Lock()
+	defer cp.batchLock.Unlock()
+	if len(cp.points) == 0 {
+		return results
+	}
+
+	merged := make(map[string][]int64, len(results))
+	for file, pos := range results {
+		merged[file] = pos
+	}
+	for file, point := range cp.points {
+		if point.Schema == db && point.Table == table {
+			merged[file] = []int64{point.Offset, point.EndPos}
+		}
+	}
+	return merged
+}
+
+// GetAllRestoringFileInfo implements CheckPoint.GetAllRestoringFileInfo, checkpoints not saved yet are included.
+func (cp *RemoteCheckPoint) GetAllRestoringFileInfo() map[string][]int64 {
+	results := cp.restoringState.GetAllRestoringFileInfo()
+	cp.batchLock.Lock()
+	defer cp.batchLock.Unlock()
+	for file, point := range cp.points {
+		results[file] = []int64{point.Offset, point.EndPos}
+	}
+	return results
+}
+
 // Clear implements CheckPoint.Clear
 func (cp *RemoteCheckPoint) Clear() error {
+	cp.batchLock.Lock()
+	defer cp.batchLock.Unlock()
+	// the table is shared by loaders of all sources in the task, only checkpoints of this loader are deleted
 	sql2 := fmt.Sprintf("DELETE FROM `%s`.`%s` WHERE `id` = '%s'", cp.schema, cp.table, cp.id)
-	err := cp.conn.executeSQL([]string{sql2}, true)
-	return errors.Trace(err)
+	err := cp.exec.Exec(context.Background(), []string{sql2}, nil)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	cp.points = make(map[string]*filePoint)
+	cp.dirty = make(map[string]struct{})
+	cp.checksums = make(map[string]uint64)
+	return nil
+}
+
+// Prune implements CheckPoint.Prune, checkpoints of all files pruned are deleted in one transaction
+func (cp *RemoteCheckPoint) Prune(existingFiles []string) error {
+	cp.batchLock.Lock()
+	defer cp.batchLock.Unlock()
+
+	existing := toFileSet(existingFiles)
+	pruned := make(map[string]struct{})
+	for file := range cp.restoringState.GetAllRestoringFileInfo() {
+		if _, ok := existing[file]; !ok {
+			pruned[file] = struct{}{}
+		}
+	}
+	for file := range cp.points {
+		if _, ok := existing[file]; !ok {
+			pruned[file] = struct{}{}
+		}
+	}
+	if len(pruned) == 0 {
+		return nil
+	}
+
+	sqls := make([]string, 0, len(pruned))
+	args := make([][]interface{}, 0, len(pruned))
+	for file := range pruned {
+		sqls = append(sqls, fmt.Sprintf("DELETE FROM `%s`.`%s` WHERE `id` = ? AND `filename` = ?", cp.schema, cp.table))
+		args = append(args, []interface{}{cp.id, file})
+	}
+	if err := cp.exec.Exec(context.Background(), sqls, args); err != nil {
+		return errors.Annotatef(err, "prune %d checkpoints", len(sqls))
+	}
+	cp.restoringState.pruneRestoringFiles(existing)
+	for file := range pruned {
+		delete(cp.points, file)
+		delete(cp.dirty, file)
+	}
+	log.Infof("[checkpoint] pruned checkpoints of %d data files not existing", len(pruned))
+	return nil
+}
+
+// SaveChecksum implements CheckPoint.SaveChecksum
+func (cp *RemoteCheckPoint) SaveChecksum(filename string, checksum uint64) error {
+	sql2 := fmt.Sprintf("UPDATE `%s`.`%s` SET `checksum` = ? WHERE `id` = ? AND `filename` = ?", cp.schema, cp.table)
+	if err := cp.exec.Exec(context.Background(), []string{sql2}, [][]interface{}{{checksum, cp.id, filename}}); err != nil {
+		return errors.Annotatef(err, "save checksum of file %s", filename)
+	}
+	cp.batchLock.Lock()
+	cp.checksums[filename] = checksum
+	cp.batchLock.Unlock()
+	return nil
+}
+
+// GetTableChecksum implements CheckPoint.GetTableChecksum
+func (cp *RemoteCheckPoint) GetTableChecksum(db, table string) uint64 {
+	cp.batchLock.Lock()
+	defer cp.batchLock.Unlock()
+	return cp.restoringState.tableChecksum(db, table)
+}
+
+// Export implements CheckPoint.Export
+func (cp *RemoteCheckPoint) Export() ([]byte, error) {
+	cp.batchLock.Lock()
+	defer cp.batchLock.Unlock()
+
+	points := make(map[string]*filePoint)
+	for schema, tables := range cp.restoringFiles {
+		for table, files := range tables {
+			for file, pos := range files {
+				points[file] = &filePoint{Schema: schema, Table: table, Offset: pos[0], EndPos: pos[1]}
+			}
+		}
+	}
+	for file, point := range cp.points {
+		p := *point
+		points[file] = &p
+	}
+	for file, checksum := range cp.checksums {
+		if point, ok := points[file]; ok {
+			point.Checksum = checksum
+		}
+	}
+	return exportPoints(cp.id, points)
+}
+
+// Import implements CheckPoint.Import, all checkpoints are replaced in one transaction
+func (cp *RemoteCheckPoint) Import(data []byte) error {
+	points, err := importPoints(data)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	cp.batchLock.Lock()
+	defer cp.batchLock.Unlock()
+	sqls := make([]string, 0, len(points)+1)
+	args := make([][]interface{}, 0, len(points)+1)
+	sqls = append(sqls, fmt.Sprintf("DELETE FROM `%s`.`%s` WHERE `id` = ?", cp.schema, cp.table))
+	args = append(args, []interface{}{cp.id})
+	for filename, point := range points {
+		sqls = append(sqls, fmt.Sprintf("INSERT INTO `%s`.`%s` (`id`, `filename`, `cp_schema`, `cp_table`, `offset`, `end_pos`, `checksum`) VALUES(?,?,?,?,?,?,?)", cp.schema, cp.table))
+		args = append(args, []interface{}{cp.id, filename, point.Schema, point.Table, point.Offset, point.EndPos, point.Checksum})
+	}
+	if err = cp.exec.Exec(context.Background(), sqls, args); err != nil {
+		return errors.Annotatef(err, "import %d checkpoints", len(points))
+	}
+
+	cp.points = make(map[string]*filePoint)
+	cp.dirty = make(map[string]struct{})
+	cp.restoringState.resetRestoringFiles(points)
+	log.Infof("[checkpoint] imported checkpoints of %d data files", len(points))
+	return nil
 }
 
 // Count implements CheckPoint.Count
@@ -14,11 +14,22 @@
 package loader
 
 import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/go-sql-driver/mysql"
 	. "github.com/pingcap/check"
 	"github.com/pingcap/dm/dm/config"
+	"github.com/pingcap/errors"
+	tmysql "github.com/pingcap/parser/mysql"
+	"golang.org/x/net/context"
 )
 
 var _ = Suite(&testCheckPointSuite{})
@@ -56,6 +67,49 @@ func (t *testCheckPointSuite) SetUpSuite(c *C) {
 func (t *testCheckPointSuite) TearDownSuite(c *C) {
 }
 
+// fakeExecutor is an in-memory Executor, whose transactions fail with errs in order before succeeding
+type fakeExecutor struct {
+	sync.Mutex
+	errs   []error
+	txns   [][]string        // statements of executed transactions
+	values [][][]interface{} // values of executed transactions
+	closed bool
+}
+
+func (e *fakeExecutor) Exec(ctx context.Context, statements []string, values [][]interface{}) error {
+	e.Lock()
+	defer e.Unlock()
+	if len(e.errs) > 0 {
+		err := e.errs[0]
+		e.errs = e.errs[1:]
+		return err
+	}
+	e.txns = append(e.txns, statements)
+	e.values = append(e.values, values)
+	return nil
+}
+
+func (e *fakeExecutor) Close() error {
+	e.Lock()
+	defer e.Unlock()
+	e.closed = true
+	return nil
+}
+
+// newFakeRemoteCheckPoint creates a RemoteCheckPoint saving checkpoints by exec, which can't be loaded from DB
+func newFakeRemoteCheckPoint(exec Executor, id string, batch int) *RemoteCheckPoint {
+	return &RemoteCheckPoint{
+		restoringState: newRestoringState(),
+		exec:           exec,
+		id:             id,
+		schema:         "dm_meta",
+		table:          "test_loader_checkpoint",
+		batch:          batch,
+		points:         make(map[string]*filePoint),
+		dirty:          make(map[string]struct{}),
+	}
+}
+
 // test checkpoint's db operation
 func (t *testCheckPointSuite) TestForDB(c *C) {
 	cases := []struct {
@@ -114,7 +168,7 @@ func (t *testCheckPointSuite) TestForDB(c *C) {
 	defer closeConn(conn)
 	for _, cs := range cases {
 		sql2 := cp.GenSQL(cs.filename, cs.endPos)
-		err = conn.executeSQL([]string{sql2}, true)
+		err = conn.executeSQL(context.Background(), []string{sql2}, true)
 		c.Assert(err, IsNil)
 	}
 
@@ -150,3 +204,536 @@ func (t *testCheckPointSuite) TestForDB(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(count, Equals, 0)
 }
+
+// test checkpoint table created by previous versions is upgraded
+func (t *testCheckPointSuite) TestMigrateFromV1(c *C) {
+	cfg := *t.cfg
+	cfg.Name = "test_migrate"
+	id := "test_migrate"
+	tableName := fmt.Sprintf("`%s`.`%s_loader_checkpoint`", cfg.MetaSchema, cfg.Name)
+
+	conn, err := createConn(&cfg)
+	c.Assert(err, IsNil)
+	defer closeConn(conn)
+	// the table of schema version 1, without `schema_version`
+	createV1 := `CREATE TABLE %s (
+		id char(32) NOT NULL,
+		filename varchar(255) NOT NULL,
+		cp_schema varchar(128) NOT NULL,
+		cp_table varchar(128) NOT NULL,
+		offset bigint NOT NULL,
+		end_pos bigint NOT NULL,
+		create_time timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		update_time timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+		UNIQUE KEY uk_id_f (id,filename)
+	)`
+	c.Assert(conn.executeSQL(context.Background(), []string{
+		fmt.Sprintf("CREATE SCHEMA IF NOT EXISTS `%s`", cfg.MetaSchema),
+		fmt.Sprintf("DROP TABLE IF EXISTS %s", tableName),
+		fmt.Sprintf(createV1, tableName),
+		fmt.Sprintf("INSERT INTO %s (`id`, `filename`, `cp_schema`, `cp_table`, `offset`, `end_pos`) VALUES ('%s', 'db1.tbl1.sql', 'db1', 'tbl1', 10, 123)", tableName, id),
+	}, false), IsNil)
+
+	cp, err := newRemoteCheckPoint(&cfg, id)
+	c.Assert(err, IsNil)
+	defer cp.Close()
+	version, err := cp.(*RemoteCheckPoint).schemaVersion()
+	c.Assert(err, IsNil)
+	c.Assert(version, Equals, checkpointSchemaVersion)
+
+	// checkpoints saved before are still loadable
+	c.Assert(cp.Load(), IsNil)
+	c.Assert(cp.GetAllRestoringFileInfo(), DeepEquals, map[string][]int64{"db1.tbl1.sql": {10, 123}})
+	c.Assert(cp.Init("db1.tbl2.sql", 456), IsNil)
+	count, err := cp.Count()
+	c.Assert(err, IsNil)
+	c.Assert(count, Equals, 2)
+
+	// upgrading is idempotent
+	cp2, err := newRemoteCheckPoint(&cfg, id)
+	c.Assert(err, IsNil)
+	defer cp2.Close()
+	c.Assert(cp2.Load(), IsNil)
+	c.Assert(cp2.GetAllRestoringFileInfo(), HasLen, 2)
+
+	c.Assert(cp.Clear(), IsNil)
+	count, err = cp.Count()
+	c.Assert(err, IsNil)
+	c.Assert(count, Equals, 0)
+
+	// newer schema version is not supported
+	c.Assert(conn.executeSQL(context.Background(), []string{fmt.Sprintf("ALTER TABLE %s ALTER COLUMN `schema_version` SET DEFAULT %d", tableName, checkpointSchemaVersion+1)}, false), IsNil)
+	_, err = newRemoteCheckPoint(&cfg, id)
+	c.Assert(err, ErrorMatches, ".*schema version 3 newer than 2 not supported.*")
+	c.Assert(conn.executeSQL(context.Background(), []string{fmt.Sprintf("DROP TABLE %s", tableName)}, false), IsNil)
+}
+
+// test checkpoint table created by loaders of sources in the same task concurrently
+func (t *testCheckPointSuite) TestConcurrentCreate(c *C) {
+	cfg := *t.cfg
+	cfg.Name = "test_concurrent"
+	tableName := fmt.Sprintf("`%s`.`%s_loader_checkpoint`", cfg.MetaSchema, cfg.Name)
+
+	conn, err := createConn(&cfg)
+	c.Assert(err, IsNil)
+	defer closeConn(conn)
+	c.Assert(conn.executeSQL(context.Background(), []string{fmt.Sprintf("DROP TABLE IF EXISTS %s", tableName)}, false), IsNil)
+
+	var (
+		wg   sync.WaitGroup
+		cps  = make([]CheckPoint, 2)
+		errs = make([]error, 2)
+	)
+	for i := range cps {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			cps[i], errs[i] = newRemoteCheckPoint(&cfg, fmt.Sprintf("source-%d", i))
+		}(i)
+	}
+	wg.Wait()
+	for i, cp := range cps {
+		c.Assert(errs[i], IsNil)
+		defer cp.Close()
+		c.Assert(cp.Init("db1.tbl1.sql", 123), IsNil)
+	}
+
+	// only checkpoints of the loader are cleared
+	c.Assert(cps[0].Clear(), IsNil)
+	count, err := cps[0].Count()
+	c.Assert(err, IsNil)
+	c.Assert(count, Equals, 0)
+	count, err = cps[1].Count()
+	c.Assert(err, IsNil)
+	c.Assert(count, Equals, 1)
+	c.Assert(conn.executeSQL(context.Background(), []string{fmt.Sprintf("DROP TABLE %s", tableName)}, false), IsNil)
+}
+
+// test the checkpoint schema and table created concurrently without a database
+func (t *testCheckPointSuite) TestCreateIfNotExists(c *C) {
+	oldBase := retryBaseInterval
+	retryBaseInterval = time.Millisecond
+	defer func() {
+		retryBaseInterval = oldBase
+	}()
+
+	// the statement failed for the table created concurrently is executed again
+	exec := &fakeExecutor{errs: []error{&mysql.MySQLError{Number: tmysql.ErrTableExists, Message: "Table exists"}}}
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			cp := newFakeRemoteCheckPoint(exec, fmt.Sprintf("source-%d", i), 0)
+			c.Assert(cp.createSchema(), IsNil)
+			c.Assert(cp.createTable(), IsNil)
+		}(i)
+	}
+	wg.Wait()
+	c.Assert(exec.txns, HasLen, 4)
+
+	// other errors are not retried
+	exec = &fakeExecutor{errs: []error{&mysql.MySQLError{Number: tmysql.ErrDBaccessDenied, Message: "Access denied"}}}
+	cp := newFakeRemoteCheckPoint(exec, "source-0", 0)
+	c.Assert(cp.createSchema(), ErrorMatches, ".*Access denied.*")
+	c.Assert(exec.txns, HasLen, 0)
+
+	// at most maxCreateRetryCount times
+	exec = &fakeExecutor{}
+	for i := 0; i <= maxCreateRetryCount; i++ {
+		exec.errs = append(exec.errs, &mysql.MySQLError{Number: tmysql.ErrDBCreateExists, Message: "Database exists"})
+	}
+	cp = newFakeRemoteCheckPoint(exec, "source-0", 0)
+	c.Assert(cp.createSchema(), ErrorMatches, ".*Database exists.*")
+	c.Assert(exec.errs, HasLen, 1)
+}
+
+// test checkpoints loaded from a flapping database by the retry policy
+func (t *testCheckPointSuite) TestLoadRetry(c *C) {
+	db, err := sql.Open("loader-mock", "")
+	c.Assert(err, IsNil)
+	defer db.Close()
+	defer func() {
+		mockDrv.errs, mockDrv.queries, mockDrv.columns, mockDrv.rows = nil, 0, nil, nil
+	}()
+
+	cp := newFakeRemoteCheckPoint(&fakeExecutor{}, "source-0", 0)
+	cp.conn = &Conn{cfg: &config.SubTaskConfig{Name: "test-load-retry"}, db: db}
+	// no wait in tests
+	cp.retry = RetryPolicy{MaxRetries: 2, RetryableFunc: isRetryableError}
+	mockDrv.columns = []string{"filename", "cp_schema", "cp_table", "offset", "end_pos", "checksum"}
+	mockDrv.rows = [][]driver.Value{{"db.t.sql", "db", "t", int64(10), int64(100), int64(0)}}
+
+	// the connection broken twice, then loaded
+	mockDrv.errs = []error{mysql.ErrInvalidConn, mysql.ErrInvalidConn}
+	c.Assert(cp.Load(), IsNil)
+	c.Assert(mockDrv.queries, Equals, 3)
+	c.Assert(cp.GetRestoringFileInfo("db", "t"), DeepEquals, map[string][]int64{"db.t.sql": {10, 100}})
+
+	// the retries run out
+	mockDrv.queries = 0
+	mockDrv.errs = []error{mysql.ErrInvalidConn, mysql.ErrInvalidConn, mysql.ErrInvalidConn}
+	c.Assert(cp.Load(), ErrorMatches, ".*invalid connection.*")
+	c.Assert(mockDrv.queries, Equals, 3)
+
+	// errors not retryable fail fast
+	mockDrv.queries = 0
+	mockDrv.errs = []error{&mysql.MySQLError{Number: tmysql.ErrNoSuchTable, Message: "Table doesn't exist"}}
+	c.Assert(cp.Load(), ErrorMatches, ".*Table doesn't exist.*")
+	c.Assert(mockDrv.queries, Equals, 1)
+
+	// not retried by the policy
+	cp.retry = noRetry
+	mockDrv.queries = 0
+	mockDrv.errs = []error{mysql.ErrInvalidConn}
+	c.Assert(cp.Load(), ErrorMatches, ".*invalid connection.*")
+	c.Assert(mockDrv.queries, Equals, 1)
+}
+
+// test checkpoint saved without a database
+func (t *testCheckPointSuite) TestSaveByExecutor(c *C) {
+	exec := &fakeExecutor{}
+	cp := newFakeRemoteCheckPoint(exec, "test_executor", 0)
+
+	c.Assert(cp.prepare(), NotNil) // schema version can't be queried
+	c.Assert(exec.txns, HasLen, 2)
+	c.Assert(exec.txns[0][0], Equals, "CREATE SCHEMA IF NOT EXISTS `dm_meta`")
+	c.Assert(exec.txns[1][0], Matches, "(?s)CREATE TABLE IF NOT EXISTS `dm_meta`.`test_loader_checkpoint` .*")
+
+	// insert default checkpoints, and checkpoints existing are skipped
+	exec.txns, exec.values = nil, nil
+	c.Assert(cp.Init("db1.tbl1.sql", 123), IsNil)
+	exec.errs = []error{&mysql.MySQLError{Number: tmysql.ErrDupEntry, Message: "Duplicate entry"}}
+	c.Assert(cp.Init("db1.tbl1.sql", 123), IsNil)
+	exec.errs = []error{&mysql.MySQLError{Number: tmysql.ErrLockDeadlock, Message: "Deadlock"}}
+	c.Assert(cp.Init("db1.tbl2.sql", 456), ErrorMatches, ".*initialize checkpoint.*Deadlock.*")
+	c.Assert(cp.Init("invalid-file", 1), NotNil)
+	c.Assert(exec.txns, DeepEquals, [][]string{{"INSERT INTO `dm_meta`.`test_loader_checkpoint` (`id`, `filename`, `cp_schema`, `cp_table`, `offset`, `end_pos`) VALUES(?,?,?,?,?,?)"}})
+	c.Assert(exec.values, DeepEquals, [][][]interface{}{{{"test_executor", "db1.tbl1.sql", "db1", "tbl1", 0, int64(123)}}})
+
+	// saved in the same transaction with data, and cleared
+	c.Assert(cp.GenSQL("db1.tbl1.sql", 100), Equals, "UPDATE `dm_meta`.`test_loader_checkpoint` SET `offset`=100 WHERE `id` ='test_executor' AND `filename`='db1.tbl1.sql';")
+	c.Assert(cp.UpdateOffset("db1.tbl1.sql", 100), IsNil)
+	c.Assert(cp.Flush(), IsNil)
+	c.Assert(cp.Clear(), IsNil)
+	c.Assert(exec.txns, HasLen, 2)
+	c.Assert(exec.txns[1], DeepEquals, []string{"DELETE FROM `dm_meta`.`test_loader_checkpoint` WHERE `id` = 'test_executor'"})
+
+	cp.Close()
+	c.Assert(exec.closed, IsTrue)
+}
+
+func (t *testCheckPointSuite) TestSaveBatch(c *C) {
+	newCheckPoint := func(exec Executor, batch int) *RemoteCheckPoint {
+		cp := newFakeRemoteCheckPoint(exec, "test_batch", batch)
+		// loaded from DB
+		for i := 0; i < 100; i++ {
+			cp.addRestoringFile("db", "t1", fmt.Sprintf("db.t1.%d.sql", i), 0, 100)
+		}
+		return cp
+	}
+
+	exec := &fakeExecutor{}
+	cp := newCheckPoint(exec, 1000)
+	for i := 0; i < 100; i++ {
+		file := fmt.Sprintf("db.t1.%d.sql", i)
+		c.Assert(cp.GenSQL(file, 50), Equals, "")
+		c.Assert(cp.UpdateOffset(file, 50), IsNil)
+		// finished out of order
+		c.Assert(cp.SaveBatch(file, 40), IsNil)
+	}
+	c.Assert(exec.txns, HasLen, 0)
+
+	// not saved yet, but reflected in memory
+	infos := cp.GetAllRestoringFileInfo()
+	c.Assert(infos, HasLen, 100)
+	for file, pos := range infos {
+		c.Assert(pos, DeepEquals, []int64{50, 100}, Commentf("file %s", file))
+	}
+	for _, pos := range cp.GetRestoringFileInfo("db", "t1") {
+		c.Assert(pos, DeepEquals, []int64{50, 100})
+	}
+	c.Assert(cp.restoringState.GetRestoringFileInfo("db", "t1")["db.t1.0.sql"], DeepEquals, []int64{0, 100})
+
+	// all saved in one transaction
+	c.Assert(cp.Flush(), IsNil)
+	c.Assert(exec.txns, HasLen, 1)
+	c.Assert(exec.txns[0], HasLen, 100)
+	for _, sql := range exec.txns[0] {
+		c.Assert(strings.HasPrefix(sql, "UPDATE `dm_meta`.`test_loader_checkpoint` SET `offset`=50"), IsTrue, Commentf("sql %s", sql))
+	}
+	c.Assert(cp.Flush(), IsNil)
+	c.Assert(exec.txns, HasLen, 1)
+
+	// kept dirty if failed to save
+	c.Assert(cp.UpdateOffset("db.t1.0.sql", 60), IsNil)
+	exec.errs = []error{&mysql.MySQLError{Number: tmysql.ErrLockDeadlock, Message: "Deadlock"}}
+	c.Assert(cp.Flush(), ErrorMatches, ".*save 1 checkpoints.*")
+	c.Assert(cp.Flush(), IsNil)
+	c.Assert(exec.txns, HasLen, 2)
+	c.Assert(exec.txns[1], HasLen, 1)
+
+	// saved when the count of dirty checkpoints reaches batch
+	exec = &fakeExecutor{}
+	cp = newCheckPoint(exec, 10)
+	for i := 0; i < 25; i++ {
+		c.Assert(cp.UpdateOffset(fmt.Sprintf("db.t1.%d.sql", i), 100), IsNil)
+	}
+	c.Assert(exec.txns, HasLen, 2)
+	c.Assert(cp.Flush(), IsNil)
+	c.Assert(exec.txns, HasLen, 3)
+	c.Assert(exec.txns[2], HasLen, 5)
+	c.Assert(cp.UpdateOffset("db.t1.not-exist.sql", 1), ErrorMatches, ".*not found.*")
+
+	// saved in the same transaction with data if not batched
+	exec = &fakeExecutor{}
+	cp = newCheckPoint(exec, 0)
+	c.Assert(cp.GenSQL("db.t1.0.sql", 10), Matches, "UPDATE .* SET `offset`=10 .*")
+	c.Assert(cp.UpdateOffset("db.t1.0.sql", 10), IsNil)
+	c.Assert(cp.GetAllRestoringFileInfo()["db.t1.0.sql"], DeepEquals, []int64{0, 100})
+	c.Assert(exec.txns, HasLen, 0)
+}
+
+func (t *testCheckPointSuite) TestPrune(c *C) {
+	existing := []string{"db.t1.sql", "db.t2.sql"}
+
+	// remote checkpoint
+	exec := &fakeExecutor{}
+	cp := newFakeRemoteCheckPoint(exec, "test_prune", 10)
+	// loaded from DB, t1 is restoring and t2 is finished, t3 is an orphan of a previous dump
+	cp.addRestoringFile("db", "t1", "db.t1.sql", 50, 100)
+	cp.addRestoringFile("db", "t2", "db.t2.sql", 100, 100)
+	cp.addRestoringFile("db", "t3", "db.t3.sql", 0, 100)
+	c.Assert(cp.UpdateOffset("db.t3.sql", 10), IsNil)
+
+	c.Assert(cp.Prune(existing), IsNil)
+	c.Assert(exec.txns, DeepEquals, [][]string{{"DELETE FROM `dm_meta`.`test_loader_checkpoint` WHERE `id` = ? AND `filename` = ?"}})
+	c.Assert(exec.values, DeepEquals, [][][]interface{}{{{"test_prune", "db.t3.sql"}}})
+	c.Assert(cp.GetAllRestoringFileInfo(), DeepEquals, map[string][]int64{"db.t1.sql": {50, 100}, "db.t2.sql": {100, 100}})
+	c.Assert(cp.restoringFiles, HasLen, 1)
+	c.Assert(cp.restoringFiles["db"], HasLen, 2)
+	// the orphan is not saved again
+	c.Assert(cp.Flush(), IsNil)
+	c.Assert(exec.txns, HasLen, 1)
+
+	// nothing to prune
+	c.Assert(cp.Prune(existing), IsNil)
+	c.Assert(exec.txns, HasLen, 1)
+
+	// kept if failed to delete
+	cp.addRestoringFile("db", "t4", "db.t4.sql", 0, 100)
+	exec.errs = []error{errors.New("connection refused")}
+	c.Assert(cp.Prune(existing), ErrorMatches, ".*prune 1 checkpoints.*")
+	c.Assert(cp.GetAllRestoringFileInfo(), HasLen, 3)
+	c.Assert(cp.Prune(existing), IsNil)
+	c.Assert(exec.txns, HasLen, 2)
+	c.Assert(exec.values[1], DeepEquals, [][]interface{}{{"test_prune", "db.t4.sql"}})
+	c.Assert(cp.GetAllRestoringFileInfo(), HasLen, 2)
+
+	// file checkpoint
+	path := filepath.Join(c.MkDir(), "checkpoint.json")
+	fcp, err := newFileCheckPoint(path, "test_prune")
+	c.Assert(err, IsNil)
+	for _, file := range []string{"db.t1.sql", "db.t2.sql", "db.t3.sql"} {
+		c.Assert(fcp.Init(file, 100), IsNil)
+	}
+	c.Assert(fcp.UpdateOffset("db.t1.sql", 50), IsNil)
+	c.Assert(fcp.Load(), IsNil)
+	c.Assert(fcp.Prune(existing), IsNil)
+	c.Assert(fcp.Prune(existing), IsNil)
+	c.Assert(fcp.GetAllRestoringFileInfo(), DeepEquals, map[string][]int64{"db.t1.sql": {50, 100}, "db.t2.sql": {0, 100}})
+
+	// pruned in the file
+	fcp2, err := newFileCheckPoint(path, "test_prune")
+	c.Assert(err, IsNil)
+	c.Assert(fcp2.Load(), IsNil)
+	c.Assert(fcp2.GetAllRestoringFileInfo(), DeepEquals, map[string][]int64{"db.t1.sql": {50, 100}, "db.t2.sql": {0, 100}})
+}
+
+// test checkpoint saved in local file
+func (t *testCheckPointSuite) TestForFile(c *C) {
+	cases := []struct {
+		filename string
+		endPos   int64
+	}{
+		{"db1.tbl1.sql", 123},
+		{"db1.tbl2.sql", 456},
+		{"db1.tbl3.sql", 789},
+	}
+
+	id := "test_for_file"
+	path := filepath.Join(c.MkDir(), "checkpoint.json")
+	cp, err := newFileCheckPoint(path, id)
+	c.Assert(err, IsNil)
+	defer cp.Close()
+
+	// no checkpoint exist
+	err = cp.Load()
+	c.Assert(err, IsNil)
+
+	infos := cp.GetAllRestoringFileInfo()
+	c.Assert(len(infos), Equals, 0)
+
+	count, err := cp.Count()
+	c.Assert(err, IsNil)
+	c.Assert(count, Equals, 0)
+
+	// insert default checkpoints
+	for _, cs := range cases {
+		err = cp.Init(cs.filename, cs.endPos)
+		c.Assert(err, IsNil)
+	}
+	c.Assert(cp.Init("invalid-file", 1), NotNil)
+
+	err = cp.Load()
+	c.Assert(err, IsNil)
+
+	infos = cp.GetAllRestoringFileInfo()
+	c.Assert(len(infos), Equals, len(cases))
+	for _, cs := range cases {
+		info, ok := infos[cs.filename]
+		c.Assert(ok, IsTrue)
+		c.Assert(info, DeepEquals, []int64{0, cs.endPos})
+	}
+	c.Assert(cp.GetRestoringFileInfo("db1", "tbl1"), HasLen, 1)
+
+	count, err = cp.Count()
+	c.Assert(err, IsNil)
+	c.Assert(count, Equals, len(cases))
+
+	// update checkpoints
+	for _, cs := range cases {
+		c.Assert(cp.GenSQL(cs.filename, cs.endPos), Equals, "")
+		err = cp.UpdateOffset(cs.filename, cs.endPos)
+		c.Assert(err, IsNil)
+	}
+	c.Assert(cp.UpdateOffset("db1.tbl4.sql", 1), NotNil)
+
+	// reload from the file by another checkpoint
+	cp2, err := newFileCheckPoint(path, id)
+	c.Assert(err, IsNil)
+	err = cp2.Load()
+	c.Assert(err, IsNil)
+
+	infos = cp2.GetAllRestoringFileInfo()
+	c.Assert(len(infos), Equals, len(cases))
+	for _, cs := range cases {
+		info, ok := infos[cs.filename]
+		c.Assert(ok, IsTrue)
+		c.Assert(info, DeepEquals, []int64{cs.endPos, cs.endPos})
+	}
+
+	allFiles := map[string]Tables2DataFiles{
+		"db1": {
+			"tbl1": {"db1.tbl1.sql"},
+			"tbl2": {"db1.tbl2.sql"},
+			"tbl3": {"db1.tbl3.sql"},
+		},
+	}
+	c.Assert(cp2.CalcProgress(allFiles), IsNil)
+	c.Assert(cp2.IsTableFinished("db1", "tbl1"), IsTrue)
+
+	count, err = cp2.Count()
+	c.Assert(err, IsNil)
+	c.Assert(count, Equals, len(cases))
+
+	// checkpoint file of another task
+	_, err = newFileCheckPoint(path, "another_id")
+	c.Assert(err, ErrorMatches, ".*belongs to.*")
+
+	// clear all
+	err = cp.Clear()
+	c.Assert(err, IsNil)
+
+	// no checkpoint exist
+	err = cp.Load()
+	c.Assert(err, IsNil)
+
+	infos = cp.GetAllRestoringFileInfo()
+	c.Assert(len(infos), Equals, 0)
+
+	// obtain count again
+	count, err = cp.Count()
+	c.Assert(err, IsNil)
+	c.Assert(count, Equals, 0)
+
+	// reload after clear
+	cp2, err = newFileCheckPoint(path, id)
+	c.Assert(err, IsNil)
+	count, err = cp2.Count()
+	c.Assert(err, IsNil)
+	c.Assert(count, Equals, 0)
+}
+
+func (t *testCheckPointSuite) TestExportImport(c *C) {
+	path := filepath.Join(c.MkDir(), "checkpoint.json")
+	cp, err := newFileCheckPoint(path, "test_export")
+	c.Assert(err, IsNil)
+
+	// finished, restored partly (resumed from the middle) and not started
+	c.Assert(cp.Init("db1.tbl1.sql", 123), IsNil)
+	c.Assert(cp.UpdateOffset("db1.tbl1.sql", 123), IsNil)
+	c.Assert(cp.SaveChecksum("db1.tbl1.sql", 0xabc), IsNil)
+	c.Assert(cp.Init("db1.tbl2.1.sql", 456), IsNil)
+	c.Assert(cp.UpdateOffset("db1.tbl2.1.sql", 200), IsNil)
+	c.Assert(cp.Init("db2.tbl1.sql", 789), IsNil)
+	c.Assert(cp.Load(), IsNil)
+	expected := cp.GetAllRestoringFileInfo()
+	c.Assert(expected, DeepEquals, map[string][]int64{
+		"db1.tbl1.sql":   {123, 123},
+		"db1.tbl2.1.sql": {200, 456},
+		"db2.tbl1.sql":   {0, 789},
+	})
+
+	data, err := cp.Export()
+	c.Assert(err, IsNil)
+	c.Assert(cp.Clear(), IsNil)
+	c.Assert(cp.Load(), IsNil)
+	c.Assert(cp.GetAllRestoringFileInfo(), HasLen, 0)
+
+	// imported into a checkpoint of another ID, like the dump is in another directory on the new node
+	cp, err = newFileCheckPoint(path, "test_import")
+	c.Assert(err, IsNil)
+	c.Assert(cp.Import(data), IsNil)
+	c.Assert(cp.GetAllRestoringFileInfo(), DeepEquals, expected)
+	c.Assert(cp.GetTableChecksum("db1", "tbl1"), Equals, uint64(0xabc))
+	// saved in the file
+	cp2, err := newFileCheckPoint(path, "test_import")
+	c.Assert(err, IsNil)
+	c.Assert(cp2.Load(), IsNil)
+	c.Assert(cp2.GetAllRestoringFileInfo(), DeepEquals, expected)
+
+	// imported into the downstream in one transaction, replacing existing checkpoints
+	exec := &fakeExecutor{}
+	rcp := newFakeRemoteCheckPoint(exec, "test_import", 1)
+	c.Assert(rcp.Init("db3.tbl1.sql", 10), IsNil)
+	c.Assert(rcp.UpdateOffset("db3.tbl1.sql", 5), IsNil)
+	exec.txns, exec.values = nil, nil
+	c.Assert(rcp.Import(data), IsNil)
+	c.Assert(exec.txns, HasLen, 1)
+	c.Assert(exec.txns[0], HasLen, 4)
+	c.Assert(exec.txns[0][0], Equals, "DELETE FROM `dm_meta`.`test_loader_checkpoint` WHERE `id` = ?")
+	c.Assert(exec.values[0][0], DeepEquals, []interface{}{"test_import"})
+	inserted := make(map[string][]interface{})
+	for i, sql := range exec.txns[0][1:] {
+		c.Assert(sql, Equals, "INSERT INTO `dm_meta`.`test_loader_checkpoint` (`id`, `filename`, `cp_schema`, `cp_table`, `offset`, `end_pos`, `checksum`) VALUES(?,?,?,?,?,?,?)")
+		inserted[exec.values[0][i+1][1].(string)] = exec.values[0][i+1]
+	}
+	c.Assert(inserted["db1.tbl2.1.sql"], DeepEquals, []interface{}{"test_import", "db1.tbl2.1.sql", "db1", "tbl2", int64(200), int64(456), uint64(0)})
+	c.Assert(inserted["db1.tbl1.sql"][6], Equals, uint64(0xabc))
+	c.Assert(rcp.GetAllRestoringFileInfo(), DeepEquals, expected)
+	c.Assert(rcp.GetTableChecksum("db1", "tbl1"), Equals, uint64(0xabc))
+
+	// exported again with the same checkpoints
+	data2, err := rcp.Export()
+	c.Assert(err, IsNil)
+	c.Assert(cp.Import(data2), IsNil)
+	c.Assert(cp.GetAllRestoringFileInfo(), DeepEquals, expected)
+
+	// invalid exported checkpoints
+	c.Assert(cp.Import([]byte("{")), ErrorMatches, ".*decode exported checkpoints.*")
+	c.Assert(cp.Import([]byte(`{"files":{"invalid":{}}}`)), NotNil)
+	c.Assert(cp.Import([]byte(`{"files":{"db1.tbl1.sql":{"cp-schema":"db1","cp-table":"tbl1","offset":2,"end-pos":1}}}`)), ErrorMatches, ".*not valid.*")
+	c.Assert(cp.Import([]byte(`{"files":{"db1.tbl1.sql":{"cp-schema":"db2","cp-table":"tbl1","offset":0,"end-pos":1}}}`)), ErrorMatches, ".*not valid.*")
+	c.Assert(cp.GetAllRestoringFileInfo(), DeepEquals, expected)
+}
@@ -0,0 +1,91 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loader
+
+import (
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pingcap/dm/pkg/utils"
+	"github.com/pingcap/errors"
+)
+
+// suffixes of compressed data files, like `db.table.sql.gz`
+const (
+	gzipSuffix = ".gz"
+	zstdSuffix = ".zst"
+)
+
+/* Positions of a compressed data file
+ * the offset and end_pos recorded in checkpoint, and the restoring progress of a compressed data file
+ * are all counted in decompressed bytes, so they keep the same semantics as a plain data file.
+ * a compressed file can not seek, so resuming from an offset needs to decompress and discard the data before it.
+ */
+
+// isCompressedFile checks whether the data file is compressed by its suffix
+func isCompressedFile(file string) bool {
+	return strings.HasSuffix(file, gzipSuffix) || strings.HasSuffix(file, zstdSuffix)
+}
+
+// trimCompressedSuffix trims the compression suffix of the data file
+func trimCompressedSuffix(file string) string {
+	for _, suffix := range []string{gzipSuffix, zstdSuffix} {
+		if strings.HasSuffix(file, suffix) {
+			return file[:len(file)-len(suffix)]
+		}
+	}
+	return file
+}
+
+// newDecompressReader returns a reader of the decompressed data of the data file
+func newDecompressReader(file string, r io.Reader) (io.ReadCloser, error) {
+	switch {
+	case strings.HasSuffix(file, gzipSuffix):
+		gr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, errors.Annotatef(err, "open gzip file %s", file)
+		}
+		return gr, nil
+	case strings.HasSuffix(file, zstdSuffix):
+		// data files are decompressed one by one in every worker, so the decoder doesn't decode blocks concurrently
+		zr, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, errors.Annotatef(err, "open zstd file %s", file)
+		}
+		return zr.IOReadCloser(), nil
+	default:
+		return ioutil.NopCloser(r), nil
+	}
+}
+
+// getDataFileSize returns the size of the data file, the file is in archive if it's not nil.
+// for a compressed or archived file, it's the decompressed size which is counted by decompressing the whole file.
+func getDataFileSize(archive *dumpArchive, file string) (int64, error) {
+	if archive == nil && !isCompressedFile(file) {
+		size, err := utils.GetFileSize(file)
+		return size, errors.Trace(err)
+	}
+
+	r, err := openDataFile(archive, file)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	defer r.Close()
+
+	size, err := io.Copy(ioutil.Discard, r)
+	return size, errors.Annotatef(err, "decompress data file %s", file)
+}
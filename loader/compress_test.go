@@ -0,0 +1,187 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loader
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/klauspost/compress/zstd"
+	. "github.com/pingcap/check"
+	"github.com/pingcap/dm/dm/config"
+	"github.com/pingcap/tidb-tools/pkg/filter"
+	"golang.org/x/net/context"
+)
+
+var _ = Suite(&testCompressSuite{})
+
+type testCompressSuite struct{}
+
+// memCheckPoint is a CheckPoint only records the initialized end positions in memory
+type memCheckPoint struct {
+	endPos map[string]int64
+}
+
+func newMemCheckPoint() *memCheckPoint {
+	return &memCheckPoint{endPos: make(map[string]int64)}
+}
+
+func (cp *memCheckPoint) Load() error { return nil }
+func (cp *memCheckPoint) GetRestoringFileInfo(db, table string) map[string][]int64 {
+	return make(map[string][]int64)
+}
+func (cp *memCheckPoint) GetAllRestoringFileInfo() map[string][]int64 {
+	return make(map[string][]int64)
+}
+func (cp *memCheckPoint) IsTableFinished(db, table string) bool                   { return false }
+func (cp *memCheckPoint) CalcProgress(allFiles map[string]Tables2DataFiles) error { return nil }
+func (cp *memCheckPoint) Init(filename string, endPos int64) error {
+	cp.endPos[filename] = endPos
+	return nil
+}
+func (cp *memCheckPoint) Close()                                      {}
+func (cp *memCheckPoint) Clear() error                                { return nil }
+func (cp *memCheckPoint) Count() (int, error)                         { return len(cp.endPos), nil }
+func (cp *memCheckPoint) GenSQL(filename string, offset int64) string { return "" }
+func (cp *memCheckPoint) UpdateOffset(filename string, offset int64) error {
+	return nil
+}
+
+func (cp *memCheckPoint) Flush() error {
+	return nil
+}
+
+func (cp *memCheckPoint) Prune(existingFiles []string) error {
+	return nil
+}
+
+func (cp *memCheckPoint) SaveChecksum(filename string, checksum uint64) error { return nil }
+func (cp *memCheckPoint) GetTableChecksum(db, table string) uint64            { return 0 }
+func (cp *memCheckPoint) Export() ([]byte, error)                             { return nil, nil }
+func (cp *memCheckPoint) Import(data []byte) error                            { return nil }
+
+func writeGzipFile(c *C, path string, data []byte) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write(data)
+	c.Assert(err, IsNil)
+	c.Assert(w.Close(), IsNil)
+	c.Assert(ioutil.WriteFile(path, buf.Bytes(), 0644), IsNil)
+}
+
+func writeZstdFile(c *C, path string, data []byte) {
+	var buf bytes.Buffer
+	w, err := zstd.NewWriter(&buf)
+	c.Assert(err, IsNil)
+	_, err = w.Write(data)
+	c.Assert(err, IsNil)
+	c.Assert(w.Close(), IsNil)
+	c.Assert(ioutil.WriteFile(path, buf.Bytes(), 0644), IsNil)
+}
+
+func (t *testCompressSuite) TestRestoreGzipFile(c *C) {
+	testRestoreCompressedFile(c, "db.t1.sql.gz", writeGzipFile)
+}
+
+func (t *testCompressSuite) TestRestoreZstdFile(c *C) {
+	testRestoreCompressedFile(c, "db.t1.sql.zst", writeZstdFile)
+}
+
+// testRestoreCompressedFile restores the data file compressed by write from the beginning and resumes it from an offset
+func testRestoreCompressedFile(c *C, file string, write func(c *C, path string, data []byte)) {
+	var (
+		dir   = c.MkDir()
+		stmt1 = "INSERT INTO `t1` VALUES (1),(2);\n"
+		stmt2 = "INSERT INTO `t1` VALUES\n(3),\n(4);\n"
+		data  = stmt1 + stmt2
+	)
+	c.Assert(ioutil.WriteFile(filepath.Join(dir, "db-schema-create.sql"), []byte("CREATE DATABASE `db`;"), 0644), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(dir, "db.t1-schema.sql"), []byte("CREATE TABLE `t1` (`id` INT PRIMARY KEY);"), 0644), IsNil)
+	write(c, filepath.Join(dir, file), []byte(data))
+
+	size, err := getDataFileSize(nil, filepath.Join(dir, file))
+	c.Assert(err, IsNil)
+	c.Assert(size, Equals, int64(len(data)))
+
+	cfg := config.NewSubTaskConfig()
+	cfg.Dir = dir
+	l := NewLoader(cfg)
+	l.bwList = filter.New(false, nil)
+	c.Assert(l.prepare(), IsNil)
+	c.Assert(l.db2Tables["db"]["t1"], DeepEquals, DataFiles{file})
+	c.Assert(l.totalDataSize.Get(), Equals, int64(len(data)))
+
+	cp := newMemCheckPoint()
+	w := &Worker{
+		cfg:        cfg,
+		checkPoint: cp,
+		jobQueue:   make(chan *dataJob, 16),
+		loader:     l,
+	}
+	table := &tableInfo{sourceSchema: "db", sourceTable: "t1", targetSchema: "db", targetTable: "t1"}
+	collectJobs := func() []*dataJob {
+		close(w.jobQueue)
+		jobs := make([]*dataJob, 0, 2)
+		for job := range w.jobQueue {
+			jobs = append(jobs, job)
+		}
+		w.jobQueue = make(chan *dataJob, 16)
+		return jobs
+	}
+
+	// restore from the beginning
+	c.Assert(w.dispatchSQL(context.Background(), filepath.Join(dir, file), 0, table), IsNil)
+	c.Assert(cp.endPos[file], Equals, int64(len(data)))
+	jobs := collectJobs()
+	c.Assert(jobs, HasLen, 2)
+	c.Assert(jobs[0].sql, Equals, "INSERT INTO `t1` VALUES (1),(2);")
+	c.Assert(jobs[0].lastOffset, Equals, int64(0))
+	c.Assert(jobs[0].offset, Equals, int64(len(stmt1)))
+	c.Assert(jobs[1].sql, Equals, "INSERT INTO `t1` VALUES\n(3),\n(4);")
+	c.Assert(jobs[1].lastOffset, Equals, int64(len(stmt1)))
+	// the checkpoint advances to the end position in decompressed bytes
+	c.Assert(jobs[1].offset, Equals, cp.endPos[file])
+
+	// resume from the checkpoint after the first statement
+	c.Assert(w.dispatchSQL(context.Background(), filepath.Join(dir, file), int64(len(stmt1)), table), IsNil)
+	jobs = collectJobs()
+	c.Assert(jobs, HasLen, 1)
+	c.Assert(jobs[0].sql, Equals, "INSERT INTO `t1` VALUES\n(3),\n(4);")
+	c.Assert(jobs[0].lastOffset, Equals, int64(len(stmt1)))
+	c.Assert(jobs[0].offset, Equals, int64(len(data)))
+
+	for _, job := range jobs {
+		l.finishJob(job)
+	}
+	c.Assert(l.finishedDataSize.Get(), Equals, int64(len(stmt2)))
+}
+
+func (t *testCompressSuite) TestTrimCompressedSuffix(c *C) {
+	cases := []struct {
+		file       string
+		trimmed    string
+		compressed bool
+	}{
+		{"db.t1.sql", "db.t1.sql", false},
+		{"db.t1.0001.sql.gz", "db.t1.0001.sql", true},
+		{"db.t1.sql.zst", "db.t1.sql", true},
+		{"db.t1-schema.sql", "db.t1-schema.sql", false},
+	}
+	for _, cs := range cases {
+		c.Assert(trimCompressedSuffix(cs.file), Equals, cs.trimmed)
+		c.Assert(isCompressedFile(cs.file), Equals, cs.compressed)
+	}
+}
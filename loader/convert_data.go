@@ -37,7 +37,8 @@ func bytes2str(bs []byte) string {
 // learn from tidb-lightning and refactor it as format of mydumper file
 // https://github.com/maxbube/mydumper/blob/master/mydumper.c#L2853
 // later let it a package
-func parseInsertStmt(sql []byte, table *tableInfo, columnMapping *cm.Mapping) ([][]string, error) {
+// rows not matched by the row filter are dropped, filter is nil if rows are not filtered.
+func parseInsertStmt(sql []byte, table *tableInfo, columnMapping *cm.Mapping, filter *rowFilter) ([][]string, error) {
 	var s, e, size int
 	var rows = make([][]string, 0, 1024)
 
@@ -63,11 +64,13 @@ func parseInsertStmt(sql []byte, table *tableInfo, columnMapping *cm.Mapping) ([
 
 		rp := e - 2
 		// extract columns' values
-		row, err := parseRowValues(sql[s+1:rp], table, columnMapping)
+		row, err := parseRowValues(sql[s+1:rp], table, columnMapping, filter)
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
-		rows = append(rows, row)
+		if row != nil {
+			rows = append(rows, row)
+		}
 
 		s = e + 1
 		if s >= size {
@@ -78,7 +81,8 @@ func parseInsertStmt(sql []byte, table *tableInfo, columnMapping *cm.Mapping) ([
 	return rows, nil
 }
 
-func parseRowValues(str []byte, table *tableInfo, columnMapping *cm.Mapping) ([]string, error) {
+// parseRowValues returns nil if the row is not matched by the row filter
+func parseRowValues(str []byte, table *tableInfo, columnMapping *cm.Mapping, filter *rowFilter) ([]string, error) {
 	// values are seperated by comma, but we can not split using comma directly
 	// string is enclosed by single quote
 
@@ -137,6 +141,17 @@ func parseRowValues(str []byte, table *tableInfo, columnMapping *cm.Mapping) ([]
 		}
 	}
 
+	// rows are filtered by values as they are dumped
+	if filter != nil {
+		ok, err := filter.match(values, isChars)
+		if err != nil {
+			return nil, errors.Annotatef(err, "table %s.%s", table.sourceSchema, table.sourceTable)
+		}
+		if !ok {
+			return nil, nil
+		}
+	}
+
 	if columnMapping != nil {
 		var err error
 		values, _, err = columnMapping.HandleRowValue(table.sourceSchema, table.sourceTable, table.columnNameList, values)
@@ -181,6 +196,7 @@ func ExportStatement(sqlFile string) ([]byte, error) {
 
 	data := make([]byte, 0, f.Size()+1)
 	buffer := make([]byte, 0, f.Size()+1)
+	inDelimiterBlock := false
 	for {
 		line, err := br.ReadString('\n')
 		if errors.Cause(err) == io.EOF {
@@ -192,6 +208,14 @@ func ExportStatement(sqlFile string) ([]byte, error) {
 			continue
 		}
 
+		// stored routines and triggers in `DELIMITER` blocks are not the structure of the table
+		if delimiter, ok := parseDelimiterCommand(line); ok {
+			inDelimiterBlock = delimiter != defaultDelimiter
+			continue
+		} else if inDelimiterBlock {
+			continue
+		}
+
 		buffer = append(buffer, []byte(line)...)
 		if buffer[len(buffer)-1] == ';' {
 			statment := string(buffer)
@@ -253,11 +277,15 @@ func parseTable(r *router.Table, schema, table, file string) (*tableInfo, error)
 }
 
 // refine it later
-func reassemble(data []byte, table *tableInfo, columnMapping *cm.Mapping) (string, error) {
-	rows, err := parseInsertStmt(data, table, columnMapping)
+func reassemble(data []byte, table *tableInfo, columnMapping *cm.Mapping, filter *rowFilter) (string, error) {
+	rows, err := parseInsertStmt(data, table, columnMapping, filter)
 	if err != nil {
 		return "", errors.Trace(err)
 	}
+	if len(rows) == 0 {
+		// all rows are dropped by the row filter
+		return "", nil
+	}
 
 	query := bytes.NewBuffer(make([]byte, 0, len(data)))
 	fmt.Fprint(query, table.insertHeadStmt)
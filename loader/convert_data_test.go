@@ -82,7 +82,7 @@ func (t *testConvertDataSuite) TestReassemble(c *C) {
 		columnMapping, err := cm.NewMapping(false, []*cm.Rule{r})
 		c.Assert(err, IsNil)
 
-		query, err := reassemble([]byte(sql), table, columnMapping)
+		query, err := reassemble([]byte(sql), table, columnMapping, nil)
 		c.Assert(err, IsNil)
 		c.Assert(expected[i], Equals, query)
 	}
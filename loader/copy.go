@@ -0,0 +1,96 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loader
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/pingcap/errors"
+	"golang.org/x/net/context"
+
+	"github.com/pingcap/dm/dm/config"
+	"github.com/pingcap/dm/pkg/log"
+)
+
+// isSameServer returns whether the source and target of the task are the same server, by their hosts and ports
+func isSameServer(cfg *config.SubTaskConfig) bool {
+	return strings.EqualFold(cfg.From.Host, cfg.To.Host) && cfg.From.Port == cfg.To.Port
+}
+
+// canCopyTable returns whether the data file of the table is restored by copying rows of the source table, see config.LoaderConfig.SameServerCopy.
+// the table must be dumped into one data file, which isn't restored partially, and the checkpoint of the file is moved to its end
+// in the same transaction of copying, so the file is either restored completely or not at all.
+func (l *Loader) canCopyTable(table *tableInfo, dataFiles int, offset int64) bool {
+	if !l.cfg.SameServerCopy || !isSameServer(l.cfg) || l.columnMapping != nil {
+		return false
+	}
+	// rows of tables with row filters are filtered when parsed
+	if filter, err := l.rowFilters.forTable(table); err != nil || filter != nil {
+		return false
+	}
+	if dataFiles != 1 || offset != 0 {
+		return false
+	}
+	// rows can't be copied to the source table itself
+	return table.sourceSchema != table.targetSchema || table.sourceTable != table.targetTable
+}
+
+// copyTableSQL returns the statement copying all rows of the source table to the target table in the same server
+func copyTableSQL(table *tableInfo) string {
+	return fmt.Sprintf("INSERT INTO `%s`.`%s` SELECT * FROM `%s`.`%s`;", table.targetSchema, table.targetTable, table.sourceSchema, table.sourceTable)
+}
+
+// copyDataFile restores the data file by copying rows of the source table in one job rather than restoring rows in the file,
+// the file is only scanned for the count and checksum of its rows, which are recorded like restoring it.
+func (w *Worker) copyDataFile(ctx context.Context, path, dataFile string, offset int64, table *tableInfo) error {
+	log.Infof("[loader][copy table data]%s/%s[start]", path, dataFile)
+	file := filepath.Join(path, dataFile)
+	baseFile := filepath.Base(file)
+	stats, err := scanDataFileRows(w.loader.archive, file, 0)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if err = w.checkPoint.Init(baseFile, stats.size); err != nil {
+		return errors.Trace(err)
+	}
+
+	j := &dataJob{
+		sql:        copyTableSQL(table),
+		schema:     table.targetSchema,
+		file:       baseFile,
+		offset:     stats.size,
+		lastOffset: offset,
+		fileSize:   stats.size,
+		rows:       stats.rows,
+		progress:   w.loader.getTableProgress(table.sourceSchema, table.sourceTable),
+		checksum:   stats.checksum,
+	}
+	if w.quota.acquire(ctx, int64(len(j.sql))) != nil {
+		return nil
+	}
+	select {
+	case <-ctx.Done():
+		return nil
+	case w.jobQueue <- j:
+	}
+
+	if !w.waitJobs(ctx) {
+		log.Infof("[loader][copy table data]%s/%s[stopped]", path, dataFile)
+		return nil
+	}
+	log.Infof("[loader][copy table data]%s/%s[finished]", path, dataFile)
+	return nil
+}
@@ -0,0 +1,112 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loader
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+
+	. "github.com/pingcap/check"
+	cm "github.com/pingcap/tidb-tools/pkg/column-mapping"
+	"golang.org/x/net/context"
+
+	"github.com/pingcap/dm/dm/config"
+	"github.com/pingcap/dm/dm/pb"
+)
+
+var _ = Suite(&testCopySuite{})
+
+type testCopySuite struct{}
+
+func (t *testCopySuite) TestCanCopyTable(c *C) {
+	cfg := config.NewSubTaskConfig()
+	cfg.From = config.DBConfig{Host: "127.0.0.1", Port: 3306}
+	cfg.To = config.DBConfig{Host: "127.0.0.1", Port: 3306}
+	l := NewLoader(cfg)
+	table := &tableInfo{sourceSchema: "db", sourceTable: "t1", targetSchema: "db_copy", targetTable: "t1"}
+
+	// not enabled
+	c.Assert(isSameServer(cfg), IsTrue)
+	c.Assert(l.canCopyTable(table, 1, 0), IsFalse)
+
+	cfg.SameServerCopy = true
+	c.Assert(l.canCopyTable(table, 1, 0), IsTrue)
+	c.Assert(copyTableSQL(table), Equals, "INSERT INTO `db_copy`.`t1` SELECT * FROM `db`.`t1`;")
+
+	// multiple data files, or restored partially
+	c.Assert(l.canCopyTable(table, 2, 0), IsFalse)
+	c.Assert(l.canCopyTable(table, 1, 10), IsFalse)
+	// to the source table itself
+	c.Assert(l.canCopyTable(&tableInfo{sourceSchema: "db", sourceTable: "t1", targetSchema: "db", targetTable: "t1"}, 1, 0), IsFalse)
+
+	// with column mapping
+	l.columnMapping = &cm.Mapping{}
+	c.Assert(l.canCopyTable(table, 1, 0), IsFalse)
+	l.columnMapping = nil
+
+	// different servers
+	cfg.To = config.DBConfig{Host: "127.0.0.1", Port: 4000}
+	c.Assert(isSameServer(cfg), IsFalse)
+	c.Assert(l.canCopyTable(table, 1, 0), IsFalse)
+	cfg.To = config.DBConfig{Host: "LOCALHOST", Port: 3306}
+	cfg.From = config.DBConfig{Host: "localhost", Port: 3306}
+	c.Assert(isSameServer(cfg), IsTrue)
+}
+
+func (t *testCopySuite) TestCopyDataFile(c *C) {
+	dir := c.MkDir()
+	file := "db.t1.sql"
+	data := "INSERT INTO `t1` VALUES (1),(2);\nINSERT INTO `t1` VALUES (3);\n"
+	c.Assert(ioutil.WriteFile(filepath.Join(dir, file), []byte(data), 0644), IsNil)
+
+	cfg := config.NewSubTaskConfig()
+	cfg.Dir = dir
+	cfg.SameServerCopy = true
+	exec := &fakeExecutor{}
+	w := &Worker{
+		cfg:        cfg,
+		checkPoint: newFakeRemoteCheckPoint(exec, "test_copy", 0),
+		exec:       exec,
+		jobQueue:   make(chan *dataJob, 1),
+		loader:     NewLoader(cfg),
+	}
+	table := &tableInfo{sourceSchema: "db", sourceTable: "t1", targetSchema: "db_copy", targetTable: "t1"}
+	fileJobQueue := make(chan *fileJob, 1)
+	fileJobQueue <- &fileJob{schema: "db", table: "t1", dataFile: file, info: table, copy: w.loader.canCopyTable(table, 1, 0)}
+	close(fileJobQueue)
+	runFatalChan := make(chan *pb.ProcessError, 1)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	w.run(context.Background(), fileJobQueue, &wg, runFatalChan)
+	c.Assert(runFatalChan, HasLen, 0)
+
+	// rows are copied in one transaction along with the checkpoint at the end of the file
+	var copied []string
+	for _, txn := range exec.txns {
+		if len(txn) > 1 && txn[0] == "USE `db_copy`;" {
+			copied = txn
+		}
+	}
+	c.Assert(copied, HasLen, 3)
+	c.Assert(copied[1], Equals, "INSERT INTO `db_copy`.`t1` SELECT * FROM `db`.`t1`;")
+	c.Assert(copied[2], Equals, "UPDATE `dm_meta`.`test_loader_checkpoint` SET `offset`=62 WHERE `id` ='test_copy' AND `filename`='db.t1.sql';")
+	c.Assert(w.loader.finishedDataSize.Get(), Equals, int64(len(data)))
+	c.Assert(w.loader.finishedRows.Get(), Equals, int64(3))
+	// the checkpoint is initialized with the size of the file, and the checksum of rows is recorded like restoring the file
+	c.Assert(exec.values[0], DeepEquals, [][]interface{}{{"test_copy", file, "db", "t1", 0, int64(len(data))}})
+	checksum := rowsChecksum("INSERT INTO `t1` VALUES (1),(2);") + rowsChecksum("INSERT INTO `t1` VALUES (3);")
+	c.Assert(w.checkPoint.(*RemoteCheckPoint).checksums[file], Equals, checksum)
+}
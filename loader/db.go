@@ -15,21 +15,46 @@ package loader
 
 import (
 	"database/sql"
+	"database/sql/driver"
 	"fmt"
+	"net"
+	"strconv"
 	"time"
 
 	"github.com/go-sql-driver/mysql"
 	"github.com/pingcap/dm/dm/config"
 	"github.com/pingcap/dm/pkg/log"
+	"github.com/pingcap/dm/pkg/utils"
 	"github.com/pingcap/errors"
 	tmysql "github.com/pingcap/parser/mysql"
+	"golang.org/x/net/context"
 )
 
+// Executor executes statements in the downstream, it's implemented by Conn for MySQL compatible databases.
+// the checkpoint and restoring data depend on it, so they can be tested without a database.
+type Executor interface {
+	// Exec executes statements in one transaction, values[i] (if exists) are the arguments of statements[i].
+	// the whole transaction is retried if it fails with a retryable error.
+	Exec(ctx context.Context, statements []string, values [][]interface{}) error
+
+	// Close closes the executor
+	Close() error
+}
+
 // Conn represents a live DB connection
 type Conn struct {
 	cfg *config.SubTaskConfig
 
 	db *sql.DB
+	// db is the pool shared with other Conns, it's closed by the owner of the pool rather than Close
+	shared bool
+	// set up the session of a connection before every transaction, nil means the session is not changed
+	session *session
+	// retries transactions failed, nil means the policy of cfg, see newRetryPolicy
+	retry *RetryPolicy
+
+	// write sqls rather than executing them in dry-run mode
+	sqlWriter *utils.SQLWriter
 }
 
 func (conn *Conn) querySQL(query string, args ...interface{}) (*sql.Rows, error) {
@@ -65,44 +90,66 @@ func (conn *Conn) querySQL(query string, args ...interface{}) (*sql.Rows, error)
 	return rows, nil
 }
 
-func (conn *Conn) executeSQL(sqls []string, enableRetry bool) error {
-	return conn.executeSQLCustomRetry(sqls, enableRetry, isRetryableError)
+// Exec implements Executor.Exec
+func (conn *Conn) Exec(ctx context.Context, statements []string, values [][]interface{}) error {
+	return conn.executeSQLCustomRetry(ctx, statements, values, conn.retryPolicy())
 }
 
-func (conn *Conn) executeDDL(sqls []string, enableRetry bool) error {
-	return conn.executeSQLCustomRetry(sqls, enableRetry, isDDLRetryableError)
+// Close implements Executor.Close
+func (conn *Conn) Close() error {
+	return closeConn(conn)
 }
 
-func (conn *Conn) executeSQLCustomRetry(sqls []string, enableRetry bool, isRetryableFn func(err error) bool) error {
-	if len(sqls) == 0 {
-		return nil
+// retryPolicy returns the policy retrying transactions of the connection
+func (conn *Conn) retryPolicy() RetryPolicy {
+	if conn != nil && conn.retry != nil {
+		return *conn.retry
 	}
-
-	if conn == nil || conn.db == nil {
-		return errors.NotValidf("database connection")
+	var cfg *config.SubTaskConfig
+	if conn != nil {
+		cfg = conn.cfg
 	}
+	return newRetryPolicy(cfg)
+}
 
-	var err error
+func (conn *Conn) executeSQL(ctx context.Context, sqls []string, enableRetry bool) error {
+	policy := noRetry
+	if enableRetry {
+		policy = conn.retryPolicy()
+	}
+	return conn.executeSQLCustomRetry(ctx, sqls, nil, policy)
+}
 
-	retryCount := 1
+func (conn *Conn) executeDDL(ctx context.Context, sqls []string, enableRetry bool) error {
+	policy := noRetry
 	if enableRetry {
-		retryCount = maxRetryCount
+		policy = conn.retryPolicy().withRetryable(isDDLRetryableError)
 	}
+	return conn.executeSQLCustomRetry(ctx, sqls, nil, policy)
+}
 
-	for i := 0; i < retryCount; i++ {
-		if i > 0 {
-			log.Warnf("exec sql retry %d - %-.100v", i, sqls)
-			time.Sleep(2 * time.Duration(i) * time.Second)
-		}
+// executeSQLCustomRetry executes sqls in a transaction, the whole transaction is executed again if it's retried by policy,
+// connections broken while acquired are retried as well.
+func (conn *Conn) executeSQLCustomRetry(ctx context.Context, sqls []string, args [][]interface{}, policy RetryPolicy) error {
+	if len(sqls) == 0 {
+		return nil
+	}
+
+	if conn != nil && conn.sqlWriter != nil {
+		return errors.Trace(conn.sqlWriter.Write(sqls, args))
+	}
+
+	if conn == nil || conn.db == nil {
+		return errors.NotValidf("database connection")
+	}
 
+	err := policy.Do(ctx, fmt.Sprintf("exec sql %v", sqls), func() error {
 		startTime := time.Now()
-		err = executeSQLImp(conn.db, sqls)
+		// the transaction has been rolled back if failed, so all sqls are executed again if retried
+		err := executeSQLImp(ctx, conn.db, conn.session, sqls, args, conn.skipError)
 		if err != nil {
 			tidbExecutionErrorCounter.WithLabelValues(conn.cfg.Name).Inc()
-			if isRetryableFn(err) {
-				continue
-			}
-			return errors.Trace(err)
+			return err
 		}
 
 		// update metrics
@@ -111,29 +158,61 @@ func (conn *Conn) executeSQLCustomRetry(sqls []string, enableRetry bool, isRetry
 		if cost > 1 {
 			log.Warnf("transaction execution costs %f seconds", cost)
 		}
-
 		return nil
-	}
-
+	})
 	return errors.Trace(err)
 }
 
-func executeSQLImp(db *sql.DB, sqls []string) error {
+// skipError returns whether the error of the statement is skipped by skip-error-codes, the error skipped is logged and counted.
+// errors rolling back the whole transaction, like deadlocks, are never skipped, they are retried instead.
+func (conn *Conn) skipError(query string, err error) bool {
+	if isRetryableError(err) {
+		return false
+	}
+	for _, code := range conn.cfg.SkipErrorCodes {
+		if isMySQLError(err, code) {
+			log.Warnf("[exec][sql]%-.100v[error]%v, skipped", query, err)
+			skippedErrorCounter.WithLabelValues(conn.cfg.Name, strconv.Itoa(int(code))).Inc()
+			return true
+		}
+	}
+	return false
+}
+
+// executeSQLImp executes sqls in a transaction, which is rolled back if ctx is done before committed.
+// a statement failed with an error skipped by skipError is rolled back alone by the server (statement atomicity),
+// and the transaction goes on with the following statements.
+// the connection executing the transaction is set up by sess if it's not nil, and reset before put back to the pool.
+func executeSQLImp(ctx context.Context, db *sql.DB, sess *session, sqls []string, args [][]interface{}, skipError func(query string, err error) bool) error {
 	var (
 		err error
 		txn *sql.Tx
 		res sql.Result
 	)
 
-	txn, err = db.Begin()
+	dbConn, err := acquireConn(ctx, db, sess)
+	if err != nil {
+		log.Errorf("exec sqls[%-.100v] get connection failed %v", sqls, errors.ErrorStack(err))
+		return err
+	}
+	defer releaseConn(dbConn, sess)
+
+	txn, err = dbConn.BeginTx(ctx, nil)
 	if err != nil {
 		log.Errorf("exec sqls[%-.100v] begin failed %v", sqls, errors.ErrorStack(err))
 		return err
 	}
 
 	for i := range sqls {
-		log.Debugf("[exec][sql]%-.200v", sqls[i])
-		res, err = txn.Exec(sqls[i])
+		var arg []interface{}
+		if i < len(args) {
+			arg = args[i]
+		}
+		log.Debugf("[exec][sql]%-.200v[args]%v", sqls[i], arg)
+		res, err = txn.ExecContext(ctx, sqls[i], arg...)
+		if err != nil && skipError(sqls[i], err) {
+			continue
+		}
 		if err != nil {
 			log.Warnf("[exec][sql]%-.100v[error]%v", sqls[i], err)
 			rerr := txn.Rollback()
@@ -163,6 +242,101 @@ func executeSQLImp(db *sql.DB, sqls []string) error {
 	return nil
 }
 
+// acquireConn gets a connection from the pool db, it blocks until a connection released if all of them are in use.
+// idle connections may be closed by the server (like wait_timeout exceeded) or broken by the network,
+// so the connection is pinged before used, and a broken one is discarded and replaced.
+// the session of the connection is set up by sess if it's not nil, see releaseConn.
+func acquireConn(ctx context.Context, db *sql.DB, sess *session) (*sql.Conn, error) {
+	for i := 0; ; i++ {
+		dbConn, err := db.Conn(ctx)
+		if err != nil {
+			return nil, err
+		}
+		err = dbConn.PingContext(ctx)
+		if err == nil && sess != nil {
+			if _, err = dbConn.ExecContext(ctx, sess.setup); err != nil {
+				log.Errorf("[exec][sql]%s[error]%v", sess.setup, err)
+				// the session may be set up partially, so don't reuse the connection
+				discardConn(dbConn)
+				return nil, err
+			}
+		}
+		if err == nil {
+			return dbConn, nil
+		}
+		// database/sql removes the connection from the pool if it's bad, so at most all open connections are pinged
+		dbConn.Close()
+		if !isRetryableError(err) || i >= db.Stats().OpenConnections {
+			return nil, err
+		}
+		log.Warnf("connection to the downstream database is broken %v, get another one", err)
+	}
+}
+
+// releaseConn puts the connection acquired by acquireConn back to the pool, after its session reset by sess if it's not nil.
+// the connection is discarded if failed to reset, so no connection in the pool keeps the session set up.
+func releaseConn(dbConn *sql.Conn, sess *session) {
+	if sess != nil {
+		// reset even if the transaction is canceled
+		if _, err := dbConn.ExecContext(context.Background(), sess.reset); err != nil {
+			log.Errorf("[exec][sql]%s[error]%v", sess.reset, err)
+			discardConn(dbConn)
+			return
+		}
+	}
+	dbConn.Close()
+}
+
+// discardConn closes the connection rather than putting it back to the pool
+func discardConn(dbConn *sql.Conn) {
+	// database/sql closes the connection if the function returns driver.ErrBadConn
+	dbConn.Raw(func(interface{}) error { return driver.ErrBadConn })
+	dbConn.Close()
+}
+
+// session sets session variables of a connection before a transaction and resets them after
+type session struct {
+	setup string
+	reset string
+}
+
+// keyChecksOffSession turns off foreign key and unique checks for speed, see config.LoaderConfig.DisableKeyChecks.
+// they are reset to the global values, the defaults of sessions.
+var keyChecksOffSession = &session{
+	setup: "SET foreign_key_checks=0, unique_checks=0",
+	reset: "SET foreign_key_checks=@@GLOBAL.foreign_key_checks, unique_checks=@@GLOBAL.unique_checks",
+}
+
+// newSession returns the session of connections in the pool created by createConnPool, nil means not changed
+func newSession(cfg *config.SubTaskConfig) *session {
+	if cfg.DisableKeyChecks {
+		return keyChecksOffSession
+	}
+	return nil
+}
+
+// createConnPool creates the pool of connections shared by workers and restoring schemas,
+// at most cfg.MaxConnections connections are opened if it's positive.
+func createConnPool(cfg *config.SubTaskConfig) (*sql.DB, error) {
+	conn, err := createConn(cfg)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	setConnPoolSize(conn.db, cfg.MaxConnections, cfg.PoolSize)
+	return conn.db, nil
+}
+
+// setConnPoolSize limits db to maxConns open connections, and keeps enough idle ones to be reused by workers,
+// the default of database/sql (2 idle connections) makes connections closed and opened again and again by concurrent workers.
+func setConnPoolSize(db *sql.DB, maxConns int, workers int) {
+	idle := workers + 1 // restoring schemas besides workers
+	if maxConns > 0 {
+		idle = maxConns
+	}
+	db.SetMaxOpenConns(maxConns)
+	db.SetMaxIdleConns(idle)
+}
+
 func createConn(cfg *config.SubTaskConfig) (*Conn, error) {
 	dbDSN := fmt.Sprintf("%s:%s@tcp(%s:%d)/?charset=utf8", cfg.To.User, cfg.To.Password, cfg.To.Host, cfg.To.Port)
 	db, err := sql.Open("mysql", dbDSN)
@@ -174,7 +348,7 @@ func createConn(cfg *config.SubTaskConfig) (*Conn, error) {
 }
 
 func closeConn(conn *Conn) error {
-	if conn.db == nil {
+	if conn.db == nil || conn.shared {
 		return nil
 	}
 
@@ -197,15 +371,38 @@ func isErrDupEntry(err error) bool {
 	return isMySQLError(err, tmysql.ErrDupEntry)
 }
 
+func isErrDupFieldName(err error) bool {
+	return isMySQLError(err, tmysql.ErrDupFieldName)
+}
+
+// retryInterval returns the default backoff before the i-th retry,
+// it grows exponentially from retryBaseInterval to retryMaxInterval.
+func retryInterval(i int) time.Duration {
+	return backoffInterval(i, retryBaseInterval, retryMaxInterval)
+}
+
+// isRetryableError checks whether the error is transient, only broken connections and
+// known transient errors (like deadlock and lock wait timeout) can be retried.
 func isRetryableError(err error) bool {
 	err = causeErr(err)
-	if isMySQLError(err, tmysql.ErrDupEntry) {
+	if err == driver.ErrBadConn || err == mysql.ErrInvalidConn {
+		return true
+	}
+	if _, ok := err.(net.Error); ok {
+		return true
+	}
+
+	mysqlErr, ok := err.(*mysql.MySQLError)
+	if !ok {
 		return false
 	}
-	if isMySQLError(err, tmysql.ErrDataTooLong) {
+	switch mysqlErr.Number {
+	case tmysql.ErrLockDeadlock, tmysql.ErrLockWaitTimeout,
+		tmysql.ErrUnknown, tmysql.ErrPDServerTimeout, tmysql.ErrTiKVServerTimeout, tmysql.ErrTiKVServerBusy, tmysql.ErrResolveLockTimeout, tmysql.ErrRegionUnavailable:
+		return true
+	default:
 		return false
 	}
-	return true
 }
 
 func isDDLRetryableError(err error) bool {
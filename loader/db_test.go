@@ -0,0 +1,468 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loader
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	. "github.com/pingcap/check"
+	"github.com/pingcap/dm/dm/config"
+	"github.com/pingcap/dm/dm/pb"
+	"github.com/pingcap/errors"
+	tmysql "github.com/pingcap/parser/mysql"
+	"golang.org/x/net/context"
+)
+
+var _ = Suite(&testDBSuite{})
+
+type testDBSuite struct{}
+
+// mockDriver is a database/sql driver, whose transactions and queries fail with errs in order before succeeding,
+// statements fail with errors returned by errFn if it's set, and queries return rows with columns
+type mockDriver struct {
+	sync.Mutex
+	errs      []error
+	errFn     func(query string) error
+	executed  []string // sqls of committed transactions
+	rollbacks int
+	queries   int // count of queries, including failed ones
+	columns   []string
+	rows      [][]driver.Value
+
+	conns    []*mockConn // connections opened and not closed
+	maxConns int         // max count of connections opened at the same time
+}
+
+func (d *mockDriver) Open(name string) (driver.Conn, error) {
+	d.Lock()
+	defer d.Unlock()
+	conn := &mockConn{d: d}
+	d.conns = append(d.conns, conn)
+	if len(d.conns) > d.maxConns {
+		d.maxConns = len(d.conns)
+	}
+	return conn, nil
+}
+
+type mockConn struct {
+	d      *mockDriver
+	txn    []string
+	broken bool     // like closed by the server, fails to ping
+	log    []string // all statements executed on the connection, including BEGIN and COMMIT
+}
+
+func (c *mockConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.NotSupportedf("prepare")
+}
+
+func (c *mockConn) Close() error {
+	c.d.Lock()
+	defer c.d.Unlock()
+	for i, conn := range c.d.conns {
+		if conn == c {
+			c.d.conns = append(c.d.conns[:i], c.d.conns[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (c *mockConn) Ping(ctx context.Context) error {
+	c.d.Lock()
+	defer c.d.Unlock()
+	if c.broken {
+		return driver.ErrBadConn
+	}
+	return nil
+}
+
+func (c *mockConn) Begin() (driver.Tx, error) {
+	c.d.Lock()
+	defer c.d.Unlock()
+	c.txn = c.txn[:0]
+	c.log = append(c.log, "BEGIN")
+	return c, nil
+}
+
+func (c *mockConn) Exec(query string, args []driver.Value) (driver.Result, error) {
+	c.d.Lock()
+	defer c.d.Unlock()
+	if len(c.d.errs) > 0 {
+		err := c.d.errs[0]
+		c.d.errs = c.d.errs[1:]
+		return nil, err
+	}
+	if c.d.errFn != nil {
+		if err := c.d.errFn(query); err != nil {
+			return nil, err
+		}
+	}
+	c.txn = append(c.txn, query)
+	c.log = append(c.log, query)
+	return driver.RowsAffected(1), nil
+}
+
+func (c *mockConn) Query(query string, args []driver.Value) (driver.Rows, error) {
+	c.d.Lock()
+	defer c.d.Unlock()
+	c.d.queries++
+	if len(c.d.errs) > 0 {
+		err := c.d.errs[0]
+		c.d.errs = c.d.errs[1:]
+		return nil, err
+	}
+	return &mockRows{columns: c.d.columns, rows: c.d.rows}, nil
+}
+
+type mockRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *mockRows) Columns() []string { return r.columns }
+func (r *mockRows) Close() error      { return nil }
+
+func (r *mockRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+func (c *mockConn) Commit() error {
+	c.d.Lock()
+	defer c.d.Unlock()
+	c.d.executed = append(c.d.executed, c.txn...)
+	c.log = append(c.log, "COMMIT")
+	return nil
+}
+
+func (c *mockConn) Rollback() error {
+	c.d.Lock()
+	defer c.d.Unlock()
+	c.d.rollbacks++
+	return nil
+}
+
+var mockDrv = &mockDriver{}
+
+func init() {
+	sql.Register("loader-mock", mockDrv)
+}
+
+func (t *testDBSuite) TestExecuteSQLRetry(c *C) {
+	oldBase := retryBaseInterval
+	retryBaseInterval = time.Millisecond
+	defer func() {
+		retryBaseInterval = oldBase
+	}()
+
+	db, err := sql.Open("loader-mock", "")
+	c.Assert(err, IsNil)
+	defer db.Close()
+	conn := &Conn{cfg: &config.SubTaskConfig{Name: "test-retry"}, db: db}
+	sqls := []string{"USE `db`;", "INSERT INTO `t1` VALUES (1);", "UPDATE `checkpoint` SET `offset`=10;"}
+
+	// deadlock twice, then the whole batch committed once
+	deadlock := &mysql.MySQLError{Number: tmysql.ErrLockDeadlock, Message: "Deadlock found when trying to get lock"}
+	mockDrv.errs = []error{deadlock, deadlock}
+	c.Assert(conn.executeSQL(context.Background(), sqls, true), IsNil)
+	c.Assert(mockDrv.executed, DeepEquals, sqls)
+	c.Assert(mockDrv.rollbacks, Equals, 2)
+
+	// fail fast for non-retryable errors
+	mockDrv.executed = nil
+	mockDrv.rollbacks = 0
+	mockDrv.errs = []error{&mysql.MySQLError{Number: tmysql.ErrDupEntry, Message: "Duplicate entry"}, deadlock}
+	c.Assert(conn.executeSQL(context.Background(), sqls, true), ErrorMatches, ".*Duplicate entry.*")
+	c.Assert(mockDrv.executed, HasLen, 0)
+	c.Assert(mockDrv.rollbacks, Equals, 1)
+	mockDrv.errs = nil
+}
+
+func (t *testDBSuite) TestIsRetryableError(c *C) {
+	cases := []struct {
+		err       error
+		retryable bool
+	}{
+		{&mysql.MySQLError{Number: tmysql.ErrLockDeadlock}, true},
+		{errors.Trace(&mysql.MySQLError{Number: tmysql.ErrLockWaitTimeout}), true},
+		{&mysql.MySQLError{Number: tmysql.ErrTiKVServerBusy}, true},
+		{driver.ErrBadConn, true},
+		{errors.Annotate(mysql.ErrInvalidConn, "exec"), true},
+		{&mysql.MySQLError{Number: tmysql.ErrDupEntry}, false},
+		{&mysql.MySQLError{Number: tmysql.ErrParse}, false},
+		{&mysql.MySQLError{Number: tmysql.ErrDataTooLong}, false},
+		{errors.New("unknown"), false},
+	}
+	for _, cs := range cases {
+		c.Assert(isRetryableError(cs.err), Equals, cs.retryable, Commentf("error %v", cs.err))
+	}
+
+	c.Assert(isDDLRetryableError(&mysql.MySQLError{Number: tmysql.ErrTableExists}), IsFalse)
+	c.Assert(isDDLRetryableError(&mysql.MySQLError{Number: tmysql.ErrLockDeadlock}), IsTrue)
+
+	c.Assert(retryInterval(1), Equals, retryBaseInterval)
+	c.Assert(retryInterval(2), Equals, 2*retryBaseInterval)
+	c.Assert(retryInterval(100), Equals, retryMaxInterval)
+}
+
+func (t *testDBSuite) TestSkipErrorCodes(c *C) {
+	var (
+		dir      = c.MkDir()
+		file     = "db.t1.sql"
+		data     string
+		stmts    []string
+		offsetRe = regexp.MustCompile("SET `offset`=(\\d+) ")
+	)
+	for i := 0; i < 10; i++ {
+		stmt := fmt.Sprintf("INSERT INTO `t1` VALUES (%d);", i)
+		stmts = append(stmts, stmt)
+		data += stmt + "\n"
+	}
+	c.Assert(ioutil.WriteFile(filepath.Join(dir, file), []byte(data), 0644), IsNil)
+
+	db, err := sql.Open("loader-mock", "")
+	c.Assert(err, IsNil)
+	defer db.Close()
+	// rows 3 and 7 are restored already
+	mockDrv.errFn = func(query string) error {
+		if strings.Contains(query, "(3)") || strings.Contains(query, "(7)") {
+			return &mysql.MySQLError{Number: tmysql.ErrDupEntry, Message: "Duplicate entry"}
+		}
+		return nil
+	}
+	defer func() {
+		mockDrv.errFn = nil
+		mockDrv.executed = nil
+		mockDrv.rollbacks = 0
+	}()
+
+	restore := func(skipErrorCodes []uint16) *pb.ProcessError {
+		mockDrv.executed = nil
+		cfg := config.NewSubTaskConfig()
+		cfg.Name = "test-skip-error-codes"
+		cfg.Dir = dir
+		cfg.SkipErrorCodes = skipErrorCodes
+		conn := &Conn{cfg: cfg, db: db}
+		w := &Worker{
+			cfg:        cfg,
+			checkPoint: newFakeRemoteCheckPoint(conn, "test_skip", 0),
+			exec:       conn,
+			jobQueue:   make(chan *dataJob, 16),
+			loader:     NewLoader(cfg),
+		}
+		table := &tableInfo{sourceSchema: "db", sourceTable: "t1", targetSchema: "db", targetTable: "t1"}
+		fileJobQueue := make(chan *fileJob, 1)
+		fileJobQueue <- &fileJob{schema: "db", table: "t1", dataFile: file, info: table}
+		close(fileJobQueue)
+		runFatalChan := make(chan *pb.ProcessError, 1)
+
+		var wg sync.WaitGroup
+		wg.Add(1)
+		w.run(context.Background(), fileJobQueue, &wg, runFatalChan)
+		if len(runFatalChan) > 0 {
+			return <-runFatalChan
+		}
+		return nil
+	}
+
+	// restored statements and the last checkpoint saved
+	restored := func() (restored []string, offset string) {
+		for _, query := range mockDrv.executed {
+			if strings.HasPrefix(query, "INSERT INTO `t1`") {
+				restored = append(restored, query)
+			} else if matches := offsetRe.FindStringSubmatch(query); len(matches) == 2 {
+				offset = matches[1]
+			}
+		}
+		return
+	}
+
+	// stopped at the first duplicate entry
+	perr := restore(nil)
+	c.Assert(perr, NotNil)
+	c.Assert(perr.Msg, Matches, "(?s).*Duplicate entry.*")
+	executed, offset := restored()
+	c.Assert(executed, DeepEquals, stmts[:3])
+	c.Assert(offset, Equals, strconv.Itoa(len(strings.Join(stmts[:3], "\n"))+1))
+
+	// duplicate entries are skipped, while other statements and checkpoints in their transactions are committed
+	c.Assert(restore([]uint16{tmysql.ErrDupEntry}), IsNil)
+	executed, offset = restored()
+	c.Assert(executed, DeepEquals, append(append(append([]string{}, stmts[:3]...), stmts[4:7]...), stmts[8:]...))
+	c.Assert(offset, Equals, strconv.Itoa(len(data)))
+}
+
+func (t *testDBSuite) TestConnPool(c *C) {
+	var (
+		maxConns = 2
+		workers  = 8
+		txns     = 10
+	)
+	db, err := sql.Open("loader-mock", "")
+	c.Assert(err, IsNil)
+	setConnPoolSize(db, maxConns, workers)
+
+	mockDrv.Lock()
+	mockDrv.executed = nil
+	mockDrv.maxConns = len(mockDrv.conns)
+	mockDrv.errFn = func(query string) error {
+		time.Sleep(time.Millisecond) // keep the connection in use for a while
+		return nil
+	}
+	mockDrv.Unlock()
+	defer func() {
+		mockDrv.errFn = nil
+		mockDrv.executed = nil
+	}()
+
+	// workers wait for connections rather than failing when all of them are in use
+	cfg := &config.SubTaskConfig{Name: "test-conn-pool"}
+	var wg sync.WaitGroup
+	errs := make([]error, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			conn := &Conn{cfg: cfg, db: db, shared: true}
+			defer conn.Close()
+			for j := 0; j < txns; j++ {
+				stmt := fmt.Sprintf("INSERT INTO `t%d` VALUES (%d);", i, j)
+				if errs[i] = conn.Exec(context.Background(), []string{stmt}, nil); errs[i] != nil {
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		c.Assert(err, IsNil)
+	}
+	c.Assert(mockDrv.executed, HasLen, workers*txns)
+	c.Assert(mockDrv.maxConns, LessEqual, maxConns)
+
+	// the pool isn't closed by Conns sharing it, and connections are reused
+	c.Assert(db.Stats().OpenConnections, Equals, maxConns)
+	c.Assert(db.Ping(), IsNil)
+
+	// broken connections are discarded before used
+	mockDrv.Lock()
+	for _, conn := range mockDrv.conns {
+		conn.broken = true
+	}
+	mockDrv.Unlock()
+	conn := &Conn{cfg: cfg, db: db, shared: true}
+	c.Assert(conn.Exec(context.Background(), []string{"INSERT INTO `t0` VALUES (100);"}, nil), IsNil)
+	c.Assert(mockDrv.executed[len(mockDrv.executed)-1], Equals, "INSERT INTO `t0` VALUES (100);")
+	c.Assert(db.Stats().OpenConnections, Equals, 1)
+
+	c.Assert(db.Close(), IsNil)
+	c.Assert(mockDrv.conns, HasLen, 0)
+}
+
+func (t *testDBSuite) TestKeyChecksOffSession(c *C) {
+	var (
+		maxConns = 2
+		workers  = 4
+		txns     = 5
+	)
+	db, err := sql.Open("loader-mock", "")
+	c.Assert(err, IsNil)
+	defer db.Close()
+	setConnPoolSize(db, maxConns, workers)
+
+	cfg := &config.SubTaskConfig{Name: "test-key-checks-off"}
+	c.Assert(newSession(cfg), IsNil)
+	cfg.DisableKeyChecks = true
+	sess := newSession(cfg)
+	c.Assert(sess, Equals, keyChecksOffSession)
+
+	mockDrv.Lock()
+	mockDrv.executed = nil
+	mockDrv.conns = nil
+	mockDrv.errFn = func(query string) error {
+		time.Sleep(time.Millisecond) // keep the connection in use for a while
+		return nil
+	}
+	mockDrv.Unlock()
+	defer func() {
+		mockDrv.errFn = nil
+		mockDrv.executed = nil
+	}()
+
+	var wg sync.WaitGroup
+	errs := make([]error, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			conn := &Conn{cfg: cfg, db: db, shared: true, session: sess}
+			for j := 0; j < txns; j++ {
+				stmt := fmt.Sprintf("INSERT INTO `t%d` VALUES (%d);", i, j)
+				if errs[i] = conn.Exec(context.Background(), []string{stmt}, nil); errs[i] != nil {
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		c.Assert(err, IsNil)
+	}
+	c.Assert(mockDrv.executed, HasLen, workers*txns)
+
+	// every connection in the pool sets up the session when acquired, and resets it before released
+	mockDrv.Lock()
+	conns := append([]*mockConn(nil), mockDrv.conns...)
+	mockDrv.Unlock()
+	c.Assert(conns, HasLen, maxConns)
+	count := 0
+	for _, conn := range conns {
+		c.Assert(len(conn.log)%5, Equals, 0)
+		for i := 0; i < len(conn.log); i += 5 {
+			c.Assert(conn.log[i], Equals, "SET foreign_key_checks=0, unique_checks=0")
+			c.Assert(conn.log[i+1], Equals, "BEGIN")
+			c.Assert(conn.log[i+2], Matches, "INSERT INTO .*")
+			c.Assert(conn.log[i+3], Equals, "COMMIT")
+			c.Assert(conn.log[i+4], Equals, "SET foreign_key_checks=@@GLOBAL.foreign_key_checks, unique_checks=@@GLOBAL.unique_checks")
+			count++
+		}
+	}
+	c.Assert(count, Equals, workers*txns)
+
+	// the connection failed to reset is discarded rather than put back to the pool
+	mockDrv.errFn = func(query string) error {
+		if query == keyChecksOffSession.reset {
+			return errors.New("reset failed")
+		}
+		return nil
+	}
+	conn := &Conn{cfg: cfg, db: db, shared: true, session: sess}
+	c.Assert(conn.Exec(context.Background(), []string{"INSERT INTO `t0` VALUES (100);"}, nil), IsNil)
+	c.Assert(db.Stats().OpenConnections, Equals, maxConns-1)
+}
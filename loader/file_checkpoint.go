@@ -0,0 +1,275 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loader
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pingcap/dm/pkg/log"
+	"github.com/pingcap/errors"
+	"github.com/siddontang/go/ioutil2"
+)
+
+// filePoint is the checkpoint of a data file saved in FileCheckPoint
+type filePoint struct {
+	Schema string `json:"cp-schema"`
+	Table  string `json:"cp-table"`
+	Offset int64  `json:"offset"`
+	EndPos int64  `json:"end-pos"`
+	// checksum of rows saved after the file restored, see rowsChecksum
+	Checksum uint64 `json:"checksum,omitempty"`
+}
+
+// fileCheckPointData is the content of checkpoint file
+type fileCheckPointData struct {
+	ID    string                `json:"id"`
+	Files map[string]*filePoint `json:"files"` // data file name -> checkpoint
+}
+
+// FileCheckPoint implements CheckPoint by saving status in a local JSON file.
+// the file is rewritten atomically (write then rename) whenever a checkpoint is initialized or updated.
+// NOTE: offset is updated after the data job executed rather than in the same transaction,
+// so the last executed statement of a file may be executed again if the loader crashes between them.
+// if path is empty, checkpoints are only kept in memory, it's used in dry-run mode.
+type FileCheckPoint struct {
+	restoringState
+
+	sync.Mutex
+	path   string
+	id     string
+	points map[string]*filePoint
+}
+
+func newFileCheckPoint(path, id string) (CheckPoint, error) {
+	cp := &FileCheckPoint{
+		restoringState: newRestoringState(),
+		path:           path,
+		id:             id,
+		points:         make(map[string]*filePoint),
+	}
+
+	if err := cp.loadPoints(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return cp, nil
+}
+
+// loadPoints loads checkpoints from the file, no checkpoints if the file not exists
+func (cp *FileCheckPoint) loadPoints() error {
+	cp.Lock()
+	defer cp.Unlock()
+	if cp.path == "" {
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(cp.path)
+	if os.IsNotExist(err) {
+		cp.points = make(map[string]*filePoint)
+		return nil
+	} else if err != nil {
+		return errors.Annotatef(err, "read checkpoint file %s", cp.path)
+	}
+
+	var content fileCheckPointData
+	if err = json.Unmarshal(data, &content); err != nil {
+		return errors.Annotatef(err, "decode checkpoint file %s", cp.path)
+	}
+	if content.ID != cp.id {
+		return errors.Errorf("checkpoint file %s belongs to %s, not %s", cp.path, content.ID, cp.id)
+	}
+
+	cp.points = content.Files
+	if cp.points == nil {
+		cp.points = make(map[string]*filePoint)
+	}
+	return nil
+}
+
+// flush writes all checkpoints into the file atomically, it should be called with lock held
+func (cp *FileCheckPoint) flush() error {
+	if cp.path == "" {
+		return nil
+	}
+	data, err := json.Marshal(&fileCheckPointData{ID: cp.id, Files: cp.points})
+	if err != nil {
+		return errors.Trace(err)
+	}
+	err = ioutil2.WriteFileAtomic(cp.path, data, 0644)
+	return errors.Annotatef(err, "write checkpoint file %s", cp.path)
+}
+
+// Load implements CheckPoint.Load
+func (cp *FileCheckPoint) Load() error {
+	begin := time.Now()
+	defer func() {
+		log.Infof("[checkpoint] load checkpoint takes %f seconds", time.Since(begin).Seconds())
+	}()
+
+	if err := cp.loadPoints(); err != nil {
+		return errors.Trace(err)
+	}
+
+	cp.Lock()
+	defer cp.Unlock()
+	cp.restoringState.resetRestoringFiles(cp.points)
+	return nil
+}
+
+// Init implements CheckPoint.Init
+func (cp *FileCheckPoint) Init(filename string, endPos int64) error {
+	db, table, err := parseDataFileName(filename)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	cp.Lock()
+	defer cp.Unlock()
+	if _, ok := cp.points[filename]; ok {
+		log.Infof("[checkpoint] id:%s filename %s already exists, skip it.", cp.id, filename)
+		return nil
+	}
+	cp.points[filename] = &filePoint{Schema: db, Table: table, EndPos: endPos}
+	return errors.Trace(cp.flush())
+}
+
+// Close implements CheckPoint.Close
+func (cp *FileCheckPoint) Close() {}
+
+// GenSQL implements CheckPoint.GenSQL
+func (cp *FileCheckPoint) GenSQL(filename string, offset int64) string {
+	// checkpoint is not saved in DB
+	return ""
+}
+
+// UpdateOffset implements CheckPoint.UpdateOffset
+func (cp *FileCheckPoint) UpdateOffset(filename string, offset int64) error {
+	cp.Lock()
+	defer cp.Unlock()
+	point, ok := cp.points[filename]
+	if !ok {
+		return errors.NotFoundf("checkpoint of file %s", filename)
+	}
+	point.Offset = offset
+	return errors.Trace(cp.flush())
+}
+
+// SaveChecksum implements CheckPoint.SaveChecksum
+func (cp *FileCheckPoint) SaveChecksum(filename string, checksum uint64) error {
+	cp.Lock()
+	defer cp.Unlock()
+	point, ok := cp.points[filename]
+	if !ok {
+		return errors.NotFoundf("checkpoint of file %s", filename)
+	}
+	point.Checksum = checksum
+	cp.checksums[filename] = checksum
+	return errors.Trace(cp.flush())
+}
+
+// GetTableChecksum implements CheckPoint.GetTableChecksum
+func (cp *FileCheckPoint) GetTableChecksum(db, table string) uint64 {
+	cp.Lock()
+	defer cp.Unlock()
+	return cp.restoringState.tableChecksum(db, table)
+}
+
+// Flush implements CheckPoint.Flush
+func (cp *FileCheckPoint) Flush() error {
+	// saved in UpdateOffset
+	return nil
+}
+
+// Clear implements CheckPoint.Clear
+func (cp *FileCheckPoint) Clear() error {
+	cp.Lock()
+	defer cp.Unlock()
+	cp.points = make(map[string]*filePoint)
+	cp.checksums = make(map[string]uint64)
+	if cp.path == "" {
+		return nil
+	}
+	err := os.Remove(cp.path)
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Annotatef(err, "remove checkpoint file %s", cp.path)
+	}
+	return nil
+}
+
+// Prune implements CheckPoint.Prune
+func (cp *FileCheckPoint) Prune(existingFiles []string) error {
+	cp.Lock()
+	defer cp.Unlock()
+
+	existing := toFileSet(existingFiles)
+	cp.restoringState.pruneRestoringFiles(existing)
+	var pruned int
+	for file := range cp.points {
+		if _, ok := existing[file]; !ok {
+			delete(cp.points, file)
+			pruned++
+		}
+	}
+	if pruned == 0 {
+		return nil
+	}
+	log.Infof("[checkpoint] pruned checkpoints of %d data files not existing", pruned)
+	return errors.Trace(cp.flush())
+}
+
+// Export implements CheckPoint.Export
+func (cp *FileCheckPoint) Export() ([]byte, error) {
+	cp.Lock()
+	defer cp.Unlock()
+	return exportPoints(cp.id, cp.points)
+}
+
+// Import implements CheckPoint.Import
+func (cp *FileCheckPoint) Import(data []byte) error {
+	points, err := importPoints(data)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	cp.Lock()
+	defer cp.Unlock()
+	cp.points = points
+	cp.restoringState.resetRestoringFiles(points)
+	log.Infof("[checkpoint] imported checkpoints of %d data files", len(points))
+	return errors.Trace(cp.flush())
+}
+
+// Count implements CheckPoint.Count
+func (cp *FileCheckPoint) Count() (int, error) {
+	cp.Lock()
+	defer cp.Unlock()
+	return len(cp.points), nil
+}
+
+func (cp *FileCheckPoint) String() string {
+	cp.Lock()
+	defer cp.Unlock()
+	result := make(map[string][]int64, len(cp.points))
+	for filename, point := range cp.points {
+		result[filename] = []int64{point.Offset, point.EndPos}
+	}
+	bytes, err := json.Marshal(result)
+	if err != nil {
+		return err.Error()
+	}
+	return string(bytes)
+}
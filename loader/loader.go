@@ -14,9 +14,10 @@
 package loader
 
 import (
-	"bufio"
+	"database/sql"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
@@ -27,19 +28,28 @@ import (
 	"github.com/pingcap/dm/dm/config"
 	"github.com/pingcap/dm/dm/pb"
 	"github.com/pingcap/dm/dm/unit"
+	"github.com/pingcap/dm/pkg/gtid"
 	"github.com/pingcap/dm/pkg/log"
 	"github.com/pingcap/dm/pkg/utils"
 	"github.com/pingcap/errors"
 	cm "github.com/pingcap/tidb-tools/pkg/column-mapping"
 	"github.com/pingcap/tidb-tools/pkg/filter"
 	"github.com/pingcap/tidb-tools/pkg/table-router"
+	"github.com/siddontang/go-mysql/mysql"
 	"github.com/siddontang/go/sync2"
 	"golang.org/x/net/context"
 )
 
 var (
-	jobCount      = 1000
-	maxRetryCount = 10
+	jobCount = 1000
+	// max times to retry operations failed with transient errors if retry-limit is not specified, see RetryPolicy
+	defaultMaxRetries = 9
+
+	retryBaseInterval = time.Second
+	retryMaxInterval  = 16 * time.Second
+
+	// interval to save checkpoints batched by RemoteCheckPoint.SaveBatch
+	checkpointFlushInterval = time.Second
 )
 
 // FilePosSet represents a set in mathematics.
@@ -57,6 +67,11 @@ type dataJob struct {
 	file       string
 	offset     int64
 	lastOffset int64
+	fileSize   int64
+	rows       int64          // count of rows inserted by sql
+	progress   *tableProgress // progress of the source table, nil if not tracked
+	// checksum of rows in the file before offset, it's saved in checkpoint after the last job of the file executed
+	checksum uint64
 }
 
 type fileJob struct {
@@ -65,6 +80,7 @@ type fileJob struct {
 	dataFile string
 	offset   int64
 	info     *tableInfo
+	copy     bool // restored by copying rows of the source table in the same server, see canCopyTable
 }
 
 // Worker represents a worker.
@@ -72,31 +88,54 @@ type Worker struct {
 	id         int
 	cfg        *config.SubTaskConfig
 	checkPoint CheckPoint
-	conn       *Conn
+	exec       Executor
 	wg         sync.WaitGroup
 	jobQueue   chan *dataJob
+	quota      *jobQuota // bounds the bytes of jobs queued, nil means no limit
 	loader     *Loader
 
+	// jobs are committed in one transaction every commitStatements jobs or every commitInterval, see config.LoaderConfig.CommitStatements
+	commitStatements int
+	commitInterval   time.Duration
+
 	closed int64
 }
 
 // NewWorker returns a Worker.
 func NewWorker(loader *Loader, id int) (worker *Worker, err error) {
-	conn, err := createConn(loader.cfg)
-	if err != nil {
-		return nil, errors.Trace(err)
+	conn := loader.newConn()
+
+	queueSize := loader.cfg.JobQueueSize
+	if queueSize <= 0 {
+		queueSize = jobCount
+	}
+
+	var commitInterval time.Duration
+	if loader.cfg.CommitInterval != "" {
+		commitInterval, err = time.ParseDuration(loader.cfg.CommitInterval)
+		if err != nil {
+			return nil, errors.Annotatef(err, "parse commit-interval %s", loader.cfg.CommitInterval)
+		}
 	}
 
 	return &Worker{
-		id:         id,
-		cfg:        loader.cfg,
-		checkPoint: loader.checkPoint,
-		conn:       conn,
-		jobQueue:   make(chan *dataJob, jobCount),
-		loader:     loader,
+		id:               id,
+		cfg:              loader.cfg,
+		checkPoint:       loader.checkPoint,
+		exec:             conn,
+		jobQueue:         make(chan *dataJob, queueSize),
+		quota:            newJobQuota(loader.cfg.JobQueueBytes),
+		loader:           loader,
+		commitStatements: loader.cfg.CommitStatements,
+		commitInterval:   commitInterval,
 	}, nil
 }
 
+// groupJobs returns whether jobs are committed in groups rather than one by one
+func (w *Worker) groupJobs() bool {
+	return w.commitStatements > 1 || w.commitInterval > 0
+}
+
 // Close closes worker
 func (w *Worker) Close() {
 	if !atomic.CompareAndSwapInt64(&w.closed, 0, 1) {
@@ -105,7 +144,9 @@ func (w *Worker) Close() {
 
 	close(w.jobQueue)
 	w.wg.Wait()
-	closeConn(w.conn)
+	if err := w.exec.Close(); err != nil {
+		log.Errorf("[loader] worker %d close executor error %v", w.id, err)
+	}
 }
 
 func (w *Worker) run(ctx context.Context, fileJobQueue chan *fileJob, workerWg *sync.WaitGroup, runFatalChan chan *pb.ProcessError) {
@@ -117,29 +158,57 @@ func (w *Worker) run(ctx context.Context, fileJobQueue chan *fileJob, workerWg *
 
 	doJob := func() {
 		defer w.wg.Done()
+		var (
+			pending []*dataJob
+			timer   *time.Timer
+			timeout <-chan time.Time // fired when pending jobs wait for commitInterval
+		)
+		commit := func() bool {
+			if timer != nil {
+				timer.Stop()
+				timer, timeout = nil, nil
+			}
+			jobs := pending
+			pending = nil
+			return w.commitJobs(newCtx, jobs, runFatalChan)
+		}
+		defer func() {
+			if timer != nil {
+				timer.Stop()
+			}
+		}()
+
 		for {
 			select {
 			case <-newCtx.Done():
+				// pending jobs are not committed, neither are their checkpoints
 				log.Debugf("[loader] worker %d execution goroutine exits", w.id)
 				return
+			case <-timeout:
+				if !commit() {
+					return
+				}
 			case job, ok := <-w.jobQueue:
 				if !ok || job == nil {
+					// all jobs of the file are read
+					commit()
 					return
 				}
-				sqls := make([]string, 0, 3)
-				sqls = append(sqls, fmt.Sprintf("USE `%s`;", job.schema))
-				sqls = append(sqls, job.sql)
-
-				offsetSQL := w.checkPoint.GenSQL(job.file, job.offset)
-				sqls = append(sqls, offsetSQL)
-
-				if err := w.conn.executeSQL(sqls, true); err != nil {
-					// expect pause rather than exit
-					err = errors.Annotatef(err, "file %s", job.file)
-					runFatalChan <- unit.NewProcessError(pb.ErrorType_ExecSQL, errors.ErrorStack(err))
-					return
+				if w.groupJobs() {
+					// jobs pending to commit are bounded by commitStatements and commitInterval rather than the quota,
+					// or the dispatcher may wait for the quota released by them forever
+					w.quota.release(int64(len(job.sql)))
+				}
+				pending = append(pending, job)
+				if len(pending) == 1 && w.commitInterval > 0 {
+					timer = time.NewTimer(w.commitInterval)
+					timeout = timer.C
+				}
+				if !w.groupJobs() || (w.commitStatements > 0 && len(pending) >= w.commitStatements) {
+					if !commit() {
+						return
+					}
 				}
-				w.loader.finishedDataSize.Add(job.offset - job.lastOffset)
 			}
 		}
 	}
@@ -159,68 +228,185 @@ func (w *Worker) run(ctx context.Context, fileJobQueue chan *fileJob, workerWg *
 			go doJob()
 
 			// restore a table
-			if err := w.restoreDataFile(ctx, w.cfg.Dir, job.dataFile, job.offset, job.info); err != nil {
+			restore := w.restoreDataFile
+			if job.copy {
+				restore = w.copyDataFile
+			}
+			if err := restore(ctx, w.loader.dumpDir(), job.dataFile, job.offset, job.info); err != nil {
 				// expect pause rather than exit
 				err = errors.Annotatef(err, "restore data file (%v) failed", job.dataFile)
 				runFatalChan <- unit.NewProcessError(pb.ErrorType_UnknownError, errors.ErrorStack(err))
 				return
 			}
+			w.loader.finishFileJob(newCtx, job)
 		}
 	}
 }
 
+// commitJobs executes jobs of a data file in one transaction along with the checkpoint of the last job,
+// so the checkpoint never goes beyond the jobs committed. it returns false if failed or stopped.
+func (w *Worker) commitJobs(ctx context.Context, jobs []*dataJob, runFatalChan chan *pb.ProcessError) bool {
+	if len(jobs) == 0 {
+		return true
+	}
+	last := jobs[len(jobs)-1]
+	sqls := make([]string, 0, len(jobs)+2)
+	sqls = append(sqls, fmt.Sprintf("USE `%s`;", last.schema))
+	for _, job := range jobs {
+		if job.sql != "" {
+			sqls = append(sqls, job.sql)
+		}
+	}
+
+	offsetSQL := w.checkPoint.GenSQL(last.file, last.offset)
+	if offsetSQL != "" {
+		sqls = append(sqls, offsetSQL)
+	}
+
+	err := w.exec.Exec(ctx, sqls, nil)
+	if !w.groupJobs() {
+		w.quota.release(int64(len(last.sql)))
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			// stopped when retrying, the jobs are executed again after resumed
+			log.Infof("[loader] worker %d stops executing jobs of file %s: %v", w.id, last.file, err)
+			return false
+		}
+		// expect pause rather than exit
+		err = errors.Annotatef(err, "file %s", last.file)
+		runFatalChan <- unit.NewProcessError(pb.ErrorType_ExecSQL, errors.ErrorStack(err))
+		return false
+	}
+	if err := w.checkPoint.UpdateOffset(last.file, last.offset); err != nil {
+		err = errors.Annotatef(err, "update checkpoint of file %s", last.file)
+		runFatalChan <- unit.NewProcessError(pb.ErrorType_UnknownError, errors.ErrorStack(err))
+		return false
+	}
+	for _, job := range jobs {
+		w.loader.finishJob(job)
+	}
+	// jobs of a file are executed in order, all rows of the file are restored after the last job executed
+	if last.offset == last.fileSize {
+		if err := w.checkPoint.SaveChecksum(last.file, last.checksum); err != nil {
+			runFatalChan <- unit.NewProcessError(pb.ErrorType_UnknownError, errors.ErrorStack(err))
+			return false
+		}
+	}
+	return true
+}
+
 func (w *Worker) restoreDataFile(ctx context.Context, path, dataFile string, offset int64, table *tableInfo) error {
 	log.Infof("[loader][restore table data sql]%s/%s[start]", path, dataFile)
-	err := w.dispatchSQL(ctx, filepath.Join(w.cfg.Dir, dataFile), offset, table)
+	err := w.dispatchSQL(ctx, filepath.Join(path, dataFile), offset, table)
 	if err != nil {
 		return errors.Trace(err)
 	}
 
-	// dispatchSQL completed, send nil.
+	if !w.waitJobs(ctx) {
+		log.Infof("[loader][restore table data sql]%s/%s[stopped]", path, dataFile)
+		return nil
+	}
+	log.Infof("[loader][restore table data sql]%s/%s[finished]", path, dataFile)
+	return nil
+}
+
+// waitJobs waits for the jobs dispatched to be executed, it returns false if stopped by ctx
+func (w *Worker) waitJobs(ctx context.Context) bool {
+	// dispatching completed, send nil.
 	// we don't want to close and re-make chan frequently
 	// but if we need to re-call w.run, we need re-make jobQueue chan
-	w.jobQueue <- nil
+	select {
+	case <-ctx.Done():
+	case w.jobQueue <- nil:
+	}
 
+	// the executing job is committed or rolled back, so no data is restored beyond the checkpoint
 	w.wg.Wait()
-	log.Infof("[loader][restore table data sql]%s/%s[finished]", w.cfg.Dir, dataFile)
-	return nil
+	return ctx.Err() == nil
 }
 
 func (w *Worker) dispatchSQL(ctx context.Context, file string, offset int64, table *tableInfo) error {
 	var (
-		f   *os.File
-		err error
-		cur int64
+		f        *os.File
+		reader   io.Reader
+		err      error
+		cur      int64
+		fileSize int64
 	)
 
-	f, err = os.Open(file)
-	if err != nil {
-		return errors.Trace(err)
+	// a compressed file or a file in the archive can't be read from the offset directly
+	baseFile := filepath.Base(file)
+	seekable := w.loader.archive == nil && !isCompressedFile(baseFile)
+	if seekable {
+		f, err = os.Open(file)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		defer f.Close()
+
+		finfo, err2 := f.Stat()
+		if err2 != nil {
+			return errors.Trace(err2)
+		}
+		fileSize = finfo.Size()
+		reader = f
+	} else {
+		// positions of a compressed or archived file are counted in decompressed bytes
+		fileSize, err = w.loader.getDecompressedSize(file)
+		if err != nil {
+			return errors.Trace(err)
+		}
 	}
-	defer f.Close()
 
-	finfo, err := f.Stat()
-	if err != nil {
-		return errors.Trace(err)
+	if offset > fileSize {
+		return errors.Errorf("offset %d in checkpoint is beyond the size %d of file %s, the data file may be changed", offset, fileSize, file)
 	}
 
-	baseFile := filepath.Base(file)
-	err = w.checkPoint.Init(baseFile, finfo.Size())
+	err = w.checkPoint.Init(baseFile, fileSize)
 	if err != nil {
 		log.Errorf("init %s checkpoint error:%s", baseFile, err)
 		return errors.Trace(err)
 	}
 
-	cur, err = f.Seek(offset, io.SeekStart)
+	// statements before the applied position in checkpoint have been restored, they must be skipped
+	if pos, ok := w.checkPoint.GetRestoringFileInfo(table.sourceSchema, table.sourceTable)[baseFile]; ok && pos[0] != offset {
+		return errors.Errorf("offset %d to restore file %s is not the applied position %d in checkpoint", offset, file, pos[0])
+	}
+
+	if seekable {
+		cur, err = f.Seek(offset, io.SeekStart)
+	} else {
+		// the data before offset are decompressed and discarded
+		dr, err2 := openDataFile(w.loader.archive, file)
+		if err2 != nil {
+			return errors.Trace(err2)
+		}
+		defer dr.Close()
+
+		cur, err = io.CopyN(ioutil.Discard, dr, offset)
+		reader = dr
+	}
 	if err != nil {
-		return errors.Trace(err)
+		return errors.Annotatef(err, "skip to offset %d of file %s", offset, file)
 	}
 	log.Debugf("read file:%s from offset %d compared to the beginning", file, offset)
 
+	// the checksum of rows is accumulated from the rows restored before
+	checksum, err := w.loader.fileChecksumBefore(file, offset)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
 	lastOffset := cur
+	first := true
+	progress := w.loader.getTableProgress(table.sourceSchema, table.sourceTable)
+	filter, err := w.loader.rowFilters.forTable(table)
+	if err != nil {
+		return errors.Trace(err)
+	}
 
-	data := make([]byte, 0, 1024*1024)
-	br := bufio.NewReader(f)
+	sr := newStatementReader(reader)
 	for {
 		select {
 		case <-ctx.Done():
@@ -229,55 +415,79 @@ func (w *Worker) dispatchSQL(ctx context.Context, file string, offset int64, tab
 		default:
 			// do nothing
 		}
-		line, err := br.ReadString('\n')
-		cur += int64(len(line))
-
+		data, err := sr.next()
 		if err == io.EOF {
+			if stmt := sr.statement(); len(stmt) > 0 {
+				return errors.Errorf("data file %s ends with an incomplete statement %-.100s", file, stmt)
+			}
 			log.Infof("data file %s scanned finished.", file)
 			break
-		} else {
-			realLine := strings.TrimSpace(line[:len(line)-1])
-			if len(realLine) == 0 {
-				continue
+		} else if err != nil {
+			return errors.Annotatef(err, "read data file %s", file)
+		}
+		// progress is counted by the bytes consumed by statements, no matter how long the lines are
+		cur += int64(len(data))
+
+		query := sr.statement()
+		if strings.HasPrefix(query, "/*") && strings.HasSuffix(query, "*/;") {
+			continue
+		}
+		// rows are hashed and counted as they are dumped, including the ones dropped by the row filter
+		checksum += rowsChecksum(query)
+		rows := countRows(query)
+
+		if w.loader.columnMapping != nil || filter != nil {
+			// column mapping, row filter and route table
+			query, err = reassemble(data, table, w.loader.columnMapping, filter)
+			if err != nil {
+				return errors.Annotatef(err, "file %s", file)
 			}
+		} else if table.sourceTable != table.targetTable {
+			query = renameShardingTable(query, table.sourceTable, table.targetTable)
+		}
 
-			data = append(data, []byte(line)...)
-			if realLine[len(realLine)-1] == ';' {
-				query := strings.TrimSpace(string(data))
-				if strings.HasPrefix(query, "/*") && strings.HasSuffix(query, "*/;") {
-					data = data[0:0]
-					continue
-				}
+		// the job of a statement with all rows dropped is still queued, so the checkpoint and progress move forward
+		if query != "" && !strings.Contains(query, "INSERT INTO") {
+			return errors.Errorf("[invalid insert sql][sql]%s", query)
+		}
 
-				if w.loader.columnMapping != nil {
-					// column mapping and route table
-					query, err = reassemble(data, table, w.loader.columnMapping)
-					if err != nil {
-						return errors.Annotatef(err, "file %s", file)
-					}
-				} else if table.sourceTable != table.targetTable {
-					query = renameShardingTable(query, table.sourceTable, table.targetTable)
-				}
+		log.Debugf("sql: %-.100v", query)
 
-				idx := strings.Index(query, "INSERT INTO")
-				if idx < 0 {
-					return errors.Errorf("[invalid insert sql][sql]%s", query)
-				}
+		if w.loader.limiter.wait(ctx, cur-lastOffset) != nil {
+			log.Infof("worker %d sql dispatcher is ready to quit.", w.id)
+			return nil
+		}
 
-				log.Debugf("sql: %-.100v", query)
-				data = data[0:0]
+		// the first statement restored must start from the applied position in checkpoint
+		if first && lastOffset != offset {
+			return errors.Errorf("the first statement to restore in file %s starts at %d, not the applied position %d in checkpoint", file, lastOffset, offset)
+		}
+		first = false
+
+		j := &dataJob{
+			sql:        query,
+			schema:     table.targetSchema,
+			file:       baseFile,
+			offset:     cur,
+			lastOffset: lastOffset,
+			fileSize:   fileSize,
+			rows:       rows,
+			progress:   progress,
+			checksum:   checksum,
+		}
+		lastOffset = cur
 
-				j := &dataJob{
-					sql:        query,
-					schema:     table.targetSchema,
-					file:       baseFile,
-					offset:     cur,
-					lastOffset: lastOffset,
-				}
-				lastOffset = cur
+		// blocks until the executor catches up if too many bytes queued
+		if w.quota.acquire(ctx, int64(len(j.sql))) != nil {
+			log.Infof("worker %d sql dispatcher is ready to quit.", w.id)
+			return nil
+		}
 
-				w.jobQueue <- j
-			}
+		select {
+		case <-ctx.Done():
+			log.Infof("worker %d sql dispatcher is ready to quit.", w.id)
+			return nil
+		case w.jobQueue <- j:
 		}
 	}
 
@@ -300,6 +510,9 @@ type Loader struct {
 	cfg        *config.SubTaskConfig
 	checkPoint CheckPoint
 
+	// connections to the downstream database shared by workers and restoring schemas, see config.LoaderConfig.MaxConnections
+	toDB *sql.DB
+
 	// db -> tables
 	// table -> data files
 	db2Tables  map[string]Tables2DataFiles
@@ -307,20 +520,61 @@ type Loader struct {
 
 	// for every worker goroutine, not for every data file
 	workerWg *sync.WaitGroup
+	// for background goroutines of the restoring, see goBackground
+	backgroundWg sync.WaitGroup
 
 	fileJobQueue       chan *fileJob
 	fileJobQueueClosed sync2.AtomicBool
+	// finished file jobs, only used when limiting the table concurrency
+	fileJobFinished chan *fileJob
+
+	// limits the restoring rate of all workers, nil means no limit
+	limiter *rateLimiter
+
+	// write sqls rather than executing them in dry-run mode
+	sqlWriter *utils.SQLWriter
+
+	// the dump archive opened if the dump directory is a gzip-compressed tarball, nil otherwise
+	archive *dumpArchive
+
+	logger         log.Logger     // the global logger by default, see SetLogger
+	statusCallback StatusCallback // nil if not set, see SetStatusCallback
 
 	tableRouter   *router.Table
 	bwList        *filter.Filter
+	restoreList   *restoreTables
+	rowFilters    *rowFilters
 	columnMapping *cm.Mapping
 
 	pool   []*Worker
 	closed sync2.AtomicBool
+	paused sync2.AtomicBool
+
+	// cancels the restoring of Process, and restoreDone is closed after the restoring returned
+	processLock   sync.Mutex
+	processCancel context.CancelFunc
+	restoreDone   chan struct{}
 
 	totalDataSize    sync2.AtomicInt64
 	finishedDataSize sync2.AtomicInt64
-	metaBinlog       sync2.AtomicString
+	totalRows        sync2.AtomicInt64 // rows of INSERT statements in all data files
+	finishedRows     sync2.AtomicInt64
+	metaBinlogName   sync2.AtomicString // binlog position parsed from the metadata of dumped files
+	metaBinlogPos    sync2.AtomicUint32
+	metaBinlogGTID   sync2.AtomicString // GTID set parsed from the metadata, empty if GTID is not enabled in the source
+	etaSeconds       sync2.AtomicInt64  // estimated remaining seconds, -1 if unknown
+
+	// source table (`db`.`table`) -> restoring progress, re-created in every prepare
+	// data file path -> decompressed size, only for compressed data files
+	// data file path -> count of rows in the file
+	progressLock      sync.RWMutex
+	tableProgresses   map[string]*tableProgress
+	decompressedSizes map[string]int64
+	dataFileRows      map[string]int64
+
+	// results of validation after all data restored
+	validationLock sync.RWMutex
+	validations    []*pb.TableValidation
 
 	// record process error rather than log.Fatal
 	runFatalChan chan *pb.ProcessError
@@ -337,9 +591,25 @@ func NewLoader(cfg *config.SubTaskConfig) *Loader {
 	}
 	loader.tableRouter, _ = router.NewTableRouter(cfg.CaseSensitive, []*router.TableRule{})
 	loader.fileJobQueueClosed.Set(true) // not open yet
+	loader.etaSeconds.Set(-1)
+	loader.limiter = newRateLimiter(cfg.RateLimit)
+	loader.logger = log.GlobalLogger()
 	return loader
 }
 
+// SetLogger sets the logger of the loader, like a logger with fields of the task,
+// it should be called before the loader is initialized.
+func (l *Loader) SetLogger(logger log.Logger) {
+	l.logger = logger
+}
+
+// SetStatusCallback sets the callback receiving the status every time it's printed, with the progress and ETA of tables.
+// the callback is called in order in another goroutine, statuses not delivered yet are dropped if newer ones come.
+// it should be called before the loader is processing.
+func (l *Loader) SetStatusCallback(callback StatusCallback) {
+	l.statusCallback = callback
+}
+
 // Type implements Unit.Type
 func (l *Loader) Type() pb.UnitType {
 	return pb.UnitType_Load
@@ -348,13 +618,29 @@ func (l *Loader) Type() pb.UnitType {
 // Init initializes loader for a load task, but not start Process.
 // if fail, it should not call l.Close.
 func (l *Loader) Init() error {
-	checkpoint, err := newRemoteCheckPoint(l.cfg, l.checkpointID())
+	checkpoint, err := newCheckPoint(l.cfg, l.checkpointID())
 	if err != nil {
 		return errors.Trace(err)
 	}
 	l.checkPoint = checkpoint
 
+	if l.cfg.DryRun {
+		l.sqlWriter, err = utils.NewSQLWriter(l.cfg.DryRunFile)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		log.Info("[loader] in dry-run mode, sqls will not be executed in downstream")
+	}
+
 	l.bwList = filter.New(l.cfg.CaseSensitive, l.cfg.BWList)
+	l.restoreList, err = newRestoreTables(l.cfg.CaseSensitive, l.cfg.RestoreAllowTables, l.cfg.RestoreBlockTables)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	l.rowFilters, err = newRowFilters(l.cfg.CaseSensitive, l.cfg.RowFilters)
+	if err != nil {
+		return errors.Trace(err)
+	}
 
 	if l.cfg.RemoveMeta {
 		err2 := l.checkPoint.Clear()
@@ -376,9 +662,19 @@ func (l *Loader) Init() error {
 		}
 	}
 
+	l.toDB, err = createConnPool(l.cfg)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
 	return nil
 }
 
+// newConn returns a Conn executing statements with connections in the pool shared by workers and restoring schemas
+func (l *Loader) newConn() *Conn {
+	return &Conn{cfg: l.cfg, db: l.toDB, shared: true, session: newSession(l.cfg), sqlWriter: l.sqlWriter}
+}
+
 // Process implements Unit.Process
 func (l *Loader) Process(ctx context.Context, pr chan pb.ProcessResult) {
 	loaderExitWithErrorCounter.WithLabelValues(l.cfg.Name).Add(0)
@@ -386,8 +682,13 @@ func (l *Loader) Process(ctx context.Context, pr chan pb.ProcessResult) {
 	newCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
+	restoreDone := make(chan struct{})
+	l.processLock.Lock()
+	l.processCancel, l.restoreDone = cancel, restoreDone
+	l.processLock.Unlock()
+	l.paused.Set(false)
+
 	l.newFileJobQueue()
-	l.getMydumpMetadata()
 
 	l.runFatalChan = make(chan *pb.ProcessError, 2*l.cfg.PoolSize)
 	errs := make([]*pb.ProcessError, 0, 2)
@@ -409,18 +710,23 @@ func (l *Loader) Process(ctx context.Context, pr chan pb.ProcessResult) {
 	}()
 
 	err := l.Restore(newCtx)
+	close(restoreDone)
 	close(l.runFatalChan) // Restore returned, all potential fatal sent to l.runFatalChan
 	wg.Wait()             // wait for receive all fatal from l.runFatalChan
 
 	if err != nil {
 		loaderExitWithErrorCounter.WithLabelValues(l.cfg.Name).Inc()
-		errs = append(errs, unit.NewProcessError(pb.ErrorType_UnknownError, errors.ErrorStack(err)))
+		errType := pb.ErrorType_UnknownError
+		if errors.Cause(err) == errValidationMismatch {
+			errType = pb.ErrorType_CheckFailed
+		}
+		errs = append(errs, unit.NewProcessError(errType, errors.ErrorStack(err)))
 	}
 
 	isCanceled := false
 	if len(errs) == 0 {
 		select {
-		case <-ctx.Done():
+		case <-newCtx.Done(): // canceled from external or by Pause
 			isCanceled = true
 		default:
 		}
@@ -455,7 +761,7 @@ func (l *Loader) skipSchemaAndTable(table *filter.Table) bool {
 
 	tbs := []*filter.Table{table}
 	tbs = l.bwList.ApplyOn(tbs)
-	return len(tbs) == 0
+	return len(tbs) == 0 || !l.restoreList.match(table.Schema, table.Name)
 }
 
 func (l *Loader) isClosed() bool {
@@ -470,13 +776,36 @@ func (l *Loader) IsFreshTask() (bool, error) {
 
 // Restore begins the restore process.
 func (l *Loader) Restore(ctx context.Context) error {
+	if err := l.openArchive(); err != nil {
+		return errors.Trace(err)
+	}
+
+	// the syncer starts from the position in metadata after restored, so check it before restoring
+	if err := l.getMydumpMetadata(); err != nil {
+		return errors.Trace(err)
+	}
+	// the handoff of a previous restoring is not valid until this one completes
+	if err := utils.RemoveHandoff(l.cfg.Dir); err != nil {
+		return errors.Annotatef(err, "remove handoff")
+	}
+
 	if err := l.prepare(); err != nil {
 		log.Errorf("[loader] scan dir[%s] failed, err[%v]", l.cfg.Dir, err)
 		return errors.Trace(err)
 	}
 
+	if l.cfg.CheckpointImport != "" {
+		if err := l.importCheckpointFile(l.cfg.CheckpointImport); err != nil {
+			return errors.Trace(err)
+		}
+	}
+
 	// not update checkpoint in memory when restoring, so when re-Restore, we need to load checkpoint from DB
 	l.checkPoint.Load()
+	// checkpoints of files not in the dump, like the files of a previous dump with another layout, confuse the progress
+	if err := l.checkPoint.Prune(l.dataFileNames()); err != nil {
+		return errors.Annotatef(err, "prune checkpoints")
+	}
 	l.checkPoint.CalcProgress(l.db2Tables)
 	l.loadFinishedSize()
 
@@ -485,23 +814,183 @@ func (l *Loader) Restore(ctx context.Context) error {
 		return errors.Trace(err)
 	}
 
-	go l.PrintStatus(ctx)
+	l.goBackground(func() { l.PrintStatus(ctx) })
+	if l.cfg.ThrottleThreadsRunning > 0 && l.limiter != nil && l.sqlWriter == nil {
+		th := newThrottle(l.limiter, threadsRunning(l.toDB), float64(l.cfg.ThrottleThreadsRunning))
+		l.goBackground(func() { th.run(ctx) })
+	}
+
+	flushCtx, cancel := context.WithCancel(ctx)
+	l.goBackground(func() { l.flushCheckPoint(flushCtx) })
+	err := l.restoreData(ctx)
+	cancel()
+	if err != nil && ctx.Err() != nil {
+		// stopped when restoring, the executing transactions are rolled back
+		log.Infof("[loader] restoring data stopped: %v", err)
+		err = nil
+	}
+
+	// jobs executed are valid even if restoring failed, so save their checkpoints anyway
+	if err2 := l.checkPoint.Flush(); err2 != nil {
+		if err == nil {
+			return errors.Annotatef(err2, "flush checkpoint")
+		}
+		log.Errorf("[loader] flush checkpoint error %v", err2)
+	}
+	if err != nil || ctx.Err() != nil {
+		return errors.Trace(err)
+	}
+
+	if l.cfg.Validation != "" {
+		if l.cfg.DryRun {
+			log.Infof("[loader] skip %s validation in dry-run mode", l.cfg.Validation)
+		} else if err = l.validate(ctx); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return errors.Trace(l.writeHandoff())
+}
+
+// writeHandoff writes the binlog position in metadata as the handoff to the syncer, after the dump restored completely
+func (l *Loader) writeHandoff() error {
+	h := &utils.Handoff{
+		Task:       l.cfg.Name,
+		BinLogName: l.metaBinlogName.Get(),
+		BinLogPos:  l.metaBinlogPos.Get(),
+		BinlogGTID: l.metaBinlogGTID.Get(),
+	}
+	if err := utils.WriteHandoff(l.cfg.Dir, h); err != nil {
+		return errors.Annotatef(err, "write handoff")
+	}
+	log.Infof("[loader] handoff binlog position %s, GTID set %s to syncer", h.Pos(), h.BinlogGTID)
+	return nil
+}
+
+// ExportCheckpoint exports the checkpoints of the loader, like when the task is paused before moved to another DM-worker,
+// they can be imported by ImportCheckpoint of a loader with the same dump. it should be called after the loader initialized.
+func (l *Loader) ExportCheckpoint() ([]byte, error) {
+	data, err := l.checkPoint.Export()
+	return data, errors.Trace(err)
+}
+
+// ImportCheckpoint replaces the checkpoints of the loader with the ones exported by ExportCheckpoint,
+// so the loader continues the restoring from them without restoring the data files again.
+// it should be called after the loader initialized and before processing, see importCheckpoint.
+func (l *Loader) ImportCheckpoint(data []byte) error {
+	if err := l.openArchive(); err != nil {
+		return errors.Trace(err)
+	}
+	if err := l.prepare(); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(l.importCheckpoint(data))
+}
+
+// importCheckpoint imports the exported checkpoints after the dump prepared,
+// every data file in the checkpoints must be in the dump with the same size, so the offsets are the positions of the same statements.
+// data files not in the checkpoints are restored from the beginning.
+func (l *Loader) importCheckpoint(data []byte) error {
+	points, err := importPoints(data)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	existing := toFileSet(l.dataFileNames())
+	for filename, point := range points {
+		if _, ok := existing[filename]; !ok {
+			return errors.NotFoundf("data file %s of exported checkpoints in dump %s", filename, l.cfg.Dir)
+		}
+		size, err := l.getDecompressedSize(filepath.Join(l.dumpDir(), filename))
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if size != point.EndPos {
+			return errors.Errorf("end position %d in exported checkpoint of data file %s mismatches its size %d in dump, the dump may be different", point.EndPos, filename, size)
+		}
+	}
+	return errors.Trace(l.checkPoint.Import(data))
+}
 
-	if err := l.restoreData(ctx); err != nil {
+// importCheckpointFile imports the exported checkpoints in the file if no checkpoints are recorded,
+// checkpoints recorded are newer, like the ones imported before and advanced.
+func (l *Loader) importCheckpointFile(path string) error {
+	count, err := l.checkPoint.Count()
+	if err != nil {
 		return errors.Trace(err)
 	}
+	if count > 0 {
+		log.Infof("[loader] skip importing checkpoints from %s, %d checkpoints are recorded", path, count)
+		return nil
+	}
 
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return errors.Annotatef(err, "read exported checkpoints")
+	}
+	if err = l.importCheckpoint(data); err != nil {
+		return errors.Annotatef(err, "import checkpoints from %s", path)
+	}
+	log.Infof("[loader] imported checkpoints from %s", path)
 	return nil
 }
 
+// flushCheckPoint saves checkpoints not saved yet every checkpointFlushInterval until ctx done
+func (l *Loader) flushCheckPoint(ctx context.Context) {
+	ticker := time.NewTicker(checkpointFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// retried in the next tick, and failed in the end of Restore if it still fails
+			if err := l.checkPoint.Flush(); err != nil {
+				log.Warnf("[loader] flush checkpoint error %v", err)
+			}
+		}
+	}
+}
+
 func (l *Loader) loadFinishedSize() {
+	l.finishedDataSize.Set(0)
+	l.finishedRows.Set(0)
 	results := l.checkPoint.GetAllRestoringFileInfo()
 	for _, pos := range results {
 		l.finishedDataSize.Add(pos[0])
 	}
+
+	for db, tables := range l.db2Tables {
+		for table := range tables {
+			progress := l.getTableProgress(db, table)
+			if progress == nil {
+				continue
+			}
+			for file, pos := range l.checkPoint.GetRestoringFileInfo(db, table) {
+				progress.finishedSize.Add(pos[0])
+				if len(pos) == 2 && pos[0] == pos[1] {
+					progress.finishedFiles.Add(1)
+				}
+				rows := l.finishedFileRows(filepath.Join(l.dumpDir(), file), pos)
+				progress.finishedRows.Add(rows)
+				l.finishedRows.Add(rows)
+			}
+		}
+	}
 }
 
-// Close do graceful shutdown
+// goBackground runs fn in a goroutine along with the restoring, fn should return after the context of the restoring done.
+// Close waits for it returned.
+func (l *Loader) goBackground(fn func()) {
+	l.backgroundWg.Add(1)
+	go func() {
+		defer l.backgroundWg.Done()
+		fn()
+	}()
+}
+
+// Close shuts down the loader gracefully, it's idempotent, and safe to call after the processing failed or Init failed.
+// the restoring in progress is stopped like Pause, the checkpoints of all committed statements are saved,
+// including the ones batched by checkpoint-batch, and then the checkpoint and connections are closed.
+// background goroutines like PrintStatus have returned when it returns.
 func (l *Loader) Close() {
 	l.Lock()
 	defer l.Unlock()
@@ -509,8 +998,27 @@ func (l *Loader) Close() {
 		return
 	}
 
+	l.stopRestore()
 	l.stopLoad()
-	l.checkPoint.Close()
+	l.backgroundWg.Wait()
+	if l.checkPoint != nil {
+		// it's flushed when the restoring stopped, flush again in case the restoring is not started
+		if err := l.checkPoint.Flush(); err != nil {
+			log.Errorf("[loader] flush checkpoint error %v", err)
+		}
+		l.checkPoint.Close()
+	}
+	if l.toDB != nil {
+		if err := l.toDB.Close(); err != nil {
+			log.Errorf("[loader] close connections to the downstream database error %v", err)
+		}
+	}
+	if l.sqlWriter != nil {
+		if err := l.sqlWriter.Close(); err != nil {
+			log.Errorf("[loader] close dry-run sql writer error %v", err)
+		}
+	}
+	l.closeArchive()
 	l.closed.Set(true)
 }
 
@@ -529,15 +1037,31 @@ func (l *Loader) stopLoad() {
 	log.Debug("all workers has been closed")
 }
 
-// Pause pauses the process, and it can be resumed later
-// should cancel context from external
+// Pause pauses the process, and it can be resumed later.
+// it stops dispatching data files, waits for the executing statements committed or rolled back,
+// and returns after the checkpoint flushed, so no data is restored beyond the checkpoint after paused.
 func (l *Loader) Pause() {
 	if l.isClosed() {
 		log.Warn("[loader] try to pause, but already closed")
 		return
 	}
 
+	l.stopRestore()
 	l.stopLoad()
+	l.paused.Set(true)
+	log.Info("[loader] paused")
+}
+
+// stopRestore cancels the restoring of Process, and waits for it returned with the checkpoint flushed
+func (l *Loader) stopRestore() {
+	l.processLock.Lock()
+	cancel, done := l.processCancel, l.restoreDone
+	l.processLock.Unlock()
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-done
 }
 
 // Resume resumes the paused process
@@ -547,7 +1071,7 @@ func (l *Loader) Resume(ctx context.Context, pr chan pb.ProcessResult) {
 		return
 	}
 
-	// continue the processing
+	// continue the processing from the checkpoint
 	l.Process(ctx, pr)
 }
 
@@ -596,10 +1120,14 @@ func (l *Loader) Update(cfg *config.SubTaskConfig) error {
 		return errors.Trace(err)
 	}
 
+	// update rate limit, it's safe because workers are not running when updating
+	l.limiter = newRateLimiter(cfg.RateLimit)
+
 	// update l.cfg
 	l.cfg.BWList = cfg.BWList
 	l.cfg.RouteRules = cfg.RouteRules
 	l.cfg.ColumnMappingRules = cfg.ColumnMappingRules
+	l.cfg.RateLimit = cfg.RateLimit
 	return nil
 }
 
@@ -692,21 +1220,28 @@ func (l *Loader) prepareTableFiles(files map[string]struct{}) error {
 }
 
 func (l *Loader) prepareDataFiles(files map[string]struct{}) error {
+	l.totalDataSize.Set(0)
+	l.totalRows.Set(0)
+	progresses := make(map[string]*tableProgress)
+	decompressedSizes := make(map[string]int64)
+	dataFileRows := make(map[string]int64)
 	for file := range files {
-		if !strings.HasSuffix(file, ".sql") || strings.Index(file, "-schema.sql") >= 0 ||
-			strings.Index(file, "-schema-create.sql") >= 0 {
+		// data files may be compressed, like `db.table.sql.gz`
+		sqlFile := trimCompressedSuffix(file)
+		if !strings.HasSuffix(sqlFile, ".sql") || strings.Index(sqlFile, "-schema.sql") >= 0 ||
+			strings.Index(sqlFile, "-schema-create.sql") >= 0 {
 			continue
 		}
 
 		// ignore view / triggers
-		if strings.Index(file, "-schema-view.sql") >= 0 || strings.Index(file, "-schema-triggers.sql") >= 0 ||
-			strings.Index(file, "-schema-post.sql") >= 0 {
+		if strings.Index(sqlFile, "-schema-view.sql") >= 0 || strings.Index(sqlFile, "-schema-triggers.sql") >= 0 ||
+			strings.Index(sqlFile, "-schema-post.sql") >= 0 {
 			log.Warnf("[loader] ignore unsupport view/trigger: %s", file)
 			continue
 		}
 
-		idx := strings.Index(file, ".sql")
-		name := file[:idx]
+		idx := strings.Index(sqlFile, ".sql")
+		name := sqlFile[:idx]
 		fields := strings.Split(name, ".")
 		if len(fields) != 2 && len(fields) != 3 {
 			log.Warnf("invalid db table sql file - %s", file)
@@ -728,34 +1263,74 @@ func (l *Loader) prepareDataFiles(files map[string]struct{}) error {
 			return errors.Errorf("invalid data sql file, cannot find table - %s", file)
 		}
 
-		size, err := utils.GetFileSize(filepath.Join(l.cfg.Dir, file))
+		// rows are counted by scanning the file, a compressed or archived file is decompressed to get its size meanwhile
+		path := filepath.Join(l.dumpDir(), file)
+		stats, err := scanDataFileRows(l.archive, path, 0)
 		if err != nil {
 			return errors.Trace(err)
 		}
+		size, rows := stats.size, stats.rows
+		if l.archive != nil || isCompressedFile(file) {
+			decompressedSizes[path] = size
+		}
+		dataFileRows[path] = rows
 		l.totalDataSize.Add(size)
+		l.totalRows.Add(rows)
+
+		progress, ok := progresses[tableName(db, table)]
+		if !ok {
+			progress = &tableProgress{}
+			progresses[tableName(db, table)] = progress
+		}
+		progress.totalSize += size
+		progress.totalRows += rows
+		progress.totalFiles++
 
 		dataFiles = append(dataFiles, file)
 		dataFileCounter.WithLabelValues(l.cfg.Name).Inc()
 		tables[table] = dataFiles
 	}
 
+	l.progressLock.Lock()
+	l.tableProgresses = progresses
+	l.decompressedSizes = decompressedSizes
+	l.dataFileRows = dataFileRows
+	l.progressLock.Unlock()
+
 	dataSizeCounter.WithLabelValues(l.cfg.Name).Add(float64(l.totalDataSize.Get()))
 	return nil
 }
 
+// dataFileNames returns names of all data files to restore
+func (l *Loader) dataFileNames() []string {
+	var files []string
+	for _, tables := range l.db2Tables {
+		for _, dataFiles := range tables {
+			files = append(files, dataFiles...)
+		}
+	}
+	return files
+}
+
 func (l *Loader) prepare() error {
 	begin := time.Now()
 	defer func() {
 		log.Infof("[loader] prepare takes %f seconds", time.Since(begin).Seconds())
 	}()
 
-	// check if mydumper dir data exists.
-	if !utils.IsDirExists(l.cfg.Dir) {
-		return errors.Errorf("%s is not exists or it's not a dir", l.cfg.Dir)
-	}
+	var files map[string]struct{}
+	if l.archive != nil {
+		// all files in the archive
+		files = l.archive.files
+	} else {
+		// check if mydumper dir data exists.
+		if !utils.IsDirExists(l.cfg.Dir) {
+			return errors.Errorf("%s is not exists or it's not a dir", l.cfg.Dir)
+		}
 
-	// collect dir files.
-	files := CollectDirFiles(l.cfg.Dir)
+		// collect dir files.
+		files = CollectDirFiles(l.cfg.Dir)
+	}
 
 	log.Debugf("collected files:%+v", files)
 
@@ -780,8 +1355,8 @@ func (l *Loader) prepare() error {
 }
 
 // restoreSchema creates schema
-func (l *Loader) restoreSchema(conn *Conn, sqlFile, schema string) error {
-	err := l.restoreStructure(conn, sqlFile, schema, "")
+func (l *Loader) restoreSchema(ctx context.Context, conn *Conn, sqlFile, schema string) error {
+	err := l.restoreStructure(ctx, conn, sqlFile, schema, "")
 	if err != nil {
 		if isErrDBExists(err) {
 			log.Infof("[loader][database already exists, skip]%s", sqlFile)
@@ -793,8 +1368,8 @@ func (l *Loader) restoreSchema(conn *Conn, sqlFile, schema string) error {
 }
 
 // restoreTable creates table
-func (l *Loader) restoreTable(conn *Conn, sqlFile, schema, table string) error {
-	err := l.restoreStructure(conn, sqlFile, schema, table)
+func (l *Loader) restoreTable(ctx context.Context, conn *Conn, sqlFile, schema, table string) error {
+	err := l.restoreStructure(ctx, conn, sqlFile, schema, table)
 	if err != nil {
 		if isErrTableExists(err) {
 			log.Infof("[loader][table already exists, skip]%s", sqlFile)
@@ -806,51 +1381,47 @@ func (l *Loader) restoreTable(conn *Conn, sqlFile, schema, table string) error {
 }
 
 // restoreStruture creates schema or table
-func (l *Loader) restoreStructure(conn *Conn, sqlFile string, schema string, table string) error {
+func (l *Loader) restoreStructure(ctx context.Context, conn *Conn, sqlFile string, schema string, table string) error {
 	f, err := os.Open(sqlFile)
 	if err != nil {
 		return errors.Trace(err)
 	}
 	defer f.Close()
 
-	data := make([]byte, 0, 1024*1024)
-	br := bufio.NewReader(f)
+	// stored routines and triggers are defined in `DELIMITER` blocks, they are restored as one statement
+	sr := newStatementReader(f)
 	for {
-		line, err := br.ReadString('\n')
+		_, err := sr.next()
 		if err == io.EOF {
-			break
-		} else {
-			realLine := strings.TrimSpace(line[:len(line)-1])
-			if len(realLine) == 0 {
-				continue
+			if stmt := sr.statement(); len(stmt) > 0 {
+				return errors.Errorf("schema file %s ends with an incomplete statement %-.100s", sqlFile, stmt)
 			}
+			break
+		} else if err != nil {
+			return errors.Annotatef(err, "read schema file %s", sqlFile)
+		}
 
-			data = append(data, []byte(realLine)...)
-			if data[len(data)-1] == ';' {
-				query := string(data)
-				data = data[0:0]
-				if strings.HasPrefix(query, "/*") && strings.HasSuffix(query, "*/;") {
-					continue
-				}
+		query := sr.statement()
+		if strings.HasPrefix(query, "/*") && strings.HasSuffix(query, "*/;") {
+			continue
+		}
 
-				var sqls []string
-				dstSchema, dstTable := fetchMatchedLiteral(l.tableRouter, schema, table)
-				// for table
-				if table != "" {
-					sqls = append(sqls, fmt.Sprintf("USE `%s`;", dstSchema))
-					query = renameShardingTable(query, table, dstTable)
-				} else {
-					query = renameShardingSchema(query, schema, dstSchema)
-				}
+		var sqls []string
+		dstSchema, dstTable := fetchMatchedLiteral(l.tableRouter, schema, table)
+		// for table
+		if table != "" {
+			sqls = append(sqls, fmt.Sprintf("USE `%s`;", dstSchema))
+			query = renameShardingTable(query, table, dstTable)
+		} else {
+			query = renameShardingSchema(query, schema, dstSchema)
+		}
 
-				log.Debugf("query:%s", query)
+		log.Debugf("query:%s", query)
 
-				sqls = append(sqls, query)
-				err = conn.executeDDL(sqls, true)
-				if err != nil {
-					return errors.Trace(err)
-				}
-			}
+		sqls = append(sqls, query)
+		err = conn.executeDDL(ctx, sqls, true)
+		if err != nil {
+			return errors.Trace(err)
 		}
 	}
 
@@ -905,11 +1476,8 @@ func causeErr(err error) error {
 func (l *Loader) restoreData(ctx context.Context) error {
 	begin := time.Now()
 
-	conn, err := createConn(l.cfg)
-	if err != nil {
-		return errors.Trace(err)
-	}
-	defer conn.db.Close()
+	var err error
+	conn := l.newConn()
 
 	dispatchMap := make(map[string]*fileJob)
 
@@ -923,9 +1491,9 @@ func (l *Loader) restoreData(ctx context.Context) error {
 		tables := l.db2Tables[db]
 
 		// create db
-		dbFile := fmt.Sprintf("%s/%s-schema-create.sql", l.cfg.Dir, db)
+		dbFile := fmt.Sprintf("%s/%s-schema-create.sql", l.dumpDir(), db)
 		log.Infof("[loader][run db schema]%s[start]", dbFile)
-		err = l.restoreSchema(conn, dbFile, db)
+		err = l.restoreSchema(ctx, conn, dbFile, db)
 		if err != nil {
 			return errors.Trace(err)
 		}
@@ -937,7 +1505,7 @@ func (l *Loader) restoreData(ctx context.Context) error {
 		}
 		for _, table := range tnames {
 			dataFiles := tables[table]
-			tableFile := fmt.Sprintf("%s/%s.%s-schema.sql", l.cfg.Dir, db, table)
+			tableFile := fmt.Sprintf("%s/%s.%s-schema.sql", l.dumpDir(), db, table)
 			if _, ok := l.tableInfos[tableName(db, table)]; !ok {
 				l.tableInfos[tableName(db, table)], err = parseTable(l.tableRouter, db, table, tableFile)
 				if err != nil {
@@ -952,7 +1520,7 @@ func (l *Loader) restoreData(ctx context.Context) error {
 
 			// create table
 			log.Infof("[loader][run table schema]%s[start]", tableFile)
-			err := l.restoreTable(conn, tableFile, db, table)
+			err := l.restoreTable(ctx, conn, tableFile, db, table)
 			if err != nil {
 				return errors.Trace(err)
 			}
@@ -977,6 +1545,10 @@ func (l *Loader) restoreData(ctx context.Context) error {
 				posSet, ok := restoringFiles[file]
 				if ok {
 					offset = posSet[0]
+					if len(posSet) == 2 && posSet[0] == posSet[1] {
+						log.Infof("[loader] data file %s has been restored, skip it", file)
+						continue
+					}
 				}
 
 				j := &fileJob{
@@ -985,6 +1557,7 @@ func (l *Loader) restoreData(ctx context.Context) error {
 					dataFile: file,
 					offset:   offset,
 					info:     info,
+					copy:     l.canCopyTable(info, len(dataFiles), offset),
 				}
 				dispatchMap[fmt.Sprintf("%s_%s_%s", db, table, file)] = j
 			}
@@ -992,24 +1565,126 @@ func (l *Loader) restoreData(ctx context.Context) error {
 	}
 	log.Infof("[loader] create tables takes %f seconds", time.Since(begin).Seconds())
 
-	// a simple and naive approach to dispatch files randomly based on the feature of golang map(range by random)
+	l.dispatchFileJobs(ctx, dispatchMap)
+	l.closeFileJobQueue() // all data file dispatched, close it
+
+	log.Info("[loader] all data files have been dispatched, waiting for them finished")
+	l.workerWg.Wait()
+
+	log.Infof("[loader] all data files has been finished, takes %f seconds", time.Since(begin).Seconds())
+	return nil
+}
+
+// dispatchFileJobs dispatches file jobs to workers,
+// at most cfg.TableConcurrency data files of a table are dispatched and not finished at the same time.
+func (l *Loader) dispatchFileJobs(ctx context.Context, dispatchMap map[string]*fileJob) {
+	// set before dispatching, so workers see it after receiving file jobs
+	limit := l.cfg.TableConcurrency
+	if limit <= 0 {
+		l.fileJobFinished = nil
+		// a simple and naive approach to dispatch files randomly based on the feature of golang map(range by random)
+		for _, j := range dispatchMap {
+			select {
+			case <-ctx.Done():
+				log.Infof("stop dispatch data file job because %v", ctx.Err())
+				return
+			case l.fileJobQueue <- j:
+			}
+		}
+		return
+	}
+
+	// source table -> file jobs not dispatched yet
+	pending := make(map[string][]*fileJob)
 	for _, j := range dispatchMap {
+		key := tableName(j.schema, j.table)
+		pending[key] = append(pending[key], j)
+	}
+	running := make(map[string]int)
+	// file jobs dispatched but not finished, wait for all of them even after all dispatched, or workers block in finishFileJob
+	unfinished := 0
+	// at most pool size files are restoring
+	l.fileJobFinished = make(chan *fileJob, l.cfg.PoolSize)
+
+	for len(pending) > 0 || unfinished > 0 {
+		// pick a file job whose table doesn't reach the limit, sending to the nil queue blocks if not found
+		var (
+			key   string
+			next  *fileJob
+			queue chan *fileJob
+		)
+		for k, jobs := range pending {
+			if running[k] < limit {
+				key, next, queue = k, jobs[0], l.fileJobQueue
+				break
+			}
+		}
+
 		select {
 		case <-ctx.Done():
 			log.Infof("stop dispatch data file job because %v", ctx.Err())
-			break
-		case l.fileJobQueue <- j:
+			return
+		case queue <- next:
+			running[key]++
+			unfinished++
+			if len(pending[key]) == 1 {
+				delete(pending, key)
+			} else {
+				pending[key] = pending[key][1:]
+			}
+		case j := <-l.fileJobFinished:
+			running[tableName(j.schema, j.table)]--
+			unfinished--
 		}
 	}
-	l.closeFileJobQueue() // all data file dispatched, close it
+}
 
-	log.Info("[loader] all data files have been dispatched, waiting for them finished")
-	l.workerWg.Wait()
+// finishFileJob notifies the dispatcher that the file job is finished
+func (l *Loader) finishFileJob(ctx context.Context, job *fileJob) {
+	finished := l.fileJobFinished
+	if finished == nil {
+		return
+	}
+	select {
+	case <-ctx.Done():
+	case finished <- job:
+	}
+}
 
-	log.Infof("[loader] all data files has been finished, takes %f seconds", time.Since(begin).Seconds())
+// openArchive opens the dump archive if the dump directory is an archive, the one opened before is closed
+func (l *Loader) openArchive() error {
+	l.closeArchive()
+	if !isDumpArchive(l.cfg.Dir) {
+		return nil
+	}
+	archive, err := openDumpArchive(l.cfg.Dir)
+	if err != nil {
+		return errors.Annotatef(err, "open dump archive")
+	}
+	l.archive = archive
 	return nil
 }
 
+// closeArchive removes the files extracted from the dump archive
+func (l *Loader) closeArchive() {
+	if l.archive == nil {
+		return
+	}
+	if err := l.archive.close(); err != nil {
+		log.Warnf("[loader] close dump archive %s error %v", l.archive.path, err)
+	}
+	l.archive = nil
+}
+
+// dumpDir returns the directory of the metadata and schema files,
+// which is the directory extracted to if the dump is an archive
+func (l *Loader) dumpDir() string {
+	if l.archive != nil {
+		return l.archive.dir
+	}
+	return l.cfg.Dir
+}
+
 // checkpointID returns ID which used for checkpoint table
 func (l *Loader) checkpointID() string {
 	if len(l.cfg.SourceID) > 0 {
@@ -1023,12 +1698,32 @@ func (l *Loader) checkpointID() string {
 	return shortSha1(dir)
 }
 
-func (l *Loader) getMydumpMetadata() {
-	metafile := filepath.Join(l.cfg.LoaderConfig.Dir, "metadata")
+// getMydumpMetadata parses the binlog position and the GTID set in metadata, either of them may be missing but not both.
+func (l *Loader) getMydumpMetadata() error {
+	metafile := filepath.Join(l.dumpDir(), "metadata")
+	gs, err := utils.ParseMetaDataGTID(metafile)
+	if err != nil {
+		return errors.Annotatef(err, "parse GTID set in metadata")
+	}
+	if gs != "" {
+		if _, err = gtid.ParserGTID(l.cfg.Flavor, gs); err != nil {
+			return errors.Annotatef(err, "parse GTID set %s in metadata", gs)
+		}
+	}
+
 	pos, err := utils.ParseMetaData(metafile)
 	if err != nil {
-		log.Errorf("[loader] parse metadata with error: %s", err)
-	} else {
-		l.metaBinlog.Set(pos.String())
+		if errors.Cause(err) != utils.ErrNoBinlogPos || gs == "" {
+			log.Errorf("[loader] parse metadata with error: %s", err)
+			return errors.Annotatef(err, "parse binlog position in metadata")
+		}
+		log.Warnf("[loader] no binlog position in metadata, only GTID set %s", gs)
+		pos = &mysql.Position{}
 	}
+
+	log.Infof("[loader] binlog position in metadata is %s, GTID set is %s", pos, gs)
+	l.metaBinlogName.Set(pos.Name)
+	l.metaBinlogPos.Set(pos.Pos)
+	l.metaBinlogGTID.Set(gs)
+	return nil
 }
@@ -0,0 +1,1010 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loader
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/dm/dm/config"
+	"github.com/pingcap/dm/dm/pb"
+	"github.com/pingcap/tidb-tools/pkg/filter"
+	"github.com/pingcap/tidb-tools/pkg/table-router"
+	"github.com/siddontang/go-mysql/mysql"
+	"golang.org/x/net/context"
+)
+
+var _ = Suite(&testLoaderSuite{})
+
+type testLoaderSuite struct{}
+
+func (t *testLoaderSuite) TestResumeFromOffset(c *C) {
+	var (
+		dir  = c.MkDir()
+		file = filepath.Join(dir, "db.t1.sql")
+		// the last statement doesn't end with a newline
+		data = "/*!40101 SET NAMES binary*/;\n" +
+			"INSERT INTO `t1` VALUES (1),(2);\n" +
+			"\n" +
+			"INSERT INTO `t1` VALUES\n(3),\n(4);\n" +
+			"INSERT INTO `t1` VALUES (5);\n" +
+			"INSERT INTO `t1` VALUES (6);"
+		expected = []string{
+			"INSERT INTO `t1` VALUES (1),(2);",
+			"INSERT INTO `t1` VALUES\n(3),\n(4);",
+			"INSERT INTO `t1` VALUES (5);",
+			"INSERT INTO `t1` VALUES (6);",
+		}
+	)
+	c.Assert(ioutil.WriteFile(file, []byte(data), 0644), IsNil)
+
+	cfg := config.NewSubTaskConfig()
+	cfg.Dir = dir
+	cp := newMemCheckPoint()
+	w := &Worker{
+		cfg:        cfg,
+		checkPoint: cp,
+		jobQueue:   make(chan *dataJob, 16),
+		loader:     NewLoader(cfg),
+	}
+	table := &tableInfo{sourceSchema: "db", sourceTable: "t1", targetSchema: "db", targetTable: "t1"}
+
+	var (
+		executed []string
+		offset   int64 // offset recorded in checkpoint
+	)
+	// execute at most limit jobs like a worker, interrupted after that
+	load := func(limit int) {
+		c.Assert(w.dispatchSQL(context.Background(), file, offset, table), IsNil)
+		close(w.jobQueue)
+		for job := range w.jobQueue {
+			if limit == 0 {
+				break
+			}
+			limit--
+			c.Assert(job.lastOffset, Equals, offset)
+			executed = append(executed, job.sql)
+			offset = job.offset
+		}
+		w.jobQueue = make(chan *dataJob, 16)
+	}
+
+	load(2)
+	c.Assert(executed, DeepEquals, expected[:2])
+	load(1)
+	c.Assert(executed, DeepEquals, expected[:3])
+	load(-1)
+	c.Assert(executed, DeepEquals, expected)
+	c.Assert(offset, Equals, cp.endPos["db.t1.sql"])
+	c.Assert(offset, Equals, int64(len(data)))
+
+	// resume from the end
+	load(-1)
+	c.Assert(executed, DeepEquals, expected)
+
+	// the data file is changed
+	offset = int64(len(data)) + 1
+	c.Assert(w.dispatchSQL(context.Background(), file, offset, table), ErrorMatches, ".*beyond the size.*")
+
+	// the data file is truncated in a statement
+	c.Assert(ioutil.WriteFile(file, []byte(data[:len(data)-3]), 0644), IsNil)
+	offset = 0
+	c.Assert(w.dispatchSQL(context.Background(), file, offset, table), ErrorMatches, ".*ends with an incomplete statement.*")
+}
+
+func (t *testLoaderSuite) TestDispatchFileJobsWithTableConcurrency(c *C) {
+	cfg := config.NewSubTaskConfig()
+	cfg.PoolSize = 4
+	cfg.TableConcurrency = 2
+	l := NewLoader(cfg)
+
+	dispatchMap := make(map[string]*fileJob)
+	for table, count := range map[string]int{"t1": 5, "t2": 3, "t3": 1} {
+		for i := 0; i < count; i++ {
+			file := fmt.Sprintf("db.%s.%d.sql", table, i)
+			dispatchMap[file] = &fileJob{schema: "db", table: table, dataFile: file}
+		}
+	}
+
+	ctx := context.Background()
+	l.newFileJobQueue()
+	go func() {
+		l.dispatchFileJobs(ctx, dispatchMap)
+		l.closeFileJobQueue()
+	}()
+
+	var (
+		mu       sync.Mutex
+		inFlight = make(map[string]int)
+		restored = make(map[string]struct{})
+		exceeded []string
+		wg       sync.WaitGroup
+	)
+	for i := 0; i < cfg.PoolSize; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range l.fileJobQueue {
+				mu.Lock()
+				inFlight[job.table]++
+				if inFlight[job.table] > cfg.TableConcurrency {
+					exceeded = append(exceeded, job.dataFile)
+				}
+				restored[job.dataFile] = struct{}{}
+				mu.Unlock()
+
+				time.Sleep(time.Millisecond) // restoring
+
+				mu.Lock()
+				inFlight[job.table]--
+				mu.Unlock()
+				l.finishFileJob(ctx, job)
+			}
+		}()
+	}
+	wg.Wait()
+
+	c.Assert(exceeded, HasLen, 0)
+	c.Assert(restored, HasLen, len(dispatchMap))
+}
+
+func (t *testLoaderSuite) TestRestoreWithTableConcurrency(c *C) {
+	dir := c.MkDir()
+	files := map[string]string{
+		"db-schema-create.sql": "CREATE DATABASE `db`;\n",
+		"metadata":             "SHOW MASTER STATUS:\n\tLog: mysql-bin.000001\n\tPos: 154\n",
+	}
+	// more tables than workers, all files are dispatched while some of them are still restoring
+	for i := 0; i < 10; i++ {
+		files[fmt.Sprintf("db.t%d-schema.sql", i)] = fmt.Sprintf("CREATE TABLE `t%d` (`id` INT PRIMARY KEY);\n", i)
+		files[fmt.Sprintf("db.t%d.sql", i)] = fmt.Sprintf("INSERT INTO `t%d` VALUES (1);\n", i)
+	}
+	for name, content := range files {
+		c.Assert(ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644), IsNil)
+	}
+
+	cfg := config.NewSubTaskConfig()
+	cfg.Name = "test-restore-with-table-concurrency"
+	cfg.Dir = dir
+	cfg.PoolSize = 2
+	cfg.TableConcurrency = 1
+	cfg.DryRun = true
+	cfg.DryRunFile = filepath.Join(c.MkDir(), "dry-run.sql")
+	cfg.To = config.DBConfig{Host: "127.0.0.1", Port: 1, User: "root"}
+
+	l := NewLoader(cfg)
+	c.Assert(l.Init(), IsNil)
+	defer l.Close()
+	pr := make(chan pb.ProcessResult, 1)
+	go l.Process(context.Background(), pr)
+	select {
+	case result := <-pr:
+		c.Assert(result.Errors, HasLen, 0)
+	case <-time.After(10 * time.Second):
+		c.Fatal("restoring is blocked")
+	}
+
+	c.Assert(l.checkPoint.Load(), IsNil)
+	c.Assert(l.checkPoint.GetAllRestoringFileInfo(), HasLen, 10)
+}
+
+func (t *testLoaderSuite) TestDryRun(c *C) {
+	dir := c.MkDir()
+	files := map[string]string{
+		"db-schema-create.sql": "CREATE DATABASE `db`;\n",
+		"db.t1-schema.sql":     "CREATE TABLE `t1` (\n`id` INT PRIMARY KEY\n);\n",
+		"db.t1.sql":            "INSERT INTO `t1` VALUES (1);\nINSERT INTO `t1` VALUES (2);\nINSERT INTO `t1` VALUES (3);\n",
+		"db.t2-schema.sql":     "CREATE TABLE `t2` (`id` INT PRIMARY KEY);\n",
+		"db.t2.sql":            "INSERT INTO `t2` VALUES (1),(2);\n",
+		"metadata":             "SHOW MASTER STATUS:\n\tLog: mysql-bin.000001\n\tPos: 154\n",
+	}
+	for name, content := range files {
+		c.Assert(ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644), IsNil)
+	}
+
+	cfg := config.NewSubTaskConfig()
+	cfg.Name = "test-dry-run"
+	cfg.Dir = dir
+	cfg.PoolSize = 2
+	cfg.DryRun = true
+	cfg.DryRunFile = filepath.Join(c.MkDir(), "dry-run.sql")
+	// nothing can be written into the downstream, any execution fails
+	cfg.To = config.DBConfig{Host: "127.0.0.1", Port: 1, User: "root"}
+
+	l := NewLoader(cfg)
+	c.Assert(l.Init(), IsNil)
+	pr := make(chan pb.ProcessResult, 1)
+	l.Process(context.Background(), pr)
+	result := <-pr
+	c.Assert(result.Errors, HasLen, 0)
+
+	// 1 for db, 2 for every table, 2 for every INSERT statement
+	c.Assert(l.sqlWriter.Count(), Equals, 1+2*2+2*4)
+	l.Close()
+	data, err := ioutil.ReadFile(cfg.DryRunFile)
+	c.Assert(err, IsNil)
+	c.Assert(strings.Count(string(data), "BEGIN;\n"), Equals, 1+2+4)
+	c.Assert(string(data), Matches, "(?s).*BEGIN;\nUSE `db`;\nINSERT INTO `t2` VALUES \\(1\\),\\(2\\);\nCOMMIT;\n.*")
+
+	// 3 rows in t1 and 2 rows in t2
+	status := l.Status().(*pb.LoadStatus)
+	c.Assert(status.TotalRows, Equals, int64(5))
+	c.Assert(status.FinishedRows, Equals, int64(5))
+
+	// checkpoint advanced in memory
+	c.Assert(l.checkPoint.Load(), IsNil)
+	infos := l.checkPoint.GetAllRestoringFileInfo()
+	c.Assert(infos, HasLen, 2)
+	for file, pos := range infos {
+		c.Assert(pos, DeepEquals, []int64{int64(len(files[file])), int64(len(files[file]))})
+	}
+
+	// checksums of rows `(1)`, `(2)`, `(3)` and `(1)`, `(2)` restored
+	c.Assert(l.checkPoint.GetTableChecksum("db", "t1"), Equals, uint64(0x1ccb7347e3675e42))
+	c.Assert(l.checkPoint.GetTableChecksum("db", "t2"), Equals, uint64(0x1335e22fecf2c7c3))
+	c.Assert(l.checkPoint.GetTableChecksum("db", "t3"), Equals, uint64(0))
+}
+
+func (t *testLoaderSuite) TestRestoreTables(c *C) {
+	dir := c.MkDir()
+	files := map[string]string{
+		"db-schema-create.sql": "CREATE DATABASE `db`;\n",
+		"metadata":             "SHOW MASTER STATUS:\n\tLog: mysql-bin.000001\n\tPos: 154\n",
+	}
+	for _, table := range []string{"t1", "t2", "t3", "tmp_a", "tmp_b"} {
+		files[fmt.Sprintf("db.%s-schema.sql", table)] = fmt.Sprintf("CREATE TABLE `%s` (`id` INT PRIMARY KEY);\n", table)
+		files[fmt.Sprintf("db.%s.sql", table)] = fmt.Sprintf("INSERT INTO `%s` VALUES (1);\n", table)
+	}
+	for name, content := range files {
+		c.Assert(ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644), IsNil)
+	}
+
+	cfg := config.NewSubTaskConfig()
+	cfg.Name = "test-restore-tables"
+	cfg.Dir = dir
+	cfg.PoolSize = 2
+	cfg.DryRun = true
+	cfg.DryRunFile = filepath.Join(c.MkDir(), "dry-run.sql")
+	cfg.To = config.DBConfig{Host: "127.0.0.1", Port: 1, User: "root"}
+	cfg.RestoreBlockTables = []*config.TablePattern{{SchemaPattern: "db", TablePattern: "tmp_*"}}
+
+	l := NewLoader(cfg)
+	c.Assert(l.Init(), IsNil)
+	// the checkpoint of an excluded table recorded before, like by a loader without restore-tables
+	c.Assert(l.checkPoint.Init("db.tmp_a.sql", int64(len(files["db.tmp_a.sql"]))), IsNil)
+	pr := make(chan pb.ProcessResult, 1)
+	l.Process(context.Background(), pr)
+	c.Assert((<-pr).Errors, HasLen, 0)
+
+	// only the tables not excluded are tracked
+	c.Assert(l.db2Tables["db"], HasLen, 3)
+	c.Assert(l.dataFileNames(), HasLen, 3)
+	c.Assert(l.Status().(*pb.LoadStatus).TotalRows, Equals, int64(3))
+	c.Assert(l.checkPoint.Load(), IsNil)
+	infos := l.checkPoint.GetAllRestoringFileInfo()
+	c.Assert(infos, HasLen, 3)
+	for _, table := range []string{"t1", "t2", "t3"} {
+		file := fmt.Sprintf("db.%s.sql", table)
+		c.Assert(infos[file], DeepEquals, []int64{int64(len(files[file])), int64(len(files[file]))})
+	}
+
+	// and loaded
+	l.Close()
+	data, err := ioutil.ReadFile(cfg.DryRunFile)
+	c.Assert(err, IsNil)
+	for _, table := range []string{"t1", "t2", "t3"} {
+		c.Assert(strings.Contains(string(data), files[fmt.Sprintf("db.%s.sql", table)]), IsTrue)
+	}
+	c.Assert(strings.Contains(string(data), "tmp_"), IsFalse)
+}
+
+func (t *testLoaderSuite) TestRowFilters(c *C) {
+	dir := c.MkDir()
+	files := map[string]string{
+		"db-schema-create.sql": "CREATE DATABASE `db`;\n",
+		"db.t1-schema.sql":     "CREATE TABLE `t1` (`id` INT PRIMARY KEY, `tenant_id` INT, `name` VARCHAR(10));\n",
+		// the second statement has no row matched
+		"db.t1.sql": "INSERT INTO `t1` VALUES\n(1,42,'a'),\n(2,7,'b'),\n(3,'42','c'),\n(4,NULL,'d');\n" +
+			"INSERT INTO `t1` VALUES\n(5,8,'e');\n" +
+			"INSERT INTO `t1` VALUES\n(6,42.0,'f');\n",
+		"metadata": "SHOW MASTER STATUS:\n\tLog: mysql-bin.000001\n\tPos: 154\n",
+	}
+	for name, content := range files {
+		c.Assert(ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644), IsNil)
+	}
+
+	cfg := config.NewSubTaskConfig()
+	cfg.Name = "test-row-filters"
+	cfg.Dir = dir
+	cfg.PoolSize = 1
+	cfg.DryRun = true
+	cfg.DryRunFile = filepath.Join(c.MkDir(), "dry-run.sql")
+	cfg.To = config.DBConfig{Host: "127.0.0.1", Port: 1, User: "root"}
+	cfg.RowFilters = []*config.RowFilterRule{{SchemaPattern: "db", TablePattern: "t*", Where: "tenant_id = 42"}}
+
+	l := NewLoader(cfg)
+	c.Assert(l.Init(), IsNil)
+	pr := make(chan pb.ProcessResult, 1)
+	l.Process(context.Background(), pr)
+	c.Assert((<-pr).Errors, HasLen, 0)
+
+	// rows dropped count in the progress
+	c.Assert(l.finishedDataSize.Get(), Equals, int64(len(files["db.t1.sql"])))
+	c.Assert(l.finishedDataSize.Get(), Equals, l.totalDataSize.Get())
+	c.Assert(l.finishedRows.Get(), Equals, int64(6))
+	c.Assert(l.checkPoint.Load(), IsNil)
+	size := int64(len(files["db.t1.sql"]))
+	c.Assert(l.checkPoint.GetAllRestoringFileInfo()["db.t1.sql"], DeepEquals, []int64{size, size})
+
+	// only rows matched are inserted
+	l.Close()
+	data, err := ioutil.ReadFile(cfg.DryRunFile)
+	c.Assert(err, IsNil)
+	for _, row := range []string{"(1,42,'a')", "(3,'42','c')", "(6,42.0,'f')"} {
+		c.Assert(strings.Contains(string(data), row), IsTrue, Commentf("row %s", row))
+	}
+	for _, row := range []string{"(2,", "(4,", "(5,"} {
+		c.Assert(strings.Contains(string(data), row), IsFalse, Commentf("row %s", row))
+	}
+}
+
+func (t *testLoaderSuite) TestRestoreFromArchive(c *C) {
+	names := []string{"metadata", "db-schema-create.sql", "db.t1-schema.sql", "db.t1.sql"}
+	files := map[string]string{
+		"db-schema-create.sql": "CREATE DATABASE `db`;\n",
+		"db.t1-schema.sql":     "CREATE TABLE `t1` (`id` INT PRIMARY KEY);\n",
+		"db.t1.sql":            "INSERT INTO `t1` VALUES (1),(2);\nINSERT INTO `t1` VALUES (3);\n",
+		"metadata":             "SHOW MASTER STATUS:\n\tLog: mysql-bin.000001\n\tPos: 154\n",
+	}
+	// files are in a sub directory of the archive
+	archive := filepath.Join(c.MkDir(), "dump.tar.gz")
+	f, err := os.Create(archive)
+	c.Assert(err, IsNil)
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+	c.Assert(tw.WriteHeader(&tar.Header{Name: "dump/", Typeflag: tar.TypeDir, Mode: 0755}), IsNil)
+	for _, name := range names {
+		content := files[name]
+		c.Assert(tw.WriteHeader(&tar.Header{Name: "dump/" + name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(content))}), IsNil)
+		_, err = tw.Write([]byte(content))
+		c.Assert(err, IsNil)
+	}
+	c.Assert(tw.Close(), IsNil)
+	c.Assert(gw.Close(), IsNil)
+	c.Assert(f.Close(), IsNil)
+
+	cfg := config.NewSubTaskConfig()
+	cfg.Name = "test-archive"
+	cfg.Dir = archive
+	cfg.PoolSize = 1
+	cfg.DryRun = true
+	cfg.DryRunFile = filepath.Join(c.MkDir(), "dry-run.sql")
+	cfg.To = config.DBConfig{Host: "127.0.0.1", Port: 1, User: "root"}
+
+	l := NewLoader(cfg)
+	c.Assert(l.Init(), IsNil)
+	pr := make(chan pb.ProcessResult, 1)
+	l.Process(context.Background(), pr)
+	result := <-pr
+	c.Assert(result.Errors, HasLen, 0)
+	c.Assert(l.metaBinlogName.Get(), Equals, "mysql-bin.000001")
+	extracted := l.archive.dir
+
+	// the checkpoint is keyed on the member name, and positions are in uncompressed bytes
+	c.Assert(l.checkPoint.Load(), IsNil)
+	size := int64(len(files["db.t1.sql"]))
+	c.Assert(l.checkPoint.GetAllRestoringFileInfo(), DeepEquals, map[string][]int64{"db.t1.sql": {size, size}})
+	status := l.Status().(*pb.LoadStatus)
+	c.Assert(status.FinishedBytes, Equals, size)
+	c.Assert(status.TotalRows, Equals, int64(3))
+
+	// files extracted are removed when closed
+	l.Close()
+	c.Assert(l.archive, IsNil)
+	_, err = os.Stat(extracted)
+	c.Assert(os.IsNotExist(err), IsTrue)
+
+	data, err := ioutil.ReadFile(cfg.DryRunFile)
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Matches, "(?s).*CREATE TABLE `t1`.*")
+	c.Assert(string(data), Matches, "(?s).*BEGIN;\nUSE `db`;\nINSERT INTO `t1` VALUES \\(3\\);\nCOMMIT;\n.*")
+}
+
+func (t *testLoaderSuite) TestPauseResume(c *C) {
+	var (
+		dir   = c.MkDir()
+		data  string
+		stmts []string
+	)
+	for i := 0; i < 50; i++ {
+		stmt := fmt.Sprintf("INSERT INTO `t1` VALUES (%d);", i)
+		stmts = append(stmts, stmt)
+		data += stmt + "\n"
+	}
+	files := map[string]string{
+		"db-schema-create.sql": "CREATE DATABASE `db`;\n",
+		"db.t1-schema.sql":     "CREATE TABLE `t1` (`id` INT PRIMARY KEY);\n",
+		"db.t1.sql":            data,
+		"metadata":             "SHOW MASTER STATUS:\n\tLog: mysql-bin.000001\n\tPos: 154\n",
+	}
+	for name, content := range files {
+		c.Assert(ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644), IsNil)
+	}
+
+	cfg := config.NewSubTaskConfig()
+	cfg.Name = "test-pause"
+	cfg.Dir = dir
+	cfg.PoolSize = 1
+	cfg.DryRun = true
+	cfg.DryRunFile = filepath.Join(c.MkDir(), "dry-run.sql")
+	cfg.To = config.DBConfig{Host: "127.0.0.1", Port: 1, User: "root"}
+	// about 10 statements restored immediately and 10 statements per second after that
+	cfg.RateLimit = int64(len(stmts[0])+1) * 10
+	// 1 for db, 2 for the table, 2 for every INSERT statement
+	total := 1 + 2 + 2*len(stmts)
+
+	l := NewLoader(cfg)
+	c.Assert(l.Init(), IsNil)
+	pr := make(chan pb.ProcessResult, 1)
+	go l.Process(context.Background(), pr)
+	for l.finishedRows.Get() < 5 {
+		time.Sleep(time.Millisecond)
+	}
+
+	l.Pause()
+	result := <-pr
+	c.Assert(result.Errors, HasLen, 0)
+	c.Assert(result.IsCanceled, IsTrue)
+	status := l.Status().(*pb.LoadStatus)
+	c.Assert(status.Paused, IsTrue)
+	c.Assert(status.FinishedRows, Less, int64(len(stmts)))
+
+	// no further writes after paused, and the checkpoint is at the last executed statement
+	written := l.sqlWriter.Count()
+	c.Assert(written, Less, total)
+	time.Sleep(100 * time.Millisecond)
+	c.Assert(l.sqlWriter.Count(), Equals, written)
+	c.Assert(l.checkPoint.Load(), IsNil)
+	offset := l.checkPoint.GetRestoringFileInfo("db", "t1")["db.t1.sql"][0]
+	paused := status.FinishedRows
+	c.Assert(data[:offset], Equals, strings.Join(stmts[:paused], "\n")+"\n")
+	// the checksum is saved after all rows of the file restored
+	c.Assert(l.checkPoint.GetTableChecksum("db", "t1"), Equals, uint64(0))
+
+	// resume from the checkpoint without rate limit, the schemas are created again
+	l.limiter = nil
+	l.Resume(context.Background(), pr)
+	result = <-pr
+	c.Assert(result.Errors, HasLen, 0)
+	c.Assert(result.IsCanceled, IsFalse)
+	status = l.Status().(*pb.LoadStatus)
+	c.Assert(status.Paused, IsFalse)
+	c.Assert(status.FinishedRows, Equals, int64(len(stmts)))
+	c.Assert(l.sqlWriter.Count(), Equals, written+1+2+2*(len(stmts)-int(paused)))
+	// the checksum covers the rows restored before paused
+	var checksum uint64
+	for _, stmt := range stmts {
+		checksum += rowsChecksum(stmt)
+	}
+	c.Assert(l.checkPoint.GetTableChecksum("db", "t1"), Equals, checksum)
+	l.Close()
+
+	// every statement is restored exactly once
+	content, err := ioutil.ReadFile(cfg.DryRunFile)
+	c.Assert(err, IsNil)
+	for _, stmt := range stmts {
+		c.Assert(strings.Count(string(content), "\n"+stmt+"\n"), Equals, 1, Commentf("%s", stmt))
+	}
+}
+
+func (t *testLoaderSuite) TestRestoreRoutines(c *C) {
+	dir := c.MkDir()
+	trigger := "CREATE TRIGGER `tr` BEFORE INSERT ON `t1` FOR EACH ROW BEGIN\nSET NEW.`id` = NEW.`id` + 1;\nEND;"
+	files := map[string]string{
+		"db-schema-create.sql": "CREATE DATABASE `db`;\n",
+		"db.t1-schema.sql":     "CREATE TABLE `t1` (`id` INT PRIMARY KEY);\nDELIMITER ;;\n" + strings.TrimSuffix(trigger, ";") + ";;\nDELIMITER ;\n",
+		"db.t1.sql":            "INSERT INTO `t1` VALUES (1);\n",
+		"metadata":             "SHOW MASTER STATUS:\n\tLog: mysql-bin.000001\n\tPos: 154\n",
+	}
+	for name, content := range files {
+		c.Assert(ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644), IsNil)
+	}
+
+	cfg := config.NewSubTaskConfig()
+	cfg.Name = "test-routines"
+	cfg.Dir = dir
+	cfg.PoolSize = 1
+	cfg.DryRun = true
+	cfg.DryRunFile = filepath.Join(c.MkDir(), "dry-run.sql")
+	cfg.To = config.DBConfig{Host: "127.0.0.1", Port: 1, User: "root"}
+
+	l := NewLoader(cfg)
+	c.Assert(l.Init(), IsNil)
+	pr := make(chan pb.ProcessResult, 1)
+	l.Process(context.Background(), pr)
+	result := <-pr
+	c.Assert(result.Errors, HasLen, 0, Commentf("errors %v", result.Errors))
+	l.Close()
+
+	// the trigger is restored as one statement after the table
+	data, err := ioutil.ReadFile(cfg.DryRunFile)
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(string(data), "BEGIN;\nUSE `db`;\n"+trigger+"\nCOMMIT;\n"), IsTrue, Commentf("dry-run sqls %s", data))
+	c.Assert(strings.Count(string(data), "BEGIN;\n"), Equals, 1+2+1)
+}
+
+func (t *testLoaderSuite) TestRouteShardedTables(c *C) {
+	dir := c.MkDir()
+	files := map[string]string{
+		"db1-schema-create.sql": "CREATE DATABASE `db1`;\n",
+		"db1.tbl1-schema.sql":   "CREATE TABLE `tbl1` (`id` INT PRIMARY KEY);\n",
+		"db1.tbl1.sql":          "INSERT INTO `tbl1` VALUES (1),(2);\n",
+		"db2-schema-create.sql": "CREATE DATABASE `db2`;\n",
+		"db2.tbl1-schema.sql":   "CREATE TABLE `tbl1` (`id` INT PRIMARY KEY);\n",
+		"db2.tbl1.sql":          "INSERT INTO `tbl1` VALUES (3);\nINSERT INTO `tbl1` VALUES (4);\n",
+		"metadata":              "SHOW MASTER STATUS:\n\tLog: mysql-bin.000001\n\tPos: 154\n",
+	}
+	for name, content := range files {
+		c.Assert(ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644), IsNil)
+	}
+
+	cfg := config.NewSubTaskConfig()
+	cfg.Name = "test-route"
+	cfg.Dir = dir
+	cfg.PoolSize = 1
+	cfg.DryRun = true
+	cfg.DryRunFile = filepath.Join(c.MkDir(), "dry-run.sql")
+	cfg.To = config.DBConfig{Host: "127.0.0.1", Port: 1, User: "root"}
+	cfg.RouteRules = []*router.TableRule{
+		{SchemaPattern: "db*", TargetSchema: "target"},
+		{SchemaPattern: "db*", TablePattern: "tbl*", TargetSchema: "target", TargetTable: "tbl"},
+	}
+
+	l := NewLoader(cfg)
+	c.Assert(l.Init(), IsNil)
+	pr := make(chan pb.ProcessResult, 1)
+	l.Process(context.Background(), pr)
+	result := <-pr
+	c.Assert(result.Errors, HasLen, 0)
+	l.Close()
+
+	// both source tables are restored into the target table
+	data, err := ioutil.ReadFile(cfg.DryRunFile)
+	c.Assert(err, IsNil)
+	content := string(data)
+	c.Assert(strings.Count(content, "CREATE DATABASE `target`;\n"), Equals, 2)
+	c.Assert(strings.Count(content, "USE `target`;\nCREATE TABLE `tbl` (`id` INT PRIMARY KEY);\n"), Equals, 2)
+	for _, insert := range []string{"INSERT INTO `tbl` VALUES (1),(2);", "INSERT INTO `tbl` VALUES (3);", "INSERT INTO `tbl` VALUES (4);"} {
+		c.Assert(strings.Count(content, "BEGIN;\nUSE `target`;\n"+insert+"\nCOMMIT;\n"), Equals, 1, Commentf("%s", insert))
+	}
+	c.Assert(content, Not(Matches), "(?s).*(db1|db2|tbl1).*")
+
+	// checkpoints are saved by source data files
+	c.Assert(l.checkPoint.Load(), IsNil)
+	infos := l.checkPoint.GetAllRestoringFileInfo()
+	c.Assert(infos, HasLen, 2)
+	for _, file := range []string{"db1.tbl1.sql", "db2.tbl1.sql"} {
+		c.Assert(infos[file], DeepEquals, []int64{int64(len(files[file])), int64(len(files[file]))})
+	}
+}
+
+func (t *testLoaderSuite) TestMetaBinlog(c *C) {
+	dir := c.MkDir()
+	metafile := filepath.Join(dir, "metadata")
+	source := "Started dump at: 2019-03-13 10:11:12\n" +
+		"SHOW MASTER STATUS:\n" +
+		"\tLog: mysql-bin.000003\n" +
+		"\tPos: 3295817\n" +
+		"\tGTID:\n\n" +
+		"Finished dump at: 2019-03-13 10:11:13\n"
+	c.Assert(ioutil.WriteFile(metafile, []byte(source), 0644), IsNil)
+
+	cfg := config.NewSubTaskConfig()
+	cfg.Dir = dir
+	cfg.Flavor = mysql.MySQLFlavor
+	l := NewLoader(cfg)
+	c.Assert(l.getMydumpMetadata(), IsNil)
+	s := l.Status().(*pb.LoadStatus)
+	c.Assert(s.MetaBinlogName, Equals, "mysql-bin.000003")
+	c.Assert(s.MetaBinlogPos, Equals, uint32(3295817))
+	c.Assert(s.MetaBinlog, Equals, "(mysql-bin.000003, 3295817)")
+
+	// truncated metafile fails rather than handing a garbage position to the syncer
+	c.Assert(ioutil.WriteFile(metafile, []byte(source[:strings.Index(source, "\tPos")]), 0644), IsNil)
+	l = NewLoader(cfg)
+	c.Assert(l.getMydumpMetadata(), ErrorMatches, ".*parse metadata for .* fail.*")
+	c.Assert(l.Restore(context.Background()), ErrorMatches, ".*parse binlog position in metadata.*")
+	s = l.Status().(*pb.LoadStatus)
+	c.Assert(s.MetaBinlogName, Equals, "")
+	c.Assert(s.MetaBinlog, Equals, "")
+
+	// the GTID set round-trips through the status
+	gs := "97b5142f-e19c-11e8-808c-0242ac110005:1-13,a7b5142f-e19c-11e8-808c-0242ac110005:1-8"
+	withGTID := strings.Replace(source, "\tGTID:\n", "\tGTID:"+gs+"\n", 1)
+	c.Assert(ioutil.WriteFile(metafile, []byte(withGTID), 0644), IsNil)
+	l = NewLoader(cfg)
+	c.Assert(l.getMydumpMetadata(), IsNil)
+	s = l.Status().(*pb.LoadStatus)
+	c.Assert(s.MetaBinlog, Equals, "(mysql-bin.000003, 3295817)")
+	c.Assert(s.MetaBinlogGTID, Equals, gs)
+	data, err := s.Marshal()
+	c.Assert(err, IsNil)
+	s2 := &pb.LoadStatus{}
+	c.Assert(s2.Unmarshal(data), IsNil)
+	c.Assert(s2.MetaBinlogGTID, Equals, gs)
+	c.Assert(s2.MetaBinlog, Equals, s.MetaBinlog)
+
+	// only the GTID set without the binlog position
+	c.Assert(ioutil.WriteFile(metafile, []byte("SHOW MASTER STATUS:\n\tGTID:"+gs+"\n"), 0644), IsNil)
+	l = NewLoader(cfg)
+	c.Assert(l.getMydumpMetadata(), IsNil)
+	s = l.Status().(*pb.LoadStatus)
+	c.Assert(s.MetaBinlogName, Equals, "")
+	c.Assert(s.MetaBinlog, Equals, "")
+	c.Assert(s.MetaBinlogGTID, Equals, gs)
+
+	// a malformed GTID set is rejected
+	for _, malformed := range []string{"97b5142f-e19c-11e8-808c:1-13", "mysql-bin.000003:3295817"} {
+		c.Assert(ioutil.WriteFile(metafile, []byte(strings.Replace(source, "\tGTID:\n", "\tGTID:"+malformed+"\n", 1)), 0644), IsNil)
+		l = NewLoader(cfg)
+		c.Assert(l.getMydumpMetadata(), ErrorMatches, ".*parse GTID set "+malformed+" in metadata.*")
+		s = l.Status().(*pb.LoadStatus)
+		c.Assert(s.MetaBinlogGTID, Equals, "")
+		c.Assert(s.MetaBinlog, Equals, "")
+	}
+}
+
+// blockExecutor executes limit transactions, and blocks the next one until ctx done like a long transaction
+type blockExecutor struct {
+	fakeExecutor
+	limit   int
+	blocked chan struct{}
+}
+
+func (e *blockExecutor) Exec(ctx context.Context, statements []string, values [][]interface{}) error {
+	e.Lock()
+	if len(e.txns) < e.limit {
+		e.Unlock()
+		return e.fakeExecutor.Exec(ctx, statements, values)
+	}
+	e.Unlock()
+	close(e.blocked)
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (t *testLoaderSuite) TestCancelRestoreDataFile(c *C) {
+	var (
+		dir      = c.MkDir()
+		file     = "db.t1.sql"
+		data     string
+		stmts    []string
+		offsetRe = regexp.MustCompile("SET `offset`=(\\d+) ")
+	)
+	for i := 0; i < 100; i++ {
+		stmt := fmt.Sprintf("INSERT INTO `t1` VALUES (%d);", i)
+		stmts = append(stmts, stmt)
+		data += stmt + "\n"
+	}
+	c.Assert(ioutil.WriteFile(filepath.Join(dir, file), []byte(data), 0644), IsNil)
+
+	cfg := config.NewSubTaskConfig()
+	cfg.Dir = dir
+	table := &tableInfo{sourceSchema: "db", sourceTable: "t1", targetSchema: "db", targetTable: "t1"}
+
+	// restore the data file from offset until blocked after limit transactions, or finished
+	restore := func(offset int64, limit int) (executed []string, checkpoint int64, checksummed bool) {
+		exec := &blockExecutor{limit: limit, blocked: make(chan struct{})}
+		cp := newFakeRemoteCheckPoint(exec, "test_cancel", 0)
+		w := &Worker{
+			cfg:        cfg,
+			checkPoint: cp,
+			exec:       exec,
+			jobQueue:   make(chan *dataJob, 16),
+			loader:     NewLoader(cfg),
+		}
+		fileJobQueue := make(chan *fileJob, 1)
+		fileJobQueue <- &fileJob{schema: "db", table: "t1", dataFile: file, offset: offset, info: table}
+		close(fileJobQueue)
+		runFatalChan := make(chan *pb.ProcessError, 1)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go w.run(ctx, fileJobQueue, &wg, runFatalChan)
+		done := make(chan struct{})
+		go func() {
+			wg.Wait()
+			close(done)
+		}()
+
+		select {
+		case <-exec.blocked:
+			cancel()
+		case <-done:
+		}
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			c.Fatal("restoring data file is not stopped in time")
+		}
+		c.Assert(runFatalChan, HasLen, 0)
+
+		// the first transaction initializes the checkpoint, and every data transaction updates it together,
+		// the checksum of the file is saved in the last transaction after the file finished
+		txns := exec.txns[1:]
+		if len(txns) > 0 && strings.Contains(txns[len(txns)-1][0], "SET `checksum`") {
+			txns = txns[:len(txns)-1]
+			checksummed = true
+		}
+		checkpoint = offset
+		for _, txn := range txns {
+			c.Assert(txn, HasLen, 3)
+			executed = append(executed, txn[1])
+			matches := offsetRe.FindStringSubmatch(txn[2])
+			c.Assert(matches, HasLen, 2)
+			checkpoint, _ = strconv.ParseInt(matches[1], 10, 64)
+		}
+		return executed, checkpoint, checksummed
+	}
+
+	executed, offset, checksummed := restore(0, 1+30)
+	c.Assert(executed, DeepEquals, stmts[:30])
+	// the checkpoint is at the boundary of the last executed statement
+	c.Assert(data[:offset], Equals, strings.Join(stmts[:30], "\n")+"\n")
+	c.Assert(checksummed, IsFalse)
+
+	// resume from the checkpoint, no statement is lost or executed twice
+	rest, offset, checksummed := restore(offset, 1000)
+	c.Assert(append(executed, rest...), DeepEquals, stmts)
+	c.Assert(offset, Equals, int64(len(data)))
+	c.Assert(checksummed, IsTrue)
+}
+
+func (t *testLoaderSuite) TestSkipAppliedStatements(c *C) {
+	var (
+		dir   = c.MkDir()
+		file  = "db.t1.sql"
+		data  string
+		stmts []string
+	)
+	for i := 0; i < 10; i++ {
+		stmt := fmt.Sprintf("INSERT INTO `t1` VALUES (%d);", i)
+		stmts = append(stmts, stmt)
+		data += stmt + "\n"
+	}
+	c.Assert(ioutil.WriteFile(filepath.Join(dir, file), []byte(data), 0644), IsNil)
+	applied := int64(len(strings.Join(stmts[:4], "\n")) + 1)
+
+	cfg := config.NewSubTaskConfig()
+	cfg.Dir = dir
+	table := &tableInfo{sourceSchema: "db", sourceTable: "t1", targetSchema: "db", targetTable: "t1"}
+
+	// 4 statements have been applied before the loader paused
+	cp, err := newFileCheckPoint(filepath.Join(dir, "checkpoint.json"), "test_skip")
+	c.Assert(err, IsNil)
+	c.Assert(cp.Init(file, int64(len(data))), IsNil)
+	c.Assert(cp.UpdateOffset(file, applied), IsNil)
+	c.Assert(cp.Load(), IsNil)
+	c.Assert(cp.GetRestoringFileInfo("db", "t1")[file], DeepEquals, []int64{applied, int64(len(data))})
+
+	restore := func(offset int64) ([]string, *pb.ProcessError) {
+		exec := &fakeExecutor{}
+		w := &Worker{
+			cfg:        cfg,
+			checkPoint: cp,
+			exec:       exec,
+			jobQueue:   make(chan *dataJob, 16),
+			loader:     NewLoader(cfg),
+		}
+		fileJobQueue := make(chan *fileJob, 1)
+		fileJobQueue <- &fileJob{schema: "db", table: "t1", dataFile: file, offset: offset, info: table}
+		close(fileJobQueue)
+		runFatalChan := make(chan *pb.ProcessError, 1)
+		var wg sync.WaitGroup
+		wg.Add(1)
+		w.run(context.Background(), fileJobQueue, &wg, runFatalChan)
+
+		var executed []string
+		for _, txn := range exec.txns {
+			executed = append(executed, txn[1])
+		}
+		if len(runFatalChan) > 0 {
+			return executed, <-runFatalChan
+		}
+		return executed, nil
+	}
+
+	// statements before the applied position are skipped
+	executed, pErr := restore(applied)
+	c.Assert(pErr, IsNil)
+	c.Assert(executed, DeepEquals, stmts[4:])
+
+	// never restore from a position other than the applied one
+	executed, pErr = restore(0)
+	c.Assert(pErr, NotNil)
+	c.Assert(pErr.Msg, Matches, "(?s).*offset 0 to restore file .* is not the applied position .* in checkpoint.*")
+	c.Assert(executed, HasLen, 0)
+}
+
+func (t *testLoaderSuite) TestImportCheckpoint(c *C) {
+	dir := c.MkDir()
+	stmts := []string{"INSERT INTO `t1` VALUES (1);\n", "INSERT INTO `t1` VALUES (2);\n", "INSERT INTO `t1` VALUES (3);\n"}
+	files := map[string]string{
+		"db-schema-create.sql": "CREATE DATABASE `db`;\n",
+		"db.t1-schema.sql":     "CREATE TABLE `t1` (`id` INT PRIMARY KEY);\n",
+		"db.t1.sql":            strings.Join(stmts, ""),
+		"db.t2-schema.sql":     "CREATE TABLE `t2` (`id` INT PRIMARY KEY);\n",
+		"db.t2.sql":            "INSERT INTO `t2` VALUES (1),(2);\n",
+		"metadata":             "SHOW MASTER STATUS:\n\tLog: mysql-bin.000001\n\tPos: 154\n",
+	}
+	for name, content := range files {
+		c.Assert(ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644), IsNil)
+	}
+	size := int64(len(files["db.t1.sql"]))
+
+	newLoader := func() *Loader {
+		cfg := config.NewSubTaskConfig()
+		cfg.Name = "test-import-checkpoint"
+		cfg.Dir = dir
+		cfg.PoolSize = 1
+		cfg.DryRun = true
+		cfg.DryRunFile = filepath.Join(c.MkDir(), "dry-run.sql")
+		cfg.To = config.DBConfig{Host: "127.0.0.1", Port: 1, User: "root"}
+		l := NewLoader(cfg)
+		c.Assert(l.Init(), IsNil)
+		return l
+	}
+
+	// data files must be in the dump with the same size
+	l := newLoader()
+	err := l.ImportCheckpoint([]byte(`{"files":{"db.t3.sql":{"cp-schema":"db","cp-table":"t3","offset":0,"end-pos":10}}}`))
+	c.Assert(err, ErrorMatches, ".*db.t3.sql.*not found.*")
+	err = l.ImportCheckpoint([]byte(fmt.Sprintf(`{"files":{"db.t1.sql":{"cp-schema":"db","cp-table":"t1","offset":0,"end-pos":%d}}}`, size+1)))
+	c.Assert(err, ErrorMatches, ".*mismatches its size.*")
+	c.Assert(l.ImportCheckpoint([]byte(fmt.Sprintf(`{"files":{"db.t1.sql":{"cp-schema":"db","cp-table":"t1","offset":%d,"end-pos":%d}}}`, len(stmts[0]), size))), IsNil)
+	data, err := l.ExportCheckpoint()
+	c.Assert(err, IsNil)
+	l.Close()
+
+	// imported from the file before restoring, the first statement restored in another loader is skipped
+	l = newLoader()
+	l.cfg.CheckpointImport = filepath.Join(c.MkDir(), "exported.json")
+	c.Assert(ioutil.WriteFile(l.cfg.CheckpointImport, data, 0644), IsNil)
+	pr := make(chan pb.ProcessResult, 1)
+	l.Process(context.Background(), pr)
+	c.Assert((<-pr).Errors, HasLen, 0)
+	c.Assert(l.checkPoint.Load(), IsNil)
+	c.Assert(l.checkPoint.GetAllRestoringFileInfo(), DeepEquals, map[string][]int64{
+		"db.t1.sql": {size, size},
+		"db.t2.sql": {int64(len(files["db.t2.sql"])), int64(len(files["db.t2.sql"]))},
+	})
+	l.Close()
+	restored, err := ioutil.ReadFile(l.cfg.DryRunFile)
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(string(restored), stmts[1]), IsTrue)
+	c.Assert(strings.Contains(string(restored), stmts[2]), IsTrue)
+	c.Assert(strings.Contains(string(restored), files["db.t2.sql"]), IsTrue)
+	c.Assert(strings.Contains(string(restored), stmts[0]), IsFalse)
+}
+
+func (t *testLoaderSuite) TestCloseFlushesCheckpoint(c *C) {
+	var (
+		dir      = c.MkDir()
+		data     string
+		stmts    []string
+		offsetRe = regexp.MustCompile("SET `offset`=(\\d+) ")
+	)
+	for i := 0; i < 50; i++ {
+		stmt := fmt.Sprintf("INSERT INTO `t1` VALUES (%d);", i)
+		stmts = append(stmts, stmt)
+		data += stmt + "\n"
+	}
+	files := map[string]string{
+		"db-schema-create.sql": "CREATE DATABASE `db`;\n",
+		"db.t1-schema.sql":     "CREATE TABLE `t1` (`id` INT PRIMARY KEY);\n",
+		"db.t1.sql":            data,
+		"metadata":             "SHOW MASTER STATUS:\n\tLog: mysql-bin.000001\n\tPos: 154\n",
+	}
+	for name, content := range files {
+		c.Assert(ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644), IsNil)
+	}
+
+	// checkpoints are batched, and never flushed in the background
+	oldInterval := checkpointFlushInterval
+	checkpointFlushInterval = time.Hour
+	defer func() {
+		checkpointFlushInterval = oldInterval
+	}()
+	mockDrv.executed = nil
+	defer func() {
+		mockDrv.executed = nil
+	}()
+
+	cfg := config.NewSubTaskConfig()
+	cfg.Name = "test-close"
+	cfg.Dir = dir
+	cfg.PoolSize = 1
+	cfg.CheckpointBatch = 1000
+	// about 10 statements restored immediately and 10 statements per second after that
+	cfg.RateLimit = int64(len(stmts[0])+1) * 10
+
+	// initialized like Init, with the downstream database and the checkpoint mocked
+	l := NewLoader(cfg)
+	l.bwList = filter.New(cfg.CaseSensitive, cfg.BWList)
+	c.Assert(l.genRouter(cfg.RouteRules), IsNil)
+	db, err := sql.Open("loader-mock", "")
+	c.Assert(err, IsNil)
+	l.toDB = db
+	exec := &fakeExecutor{}
+	l.checkPoint = newFakeRemoteCheckPoint(exec, "test_close", cfg.CheckpointBatch)
+
+	pr := make(chan pb.ProcessResult, 1)
+	go l.Process(context.Background(), pr)
+	for l.finishedRows.Get() < 5 {
+		time.Sleep(time.Millisecond)
+	}
+	// no checkpoint saved before closed, except the one initialized
+	exec.Lock()
+	c.Assert(exec.txns, HasLen, 1)
+	c.Assert(exec.txns[0][0], Matches, "INSERT INTO .*")
+	exec.Unlock()
+
+	l.Close()
+	result := <-pr
+	c.Assert(result.Errors, HasLen, 0)
+	c.Assert(result.IsCanceled, IsTrue)
+
+	// the checkpoint saved is at the last committed statement
+	var restored []string
+	for _, query := range mockDrv.executed {
+		if strings.HasPrefix(query, "INSERT INTO `t1`") {
+			restored = append(restored, query)
+		}
+	}
+	c.Assert(len(restored), Less, len(stmts))
+	c.Assert(restored, DeepEquals, stmts[:len(restored)])
+	exec.Lock()
+	txns := exec.txns
+	closed := exec.closed
+	exec.Unlock()
+	c.Assert(txns, HasLen, 2)
+	matches := offsetRe.FindStringSubmatch(txns[1][0])
+	c.Assert(matches, HasLen, 2)
+	offset, err := strconv.Atoi(matches[1])
+	c.Assert(err, IsNil)
+	c.Assert(data[:offset], Equals, strings.Join(restored, "\n")+"\n")
+
+	// the checkpoint and connections are closed
+	c.Assert(closed, IsTrue)
+	c.Assert(db.Ping(), NotNil)
+
+	// idempotent
+	l.Close()
+	c.Assert(exec.txns, HasLen, 2)
+	// not initialized or failed to initialize
+	NewLoader(cfg).Close()
+}
@@ -69,6 +69,14 @@ var (
 			Help:      "data size in total",
 		}, []string{"task"})
 
+	skippedErrorCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "dm",
+			Subsystem: "loader",
+			Name:      "skipped_error_count",
+			Help:      "counter for errors of statements skipped by skip-error-codes",
+		}, []string{"task", "code"})
+
 	progressGauge = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Namespace: "dm",
@@ -95,6 +103,7 @@ func RegisterMetrics(registry *prometheus.Registry) {
 	registry.MustRegister(dataFileCounter)
 	registry.MustRegister(tableCounter)
 	registry.MustRegister(dataSizeCounter)
+	registry.MustRegister(skippedErrorCounter)
 	registry.MustRegister(progressGauge)
 	registry.MustRegister(loaderExitWithErrorCounter)
 }
@@ -0,0 +1,84 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loader
+
+import (
+	"sync"
+
+	"github.com/pingcap/errors"
+	"golang.org/x/net/context"
+)
+
+// jobQuota bounds the bytes of data jobs read from data files but not executed yet of a worker.
+// the dispatcher acquires the size of a job before queueing it, and the size is released after the job executed,
+// so reading blocks when the executor falls behind. a job larger than the limit is allowed when nothing is queued.
+type jobQuota struct {
+	sync.Mutex
+	limit    int64
+	used     int64
+	peak     int64         // max bytes used ever, for tests
+	released chan struct{} // notifies the dispatcher waiting for the quota
+}
+
+// newJobQuota creates a jobQuota, it returns nil (no limit) if limit <= 0
+func newJobQuota(limit int64) *jobQuota {
+	if limit <= 0 {
+		return nil
+	}
+	return &jobQuota{
+		limit:    limit,
+		released: make(chan struct{}, 1),
+	}
+}
+
+// acquire blocks until n bytes are available, or ctx is done.
+// it returns immediately for a nil jobQuota.
+func (q *jobQuota) acquire(ctx context.Context, n int64) error {
+	if q == nil {
+		return nil
+	}
+	for {
+		q.Lock()
+		if q.used == 0 || q.used+n <= q.limit {
+			q.used += n
+			if q.used > q.peak {
+				q.peak = q.used
+			}
+			q.Unlock()
+			return nil
+		}
+		q.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return errors.Trace(ctx.Err())
+		case <-q.released:
+		}
+	}
+}
+
+// release returns n bytes acquired
+func (q *jobQuota) release(n int64) {
+	if q == nil {
+		return
+	}
+	q.Lock()
+	q.used -= n
+	q.Unlock()
+
+	select {
+	case q.released <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,225 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loader
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+	"time"
+
+	. "github.com/pingcap/check"
+	"golang.org/x/net/context"
+
+	"github.com/pingcap/dm/dm/config"
+	"github.com/pingcap/dm/dm/pb"
+)
+
+var _ = Suite(&testQueueSuite{})
+
+type testQueueSuite struct{}
+
+func (t *testQueueSuite) TestJobQuota(c *C) {
+	// no limit
+	var q *jobQuota
+	c.Assert(newJobQuota(0), IsNil)
+	c.Assert(q.acquire(context.Background(), 1<<30), IsNil)
+	q.release(1 << 30)
+
+	q = newJobQuota(100)
+	c.Assert(q.acquire(context.Background(), 60), IsNil)
+	c.Assert(q.acquire(context.Background(), 40), IsNil)
+
+	// blocks until released
+	acquired := make(chan struct{})
+	go func() {
+		c.Assert(q.acquire(context.Background(), 50), IsNil)
+		close(acquired)
+	}()
+	select {
+	case <-acquired:
+		c.Fatal("acquired beyond the limit")
+	case <-time.After(50 * time.Millisecond):
+	}
+	q.release(60)
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		c.Fatal("not acquired after released")
+	}
+	c.Assert(q.used, Equals, int64(90))
+
+	// canceled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c.Assert(q.acquire(ctx, 50), NotNil)
+
+	// a job larger than the limit is allowed alone
+	q.release(90)
+	c.Assert(q.acquire(context.Background(), 1000), IsNil)
+	c.Assert(q.peak, Equals, int64(1000))
+}
+
+// slowExecutor executes jobs slowly, and records the peak count of jobs queued in the worker
+type slowExecutor struct {
+	fakeExecutor
+	w          *Worker
+	peakQueued int
+}
+
+func (e *slowExecutor) Exec(ctx context.Context, statements []string, values [][]interface{}) error {
+	if queued := len(e.w.jobQueue); queued > e.peakQueued {
+		e.peakQueued = queued
+	}
+	time.Sleep(time.Millisecond)
+	return e.fakeExecutor.Exec(ctx, statements, values)
+}
+
+func (t *testQueueSuite) TestBoundedJobQueue(c *C) {
+	var (
+		dir  = c.MkDir()
+		file = "db.t1.sql"
+		data string
+		size int64
+	)
+	for i := 0; i < 100; i++ {
+		stmt := fmt.Sprintf("INSERT INTO `t1` VALUES (%03d);", i)
+		size = int64(len(stmt))
+		data += stmt + "\n"
+	}
+	c.Assert(ioutil.WriteFile(filepath.Join(dir, file), []byte(data), 0644), IsNil)
+
+	restore := func(queueSize int, queueBytes int64) (*slowExecutor, *Worker) {
+		cfg := config.NewSubTaskConfig()
+		cfg.Dir = dir
+		cfg.JobQueueSize = queueSize
+		cfg.JobQueueBytes = queueBytes
+		exec := &slowExecutor{}
+		w := &Worker{
+			cfg:        cfg,
+			checkPoint: newFakeRemoteCheckPoint(exec, "test_queue", 0),
+			exec:       exec,
+			jobQueue:   make(chan *dataJob, cfg.JobQueueSize),
+			quota:      newJobQuota(cfg.JobQueueBytes),
+			loader:     NewLoader(cfg),
+		}
+		exec.w = w
+		table := &tableInfo{sourceSchema: "db", sourceTable: "t1", targetSchema: "db", targetTable: "t1"}
+		fileJobQueue := make(chan *fileJob, 1)
+		fileJobQueue <- &fileJob{schema: "db", table: "t1", dataFile: file, info: table}
+		close(fileJobQueue)
+		runFatalChan := make(chan *pb.ProcessError, 1)
+
+		var wg sync.WaitGroup
+		wg.Add(1)
+		w.run(context.Background(), fileJobQueue, &wg, runFatalChan)
+		c.Assert(runFatalChan, HasLen, 0)
+		// all statements are executed
+		c.Assert(w.loader.finishedDataSize.Get(), Equals, int64(len(data)))
+		return exec, w
+	}
+
+	// bounded by the count of jobs
+	exec, _ := restore(4, 0)
+	c.Assert(exec.peakQueued <= 4, IsTrue, Commentf("peak queued %d", exec.peakQueued))
+
+	// bounded by the bytes of jobs, including the one executing
+	exec, w := restore(100, 3*size)
+	c.Assert(exec.peakQueued <= 2, IsTrue, Commentf("peak queued %d", exec.peakQueued))
+	c.Assert(w.quota.peak <= 3*size, IsTrue, Commentf("peak bytes %d", w.quota.peak))
+	c.Assert(w.quota.used, Equals, int64(0))
+}
+
+func (t *testQueueSuite) TestCommitStatements(c *C) {
+	var (
+		dir     = c.MkDir()
+		file    = "db.t1.sql"
+		data    string
+		offsets []int64 // offset after each statement
+	)
+	for i := 0; i < 25; i++ {
+		data += fmt.Sprintf("INSERT INTO `t1` VALUES (%03d);\n", i)
+		offsets = append(offsets, int64(len(data)))
+	}
+	c.Assert(ioutil.WriteFile(filepath.Join(dir, file), []byte(data), 0644), IsNil)
+
+	restore := func(statements int, interval time.Duration) [][]string {
+		cfg := config.NewSubTaskConfig()
+		cfg.Dir = dir
+		cfg.JobQueueBytes = 64 // less than the statements committed together
+		exec := &fakeExecutor{}
+		w := &Worker{
+			cfg:              cfg,
+			checkPoint:       newFakeRemoteCheckPoint(exec, "test_commit", 0),
+			exec:             exec,
+			jobQueue:         make(chan *dataJob, 100),
+			quota:            newJobQuota(cfg.JobQueueBytes),
+			loader:           NewLoader(cfg),
+			commitStatements: statements,
+			commitInterval:   interval,
+		}
+		table := &tableInfo{sourceSchema: "db", sourceTable: "t1", targetSchema: "db", targetTable: "t1"}
+		fileJobQueue := make(chan *fileJob, 1)
+		fileJobQueue <- &fileJob{schema: "db", table: "t1", dataFile: file, info: table}
+		close(fileJobQueue)
+		runFatalChan := make(chan *pb.ProcessError, 1)
+
+		var wg sync.WaitGroup
+		wg.Add(1)
+		w.run(context.Background(), fileJobQueue, &wg, runFatalChan)
+		c.Assert(runFatalChan, HasLen, 0)
+		c.Assert(w.loader.finishedDataSize.Get(), Equals, int64(len(data)))
+		c.Assert(w.loader.finishedRows.Get(), Equals, int64(25))
+
+		// transactions of statements, the first one initializes the checkpoint
+		var txns [][]string
+		for _, txn := range exec.txns {
+			if txn[0] == "USE `db`;" {
+				txns = append(txns, txn)
+			}
+		}
+		return txns
+	}
+	checkpointSQL := func(offset int64) string {
+		return fmt.Sprintf("UPDATE `dm_meta`.`test_loader_checkpoint` SET `offset`=%d WHERE `id` ='test_commit' AND `filename`='db.t1.sql';", offset)
+	}
+
+	// committed every 10 statements, and the rest when the file read to the end
+	txns := restore(10, 0)
+	c.Assert(txns, HasLen, 3)
+	for i, count := range []int{10, 10, 5} {
+		c.Assert(txns[i], HasLen, count+2)
+		// the checkpoint is the offset after the last statement committed
+		c.Assert(txns[i][count+1], Equals, checkpointSQL(offsets[i*10+count-1]))
+	}
+	c.Assert(txns[1][1], Equals, "INSERT INTO `t1` VALUES (010);")
+
+	// committed every statement by default
+	txns = restore(0, 0)
+	c.Assert(txns, HasLen, 25)
+	for i, txn := range txns {
+		c.Assert(txn, HasLen, 3)
+		c.Assert(txn[2], Equals, checkpointSQL(offsets[i]))
+	}
+
+	// committed by the interval, the checkpoint never goes beyond the statements committed
+	txns = restore(0, time.Nanosecond)
+	restored := 0
+	for _, txn := range txns {
+		restored += len(txn) - 2
+		c.Assert(txn[len(txn)-1], Equals, checkpointSQL(offsets[restored-1]))
+	}
+	c.Assert(restored, Equals, 25)
+}
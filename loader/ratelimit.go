@@ -0,0 +1,122 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loader
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pingcap/errors"
+	"golang.org/x/net/context"
+)
+
+// rateLimiter limits the bytes restored per second by a token bucket, it's shared by all workers.
+// the bucket holds at most one second of tokens, and a request larger than the tokens left
+// takes them in debt, so statements larger than the rate can still pass.
+type rateLimiter struct {
+	sync.Mutex
+	rate   float64 // tokens (bytes) per second
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// newRateLimiter creates a rateLimiter, it returns nil (no limit) if bytesPerSec <= 0
+func newRateLimiter(bytesPerSec int64) *rateLimiter {
+	if bytesPerSec <= 0 {
+		return nil
+	}
+	return &rateLimiter{
+		rate:   float64(bytesPerSec),
+		burst:  float64(bytesPerSec),
+		tokens: float64(bytesPerSec),
+		last:   time.Now(),
+	}
+}
+
+// pausedRetryInterval is the interval to check whether the restoring is resumed by a paused rateLimiter
+var pausedRetryInterval = 100 * time.Millisecond
+
+// setRate changes the rate to bytesPerSec, and the bucket holds at most one second of tokens at the new rate.
+// a rate of 0 pauses the restoring until the rate changed again, see throttle.
+func (l *rateLimiter) setRate(bytesPerSec float64) {
+	l.Lock()
+	defer l.Unlock()
+
+	l.refill(time.Now()) // tokens accumulated before are at the old rate
+	l.rate = bytesPerSec
+	l.burst = bytesPerSec
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+}
+
+// currentRate returns the rate in bytes per second
+func (l *rateLimiter) currentRate() float64 {
+	l.Lock()
+	defer l.Unlock()
+	return l.rate
+}
+
+func (l *rateLimiter) refill(now time.Time) {
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+}
+
+// reserve takes n tokens and returns the duration to wait before using them.
+// if the rate is 0, no token is taken, and it returns false and the duration to wait before reserving again.
+func (l *rateLimiter) reserve(n int64) (time.Duration, bool) {
+	l.Lock()
+	defer l.Unlock()
+
+	l.refill(time.Now())
+	if l.rate <= 0 {
+		return pausedRetryInterval, false
+	}
+
+	l.tokens -= float64(n)
+	if l.tokens >= 0 {
+		return 0, true
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second)), true
+}
+
+// wait blocks until n bytes are allowed to restore, or ctx is done.
+// it returns immediately for a nil rateLimiter.
+func (l *rateLimiter) wait(ctx context.Context, n int64) error {
+	if l == nil || n <= 0 {
+		return nil
+	}
+
+	for {
+		d, reserved := l.reserve(n)
+		if d <= 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(d)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Trace(ctx.Err())
+		case <-timer.C:
+		}
+		if reserved {
+			return nil
+		}
+	}
+}
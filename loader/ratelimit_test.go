@@ -0,0 +1,131 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loader
+
+import (
+	"sync"
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/errors"
+	"golang.org/x/net/context"
+)
+
+var _ = Suite(&testRateLimitSuite{})
+
+type testRateLimitSuite struct{}
+
+func (t *testRateLimitSuite) TestRateLimiter(c *C) {
+	// no limit
+	var l *rateLimiter
+	c.Assert(newRateLimiter(0), IsNil)
+	c.Assert(l.wait(context.Background(), 1<<30), IsNil)
+
+	// shared by workers, the first second of tokens are taken without waiting
+	var (
+		rate    int64 = 10000
+		workers       = 3
+		chunk   int64 = 500
+		total   int64 = 15000
+		wg      sync.WaitGroup
+	)
+	l = newRateLimiter(rate)
+	begin := time.Now()
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for restored := int64(0); restored < total/int64(workers); restored += chunk {
+				c.Assert(l.wait(context.Background(), chunk), IsNil)
+			}
+		}()
+	}
+	wg.Wait()
+	floor := time.Duration(float64(total-rate) / float64(rate) * float64(time.Second))
+	c.Assert(time.Since(begin) >= floor, IsTrue)
+
+	// larger than the rate and canceled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c.Assert(l.wait(ctx, rate*10), NotNil)
+}
+
+func (t *testRateLimitSuite) TestThrottle(c *C) {
+	var (
+		maxRate   int64 = 8000
+		threshold       = 40.0
+		loads     []float64
+	)
+	signal := func(ctx context.Context) (float64, error) {
+		if len(loads) == 0 {
+			return 0, errors.New("no load")
+		}
+		load := loads[0]
+		loads = loads[1:]
+		return load, nil
+	}
+	l := newRateLimiter(maxRate)
+	th := newThrottle(l, signal, threshold)
+
+	// the rate after each sample of the load
+	adjust := func(samples ...float64) []float64 {
+		loads = samples
+		rates := make([]float64, 0, len(samples))
+		for range samples {
+			c.Assert(th.adjust(context.Background()), IsNil)
+			rates = append(rates, l.currentRate())
+		}
+		return rates
+	}
+
+	// kept at the max rate while the load is low
+	c.Assert(adjust(10, 20), DeepEquals, []float64{8000, 8000})
+	// the load spikes, the rate is halved every sample and paused at last
+	c.Assert(adjust(50, 60, 45, 40, 50), DeepEquals, []float64{4000, 2000, 1000, 0, 0})
+	// paused
+	ctx, cancel := context.WithTimeout(context.Background(), 3*pausedRetryInterval)
+	c.Assert(l.wait(ctx, 1), NotNil)
+	cancel()
+	// kept while the load is between 80% of the threshold and the threshold, so it doesn't oscillate
+	c.Assert(adjust(39, 32), DeepEquals, []float64{0, 0})
+	// recovers step by step after the load falls
+	c.Assert(adjust(31, 10, 10, 35, 10, 10, 10, 10, 10, 10, 10), DeepEquals, []float64{1000, 2000, 3000, 3000, 4000, 5000, 6000, 7000, 8000, 8000, 8000})
+	c.Assert(l.wait(context.Background(), 1), IsNil)
+	// drops again
+	c.Assert(adjust(100), DeepEquals, []float64{4000})
+
+	// kept if failed to sample
+	c.Assert(th.adjust(context.Background()), ErrorMatches, "no load")
+	c.Assert(l.currentRate(), Equals, float64(4000))
+
+	// the max rate is restored after stopped
+	oldInterval := throttleInterval
+	throttleInterval = time.Millisecond
+	defer func() {
+		throttleInterval = oldInterval
+	}()
+	loads = []float64{100, 100, 100, 100, 100, 100}
+	ctx, cancel = context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		th.run(ctx)
+		close(done)
+	}()
+	for l.currentRate() != 0 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+	c.Assert(l.currentRate(), Equals, float64(maxRate))
+}
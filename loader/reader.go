@@ -0,0 +1,263 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loader
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+
+	"github.com/pingcap/errors"
+)
+
+const (
+	// size of the buffer reading data files
+	statementReadBufferSize = 64 * 1024
+	// the buffer of statements is released after a statement larger than it, rather than reused
+	maxReusedStatementSize = 1024 * 1024
+
+	defaultDelimiter = ";"
+	delimiterCommand = "DELIMITER"
+)
+
+// statementReader reads statements of a data file one by one, no matter how many lines a statement spans,
+// or how many statements a line has.
+// a statement ends with the delimiter outside quoted strings, quoted identifiers and comments.
+// the delimiter is `;`, and is changed by `DELIMITER` commands before statements like the mysql client,
+// so stored routines and triggers with `;` in their bodies are read as one statement.
+// it only holds the statement being read besides a fixed-size read buffer,
+// so the memory is bounded by the largest statement, rather than the longest line or the file.
+type statementReader struct {
+	br  *bufio.Reader
+	buf []byte // the statement being read
+	// the statement starts from start of buf, bytes before it are whitespaces and `DELIMITER` commands
+	start     int
+	delimiter string
+	matched   int // count of bytes of the delimiter matched
+
+	quote       byte // the quote character of the string or identifier being read, 0 if not in any
+	escape      bool // whether the previous character is `\` in a quoted string
+	comment     bool // whether in a block comment
+	lineComment bool // whether in a line comment started by `#` or `-- `
+	prev        byte // the previous character, to find `/*` and `*/`
+	dashes      int  // count of `-` just before, `--` followed by a whitespace starts a line comment
+}
+
+func newStatementReader(r io.Reader) *statementReader {
+	return &statementReader{br: bufio.NewReaderSize(r, statementReadBufferSize), delimiter: defaultDelimiter}
+}
+
+// next returns the data of the next statement, which are all bytes consumed by it,
+// including the whitespaces before it and the rest of the line after it if they are whitespaces.
+// so the sum of lengths of data returned is the position in the file, which can be saved in checkpoint.
+// `DELIMITER` commands before the statement are also in data, use statement to get the statement itself.
+// it returns io.EOF with the rest data if no statements left, the rest data are not empty if the file ends in a statement.
+// the data returned are only valid before the next call.
+func (r *statementReader) next() ([]byte, error) {
+	if cap(r.buf) > maxReusedStatementSize {
+		r.buf = nil
+	} else {
+		r.buf = r.buf[:0]
+	}
+	r.start = 0
+	if err := r.readDelimiterCommands(); err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	for {
+		if r.br.Buffered() == 0 {
+			_, err := r.br.Peek(1)
+			if err == io.EOF {
+				return r.buf, io.EOF
+			} else if err != nil {
+				return nil, errors.Trace(err)
+			}
+		}
+
+		chunk, _ := r.br.Peek(r.br.Buffered())
+		n, ended := r.scan(chunk)
+		r.append(chunk[:n])
+		r.br.Discard(n)
+		if ended {
+			r.skipLineEnd()
+			return r.buf, nil
+		}
+	}
+}
+
+// statement returns the statement read by the last next with whitespaces trimmed,
+// the delimiter in the end is replaced by `;` if it's not `;`, so it can be executed as other statements.
+func (r *statementReader) statement() string {
+	stmt := bytes.TrimSpace(r.buf[r.start:])
+	if r.delimiter == defaultDelimiter || !bytes.HasSuffix(stmt, []byte(r.delimiter)) {
+		return string(stmt)
+	}
+	stmt = bytes.TrimSpace(stmt[:len(stmt)-len(r.delimiter)])
+	return string(stmt) + defaultDelimiter
+}
+
+// readDelimiterCommands reads whitespaces and `DELIMITER` commands before the statement,
+// a command like `DELIMITER $$` takes a whole line.
+func (r *statementReader) readDelimiterCommands() error {
+	for {
+		b, err := r.br.Peek(1)
+		if err != nil {
+			return nil // EOF or the error is returned by the following read
+		}
+		if isSpace(b[0]) {
+			r.append(b)
+			r.br.Discard(1)
+			continue
+		}
+
+		b, _ = r.br.Peek(len(delimiterCommand) + 1)
+		if _, ok := parseDelimiterCommand(string(b)); !ok {
+			return nil
+		}
+		line, err := r.br.ReadSlice('\n')
+		if err != nil && err != io.EOF {
+			return errors.Annotatef(err, "read %-.100s", line)
+		}
+		delimiter, _ := parseDelimiterCommand(string(line))
+		if len(delimiter) == 0 || strings.ContainsAny(delimiter, "'\"`\\") {
+			return errors.NotValidf("delimiter %q", delimiter)
+		}
+		r.append(line)
+		r.delimiter = delimiter
+		r.start = len(r.buf)
+	}
+}
+
+// parseDelimiterCommand returns the delimiter in line and true if line is a `DELIMITER` command
+func parseDelimiterCommand(line string) (string, bool) {
+	if len(line) <= len(delimiterCommand) || !strings.EqualFold(line[:len(delimiterCommand)], delimiterCommand) || !isSpace(line[len(delimiterCommand)]) {
+		return "", false
+	}
+	return strings.TrimSpace(line[len(delimiterCommand):]), true
+}
+
+func isSpace(ch byte) bool {
+	return ch == ' ' || ch == '\t' || ch == '\r' || ch == '\n'
+}
+
+// scan scans chunk for the end of the statement,
+// it returns the count of bytes in chunk belonging to the statement, and whether the statement ends in chunk.
+func (r *statementReader) scan(chunk []byte) (int, bool) {
+	for i, ch := range chunk {
+		prev := r.prev
+		r.prev = ch
+		switch {
+		case r.escape:
+			r.escape = false
+		case r.quote != 0:
+			// quotes doubled like 'it''s' are read as two adjacent strings
+			if ch == '\\' && r.quote != '`' {
+				r.escape = true
+			} else if ch == r.quote {
+				r.quote = 0
+			}
+		case r.comment:
+			if prev == '*' && ch == '/' {
+				r.comment = false
+				r.prev = 0
+			}
+		case r.lineComment:
+			if ch == '\n' {
+				r.lineComment = false
+			}
+		default:
+			// like the mysql client, `--` followed by a whitespace or control character starts a comment, or else they are two minus signs
+			if r.dashes >= 2 && ch <= ' ' {
+				r.dashes = 0
+				r.lineComment = ch != '\n'
+				r.matched = 0
+				continue
+			}
+			if ch == '-' {
+				r.dashes++
+			} else {
+				r.dashes = 0
+			}
+			switch ch {
+			case '#':
+				r.lineComment = true
+				r.matched = 0
+				continue
+			case '\'', '"', '`':
+				r.quote = ch
+				r.matched = 0
+				continue
+			case '*':
+				if prev == '/' {
+					r.comment = true
+					r.prev = 0 // `/*/` doesn't end the comment
+					r.matched = 0
+					continue
+				}
+			}
+			if ch == r.delimiter[r.matched] {
+				r.matched++
+			} else if ch == r.delimiter[0] {
+				r.matched = 1
+			} else {
+				r.matched = 0
+			}
+			if r.matched == len(r.delimiter) {
+				r.matched = 0
+				return i + 1, true
+			}
+		}
+	}
+	return len(chunk), false
+}
+
+// skipLineEnd consumes whitespaces after the statement until the end of the line
+func (r *statementReader) skipLineEnd() {
+	for {
+		b, err := r.br.Peek(1)
+		if err != nil {
+			return
+		}
+		switch b[0] {
+		case ' ', '\t', '\r':
+		case '\n':
+			r.append(b)
+			r.br.Discard(1)
+			return
+		default:
+			return
+		}
+		r.append(b)
+		r.br.Discard(1)
+	}
+}
+
+// append appends data to the statement being read, the buffer is doubled when it's full,
+// which allocates less than append for huge statements
+func (r *statementReader) append(data []byte) {
+	if need := len(r.buf) + len(data); need > cap(r.buf) {
+		size := 2 * cap(r.buf)
+		if size < need {
+			size = need
+		}
+		if size < statementReadBufferSize {
+			size = statementReadBufferSize
+		}
+		buf := make([]byte, len(r.buf), size)
+		copy(buf, r.buf)
+		r.buf = buf
+	}
+	r.buf = append(r.buf, data...)
+}
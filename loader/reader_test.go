@@ -0,0 +1,169 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loader
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testReaderSuite{})
+
+type testReaderSuite struct{}
+
+// readStatements reads all statements from data, and checks the positions sum to the length of data
+func readStatements(c *C, data string) ([]string, string) {
+	var (
+		stmts []string
+		pos   int
+	)
+	sr := newStatementReader(strings.NewReader(data))
+	for {
+		stmt, err := sr.next()
+		pos += len(stmt)
+		if err == io.EOF {
+			c.Assert(pos, Equals, len(data))
+			return stmts, string(stmt)
+		}
+		c.Assert(err, IsNil)
+		stmts = append(stmts, string(stmt))
+	}
+}
+
+func (t *testReaderSuite) TestStatementReader(c *C) {
+	cases := []struct {
+		data  string
+		stmts []string
+		rest  string
+	}{
+		{"", nil, ""},
+		{"\n\n", nil, "\n\n"},
+		{"INSERT INTO `t` VALUES (1);\n", []string{"INSERT INTO `t` VALUES (1);\n"}, ""},
+		// the last line without '\n'
+		{"INSERT INTO `t` VALUES (1);", []string{"INSERT INTO `t` VALUES (1);"}, ""},
+		// the rest of the line after `;` is consumed only if they are whitespaces
+		{"INSERT INTO `t` VALUES (1); \r\n\nINSERT INTO `t` VALUES (2);\n", []string{"INSERT INTO `t` VALUES (1); \r\n", "\nINSERT INTO `t` VALUES (2);\n"}, ""},
+		{"INSERT INTO `t` VALUES (1);INSERT INTO `t` VALUES (2);\n", []string{"INSERT INTO `t` VALUES (1);", "INSERT INTO `t` VALUES (2);\n"}, ""},
+		{"INSERT INTO `t` VALUES\n(1),\n(2);\n", []string{"INSERT INTO `t` VALUES\n(1),\n(2);\n"}, ""},
+		// `;` in quoted strings, identifiers and comments
+		{"INSERT INTO `t;` VALUES (1,'a;\nb'),(2,\"c;\");\n", []string{"INSERT INTO `t;` VALUES (1,'a;\nb'),(2,\"c;\");\n"}, ""},
+		{"INSERT INTO `t` VALUES (1,'it\\'s;'),(2,'it''s;'),(3,'\\\\');\n", []string{"INSERT INTO `t` VALUES (1,'it\\'s;'),(2,'it''s;'),(3,'\\\\');\n"}, ""},
+		{"/*!40101 SET NAMES binary*/;\n/* a;'b */INSERT INTO `t` VALUES (1);\n", []string{"/*!40101 SET NAMES binary*/;\n", "/* a;'b */INSERT INTO `t` VALUES (1);\n"}, ""},
+		{"/*/;*/;\n", []string{"/*/;*/;\n"}, ""},
+		{"# a;'b\nINSERT INTO `t` VALUES (1); # c\n;\n", []string{"# a;'b\nINSERT INTO `t` VALUES (1); ", "# c\n;\n"}, ""},
+		{"-- a;'b\nINSERT INTO `t` VALUES\n--\t(0);\n(1);\n--\n", []string{"-- a;'b\nINSERT INTO `t` VALUES\n--\t(0);\n(1);\n"}, "--\n"},
+		// `--` not followed by a whitespace are minus signs
+		{"INSERT INTO `t` VALUES (1--1);INSERT INTO `t` VALUES (2);\n", []string{"INSERT INTO `t` VALUES (1--1);", "INSERT INTO `t` VALUES (2);\n"}, ""},
+		// ends in a statement
+		{"INSERT INTO `t` VALUES (1);\nINSERT INTO `t` VALUES (2)", []string{"INSERT INTO `t` VALUES (1);\n"}, "INSERT INTO `t` VALUES (2)"},
+		{"INSERT INTO `t` VALUES (1,'a;);\n", nil, "INSERT INTO `t` VALUES (1,'a;);\n"},
+	}
+	for _, cs := range cases {
+		stmts, rest := readStatements(c, cs.data)
+		c.Assert(stmts, DeepEquals, cs.stmts, Commentf("data %q", cs.data))
+		c.Assert(rest, Equals, cs.rest, Commentf("data %q", cs.data))
+	}
+}
+
+func (t *testReaderSuite) TestStatementReaderHugeStatement(c *C) {
+	// a statement of about 16MB in one line, with `;` and escaped quotes in strings
+	var buf bytes.Buffer
+	buf.WriteString("INSERT INTO `t` VALUES ")
+	rows := 0
+	for buf.Len() < 16*1024*1024 {
+		if rows > 0 {
+			buf.WriteByte(',')
+		}
+		fmt.Fprintf(&buf, "(%d,'a;b\\'c;%s')", rows, strings.Repeat("x", 100))
+		rows++
+	}
+	buf.WriteString(";\nINSERT INTO `t` VALUES (0,'');\n")
+	data := buf.Bytes()
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	sr := newStatementReader(bytes.NewReader(data))
+	stmt, err := sr.next()
+	runtime.ReadMemStats(&after)
+	c.Assert(err, IsNil)
+	size := len(stmt)
+	c.Assert(bytes.Equal(stmt, data[:size]), IsTrue)
+	c.Assert(countRows(string(stmt)), Equals, int64(rows))
+	// only the statement is held, in a buffer at most twice of it.
+	// the buffer is doubled when it's full, so all buffers allocated sum to less than twice of the last one
+	c.Assert(cap(sr.buf) <= 2*size, IsTrue)
+	allocated := after.TotalAlloc - before.TotalAlloc
+	c.Assert(allocated < uint64(2*cap(sr.buf)+statementReadBufferSize), IsTrue, Commentf("allocated %d bytes for a statement of %d bytes", allocated, size))
+
+	// the buffer of the huge statement is released after it
+	stmt, err = sr.next()
+	c.Assert(err, IsNil)
+	c.Assert(string(stmt), Equals, "INSERT INTO `t` VALUES (0,'');\n")
+	stmt, err = sr.next()
+	c.Assert(err, Equals, io.EOF)
+	c.Assert(stmt, HasLen, 0)
+
+	c.Assert(cap(sr.buf) <= maxReusedStatementSize, IsTrue)
+}
+
+func (t *testReaderSuite) TestStatementReaderDelimiter(c *C) {
+	data := "CREATE TABLE `t` (`id` INT);\n" +
+		"DELIMITER ;;\n" +
+		"CREATE TRIGGER `tr` BEFORE INSERT ON `t` FOR EACH ROW BEGIN\n  SET NEW.`id` = NEW.`id` + 1;\n  SET @a = ';;';\nEND ;;\n" +
+		"delimiter $$\n\n" +
+		"CREATE PROCEDURE `p`() BEGIN SELECT 1; SELECT 2; END$$\n" +
+		"DELIMITER ;\n" +
+		"INSERT INTO `t` VALUES (1);\n" +
+		"DELIMITER //\n"
+
+	var (
+		stmts []string
+		pos   int
+	)
+	sr := newStatementReader(strings.NewReader(data))
+	for {
+		stmt, err := sr.next()
+		pos += len(stmt)
+		if err == io.EOF {
+			c.Assert(sr.statement(), Equals, "")
+			break
+		}
+		c.Assert(err, IsNil)
+		stmts = append(stmts, sr.statement())
+	}
+	c.Assert(pos, Equals, len(data))
+	c.Assert(stmts, DeepEquals, []string{
+		"CREATE TABLE `t` (`id` INT);",
+		"CREATE TRIGGER `tr` BEFORE INSERT ON `t` FOR EACH ROW BEGIN\n  SET NEW.`id` = NEW.`id` + 1;\n  SET @a = ';;';\nEND;",
+		"CREATE PROCEDURE `p`() BEGIN SELECT 1; SELECT 2; END;",
+		"INSERT INTO `t` VALUES (1);",
+	})
+
+	// statements without `DELIMITER` commands are the same as their data
+	sr = newStatementReader(strings.NewReader("  INSERT INTO `delimiter` VALUES (1);\n"))
+	stmt, err := sr.next()
+	c.Assert(err, IsNil)
+	c.Assert(string(stmt), Equals, "  INSERT INTO `delimiter` VALUES (1);\n")
+	c.Assert(sr.statement(), Equals, "INSERT INTO `delimiter` VALUES (1);")
+
+	_, err = newStatementReader(strings.NewReader("DELIMITER \n")).next()
+	c.Assert(err, ErrorMatches, ".*delimiter \"\" not valid")
+}
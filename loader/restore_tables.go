@@ -0,0 +1,90 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loader
+
+import (
+	"strings"
+
+	"github.com/pingcap/errors"
+	selector "github.com/pingcap/tidb-tools/pkg/table-rule-selector"
+
+	"github.com/pingcap/dm/dm/config"
+)
+
+// restoreTables decides which tables in the dump are restored,
+// see config.LoaderConfig.RestoreAllowTables and config.LoaderConfig.RestoreBlockTables.
+type restoreTables struct {
+	caseSensitive bool
+	allow         selector.Selector // nil means all tables allowed
+	block         selector.Selector // nil means no table blocked
+}
+
+func newRestoreTables(caseSensitive bool, allow, block []*config.TablePattern) (*restoreTables, error) {
+	r := &restoreTables{caseSensitive: caseSensitive}
+	var err error
+	if r.allow, err = r.newSelector(allow); err != nil {
+		return nil, errors.Annotate(err, "restore-allow-tables")
+	}
+	if r.block, err = r.newSelector(block); err != nil {
+		return nil, errors.Annotate(err, "restore-block-tables")
+	}
+	return r, nil
+}
+
+func (r *restoreTables) newSelector(patterns []*config.TablePattern) (selector.Selector, error) {
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+	s := selector.NewTrieSelector()
+	for _, p := range patterns {
+		schema, table := r.fold(p.SchemaPattern, p.TablePattern)
+		if err := s.Insert(schema, table, p, false); err != nil {
+			return nil, errors.Annotatef(err, "table pattern %+v", p)
+		}
+	}
+	return s, nil
+}
+
+func (r *restoreTables) fold(schema, table string) (string, string) {
+	if !r.caseSensitive {
+		return strings.ToLower(schema), strings.ToLower(table)
+	}
+	return schema, table
+}
+
+// match returns whether the table is restored. for a schema (the table is empty), only schema level rules of block lists apply,
+// as whether its tables are allowed is not known.
+func (r *restoreTables) match(schema, table string) bool {
+	if r == nil {
+		return true
+	}
+	schema, table = r.fold(schema, table)
+	if table == "" {
+		return r.block == nil || !matchSchemaLevel(r.block, schema)
+	}
+	if r.allow != nil && len(r.allow.Match(schema, table)) == 0 {
+		return false
+	}
+	return r.block == nil || len(r.block.Match(schema, table)) == 0
+}
+
+// matchSchemaLevel returns whether the schema is matched by a rule without table-pattern
+func matchSchemaLevel(s selector.Selector, schema string) bool {
+	for _, rule := range s.Match(schema, "") {
+		if p, ok := rule.(*config.TablePattern); ok && p.TablePattern == "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loader
+
+import (
+	. "github.com/pingcap/check"
+
+	"github.com/pingcap/dm/dm/config"
+)
+
+var _ = Suite(&testRestoreTablesSuite{})
+
+type testRestoreTablesSuite struct{}
+
+func (t *testRestoreTablesSuite) TestMatch(c *C) {
+	// all restored without rules
+	r, err := newRestoreTables(false, nil, nil)
+	c.Assert(err, IsNil)
+	c.Assert(r.match("db", "t1"), IsTrue)
+	c.Assert(r.match("db", ""), IsTrue)
+
+	allow := []*config.TablePattern{{SchemaPattern: "db*", TablePattern: "t?"}, {SchemaPattern: "log"}}
+	block := []*config.TablePattern{{SchemaPattern: "db1", TablePattern: "t2"}, {SchemaPattern: "tmp_*"}}
+	r, err = newRestoreTables(false, allow, block)
+	c.Assert(err, IsNil)
+	cases := []struct {
+		schema, table string
+		restored      bool
+	}{
+		{"db1", "t1", true},
+		{"DB2", "T1", true}, // case insensitive
+		{"db1", "t2", false},
+		{"db1", "t10", false},
+		{"log", "any", true},
+		{"other", "t1", false},
+		{"tmp_db", "t1", false},
+		// schemas are only blocked by schema level rules
+		{"db1", "", true},
+		{"other", "", true},
+		{"tmp_db", "", false},
+	}
+	for _, cs := range cases {
+		c.Assert(r.match(cs.schema, cs.table), Equals, cs.restored, Commentf("%s.%s", cs.schema, cs.table))
+	}
+
+	// case sensitive
+	r, err = newRestoreTables(true, allow, nil)
+	c.Assert(err, IsNil)
+	c.Assert(r.match("DB2", "t1"), IsFalse)
+
+	// invalid patterns, the asterisk must be the last character
+	_, err = newRestoreTables(false, nil, []*config.TablePattern{{SchemaPattern: "*db"}})
+	c.Assert(err, ErrorMatches, ".*restore-block-tables.*")
+}
@@ -0,0 +1,102 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loader
+
+import (
+	"time"
+
+	"github.com/pingcap/dm/dm/config"
+	"github.com/pingcap/dm/pkg/log"
+	"github.com/pingcap/errors"
+	"golang.org/x/net/context"
+)
+
+// RetryPolicy decides whether and when a failed operation is retried,
+// like executing statements in the downstream database and loading checkpoints.
+type RetryPolicy struct {
+	// max times to retry after the first attempt failed, 0 means not retried
+	MaxRetries int
+	// backoff before the i-th retry (starts from 1), nil means retried immediately
+	Backoff func(i int) time.Duration
+	// whether the error is retried, like transient network errors rather than duplicate entries, nil means no error is retried
+	RetryableFunc func(err error) bool
+}
+
+// noRetry executes the operation only once
+var noRetry = RetryPolicy{}
+
+// newRetryPolicy returns the policy retrying transient errors (see isRetryableError) with exponential backoff,
+// it's tuned by retry-limit and retry-max-interval of cfg.
+func newRetryPolicy(cfg *config.SubTaskConfig) RetryPolicy {
+	maxRetries := defaultMaxRetries
+	maxInterval := retryMaxInterval
+	if cfg != nil {
+		if cfg.RetryLimit > 0 {
+			maxRetries = cfg.RetryLimit
+		}
+		if cfg.RetryMaxInterval != "" {
+			// validated by the config
+			if d, err := time.ParseDuration(cfg.RetryMaxInterval); err == nil {
+				maxInterval = d
+			}
+		}
+	}
+	return RetryPolicy{
+		MaxRetries: maxRetries,
+		Backoff: func(i int) time.Duration {
+			return backoffInterval(i, retryBaseInterval, maxInterval)
+		},
+		RetryableFunc: isRetryableError,
+	}
+}
+
+// withRetryable returns a copy of the policy retrying errors decided by fn
+func (p RetryPolicy) withRetryable(fn func(err error) bool) RetryPolicy {
+	p.RetryableFunc = fn
+	return p
+}
+
+// Do executes fn until it succeeds, fails with an error not retryable, the retries run out or ctx is done,
+// and returns the last error of fn. name is the operation logged when retried.
+func (p RetryPolicy) Do(ctx context.Context, name string, fn func() error) error {
+	var err error
+	for i := 0; ; i++ {
+		if i > 0 {
+			log.Warnf("[retry] %-.100s retry %d: %v", name, i, err)
+			var backoff time.Duration
+			if p.Backoff != nil {
+				backoff = p.Backoff(i)
+			}
+			select {
+			case <-ctx.Done():
+				return errors.Annotatef(err, "stop retrying because %v", ctx.Err())
+			case <-time.After(backoff):
+			}
+		}
+
+		err = fn()
+		if err == nil || i >= p.MaxRetries || p.RetryableFunc == nil || !p.RetryableFunc(err) {
+			return err
+		}
+	}
+}
+
+// backoffInterval returns the backoff before the i-th retry, it grows exponentially from base to max.
+func backoffInterval(i int, base, max time.Duration) time.Duration {
+	d := base << uint(i-1)
+	if d <= 0 || d > max {
+		d = max
+	}
+	return d
+}
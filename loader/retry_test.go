@@ -0,0 +1,94 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loader
+
+import (
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	. "github.com/pingcap/check"
+	"github.com/pingcap/dm/dm/config"
+	"github.com/pingcap/errors"
+	tmysql "github.com/pingcap/parser/mysql"
+	"golang.org/x/net/context"
+)
+
+var _ = Suite(&testRetrySuite{})
+
+type testRetrySuite struct{}
+
+func (t *testRetrySuite) TestRetryPolicy(c *C) {
+	deadlock := &mysql.MySQLError{Number: tmysql.ErrLockDeadlock, Message: "Deadlock found when trying to get lock"}
+	flapping := func(errs ...error) (func() error, *int) {
+		calls := 0
+		return func() error {
+			calls++
+			if calls <= len(errs) {
+				return errs[calls-1]
+			}
+			return nil
+		}, &calls
+	}
+
+	var backoffs []int
+	policy := RetryPolicy{
+		MaxRetries: 2,
+		Backoff: func(i int) time.Duration {
+			backoffs = append(backoffs, i)
+			return 0
+		},
+		RetryableFunc: isRetryableError,
+	}
+
+	// retried until succeeded
+	fn, calls := flapping(deadlock, deadlock)
+	c.Assert(policy.Do(context.Background(), "test", fn), IsNil)
+	c.Assert(*calls, Equals, 3)
+	c.Assert(backoffs, DeepEquals, []int{1, 2})
+
+	// the retries run out
+	fn, calls = flapping(deadlock, deadlock, deadlock)
+	c.Assert(policy.Do(context.Background(), "test", fn), Equals, deadlock)
+	c.Assert(*calls, Equals, 3)
+
+	// errors not retryable, like duplicate entries
+	fn, calls = flapping(&mysql.MySQLError{Number: tmysql.ErrDupEntry, Message: "Duplicate entry"})
+	c.Assert(policy.Do(context.Background(), "test", fn), ErrorMatches, ".*Duplicate entry.*")
+	c.Assert(*calls, Equals, 1)
+	fn, calls = flapping(deadlock)
+	c.Assert(policy.withRetryable(func(error) bool { return false }).Do(context.Background(), "test", fn), Equals, deadlock)
+	c.Assert(*calls, Equals, 1)
+	fn, calls = flapping(deadlock)
+	c.Assert(noRetry.Do(context.Background(), "test", fn), Equals, deadlock)
+	c.Assert(*calls, Equals, 1)
+
+	// stop retrying if canceled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	policy.Backoff = func(int) time.Duration { return time.Hour }
+	fn, calls = flapping(deadlock)
+	err := policy.Do(ctx, "test", fn)
+	c.Assert(err, ErrorMatches, ".*stop retrying because context canceled.*")
+	c.Assert(errors.Cause(err), Equals, deadlock)
+	c.Assert(*calls, Equals, 1)
+
+	// tuned by the config
+	policy = newRetryPolicy(&config.SubTaskConfig{LoaderConfig: config.LoaderConfig{RetryLimit: 3, RetryMaxInterval: "4s"}})
+	c.Assert(policy.MaxRetries, Equals, 3)
+	c.Assert(policy.Backoff(1), Equals, retryBaseInterval)
+	c.Assert(policy.Backoff(10), Equals, 4*time.Second)
+	policy = newRetryPolicy(&config.SubTaskConfig{})
+	c.Assert(policy.MaxRetries, Equals, defaultMaxRetries)
+	c.Assert(policy.Backoff(10), Equals, retryMaxInterval)
+}
@@ -0,0 +1,508 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loader
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/parser"
+	"github.com/pingcap/parser/ast"
+	"github.com/pingcap/parser/opcode"
+	_ "github.com/pingcap/tidb/types/parser_driver" // use value expression impl in TiDB
+
+	"github.com/pingcap/dm/dm/config"
+	"github.com/pingcap/dm/pkg/utils"
+)
+
+// rowFilters resolves the row filters of source tables, see config.LoaderConfig.RowFilters.
+type rowFilters struct {
+	rules *utils.TableRules
+}
+
+func newRowFilters(caseSensitive bool, rules []*config.RowFilterRule) (*rowFilters, error) {
+	f := &rowFilters{rules: utils.NewTableRules("row filters", caseSensitive)}
+	for _, rule := range rules {
+		// conditions are parsed here, so invalid ones fail the task before any data restored
+		if _, err := parseWhere(rule.Where); err != nil {
+			return nil, errors.Annotatef(err, "row filter %+v", rule)
+		}
+		if err := f.rules.Insert(rule.SchemaPattern, rule.TablePattern, rule); err != nil {
+			return nil, errors.Annotatef(err, "row filter %+v", rule)
+		}
+	}
+	return f, nil
+}
+
+// forTable returns the row filter of the source table, nil if the table is not matched by any rule.
+// see utils.TableRules for rules matching the table.
+func (f *rowFilters) forTable(table *tableInfo) (*rowFilter, error) {
+	if f == nil {
+		return nil, nil
+	}
+	rule, err := f.rules.Match(table.sourceSchema, table.sourceTable)
+	if err != nil || rule == nil {
+		return nil, errors.Trace(err)
+	}
+	filter, err := compileRowFilter(rule.(*config.RowFilterRule).Where, table.columnNameList)
+	return filter, errors.Annotatef(err, "row filter of table %s.%s", table.sourceSchema, table.sourceTable)
+}
+
+/* Evaluation of row filters
+ * a row filter is a condition like the WHERE clause, evaluated against the values of rows as they are dumped, before column mapping.
+ * values are typed by how they are dumped rather than the types of columns: NULL is NULL, quoted values are strings,
+ * and other values are numbers. like MySQL, a string compared with a number is converted to a number,
+ * (but compared as a string if it's not a number at all), and strings are compared byte by byte (case sensitive).
+ * any comparison with NULL is unknown (NULL) except `<=>` and `IS [NOT] NULL`, and rows are restored only if the condition is TRUE.
+ */
+
+type valueKind byte
+
+const (
+	kindNull valueKind = iota
+	kindNumber
+	kindString
+)
+
+// filterValue is a value of a column or a literal in row filters
+type filterValue struct {
+	kind valueKind
+	str  string // the text of the number or the unescaped string
+}
+
+var (
+	nullValue  = filterValue{kind: kindNull}
+	trueValue  = filterValue{kind: kindNumber, str: "1"}
+	falseValue = filterValue{kind: kindNumber, str: "0"}
+)
+
+func boolValue(b bool) filterValue {
+	if b {
+		return trueValue
+	}
+	return falseValue
+}
+
+func (v filterValue) number() (*big.Float, bool) {
+	f, ok := new(big.Float).SetPrec(128).SetString(strings.TrimSpace(v.str))
+	return f, ok
+}
+
+// truth returns whether the value is true, and whether it's NULL
+func (v filterValue) truth() (bool, bool) {
+	if v.kind == kindNull {
+		return false, true
+	}
+	f, ok := v.number()
+	return ok && f.Sign() != 0, false
+}
+
+// compareValues compares a and b, it returns false if any of them is NULL
+func compareValues(a, b filterValue) (int, bool) {
+	if a.kind == kindNull || b.kind == kindNull {
+		return 0, false
+	}
+	if a.kind == kindNumber || b.kind == kindNumber {
+		fa, okA := a.number()
+		fb, okB := b.number()
+		if okA && okB {
+			return fa.Cmp(fb), true
+		}
+	}
+	return strings.Compare(a.str, b.str), true
+}
+
+type evalFunc func(row []filterValue) filterValue
+
+// rowFilter drops rows of a table not satisfying the condition
+type rowFilter struct {
+	where   string
+	columns int // count of columns of rows
+	eval    evalFunc
+}
+
+func parseWhere(where string) (ast.ExprNode, error) {
+	if strings.TrimSpace(where) == "" {
+		return nil, errors.NotValidf("empty condition")
+	}
+	stmt, err := parser.New().ParseOneStmt(fmt.Sprintf("SELECT * FROM t WHERE %s", where), "", "")
+	if err != nil {
+		return nil, errors.Annotatef(err, "parse condition %s", where)
+	}
+	sel, ok := stmt.(*ast.SelectStmt)
+	if !ok || sel.Where == nil || sel.OrderBy != nil || sel.Limit != nil || sel.GroupBy != nil {
+		return nil, errors.NotValidf("condition %s", where)
+	}
+	return sel.Where, nil
+}
+
+// compileRowFilter compiles the condition against the columns of rows
+func compileRowFilter(where string, columns []string) (*rowFilter, error) {
+	expr, err := parseWhere(where)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	c := &filterCompiler{columns: make(map[string]int, len(columns))}
+	for i, col := range columns {
+		c.columns[strings.ToLower(col)] = i
+	}
+	eval, err := c.compile(expr)
+	if err != nil {
+		return nil, errors.Annotatef(err, "condition %s", where)
+	}
+	return &rowFilter{where: where, columns: len(columns), eval: eval}, nil
+}
+
+// match returns whether the row is restored. values are the texts of values of the row in the data file,
+// and quotes are their quote characters, 0 if not quoted, see parseRowValues.
+func (f *rowFilter) match(values []interface{}, quotes []byte) (bool, error) {
+	if len(values) != f.columns {
+		return false, errors.Errorf("row filter %s: %d values of the row mismatch %d columns", f.where, len(values), f.columns)
+	}
+	row := make([]filterValue, len(values))
+	for i, v := range values {
+		s, _ := v.(string)
+		switch {
+		case quotes[i] != 0x0:
+			row[i] = filterValue{kind: kindString, str: unescapeValue(s)}
+		case strings.EqualFold(s, "NULL"):
+			row[i] = nullValue
+		default:
+			row[i] = filterValue{kind: kindNumber, str: s}
+		}
+	}
+	ok, _ := f.eval(row).truth()
+	return ok, nil
+}
+
+// unescapeValue unescapes a quoted value in the data file, like `it\'s` to `it's`
+func unescapeValue(s string) string {
+	if strings.IndexByte(s, '\\') < 0 {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		if ch != '\\' || i == len(s)-1 {
+			b.WriteByte(ch)
+			continue
+		}
+		i++
+		switch s[i] {
+		case '0':
+			b.WriteByte(0)
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case 'Z':
+			b.WriteByte(0x1a)
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+type filterCompiler struct {
+	columns map[string]int // lower case name -> index
+}
+
+func (c *filterCompiler) compile(expr ast.ExprNode) (evalFunc, error) {
+	switch e := expr.(type) {
+	case *ast.ParenthesesExpr:
+		return c.compile(e.Expr)
+	case *ast.ColumnNameExpr:
+		idx, ok := c.columns[e.Name.Name.L]
+		if !ok {
+			return nil, errors.NotFoundf("column %s", e.Name.Name.O)
+		}
+		return func(row []filterValue) filterValue { return row[idx] }, nil
+	case ast.ValueExpr:
+		v, err := literalValue(e)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		return func([]filterValue) filterValue { return v }, nil
+	case *ast.UnaryOperationExpr:
+		return c.compileUnary(e)
+	case *ast.BinaryOperationExpr:
+		return c.compileBinary(e)
+	case *ast.IsNullExpr:
+		operand, err := c.compile(e.Expr)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		return func(row []filterValue) filterValue {
+			return boolValue((operand(row).kind == kindNull) != e.Not)
+		}, nil
+	case *ast.BetweenExpr:
+		return c.compileBetween(e)
+	case *ast.PatternInExpr:
+		return c.compileIn(e)
+	case *ast.PatternLikeExpr:
+		return c.compileLike(e)
+	default:
+		return nil, errors.NotSupportedf("expression %T in row filter", expr)
+	}
+}
+
+func literalValue(e ast.ValueExpr) (filterValue, error) {
+	switch v := e.GetValue().(type) {
+	case nil:
+		return nullValue, nil
+	case string:
+		return filterValue{kind: kindString, str: v}, nil
+	case int64, uint64, float64:
+		return filterValue{kind: kindNumber, str: fmt.Sprint(v)}, nil
+	case fmt.Stringer: // decimal
+		return filterValue{kind: kindNumber, str: v.String()}, nil
+	default:
+		return nullValue, errors.NotSupportedf("literal %v (%T) in row filter", v, v)
+	}
+}
+
+func (c *filterCompiler) compileUnary(e *ast.UnaryOperationExpr) (evalFunc, error) {
+	operand, err := c.compile(e.V)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	switch e.Op {
+	case opcode.Not:
+		return func(row []filterValue) filterValue {
+			t, isNull := operand(row).truth()
+			if isNull {
+				return nullValue
+			}
+			return boolValue(!t)
+		}, nil
+	case opcode.Plus:
+		return operand, nil
+	case opcode.Minus:
+		return func(row []filterValue) filterValue {
+			v := operand(row)
+			f, ok := v.number()
+			if v.kind == kindNull || !ok {
+				return nullValue
+			}
+			return filterValue{kind: kindNumber, str: f.Neg(f).Text('g', -1)}
+		}, nil
+	default:
+		return nil, errors.NotSupportedf("operator %s in row filter", e.Op)
+	}
+}
+
+func (c *filterCompiler) compileBinary(e *ast.BinaryOperationExpr) (evalFunc, error) {
+	left, err := c.compile(e.L)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	right, err := c.compile(e.R)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	switch e.Op {
+	case opcode.LogicAnd:
+		return func(row []filterValue) filterValue {
+			l, lNull := left(row).truth()
+			if !l && !lNull {
+				return falseValue
+			}
+			r, rNull := right(row).truth()
+			if !r && !rNull {
+				return falseValue
+			}
+			if lNull || rNull {
+				return nullValue
+			}
+			return trueValue
+		}, nil
+	case opcode.LogicOr:
+		return func(row []filterValue) filterValue {
+			l, lNull := left(row).truth()
+			if l {
+				return trueValue
+			}
+			r, rNull := right(row).truth()
+			if r {
+				return trueValue
+			}
+			if lNull || rNull {
+				return nullValue
+			}
+			return falseValue
+		}, nil
+	case opcode.LogicXor:
+		return func(row []filterValue) filterValue {
+			l, lNull := left(row).truth()
+			r, rNull := right(row).truth()
+			if lNull || rNull {
+				return nullValue
+			}
+			return boolValue(l != r)
+		}, nil
+	case opcode.NullEQ:
+		return func(row []filterValue) filterValue {
+			l, r := left(row), right(row)
+			if l.kind == kindNull || r.kind == kindNull {
+				return boolValue(l.kind == r.kind)
+			}
+			cmp, _ := compareValues(l, r)
+			return boolValue(cmp == 0)
+		}, nil
+	}
+
+	var test func(cmp int) bool
+	switch e.Op {
+	case opcode.EQ:
+		test = func(cmp int) bool { return cmp == 0 }
+	case opcode.NE:
+		test = func(cmp int) bool { return cmp != 0 }
+	case opcode.LT:
+		test = func(cmp int) bool { return cmp < 0 }
+	case opcode.LE:
+		test = func(cmp int) bool { return cmp <= 0 }
+	case opcode.GT:
+		test = func(cmp int) bool { return cmp > 0 }
+	case opcode.GE:
+		test = func(cmp int) bool { return cmp >= 0 }
+	default:
+		return nil, errors.NotSupportedf("operator %s in row filter", e.Op)
+	}
+	return func(row []filterValue) filterValue {
+		cmp, ok := compareValues(left(row), right(row))
+		if !ok {
+			return nullValue
+		}
+		return boolValue(test(cmp))
+	}, nil
+}
+
+func (c *filterCompiler) compileBetween(e *ast.BetweenExpr) (evalFunc, error) {
+	var operands [3]evalFunc
+	for i, expr := range []ast.ExprNode{e.Expr, e.Left, e.Right} {
+		operand, err := c.compile(expr)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		operands[i] = operand
+	}
+	return func(row []filterValue) filterValue {
+		v := operands[0](row)
+		low, lowOK := compareValues(v, operands[1](row))
+		high, highOK := compareValues(v, operands[2](row))
+		// `v BETWEEN l AND h` is `v >= l AND v <= h`, so it's FALSE rather than NULL if either side is FALSE
+		if (lowOK && low < 0) || (highOK && high > 0) {
+			return boolValue(e.Not)
+		}
+		if !lowOK || !highOK {
+			return nullValue
+		}
+		return boolValue(!e.Not)
+	}, nil
+}
+
+func (c *filterCompiler) compileIn(e *ast.PatternInExpr) (evalFunc, error) {
+	if e.Sel != nil {
+		return nil, errors.NotSupportedf("subquery in row filter")
+	}
+	operand, err := c.compile(e.Expr)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	list := make([]evalFunc, 0, len(e.List))
+	for _, expr := range e.List {
+		item, err := c.compile(expr)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		list = append(list, item)
+	}
+	return func(row []filterValue) filterValue {
+		v := operand(row)
+		if v.kind == kindNull {
+			return nullValue
+		}
+		hasNull := false
+		for _, item := range list {
+			cmp, ok := compareValues(v, item(row))
+			if !ok {
+				hasNull = true
+			} else if cmp == 0 {
+				return boolValue(!e.Not)
+			}
+		}
+		if hasNull {
+			return nullValue
+		}
+		return boolValue(e.Not)
+	}, nil
+}
+
+func (c *filterCompiler) compileLike(e *ast.PatternLikeExpr) (evalFunc, error) {
+	operand, err := c.compile(e.Expr)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	pattern, ok := e.Pattern.(ast.ValueExpr)
+	if !ok {
+		return nil, errors.NotSupportedf("LIKE with pattern %T in row filter", e.Pattern)
+	}
+	v, err := literalValue(pattern)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if v.kind == kindNull {
+		return func([]filterValue) filterValue { return nullValue }, nil
+	}
+	re, err := likeRegexp(v.str, e.Escape)
+	if err != nil {
+		return nil, errors.Annotatef(err, "LIKE pattern %s", v.str)
+	}
+	return func(row []filterValue) filterValue {
+		v := operand(row)
+		if v.kind == kindNull {
+			return nullValue
+		}
+		return boolValue(re.MatchString(v.str) != e.Not)
+	}, nil
+}
+
+// likeRegexp converts the pattern of LIKE to a regular expression, `%` matches any characters and `_` matches one character
+func likeRegexp(pattern string, escape byte) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("(?s)^")
+	for i := 0; i < len(pattern); i++ {
+		ch := pattern[i]
+		switch {
+		case ch == escape && i < len(pattern)-1:
+			i++
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		case ch == '%':
+			b.WriteString(".*")
+		case ch == '_':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
@@ -0,0 +1,116 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loader
+
+import (
+	. "github.com/pingcap/check"
+
+	"github.com/pingcap/dm/dm/config"
+)
+
+var _ = Suite(&testRowFilterSuite{})
+
+type testRowFilterSuite struct{}
+
+func (t *testRowFilterSuite) TestMatch(c *C) {
+	columns := []string{"id", "tenant_id", "name"}
+	// values as they are parsed from `(1,42,'it\'s')`, `(2,NULL,'b')` and so on
+	rows := []struct {
+		values []interface{}
+		quotes []byte
+	}{
+		{[]interface{}{"1", "42", `it\'s`}, []byte{0, 0, '\''}},
+		{[]interface{}{"2", "NULL", "b"}, []byte{0, 0, '\''}},
+		{[]interface{}{"3", "42.0", "NULL"}, []byte{0, 0, '\''}},
+		{[]interface{}{"4", "7", "abc"}, []byte{0, 0, '\''}},
+		{[]interface{}{"5", "0042", "ab"}, []byte{0, '\'', '"'}},
+	}
+	cases := []struct {
+		where   string
+		matched []bool
+	}{
+		{"tenant_id = 42", []bool{true, false, true, false, true}},
+		{"tenant_id > 10", []bool{true, false, true, false, true}},
+		{"tenant_id <> 42", []bool{false, false, false, true, false}},
+		{"tenant_id = '42'", []bool{true, false, true, false, false}}, // '0042' is compared as a string
+		{"id >= 2 AND id < 4", []bool{false, true, true, false, false}},
+		{"tenant_id IS NULL", []bool{false, true, false, false, false}},
+		{"NOT (tenant_id = 42)", []bool{false, false, false, true, false}},
+		{"tenant_id <=> NULL", []bool{false, true, false, false, false}},
+		{"tenant_id = 42 OR id = 2", []bool{true, true, true, false, true}},
+		{"tenant_id IN (7, NULL)", []bool{false, false, false, true, false}},
+		{"tenant_id NOT IN (7, 8)", []bool{true, false, true, false, true}},
+		{"id BETWEEN 2 AND 4", []bool{false, true, true, true, false}},
+		{"id > -1 AND tenant_id BETWEEN 1 AND 10", []bool{false, false, false, true, false}},
+		{"name = 'it''s'", []bool{true, false, false, false, false}},
+		{"name LIKE 'ab%'", []bool{false, false, false, true, true}},
+		// a quoted NULL is a string
+		{"name IS NOT NULL AND name = 'NULL'", []bool{false, false, true, false, false}},
+		// strings are compared byte by byte
+		{"name > 'abb'", []bool{true, true, false, true, false}},
+	}
+	for _, cs := range cases {
+		filter, err := compileRowFilter(cs.where, columns)
+		c.Assert(err, IsNil, Commentf("condition %s", cs.where))
+		for i, row := range rows {
+			matched, err := filter.match(row.values, row.quotes)
+			c.Assert(err, IsNil)
+			c.Assert(matched, Equals, cs.matched[i], Commentf("condition %s, row %d", cs.where, i))
+		}
+	}
+
+	// the row doesn't match the columns
+	filter, err := compileRowFilter("id = 1", columns)
+	c.Assert(err, IsNil)
+	_, err = filter.match([]interface{}{"1"}, []byte{0})
+	c.Assert(err, ErrorMatches, ".*mismatch 3 columns")
+
+	// invalid conditions
+	for _, where := range []string{"", "id = ", "age = 1", "id IN (SELECT 1)", "id + 1 = 2"} {
+		_, err = compileRowFilter(where, columns)
+		c.Assert(err, NotNil, Commentf("condition %s", where))
+	}
+}
+
+func (t *testRowFilterSuite) TestForTable(c *C) {
+	table := &tableInfo{sourceSchema: "DB", sourceTable: "orders_1", columnNameList: []string{"id", "tenant_id"}}
+	f, err := newRowFilters(false, nil)
+	c.Assert(err, IsNil)
+	filter, err := f.forTable(table)
+	c.Assert(err, IsNil)
+	c.Assert(filter, IsNil)
+
+	// the table level rule is preferred
+	f, err = newRowFilters(false, []*config.RowFilterRule{
+		{SchemaPattern: "db", Where: "id > 0"},
+		{SchemaPattern: "db", TablePattern: "orders_*", Where: "tenant_id = 42"},
+	})
+	c.Assert(err, IsNil)
+	filter, err = f.forTable(table)
+	c.Assert(err, IsNil)
+	c.Assert(filter.where, Equals, "tenant_id = 42")
+
+	// ambiguous rules
+	f, err = newRowFilters(false, []*config.RowFilterRule{
+		{SchemaPattern: "db", TablePattern: "orders_*", Where: "id > 0"},
+		{SchemaPattern: "db", TablePattern: "orders_?", Where: "tenant_id = 42"},
+	})
+	c.Assert(err, IsNil)
+	_, err = f.forTable(table)
+	c.Assert(err, ErrorMatches, ".*matched by 2 row filters.*")
+
+	// invalid conditions fail early
+	_, err = newRowFilters(false, []*config.RowFilterRule{{SchemaPattern: "db", Where: "id ="}})
+	c.Assert(err, NotNil)
+}
@@ -0,0 +1,153 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loader
+
+import (
+	"hash/fnv"
+	"io"
+	"strings"
+
+	"github.com/pingcap/errors"
+)
+
+// countRows returns the count of rows inserted by the INSERT statement, like 2 for `INSERT INTO t VALUES (1),(2);`.
+func countRows(query string) int64 {
+	var rows int64
+	forEachRow(query, func(string) { rows++ })
+	return rows
+}
+
+/* Checksum of rows
+ * the checksum of rows is the sum (modulo 2^64) of the 64-bit FNV-1a hashes of the rows,
+ * a row is hashed in the text of it in the data file, from `(` to `)` inclusive, like `(1,'a')`.
+ * so it's independent of the order of rows and statements, and a dump tool can compute the same checksum of the rows dumped.
+ * rows are hashed as they are dumped, before column mapping and table routing.
+ * the checksum of a data file is saved in checkpoint after all rows of it restored, it's accumulated from the rows restored before
+ * when resumed. it's not saved if the loader exits between the last statement executed and the checksum saved.
+ */
+
+// rowsChecksum returns the checksum of rows inserted by the INSERT statement
+func rowsChecksum(query string) uint64 {
+	var checksum uint64
+	h := fnv.New64a()
+	forEachRow(query, func(row string) {
+		h.Reset()
+		h.Write([]byte(row))
+		checksum += h.Sum64()
+	})
+	return checksum
+}
+
+// forEachRow calls fn with the text of every row inserted by the INSERT statement in order.
+// rows are the parenthesized values after `VALUES`, quoted strings and identifiers are skipped.
+func forEachRow(query string, fn func(row string)) {
+	var (
+		start  int
+		depth  int
+		quote  byte
+		values bool // whether `VALUES` is passed
+	)
+	for i := 0; i < len(query); i++ {
+		ch := query[i]
+		if quote != 0 {
+			if ch == '\\' && quote != '`' {
+				i++ // escaped character
+			} else if ch == quote {
+				quote = 0
+			}
+			continue
+		}
+
+		switch ch {
+		case '\'', '"', '`':
+			quote = ch
+		case '(':
+			if depth == 0 && values {
+				start = i
+			}
+			depth++
+		case ')':
+			depth--
+			if depth == 0 && values {
+				fn(query[start : i+1])
+			}
+		case 'V', 'v':
+			if depth == 0 && !values && isKeywordAt(query, i, "VALUES") {
+				values = true
+				i += len("VALUES") - 1
+			}
+		}
+	}
+}
+
+// isKeywordAt returns whether the keyword (case-insensitive) is at i of query and not a part of an identifier
+func isKeywordAt(query string, i int, keyword string) bool {
+	end := i + len(keyword)
+	if end > len(query) || !strings.EqualFold(query[i:end], keyword) {
+		return false
+	}
+	if i > 0 && isIdentifierChar(query[i-1]) {
+		return false
+	}
+	return end == len(query) || !isIdentifierChar(query[end])
+}
+
+func isIdentifierChar(ch byte) bool {
+	return ch == '_' || ch == '$' || ch >= '0' && ch <= '9' || ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch >= 0x80
+}
+
+// dataFileStats is the statistics of rows in a data file
+type dataFileStats struct {
+	size           int64  // (decompressed) size of the file
+	rows           int64  // count of rows in all INSERT statements
+	checksum       uint64 // checksum of rows in all INSERT statements
+	rowsBefore     int64  // count of rows in the statements ending before the offset scanned to
+	checksumBefore uint64 // checksum of rows in the statements ending before the offset scanned to
+}
+
+// scanDataFileRows reads statements of the data file in the same way as restoring it,
+// and returns the statistics of rows in the file and in the statements ending before offset.
+// the file is in archive if it's not nil.
+func scanDataFileRows(archive *dumpArchive, file string, offset int64) (*dataFileStats, error) {
+	r, err := openDataFile(archive, file)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	defer r.Close()
+
+	stats := &dataFileStats{}
+	sr := newStatementReader(r)
+	for {
+		data, err := sr.next()
+		stats.size += int64(len(data))
+		if err == io.EOF {
+			// the incomplete statement in the end is reported when restoring
+			return stats, nil
+		} else if err != nil {
+			return nil, errors.Annotatef(err, "read data file %s", file)
+		}
+
+		query := sr.statement()
+		if strings.HasPrefix(query, "/*") && strings.HasSuffix(query, "*/;") {
+			continue
+		}
+		rows, checksum := countRows(query), rowsChecksum(query)
+		stats.rows += rows
+		stats.checksum += checksum
+		if stats.size <= offset {
+			stats.rowsBefore += rows
+			stats.checksumBefore += checksum
+		}
+	}
+}
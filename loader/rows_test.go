@@ -0,0 +1,133 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loader
+
+import (
+	"io/ioutil"
+	"path/filepath"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb-tools/pkg/filter"
+
+	"github.com/pingcap/dm/dm/config"
+	"github.com/pingcap/dm/dm/pb"
+)
+
+var _ = Suite(&testRowsSuite{})
+
+type testRowsSuite struct{}
+
+func (t *testRowsSuite) TestCountRows(c *C) {
+	cases := []struct {
+		query string
+		rows  int64
+	}{
+		{"INSERT INTO `t` VALUES (1);", 1},
+		{"INSERT INTO `t` VALUES (1),(2),\n(3);", 3},
+		{"insert into `t` values(1,'a'),(2,'b');", 2},
+		{"INSERT INTO `t` (`id`,`name`) VALUES (1,'a'),(2,'b');", 2},
+		{"INSERT INTO `t` VALUES (1,'(a),(b)'),(2,'it\\'s (c)');", 2},
+		{"INSERT INTO `t` VALUES (1,\"))((\"),(2,'''(');", 2},
+		{"INSERT INTO `values` VALUES (1,`x`),(2,CONCAT('a','b'));", 2},
+		{"INSERT INTO `t_values` VALUES (1);", 1},
+		{"CREATE TABLE `t` (`id` INT);", 0},
+		{"/*!40101 SET NAMES binary*/;", 0},
+	}
+	for _, cs := range cases {
+		c.Assert(countRows(cs.query), Equals, cs.rows, Commentf("query %s", cs.query))
+	}
+}
+
+func (t *testRowsSuite) TestRowsChecksum(c *C) {
+	// FNV-1a 64 of `(1,'a')` and `(2,'b')`
+	var (
+		hash1 uint64 = 0xa959a2887299d432
+		hash2 uint64 = 0x5428a5588d54f7cc
+	)
+	c.Assert(rowsChecksum("INSERT INTO `t` VALUES (1,'a');"), Equals, hash1)
+	c.Assert(rowsChecksum("INSERT INTO `t` VALUES (1,'a'),(2,'b');"), Equals, hash1+hash2)
+	// independent of the order of rows
+	c.Assert(rowsChecksum("INSERT INTO `t` (`id`,`name`) VALUES (2,'b'),\n(1,'a');"), Equals, hash1+hash2)
+	c.Assert(rowsChecksum("INSERT INTO `t` VALUES (1,'a'),(1,'a');"), Equals, hash1+hash1)
+	c.Assert(rowsChecksum("CREATE TABLE `t` (`id` INT);"), Equals, uint64(0))
+}
+
+func (t *testRowsSuite) TestScanDataFileRows(c *C) {
+	dir := c.MkDir()
+	content := "/*!40101 SET NAMES binary*/;\nINSERT INTO `t1` VALUES\n(1),\n(2);\nINSERT INTO `t1` VALUES (3);\n"
+	path := filepath.Join(dir, "db.t1.sql")
+	c.Assert(ioutil.WriteFile(path, []byte(content), 0644), IsNil)
+	gzPath := filepath.Join(dir, "db.t2.sql.gz")
+	writeGzipFile(c, gzPath, []byte(content))
+
+	// offset after the first INSERT statement
+	offset := int64(len("/*!40101 SET NAMES binary*/;\nINSERT INTO `t1` VALUES\n(1),\n(2);\n"))
+	for _, file := range []string{path, gzPath} {
+		stats, err := scanDataFileRows(nil, file, offset)
+		c.Assert(err, IsNil)
+		c.Assert(stats.size, Equals, int64(len(content)))
+		c.Assert(stats.rows, Equals, int64(3))
+		c.Assert(stats.rowsBefore, Equals, int64(2))
+		c.Assert(stats.checksum, Equals, rowsChecksum("INSERT INTO `t1` VALUES (1),(2),(3);"))
+		c.Assert(stats.checksumBefore, Equals, rowsChecksum("INSERT INTO `t1` VALUES (1),(2);"))
+
+		// in the middle of a statement
+		stats, err = scanDataFileRows(nil, file, offset-1)
+		c.Assert(err, IsNil)
+		c.Assert(stats.rowsBefore, Equals, int64(0))
+		c.Assert(stats.checksumBefore, Equals, uint64(0))
+	}
+
+	_, err := scanDataFileRows(nil, filepath.Join(dir, "not-exist.sql"), 0)
+	c.Assert(err, NotNil)
+}
+
+func (t *testRowsSuite) TestFinishedRowsFromCheckpoint(c *C) {
+	dir := c.MkDir()
+	files := map[string]string{
+		"db-schema-create.sql": "CREATE DATABASE `db`;\n",
+		"db.t1-schema.sql":     "CREATE TABLE `t1` (`id` INT PRIMARY KEY);\n",
+		"db.t1.1.sql":          "INSERT INTO `t1` VALUES (1),(2);\n",
+		"db.t1.2.sql":          "INSERT INTO `t1` VALUES (3),(4),(5);\nINSERT INTO `t1` VALUES (6);\n",
+	}
+	for name, content := range files {
+		c.Assert(ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644), IsNil)
+	}
+
+	cfg := config.NewSubTaskConfig()
+	cfg.Name = "test-rows"
+	cfg.Dir = dir
+	l := NewLoader(cfg)
+	l.bwList = filter.New(false, nil)
+	c.Assert(l.prepare(), IsNil)
+
+	// db.t1.1.sql has been restored, and the first statement of db.t1.2.sql
+	size := func(name string) int64 { return int64(len(files[name])) }
+	cp, err := newFileCheckPoint(filepath.Join(dir, "checkpoint.json"), cfg.Name)
+	c.Assert(err, IsNil)
+	c.Assert(cp.Init("db.t1.1.sql", size("db.t1.1.sql")), IsNil)
+	c.Assert(cp.UpdateOffset("db.t1.1.sql", size("db.t1.1.sql")), IsNil)
+	c.Assert(cp.Init("db.t1.2.sql", size("db.t1.2.sql")), IsNil)
+	c.Assert(cp.UpdateOffset("db.t1.2.sql", int64(len("INSERT INTO `t1` VALUES (3),(4),(5);\n"))), IsNil)
+	c.Assert(cp.Load(), IsNil)
+	l.checkPoint = cp
+	l.loadFinishedSize()
+
+	status := l.Status().(*pb.LoadStatus)
+	c.Assert(status.TotalRows, Equals, int64(6))
+	c.Assert(status.FinishedRows, Equals, int64(5))
+	c.Assert(status.Tables, HasLen, 1)
+	c.Assert(status.Tables[0].TotalRows, Equals, int64(6))
+	c.Assert(status.Tables[0].FinishedRows, Equals, int64(5))
+}
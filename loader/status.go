@@ -14,9 +14,14 @@
 package loader
 
 import (
+	"math"
+	"sort"
 	"time"
 
 	"github.com/pingcap/dm/pkg/log"
+	"github.com/pingcap/errors"
+	"github.com/siddontang/go-mysql/mysql"
+	"github.com/siddontang/go/sync2"
 	"golang.org/x/net/context"
 
 	"github.com/pingcap/dm/dm/pb"
@@ -24,28 +29,186 @@ import (
 
 const (
 	printStatusInterval = time.Second * 5
+
+	// weight of the latest sample when smoothing the restoring rate
+	rateSmoothingFactor = 0.3
+
+	// max count of statuses waiting for the status callback, the stalest one is dropped if it's full
+	statusCallbackQueueSize = 2
 )
 
+// StatusCallback receives the status of the loader every time the status is printed,
+// like pushing the progress to an external orchestrator, see Loader.SetStatusCallback.
+type StatusCallback func(status *pb.LoadStatus)
+
+// statusNotifier calls the status callback in its own goroutine, so a slow callback never blocks the loader
+type statusNotifier struct {
+	callback StatusCallback
+	queue    chan *pb.LoadStatus
+}
+
+func newStatusNotifier(callback StatusCallback) *statusNotifier {
+	return &statusNotifier{
+		callback: callback,
+		queue:    make(chan *pb.LoadStatus, statusCallbackQueueSize),
+	}
+}
+
+// run calls the callback with queued statuses until the notifier is closed
+func (n *statusNotifier) run() {
+	for status := range n.queue {
+		n.callback(status)
+	}
+}
+
+// notify queues the status without blocking, the stalest status queued is dropped if the queue is full.
+// it must not be called concurrently, nor after the notifier is closed.
+func (n *statusNotifier) notify(status *pb.LoadStatus) {
+	for {
+		select {
+		case n.queue <- status:
+			return
+		default:
+		}
+		select {
+		case <-n.queue:
+		default:
+		}
+	}
+}
+
+// close stops run after the statuses queued are delivered
+func (n *statusNotifier) close() {
+	close(n.queue)
+}
+
 // Status implements SubTaskUnit.Status
 func (l *Loader) Status() interface{} {
 	finishedSize := l.finishedDataSize.Get()
 	totalSize := l.totalDataSize.Get()
 	progress := percent(finishedSize, totalSize)
 	s := &pb.LoadStatus{
-		FinishedBytes: finishedSize,
-		TotalBytes:    totalSize,
-		Progress:      progress,
-		MetaBinlog:    l.metaBinlog.Get(),
+		FinishedBytes:  finishedSize,
+		TotalBytes:     totalSize,
+		Progress:       progress,
+		Tables:         l.tableStatus(),
+		EtaSeconds:     l.etaSeconds.Get(),
+		MetaBinlogName: l.metaBinlogName.Get(),
+		MetaBinlogPos:  l.metaBinlogPos.Get(),
+		Validations:    l.validationStatus(),
+		FinishedRows:   l.finishedRows.Get(),
+		TotalRows:      l.totalRows.Get(),
+		Paused:         l.paused.Get(),
+		MetaBinlogGTID: l.metaBinlogGTID.Get(),
+	}
+	if s.MetaBinlogName != "" {
+		s.MetaBinlog = mysql.Position{Name: s.MetaBinlogName, Pos: s.MetaBinlogPos}.String()
 	}
 	return s
 }
 
+// tableProgress records the restoring progress of a source table
+type tableProgress struct {
+	totalSize  int64 // total size of data files
+	totalRows  int64 // count of rows in data files
+	totalFiles int   // count of data files
+
+	finishedSize  sync2.AtomicInt64
+	finishedRows  sync2.AtomicInt64
+	finishedFiles sync2.AtomicInt64
+}
+
+// getTableProgress returns the progress of the source table, nil if not found
+func (l *Loader) getTableProgress(db, table string) *tableProgress {
+	l.progressLock.RLock()
+	defer l.progressLock.RUnlock()
+	return l.tableProgresses[tableName(db, table)]
+}
+
+// getDecompressedSize returns the decompressed size of the compressed or archived data file
+func (l *Loader) getDecompressedSize(file string) (int64, error) {
+	l.progressLock.RLock()
+	size, ok := l.decompressedSizes[file]
+	l.progressLock.RUnlock()
+	if ok {
+		return size, nil
+	}
+	return getDataFileSize(l.archive, file)
+}
+
+// finishedFileRows returns the count of rows restored before the checkpoint pos of the data file
+func (l *Loader) finishedFileRows(file string, pos []int64) int64 {
+	if pos[0] == 0 {
+		return 0
+	}
+	if len(pos) == 2 && pos[0] == pos[1] {
+		l.progressLock.RLock()
+		rows, ok := l.dataFileRows[file]
+		l.progressLock.RUnlock()
+		if ok {
+			return rows
+		}
+	}
+	stats, err := scanDataFileRows(l.archive, file, pos[0])
+	if err != nil {
+		log.Warnf("[loader] count restored rows of %s error %v", file, err)
+		return 0
+	}
+	return stats.rowsBefore
+}
+
+// fileChecksumBefore returns the checksum of rows restored before offset of the data file
+func (l *Loader) fileChecksumBefore(file string, offset int64) (uint64, error) {
+	if offset == 0 {
+		return 0, nil
+	}
+	stats, err := scanDataFileRows(l.archive, file, offset)
+	if err != nil {
+		return 0, errors.Annotatef(err, "compute checksum of rows restored")
+	}
+	return stats.checksumBefore, nil
+}
+
+// finishJob records the progress of an executed data job
+func (l *Loader) finishJob(job *dataJob) {
+	size := job.offset - job.lastOffset
+	l.finishedDataSize.Add(size)
+	l.finishedRows.Add(job.rows)
+	if job.progress != nil {
+		job.progress.finishedSize.Add(size)
+		job.progress.finishedRows.Add(job.rows)
+		if job.offset == job.fileSize {
+			job.progress.finishedFiles.Add(1)
+		}
+	}
+}
+
+// tableStatus returns the restoring progress of source tables, ordered by table name
+func (l *Loader) tableStatus() []*pb.TableLoadStatus {
+	l.progressLock.RLock()
+	defer l.progressLock.RUnlock()
+
+	tables := make([]*pb.TableLoadStatus, 0, len(l.tableProgresses))
+	for name, progress := range l.tableProgresses {
+		tables = append(tables, &pb.TableLoadStatus{
+			Table:          name,
+			FinishedBytes:  progress.finishedSize.Get(),
+			TotalBytes:     progress.totalSize,
+			RemainingFiles: int32(int64(progress.totalFiles) - progress.finishedFiles.Get()),
+			FinishedRows:   progress.finishedRows.Get(),
+			TotalRows:      progress.totalRows,
+		})
+	}
+	sort.Slice(tables, func(i, j int) bool { return tables[i].Table < tables[j].Table })
+	return tables
+}
+
 // Error implements SubTaskUnit.Error
 func (l *Loader) Error() interface{} {
 	return &pb.LoadError{}
 }
 
-// PrintStatus prints status like progress percentage.
+// PrintStatus prints status like progress percentage, and passes the status to the status callback if set.
 func (l *Loader) PrintStatus(ctx context.Context) {
 	ticker := time.NewTicker(printStatusInterval)
 	defer ticker.Stop()
@@ -53,6 +216,14 @@ func (l *Loader) PrintStatus(ctx context.Context) {
 	newCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
+	var notifier *statusNotifier
+	if l.statusCallback != nil {
+		notifier = newStatusNotifier(l.statusCallback)
+		go notifier.run()
+		defer notifier.close()
+	}
+
+	estimator := newRateEstimator(rateSmoothingFactor)
 	var done bool
 	for {
 		select {
@@ -63,10 +234,71 @@ func (l *Loader) PrintStatus(ctx context.Context) {
 
 		finishedSize := l.finishedDataSize.Get()
 		totalSize := l.totalDataSize.Get()
-		log.Infof("[loader] finished_bytes = %d, total_bytes = GetAllRestoringFiles%d, progress = %s", finishedSize, totalSize, percent(finishedSize, totalSize))
-		progressGauge.WithLabelValues(l.cfg.Name).Set(float64(finishedSize) / float64(totalSize))
+		estimator.update(finishedSize, time.Now())
+		eta := estimator.eta(finishedSize, totalSize)
+		l.etaSeconds.Set(eta)
+		l.logger.Infof("[loader] finished_bytes = %d, total_bytes = %d, finished_rows = %d, total_rows = %d, progress = %s, eta = %ds",
+			finishedSize, totalSize, l.finishedRows.Get(), l.totalRows.Get(), percent(finishedSize, totalSize), eta)
+		progressGauge.WithLabelValues(l.cfg.Name).Set(ratio(finishedSize, totalSize))
+		if notifier != nil {
+			notifier.notify(l.Status().(*pb.LoadStatus))
+		}
 		if done {
 			return
 		}
 	}
 }
+
+// rateEstimator estimates the restoring rate (bytes per second) from progress samples,
+// the rate is smoothed by an exponential moving average to absorb short-term spikes.
+type rateEstimator struct {
+	alpha float64 // weight of the latest sample, in (0, 1]
+	rate  float64
+
+	sampled  bool
+	lastSize int64
+	lastTime time.Time
+}
+
+func newRateEstimator(alpha float64) *rateEstimator {
+	return &rateEstimator{alpha: alpha}
+}
+
+// update feeds a sample of finished size at time now
+func (e *rateEstimator) update(finishedSize int64, now time.Time) {
+	if !e.sampled {
+		e.sampled = true
+		e.lastSize, e.lastTime = finishedSize, now
+		return
+	}
+
+	elapsed := now.Sub(e.lastTime).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+	rate := float64(finishedSize-e.lastSize) / elapsed
+	if rate < 0 {
+		// finished size is reset, like resuming from the checkpoint
+		rate = 0
+	}
+	if e.rate == 0 {
+		e.rate = rate
+	} else {
+		e.rate = e.alpha*rate + (1-e.alpha)*e.rate
+	}
+	e.lastSize, e.lastTime = finishedSize, now
+}
+
+// eta returns the estimated remaining seconds, -1 if it can not be estimated yet
+func (e *rateEstimator) eta(finishedSize, totalSize int64) int64 {
+	if totalSize <= 0 {
+		return -1
+	}
+	if finishedSize >= totalSize {
+		return 0
+	}
+	if e.rate <= 0 {
+		return -1
+	}
+	return int64(math.Ceil(float64(totalSize-finishedSize) / e.rate))
+}
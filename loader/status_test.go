@@ -0,0 +1,219 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loader
+
+import (
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/dm/dm/config"
+	"github.com/pingcap/dm/dm/pb"
+	"github.com/pingcap/tidb-tools/pkg/filter"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"golang.org/x/net/context"
+)
+
+var _ = Suite(&testStatusSuite{})
+
+type testStatusSuite struct{}
+
+func (t *testStatusSuite) TestTableStatus(c *C) {
+	dir := c.MkDir()
+	files := map[string]string{
+		"db-schema-create.sql": "CREATE DATABASE `db`;",
+		"db.t1-schema.sql":     "CREATE TABLE `t1` (`id` INT PRIMARY KEY);",
+		"db.t1.1.sql":          "INSERT INTO `t1` VALUES (1),(2);",
+		"db.t1.2.sql":          "INSERT INTO `t1` VALUES (3);",
+		"db.t2-schema.sql":     "CREATE TABLE `t2` (`id` INT PRIMARY KEY);",
+		"db.t2.sql":            "INSERT INTO `t2` VALUES (1),(2),(3),(4);",
+	}
+	for name, content := range files {
+		c.Assert(ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644), IsNil)
+	}
+
+	cfg := config.NewSubTaskConfig()
+	cfg.Dir = dir
+	l := NewLoader(cfg)
+	l.bwList = filter.New(false, nil)
+	c.Assert(l.prepare(), IsNil)
+
+	size := func(name string) int64 { return int64(len(files[name])) }
+	tables := l.Status().(*pb.LoadStatus).Tables
+	c.Assert(tables, HasLen, 2)
+	c.Assert(tables[0], DeepEquals, &pb.TableLoadStatus{
+		Table:          "`db`.`t1`",
+		TotalBytes:     size("db.t1.1.sql") + size("db.t1.2.sql"),
+		RemainingFiles: 2,
+		TotalRows:      3,
+	})
+	c.Assert(tables[1], DeepEquals, &pb.TableLoadStatus{
+		Table:          "`db`.`t2`",
+		TotalBytes:     size("db.t2.sql"),
+		RemainingFiles: 1,
+		TotalRows:      4,
+	})
+
+	// finish db.t1.1.sql and part of db.t2.sql
+	t1 := l.getTableProgress("db", "t1")
+	l.finishJob(&dataJob{offset: size("db.t1.1.sql"), fileSize: size("db.t1.1.sql"), rows: 2, progress: t1})
+	t2 := l.getTableProgress("db", "t2")
+	l.finishJob(&dataJob{offset: 10, fileSize: size("db.t2.sql"), progress: t2})
+
+	status := l.Status().(*pb.LoadStatus)
+	c.Assert(status.Tables[0].FinishedBytes, Equals, size("db.t1.1.sql"))
+	c.Assert(status.Tables[0].RemainingFiles, Equals, int32(1))
+	c.Assert(status.Tables[1].FinishedBytes, Equals, int64(10))
+	c.Assert(status.Tables[1].RemainingFiles, Equals, int32(1))
+	c.Assert(status.Tables[0].FinishedRows, Equals, int64(2))
+	c.Assert(status.Tables[1].FinishedRows, Equals, int64(0))
+
+	// per-table progress sums to the aggregated progress
+	var finished, total, finishedRows, totalRows int64
+	for _, table := range status.Tables {
+		finished += table.FinishedBytes
+		total += table.TotalBytes
+		finishedRows += table.FinishedRows
+		totalRows += table.TotalRows
+	}
+	c.Assert(finished, Equals, status.FinishedBytes)
+	c.Assert(total, Equals, status.TotalBytes)
+	c.Assert(finishedRows, Equals, status.FinishedRows)
+	c.Assert(totalRows, Equals, status.TotalRows)
+}
+
+func (t *testStatusSuite) TestRateEstimator(c *C) {
+	e := newRateEstimator(rateSmoothingFactor)
+	c.Assert(e.eta(0, 0), Equals, int64(-1))
+	c.Assert(e.eta(0, 1000), Equals, int64(-1))
+
+	var (
+		total    int64 = 100000
+		finished int64
+		now            = time.Now()
+		lastETA  int64 = math.MaxInt64
+	)
+	e.update(finished, now)
+	c.Assert(e.eta(finished, total), Equals, int64(-1)) // no rate yet
+
+	// about 1000 bytes per second with spikes
+	for _, delta := range []int64{5000, 5500, 4500, 6000, 4800, 5000, 5300, 4700} {
+		finished += delta
+		now = now.Add(printStatusInterval)
+		e.update(finished, now)
+		eta := e.eta(finished, total)
+		c.Assert(eta, Greater, int64(0))
+		c.Assert(eta, Less, lastETA)
+		lastETA = eta
+	}
+
+	c.Assert(e.eta(total, total), Equals, int64(0))
+	c.Assert(e.eta(total, 0), Equals, int64(-1))
+}
+
+func (t *testStatusSuite) TestPrintStatusZeroTotal(c *C) {
+	cfg := config.NewSubTaskConfig()
+	cfg.Name = "test-print-status-zero-total"
+	l := NewLoader(cfg)
+	c.Assert(l.totalDataSize.Get(), Equals, int64(0))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	l.PrintStatus(ctx) // returns after printing once
+
+	progress := testutil.ToFloat64(progressGauge.WithLabelValues(cfg.Name))
+	c.Assert(math.IsNaN(progress), IsFalse)
+	c.Assert(progress, Equals, float64(0))
+	c.Assert(l.Status().(*pb.LoadStatus).Progress, Equals, "0.00 %")
+}
+
+func (t *testStatusSuite) TestStatusNotifier(c *C) {
+	var (
+		received []int64
+		block    = make(chan struct{})
+		done     = make(chan struct{})
+	)
+	n := newStatusNotifier(func(status *pb.LoadStatus) {
+		<-block
+		received = append(received, status.FinishedBytes)
+	})
+	go func() {
+		n.run()
+		close(done)
+	}()
+
+	// never blocked by the slow callback, and stale statuses are dropped
+	for i := int64(1); i <= 10; i++ {
+		n.notify(&pb.LoadStatus{FinishedBytes: i})
+	}
+	n.close()
+	close(block)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		c.Fatal("statuses queued are not delivered")
+	}
+	c.Assert(len(received) <= statusCallbackQueueSize+1, IsTrue, Commentf("received %v", received))
+	c.Assert(received[len(received)-1], Equals, int64(10))
+}
+
+func (t *testStatusSuite) TestStatusCallback(c *C) {
+	dir := c.MkDir()
+	files := map[string]string{
+		"db-schema-create.sql": "CREATE DATABASE `db`;\n",
+		"db.t1-schema.sql":     "CREATE TABLE `t1` (`id` INT PRIMARY KEY);\n",
+		"db.t1.sql":            "INSERT INTO `t1` VALUES (1),(2);\nINSERT INTO `t1` VALUES (3);\n",
+		"metadata":             "SHOW MASTER STATUS:\n\tLog: mysql-bin.000001\n\tPos: 154\n",
+	}
+	for name, content := range files {
+		c.Assert(ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644), IsNil)
+	}
+
+	cfg := config.NewSubTaskConfig()
+	cfg.Name = "test-status-callback"
+	cfg.Dir = dir
+	cfg.PoolSize = 1
+	cfg.DryRun = true
+	cfg.DryRunFile = filepath.Join(c.MkDir(), "dry-run.sql")
+	cfg.To = config.DBConfig{Host: "127.0.0.1", Port: 1, User: "root"}
+
+	l := NewLoader(cfg)
+	statuses := make(chan *pb.LoadStatus, 10)
+	l.SetStatusCallback(func(status *pb.LoadStatus) {
+		statuses <- status
+	})
+	c.Assert(l.Init(), IsNil)
+	pr := make(chan pb.ProcessResult, 1)
+	l.Process(context.Background(), pr)
+	c.Assert((<-pr).Errors, HasLen, 0)
+	l.Close()
+
+	// printed once at least when the restoring finished
+	var status *pb.LoadStatus
+	select {
+	case status = <-statuses:
+	case <-time.After(time.Second):
+		c.Fatal("status callback not called")
+	}
+	for len(statuses) > 0 {
+		status = <-statuses
+	}
+	c.Assert(status.FinishedBytes, Equals, status.TotalBytes)
+	c.Assert(status.Progress, Equals, "100.00 %")
+	c.Assert(status.EtaSeconds, Equals, int64(0))
+	c.Assert(status.Tables, HasLen, 1)
+	c.Assert(status.Tables[0].FinishedRows, Equals, int64(3))
+}
@@ -0,0 +1,116 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loader
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/pingcap/dm/pkg/log"
+	"github.com/pingcap/errors"
+	"golang.org/x/net/context"
+)
+
+// interval to sample the load of the downstream database
+var throttleInterval = time.Second
+
+const (
+	// the rate is increased only after the load falls below this ratio of the threshold,
+	// so it doesn't oscillate when the load is around the threshold
+	throttleRecoverRatio = 0.8
+	// the rate is increased by this ratio of the max rate every interval, and paused if it's halved below it
+	throttleStep = 1.0 / 8
+)
+
+// loadSignal samples the load of the downstream database, like Threads_running
+type loadSignal func(ctx context.Context) (float64, error)
+
+// threadsRunning returns a loadSignal sampling Threads_running of db
+func threadsRunning(db *sql.DB) loadSignal {
+	return func(ctx context.Context) (float64, error) {
+		var (
+			name  string
+			value float64
+		)
+		err := db.QueryRowContext(ctx, "SHOW GLOBAL STATUS LIKE 'Threads_running'").Scan(&name, &value)
+		return value, errors.Trace(err)
+	}
+}
+
+// throttle scales the rate of limiter by the load of the downstream database, like the congestion control of TCP:
+// the rate is halved every interval while the load is not below threshold, and paused if it's below throttleStep of maxRate,
+// it's increased by throttleStep of maxRate every interval while the load is below throttleRecoverRatio of threshold,
+// and kept while the load is between them.
+type throttle struct {
+	limiter   *rateLimiter
+	signal    loadSignal
+	threshold float64
+	maxRate   float64 // the rate-limit
+}
+
+func newThrottle(limiter *rateLimiter, signal loadSignal, threshold float64) *throttle {
+	return &throttle{
+		limiter:   limiter,
+		signal:    signal,
+		threshold: threshold,
+		maxRate:   limiter.currentRate(),
+	}
+}
+
+// adjust samples the load once and scales the rate by it, the rate is kept if failed to sample.
+func (t *throttle) adjust(ctx context.Context) error {
+	load, err := t.signal(ctx)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	rate := t.limiter.currentRate()
+	newRate := rate
+	switch {
+	case load >= t.threshold:
+		newRate = rate / 2
+		if newRate < t.maxRate*throttleStep {
+			newRate = 0
+		}
+	case load < t.threshold*throttleRecoverRatio:
+		newRate = rate + t.maxRate*throttleStep
+		if newRate > t.maxRate {
+			newRate = t.maxRate
+		}
+	}
+
+	if newRate != rate {
+		log.Infof("[loader] downstream load %v (threshold %v), restoring rate changed from %.0f to %.0f bytes/s", load, t.threshold, rate, newRate)
+		t.limiter.setRate(newRate)
+	}
+	return nil
+}
+
+// run adjusts the rate every throttleInterval until ctx is done, and restores the max rate before returning
+func (t *throttle) run(ctx context.Context) {
+	defer t.limiter.setRate(t.maxRate)
+
+	ticker := time.NewTicker(throttleInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := t.adjust(ctx); err != nil {
+			log.Warnf("[loader] sample downstream load error %v, restoring rate kept", errors.ErrorStack(err))
+		}
+	}
+}
@@ -71,5 +71,13 @@ func shortSha1(s string) string {
 
 // percent calculates percentage of a/b.
 func percent(a int64, b int64) string {
-	return fmt.Sprintf("%.2f %%", float64(a)/float64(b)*100)
+	return fmt.Sprintf("%.2f %%", ratio(a, b)*100)
+}
+
+// ratio returns a/b, 0 if b is 0
+func ratio(a int64, b int64) float64 {
+	if b == 0 {
+		return 0
+	}
+	return float64(a) / float64(b)
 }
@@ -61,3 +61,9 @@ func (t *testUtilSuite) TestSQLReplace(c *C) {
 func (t *testUtilSuite) TestShortSha1(c *C) {
 	c.Assert(shortSha1("/tmp/test_sha1_short_6"), Equals, "97b645")
 }
+
+func (t *testUtilSuite) TestPercent(c *C) {
+	c.Assert(percent(1, 4), Equals, "25.00 %")
+	c.Assert(percent(0, 0), Equals, "0.00 %")
+	c.Assert(percent(10, 0), Equals, "0.00 %")
+}
@@ -0,0 +1,264 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loader
+
+import (
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pingcap/dm/dm/config"
+	"github.com/pingcap/dm/dm/pb"
+	"github.com/pingcap/dm/pkg/log"
+	"github.com/pingcap/errors"
+	"golang.org/x/net/context"
+)
+
+// errValidationMismatch is the cause of the error returned if some tables mismatched in validation
+var errValidationMismatch = errors.New("tables of source and target mismatched after restored")
+
+// tableValidator queries a table in source or target to compare them
+type tableValidator interface {
+	// count returns the row count of the table
+	count(ctx context.Context, schema, table string) (uint64, error)
+	// checksum returns CRC32 of at most limit rows of the columns, rows are ordered by the columns
+	checksum(ctx context.Context, schema, table string, columns []string, limit int) (uint64, error)
+	// primaryKey returns the columns of the primary key, empty if the table has no primary key
+	primaryKey(ctx context.Context, schema, table string) ([]string, error)
+	close() error
+}
+
+type dbValidator struct {
+	db *sql.DB
+}
+
+func newDBValidator(cfg config.DBConfig) (*dbValidator, error) {
+	dbDSN := fmt.Sprintf("%s:%s@tcp(%s:%d)/?charset=utf8", cfg.User, cfg.Password, cfg.Host, cfg.Port)
+	db, err := sql.Open("mysql", dbDSN)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &dbValidator{db: db}, nil
+}
+
+func (v *dbValidator) count(ctx context.Context, schema, table string) (uint64, error) {
+	var count uint64
+	query := fmt.Sprintf("SELECT COUNT(*) FROM `%s`.`%s`", schema, table)
+	err := v.db.QueryRowContext(ctx, query).Scan(&count)
+	return count, errors.Annotatef(err, "query %s", query)
+}
+
+func (v *dbValidator) checksum(ctx context.Context, schema, table string, columns []string, limit int) (uint64, error) {
+	names := make([]string, 0, len(columns))
+	for _, col := range columns {
+		names = append(names, fmt.Sprintf("`%s`", col))
+	}
+	query := fmt.Sprintf("SELECT %s FROM `%s`.`%s` ORDER BY %s LIMIT %d",
+		strings.Join(names, ","), schema, table, strings.Join(names, ","), limit)
+	rows, err := v.db.QueryContext(ctx, query)
+	if err != nil {
+		return 0, errors.Annotatef(err, "query %s", query)
+	}
+	defer rows.Close()
+
+	var (
+		crc    uint32
+		values = make([]sql.RawBytes, len(columns))
+		dest   = make([]interface{}, len(columns))
+	)
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	for rows.Next() {
+		if err = rows.Scan(dest...); err != nil {
+			return 0, errors.Trace(err)
+		}
+		crc = checksumRow(crc, values)
+	}
+	return uint64(crc), errors.Trace(rows.Err())
+}
+
+// checksumRow updates crc with the length-prefixed values of a row, nil values are distinguished from empty values
+func checksumRow(crc uint32, values []sql.RawBytes) uint32 {
+	var prefix [binary.MaxVarintLen64 + 1]byte
+	for _, value := range values {
+		if value == nil {
+			prefix[0] = 0
+			crc = crc32.Update(crc, crc32.IEEETable, prefix[:1])
+			continue
+		}
+		prefix[0] = 1
+		n := binary.PutUvarint(prefix[1:], uint64(len(value)))
+		crc = crc32.Update(crc, crc32.IEEETable, prefix[:n+1])
+		crc = crc32.Update(crc, crc32.IEEETable, value)
+	}
+	return crc
+}
+
+func (v *dbValidator) primaryKey(ctx context.Context, schema, table string) ([]string, error) {
+	query := "SELECT `COLUMN_NAME` FROM `information_schema`.`KEY_COLUMN_USAGE` WHERE `TABLE_SCHEMA` = ? AND `TABLE_NAME` = ? AND `CONSTRAINT_NAME` = 'PRIMARY' ORDER BY `ORDINAL_POSITION`"
+	rows, err := v.db.QueryContext(ctx, query, schema, table)
+	if err != nil {
+		return nil, errors.Annotatef(err, "query primary key of `%s`.`%s`", schema, table)
+	}
+	defer rows.Close()
+
+	var columns []string
+	for rows.Next() {
+		var column string
+		if err = rows.Scan(&column); err != nil {
+			return nil, errors.Trace(err)
+		}
+		columns = append(columns, column)
+	}
+	return columns, errors.Trace(rows.Err())
+}
+
+func (v *dbValidator) close() error {
+	return errors.Trace(v.db.Close())
+}
+
+// validate compares tables between source and target after all data restored
+func (l *Loader) validate(ctx context.Context) error {
+	src, err := newDBValidator(l.cfg.From)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer src.close()
+	dst, err := newDBValidator(l.cfg.To)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer dst.close()
+
+	return errors.Trace(l.validateTables(ctx, src, dst))
+}
+
+// validateTables compares every target table with its source tables, results are recorded in Status.
+// source tables merged into a same target table are compared by the sum of their row counts.
+// tables without primary key are compared by row counts in checksum mode.
+func (l *Loader) validateTables(ctx context.Context, src, dst tableValidator) error {
+	begin := time.Now()
+	l.validationLock.Lock()
+	l.validations = nil
+	l.validationLock.Unlock()
+
+	// target table -> source tables
+	targets := make(map[string][]*tableInfo)
+	for db, tables := range l.db2Tables {
+		for table := range tables {
+			info, ok := l.tableInfos[tableName(db, table)]
+			if !ok {
+				return errors.NotFoundf("table info of %s", tableName(db, table))
+			}
+			target := tableName(info.targetSchema, info.targetTable)
+			targets[target] = append(targets[target], info)
+		}
+	}
+	names := make([]string, 0, len(targets))
+	for target, infos := range targets {
+		sort.Slice(infos, func(i, j int) bool {
+			return tableName(infos[i].sourceSchema, infos[i].sourceTable) < tableName(infos[j].sourceSchema, infos[j].sourceTable)
+		})
+		names = append(names, target)
+	}
+	sort.Strings(names)
+
+	var mismatched []string
+	for _, target := range names {
+		result, err := l.validateTable(ctx, src, dst, targets[target])
+		if err != nil {
+			return errors.Annotatef(err, "validate table %s", target)
+		}
+		if !result.Matched {
+			log.Warnf("[loader] validation of %s -> %s mismatched: source %d, target %d by %s", result.Table, result.TargetTable, result.SourceValue, result.TargetValue, result.Mode)
+			mismatched = append(mismatched, target)
+		}
+		l.validationLock.Lock()
+		l.validations = append(l.validations, result)
+		l.validationLock.Unlock()
+	}
+
+	log.Infof("[loader] validate %d tables takes %f seconds", len(names), time.Since(begin).Seconds())
+	if len(mismatched) > 0 {
+		return errors.Annotatef(errValidationMismatch, "%s", strings.Join(mismatched, ", "))
+	}
+	return nil
+}
+
+// validateTable compares a target table with its source tables
+func (l *Loader) validateTable(ctx context.Context, src, dst tableValidator, sources []*tableInfo) (*pb.TableValidation, error) {
+	var (
+		target  = sources[0]
+		mode    = l.cfg.Validation
+		columns []string
+		err     error
+		result  = &pb.TableValidation{TargetTable: tableName(target.targetSchema, target.targetTable)}
+	)
+	names := make([]string, 0, len(sources))
+	for _, info := range sources {
+		names = append(names, tableName(info.sourceSchema, info.sourceTable))
+	}
+	result.Table = strings.Join(names, ",")
+
+	if mode == config.ValidationChecksum && len(sources) == 1 {
+		columns, err = src.primaryKey(ctx, target.sourceSchema, target.sourceTable)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	if len(columns) == 0 {
+		mode = config.ValidationCount
+	}
+	result.Mode = mode
+
+	if mode == config.ValidationChecksum {
+		limit := l.cfg.ValidationSampleSize
+		if result.SourceValue, err = src.checksum(ctx, target.sourceSchema, target.sourceTable, columns, limit); err != nil {
+			return nil, errors.Trace(err)
+		}
+		if result.TargetValue, err = dst.checksum(ctx, target.targetSchema, target.targetTable, columns, limit); err != nil {
+			return nil, errors.Trace(err)
+		}
+	} else {
+		for _, info := range sources {
+			count, err2 := src.count(ctx, info.sourceSchema, info.sourceTable)
+			if err2 != nil {
+				return nil, errors.Trace(err2)
+			}
+			result.SourceValue += count
+		}
+		if result.TargetValue, err = dst.count(ctx, target.targetSchema, target.targetTable); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	result.Matched = result.SourceValue == result.TargetValue
+	return result, nil
+}
+
+// validationStatus returns the results of validation
+func (l *Loader) validationStatus() []*pb.TableValidation {
+	l.validationLock.RLock()
+	defer l.validationLock.RUnlock()
+	if len(l.validations) == 0 {
+		return nil
+	}
+	results := make([]*pb.TableValidation, len(l.validations))
+	copy(results, l.validations)
+	return results
+}
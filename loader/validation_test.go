@@ -0,0 +1,144 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loader
+
+import (
+	"database/sql"
+	"hash/crc32"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/dm/dm/config"
+	"github.com/pingcap/dm/dm/pb"
+	"github.com/pingcap/errors"
+	"golang.org/x/net/context"
+)
+
+var _ = Suite(&testValidationSuite{})
+
+type testValidationSuite struct{}
+
+// fakeValidator answers queries of tables (`db`.`table`) from memory
+type fakeValidator struct {
+	counts      map[string]uint64
+	checksums   map[string]uint64
+	primaryKeys map[string][]string
+}
+
+func (v *fakeValidator) count(ctx context.Context, schema, table string) (uint64, error) {
+	count, ok := v.counts[tableName(schema, table)]
+	if !ok {
+		return 0, errors.NotFoundf("table %s", tableName(schema, table))
+	}
+	return count, nil
+}
+
+func (v *fakeValidator) checksum(ctx context.Context, schema, table string, columns []string, limit int) (uint64, error) {
+	return v.checksums[tableName(schema, table)], nil
+}
+
+func (v *fakeValidator) primaryKey(ctx context.Context, schema, table string) ([]string, error) {
+	return v.primaryKeys[tableName(schema, table)], nil
+}
+
+func (v *fakeValidator) close() error { return nil }
+
+func (t *testValidationSuite) newLoader(mode string) *Loader {
+	cfg := config.NewSubTaskConfig()
+	cfg.Validation = mode
+	cfg.ValidationSampleSize = 100
+	l := NewLoader(cfg)
+	l.db2Tables = map[string]Tables2DataFiles{
+		"db":  {"t1": nil, "t2": nil},
+		"shd": {"s1": nil, "s2": nil},
+	}
+	l.tableInfos = map[string]*tableInfo{
+		"`db`.`t1`":  {sourceSchema: "db", sourceTable: "t1", targetSchema: "db", targetTable: "t1"},
+		"`db`.`t2`":  {sourceSchema: "db", sourceTable: "t2", targetSchema: "db", targetTable: "t2"},
+		"`shd`.`s1`": {sourceSchema: "shd", sourceTable: "s1", targetSchema: "shd", targetTable: "s"},
+		"`shd`.`s2`": {sourceSchema: "shd", sourceTable: "s2", targetSchema: "shd", targetTable: "s"},
+	}
+	return l
+}
+
+func (t *testValidationSuite) TestValidateCount(c *C) {
+	src := &fakeValidator{counts: map[string]uint64{"`db`.`t1`": 10, "`db`.`t2`": 20, "`shd`.`s1`": 3, "`shd`.`s2`": 4}}
+	dst := &fakeValidator{counts: map[string]uint64{"`db`.`t1`": 10, "`db`.`t2`": 20, "`shd`.`s`": 7}}
+	l := t.newLoader(config.ValidationCount)
+	c.Assert(l.validateTables(context.Background(), src, dst), IsNil)
+	c.Assert(l.Status().(*pb.LoadStatus).Validations, DeepEquals, []*pb.TableValidation{
+		{Table: "`db`.`t1`", TargetTable: "`db`.`t1`", Mode: "count", SourceValue: 10, TargetValue: 10, Matched: true},
+		{Table: "`db`.`t2`", TargetTable: "`db`.`t2`", Mode: "count", SourceValue: 20, TargetValue: 20, Matched: true},
+		{Table: "`shd`.`s1`,`shd`.`s2`", TargetTable: "`shd`.`s`", Mode: "count", SourceValue: 7, TargetValue: 7, Matched: true},
+	})
+
+	// seeded mismatch
+	dst.counts["`db`.`t2`"] = 19
+	err := l.validateTables(context.Background(), src, dst)
+	c.Assert(errors.Cause(err), Equals, errValidationMismatch)
+	c.Assert(err, ErrorMatches, "`db`.`t2`: tables of source and target mismatched after restored")
+	s := l.Status().(*pb.LoadStatus)
+	c.Assert(s.Validations, HasLen, 3)
+	c.Assert(s.Validations[1], DeepEquals, &pb.TableValidation{Table: "`db`.`t2`", TargetTable: "`db`.`t2`", Mode: "count", SourceValue: 20, TargetValue: 19})
+
+	// results are encoded in status
+	data, err := s.Marshal()
+	c.Assert(err, IsNil)
+	s2 := &pb.LoadStatus{}
+	c.Assert(s2.Unmarshal(data), IsNil)
+	c.Assert(s2.Validations, DeepEquals, s.Validations)
+
+	// failed to query
+	delete(dst.counts, "`db`.`t1`")
+	c.Assert(l.validateTables(context.Background(), src, dst), ErrorMatches, "validate table `db`.`t1`: table `db`.`t1` not found")
+}
+
+func (t *testValidationSuite) TestValidateChecksum(c *C) {
+	src := &fakeValidator{
+		counts:      map[string]uint64{"`db`.`t1`": 10, "`db`.`t2`": 20, "`shd`.`s1`": 3, "`shd`.`s2`": 4},
+		checksums:   map[string]uint64{"`db`.`t1`": 111, "`db`.`t2`": 222},
+		primaryKeys: map[string][]string{"`db`.`t1`": {"id"}, "`shd`.`s1`": {"id"}},
+	}
+	dst := &fakeValidator{
+		counts:    map[string]uint64{"`db`.`t1`": 10, "`db`.`t2`": 20, "`shd`.`s`": 7},
+		checksums: map[string]uint64{"`db`.`t1`": 112},
+	}
+	l := t.newLoader(config.ValidationChecksum)
+	err := l.validateTables(context.Background(), src, dst)
+	c.Assert(errors.Cause(err), Equals, errValidationMismatch)
+	c.Assert(err, ErrorMatches, "`db`.`t1`: .*")
+	// tables without primary key and merged tables are compared by row counts
+	c.Assert(l.Status().(*pb.LoadStatus).Validations, DeepEquals, []*pb.TableValidation{
+		{Table: "`db`.`t1`", TargetTable: "`db`.`t1`", Mode: "checksum", SourceValue: 111, TargetValue: 112},
+		{Table: "`db`.`t2`", TargetTable: "`db`.`t2`", Mode: "count", SourceValue: 20, TargetValue: 20, Matched: true},
+		{Table: "`shd`.`s1`,`shd`.`s2`", TargetTable: "`shd`.`s`", Mode: "count", SourceValue: 7, TargetValue: 7, Matched: true},
+	})
+}
+
+func (t *testValidationSuite) TestChecksumRow(c *C) {
+	rows := [][]sql.RawBytes{
+		{sql.RawBytes("1"), nil},
+		{sql.RawBytes("1"), sql.RawBytes("")},
+		{sql.RawBytes("1"), sql.RawBytes("null")},
+		{sql.RawBytes("12"), sql.RawBytes("3")},
+		{sql.RawBytes("1"), sql.RawBytes("23")},
+	}
+	seen := make(map[uint32]int)
+	for i, row := range rows {
+		crc := checksumRow(0, row)
+		j, ok := seen[crc]
+		c.Assert(ok, IsFalse, Commentf("row %d and %d have a same checksum", i, j))
+		seen[crc] = i
+	}
+	c.Assert(checksumRow(0, rows[0]), Equals, crc32.Update(0, crc32.IEEETable, []byte{1, 1, '1', 0}))
+}
@@ -55,6 +55,39 @@ func SetOutputByName(filename string) {
 	log.SetOutput(output)
 }
 
+// Logger is the leveled logging used by dm units, so a unit can be given a logger with task scoped fields,
+// or a logger capturing entries in tests. GlobalLogger returns the default one.
+type Logger interface {
+	Debugf(format string, v ...interface{})
+	Infof(format string, v ...interface{})
+	Warnf(format string, v ...interface{})
+	Errorf(format string, v ...interface{})
+}
+
+// globalLogger is the Logger writing to the wrapped logger like the package-level functions
+type globalLogger struct{}
+
+// GlobalLogger returns the Logger writing to the wrapped logger
+func GlobalLogger() Logger {
+	return globalLogger{}
+}
+
+func (globalLogger) Debugf(format string, v ...interface{}) {
+	log.Debugf(format, v...)
+}
+
+func (globalLogger) Infof(format string, v ...interface{}) {
+	log.Infof(format, v...)
+}
+
+func (globalLogger) Warnf(format string, v ...interface{}) {
+	log.Warnf(format, v...)
+}
+
+func (globalLogger) Errorf(format string, v ...interface{}) {
+	log.Errorf(format, v...)
+}
+
 // Info logs a message at level Info on the wrapped logger.
 func Info(v ...interface{}) {
 	log.Info(v...)
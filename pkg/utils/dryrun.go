@@ -0,0 +1,96 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/pingcap/dm/pkg/log"
+	"github.com/pingcap/errors"
+)
+
+// SQLWriter writes sqls into a file or the log rather than executing them, it's used in dry-run mode.
+// sqls executed in a transaction are written together and wrapped by BEGIN and COMMIT.
+type SQLWriter struct {
+	sync.Mutex
+	f     *os.File // nil means writing into the log
+	count int
+}
+
+// NewSQLWriter creates a SQLWriter appending sqls to the file, sqls are written into the log if path is empty
+func NewSQLWriter(path string) (*SQLWriter, error) {
+	w := &SQLWriter{}
+	if path == "" {
+		return w, nil
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, errors.Annotatef(err, "open dry-run file %s", path)
+	}
+	w.f = f
+	return w, nil
+}
+
+// Write writes sqls of a transaction, args can be nil if sqls have no arguments
+func (w *SQLWriter) Write(sqls []string, args [][]interface{}) error {
+	if len(sqls) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("BEGIN;\n")
+	for i, sql := range sqls {
+		buf.WriteString(strings.TrimRight(strings.TrimSpace(sql), ";"))
+		buf.WriteString(";")
+		if i < len(args) && len(args[i]) > 0 {
+			fmt.Fprintf(&buf, " -- args: %v", args[i])
+		}
+		buf.WriteString("\n")
+	}
+	buf.WriteString("COMMIT;\n")
+
+	w.Lock()
+	defer w.Unlock()
+	w.count += len(sqls)
+	if w.f == nil {
+		log.Infof("[dry-run] %s", buf.String())
+		return nil
+	}
+	_, err := w.f.Write(buf.Bytes())
+	return errors.Trace(err)
+}
+
+// Count returns the count of sqls written
+func (w *SQLWriter) Count() int {
+	w.Lock()
+	defer w.Unlock()
+	return w.count
+}
+
+// Close closes the file written into
+func (w *SQLWriter) Close() error {
+	w.Lock()
+	defer w.Unlock()
+	if w.f == nil {
+		return nil
+	}
+	err := w.f.Close()
+	w.f = nil
+	return errors.Trace(err)
+}
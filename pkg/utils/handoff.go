@@ -0,0 +1,114 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+
+	"github.com/BurntSushi/toml"
+	"github.com/pingcap/errors"
+	"github.com/siddontang/go-mysql/mysql"
+	"github.com/siddontang/go/ioutil2"
+
+	"github.com/pingcap/dm/pkg/gtid"
+)
+
+// HandoffFilename is the name of the file the loader writes into the dump directory after restoring the dump completely.
+const HandoffFilename = "loader-handoff.toml"
+
+// Handoff is the contract between the loader and the syncer of a task in `all` mode:
+// the loader writes it only after all data in the dump restored, and the syncer starts from the binlog position in it,
+// so no row is synced twice or missed at the boundary of full and incremental data.
+type Handoff struct {
+	Task       string `toml:"task" json:"task"`
+	BinLogName string `toml:"binlog-name" json:"binlog-name"`
+	BinLogPos  uint32 `toml:"binlog-pos" json:"binlog-pos"`
+	BinlogGTID string `toml:"binlog-gtid" json:"binlog-gtid"`
+	// checksum of the fields above, to detect the file truncated or modified
+	Checksum uint32 `toml:"checksum" json:"checksum"`
+}
+
+// HandoffPath returns the path of the handoff file of the dump, which is beside the archive if the dump is archived
+func HandoffPath(dumpDir string) string {
+	if IsFileExists(dumpDir) {
+		return dumpDir + "." + HandoffFilename
+	}
+	return filepath.Join(dumpDir, HandoffFilename)
+}
+
+// Pos returns the binlog position the syncer starts from
+func (h *Handoff) Pos() mysql.Position {
+	return mysql.Position{Name: h.BinLogName, Pos: h.BinLogPos}
+}
+
+func (h *Handoff) checksum() uint32 {
+	return crc32.ChecksumIEEE([]byte(fmt.Sprintf("%s\n%s\n%d\n%s", h.Task, h.BinLogName, h.BinLogPos, h.BinlogGTID)))
+}
+
+// WriteHandoff writes the handoff into the dump directory atomically
+func WriteHandoff(dumpDir string, h *Handoff) error {
+	h.Checksum = h.checksum()
+
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(h); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(ioutil2.WriteFileAtomic(HandoffPath(dumpDir), buf.Bytes(), 0644))
+}
+
+// ReadHandoff reads the handoff in the dump directory and verifies it, the GTID set in it is parsed in flavor.
+// either the binlog position or the GTID set may be empty, but not both.
+// an error satisfying errors.IsNotFound is returned if the loader didn't write it.
+func ReadHandoff(dumpDir string, flavor string) (*Handoff, error) {
+	filename := HandoffPath(dumpDir)
+	h := &Handoff{}
+	if _, err := toml.DecodeFile(filename, h); err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.NotFoundf("handoff %s", filename)
+		}
+		return nil, errors.Annotatef(err, "decode handoff %s", filename)
+	}
+
+	if h.Checksum != h.checksum() {
+		return nil, errors.NotValidf("checksum %d of handoff %s", h.Checksum, filename)
+	}
+	if h.BinLogName == "" && h.BinlogGTID == "" {
+		return nil, errors.NotValidf("handoff %s without binlog position and GTID set", filename)
+	}
+	if h.BinLogName != "" {
+		pos := h.Pos()
+		if err := verifyBinlogPos(&pos); err != nil {
+			return nil, errors.Annotatef(err, "handoff %s", filename)
+		}
+	}
+	if h.BinlogGTID != "" {
+		if _, err := gtid.ParserGTID(flavor, h.BinlogGTID); err != nil {
+			return nil, errors.Annotatef(err, "GTID set %s in handoff %s", h.BinlogGTID, filename)
+		}
+	}
+	return h, nil
+}
+
+// RemoveHandoff removes the handoff in the dump directory if it exists
+func RemoveHandoff(dumpDir string) error {
+	err := os.Remove(HandoffPath(dumpDir))
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Trace(err)
+	}
+	return nil
+}
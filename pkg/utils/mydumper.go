@@ -16,6 +16,7 @@ package utils
 import (
 	"bufio"
 	"io"
+	"io/ioutil"
 	"os"
 	"strconv"
 	"strings"
@@ -24,7 +25,15 @@ import (
 	"github.com/siddontang/go-mysql/mysql"
 )
 
-// ParseMetaData parses mydumper's output meta file and returns binlog position
+// binlogHeaderSize is the size of magic number at the beginning of a binlog file
+const binlogHeaderSize = 4
+
+// ErrNoBinlogPos is the cause of the error returned by ParseMetaData if no binlog position found in the file,
+// like the dump is truncated, or only the GTID set is available in it.
+var ErrNoBinlogPos = errors.New("no binlog position")
+
+// ParseMetaData parses mydumper's output meta file and returns binlog position,
+// an error is returned if no well-formed binlog position found in the file.
 func ParseMetaData(filename string) (*mysql.Position, error) {
 	fd, err := os.Open(filename)
 	if err != nil {
@@ -36,12 +45,12 @@ func ParseMetaData(filename string) (*mysql.Position, error) {
 	br := bufio.NewReader(fd)
 	for {
 		line, err := br.ReadString('\n')
-		if err == io.EOF {
+		if err == io.EOF && len(line) == 0 {
 			break
-		} else if err != nil {
+		} else if err != nil && err != io.EOF {
 			return nil, errors.Trace(err)
 		}
-		line = strings.TrimSpace(line[:len(line)-1])
+		line = strings.TrimSpace(line)
 		if len(line) == 0 {
 			continue
 		}
@@ -59,14 +68,65 @@ func ParseMetaData(filename string) (*mysql.Position, error) {
 		} else if parts[0] == "Pos" {
 			pos64, err := strconv.ParseUint(parts[1], 10, 32)
 			if err != nil {
-				return nil, errors.Trace(err)
+				return nil, errors.Annotatef(err, "parse binlog pos %s in metadata %s", parts[1], filename)
 			}
 			if len(logName) > 0 {
-				return &mysql.Position{Name: logName, Pos: uint32(pos64)}, nil
+				pos := &mysql.Position{Name: logName, Pos: uint32(pos64)}
+				if err = verifyBinlogPos(pos); err != nil {
+					return nil, errors.Annotatef(err, "metadata %s", filename)
+				}
+				return pos, nil
 			}
 			break // Pos extracted, but no Log, error occurred
 		}
 	}
 
-	return nil, errors.Errorf("parse metadata for %s fail", filename)
+	return nil, errors.Annotatef(ErrNoBinlogPos, "parse metadata for %s fail", filename)
+}
+
+// verifyBinlogPos checks whether pos is a well-formed binlog position,
+// the name should be like `mysql-bin.000001`, and the pos should not be in the binlog file header.
+func verifyBinlogPos(pos *mysql.Position) error {
+	idx := strings.LastIndex(pos.Name, ".")
+	if idx <= 0 || idx == len(pos.Name)-1 {
+		return errors.NotValidf("binlog name %s", pos.Name)
+	}
+	for _, ch := range pos.Name[idx+1:] {
+		if ch < '0' || ch > '9' {
+			return errors.NotValidf("binlog name %s", pos.Name)
+		}
+	}
+	if pos.Pos < binlogHeaderSize {
+		return errors.NotValidf("binlog pos %d in %s", pos.Pos, pos.Name)
+	}
+	return nil
+}
+
+// ParseMetaDataGTID parses mydumper's output meta file and returns the GTID set of `SHOW MASTER STATUS`,
+// which is empty if GTID is not enabled in the source.
+func ParseMetaDataGTID(filename string) (string, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+
+	lines := strings.Split(string(data), "\n")
+	inMaster := false
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.Contains(line, "SHOW MASTER STATUS"):
+			inMaster = true
+		case strings.Contains(line, "SHOW SLAVE STATUS"):
+			inMaster = false
+		case inMaster && strings.HasPrefix(line, "GTID:"):
+			// mydumper writes no space after `GTID:`, and a set of several UUIDs is split into lines after `,`
+			gtid := strings.TrimSpace(strings.TrimPrefix(line, "GTID:"))
+			for j := i + 1; strings.HasSuffix(gtid, ",") && j < len(lines); j++ {
+				gtid += strings.TrimSpace(lines[j])
+			}
+			return gtid, nil
+		}
+	}
+	return "", nil
 }
@@ -16,6 +16,7 @@ package utils
 import (
 	"io/ioutil"
 	"os"
+	"strings"
 
 	. "github.com/pingcap/check"
 	"github.com/siddontang/go-mysql/mysql"
@@ -72,3 +73,93 @@ Finished dump at: 2018-12-27 19:51:22`,
 		c.Assert(pos, DeepEquals, tc.pos)
 	}
 }
+
+func (t *testUtilsSuite) TestParseMetaDataInvalid(c *C) {
+	f, err := ioutil.TempFile("", "metadata")
+	c.Assert(err, IsNil)
+	defer os.Remove(f.Name())
+
+	source := `Started dump at: 2018-12-28 07:20:49
+SHOW MASTER STATUS:
+        Log: bin.000001
+        Pos: 2479
+        GTID:97b5142f-e19c-11e8-808c-0242ac110005:1-13
+
+Finished dump at: 2018-12-28 07:20:51`
+
+	// the last line without newline
+	truncated := source[:strings.Index(source, "\n        GTID")]
+	c.Assert(ioutil.WriteFile(f.Name(), []byte(truncated), 0644), IsNil)
+	pos, err := ParseMetaData(f.Name())
+	c.Assert(err, IsNil)
+	c.Assert(pos, DeepEquals, &mysql.Position{Name: "bin.000001", Pos: 2479})
+
+	testCases := []struct {
+		source string
+		err    string
+	}{
+		{source[:strings.Index(source, "        Pos")], ".*parse metadata for .* fail.*"}, // truncated before Pos
+		{source[:strings.Index(source, "2479")], ".*parse metadata for .* fail.*"},        // truncated in Pos
+		{source[:strings.Index(source, "000001")], ".*parse metadata for .* fail.*"},      // truncated in Log
+		{strings.Replace(source, "bin.000001", "bin.", 1), ".*binlog name bin. not valid.*"},
+		{strings.Replace(source, "bin.000001", "bin.x00001", 1), ".*binlog name bin.x00001 not valid.*"},
+		{strings.Replace(source, "2479", "3", 1), ".*binlog pos 3 in bin.000001 not valid.*"},
+		{strings.Replace(source, "2479", "24x9", 1), ".*parse binlog pos 24x9.*"},
+	}
+	for _, tc := range testCases {
+		c.Assert(ioutil.WriteFile(f.Name(), []byte(tc.source), 0644), IsNil)
+		pos, err := ParseMetaData(f.Name())
+		c.Assert(err, ErrorMatches, tc.err, Commentf("source %s", tc.source))
+		c.Assert(pos, IsNil)
+	}
+}
+
+func (t *testUtilsSuite) TestParseMetaDataGTID(c *C) {
+	f, err := ioutil.TempFile("", "metadata")
+	c.Assert(err, IsNil)
+	defer os.Remove(f.Name())
+
+	testCases := []struct {
+		source string
+		gtid   string
+	}{
+		{
+			`SHOW MASTER STATUS:
+        Log: bin.000001
+        Pos: 2479
+        GTID:97b5142f-e19c-11e8-808c-0242ac110005:1-13
+
+SHOW SLAVE STATUS:
+        Host: 10.128.27.98
+        Log: bin.000003
+        Pos: 329635
+        GTID:a7b5142f-e19c-11e8-808c-0242ac110005:1-8
+`,
+			"97b5142f-e19c-11e8-808c-0242ac110005:1-13",
+		},
+		{
+			`SHOW MASTER STATUS:
+        Log: bin.000001
+        Pos: 2479
+        GTID:97b5142f-e19c-11e8-808c-0242ac110005:1-13,
+a7b5142f-e19c-11e8-808c-0242ac110005:1-8
+
+`,
+			"97b5142f-e19c-11e8-808c-0242ac110005:1-13,a7b5142f-e19c-11e8-808c-0242ac110005:1-8",
+		},
+		{
+			`SHOW MASTER STATUS:
+        Log: bin.000001
+        Pos: 2479
+        GTID:
+`,
+			"",
+		},
+	}
+	for _, tc := range testCases {
+		c.Assert(ioutil.WriteFile(f.Name(), []byte(tc.source), 0644), IsNil)
+		gtid, err := ParseMetaDataGTID(f.Name())
+		c.Assert(err, IsNil)
+		c.Assert(gtid, Equals, tc.gtid)
+	}
+}
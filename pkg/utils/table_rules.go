@@ -0,0 +1,92 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"strings"
+
+	"github.com/pingcap/errors"
+	selector "github.com/pingcap/tidb-tools/pkg/table-rule-selector"
+)
+
+// TableRules resolves the rule of a table among rules of tables matched by patterns, patterns are like route rules,
+// and a rule without table pattern is a schema level rule matching all tables of the schema.
+// the table level rule is preferred if the table is matched by both table level and schema level rules,
+// and it's an error if the table is matched by more than one rule in the same level.
+type TableRules struct {
+	name          string // like `row limits`, used in errors
+	caseSensitive bool
+	selector      selector.Selector // nil if no rules
+}
+
+// tableRule is a rule inserted into the selector
+type tableRule struct {
+	rule       interface{}
+	tableLevel bool
+}
+
+// NewTableRules creates TableRules without rules, name is the kind of rules in plural used in errors, like `row limits`
+func NewTableRules(name string, caseSensitive bool) *TableRules {
+	return &TableRules{name: name, caseSensitive: caseSensitive}
+}
+
+// Insert adds the rule of tables matched by the patterns
+func (r *TableRules) Insert(schemaPattern, tablePattern string, rule interface{}) error {
+	if r.selector == nil {
+		r.selector = selector.NewTrieSelector()
+	}
+	schema, table := r.fold(schemaPattern, tablePattern)
+	err := r.selector.Insert(schema, table, &tableRule{rule: rule, tableLevel: len(tablePattern) > 0}, false)
+	return errors.Trace(err)
+}
+
+// Match returns the rule of the table, nil if the table is not matched by any rule
+func (r *TableRules) Match(schema, table string) (interface{}, error) {
+	if r == nil || r.selector == nil {
+		return nil, nil
+	}
+
+	var schemaRules, tableRules []interface{}
+	for _, m := range r.selector.Match(r.fold(schema, table)) {
+		rule, ok := m.(*tableRule)
+		if !ok {
+			return nil, errors.NotValidf("%s %+v", r.name, m)
+		}
+		if rule.tableLevel {
+			tableRules = append(tableRules, rule.rule)
+		} else {
+			schemaRules = append(schemaRules, rule.rule)
+		}
+	}
+
+	rules := tableRules
+	if len(rules) == 0 {
+		rules = schemaRules
+	}
+	switch len(rules) {
+	case 0:
+		return nil, nil
+	case 1:
+		return rules[0], nil
+	default:
+		return nil, errors.NotSupportedf("table %s.%s matched by %d %s", schema, table, len(rules), r.name)
+	}
+}
+
+func (r *TableRules) fold(schema, table string) (string, string) {
+	if !r.caseSensitive {
+		return strings.ToLower(schema), strings.ToLower(table)
+	}
+	return schema, table
+}
@@ -0,0 +1,72 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	. "github.com/pingcap/check"
+)
+
+func (t *testUtilsSuite) TestTableRules(c *C) {
+	// no rules
+	var r *TableRules
+	rule, err := r.Match("db", "tbl")
+	c.Assert(err, IsNil)
+	c.Assert(rule, IsNil)
+	r = NewTableRules("rules", false)
+	rule, err = r.Match("db", "tbl")
+	c.Assert(err, IsNil)
+	c.Assert(rule, IsNil)
+
+	c.Assert(r.Insert("db", "log_*", "log"), IsNil)
+	c.Assert(r.Insert("db", "users", "users"), IsNil)
+	c.Assert(r.Insert("stats*", "", "stats"), IsNil)
+	c.Assert(r.Insert("stats_1", "t", "stats_1.t"), IsNil)
+	c.Assert(r.Insert("dup", "t*", "t*"), IsNil)
+	c.Assert(r.Insert("dup", "tb*", "tb*"), IsNil)
+	// the same patterns
+	c.Assert(r.Insert("DB", "USERS", "users"), NotNil)
+
+	cases := []struct {
+		schema, table string
+		rule          interface{}
+	}{
+		{"db", "users", "users"},
+		{"DB", "LOG_1", "log"},
+		{"stats_2", "t", "stats"},
+		// the table level rule is preferred
+		{"stats_1", "t", "stats_1.t"},
+		{"stats_1", "t2", "stats"},
+		{"dup", "a", nil},
+		{"other", "t", nil},
+	}
+	for _, cs := range cases {
+		rule, err = r.Match(cs.schema, cs.table)
+		c.Assert(err, IsNil)
+		c.Assert(rule, Equals, cs.rule, Commentf("table %s.%s", cs.schema, cs.table))
+	}
+
+	// ambiguous rules in the same level
+	_, err = r.Match("dup", "tbl")
+	c.Assert(err, ErrorMatches, "table dup.tbl matched by 2 rules not supported")
+
+	// case sensitive
+	r = NewTableRules("rules", true)
+	c.Assert(r.Insert("db", "tbl", "tbl"), IsNil)
+	rule, err = r.Match("DB", "tbl")
+	c.Assert(err, IsNil)
+	c.Assert(rule, IsNil)
+	rule, err = r.Match("db", "tbl")
+	c.Assert(err, IsNil)
+	c.Assert(rule, Equals, "tbl")
+}
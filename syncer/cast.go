@@ -0,0 +1,94 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syncer
+
+import (
+	"strings"
+	"sync"
+)
+
+// CastFunc casts the value of a column in source to the value bound to DML statements in target,
+// it's used when the types of a column differ between source and target, like YEAR(2) to YEAR(4).
+type CastFunc func(value interface{}) (interface{}, error)
+
+var (
+	castFuncsLock sync.RWMutex
+	castFuncs     = make(map[string]CastFunc)
+)
+
+// RegisterCastFunc registers fn to cast values of columns whose source type is tp.
+// tp is either a full column type like `year(2)` or `tinyint(3) unsigned`, or a type name like `year`,
+// the full column type is preferred when both of them are registered.
+// it should be called before syncers are created, and fn replaces the previous one registered for tp.
+func RegisterCastFunc(tp string, fn CastFunc) {
+	castFuncsLock.Lock()
+	defer castFuncsLock.Unlock()
+	castFuncs[strings.ToLower(tp)] = fn
+}
+
+// UnregisterCastFunc removes the cast function registered for tp
+func UnregisterCastFunc(tp string) {
+	castFuncsLock.Lock()
+	defer castFuncsLock.Unlock()
+	delete(castFuncs, strings.ToLower(tp))
+}
+
+// registeredCastFuncs returns a copy of all registered cast functions, nil if none registered
+func registeredCastFuncs() map[string]CastFunc {
+	castFuncsLock.RLock()
+	defer castFuncsLock.RUnlock()
+	if len(castFuncs) == 0 {
+		return nil
+	}
+	funcs := make(map[string]CastFunc, len(castFuncs))
+	for tp, fn := range castFuncs {
+		funcs[tp] = fn
+	}
+	return funcs
+}
+
+// findCastFunc returns the cast function of col in funcs, nil if not found
+func findCastFunc(funcs map[string]CastFunc, col *column) CastFunc {
+	if len(funcs) == 0 {
+		return nil
+	}
+	tp := strings.ToLower(col.tp)
+	if fn, ok := funcs[tp]; ok {
+		return fn
+	}
+	if i := strings.IndexAny(tp, "( "); i >= 0 {
+		tp = tp[:i]
+	}
+	return funcs[tp]
+}
+
+// castRow casts values of a row by castValue, then by the cast functions in opts, it's ErrUnsupportedType if a cast function fails
+func castRow(data []interface{}, columns []*column, opts *dmlOptions) ([]interface{}, error) {
+	values := make([]interface{}, 0, len(data))
+	for i := range data {
+		value := castValue(data[i], columns[i], opts.timezone, opts.getLogger())
+		if opts.zeroDateToNull {
+			value = castZeroDate(value, columns[i])
+		}
+		if fn := findCastFunc(opts.casts, columns[i]); fn != nil {
+			var err error
+			value, err = fn(value)
+			if err != nil {
+				return nil, newDMLError(ErrUnsupportedType, "cast value %v of column %s (%s): %v", data[i], columns[i].name, columns[i].tp, err)
+			}
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
@@ -0,0 +1,95 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syncer
+
+import (
+	. "github.com/pingcap/check"
+	"github.com/pingcap/errors"
+
+	"github.com/pingcap/dm/dm/config"
+)
+
+// castYear2 widens the value of YEAR(2) to YEAR(4), 70-99 for 1970-1999, 00-69 for 2000-2069
+func castYear2(value interface{}) (interface{}, error) {
+	var year int64
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case int:
+		year = int64(v)
+	case int64:
+		year = v
+	default:
+		return nil, errors.NotValidf("YEAR(2) value %v (%T)", value, value)
+	}
+	switch {
+	case year >= 100:
+		return year, nil
+	case year >= 70:
+		return 1900 + year, nil
+	default:
+		return 2000 + year, nil
+	}
+}
+
+func (s *testSyncerSuite) TestCastFunc(c *C) {
+	RegisterCastFunc("YEAR(2)", castYear2)
+	defer UnregisterCastFunc("year(2)")
+	RegisterCastFunc("tinyint", func(value interface{}) (interface{}, error) {
+		// runs after castUnsigned
+		if v, ok := value.(uint8); ok {
+			return int16(v), nil
+		}
+		return value, nil
+	})
+	defer UnregisterCastFunc("tinyint")
+
+	columns := []*column{
+		{idx: 0, name: "id", unsigned: true, tp: "tinyint(3) unsigned"},
+		{idx: 1, name: "y", tp: "year(2)"},
+		{idx: 2, name: "y4", tp: "year(4)"},
+	}
+	indexColumns := map[string][]*column{"primary": {columns[0]}}
+	opts := &dmlOptions{keyGen: joinKeyGenerator{}, casts: registeredCastFuncs()}
+	c.Assert(findCastFunc(opts.casts, columns[0]), NotNil)
+	c.Assert(findCastFunc(opts.casts, columns[2]), IsNil)
+
+	sqls, keys, values, err := genInsertSQLs("db", "tbl", [][]interface{}{{int8(-1), 99, 99}}, columns, indexColumns, 1, config.ConflictReplace, opts)
+	c.Assert(err, IsNil)
+	c.Assert(sqls, HasLen, 1)
+	c.Assert(values, DeepEquals, [][]interface{}{{int16(255), int64(1999), 99}})
+	c.Assert(keys, DeepEquals, [][]string{{"255"}})
+
+	data := [][]interface{}{{int8(1), 5, 5}, {int8(1), 69, 69}}
+	sqls, _, values, err = genUpdateSQLs("db", "tbl", data, columns, indexColumns, nil, false, opts)
+	c.Assert(err, IsNil)
+	c.Assert(sqls, DeepEquals, []string{"UPDATE `db`.`tbl` SET `y` = ?, `y4` = ? WHERE `id` = ? LIMIT 1;"})
+	c.Assert(values, DeepEquals, [][]interface{}{{int64(2069), 69, int16(1)}})
+
+	_, _, values, err = genDeleteSQLs("db", "tbl", data[:1], columns, indexColumns, opts)
+	c.Assert(err, IsNil)
+	c.Assert(values, DeepEquals, [][]interface{}{{int16(1)}})
+
+	// values failed to cast
+	_, _, _, err = genInsertSQLs("db", "tbl", [][]interface{}{{int8(1), "abc", 1}}, columns, indexColumns, 1, config.ConflictReplace, opts)
+	c.Assert(err, ErrorMatches, "cast value abc of column y \\(year\\(2\\)\\): YEAR\\(2\\) value abc \\(string\\) not valid")
+
+	// not registered any more
+	UnregisterCastFunc("tinyint")
+	c.Assert(findCastFunc(registeredCastFuncs(), columns[0]), IsNil)
+	opts.casts = nil
+	_, _, values, err = genInsertSQLs("db", "tbl", [][]interface{}{{int8(-1), 99, 99}}, columns, indexColumns, 1, config.ConflictReplace, opts)
+	c.Assert(err, IsNil)
+	c.Assert(values, DeepEquals, [][]interface{}{{uint8(255), 99, 99}})
+}
@@ -80,3 +80,51 @@ func (c *causality) detectConflict(keys []string) bool {
 
 	return false
 }
+
+// BuildConflictGroups groups the indices of statements by their keys, like keys returned by genInsertSQLs,
+// statements sharing a key (directly or through other statements) are in the same group,
+// so groups can be applied concurrently while statements in a group are applied in order.
+// indices in a group are ascending, and groups are ordered by their first indices.
+// a statement without keys doesn't conflict with others, it's in a group alone.
+func BuildConflictGroups(keys [][]string) [][]int {
+	// union-find of statement indices, the root of a set is the smallest index in it
+	parents := make([]int, len(keys))
+	var find func(i int) int
+	find = func(i int) int {
+		if parents[i] != i {
+			parents[i] = find(parents[i])
+		}
+		return parents[i]
+	}
+
+	owners := make(map[string]int) // key -> index of the first statement with the key
+	for i, ks := range keys {
+		parents[i] = i
+		for _, key := range ks {
+			owner, ok := owners[key]
+			if !ok {
+				owners[key] = i
+				continue
+			}
+			a, b := find(owner), find(i)
+			if a > b {
+				a, b = b, a
+			}
+			parents[b] = a
+		}
+	}
+
+	var groups [][]int
+	rootGroups := make(map[int]int) // root index -> index of its group in groups
+	for i := range keys {
+		root := find(i)
+		g, ok := rootGroups[root]
+		if !ok {
+			g = len(groups)
+			rootGroups[root] = g
+			groups = append(groups, nil)
+		}
+		groups[g] = append(groups[g], i)
+	}
+	return groups
+}
@@ -36,3 +36,25 @@ func (s *testSyncerSuite) TestCausality(c *C) {
 	ca.reset()
 	c.Assert(ca.relations, HasLen, 0)
 }
+
+func (s *testSyncerSuite) TestBuildConflictGroups(c *C) {
+	// fully independent rows
+	c.Assert(BuildConflictGroups([][]string{{"1"}, {"2"}, {"3"}}), DeepEquals, [][]int{{0}, {1}, {2}})
+
+	// a chain of dependent updates: 1 -> 2, 2 -> 3, 3 -> 4, and an independent row between them
+	keys := [][]string{
+		{"1", "2"},
+		{"10"},
+		{"2", "3"},
+		{"3", "4"},
+		{"11", "12"},
+		{"4"},
+	}
+	c.Assert(BuildConflictGroups(keys), DeepEquals, [][]int{{0, 2, 3, 5}, {1}, {4}})
+
+	// groups merged by a later statement keep the order of statements
+	keys = [][]string{{"a"}, {"b"}, {"c"}, {"b", "a"}, {"c"}, {}, {"d"}}
+	c.Assert(BuildConflictGroups(keys), DeepEquals, [][]int{{0, 1, 3}, {2, 4}, {5}, {6}})
+
+	c.Assert(BuildConflictGroups(nil), HasLen, 0)
+}
@@ -21,6 +21,7 @@ import (
 
 	"github.com/pingcap/dm/pkg/log"
 	"github.com/pingcap/errors"
+	tmysql "github.com/pingcap/parser/mysql"
 	"github.com/siddontang/go-mysql/mysql"
 
 	"github.com/pingcap/dm/dm/config"
@@ -224,6 +225,14 @@ func (cp *RemoteCheckPoint) Init() error {
 	}
 	cp.db = db
 
+	if cp.cfg.DryRun {
+		// checkpoints only advance in memory, sqls to save them are written together with other sqls
+		cp.db.sqlWriter, err = utils.NewSQLWriter(cp.cfg.DryRunFile)
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+
 	err = cp.prepare()
 	if err != nil {
 		return errors.Trace(err)
@@ -234,6 +243,11 @@ func (cp *RemoteCheckPoint) Init() error {
 
 // Close implements CheckPoint.Close
 func (cp *RemoteCheckPoint) Close() {
+	if cp.db != nil && cp.db.sqlWriter != nil {
+		if err := cp.db.sqlWriter.Close(); err != nil {
+			log.Errorf("[checkpoint] close dry-run sql writer error %v", err)
+		}
+	}
 	closeDBs(cp.db)
 }
 
@@ -494,7 +508,10 @@ func (cp *RemoteCheckPoint) createTable() error {
 func (cp *RemoteCheckPoint) Load() error {
 	query := fmt.Sprintf("SELECT `cp_schema`, `cp_table`, `binlog_name`, `binlog_pos`, `is_global` FROM `%s`.`%s` WHERE `id`='%s'", cp.schema, cp.table, cp.id)
 	rows, err := cp.db.querySQL(query, maxRetryCount)
-	if err != nil {
+	if err != nil && cp.cfg.DryRun && (isMysqlError(err, tmysql.ErrBadDB) || isMysqlError(err, tmysql.ErrNoSuchTable)) {
+		log.Infof("[checkpoint] %s.%s not exists in dry-run mode, start without checkpoint", cp.schema, cp.table)
+		return nil
+	} else if err != nil {
 		return errors.Trace(err)
 	}
 	defer rows.Close()
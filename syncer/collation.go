@@ -0,0 +1,66 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syncer
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// foldCollationKey folds the string value of a column with the collation, so values equal under the collation
+// have the same key of rows for conflict detection, like `A` and `a` in a unique index of a `utf8mb4_general_ci` column.
+//   - case is folded for case-insensitive collations like `utf8mb4_general_ci` and `utf8mb4_0900_ai_ci`
+//   - accents are removed for accent-insensitive ones, they are `_ai` ones and `_ci` ones before `_0900_` ones like `utf8_general_ci`
+//   - trailing spaces are trimmed for PAD SPACE collations, they are all except `_0900_` ones, including `_bin` ones
+//
+// the folding is a bit looser than the collation for some characters, it only makes more rows conflict, which is safe.
+func foldCollationKey(value string, collation string) string {
+	if collation == "" {
+		return value
+	}
+	collation = strings.ToLower(collation)
+	nopad := strings.Contains(collation, "_0900_")
+
+	if !nopad {
+		value = strings.TrimRight(value, " ")
+	}
+	if !strings.HasSuffix(collation, "_ci") && !strings.HasSuffix(collation, "_ai") {
+		return value
+	}
+
+	if strings.Contains(collation, "_ai") || (!nopad && !strings.Contains(collation, "_as")) {
+		value = removeAccents(value)
+	}
+	if strings.HasSuffix(collation, "_ci") {
+		value = strings.ToLower(value)
+	}
+	return value
+}
+
+// removeAccents removes the combining marks of the decomposed value, like `é` to `e`
+func removeAccents(value string) string {
+	for _, r := range value {
+		if r >= unicode.MaxASCII {
+			return strings.Map(func(r rune) rune {
+				if unicode.Is(unicode.Mn, r) {
+					return -1
+				}
+				return r
+			}, norm.NFD.String(value))
+		}
+	}
+	return value
+}
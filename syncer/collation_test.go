@@ -0,0 +1,65 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syncer
+
+import (
+	. "github.com/pingcap/check"
+
+	"github.com/pingcap/dm/dm/config"
+)
+
+func (s *testSyncerSuite) TestFoldCollationKey(c *C) {
+	cases := []struct {
+		collation string
+		a, b      string
+		equal     bool
+	}{
+		{"utf8mb4_general_ci", "A", "a", true},
+		{"UTF8_GENERAL_CI", "Abc", "aBC", true},
+		{"utf8mb4_general_ci", "café", "CAFE", true},
+		{"utf8mb4_general_ci", "a  ", "a", true},
+		{"latin1_swedish_ci", "A", "a", true},
+		{"utf8mb4_0900_ai_ci", "Café", "cafe", true},
+		{"utf8mb4_0900_ai_ci", "a ", "a", false}, // NO PAD
+		{"utf8mb4_0900_as_ci", "É", "é", true},
+		{"utf8mb4_0900_as_ci", "é", "e", false},
+		{"utf8mb4_0900_as_cs", "A", "a", false},
+		{"utf8mb4_bin", "A", "a", false},
+		{"utf8mb4_bin", "a ", "a", true}, // PAD SPACE
+		{"", "A", "a", false},
+	}
+	for _, keyGen := range []KeyGenerator{NewKeyGenerator(config.KeyStrategyJoin), NewKeyGenerator(config.KeyStrategyHash)} {
+		for _, tc := range cases {
+			col := &column{idx: 0, name: "name", tp: "varchar(20)", collation: tc.collation}
+			ka := keyGen.GenKey([]*column{col}, []interface{}{tc.a})
+			kb := keyGen.GenKey([]*column{col}, []interface{}{tc.b})
+			c.Assert(ka == kb, Equals, tc.equal, Commentf("%s: %q vs %q", tc.collation, tc.a, tc.b))
+		}
+	}
+
+	// rows with `A` and `a` conflict in a unique index of a case-insensitive column, but the values are kept as they are
+	columns := []*column{
+		{idx: 0, name: "id", NotNull: true, tp: "int(11)"},
+		{idx: 1, name: "name", NotNull: true, tp: "varchar(20)", collation: "utf8mb4_general_ci"},
+	}
+	indexColumns := map[string][]*column{"uk": {columns[1]}}
+	_, keys, values, err := genInsertSQLs("db", "tbl", [][]interface{}{{int32(1), "A"}, {int32(2), "a"}}, columns, indexColumns, 1, config.ConflictReplace, testDMLOptions)
+	c.Assert(err, IsNil)
+	c.Assert(keys, DeepEquals, [][]string{{"a"}, {"a"}})
+	c.Assert(values, DeepEquals, [][]interface{}{{int32(1), "A"}, {int32(2), "a"}})
+
+	// binary strings are not folded
+	col := &column{idx: 0, name: "name", tp: "varbinary(20)", binary: true, collation: "utf8mb4_general_ci"}
+	c.Assert(genKeyList([]*column{col}, []interface{}{[]byte("A")}), Not(Equals), genKeyList([]*column{col}, []interface{}{[]byte("a")}))
+}
@@ -0,0 +1,113 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syncer
+
+import (
+	"strings"
+
+	"github.com/pingcap/errors"
+)
+
+// ColumnDiff is the difference between the columns of rows in an event and the current columns of the table,
+// which differ if the table is altered upstream after the event written, like by online DDL.
+type ColumnDiff struct {
+	Added     []string // columns of the table missing in the event
+	Removed   []string // columns of the event missing in the table
+	Reordered []string // columns of both in different orders, in the order of the table
+	// Mapping maps the index of a column in the event to its index in the table, -1 if removed
+	Mapping []int
+
+	columns []*column // columns of the table
+}
+
+// DiffColumns compares the columns of rows in an event with the columns of the table by names (case insensitive),
+// so values of the rows can be realigned to the columns of the table by Realign before statements generated by genInsertSQLs and others,
+// rather than rejected as mismatched in length.
+func DiffColumns(event []*column, table []*column) *ColumnDiff {
+	tableIdx := make(map[string]int, len(table))
+	for i, col := range table {
+		tableIdx[strings.ToLower(col.name)] = i
+	}
+
+	diff := &ColumnDiff{Mapping: make([]int, len(event)), columns: table}
+	inEvent := make([]bool, len(table))
+	var common []int // indexes in the table of columns of both, in the order of the event
+	for i, col := range event {
+		j, ok := tableIdx[strings.ToLower(col.name)]
+		if !ok {
+			diff.Mapping[i] = -1
+			diff.Removed = append(diff.Removed, col.name)
+			continue
+		}
+		diff.Mapping[i] = j
+		inEvent[j] = true
+		common = append(common, j)
+	}
+
+	// the k-th column of both in the order of the event should be the k-th of them in the order of the table
+	k := 0
+	reordered := make([]bool, len(table))
+	for j, col := range table {
+		if !inEvent[j] {
+			diff.Added = append(diff.Added, col.name)
+			continue
+		}
+		if common[k] != j {
+			reordered[j] = true
+		}
+		k++
+	}
+	for j, col := range table {
+		if reordered[j] {
+			diff.Reordered = append(diff.Reordered, col.name)
+		}
+	}
+	return diff
+}
+
+// IsEmpty returns whether the columns of the event are the same as the columns of the table
+func (d *ColumnDiff) IsEmpty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Reordered) == 0
+}
+
+// Realign returns the values of a row in the event in the order of the columns of the table,
+// values of removed columns are dropped, and added columns are filled with their DEFAULT values like fillMissingColumns.
+func (d *ColumnDiff) Realign(value []interface{}) ([]interface{}, error) {
+	if len(value) != len(d.Mapping) {
+		return nil, newDMLError(ErrColumnCountMismatch, "event columns and data mismatch in length: %d (columns) vs %d (data)", len(d.Mapping), len(value))
+	}
+	if d.IsEmpty() {
+		return value, nil
+	}
+
+	realigned := make([]interface{}, len(d.columns))
+	filled := make([]bool, len(d.columns))
+	for i, j := range d.Mapping {
+		if j < 0 {
+			continue
+		}
+		realigned[j] = value[i]
+		filled[j] = true
+	}
+	for j, col := range d.columns {
+		if filled[j] {
+			continue
+		}
+		if col.defaultExpr && !col.IsGenerated {
+			return nil, errors.NotSupportedf("fill column %s missing in event with DEFAULT expression %v", col.name, col.defaultValue)
+		}
+		realigned[j] = col.defaultValue
+	}
+	return realigned, nil
+}
@@ -0,0 +1,94 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syncer
+
+import (
+	. "github.com/pingcap/check"
+
+	"github.com/pingcap/dm/dm/config"
+)
+
+func (s *testSyncerSuite) TestDiffColumns(c *C) {
+	newColumns := func(names ...string) []*column {
+		columns := make([]*column, 0, len(names))
+		for i, name := range names {
+			columns = append(columns, &column{idx: i, name: name, tp: "int(11)"})
+		}
+		return columns
+	}
+	row := []interface{}{int32(1), int32(2), int32(3)}
+
+	// the same columns
+	diff := DiffColumns(newColumns("id", "a", "b"), newColumns("ID", "a", "b"))
+	c.Assert(diff.IsEmpty(), IsTrue)
+	c.Assert(diff.Mapping, DeepEquals, []int{0, 1, 2})
+	value, err := diff.Realign(row)
+	c.Assert(err, IsNil)
+	c.Assert(value, DeepEquals, row)
+
+	// added at end, filled with the DEFAULT value
+	table := newColumns("id", "a", "b", "c")
+	table[3].defaultValue = "10"
+	diff = DiffColumns(newColumns("id", "a", "b"), table)
+	c.Assert(diff.Added, DeepEquals, []string{"c"})
+	c.Assert(diff.Removed, IsNil)
+	c.Assert(diff.Reordered, IsNil)
+	c.Assert(diff.Mapping, DeepEquals, []int{0, 1, 2})
+	value, err = diff.Realign(row)
+	c.Assert(err, IsNil)
+	c.Assert(value, DeepEquals, []interface{}{int32(1), int32(2), int32(3), "10"})
+	sqls, _, values, err := genInsertSQLs("db", "tbl", [][]interface{}{value}, table, nil, 1, config.ConflictReplace, testDMLOptions)
+	c.Assert(err, IsNil)
+	c.Assert(sqls, DeepEquals, []string{"REPLACE INTO `db`.`tbl` (`id`,`a`,`b`,`c`) VALUES (?,?,?,?);"})
+	c.Assert(values, DeepEquals, [][]interface{}{{int32(1), int32(2), int32(3), "10"}})
+
+	// added with DEFAULT expression, which can't be filled
+	table[3].defaultValue, table[3].defaultExpr = "CURRENT_TIMESTAMP", true
+	_, err = diff.Realign(row)
+	c.Assert(err, ErrorMatches, ".*DEFAULT expression.*not supported")
+
+	// dropped in the middle
+	diff = DiffColumns(newColumns("id", "a", "b"), newColumns("id", "b"))
+	c.Assert(diff.Added, IsNil)
+	c.Assert(diff.Removed, DeepEquals, []string{"a"})
+	c.Assert(diff.Reordered, IsNil)
+	c.Assert(diff.Mapping, DeepEquals, []int{0, -1, 1})
+	value, err = diff.Realign(row)
+	c.Assert(err, IsNil)
+	c.Assert(value, DeepEquals, []interface{}{int32(1), int32(3)})
+
+	// reordered, like by `MODIFY COLUMN ... AFTER`
+	diff = DiffColumns(newColumns("id", "a", "b"), newColumns("id", "b", "a"))
+	c.Assert(diff.Added, IsNil)
+	c.Assert(diff.Removed, IsNil)
+	c.Assert(diff.Reordered, DeepEquals, []string{"b", "a"})
+	c.Assert(diff.Mapping, DeepEquals, []int{0, 2, 1})
+	value, err = diff.Realign(row)
+	c.Assert(err, IsNil)
+	c.Assert(value, DeepEquals, []interface{}{int32(1), int32(3), int32(2)})
+
+	// all at once
+	diff = DiffColumns(newColumns("id", "a", "b", "c"), newColumns("c", "id", "b", "d"))
+	c.Assert(diff.Added, DeepEquals, []string{"d"})
+	c.Assert(diff.Removed, DeepEquals, []string{"a"})
+	c.Assert(diff.Reordered, DeepEquals, []string{"c", "id", "b"})
+	c.Assert(diff.Mapping, DeepEquals, []int{1, -1, 2, 0})
+	value, err = diff.Realign([]interface{}{int32(1), int32(2), int32(3), int32(4)})
+	c.Assert(err, IsNil)
+	c.Assert(value, DeepEquals, []interface{}{int32(4), int32(1), int32(3), nil})
+
+	// the row doesn't match the columns of the event
+	_, err = diff.Realign(row)
+	c.Assert(err, ErrorMatches, ".*mismatch in length.*")
+}
@@ -0,0 +1,50 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syncer
+
+import (
+	"github.com/pingcap/errors"
+
+	"github.com/pingcap/dm/dm/config"
+	"github.com/pingcap/dm/pkg/utils"
+)
+
+// conflictStrategies resolves the conflict strategies of target tables,
+// tables not matched by any rule use the default strategy.
+type conflictStrategies struct {
+	defaultStrategy string
+	rules           *utils.TableRules
+}
+
+func newConflictStrategies(caseSensitive bool, defaultStrategy string, rules []*config.TableConflictStrategy) (*conflictStrategies, error) {
+	c := &conflictStrategies{defaultStrategy: defaultStrategy, rules: utils.NewTableRules("conflict strategies", caseSensitive)}
+	for _, rule := range rules {
+		if err := c.rules.Insert(rule.SchemaPattern, rule.TablePattern, rule); err != nil {
+			return nil, errors.Annotatef(err, "table conflict strategy %+v", rule)
+		}
+	}
+	return c, nil
+}
+
+// strategy returns the conflict strategy of the table, see utils.TableRules for rules matching the table
+func (c *conflictStrategies) strategy(schema, table string) (string, error) {
+	rule, err := c.rules.Match(schema, table)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	if rule == nil {
+		return c.defaultStrategy, nil
+	}
+	return rule.(*config.TableConflictStrategy).Strategy, nil
+}
@@ -0,0 +1,88 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syncer
+
+import (
+	"strings"
+
+	. "github.com/pingcap/check"
+
+	"github.com/pingcap/dm/dm/config"
+)
+
+func (s *testSyncerSuite) TestConflictStrategies(c *C) {
+	rules := []*config.TableConflictStrategy{
+		{SchemaPattern: "db", TablePattern: "log_*", Strategy: config.ConflictIgnore},
+		{SchemaPattern: "db", TablePattern: "users", Strategy: config.ConflictReplace},
+		{SchemaPattern: "stats*", Strategy: config.ConflictOnDuplicate},
+	}
+	cs, err := newConflictStrategies(false, config.ConflictReplace, rules)
+	c.Assert(err, IsNil)
+
+	columns := []*column{
+		{idx: 0, name: "id", NotNull: true, tp: "int(11)"},
+		{idx: 1, name: "a", tp: "int(11)"},
+	}
+	indexColumns := map[string][]*column{"primary": {columns[0]}}
+	rows := [][]interface{}{{int32(1), int32(10)}}
+
+	cases := []struct {
+		schema, table string
+		strategy      string
+		prefix        string
+	}{
+		{"db", "users", config.ConflictReplace, "REPLACE INTO `db`.`users`"},
+		{"db", "log_202001", config.ConflictIgnore, "INSERT IGNORE INTO `db`.`log_202001`"},
+		{"DB", "LOG_1", config.ConflictIgnore, "INSERT IGNORE INTO `DB`.`LOG_1`"},
+		{"stats_1", "t", config.ConflictOnDuplicate, "INSERT INTO `stats_1`.`t`"},
+		{"other", "t", config.ConflictReplace, "REPLACE INTO `other`.`t`"},
+	}
+	for _, tc := range cases {
+		strategy, err := cs.strategy(tc.schema, tc.table)
+		c.Assert(err, IsNil)
+		c.Assert(strategy, Equals, tc.strategy)
+		sqls, _, _, err := genInsertSQLs(tc.schema, tc.table, rows, columns, indexColumns, 1, strategy, testDMLOptions)
+		c.Assert(err, IsNil)
+		c.Assert(sqls, HasLen, 1)
+		c.Assert(strings.HasPrefix(sqls[0], tc.prefix+" "), IsTrue, Commentf("%s", sqls[0]))
+	}
+
+	// table level rule takes precedence over schema level rule
+	cs, err = newConflictStrategies(true, config.ConflictReplace, []*config.TableConflictStrategy{
+		{SchemaPattern: "db", Strategy: config.ConflictOnDuplicate},
+		{SchemaPattern: "db", TablePattern: "log", Strategy: config.ConflictIgnore},
+		{SchemaPattern: "db", TablePattern: "t*", Strategy: config.ConflictIgnore},
+		{SchemaPattern: "db", TablePattern: "tb*", Strategy: config.ConflictReplace},
+	})
+	c.Assert(err, IsNil)
+	strategy, err := cs.strategy("db", "log")
+	c.Assert(err, IsNil)
+	c.Assert(strategy, Equals, config.ConflictIgnore)
+	strategy, err = cs.strategy("db", "other")
+	c.Assert(err, IsNil)
+	c.Assert(strategy, Equals, config.ConflictOnDuplicate)
+	strategy, err = cs.strategy("DB", "log")
+	c.Assert(err, IsNil)
+	c.Assert(strategy, Equals, config.ConflictReplace)
+	// ambiguous rules in the same level
+	_, err = cs.strategy("db", "tbl")
+	c.Assert(err, NotNil)
+
+	// duplicate rules
+	_, err = newConflictStrategies(false, config.ConflictReplace, []*config.TableConflictStrategy{
+		{SchemaPattern: "db", TablePattern: "t", Strategy: config.ConflictIgnore},
+		{SchemaPattern: "DB", TablePattern: "T", Strategy: config.ConflictReplace},
+	})
+	c.Assert(err, NotNil)
+}
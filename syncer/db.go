@@ -16,10 +16,12 @@ package syncer
 import (
 	"database/sql"
 	"fmt"
+	"regexp"
 	"strings"
 	"time"
 
 	"github.com/pingcap/dm/pkg/log"
+	"github.com/pingcap/dm/pkg/utils"
 	"github.com/pingcap/errors"
 	"github.com/siddontang/go-mysql/mysql"
 
@@ -32,11 +34,26 @@ var (
 )
 
 type column struct {
-	idx      int
-	name     string
-	NotNull  bool
-	unsigned bool
-	tp       string
+	idx         int // ordinal position of the column in table definition, starts from 0
+	name        string
+	NotNull     bool
+	unsigned    bool
+	tp          string
+	IsGenerated bool     // whether it's a VIRTUAL or STORED generated column
+	elems       []string // elements of ENUM or SET column
+	fsp         int      // fractional seconds precision of TIMESTAMP column
+	bitWidth    int      // width of BIT column, 0 for other types
+	precision   int      // precision of DECIMAL column, 0 for other types
+	scale       int      // scale of DECIMAL column
+	binary      bool     // whether it's a BINARY, VARBINARY or BLOB column, whose values are raw bytes rather than text
+	geometry    bool     // whether it's a spatial column like GEOMETRY or POINT, whose values are bound as WKB
+	collation   string   // collation of a string column like `utf8mb4_general_ci`, empty for other types, see foldCollationKey
+	bindExpr    string   // expression of the placeholder of the value in VALUES and SET clauses, like `f(?)`, empty means a bare `?`
+	// DEFAULT value of the column in text, nil for NULL or no DEFAULT
+	defaultValue interface{}
+	defaultExpr  bool // whether DEFAULT is an expression like CURRENT_TIMESTAMP, which can't be bound as a value
+	// whether it's an AUTO_INCREMENT column, whose values are generated if omitted
+	autoIncrement bool
 }
 
 type table struct {
@@ -47,6 +64,20 @@ type table struct {
 	indexColumns map[string][]*column
 }
 
+// normalizeIdentifiers converts the schema, table and column names of t to identifierCase,
+// so all DML statements of t are generated with the normalized names.
+// index columns refer to the same columns as t.columns, so they are normalized consistently.
+func normalizeIdentifiers(t *table, identifierCase string) {
+	if identifierCase != config.IdentifierCaseLower {
+		return
+	}
+	t.schema = strings.ToLower(t.schema)
+	t.name = strings.ToLower(t.name)
+	for _, col := range t.columns {
+		col.name = strings.ToLower(col.name)
+	}
+}
+
 // in MySQL, we can set `max_binlog_size` to control the max size of a binlog file.
 // but this is not absolute:
 // > A transaction is written in one chunk to the binary log, so it is never split between several binary logs.
@@ -66,6 +97,21 @@ type Conn struct {
 	cfg *config.SubTaskConfig
 
 	db *sql.DB
+
+	// write sqls rather than executing them in dry-run mode
+	sqlWriter *utils.SQLWriter
+
+	// DML statements taking longer than it are logged, 0 means not logged, see config.SyncerConfig.SlowStatementThreshold
+	slowThreshold time.Duration
+	logger        log.Logger // the global logger is used if it's nil
+}
+
+// getLogger returns the logger of conn, or the global logger if not specified
+func (conn *Conn) getLogger() log.Logger {
+	if conn.logger == nil {
+		return log.GlobalLogger()
+	}
+	return conn.logger
 }
 
 func (conn *Conn) querySQL(query string, maxRetry int) (*sql.Rows, error) {
@@ -113,6 +159,10 @@ func (conn *Conn) executeSQL(sqls []string, args [][]interface{}, maxRetry int)
 		return nil
 	}
 
+	if conn != nil && conn.sqlWriter != nil {
+		return errors.Trace(conn.sqlWriter.Write(sqls, args))
+	}
+
 	if conn == nil || conn.db == nil {
 		return errors.NotValidf("database connection")
 	}
@@ -183,6 +233,19 @@ func (conn *Conn) executeSQLJob(jobs []*job, maxRetry int) *ExecErrorContext {
 		return nil
 	}
 
+	if conn != nil && conn.sqlWriter != nil {
+		sqls := make([]string, 0, len(jobs))
+		args := make([][]interface{}, 0, len(jobs))
+		for _, j := range jobs {
+			sqls = append(sqls, j.sql)
+			args = append(args, j.args)
+		}
+		if err := conn.sqlWriter.Write(sqls, args); err != nil {
+			return &ExecErrorContext{err: errors.Trace(err), pos: jobs[0].currentPos, jobs: fmt.Sprintf("%v", jobs)}
+		}
+		return nil
+	}
+
 	var errCtx *ExecErrorContext
 
 	for i := 0; i < maxRetry; i++ {
@@ -199,6 +262,9 @@ func (conn *Conn) executeSQLJob(jobs []*job, maxRetry int) *ExecErrorContext {
 			}
 			log.Errorf("[exec][sql]%v[error]%v", jobs, err)
 			errCtx.err = errors.Trace(errCtx.err)
+			if conn.cfg.DiagnoseBatchFailure && len(jobs) > 1 {
+				conn.diagnoseJobs(jobs, errCtx)
+			}
 			return errCtx
 		}
 
@@ -209,6 +275,33 @@ func (conn *Conn) executeSQLJob(jobs []*job, maxRetry int) *ExecErrorContext {
 	return errCtx
 }
 
+// diagnoseJobs executes the jobs of a failed batch one at a time, every job in a transaction rolled back,
+// the first job failing is reported in errCtx with its rendered SQL and keys, so the row causing the failure is identified.
+// nothing is changed in the target, but it's slow for a large batch.
+func (conn *Conn) diagnoseJobs(jobs []*job, errCtx *ExecErrorContext) {
+	log.Infof("[exec] diagnose the failed batch of %d jobs", len(jobs))
+	for i, j := range jobs {
+		txn, err := conn.db.Begin()
+		if err != nil {
+			log.Warnf("[exec] begin transaction to diagnose the failed batch error %v", err)
+			return
+		}
+		_, err = txn.Exec(j.sql, j.args...)
+		if rerr := txn.Rollback(); rerr != nil {
+			log.Warnf("[exec] rollback transaction to diagnose the failed batch error %v", rerr)
+		}
+		if err != nil {
+			sql := RenderSQL(j.sql, j.args, nil)
+			log.Errorf("[exec] statement %d of the failed batch fails: %s[keys]%v[error]%v", i, sql, j.keys, err)
+			errCtx.err = errors.Annotatef(errCtx.err, "statement %d of batch failed: %s, keys %v", i, sql, j.keys)
+			errCtx.pos = j.currentPos
+			errCtx.jobs = j.String()
+			return
+		}
+	}
+	log.Warnf("[exec] no statement of the failed batch fails by itself")
+}
+
 func (conn *Conn) executeSQLJobImp(jobs []*job) *ExecErrorContext {
 	startTime := time.Now()
 	defer func() {
@@ -222,10 +315,27 @@ func (conn *Conn) executeSQLJobImp(jobs []*job) *ExecErrorContext {
 		return &ExecErrorContext{err: errors.Trace(err), jobs: fmt.Sprintf("%v", jobs)}
 	}
 
+	var slowJobs []*job // slow UPDATE and DELETE statements explained after committed
 	for i := range jobs {
 		log.Debugf("[exec][checkpoint]%s[sql]%s[args]%v", jobs[i].currentPos, jobs[i].sql, jobs[i].args)
 
-		_, err = txn.Exec(jobs[i].sql, jobs[i].args...)
+		var res sql.Result
+		execTime := time.Now()
+		res, err = txn.Exec(jobs[i].sql, jobs[i].args...)
+		if conn.slowThreshold > 0 {
+			if cost := time.Since(execTime); cost >= conn.slowThreshold {
+				conn.getLogger().Warnf("[exec][checkpoint]%s slow statement takes %v (threshold %v): %s", jobs[i].currentPos, cost, conn.slowThreshold, RenderSQL(jobs[i].sql, jobs[i].args, nil))
+				if conn.cfg.ExplainSlowStatements && (jobs[i].tp == update || jobs[i].tp == del) {
+					slowJobs = append(slowJobs, jobs[i])
+				}
+			}
+		}
+		if err == nil && conn.cfg.UpsertOnMissing {
+			err = conn.handleMissingRow(txn, jobs[i], res)
+		}
+		if err != nil && len(jobs[i].deletes) > 0 {
+			err = conn.handleKeyConflict(txn, jobs[i], err)
+		}
 		if err != nil {
 			log.Warnf("[exec][checkpoint]%s[sql]%s[args]%v[error]%v", jobs[i].currentPos, jobs[i].sql, jobs[i].args, err)
 			rerr := txn.Rollback()
@@ -241,11 +351,149 @@ func (conn *Conn) executeSQLJobImp(jobs []*job) *ExecErrorContext {
 		log.Errorf("exec jobs[%v] commit failed %v", jobs, errors.ErrorStack(err))
 		return &ExecErrorContext{err: errors.Trace(err), pos: jobs[0].currentPos, jobs: fmt.Sprintf("%v", jobs)}
 	}
+	for _, j := range slowJobs {
+		conn.explainSlowStatement(j)
+	}
 	return nil
 }
 
+// explainSlowStatement runs EXPLAIN for the slow statement of the job and logs the plan, it's run outside the transaction
+// to not hold locks longer. the plan scanning the whole table reveals the WHERE clause not using any index, like the one of all columns
+// generated for a row with NULL values in unique keys (see findFitIndex). errors are logged only, as the statement has been executed.
+func (conn *Conn) explainSlowStatement(j *job) {
+	if !strings.HasPrefix(j.sql, "UPDATE ") && !strings.HasPrefix(j.sql, "DELETE ") {
+		// the REPLACE of UPDATE events in safe mode
+		return
+	}
+	logger := conn.getLogger()
+	rows, err := conn.db.Query("EXPLAIN "+j.sql, j.args...)
+	if err != nil {
+		logger.Warnf("[exec] explain slow statement %s error %v", RenderSQL(j.sql, j.args, nil), err)
+		return
+	}
+	defer rows.Close()
+
+	plan, fullScan, err := readExplainRows(rows)
+	if err != nil {
+		logger.Warnf("[exec] explain slow statement %s error %v", RenderSQL(j.sql, j.args, nil), err)
+		return
+	}
+	if fullScan {
+		logger.Warnf("[exec] slow statement uses no index of `%s`.`%s`, plan %s: %s", j.targetSchema, j.targetTable, plan, RenderSQL(j.sql, j.args, nil))
+	} else {
+		logger.Infof("[exec] plan of slow statement %s: %s", RenderSQL(j.sql, j.args, nil), plan)
+	}
+}
+
+// readExplainRows formats the rows of EXPLAIN like `[id=1 type=ALL key=NULL]`, and returns whether the table is fully scanned,
+// like `type` is `ALL` in MySQL or the operator is `TableFullScan` in TiDB.
+func readExplainRows(rows *sql.Rows) (string, bool, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return "", false, errors.Trace(err)
+	}
+	var (
+		plan     []string
+		fullScan bool
+	)
+	for rows.Next() {
+		values := make([]sql.NullString, len(columns))
+		dest := make([]interface{}, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err = rows.Scan(dest...); err != nil {
+			return "", false, errors.Trace(err)
+		}
+
+		fields := make([]string, 0, len(columns))
+		for i, col := range columns {
+			value := "NULL"
+			if values[i].Valid {
+				value = values[i].String
+			}
+			fields = append(fields, fmt.Sprintf("%s=%s", col, value))
+			if (strings.EqualFold(col, "type") && value == "ALL") || (strings.EqualFold(col, "id") && strings.Contains(value, "TableFullScan")) {
+				fullScan = true
+			}
+		}
+		plan = append(plan, "["+strings.Join(fields, " ")+"]")
+	}
+	return strings.Join(plan, ","), fullScan, errors.Trace(rows.Err())
+}
+
+// handleMissingRow handles the UPDATE or DELETE statement matching no row in the target if upsert-on-missing is set,
+// the fallback of the UPDATE statement (the REPLACE of the changed row) is executed in the same transaction,
+// and the DELETE statement (or the UPDATE statement without fallback) is logged and counted only.
+// DELETE statements of UPDATE events in safe mode are not checked, their rows may be deleted already when events are replicated again.
+func (conn *Conn) handleMissingRow(txn *sql.Tx, j *job, res sql.Result) error {
+	if !(j.tp == update && strings.HasPrefix(j.sql, "UPDATE ")) && !(j.tp == del && strings.HasPrefix(j.sql, "DELETE ")) {
+		return nil
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		log.Warnf("[exec] get affected rows of %s error %v", j.sql, err)
+		return nil
+	}
+	if affected > 0 {
+		return nil
+	}
+
+	missingRowsTotal.WithLabelValues(j.tp.String(), conn.cfg.Name).Inc()
+	if j.fallback == nil {
+		log.Warnf("[exec][checkpoint]%s %s matched no row in the target: %s", j.currentPos, j.tp, RenderSQL(j.sql, j.args, nil))
+		return nil
+	}
+	log.Warnf("[exec][checkpoint]%s %s matched no row in the target, replace the changed row: %s", j.currentPos, j.tp, RenderSQL(j.fallback.sql, j.fallback.args, nil))
+	_, err = txn.Exec(j.fallback.sql, j.fallback.args...)
+	return errors.Trace(err)
+}
+
+// handleKeyConflict handles the INSERT ... ON DUPLICATE KEY UPDATE statement failed with a duplicate entry if delete-on-key-conflict is set,
+// the row conflicting on the key named in the error is deleted by its conflict delete, and the statement is executed again in the same transaction,
+// until it succeeds or fails with an error other than a duplicate entry of keys not deleted yet, which is returned.
+func (conn *Conn) handleKeyConflict(txn *sql.Tx, j *job, err error) error {
+	deleted := make(map[string]bool, len(j.deletes))
+	for utils.IsErrDupEntry(err) {
+		key := dupEntryKey(err)
+		del, ok := j.deletes[key]
+		if !ok || deleted[key] {
+			return err
+		}
+		deleted[key] = true
+
+		log.Warnf("[exec][checkpoint]%s %s conflicts with another row on key %s in the target, delete it: %s", j.currentPos, j.tp, key, RenderSQL(del.sql, del.args, nil))
+		if _, err = txn.Exec(del.sql, del.args...); err != nil {
+			return errors.Trace(err)
+		}
+		_, err = txn.Exec(j.sql, j.args...)
+	}
+	return err
+}
+
+// dupEntryRegexp matches the key name in the message of a duplicate entry error, like `Duplicate entry 'a' for key 'uk_name'`
+var dupEntryRegexp = regexp.MustCompile("for key '([^']*)'")
+
+// dupEntryKey returns the lower-case name of the key in the duplicate entry error, or "" if not found.
+// MySQL 8.0 prefixes the name with the table, like `t.uk_name`, which is trimmed.
+func dupEntryKey(err error) string {
+	matches := dupEntryRegexp.FindStringSubmatch(errors.Cause(err).Error())
+	if len(matches) < 2 {
+		return ""
+	}
+	key := matches[1]
+	if i := strings.LastIndex(key, "."); i >= 0 {
+		key = key[i+1:]
+	}
+	return strings.ToLower(key)
+}
+
 func createDB(cfg *config.SubTaskConfig, dbCfg config.DBConfig, timeout string) (*Conn, error) {
 	dbDSN := fmt.Sprintf("%s:%s@tcp(%s:%d)/?charset=utf8&interpolateParams=true&readTimeout=%s", dbCfg.User, dbCfg.Password, dbCfg.Host, dbCfg.Port, timeout)
+	if cfg.UpsertOnMissing {
+		// UPDATE statements setting the same values report the rows matched rather than 0, so they are not taken as missing rows
+		dbDSN += "&clientFoundRows=true"
+	}
 	db, err := sql.Open("mysql", dbDSN)
 	if err != nil {
 		return nil, errors.Trace(err)
@@ -314,7 +562,16 @@ func getTableIndex(db *Conn, table *table, maxRetry int) error {
 		| t     |          0 | ucd      |            2 | d           | A         |           0 |     NULL | NULL   | YES  | BTREE      |         |               |
 		+-------+------------+----------+--------------+-------------+-----------+-------------+----------+--------+------+------------+---------+---------------+
 	*/
+	// MySQL 8.0.13 and later has an extra column `Expression`, `Column_name` is NULL for a functional key part like `(lower(c))`.
+	exprIdx := -1
+	for i, name := range rowColumns {
+		if strings.EqualFold(name, "Expression") {
+			exprIdx = i
+		}
+	}
+
 	var columns = make(map[string][]string)
+	var exprs = make(map[string][]string) // key name -> expressions of functional key parts
 	for rows.Next() {
 		data := make([]sql.RawBytes, len(rowColumns))
 		values := make([]interface{}, len(rowColumns))
@@ -331,13 +588,21 @@ func getTableIndex(db *Conn, table *table, maxRetry int) error {
 		nonUnique := string(data[1])
 		if nonUnique == "0" {
 			keyName := strings.ToLower(string(data[2]))
+			// the name of a functional key part is empty, which is not resolved by findColumns
 			columns[keyName] = append(columns[keyName], string(data[4]))
+			if data[4] == nil && exprIdx >= 0 {
+				exprs[keyName] = append(exprs[keyName], string(data[exprIdx]))
+			}
 		}
 	}
 	if rows.Err() != nil {
 		return errors.Trace(rows.Err())
 	}
 
+	for keyName, keyExprs := range exprs {
+		log.Infof("[syncer] unique index %s of `%s`.`%s` has functional key parts %v, it's not used to identify rows", keyName, table.schema, table.name, keyExprs)
+	}
+
 	table.indexColumns = findColumns(table.columns, columns)
 	return nil
 }
@@ -347,7 +612,7 @@ func getTableColumns(db *Conn, table *table, maxRetry int) error {
 		return errors.New("schema/table is empty")
 	}
 
-	query := fmt.Sprintf("SHOW COLUMNS FROM `%s`.`%s`", table.schema, table.name)
+	query := fmt.Sprintf("SHOW FULL COLUMNS FROM `%s`.`%s`", table.schema, table.name)
 	rows, err := db.querySQL(query, maxRetry)
 	if err != nil {
 		return errors.Trace(err)
@@ -361,15 +626,15 @@ func getTableColumns(db *Conn, table *table, maxRetry int) error {
 
 	// Show an example.
 	/*
-	   mysql> show columns from test.t;
-	   +-------+---------+------+-----+---------+-------+
-	   | Field | Type    | Null | Key | Default | Extra |
-	   +-------+---------+------+-----+---------+-------+
-	   | a     | int(11) | NO   | PRI | NULL    |       |
-	   | b     | int(11) | NO   | PRI | NULL    |       |
-	   | c     | int(11) | YES  | MUL | NULL    |       |
-	   | d     | int(11) | YES  |     | NULL    |       |
-	   +-------+---------+------+-----+---------+-------+
+	   mysql> show full columns from test.t;
+	   +-------+-------------+--------------------+------+-----+---------+-------+---------------------------------+---------+
+	   | Field | Type        | Collation          | Null | Key | Default | Extra | Privileges                      | Comment |
+	   +-------+-------------+--------------------+------+-----+---------+-------+---------------------------------+---------+
+	   | a     | int(11)     | NULL               | NO   | PRI | NULL    |       | select,insert,update,references |         |
+	   | b     | int(11)     | NULL               | NO   | PRI | NULL    |       | select,insert,update,references |         |
+	   | c     | varchar(20) | utf8mb4_general_ci | YES  | MUL | NULL    |       | select,insert,update,references |         |
+	   | d     | int(11)     | NULL               | YES  |     | NULL    |       | select,insert,update,references |         |
+	   +-------+-------------+--------------------+------+-----+---------+-------+---------------------------------+---------+
 	*/
 
 	idx := 0
@@ -390,8 +655,9 @@ func getTableColumns(db *Conn, table *table, maxRetry int) error {
 		column.idx = idx
 		column.name = string(data[0])
 		column.tp = string(data[1])
+		column.collation = string(data[2])
 
-		if strings.ToLower(string(data[2])) == "no" {
+		if strings.ToLower(string(data[3])) == "no" {
 			column.NotNull = true
 		}
 
@@ -400,6 +666,22 @@ func getTableColumns(db *Conn, table *table, maxRetry int) error {
 			column.unsigned = true
 		}
 
+		initColumnType(column)
+
+		// Check whether column is a generated column, `VIRTUAL GENERATED` or `STORED GENERATED` in `Extra`.
+		// `DEFAULT_GENERATED` in `Extra` means DEFAULT is an expression in MySQL 8.0.
+		extra := strings.ToLower(string(data[6]))
+		column.autoIncrement = strings.Contains(extra, "auto_increment")
+		if strings.Contains(extra, "default_generated") {
+			column.defaultExpr = true
+		} else if strings.Contains(extra, "generated") {
+			column.IsGenerated = true
+		}
+		if data[5] != nil {
+			column.defaultValue = string(data[5])
+			column.defaultExpr = column.defaultExpr || isDefaultExpr(string(data[5]))
+		}
+
 		table.columns = append(table.columns, column)
 		idx++
 	}
@@ -411,6 +693,19 @@ func getTableColumns(db *Conn, table *table, maxRetry int) error {
 	return nil
 }
 
+// initColumnType sets the attributes of the column derived from its type, like elements of ENUM and precision of DECIMAL.
+func initColumnType(column *column) {
+	column.elems = parseEnumSetElems(column.tp)
+	if isTimestampColumn(column) {
+		column.fsp = parseFsp(column.tp)
+	}
+	column.bitWidth = parseBitWidth(column.tp)
+	column.precision, column.scale = parseDecimal(column.tp)
+	column.binary = isBinaryType(column.tp)
+	column.geometry = isGeometryType(column.tp)
+	column.bindExpr = defaultBindExpr(column)
+}
+
 func countBinaryLogsSize(fromFile mysql.Position, db *sql.DB) (int64, error) {
 	files, err := getBinaryLogs(db)
 	if err != nil {
@@ -14,21 +14,490 @@
 package syncer
 
 import (
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
 	. "github.com/pingcap/check"
-	gouuid "github.com/satori/go.uuid"
+	"github.com/pingcap/errors"
+	tmysql "github.com/pingcap/parser/mysql"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	gmysql "github.com/siddontang/go-mysql/mysql"
+	"golang.org/x/net/context"
 
+	"github.com/pingcap/dm/dm/config"
 	"github.com/pingcap/dm/pkg/utils"
 )
 
-func (s *testSyncerSuite) TestGetServerUUID(c *C) {
-	uuid, err := utils.GetServerUUID(s.db, "mysql")
+var _ = Suite(&testDBSuite{})
+
+type testDBSuite struct{}
+
+// mockDriver is a database/sql driver, whose statements containing poison fail with err (at most poisonTimes times if it's positive),
+// statements containing noRows affect no rows, and statements containing slow take delay to execute.
+// queries return explainColumns and explainRows.
+type mockDriver struct {
+	sync.Mutex
+	poison         string
+	poisonTimes    int
+	err            error
+	noRows         string
+	slow           string
+	delay          time.Duration
+	executed       []string // sqls of committed transactions
+	rollbacks      int
+	queries        []string
+	explainColumns []string
+	explainRows    [][]driver.Value
+}
+
+func (d *mockDriver) Open(name string) (driver.Conn, error) { return &mockConn{d: d}, nil }
+
+type mockConn struct {
+	d   *mockDriver
+	txn []string
+}
+
+func (c *mockConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.NotSupportedf("prepare")
+}
+func (c *mockConn) Close() error              { return nil }
+func (c *mockConn) Begin() (driver.Tx, error) { c.txn = c.txn[:0]; return c, nil }
+
+func (c *mockConn) Exec(query string, args []driver.Value) (driver.Result, error) {
+	c.d.Lock()
+	defer c.d.Unlock()
+	if c.d.poison != "" && strings.Contains(query, c.d.poison) {
+		if c.d.poisonTimes <= 0 {
+			return nil, c.d.err
+		}
+		c.d.poisonTimes--
+		if c.d.poisonTimes == 0 {
+			c.d.poison = ""
+		}
+		return nil, c.d.err
+	}
+	if c.d.slow != "" && strings.Contains(query, c.d.slow) {
+		time.Sleep(c.d.delay)
+	}
+	c.txn = append(c.txn, query)
+	if c.d.noRows != "" && strings.Contains(query, c.d.noRows) {
+		return driver.RowsAffected(0), nil
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (c *mockConn) Query(query string, args []driver.Value) (driver.Rows, error) {
+	c.d.Lock()
+	defer c.d.Unlock()
+	c.d.queries = append(c.d.queries, query)
+	return &mockRows{columns: c.d.explainColumns, rows: c.d.explainRows}, nil
+}
+
+type mockRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *mockRows) Columns() []string { return r.columns }
+func (r *mockRows) Close() error      { return nil }
+
+func (r *mockRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+func (c *mockConn) Commit() error {
+	c.d.Lock()
+	defer c.d.Unlock()
+	c.d.executed = append(c.d.executed, c.txn...)
+	return nil
+}
+
+func (c *mockConn) Rollback() error {
+	c.d.Lock()
+	defer c.d.Unlock()
+	c.d.rollbacks++
+	return nil
+}
+
+var mockDrv = &mockDriver{}
+
+func init() {
+	sql.Register("syncer-mock", mockDrv)
+}
+
+func (t *testDBSuite) TestDryRun(c *C) {
+	file := filepath.Join(c.MkDir(), "dry-run.sql")
+	w, err := utils.NewSQLWriter(file)
+	c.Assert(err, IsNil)
+	// no DB connection, any execution fails
+	conn := &Conn{cfg: &config.SubTaskConfig{Name: "test-dry-run"}, sqlWriter: w}
+
+	columns := []*column{
+		{idx: 0, name: "id", NotNull: true, tp: "int(11)"},
+		{idx: 1, name: "name", tp: "varchar(20)"},
+	}
+	indexColumns := map[string][]*column{"primary": {columns[0]}}
+	dataSeq := [][]interface{}{{int32(1), "a"}, {int32(2), "b"}, {int32(3), "c"}}
+	sqls, _, values, err := genInsertSQLs("db", "tbl", dataSeq, columns, indexColumns, 1, config.ConflictReplace, testDMLOptions)
+	c.Assert(err, IsNil)
+	c.Assert(sqls, HasLen, 3)
+
+	pos := gmysql.Position{Name: "mysql-bin.000001", Pos: 4}
+	jobs := make([]*job, 0, len(sqls))
+	for i := range sqls {
+		jobs = append(jobs, newJob(insert, "db", "tbl", "db", "tbl", sqls[i], values[i], "", pos, pos, nil))
+	}
+	c.Assert(conn.executeSQLJob(jobs, 1), IsNil)
+	c.Assert(conn.executeSQL([]string{"ALTER TABLE `db`.`tbl` ADD COLUMN `c` INT"}, [][]interface{}{nil}, 1), IsNil)
+	c.Assert(w.Count(), Equals, 4)
+	c.Assert(w.Close(), IsNil)
+
+	data, err := ioutil.ReadFile(file)
+	c.Assert(err, IsNil)
+	c.Assert(strings.Count(string(data), "BEGIN;\n"), Equals, 2)
+	c.Assert(string(data), Matches, "(?s)BEGIN;\nREPLACE INTO `db`.`tbl` .* -- args: \\[1 a\\]\n.*COMMIT;\nBEGIN;\nALTER TABLE `db`.`tbl` ADD COLUMN `c` INT;\nCOMMIT;\n")
+
+	// fails without the writer
+	conn.sqlWriter = nil
+	c.Assert(conn.executeSQL(sqls[:1], values[:1], 1), ErrorMatches, ".*database connection not valid.*")
+}
+
+func (t *testDBSuite) TestNormalizeIdentifiers(c *C) {
+	newTable := func() *table {
+		columns := []*column{
+			{idx: 0, name: "ID", NotNull: true, tp: "int(11)"},
+			{idx: 1, name: "Name", tp: "varchar(20)"},
+		}
+		return &table{schema: "Shop", name: "Orders", columns: columns, indexColumns: map[string][]*column{"primary": {columns[0]}}}
+	}
+	dataSeq := [][]interface{}{{int32(1), "a"}}
+	updateSeq := [][]interface{}{{int32(1), "a"}, {int32(1), "b"}}
+
+	cases := []struct {
+		identifierCase string
+		insert         string
+		update         string
+		del            string
+	}{
+		{
+			identifierCase: config.IdentifierCasePreserve,
+			insert:         "REPLACE INTO `Shop`.`Orders` (`ID`,`Name`) VALUES (?,?);",
+			update:         "UPDATE `Shop`.`Orders` SET `Name` = ? WHERE `ID` = ? LIMIT 1;",
+			del:            "DELETE FROM `Shop`.`Orders` WHERE `ID` = ?;",
+		},
+		{
+			identifierCase: config.IdentifierCaseLower,
+			insert:         "REPLACE INTO `shop`.`orders` (`id`,`name`) VALUES (?,?);",
+			update:         "UPDATE `shop`.`orders` SET `name` = ? WHERE `id` = ? LIMIT 1;",
+			del:            "DELETE FROM `shop`.`orders` WHERE `id` = ?;",
+		},
+	}
+	for _, cs := range cases {
+		tbl := newTable()
+		normalizeIdentifiers(tbl, cs.identifierCase)
+		c.Assert(tbl.indexColumns["primary"][0], Equals, tbl.columns[0])
+
+		sqls, keys, _, err := genInsertSQLs(tbl.schema, tbl.name, dataSeq, tbl.columns, tbl.indexColumns, 1, config.ConflictReplace, testDMLOptions)
+		c.Assert(err, IsNil)
+		c.Assert(sqls, DeepEquals, []string{cs.insert})
+		// keys are the same no matter which case the names are in, so conflicts are detected consistently
+		c.Assert(keys, DeepEquals, [][]string{{"1"}})
+
+		sqls, keys, _, err = genUpdateSQLs(tbl.schema, tbl.name, updateSeq, tbl.columns, tbl.indexColumns, nil, false, testDMLOptions)
+		c.Assert(err, IsNil)
+		c.Assert(sqls, DeepEquals, []string{cs.update})
+		c.Assert(keys, DeepEquals, [][]string{{"1", "1"}})
+
+		sqls, keys, _, err = genDeleteSQLs(tbl.schema, tbl.name, dataSeq, tbl.columns, tbl.indexColumns, testDMLOptions)
+		c.Assert(err, IsNil)
+		c.Assert(sqls, DeepEquals, []string{cs.del})
+		c.Assert(keys, DeepEquals, [][]string{{"1"}})
+	}
+}
+
+func (t *testDBSuite) TestDiagnoseBatchFailure(c *C) {
+	db, err := sql.Open("syncer-mock", "")
 	c.Assert(err, IsNil)
-	_, err = gouuid.FromString(uuid)
+	defer db.Close()
+	cfg := &config.SubTaskConfig{Name: "test-diagnose"}
+	conn := &Conn{cfg: cfg, db: db}
+
+	pos := gmysql.Position{Name: "mysql-bin.000001", Pos: 4}
+	var jobs []*job
+	for i, name := range []string{"a", "b", "c"} {
+		pos.Pos += 10
+		j := newJob(insert, "db", "tbl", "db", "tbl", "INSERT INTO `db`.`tbl` (`id`,`name`) VALUES (?,?);", []interface{}{int32(i + 1), name}, "", pos, pos, nil)
+		j.keys = []string{strconv.Itoa(i + 1)}
+		jobs = append(jobs, j)
+	}
+	// the second job fails, and the batch is rolled back
+	jobs[1].sql = "INSERT INTO `db`.`tbl` (`id`,`name`) VALUES (?,?) /* poison */;"
+	mockDrv.poison = "poison"
+	mockDrv.err = &mysql.MySQLError{Number: tmysql.ErrDataTooLong, Message: "Data too long for column 'name' at row 1"}
+	defer func() {
+		mockDrv.poison, mockDrv.err, mockDrv.executed, mockDrv.rollbacks = "", nil, nil, 0
+	}()
+
+	// the failing job isn't identified if not diagnosed
+	errCtx := conn.executeSQLJob(jobs, 1)
+	c.Assert(errCtx, NotNil)
+	c.Assert(errCtx.err, ErrorMatches, ".*Data too long.*")
+	c.Assert(errCtx.err.Error(), Not(Matches), ".*statement 1.*")
+	c.Assert(mockDrv.rollbacks, Equals, 1)
+
+	// every job is executed in a transaction rolled back
+	cfg.DiagnoseBatchFailure = true
+	errCtx = conn.executeSQLJob(jobs, 1)
+	c.Assert(errCtx, NotNil)
+	c.Assert(errCtx.err.Error(), Equals, "statement 1 of batch failed: "+
+		"INSERT INTO `db`.`tbl` (`id`,`name`) VALUES (2,'b') /* poison */;, keys [2]: Error 1406: Data too long for column 'name' at row 1")
+	c.Assert(errCtx.pos, DeepEquals, jobs[1].currentPos)
+	c.Assert(mockDrv.rollbacks, Equals, 1+1+2)
+	c.Assert(mockDrv.executed, HasLen, 0)
+}
+
+func (t *testDBSuite) TestUpsertOnMissing(c *C) {
+	db, err := sql.Open("syncer-mock", "")
 	c.Assert(err, IsNil)
+	defer db.Close()
+	cfg := &config.SubTaskConfig{Name: "test-upsert-on-missing", UpsertOnMissing: true}
+	conn := &Conn{cfg: cfg, db: db}
+	missing := func(tp opType) float64 {
+		return testutil.ToFloat64(missingRowsTotal.WithLabelValues(tp.String(), cfg.Name))
+	}
+
+	columns := []*column{
+		{idx: 0, name: "id", NotNull: true, tp: "int(11)"},
+		{idx: 1, name: "name", tp: "varchar(20)"},
+	}
+	indexColumns := map[string][]*column{"primary": {columns[0]}}
+	opts := &dmlOptions{keyGen: testDMLOptions.keyGen, upsertOnMissing: true}
+	sqls, _, values, fallbacks, err := genUpdateSQLsWithFallbacks("db", "tbl", [][]interface{}{{int32(1), "a"}, {int32(1), "b"}}, columns, indexColumns, nil, false, opts)
+	c.Assert(err, IsNil)
+	c.Assert(sqls, DeepEquals, []string{"UPDATE `db`.`tbl` SET `name` = ? WHERE `id` = ? LIMIT 1;"})
+	c.Assert(fallbacks, DeepEquals, []*fallbackStmt{{sql: "REPLACE INTO `db`.`tbl` (`id`,`name`) VALUES (?,?);", args: []interface{}{int32(1), "b"}}})
+
+	pos := gmysql.Position{Name: "mysql-bin.000001", Pos: 4}
+	updateJob := newJob(update, "db", "tbl", "db", "tbl", sqls[0], values[0], "", pos, pos, nil)
+	updateJob.fallback = fallbacks[0]
+	deleteJob := newJob(del, "db", "tbl", "db", "tbl", "DELETE FROM `db`.`tbl` WHERE `id` = ?;", []interface{}{int32(2)}, "", pos, pos, nil)
+	defer func() {
+		mockDrv.noRows, mockDrv.executed = "", nil
+	}()
+
+	// the target rows are missing, the UPDATE is converted to the REPLACE of the changed row, and the DELETE is counted
+	mockDrv.noRows = "WHERE"
+	c.Assert(conn.executeSQLJob([]*job{updateJob, deleteJob}, 1), IsNil)
+	c.Assert(mockDrv.executed, DeepEquals, []string{sqls[0], fallbacks[0].sql, deleteJob.sql})
+	c.Assert(missing(update), Equals, float64(1))
+	c.Assert(missing(del), Equals, float64(1))
+
+	// the target rows exist
+	mockDrv.noRows, mockDrv.executed = "", nil
+	c.Assert(conn.executeSQLJob([]*job{updateJob, deleteJob}, 1), IsNil)
+	c.Assert(mockDrv.executed, DeepEquals, []string{sqls[0], deleteJob.sql})
+	c.Assert(missing(update), Equals, float64(1))
+
+	// not checked if it's not set
+	cfg.UpsertOnMissing = false
+	mockDrv.noRows, mockDrv.executed = "WHERE", nil
+	c.Assert(conn.executeSQLJob([]*job{updateJob, deleteJob}, 1), IsNil)
+	c.Assert(mockDrv.executed, DeepEquals, []string{sqls[0], deleteJob.sql})
+	c.Assert(missing(update), Equals, float64(1))
+	c.Assert(missing(del), Equals, float64(1))
+
+	// no fallbacks for statements in safe mode and rows of partial images
+	_, _, _, fallbacks, err = genUpdateSQLsWithFallbacks("db", "tbl", [][]interface{}{{int32(1), "a"}, {int32(1), "b"}}, columns, indexColumns, nil, true, opts)
+	c.Assert(err, IsNil)
+	c.Assert(fallbacks, DeepEquals, []*fallbackStmt{nil, nil})
+	images := newRowImages([]byte{0x1}, []byte{0x3}, 2)
+	sqls, _, _, fallbacks, err = genUpdateSQLsWithFallbacks("db", "tbl", [][]interface{}{{int32(1), nil}, {int32(1), "b"}}, columns, indexColumns, images, false, opts)
+	c.Assert(err, IsNil)
+	c.Assert(sqls, HasLen, 1)
+	c.Assert(fallbacks, DeepEquals, []*fallbackStmt{nil})
+	opts.upsertOnMissing = false
+	_, _, _, fallbacks, err = genUpdateSQLsWithFallbacks("db", "tbl", [][]interface{}{{int32(1), "a"}, {int32(1), "b"}}, columns, indexColumns, nil, false, opts)
+	c.Assert(err, IsNil)
+	c.Assert(fallbacks, IsNil)
 }
 
-func (s *testSyncerSuite) TestGetServerID(c *C) {
-	id, err := utils.GetServerID(s.db)
+func (t *testDBSuite) TestDeleteOnKeyConflict(c *C) {
+	db, err := sql.Open("syncer-mock", "")
+	c.Assert(err, IsNil)
+	defer db.Close()
+	cfg := &config.SubTaskConfig{Name: "test-delete-on-key-conflict", DeleteOnKeyConflict: true}
+	conn := &Conn{cfg: cfg, db: db}
+
+	// two unique keys
+	columns := []*column{
+		{idx: 0, name: "id", NotNull: true, tp: "int(11)"},
+		{idx: 1, name: "email", tp: "varchar(64)"},
+		{idx: 2, name: "name", tp: "varchar(20)"},
+	}
+	indexColumns := map[string][]*column{"primary": {columns[0]}, "uk_email": {columns[1]}}
+	opts := &dmlOptions{keyGen: testDMLOptions.keyGen, deleteOnKeyConflict: true}
+	sqls, _, values, deletes, err := genInsertSQLsWithConflictDeletes("db", "tbl", [][]interface{}{{int32(1), "a@pingcap.com", "a"}}, columns, indexColumns, 1, config.ConflictOnDuplicate, opts)
+	c.Assert(err, IsNil)
+	c.Assert(sqls, DeepEquals, []string{"INSERT INTO `db`.`tbl` (`id`,`email`,`name`) VALUES (?,?,?) ON DUPLICATE KEY UPDATE `id`=VALUES(`id`),`email`=VALUES(`email`),`name`=VALUES(`name`);"})
+	c.Assert(deletes, DeepEquals, []conflictDeletes{{
+		"primary":  {sql: "DELETE FROM `db`.`tbl` WHERE `id` = ?;", args: []interface{}{int32(1)}},
+		"uk_email": {sql: "DELETE FROM `db`.`tbl` WHERE `email` = ?;", args: []interface{}{"a@pingcap.com"}},
+	}})
+
+	pos := gmysql.Position{Name: "mysql-bin.000001", Pos: 4}
+	insertJob := newJob(insert, "db", "tbl", "db", "tbl", sqls[0], values[0], "", pos, pos, nil)
+	insertJob.deletes = deletes[0]
+	defer func() {
+		mockDrv.poison, mockDrv.poisonTimes, mockDrv.err, mockDrv.executed, mockDrv.rollbacks = "", 0, nil, nil, 0
+	}()
+
+	// the row conflicts with another row on the second unique key, which is deleted before the INSERT executed again
+	mockDrv.poison, mockDrv.poisonTimes = "ON DUPLICATE", 1
+	mockDrv.err = &mysql.MySQLError{Number: tmysql.ErrDupEntry, Message: "Duplicate entry 'a@pingcap.com' for key 'tbl.uk_email'"}
+	c.Assert(conn.executeSQLJob([]*job{insertJob}, 1), IsNil)
+	c.Assert(mockDrv.executed, DeepEquals, []string{deletes[0]["uk_email"].sql, sqls[0]})
+	c.Assert(mockDrv.rollbacks, Equals, 0)
+
+	// the conflict persists after deleted
+	mockDrv.poison, mockDrv.poisonTimes, mockDrv.executed = "ON DUPLICATE", 0, nil
+	errCtx := conn.executeSQLJob([]*job{insertJob}, 1)
+	c.Assert(errCtx, NotNil)
+	c.Assert(errCtx.err, ErrorMatches, ".*Duplicate entry.*")
+	c.Assert(mockDrv.executed, HasLen, 0)
+	c.Assert(mockDrv.rollbacks, Equals, 1)
+
+	// other errors are returned as before
+	mockDrv.poisonTimes = 1
+	mockDrv.err = &mysql.MySQLError{Number: tmysql.ErrDataTooLong, Message: "Data too long for column 'name' at row 1"}
+	errCtx = conn.executeSQLJob([]*job{insertJob}, 1)
+	c.Assert(errCtx, NotNil)
+	c.Assert(errCtx.err, ErrorMatches, ".*Data too long.*")
+
+	// no conflict deletes for other strategies, multi-row statements or if it's not set
+	_, _, _, deletes, err = genInsertSQLsWithConflictDeletes("db", "tbl", [][]interface{}{{int32(1), "a@pingcap.com", "a"}}, columns, indexColumns, 1, config.ConflictReplace, opts)
+	c.Assert(err, IsNil)
+	c.Assert(deletes, IsNil)
+	_, _, _, deletes, err = genInsertSQLsWithConflictDeletes("db", "tbl", [][]interface{}{{int32(1), "a@pingcap.com", "a"}, {int32(2), "b@pingcap.com", "b"}}, columns, indexColumns, 2, config.ConflictOnDuplicate, opts)
+	c.Assert(err, IsNil)
+	c.Assert(deletes, DeepEquals, []conflictDeletes{nil})
+	opts.deleteOnKeyConflict = false
+	_, _, _, deletes, err = genInsertSQLsWithConflictDeletes("db", "tbl", [][]interface{}{{int32(1), "a@pingcap.com", "a"}}, columns, indexColumns, 1, config.ConflictOnDuplicate, opts)
+	c.Assert(err, IsNil)
+	c.Assert(deletes, IsNil)
+
+	c.Assert(dupEntryKey(&mysql.MySQLError{Number: tmysql.ErrDupEntry, Message: "Duplicate entry '1' for key 'PRIMARY'"}), Equals, "primary")
+	c.Assert(dupEntryKey(errors.New("unknown")), Equals, "")
+}
+
+func (t *testDBSuite) TestSlowStatement(c *C) {
+	db, err := sql.Open("syncer-mock", "")
+	c.Assert(err, IsNil)
+	defer db.Close()
+	logger := &capturingLogger{}
+	cfg := &config.SubTaskConfig{Name: "test-slow-statement", SyncerConfig: config.SyncerConfig{ExplainSlowStatements: true}}
+	conn := &Conn{cfg: cfg, db: db, slowThreshold: 20 * time.Millisecond, logger: logger}
+	defer func() {
+		mockDrv.slow, mockDrv.delay, mockDrv.executed, mockDrv.queries, mockDrv.explainColumns, mockDrv.explainRows = "", 0, nil, nil, nil, nil
+	}()
+
+	// the DELETE of a row with NULL values in the unique key matches all columns, which uses no index
+	pos := gmysql.Position{Name: "mysql-bin.000001", Pos: 4}
+	deleteSQL := "DELETE FROM `db`.`tbl` WHERE `id` = ? AND `email` IS ? LIMIT 1;"
+	deleteJob := newJob(del, "db", "tbl", "db", "tbl", deleteSQL, []interface{}{int32(1), nil}, "", pos, pos, nil)
+	insertSQL := "INSERT INTO `db`.`tbl` (`id`,`email`) VALUES (?,?);"
+	insertJob := newJob(insert, "db", "tbl", "db", "tbl", insertSQL, []interface{}{int32(2), "b@pingcap.com"}, "", pos, pos, nil)
+	mockDrv.slow, mockDrv.delay = "DELETE", 30*time.Millisecond
+	mockDrv.explainColumns = []string{"id", "select_type", "table", "type", "possible_keys", "key", "rows"}
+	mockDrv.explainRows = [][]driver.Value{{int64(1), "DELETE", "tbl", "ALL", nil, nil, int64(1000)}}
+	c.Assert(conn.executeSQLJob([]*job{deleteJob, insertJob}, 1), IsNil)
+	c.Assert(mockDrv.executed, DeepEquals, []string{deleteSQL, insertSQL})
+
+	// only the slow statement is logged with its timing, and explained after committed
+	c.Assert(logger.entries, HasLen, 2)
+	c.Assert(logger.entries[0], Matches, `\[warn\] .* slow statement takes [0-9.]+ms \(threshold 20ms\): DELETE FROM `+"`db`.`tbl` WHERE `id` = 1 AND `email` IS NULL LIMIT 1;")
+	c.Assert(mockDrv.queries, DeepEquals, []string{"EXPLAIN " + deleteSQL})
+	c.Assert(logger.entries[1], Matches, `\[warn\] .* uses no index of `+"`db`.`tbl`"+`, plan \[id=1 select_type=DELETE table=tbl type=ALL possible_keys=NULL key=NULL rows=1000\]: DELETE .*`)
+
+	// INSERT statements are not explained
+	logger.entries, mockDrv.queries = nil, nil
+	mockDrv.slow = "INSERT"
+	c.Assert(conn.executeSQLJob([]*job{insertJob}, 1), IsNil)
+	c.Assert(logger.entries, HasLen, 1)
+	c.Assert(logger.entries[0], Matches, `\[warn\] .* slow statement takes .*: INSERT INTO .*`)
+	c.Assert(mockDrv.queries, HasLen, 0)
+
+	// not logged without threshold
+	logger.entries = nil
+	conn.slowThreshold = 0
+	c.Assert(conn.executeSQLJob([]*job{insertJob}, 1), IsNil)
+	c.Assert(logger.entries, HasLen, 0)
+}
+
+func (t *testDBSuite) TestKeepTransaction(c *C) {
+	file := filepath.Join(c.MkDir(), "dry-run.sql")
+	w, err := utils.NewSQLWriter(file)
+	c.Assert(err, IsNil)
+
+	cfg := &config.SubTaskConfig{Name: "test-keep-transaction", WorkerCount: 2, Batch: 100, MaxRetry: 1, KeepTransaction: true}
+	s := NewSyncer(cfg)
+	for i := 0; i < cfg.WorkerCount; i++ {
+		s.toDBs = append(s.toDBs, &Conn{cfg: cfg, sqlWriter: w})
+		if len(queueBucketMapping) <= i {
+			queueBucketMapping = append(queueBucketMapping, queueBucketName(i))
+		}
+	}
+	// no checkpoint flushed when dispatching jobs
+	s.checkpoint.(*RemoteCheckPoint).globalPointSaveTime = time.Now()
+	s.newJobChans(cfg.WorkerCount + 1)
+	for i := 0; i < cfg.WorkerCount; i++ {
+		s.wg.Add(1)
+		go s.sync(context.Background(), queueBucketMapping[i], s.toDBs[i], s.jobs[i])
+	}
+
+	pos := gmysql.Position{Name: "mysql-bin.000001", Pos: 4}
+	dml := func(sql string, keys ...string) {
+		pos.Pos += 10
+		c.Assert(s.commitJob(insert, "db", "tbl", "db", "tbl", sql, nil, keys, nil, nil, true, pos, pos, nil), IsNil)
+	}
+	// two source transactions, the second one depends on the first one
+	dml("INSERT INTO `db`.`tbl` VALUES (1)", "1")
+	dml("INSERT INTO `db`.`tbl` VALUES (2)", "2")
+	c.Assert(s.addJob(newXIDJob(pos, pos, nil)), IsNil)
+	dml("UPDATE `db`.`tbl` SET `id` = 3 WHERE `id` = 2", "2", "3")
+	dml("INSERT INTO `db`.`tbl` VALUES (4)", "4")
+	dml("INSERT INTO `db`.`tbl` VALUES (5)", "5")
+	c.Assert(s.addJob(newXIDJob(pos, pos, nil)), IsNil)
+	// not ended
+	dml("INSERT INTO `db`.`tbl` VALUES (6)", "6")
+
+	s.jobWg.Wait()
+	s.closeJobChans()
+	s.wg.Wait()
+	c.Assert(s.txnJobs, HasLen, 1)
+	c.Assert(w.Close(), IsNil)
+
+	data, err := ioutil.ReadFile(file)
 	c.Assert(err, IsNil)
-	c.Assert(id, Greater, int64(0))
+	c.Assert(string(data), Equals, "BEGIN;\n"+
+		"INSERT INTO `db`.`tbl` VALUES (1);\n"+
+		"INSERT INTO `db`.`tbl` VALUES (2);\n"+
+		"COMMIT;\n"+
+		"BEGIN;\n"+
+		"UPDATE `db`.`tbl` SET `id` = 3 WHERE `id` = 2;\n"+
+		"INSERT INTO `db`.`tbl` VALUES (4);\n"+
+		"INSERT INTO `db`.`tbl` VALUES (5);\n"+
+		"COMMIT;\n")
 }
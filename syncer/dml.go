@@ -15,94 +15,428 @@ package syncer
 
 import (
 	"bytes"
-	"encoding/binary"
 	"fmt"
+	"reflect"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/pingcap/dm/dm/config"
 	"github.com/pingcap/dm/pkg/log"
 	"github.com/pingcap/errors"
+	"github.com/pingcap/tidb-tools/pkg/dbutil"
 )
 
-func genInsertSQLs(schema string, table string, dataSeq [][]interface{}, columns []*column, indexColumns map[string][]*column) ([]string, [][]string, [][]interface{}, error) {
+// maxDMLPacketSize is the estimated size limit of a batched DML statement if it's not specified,
+// it keeps the same as the default `maxAllowedPacket` of go-sql-driver/mysql.
+var maxDMLPacketSize = 4 << 20
+
+// defaultMaxStatementSize returns the size limit of batched DML statements for the `max_allowed_packet` of the target.
+// a quarter is left for the overhead not estimated, like escaping of values and the statement itself.
+func defaultMaxStatementSize(maxAllowedPacket int) int {
+	if maxAllowedPacket <= 0 || maxAllowedPacket > maxDMLPacketSize {
+		maxAllowedPacket = maxDMLPacketSize
+	}
+	return maxAllowedPacket / 4 * 3
+}
+
+// dmlOptions holds the options of syncer used when generating DML statements
+type dmlOptions struct {
+	keyGen   KeyGenerator   // generates keys of rows for conflict detection
+	timezone *time.Location // target time zone of TIMESTAMP values, nil means values are bound as they are
+	// update all rows matched by the full-column WHERE rather than one of them, see genUpdateSQLs
+	updateAllDuplicates bool
+	// max rows changed by an UPDATE or DELETE statement matching a row without unique index, see limitClause
+	rowLimit int
+	// name of the unique index (in lower case) preferred to identify rows of UPDATE and DELETE events, empty if none
+	preferredIndex string
+	// names of columns (in lower case) ignored when detecting changes of rows of UPDATE events, see isChangeIgnored
+	changeIgnoredColumns map[string]struct{}
+	// fill the trailing columns missing in inserted rows with their DEFAULT values, see fillMissingColumns
+	fillMissingColumns bool
+	// convert zero dates of nullable date and time columns to NULL, see castZeroDate
+	zeroDateToNull bool
+	// reject rows with NULL values for NOT NULL columns before generating INSERT statements, see checkNotNullColumns
+	strictNotNull bool
+	// estimated size limit of a batched statement, maxDMLPacketSize is used if it's 0
+	maxStatementSize int
+	// `max_allowed_packet` of the target, rows are not checked against it if it's 0, see checkRowSize
+	maxPacketSize int
+	// generate REPLACE statements of changed rows executed if UPDATE statements match no row, see genUpdateSQLsWithFallbacks
+	upsertOnMissing bool
+	// generate DELETE statements of rows conflicting on unique keys for ON DUPLICATE KEY UPDATE statements, see genInsertSQLsWithConflictDeletes
+	deleteOnKeyConflict bool
+	logger              log.Logger               // the global logger is used if it's nil
+	casts               map[string]CastFunc      // source column type -> cast function, see RegisterCastFunc
+	partitions          map[string]PartitionFunc // target table -> partition function, see RegisterPartitionFunc
+	stmtCache           *statementCache          // caches templates of statements, nil means not cached
+}
+
+// limitClause returns the LIMIT clause of UPDATE and DELETE statements matching a row without unique index,
+// rowLimit 0 means `LIMIT 1`, and a negative one (config.NoRowLimit) means no LIMIT clause.
+func (o *dmlOptions) limitClause() string {
+	switch {
+	case o.rowLimit < 0:
+		return ""
+	case o.rowLimit == 0:
+		return " LIMIT 1"
+	default:
+		return fmt.Sprintf(" LIMIT %d", o.rowLimit)
+	}
+}
+
+// getLogger returns the logger of opts, or the global logger if not specified
+func (o *dmlOptions) getLogger() log.Logger {
+	if o.logger == nil {
+		return log.GlobalLogger()
+	}
+	return o.logger
+}
+
+// statementSizeLimit returns the estimated size limit of a batched statement
+func (o *dmlOptions) statementSizeLimit() int {
+	if o.maxStatementSize > 0 {
+		return o.maxStatementSize
+	}
+	return maxDMLPacketSize
+}
+
+// packetSizeLimit returns the size limit of a statement sent to the target, 0 if it's unknown.
+// it's the `max_allowed_packet` of the target, but no more than maxDMLPacketSize, which is the limit of the driver.
+func (o *dmlOptions) packetSizeLimit() int {
+	if o.maxPacketSize > maxDMLPacketSize {
+		return maxDMLPacketSize
+	}
+	return o.maxPacketSize
+}
+
+// checkRowSize checks the estimated size of a row against the size limits of opts,
+// it returns whether the row is too large to be batched with other rows, then it's sent by its own statement.
+// the row can't be sent even by its own statement if it exceeds the packet size limit,
+// then an error naming the largest value is returned rather than an opaque packet error from the target.
+func checkRowSize(schema, table string, columns []*column, value []interface{}, size int, opts *dmlOptions) (bool, error) {
+	if size <= opts.statementSizeLimit() {
+		return false, nil
+	}
+	if limit := opts.packetSizeLimit(); limit > 0 && size > limit {
+		largest, largestSize := "", -1
+		for i := range value {
+			if n := len(columnValue(value[i], columns[i].unsigned, columns[i].tp)); n > largestSize {
+				largest, largestSize = columns[i].name, n
+			}
+		}
+		return true, errors.NotSupportedf("row of table `%s`.`%s` of about %d bytes exceeding the packet size limit %d of the target, with %d bytes of column `%s`", schema, table, size, limit, largestSize, largest)
+	}
+	return true, nil
+}
+
+// genInsertSQLs generates INSERT statements for dataSeq, conflicts are resolved according to strategy.
+// if batch > 1, at most batch consecutive rows are coalesced into one multi-row statement,
+// the values of them are flattened and the keys of them are merged.
+// a new statement is started before the estimated size of the batch exceeds the limit of opts,
+// and a row larger than the limit is inserted by its own statement, see checkRowSize.
+// rows shorter than columns are rejected unless opts.fillMissingColumns is set.
+// rows are grouped by their partitions before coalesced if a partition function is registered for the table.
+func genInsertSQLs(schema string, table string, dataSeq [][]interface{}, columns []*column, indexColumns map[string][]*column, batch int, strategy string, opts *dmlOptions) ([]string, [][]string, [][]interface{}, error) {
+	sqls, keys, values, _, err := genInsertSQLsWithConflictDeletes(schema, table, dataSeq, columns, indexColumns, batch, strategy, opts)
+	return sqls, keys, values, err
+}
+
+// conflictDeletes are DELETE statements of the rows conflicting with an inserted row on its unique keys,
+// keyed by the names of the keys in lower case, see Conn.handleKeyConflict.
+type conflictDeletes map[string]*fallbackStmt
+
+// genInsertSQLsWithConflictDeletes generates statements like genInsertSQLs, and the conflict deletes of them
+// if opts.deleteOnKeyConflict is set and strategy is config.ConflictOnDuplicate.
+// ON DUPLICATE KEY UPDATE updates the row conflicting on the first unique key, and fails if the updated row conflicts with another row
+// on another unique key, then the row is deleted by the conflict delete of the key, like REPLACE does.
+// only statements of single rows have conflict deletes, as the row failed in a multi-row statement is unknown.
+// conflict deletes are nil if not generated, or else in the same order of statements.
+func genInsertSQLsWithConflictDeletes(schema string, table string, dataSeq [][]interface{}, columns []*column, indexColumns map[string][]*column, batch int, strategy string, opts *dmlOptions) ([]string, [][]string, [][]interface{}, []conflictDeletes, error) {
+	var deletes []conflictDeletes
+	withDeletes := opts.deleteOnKeyConflict && strategy == config.ConflictOnDuplicate
 	sqls := make([]string, 0, len(dataSeq))
 	keys := make([][]string, 0, len(dataSeq))
 	values := make([][]interface{}, 0, len(dataSeq))
-	columnList := genColumnList(columns)
-	columnPlaceholders := genColumnPlaceholders(len(columns))
+	insertColumns, _ := filterGeneratedColumns(columns, nil)
+	tmpl := insertTemplate(opts.stmtCache, schema, table, columns, strategy)
+	if batch < 1 {
+		batch = 1
+	}
+
+	var (
+		batchValues [][]interface{}
+		batchKeys   [][]string
+		batchRows   [][]interface{} // rows of batchValues with generated columns, for conflict deletes
+		batchSize   int
+	)
+	sizeLimit := opts.statementSizeLimit()
+	flush := func() {
+		if len(batchValues) == 0 {
+			return
+		}
+		if len(batchValues) == 1 {
+			for i := range batchValues {
+				sqls = append(sqls, tmpl.single)
+				values = append(values, batchValues[i])
+				keys = append(keys, batchKeys[i])
+				if withDeletes {
+					deletes = append(deletes, genConflictDeletes(schema, table, batchRows[i], columns, indexColumns, opts))
+				}
+			}
+		} else {
+			value := make([]interface{}, 0, len(batchValues)*len(insertColumns))
+			ks := make([]string, 0, len(batchKeys)*len(indexColumns))
+			for i := range batchValues {
+				value = append(value, batchValues[i]...)
+				ks = append(ks, batchKeys[i]...)
+			}
+			sqls = append(sqls, tmpl.sql(len(batchValues)))
+			values = append(values, value)
+			keys = append(keys, ks)
+			if withDeletes {
+				deletes = append(deletes, nil)
+			}
+		}
+		batchValues = batchValues[:0]
+		batchKeys = batchKeys[:0]
+		batchRows = batchRows[:0]
+		batchSize = 0
+	}
+
+	rows := make([][]interface{}, 0, len(dataSeq))
 	for _, data := range dataSeq {
-		if len(data) != len(columns) {
-			return nil, nil, nil, errors.Errorf("insert columns and data mismatch in length: %d (columns) vs %d (data)", len(columns), len(data))
+		if len(data) > len(columns) || (len(data) < len(columns) && !opts.fillMissingColumns) {
+			return nil, nil, nil, nil, newDMLError(ErrColumnCountMismatch, "insert columns and data mismatch in length: %d (columns) vs %d (data)", len(columns), len(data))
 		}
 
-		value := make([]interface{}, 0, len(data))
-		for i := range data {
-			value = append(value, castUnsigned(data[i], columns[i].unsigned, columns[i].tp))
+		value, err := castRow(data, columns, opts)
+		if err != nil {
+			return nil, nil, nil, nil, errors.Trace(err)
+		}
+		if len(value) < len(columns) {
+			value, err = fillMissingColumns(columns, value)
+			if err != nil {
+				return nil, nil, nil, nil, errors.Trace(err)
+			}
+		}
+		if opts.strictNotNull {
+			if err = checkNotNullColumns(schema, table, columns, value); err != nil {
+				return nil, nil, nil, nil, errors.Trace(err)
+			}
 		}
+		rows = append(rows, value)
+	}
 
-		sql := fmt.Sprintf("REPLACE INTO `%s`.`%s` (%s) VALUES (%s);", schema, table, columnList, columnPlaceholders)
-		ks := genMultipleKeys(columns, value, indexColumns)
-		sqls = append(sqls, sql)
-		values = append(values, value)
-		keys = append(keys, ks)
+	var order []int
+	if fn := opts.partitions[dbutil.TableName(schema, table)]; fn != nil && batch > 1 {
+		order = groupByPartition(fn, columns, rows)
+	}
+	for i := range rows {
+		value := rows[i]
+		if order != nil {
+			value = rows[order[i]]
+		}
+		ks := genMultipleKeys(columns, value, indexColumns, opts.keyGen)
+		row := value
+		_, value = filterGeneratedColumns(columns, value)
+		size := estimateRowSize(insertColumns, value) + 2 // parentheses of the row
+		oversized, err := checkRowSize(schema, table, insertColumns, value, size, opts)
+		if err != nil {
+			return nil, nil, nil, nil, errors.Trace(err)
+		}
+		if oversized || batchSize+size > sizeLimit {
+			flush()
+		}
+		batchValues = append(batchValues, value)
+		batchKeys = append(batchKeys, ks)
+		batchRows = append(batchRows, row)
+		batchSize += size
+		if oversized || len(batchValues) >= batch {
+			flush()
+		}
+	}
+	flush()
+
+	return sqls, keys, values, deletes, nil
+}
+
+// genConflictDeletes generates the conflict deletes of the inserted row value, which includes values of generated columns.
+// keys with NULL values are skipped, as NULL values never conflict.
+func genConflictDeletes(schema string, table string, value []interface{}, columns []*column, indexColumns map[string][]*column, opts *dmlOptions) conflictDeletes {
+	deletes := make(conflictDeletes, len(indexColumns))
+	for name, cols := range indexColumns {
+		if len(cols) == 0 {
+			continue
+		}
+		_, keyValues := getColumnData(columns, cols, value)
+		if containsNull(keyValues) {
+			continue
+		}
+		sql, args := genDeleteSQL(schema, table, value, columns, cols, opts)
+		deletes[strings.ToLower(name)] = &fallbackStmt{sql: sql, args: args}
+	}
+	return deletes
+}
+
+// fillMissingColumns appends the DEFAULT values of the trailing columns missing in value,
+// it happens if the row was written before the columns were added upstream, like by online DDL,
+// while the columns exist in the downstream table already.
+// values of generated columns are not inserted, so they are filled with NULL.
+func fillMissingColumns(columns []*column, value []interface{}) ([]interface{}, error) {
+	for _, col := range columns[len(value):] {
+		if col.defaultExpr && !col.IsGenerated {
+			return nil, errors.NotSupportedf("fill column %s missing in row with DEFAULT expression %v", col.name, col.defaultValue)
+		}
+		value = append(value, col.defaultValue)
+	}
+	return value, nil
+}
+
+// checkNotNullColumns checks the values (casted) of a row against NOT NULL columns,
+// so the row is rejected with the column named rather than by the target with an opaque error.
+// values of generated columns are not inserted, so they are not checked.
+func checkNotNullColumns(schema, table string, columns []*column, value []interface{}) error {
+	for i, col := range columns {
+		if col.NotNull && !col.IsGenerated && value[i] == nil {
+			return errors.NotValidf("NULL value for NOT NULL column `%s` of table `%s`.`%s`", col.name, schema, table)
+		}
+	}
+	return nil
+}
+
+// genInsertHeadTail returns the statement head and tail of INSERT statements for strategy
+func genInsertHeadTail(strategy string, columns []*column) (string, string) {
+	switch strategy {
+	case config.ConflictOnDuplicate:
+		kvs := make([]string, 0, len(columns))
+		for _, col := range columns {
+			kvs = append(kvs, fmt.Sprintf("`%s`=VALUES(`%s`)", col.name, col.name))
+		}
+		return "INSERT INTO", " ON DUPLICATE KEY UPDATE " + strings.Join(kvs, ",")
+	case config.ConflictIgnore:
+		// rows conflicting with existing rows are dropped silently
+		return "INSERT IGNORE INTO", ""
+	default:
+		return "REPLACE INTO", ""
+	}
+}
+
+// estimateRowSize estimates the size of a row when it is sent to the downstream
+func estimateRowSize(columns []*column, value []interface{}) int {
+	size := len(value) // separators
+	for i := range value {
+		size += len(columnValue(value[i], columns[i].unsigned, columns[i].tp))
 	}
+	return size
+}
+
+// genUpdateSQLs generates UPDATE statements for pairs of old and changed rows in data,
+// or DELETE and REPLACE statements in safe mode.
+// if the primary key (or the not null unique index used instead) of a row is changed, DELETE and REPLACE statements are also generated,
+// so the changed row replaces the row with the same key in target rather than failing for the duplicate key.
+// the row is identified by the preferred index of opts if it has no NULL in the old row,
+// or else by the primary key or a not null unique index, or else by a unique index without NULL in the old row.
+// if no such index exists, the WHERE clause uses all (non-generated and non-spatial) columns of the old row, with `IS NULL` for NULL values.
+// rows with the same values can't be told apart in that case, at most opts.rowLimit (`LIMIT 1` by default) of them are updated,
+// and all of them are updated if opts.updateAllDuplicates is set.
+// images are the columns present in partial row images, nil means the full rows are present, see rowImages.
+func genUpdateSQLs(schema string, table string, data [][]interface{}, columns []*column, indexColumns map[string][]*column, images *rowImages, safeMode bool, opts *dmlOptions) ([]string, [][]string, [][]interface{}, error) {
+	sqls, keys, values, _, err := genUpdateSQLsWithFallbacks(schema, table, data, columns, indexColumns, images, safeMode, opts)
+	return sqls, keys, values, err
+}
 
-	return sqls, keys, values, nil
+// fallbackStmt is the statement executed in place of a DML statement matching no row in the target
+type fallbackStmt struct {
+	sql  string
+	args []interface{}
 }
 
-func genUpdateSQLs(schema string, table string, data [][]interface{}, columns []*column, indexColumns map[string][]*column, safeMode bool) ([]string, [][]string, [][]interface{}, error) {
+// genUpdateSQLsWithFallbacks generates statements like genUpdateSQLs, and the fallbacks of them if opts.upsertOnMissing is set.
+// the fallback of an UPDATE statement is the REPLACE statement of the changed row, executed if the UPDATE matches no row in the target,
+// like the row is missing for an INSERT skipped before. other statements and UPDATE statements of partial images have no fallbacks,
+// as REPLACE needs the full row. fallbacks are nil if opts.upsertOnMissing is not set, or else in the same order of statements.
+func genUpdateSQLsWithFallbacks(schema string, table string, data [][]interface{}, columns []*column, indexColumns map[string][]*column, images *rowImages, safeMode bool, opts *dmlOptions) ([]string, [][]string, [][]interface{}, []*fallbackStmt, error) {
+	var fallbacks []*fallbackStmt
 	sqls := make([]string, 0, len(data)/2)
 	keys := make([][]string, 0, len(data)/2)
 	values := make([][]interface{}, 0, len(data)/2)
-	columnList := genColumnList(columns)
-	columnPlaceholders := genColumnPlaceholders(len(columns))
-	defaultIndexColumns := findFitIndex(indexColumns)
+	defaultIndexColumns := findFitIndex(indexColumns, opts.preferredIndex, opts.getLogger())
+	var replaceTmpl *stmtTemplate
 
 	for i := 0; i < len(data); i += 2 {
 		oldData := data[i]
 		changedData := data[i+1]
 
 		if len(oldData) != len(changedData) {
-			return nil, nil, nil, errors.Errorf("update data mismatch in length: %d (columns) vs %d (data)", len(oldData), len(changedData))
+			return nil, nil, nil, nil, newDMLError(ErrColumnCountMismatch, "update data mismatch in length: %d (columns) vs %d (data)", len(oldData), len(changedData))
 		}
 
 		if len(oldData) != len(columns) {
-			return nil, nil, nil, errors.Errorf("update columns and data mismatch in length: %d (columns) vs %d (data)", len(columns), len(oldData))
+			return nil, nil, nil, nil, newDMLError(ErrColumnCountMismatch, "update columns and data mismatch in length: %d (columns) vs %d (data)", len(columns), len(oldData))
 		}
 
-		oldValues := make([]interface{}, 0, len(oldData))
-		for i := range oldData {
-			oldValues = append(oldValues, castUnsigned(oldData[i], columns[i].unsigned, columns[i].tp))
+		oldValues, err := castRow(oldData, columns, opts)
+		if err != nil {
+			return nil, nil, nil, nil, errors.Trace(err)
+		}
+		changedValues, err := castRow(changedData, columns, opts)
+		if err != nil {
+			return nil, nil, nil, nil, errors.Trace(err)
+		}
+
+		if images != nil {
+			images.fillAfter(oldValues, changedValues)
 		}
-		changedValues := make([]interface{}, 0, len(changedData))
-		for i := range changedData {
-			changedValues = append(changedValues, castUnsigned(changedData[i], columns[i].unsigned, columns[i].tp))
+
+		// the available index may differ between rows, as index columns may be NULL in some rows
+		rowIndexColumns := getRowIndexColumn(indexColumns, defaultIndexColumns, opts.preferredIndex, oldValues)
+		if images != nil && !images.inBefore(rowIndexColumns) {
+			rowIndexColumns = nil
 		}
 
-		if len(defaultIndexColumns) == 0 {
-			defaultIndexColumns = getAvailableIndexColumn(indexColumns, oldValues)
+		if opts.isChangeIgnored(columns, images, oldValues, changedValues) {
+			continue
 		}
 
-		ks := genMultipleKeys(columns, oldValues, indexColumns)
-		ks = append(ks, genMultipleKeys(columns, changedValues, indexColumns)...)
+		ks := genMultipleKeys(columns, oldValues, indexColumns, opts.keyGen)
+		ks = append(ks, genMultipleKeys(columns, changedValues, indexColumns, opts.keyGen)...)
 
-		if safeMode {
+		// REPLACE needs the full row, rows of partial images are always updated by UPDATE statements, which are idempotent too
+		if images == nil && (safeMode || isKeyChanged(defaultIndexColumns, oldValues, changedValues)) {
+			if replaceTmpl == nil {
+				replaceTmpl = insertTemplate(opts.stmtCache, schema, table, columns, config.ConflictReplace)
+			}
 			// generate delete sql from old data
-			sql, value := genDeleteSQL(schema, table, oldValues, columns, defaultIndexColumns)
+			if err = checkWhereColumns(schema, table, columns, rowIndexColumns); err != nil {
+				return nil, nil, nil, nil, errors.Trace(err)
+			}
+			sql, value := genDeleteSQL(schema, table, oldValues, columns, rowIndexColumns, opts)
 			sqls = append(sqls, sql)
 			values = append(values, value)
 			keys = append(keys, ks)
 			// generate replace sql from new data
-			sql = fmt.Sprintf("REPLACE INTO `%s`.`%s` (%s) VALUES (%s);", schema, table, columnList, columnPlaceholders)
-			sqls = append(sqls, sql)
-			values = append(values, changedValues)
+			replaceColumns, replaceValues := filterGeneratedColumns(columns, changedValues)
+			if _, err = checkRowSize(schema, table, replaceColumns, replaceValues, estimateRowSize(replaceColumns, replaceValues), opts); err != nil {
+				return nil, nil, nil, nil, errors.Trace(err)
+			}
+			sqls = append(sqls, replaceTmpl.single)
+			values = append(values, replaceValues)
 			keys = append(keys, ks)
+			if opts.upsertOnMissing {
+				fallbacks = append(fallbacks, nil, nil)
+			}
 			continue
 		}
 
-		updateColumns := make([]*column, 0, len(defaultIndexColumns))
-		updateValues := make([]interface{}, 0, len(defaultIndexColumns))
+		updateColumns := make([]*column, 0, len(oldValues))
+		updateValues := make([]interface{}, 0, len(oldValues))
 		for j := range oldValues {
+			if columns[j].IsGenerated || (images != nil && !images.isChanged(j, oldValues, changedValues)) ||
+				(images == nil && reflect.DeepEqual(oldValues[j], changedValues[j])) {
+				continue
+			}
 			updateColumns = append(updateColumns, columns[j])
 			updateValues = append(updateValues, changedValues[j])
 		}
@@ -116,85 +450,801 @@ func genUpdateSQLs(schema string, table string, data [][]interface{}, columns []
 		kvs := genKVs(updateColumns)
 		value = append(value, updateValues...)
 
-		whereColumns, whereValues := columns, oldValues
-		if len(defaultIndexColumns) > 0 {
-			whereColumns, whereValues = getColumnData(columns, defaultIndexColumns, oldValues)
+		whereColumns, whereValues := filterWhereColumns(columns, oldValues)
+		if images != nil {
+			whereColumns, whereValues = images.filterBefore(whereColumns, whereValues)
+		}
+		limit := " LIMIT 1"
+		if len(rowIndexColumns) > 0 {
+			whereColumns, whereValues = getColumnData(columns, rowIndexColumns, oldValues)
+		} else if opts.updateAllDuplicates {
+			limit = ""
+		} else {
+			limit = opts.limitClause()
+			if limit == " LIMIT 1" {
+				opts.getLogger().Warnf("[syncer] update a row of `%s`.`%s` without unique key, only one of the rows matched is updated, the target may diverge if there are duplicate rows", schema, table)
+			}
 		}
 
+		if len(whereColumns) == 0 {
+			return nil, nil, nil, nil, newDMLError(ErrNoUsableIndex, "no unique index or columns to identify the updated row of table `%s`.`%s`", schema, table)
+		}
 		where := genWhere(whereColumns, whereValues)
 		value = append(value, whereValues...)
+		valueColumns := append(updateColumns[:len(updateColumns):len(updateColumns)], whereColumns...)
+		if _, err = checkRowSize(schema, table, valueColumns, value, estimateRowSize(valueColumns, value), opts); err != nil {
+			return nil, nil, nil, nil, errors.Trace(err)
+		}
 
-		sql := fmt.Sprintf("UPDATE `%s`.`%s` SET %s WHERE %s LIMIT 1;", schema, table, kvs, where)
+		sql := fmt.Sprintf("UPDATE `%s`.`%s` SET %s WHERE %s%s;", schema, table, kvs, where, limit)
 		sqls = append(sqls, sql)
 		values = append(values, value)
 		keys = append(keys, ks)
+		if opts.upsertOnMissing {
+			var fallback *fallbackStmt
+			if images == nil {
+				if replaceTmpl == nil {
+					replaceTmpl = insertTemplate(opts.stmtCache, schema, table, columns, config.ConflictReplace)
+				}
+				_, replaceValues := filterGeneratedColumns(columns, changedValues)
+				fallback = &fallbackStmt{sql: replaceTmpl.single, args: replaceValues}
+			}
+			fallbacks = append(fallbacks, fallback)
+		}
 	}
 
-	return sqls, keys, values, nil
+	return sqls, keys, values, fallbacks, nil
 }
 
-func genDeleteSQLs(schema string, table string, dataSeq [][]interface{}, columns []*column, indexColumns map[string][]*column) ([]string, [][]string, [][]interface{}, error) {
+// isChangeIgnored returns whether the row is changed only in the columns of opts.changeIgnoredColumns,
+// then it's not updated in the target, even in safe mode. the row without any change is not ignored here.
+func (o *dmlOptions) isChangeIgnored(columns []*column, images *rowImages, oldValues, changedValues []interface{}) bool {
+	if len(o.changeIgnoredColumns) == 0 {
+		return false
+	}
+	ignored := false
+	for j, col := range columns {
+		if col.IsGenerated || (images != nil && !images.isChanged(j, oldValues, changedValues)) ||
+			(images == nil && reflect.DeepEqual(oldValues[j], changedValues[j])) {
+			continue
+		}
+		if _, ok := o.changeIgnoredColumns[strings.ToLower(col.name)]; !ok {
+			return false
+		}
+		ignored = true
+	}
+	return ignored
+}
+
+// isKeyChanged returns whether values of the key columns are changed
+func isKeyChanged(keyColumns []*column, oldValues, changedValues []interface{}) bool {
+	for _, col := range keyColumns {
+		if !reflect.DeepEqual(oldValues[col.idx], changedValues[col.idx]) {
+			return true
+		}
+	}
+	return false
+}
+
+// genDeleteSQLs generates DELETE statements for dataSeq.
+// if the table has a fit index, rows are deleted in batch with `WHERE (cols) IN (...)`,
+// otherwise one statement is generated for every row.
+// keys are generated for every row rather than every statement, in the order of the statements deleting them.
+func genDeleteSQLs(schema string, table string, dataSeq [][]interface{}, columns []*column, indexColumns map[string][]*column, opts *dmlOptions) ([]string, [][]string, [][]interface{}, error) {
+	sqls, keys, values, _, err := genDeleteSQLsWithRows(schema, table, dataSeq, columns, indexColumns, opts)
+	return sqls, keys, values, err
+}
+
+// genDeleteSQLsWithRows generates statements like genDeleteSQLs, and the number of rows deleted by every statement,
+// the keys of the rows deleted by a statement follow the keys of the rows deleted by the statements before it.
+func genDeleteSQLsWithRows(schema string, table string, dataSeq [][]interface{}, columns []*column, indexColumns map[string][]*column, opts *dmlOptions) ([]string, [][]string, [][]interface{}, []int, error) {
 	sqls := make([]string, 0, len(dataSeq))
 	keys := make([][]string, 0, len(dataSeq))
 	values := make([][]interface{}, 0, len(dataSeq))
-	defaultIndexColumns := findFitIndex(indexColumns)
+	rows := make([]int, 0, len(dataSeq))
+	defaultIndexColumns := findFitIndex(indexColumns, opts.preferredIndex, opts.getLogger())
+
+	// rows are deleted in batches by the default index, unless the preferred index with nullable columns is used for some rows
+	preferredColumns := indexColumns[opts.preferredIndex]
+	if len(defaultIndexColumns) > 0 && len(dataSeq) > 1 && (len(preferredColumns) == 0 || !hasNullableColumn(preferredColumns)) {
+		return genBatchDeleteSQLs(schema, table, dataSeq, columns, indexColumns, defaultIndexColumns, opts)
+	}
 
 	for _, data := range dataSeq {
 		if len(data) != len(columns) {
-			return nil, nil, nil, errors.Errorf("delete columns and data mismatch in length: %d (columns) vs %d (data)", len(columns), len(data))
+			return nil, nil, nil, nil, newDMLError(ErrColumnCountMismatch, "delete columns and data mismatch in length: %d (columns) vs %d (data)", len(columns), len(data))
 		}
 
-		value := make([]interface{}, 0, len(data))
-		for i := range data {
-			value = append(value, castUnsigned(data[i], columns[i].unsigned, columns[i].tp))
+		value, err := castRow(data, columns, opts)
+		if err != nil {
+			return nil, nil, nil, nil, errors.Trace(err)
 		}
 
-		if len(defaultIndexColumns) == 0 {
-			defaultIndexColumns = getAvailableIndexColumn(indexColumns, value)
+		rowIndexColumns := getRowIndexColumn(indexColumns, defaultIndexColumns, opts.preferredIndex, value)
+		if err = checkWhereColumns(schema, table, columns, rowIndexColumns); err != nil {
+			return nil, nil, nil, nil, errors.Trace(err)
 		}
-		ks := genMultipleKeys(columns, value, indexColumns)
+		ks := genMultipleKeys(columns, value, indexColumns, opts.keyGen)
 
-		sql, value := genDeleteSQL(schema, table, value, columns, defaultIndexColumns)
+		sql, value := genDeleteSQL(schema, table, value, columns, rowIndexColumns, opts)
 		sqls = append(sqls, sql)
 		values = append(values, value)
 		keys = append(keys, ks)
+		rows = append(rows, 1)
+	}
+
+	return sqls, keys, values, rows, nil
+}
+
+// genBatchDeleteSQLs generates `DELETE FROM ... WHERE (cols) IN (...)` statements for dataSeq like genDeleteSQLsWithRows.
+// a new statement is started if the estimated size exceeds the limit of opts.
+// rows with NULL values in whereColumns are deleted by their own statements.
+func genBatchDeleteSQLs(schema string, table string, dataSeq [][]interface{}, columns []*column, indexColumns map[string][]*column, whereColumns []*column, opts *dmlOptions) ([]string, [][]string, [][]interface{}, []int, error) {
+	var (
+		sqls   []string
+		keys   [][]string
+		values [][]interface{}
+		rows   []int
+
+		batchRows [][]interface{}
+		batchKeys [][]string
+		batchSize int
+	)
+	sizeLimit := opts.statementSizeLimit()
+	flush := func() {
+		if len(batchRows) == 0 {
+			return
+		}
+		where, whereValues, nullRows := genWhereIn(whereColumns, batchRows)
+		isNull := make(map[int]bool, len(nullRows))
+		for _, i := range nullRows {
+			isNull[i] = true
+			sql, value := genDeleteSQL(schema, table, batchRows[i], columns, whereColumns, opts)
+			sqls = append(sqls, sql)
+			values = append(values, value)
+			keys = append(keys, batchKeys[i])
+			rows = append(rows, 1)
+		}
+		if len(whereValues) > 0 {
+			for i := range batchKeys {
+				if !isNull[i] {
+					keys = append(keys, batchKeys[i])
+				}
+			}
+			sqls = append(sqls, fmt.Sprintf("DELETE FROM `%s`.`%s` WHERE %s;", schema, table, where))
+			values = append(values, whereValues)
+			rows = append(rows, len(batchRows)-len(nullRows))
+		}
+		batchRows, batchKeys, batchSize = nil, nil, 0
+	}
+
+	for _, data := range dataSeq {
+		if len(data) != len(columns) {
+			return nil, nil, nil, nil, newDMLError(ErrColumnCountMismatch, "delete columns and data mismatch in length: %d (columns) vs %d (data)", len(columns), len(data))
+		}
+
+		value, err := castRow(data, columns, opts)
+		if err != nil {
+			return nil, nil, nil, nil, errors.Trace(err)
+		}
+
+		_, whereValues := getColumnData(columns, whereColumns, value)
+		size := estimateRowSize(whereColumns, whereValues)
+		if len(whereColumns) > 1 {
+			size += 2 // parentheses of the tuple
+		}
+		oversized, err := checkRowSize(schema, table, whereColumns, whereValues, size, opts)
+		if err != nil {
+			return nil, nil, nil, nil, errors.Trace(err)
+		}
+		if oversized || batchSize+size > sizeLimit {
+			flush()
+		}
+		batchSize += size
+		batchRows = append(batchRows, value)
+		batchKeys = append(batchKeys, genMultipleKeys(columns, value, indexColumns, opts.keyGen))
+		if oversized {
+			flush()
+		}
 	}
+	flush()
 
-	return sqls, keys, values, nil
+	return sqls, keys, values, rows, nil
 }
 
-func genDeleteSQL(schema string, table string, value []interface{}, columns []*column, indexColumns []*column) (string, []interface{}) {
-	whereColumns, whereValues := columns, value
+// genDeleteSQL generates a DELETE statement for the row value.
+// if the row is matched by the unique indexColumns, all rows matched are deleted by the statement, there is at most one.
+// otherwise the row is matched by all columns with the LIMIT clause of opts, by default `LIMIT 1` deletes only one of the duplicate rows,
+// it's the same as the row deleted in source, but the other rows should be deleted by their own row events.
+func genDeleteSQL(schema string, table string, value []interface{}, columns []*column, indexColumns []*column, opts *dmlOptions) (string, []interface{}) {
+	whereColumns, whereValues := filterWhereColumns(columns, value)
+	unique := false
 	if len(indexColumns) > 0 {
 		whereColumns, whereValues = getColumnData(columns, indexColumns, value)
+		// NULL values are not unique in a unique index
+		unique = !containsNull(whereValues)
 	}
 
 	where := genWhere(whereColumns, whereValues)
-	sql := fmt.Sprintf("DELETE FROM `%s`.`%s` WHERE %s LIMIT 1;", schema, table, where)
+	if unique {
+		return fmt.Sprintf("DELETE FROM `%s`.`%s` WHERE %s;", schema, table, where), whereValues
+	}
 
+	limit := opts.limitClause()
+	if limit == " LIMIT 1" {
+		opts.getLogger().Warnf("[syncer] delete a row of `%s`.`%s` without unique key, only one of the rows matched [%s] is deleted, the target may diverge if there are duplicate rows", schema, table, where)
+	}
+	sql := fmt.Sprintf("DELETE FROM `%s`.`%s` WHERE %s%s;", schema, table, where, limit)
 	return sql, whereValues
 }
 
+// checkWhereColumns checks the row of the table can be matched by genDeleteSQL, by indexColumns or the columns of the row,
+// generated and spatial columns are not used to match the row, it's ErrNoUsableIndex if all columns are such ones.
+func checkWhereColumns(schema, table string, columns []*column, indexColumns []*column) error {
+	if len(indexColumns) > 0 {
+		return nil
+	}
+	for _, col := range columns {
+		if !col.IsGenerated && !col.geometry {
+			return nil
+		}
+	}
+	return newDMLError(ErrNoUsableIndex, "no unique index or columns to identify the deleted row of table `%s`.`%s`", schema, table)
+}
+
+func containsNull(values []interface{}) bool {
+	for _, value := range values {
+		if value == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// filterGeneratedColumns filters out generated columns and the corresponding values in data.
+// values of generated columns can not be specified in INSERT or UPDATE statements,
+// and they are only used in WHERE clauses when they are part of an index.
+func filterGeneratedColumns(columns []*column, data []interface{}) ([]*column, []interface{}) {
+	cols := make([]*column, 0, len(columns))
+	var values []interface{}
+	if data != nil {
+		values = make([]interface{}, 0, len(data))
+	}
+	for i, col := range columns {
+		if col.IsGenerated {
+			continue
+		}
+		cols = append(cols, col)
+		if data != nil {
+			values = append(values, data[i])
+		}
+	}
+	return cols, values
+}
+
+// filterWhereColumns filters out the columns not used in WHERE clauses matching a row by all its columns, and the corresponding values in data.
+// they are generated columns (see filterGeneratedColumns) and spatial columns, whose values can't be compared reliably in binary.
+func filterWhereColumns(columns []*column, data []interface{}) ([]*column, []interface{}) {
+	cols := make([]*column, 0, len(columns))
+	values := make([]interface{}, 0, len(data))
+	for i, col := range columns {
+		if col.IsGenerated || col.geometry {
+			continue
+		}
+		cols = append(cols, col)
+		values = append(values, data[i])
+	}
+	return cols, values
+}
+
+// genColumnList generates the column list like `a`,`b` of columns
 func genColumnList(columns []*column) string {
-	var columnList []byte
-	for i, column := range columns {
-		name := fmt.Sprintf("`%s`", column.name)
-		columnList = append(columnList, []byte(name)...)
+	return clauses.get(clauseColumnList, columns, writeColumnList)
+}
 
-		if i != len(columns)-1 {
-			columnList = append(columnList, ',')
+func writeColumnList(buf *bytes.Buffer, columns []*column) {
+	for i, col := range columns {
+		if i > 0 {
+			buf.WriteByte(',')
 		}
+		buf.WriteByte('`')
+		buf.WriteString(col.name)
+		buf.WriteByte('`')
 	}
+}
 
-	return string(columnList)
+// genColumnPlaceholders generates the placeholders like ?,? of values of columns in VALUES clauses
+func genColumnPlaceholders(columns []*column) string {
+	return clauses.get(clausePlaceholders, columns, writeColumnPlaceholders)
 }
 
-func genColumnPlaceholders(length int) string {
-	values := make([]string, length, length)
-	for i := 0; i < length; i++ {
-		values[i] = "?"
+func writeColumnPlaceholders(buf *bytes.Buffer, columns []*column) {
+	for i, col := range columns {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(columnPlaceholder(col))
 	}
-	return strings.Join(values, ",")
+}
+
+// columnPlaceholder returns the placeholder of the value of col in VALUES and SET clauses,
+// it's the bind expression of col wrapping `?`, or a bare `?` if col has none.
+func columnPlaceholder(col *column) string {
+	if col.bindExpr == "" {
+		return "?"
+	}
+	return col.bindExpr
+}
+
+// defaultBindExpr returns the bind expression of col according to its type, the value bound should be cast by castValue for it.
+// the WKB value of a spatial column is converted to a geometry by `ST_GeomFromWKB`.
+func defaultBindExpr(col *column) string {
+	if col.geometry {
+		return geometryBindExpr
+	}
+	return ""
+}
+
+const geometryBindExpr = "ST_GeomFromWKB(?)"
+
+// castValue casts data of col to the value bound to DML statements,
+// values of TIMESTAMP columns are converted to the wall-clock time in timezone if it's not nil.
+// values failed to cast are kept as they are, and warned by logger.
+func castValue(data interface{}, col *column, timezone *time.Location, logger log.Logger) interface{} {
+	data = castUnsigned(data, col.unsigned, col.tp)
+	if len(col.elems) > 0 {
+		data = castEnumSet(data, col)
+	}
+	if isJSONColumn(col) {
+		data = castJSON(data)
+	}
+	if timezone != nil && isTimestampColumn(col) {
+		data = castTimestamp(data, col, timezone, logger)
+	}
+	data = castTime(data, col)
+	if col.bitWidth > 0 {
+		data = castBit(data, col, logger)
+	}
+	if col.precision > 0 {
+		data = castDecimal(data, col.scale)
+	}
+	if col.geometry {
+		data = castGeometry(data)
+	}
+	return data
+}
+
+// castGeometry casts the value of spatial column to WKB bound to `ST_GeomFromWKB(?)`.
+// spatial values are decoded from binlog in the internal format of MySQL, a 4-byte SRID followed by WKB,
+// the SRID is dropped as it's not accepted by `ST_GeomFromWKB` in WKB.
+func castGeometry(data interface{}) interface{} {
+	var v []byte
+	switch d := data.(type) {
+	case []byte:
+		v = d
+	case string:
+		v = []byte(d)
+	default:
+		return data
+	}
+	if len(v) < 4 {
+		return data
+	}
+	return v[4:]
+}
+
+func isTimestampColumn(col *column) bool {
+	return strings.HasPrefix(strings.ToLower(col.tp), "timestamp")
+}
+
+// castTimestamp converts the value of TIMESTAMP column decoded in UTC to the wall-clock time in timezone,
+// zero value is kept as it is, and the fractional seconds are formatted with the precision of the column.
+func castTimestamp(data interface{}, col *column, timezone *time.Location, logger log.Logger) interface{} {
+	var (
+		t   time.Time
+		fsp = col.fsp
+	)
+	switch v := data.(type) {
+	case time.Time:
+		if v.IsZero() {
+			return formatZeroTime(fsp)
+		}
+		t = v
+	case string:
+		if dot := strings.IndexByte(v, '.'); dot >= 0 && len(v)-dot-1 > fsp {
+			fsp = len(v) - dot - 1
+		}
+		if strings.HasPrefix(v, "0000-00-00") {
+			return v
+		}
+		// fractional seconds are accepted by time.Parse even they are not in the layout
+		parsed, err := time.ParseInLocation(timeLayout(0), v, time.UTC)
+		if err != nil {
+			logger.Warnf("[syncer] fail to parse TIMESTAMP value %s of column %s: %v", v, col.name, err)
+			return data
+		}
+		t = parsed
+	default:
+		return data
+	}
+	return t.In(timezone).Format(timeLayout(fsp))
+}
+
+// castTime casts time.Time and time.Duration values of temporal columns to strings formatted like what MySQL returns,
+// rather than binding them as they are, the driver formats time.Time as a DATETIME, and binds time.Duration as nanoseconds.
+func castTime(data interface{}, col *column) interface{} {
+	switch data.(type) {
+	case time.Time, time.Duration:
+		return columnValue(data, false, col.tp)
+	default:
+		return data
+	}
+}
+
+// formatTime formats the value of a column with type tp, the time zone of t is kept, see castTimestamp for TIMESTAMP values.
+// it's the date only for DATE, the time of day for TIME, and the date and time with the fractional seconds precision of the column for others.
+func formatTime(t time.Time, tp string) string {
+	tp = strings.ToLower(tp)
+	fsp := parseFsp(tp)
+	switch {
+	case tp == "date":
+		if t.IsZero() {
+			return "0000-00-00"
+		}
+		return t.Format("2006-01-02")
+	case strings.HasPrefix(tp, "time") && !strings.HasPrefix(tp, "timestamp"):
+		return t.Format(timeLayout(fsp)[len("2006-01-02 "):])
+	case t.IsZero():
+		return formatZeroTime(fsp)
+	default:
+		return t.Format(timeLayout(fsp))
+	}
+}
+
+// formatDuration formats the value of TIME column like `-838:59:59.000000`, hours may be more than 24
+func formatDuration(d time.Duration, fsp int) string {
+	sign := ""
+	if d < 0 {
+		sign = "-"
+		d = -d
+	}
+	hours := d / time.Hour
+	minutes := d % time.Hour / time.Minute
+	seconds := d % time.Minute / time.Second
+	s := fmt.Sprintf("%s%02d:%02d:%02d", sign, hours, minutes, seconds)
+	if fsp > 6 {
+		fsp = 6
+	}
+	if fsp > 0 {
+		micros := fmt.Sprintf("%06d", d%time.Second/time.Microsecond)
+		s += "." + micros[:fsp]
+	}
+	return s
+}
+
+func isDateColumn(col *column) bool {
+	tp := strings.ToLower(col.tp)
+	return strings.HasPrefix(tp, "date") || strings.HasPrefix(tp, "timestamp")
+}
+
+// castZeroDate converts the zero date like `0000-00-00 00:00:00` and the date with zero parts like `2020-00-05`
+// of a nullable DATE, DATETIME or TIMESTAMP column to NULL, values of NOT NULL columns are kept as they are.
+func castZeroDate(data interface{}, col *column) interface{} {
+	if col.NotNull || !isDateColumn(col) {
+		return data
+	}
+	var s string
+	switch v := data.(type) {
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		return data
+	}
+	if isZeroDate(s) {
+		return nil
+	}
+	return data
+}
+
+// isZeroDate returns whether the month or the day of the date (and time) in `YYYY-MM-DD` is zero
+func isZeroDate(s string) bool {
+	if len(s) < len("0000-00-00") || s[4] != '-' || s[7] != '-' {
+		return false
+	}
+	return s[5:7] == "00" || s[8:10] == "00"
+}
+
+func timeLayout(fsp int) string {
+	if fsp <= 0 {
+		return "2006-01-02 15:04:05"
+	}
+	if fsp > 6 {
+		fsp = 6
+	}
+	return "2006-01-02 15:04:05." + strings.Repeat("0", fsp)
+}
+
+func formatZeroTime(fsp int) string {
+	if fsp <= 0 {
+		return "0000-00-00 00:00:00"
+	}
+	if fsp > 6 {
+		fsp = 6
+	}
+	return "0000-00-00 00:00:00." + strings.Repeat("0", fsp)
+}
+
+// parseFsp parses the fractional seconds precision from column type, like `timestamp(3)`
+func parseFsp(tp string) int {
+	start := strings.IndexByte(tp, '(')
+	end := strings.IndexByte(tp, ')')
+	if start < 0 || end <= start {
+		return 0
+	}
+	fsp, err := strconv.Atoi(tp[start+1 : end])
+	if err != nil {
+		return 0
+	}
+	return fsp
+}
+
+// parseBitWidth parses the width of BIT column from column type, like `bit(8)`, it returns 0 if it's not a BIT column
+func parseBitWidth(tp string) int {
+	tp = strings.ToLower(tp)
+	if tp == "bit" {
+		return 1 // BIT means BIT(1)
+	}
+	if !strings.HasPrefix(tp, "bit(") {
+		return 0
+	}
+	return parseFsp(tp)
+}
+
+// castBit casts the value of BIT column to an unsigned integer of the column width,
+// so it's bound as a number and formatted as the same key whatever it's decoded as.
+// BIT value is decoded as int64 from binlog (negative for BIT(64) with the highest bit set),
+// and it may be raw bytes in big-endian like what is returned by a query.
+func castBit(data interface{}, col *column, logger log.Logger) interface{} {
+	var v uint64
+	switch d := data.(type) {
+	case bool:
+		if d {
+			v = 1
+		}
+	case int8:
+		v = uint64(uint8(d))
+	case int16:
+		v = uint64(uint16(d))
+	case int32:
+		v = uint64(uint32(d))
+	case int:
+		v = uint64(d)
+	case int64:
+		v = uint64(d)
+	case uint8:
+		v = uint64(d)
+	case uint16:
+		v = uint64(d)
+	case uint32:
+		v = uint64(d)
+	case uint64:
+		v = d
+	case []byte:
+		if len(d) > 8 {
+			logger.Warnf("[syncer] BIT value %x of column %s is longer than 8 bytes", d, col.name)
+			return data
+		}
+		for _, b := range d {
+			v = v<<8 | uint64(b)
+		}
+	case string:
+		return castBit([]byte(d), col, logger)
+	default:
+		return data
+	}
+	if col.bitWidth < 64 {
+		v &= 1<<uint(col.bitWidth) - 1
+	}
+	return v
+}
+
+// bitLiteral formats the value of BIT column as a bit-value literal like b'0101' of the column width
+func bitLiteral(v uint64, width int) string {
+	bits := strconv.FormatUint(v, 2)
+	if len(bits) < width {
+		bits = strings.Repeat("0", width-len(bits)) + bits
+	}
+	return "b'" + bits + "'"
+}
+
+// isBinaryType returns whether the column type holds raw bytes, like `varbinary(16)` or `blob`.
+// values of BINARY and BLOB columns may be invalid UTF8, unlike CHAR and TEXT columns.
+func isBinaryType(tp string) bool {
+	return hasTypeName(tp, "binary", "varbinary", "tinyblob", "blob", "mediumblob", "longblob")
+}
+
+// isGeometryType returns whether the column type is a spatial type, like `geometry` or `point`
+func isGeometryType(tp string) bool {
+	return hasTypeName(tp, "geometry", "point", "linestring", "polygon", "multipoint", "multilinestring", "multipolygon", "geometrycollection", "geomcollection")
+}
+
+// isTextType returns whether the column type holds text, like `varchar(20)` or `text`
+func isTextType(tp string) bool {
+	return hasTypeName(tp, "char", "varchar", "tinytext", "text", "mediumtext", "longtext", "enum", "set", "json")
+}
+
+// isDefaultExpr returns whether the DEFAULT value shown by `SHOW COLUMNS` is an expression or a literal not bound as text,
+// like `CURRENT_TIMESTAMP(3)` or `b'1'` of BIT columns.
+func isDefaultExpr(value string) bool {
+	value = strings.ToLower(value)
+	for _, prefix := range []string{"current_timestamp", "now(", "localtime", "b'"} {
+		if strings.HasPrefix(value, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasTypeName returns whether the name of column type (without length and attributes) is one of names
+func hasTypeName(tp string, names ...string) bool {
+	tp = strings.ToLower(tp)
+	if idx := strings.IndexAny(tp, "( "); idx >= 0 {
+		tp = tp[:idx]
+	}
+	for _, name := range names {
+		if tp == name {
+			return true
+		}
+	}
+	return false
+}
+
+// hexLiteral formats raw bytes as a hexadecimal literal like 0x00ff
+func hexLiteral(data []byte) string {
+	if len(data) == 0 {
+		return "''" // 0x is not a valid literal
+	}
+	return fmt.Sprintf("0x%x", data)
+}
+
+// parseDecimal parses the precision and scale from column type, like `decimal(20,2)`, it returns 0 precision if it's not a DECIMAL column.
+// the default precision is 10, and the default scale is 0.
+func parseDecimal(tp string) (precision, scale int) {
+	tp = strings.ToLower(tp)
+	if !strings.HasPrefix(tp, "decimal") {
+		return 0, 0
+	}
+	precision = 10
+	start := strings.IndexByte(tp, '(')
+	end := strings.IndexByte(tp, ')')
+	if start < 0 || end <= start {
+		return precision, 0
+	}
+	parts := strings.Split(tp[start+1:end], ",")
+	if p, err := strconv.Atoi(strings.TrimSpace(parts[0])); err == nil {
+		precision = p
+	}
+	if len(parts) > 1 {
+		if s, err := strconv.Atoi(strings.TrimSpace(parts[1])); err == nil {
+			scale = s
+		}
+	}
+	return precision, scale
+}
+
+// castDecimal casts the float value of DECIMAL column to a string with the scale of the column,
+// so that no digits are lost or added by float formatting, and raw bytes are cast to a string.
+// other values, like strings or decimal.Decimal (decoded from binlog), are kept as they are.
+func castDecimal(data interface{}, scale int) interface{} {
+	switch v := data.(type) {
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', scale, 32)
+	case float64:
+		return strconv.FormatFloat(v, 'f', scale, 64)
+	case []byte:
+		return string(v)
+	}
+	return data
+}
+
+// floatFormatPrec returns the number of digits after the decimal point to format a float value of the column type,
+// it's the scale for DECIMAL column, and -1 (the minimum digits necessary) for others.
+func floatFormatPrec(tp string) int {
+	if precision, scale := parseDecimal(tp); precision > 0 {
+		return scale
+	}
+	return -1
+}
+
+func isJSONColumn(col *column) bool {
+	return strings.HasPrefix(strings.ToLower(col.tp), "json")
+}
+
+// castJSON casts JSON value to string.
+// JSON documents are decoded as []byte from binlog, but if they are bound as []byte (binary string),
+// MySQL reports `Cannot create a JSON value from a string with CHARACTER SET 'binary'`.
+func castJSON(data interface{}) interface{} {
+	if v, ok := data.([]byte); ok {
+		return string(v)
+	}
+	return data
+}
+
+// castEnumSet maps the integer index of ENUM or the integer bitmap of SET to the string labels
+func castEnumSet(data interface{}, col *column) interface{} {
+	var v uint64
+	switch d := data.(type) {
+	case int:
+		v = uint64(d)
+	case int8:
+		v = uint64(d)
+	case int16:
+		v = uint64(d)
+	case int32:
+		v = uint64(d)
+	case int64:
+		v = uint64(d)
+	case uint64:
+		v = d
+	default:
+		return data
+	}
+
+	if strings.HasPrefix(strings.ToLower(col.tp), "enum") {
+		// ENUM index starts from 1, 0 is the index of the empty string (error value)
+		if v == 0 || v > uint64(len(col.elems)) {
+			return ""
+		}
+		return col.elems[v-1]
+	}
+
+	labels := make([]string, 0, len(col.elems))
+	for i, elem := range col.elems {
+		if i < 64 && v&(1<<uint(i)) > 0 {
+			labels = append(labels, elem)
+		}
+	}
+	return strings.Join(labels, ",")
+}
+
+// parseEnumSetElems parses the elements from ENUM or SET column type, like `enum('a','b')` or `set('a','b')`
+func parseEnumSetElems(tp string) []string {
+	lower := strings.ToLower(tp)
+	if !strings.HasPrefix(lower, "enum(") && !strings.HasPrefix(lower, "set(") {
+		return nil
+	}
+	start := strings.Index(tp, "(")
+	end := strings.LastIndex(tp, ")")
+	if end <= start {
+		return nil
+	}
+
+	var (
+		elems   []string
+		elem    []byte
+		inQuote bool
+		body    = tp[start+1 : end]
+	)
+	for i := 0; i < len(body); i++ {
+		ch := body[i]
+		switch {
+		case ch == '\'' && inQuote && i+1 < len(body) && body[i+1] == '\'':
+			// escaped quote like 'a''b'
+			elem = append(elem, ch)
+			i++
+		case ch == '\'':
+			if inQuote {
+				elems = append(elems, string(elem))
+				elem = elem[:0]
+			}
+			inQuote = !inQuote
+		case inQuote:
+			elem = append(elem, ch)
+		}
+	}
+	return elems
 }
 
 func castUnsigned(data interface{}, unsigned bool, tp string) interface{} {
@@ -202,6 +1252,21 @@ func castUnsigned(data interface{}, unsigned bool, tp string) interface{} {
 		return data
 	}
 
+	if strings.Contains(strings.ToLower(tp), "mediumint") {
+		switch v := data.(type) {
+		case int:
+			return mediumIntUnsigned(int64(v))
+		case int8:
+			return mediumIntUnsigned(int64(v))
+		case int16:
+			return mediumIntUnsigned(int64(v))
+		case int32:
+			return mediumIntUnsigned(int64(v))
+		case int64:
+			return mediumIntUnsigned(v)
+		}
+	}
+
 	switch v := data.(type) {
 	case int:
 		return uint(v)
@@ -210,22 +1275,24 @@ func castUnsigned(data interface{}, unsigned bool, tp string) interface{} {
 	case int16:
 		return uint16(v)
 	case int32:
-		if strings.Contains(strings.ToLower(tp), "mediumint") {
-			// we use int32 to store MEDIUMINT, if the value is signed, it's fine
-			// but if the value is un-signed, simply convert it use `uint32` may out of the range
-			// like -4692783 converted to 4290274513 (2^32 - 4692783), but we expect 12084433 (2^24 - 4692783)
-			data := make([]byte, 4)
-			binary.LittleEndian.PutUint32(data, uint32(v))
-			return uint32(data[0]) | uint32(data[1])<<8 | uint32(data[2])<<16
-		}
 		return uint32(v)
 	case int64:
-		return strconv.FormatUint(uint64(v), 10)
+		return uint64(v)
+	case uint64:
+		return v
 	}
 
 	return data
 }
 
+// mediumIntUnsigned converts the signed value of an unsigned MEDIUMINT column to its unsigned value.
+// MEDIUMINT is stored in a wider integer, if the value is un-signed, simply convert it use `uint32` may out of the range
+// like -4692783 converted to 4290274513 (2^32 - 4692783), but we expect 12084433 (2^24 - 4692783),
+// so only the lower 24 bits are kept.
+func mediumIntUnsigned(v int64) uint32 {
+	return uint32(v) & (1<<24 - 1)
+}
+
 func columnValue(value interface{}, unsigned bool, tp string) string {
 	castValue := castUnsigned(value, unsigned, tp)
 
@@ -258,13 +1325,17 @@ func columnValue(value interface{}, unsigned bool, tp string) string {
 	case uint64:
 		data = strconv.FormatUint(uint64(v), 10)
 	case float32:
-		data = strconv.FormatFloat(float64(v), 'f', -1, 32)
+		data = strconv.FormatFloat(float64(v), 'f', floatFormatPrec(tp), 32)
 	case float64:
-		data = strconv.FormatFloat(float64(v), 'f', -1, 64)
+		data = strconv.FormatFloat(float64(v), 'f', floatFormatPrec(tp), 64)
 	case string:
 		data = v
 	case []byte:
 		data = string(v)
+	case time.Time:
+		data = formatTime(v, tp)
+	case time.Duration:
+		data = formatDuration(v, parseFsp(tp))
 	default:
 		data = fmt.Sprintf("%v", v)
 	}
@@ -272,6 +1343,67 @@ func columnValue(value interface{}, unsigned bool, tp string) string {
 	return data
 }
 
+// RenderSQL substitutes the placeholders in sql with the literals of values, columns[i] (if exists) is used to format values[i].
+// it's only used to log a fully-materialized statement for debugging, and the result should never be executed.
+func RenderSQL(sql string, values []interface{}, columns []*column) string {
+	var (
+		buf     bytes.Buffer
+		idx     int
+		inQuote bool // in backquoted identifier
+	)
+	for i := 0; i < len(sql); i++ {
+		ch := sql[i]
+		switch {
+		case ch == '`':
+			inQuote = !inQuote
+		case ch == '?' && !inQuote && idx < len(values):
+			var col *column
+			if idx < len(columns) {
+				col = columns[idx]
+			}
+			buf.WriteString(literalValue(values[idx], col))
+			idx++
+			continue
+		}
+		buf.WriteByte(ch)
+	}
+	return buf.String()
+}
+
+// literalValue formats value as a SQL literal, strings are quoted and escaped, binaries are hex-encoded.
+// values of BINARY and BLOB columns are always hex-encoded, and bytes of CHAR and TEXT columns are quoted as strings.
+func literalValue(value interface{}, col *column) string {
+	unsigned, tp := false, ""
+	if col != nil {
+		unsigned, tp = col.unsigned, col.tp
+		if col.bitWidth > 0 {
+			if v, ok := castBit(value, col, log.GlobalLogger()).(uint64); ok {
+				return bitLiteral(v, col.bitWidth)
+			}
+		}
+	}
+	switch v := castUnsigned(value, unsigned, tp).(type) {
+	case nil:
+		return "NULL"
+	case bool, int, int8, int16, int32, int64, uint8, uint16, uint32, uint64, float32, float64:
+		return columnValue(v, unsigned, tp)
+	case []byte:
+		if col == nil || col.binary || !isTextType(tp) {
+			return hexLiteral(v)
+		}
+		return "'" + literalEscaper.Replace(string(v)) + "'"
+	case string:
+		if col != nil && col.binary {
+			return hexLiteral([]byte(v))
+		}
+		return "'" + literalEscaper.Replace(v) + "'"
+	default:
+		return "'" + literalEscaper.Replace(columnValue(v, unsigned, tp)) + "'"
+	}
+}
+
+var literalEscaper = strings.NewReplacer("\\", "\\\\", "'", "\\'", "\x00", "\\0", "\n", "\\n", "\r", "\\r", "\x1a", "\\Z")
+
 func findColumn(columns []*column, indexColumn string) *column {
 	for _, column := range columns {
 		if column.name == indexColumn {
@@ -293,37 +1425,114 @@ func findColumns(columns []*column, indexColumns map[string][]string) map[string
 				cols = append(cols, column)
 			}
 		}
-		result[keyName] = cols
+		if len(cols) < len(indexCols) {
+			// a functional key part like `(lower(c))` (or a multi-valued one on JSON arrays) has no column,
+			// its values can't be computed from rows, and the rest columns are not unique.
+			// so the index is used neither in WHERE clauses nor in keys, like an index of a spatial column.
+			continue
+		}
+		if hasGeometryColumn(cols) {
+			// spatial values can't identify rows reliably, so the index is used neither in WHERE clauses nor in keys
+			continue
+		}
+		result[keyName] = sortColumnsByOrdinal(cols)
+	}
+
+	return result
+}
+
+func hasGeometryColumn(cols []*column) bool {
+	for _, col := range cols {
+		if col.geometry {
+			return true
+		}
+	}
+	return false
+}
+
+// sortColumnsByOrdinal sorts the columns of an index by their ordinal positions in table definition,
+// so the WHERE clauses and keys of rows are generated in a stable order.
+// cols is returned directly if it's sorted already, otherwise a sorted copy is returned.
+func sortColumnsByOrdinal(cols []*column) []*column {
+	less := func(a, b *column) bool { return a.idx < b.idx }
+	sorted := true
+	for i := 1; i < len(cols); i++ {
+		if less(cols[i], cols[i-1]) {
+			sorted = false
+			break
+		}
+	}
+	if sorted {
+		return cols
 	}
 
+	result := make([]*column, len(cols))
+	copy(result, cols)
+	sort.Slice(result, func(i, j int) bool { return less(result[i], result[j]) })
 	return result
 }
 
 func genKeyList(columns []*column, dataSeq []interface{}) string {
 	values := make([]string, 0, len(dataSeq))
 	for i, data := range dataSeq {
-		values = append(values, columnValue(data, columns[i].unsigned, columns[i].tp))
+		values = append(values, keySafeValue(data, columns[i]))
 	}
 
 	return strings.Join(values, ",")
 }
 
-func genMultipleKeys(columns []*column, value []interface{}, indexColumns map[string][]*column) []string {
+// keySafeValue returns the value used to build the key of a row.
+// the separator of the key (',') and the escape character ('\') in strings are escaped,
+// so values of adjacent string columns can not be combined into a same key.
+// values of binary columns are prefixed with their lengths instead, so any bytes are kept as what they are.
+// strings are folded by the collation of the column before escaped, so values equal under the collation have the same key.
+// NULL is encoded as nullKeyValue, which is different from any escaped string like "null" or `\N`.
+func keySafeValue(value interface{}, col *column) string {
+	if value == nil {
+		return nullKeyValue
+	}
+	data := columnValue(value, col.unsigned, col.tp)
+	switch value.(type) {
+	case string, []byte:
+		if col.binary {
+			return strconv.Itoa(len(data)) + ":" + data
+		}
+		data = foldCollationKey(data, col.collation)
+		if strings.ContainsAny(data, ",\\") {
+			data = keyEscaper.Replace(data)
+		}
+	}
+	return data
+}
+
+var keyEscaper = strings.NewReplacer("\\", "\\\\", ",", "\\,")
+
+// nullKeyValue is the value of NULL in keys, an unpaired escape character never appears in escaped strings
+const nullKeyValue = `\N`
+
+func genMultipleKeys(columns []*column, value []interface{}, indexColumns map[string][]*column, keyGen KeyGenerator) []string {
 	var multipleKeys []string
 	for _, indexCols := range indexColumns {
 		cols, vals := getColumnData(columns, indexCols, value)
-		multipleKeys = append(multipleKeys, genKeyList(cols, vals))
+		multipleKeys = append(multipleKeys, keyGen.GenKey(cols, vals))
 	}
 	return multipleKeys
 }
 
-func findFitIndex(indexColumns map[string][]*column) []*column {
+// findFitIndex finds the preferred unique key if it's not null, or else the primary key or the first not null unique key by name,
+// columns are returned in ordinal order. preferred is the name of the index in lower case, empty if none.
+// a prefix index is also fit, the full values of its columns are used in the WHERE clauses, which still match the same rows.
+func findFitIndex(indexColumns map[string][]*column, preferred string, logger log.Logger) []*column {
+	if cols := indexColumns[preferred]; preferred != "" && len(cols) > 0 && !hasNullableColumn(cols) {
+		return sortColumnsByOrdinal(cols)
+	}
+
 	cols, ok := indexColumns["primary"]
 	if ok {
 		if len(cols) == 0 {
-			log.Error("cols is empty")
+			logger.Errorf("cols is empty")
 		} else {
-			return cols
+			return sortColumnsByOrdinal(cols)
 		}
 	}
 
@@ -332,7 +1541,28 @@ func findFitIndex(indexColumns map[string][]*column) []*column {
 		return !c.NotNull
 	}
 
-	return getSpecifiedIndexColumn(indexColumns, fn)
+	return sortColumnsByOrdinal(getSpecifiedIndexColumn(indexColumns, fn))
+}
+
+// getRowIndexColumn returns the index identifying the row data, it's the preferred index if it has no NULL values in the row,
+// or else the default index found by findFitIndex, or else the first unique index without NULL values in the row.
+func getRowIndexColumn(indexColumns map[string][]*column, defaultIndexColumns []*column, preferred string, data []interface{}) []*column {
+	if cols := indexColumns[preferred]; preferred != "" && len(cols) > 0 && !hasNullValue(cols, data) {
+		return sortColumnsByOrdinal(cols)
+	}
+	if len(defaultIndexColumns) > 0 {
+		return defaultIndexColumns
+	}
+	return getAvailableIndexColumn(indexColumns, data)
+}
+
+func hasNullableColumn(cols []*column) bool {
+	for _, col := range cols {
+		if !col.NotNull {
+			return true
+		}
+	}
+	return false
 }
 
 func getAvailableIndexColumn(indexColumns map[string][]*column, data []interface{}) []*column {
@@ -343,8 +1573,17 @@ func getAvailableIndexColumn(indexColumns map[string][]*column, data []interface
 	return getSpecifiedIndexColumn(indexColumns, fn)
 }
 
+// getSpecifiedIndexColumn returns the first index without any column matched by fn,
+// indexes are checked in the order of their names, so the same index is chosen for every row.
 func getSpecifiedIndexColumn(indexColumns map[string][]*column, fn func(col *column) bool) []*column {
-	for _, indexCols := range indexColumns {
+	keyNames := make([]string, 0, len(indexColumns))
+	for keyName := range indexColumns {
+		keyNames = append(keyNames, keyName)
+	}
+	sort.Strings(keyNames)
+
+	for _, keyName := range keyNames {
+		indexCols := indexColumns[keyName]
 		if len(indexCols) == 0 {
 			continue
 		}
@@ -380,46 +1619,239 @@ func genWhere(columns []*column, data []interface{}) string {
 	var kvs bytes.Buffer
 	for i := range columns {
 		kvSplit := "="
+		placeholder := "?"
 		if data[i] == nil {
 			kvSplit = "IS"
+		} else if isJSONColumn(columns[i]) {
+			// compare JSON column with a JSON document rather than a string
+			placeholder = "CAST(? AS JSON)"
 		}
 
 		if i == len(columns)-1 {
-			fmt.Fprintf(&kvs, "`%s` %s ?", columns[i].name, kvSplit)
+			fmt.Fprintf(&kvs, "`%s` %s %s", columns[i].name, kvSplit, placeholder)
 		} else {
-			fmt.Fprintf(&kvs, "`%s` %s ? AND ", columns[i].name, kvSplit)
+			fmt.Fprintf(&kvs, "`%s` %s %s AND ", columns[i].name, kvSplit, placeholder)
 		}
 	}
 
 	return kvs.String()
 }
 
-func genKVs(columns []*column) string {
-	var kvs bytes.Buffer
-	for i := range columns {
-		if i == len(columns)-1 {
-			fmt.Fprintf(&kvs, "`%s` = ?", columns[i].name)
+// genWhereIn generates the `(a,b) IN ((?,?),(?,?))` predicate of columns for rows of dataSeq,
+// and returns the flattened values in the order of the placeholders.
+// NULL can't be matched in an IN list, so the indices of rows with NULL values are returned in nullRows
+// and those rows are left out of the predicate, the caller should handle them with genWhere one by one.
+func genWhereIn(columns []*column, dataSeq [][]interface{}) (where string, values []interface{}, nullRows []int) {
+	placeholders := make([]string, 0, len(columns))
+	names := make([]string, 0, len(columns))
+	for _, col := range columns {
+		names = append(names, fmt.Sprintf("`%s`", col.name))
+		if isJSONColumn(col) {
+			placeholders = append(placeholders, "CAST(? AS JSON)")
 		} else {
-			fmt.Fprintf(&kvs, "`%s` = ?, ", columns[i].name)
+			placeholders = append(placeholders, "?")
 		}
 	}
+	tuple := strings.Join(placeholders, ",")
+	if len(columns) > 1 {
+		tuple = fmt.Sprintf("(%s)", tuple)
+	}
 
-	return kvs.String()
+	tuples := make([]string, 0, len(dataSeq))
+	values = make([]interface{}, 0, len(dataSeq)*len(columns))
+	for i, data := range dataSeq {
+		if hasNullValue(columns, data) {
+			nullRows = append(nullRows, i)
+			continue
+		}
+		tuples = append(tuples, tuple)
+		for _, col := range columns {
+			values = append(values, data[col.idx])
+		}
+	}
+	if len(tuples) == 0 {
+		return "", nil, nullRows
+	}
+
+	lhs := strings.Join(names, ",")
+	if len(columns) > 1 {
+		lhs = fmt.Sprintf("(%s)", lhs)
+	}
+	return fmt.Sprintf("%s IN (%s)", lhs, strings.Join(tuples, ",")), values, nullRows
 }
 
-func (s *Syncer) mappingDML(schema, table string, columns []string, data [][]interface{}) ([][]interface{}, error) {
-	if s.columnMapping == nil {
-		return data, nil
+func hasNullValue(columns []*column, data []interface{}) bool {
+	for _, col := range columns {
+		if data[col.idx] == nil {
+			return true
+		}
 	}
-	var (
-		err  error
-		rows = make([][]interface{}, len(data))
-	)
-	for i := range data {
-		rows[i], _, err = s.columnMapping.HandleRowValue(schema, table, columns, data[i])
+	return false
+}
+
+// genKVs generates the assignments like `a` = ?, `b` = ? of columns in SET clauses
+func genKVs(columns []*column) string {
+	return clauses.get(clauseKVs, columns, writeKVs)
+}
+
+func writeKVs(buf *bytes.Buffer, columns []*column) {
+	for i, col := range columns {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteByte('`')
+		buf.WriteString(col.name)
+		buf.WriteString("` = ")
+		buf.WriteString(columnPlaceholder(col))
+	}
+}
+
+// ColumnExpression computes the value of a target column from the whole row of the source table,
+// columns are column names of the target table, and row is the row value after column mapping.
+type ColumnExpression func(schema, table string, columns []string, row []interface{}) (interface{}, error)
+
+// RegisterColumnExpression registers expr to compute the value of the target column for rows of the source table.
+// the value replaces the one in the row, or is appended to the row if the column is just after the last value.
+// it should be called before Process.
+func (s *Syncer) RegisterColumnExpression(schema, table, column string, expr ColumnExpression) {
+	if s.columnExprs == nil {
+		s.columnExprs = make(map[string]map[string]ColumnExpression)
+	}
+	id, _ := GenTableID(schema, table)
+	exprs, ok := s.columnExprs[id]
+	if !ok {
+		exprs = make(map[string]ColumnExpression)
+		s.columnExprs[id] = exprs
+	}
+	exprs[column] = expr
+}
+
+// mappingDML transforms rows of the source table by column mapping rules and column expressions,
+// and returns the columns renamed by RegisterColumnRename along with the rows.
+func (s *Syncer) mappingDML(schema, table string, columns []string, data [][]interface{}) ([]string, [][]interface{}, error) {
+	var err error
+	if s.columnMapping != nil {
+		rows := make([][]interface{}, len(data))
+		for i := range data {
+			rows[i], _, err = s.columnMapping.HandleRowValue(schema, table, columns, data[i])
+			if err != nil {
+				return nil, nil, errors.Trace(err)
+			}
+		}
+		data = rows
+	}
+
+	id, _ := GenTableID(schema, table)
+	if exprs, ok := s.columnExprs[id]; ok {
+		// rows of the binlog event are not changed if they are not mapped before
+		rows := make([][]interface{}, len(data))
+		for i := range data {
+			rows[i], err = evalColumnExpressions(schema, table, columns, data[i], exprs)
+			if err != nil {
+				return nil, nil, errors.Trace(err)
+			}
+		}
+		data = rows
+	}
+
+	renamed, err := renameColumns(schema, table, columns, s.columnRenames[id])
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	return renamed, data, nil
+}
+
+// RegisterColumnRename registers the column named from in the source table to be named to in the statements generated for its rows,
+// column mapping rules and column expressions still match the name from. it should be called before Process.
+func (s *Syncer) RegisterColumnRename(schema, table, from, to string) {
+	if s.columnRenames == nil {
+		s.columnRenames = make(map[string]map[string]string)
+	}
+	id, _ := GenTableID(schema, table)
+	renames, ok := s.columnRenames[id]
+	if !ok {
+		renames = make(map[string]string)
+		s.columnRenames[id] = renames
+	}
+	renames[from] = to
+}
+
+// renameColumns returns columns renamed by renames, or columns itself if none of them renamed.
+// a column can't be renamed to the name of another column, case-insensitively like MySQL.
+func renameColumns(schema, table string, columns []string, renames map[string]string) ([]string, error) {
+	if len(renames) == 0 {
+		return columns, nil
+	}
+
+	renamed := make([]string, len(columns))
+	names := make(map[string]string, len(columns)) // lower case name -> the column named it
+	for i, column := range columns {
+		renamed[i] = column
+		if to, ok := renames[column]; ok {
+			renamed[i] = to
+		}
+		name := strings.ToLower(renamed[i])
+		if other, ok := names[name]; ok {
+			return nil, errors.NotValidf("columns %s and %s of table %s both named %s after renamed", other, column, dbutil.TableName(schema, table), renamed[i])
+		}
+		names[name] = column
+	}
+	return renamed, nil
+}
+
+// renameTableColumns returns a copy of t whose columns are named renamed rather than columns, or t itself if none of them renamed.
+// t is shared by all source tables routed to it, so it's never modified.
+func renameTableColumns(t *table, columns, renamed []string) *table {
+	var copied *table
+	for i := range columns {
+		if i >= len(t.columns) || columns[i] == renamed[i] {
+			continue
+		}
+		if copied == nil {
+			copied = &table{schema: t.schema, name: t.name, columns: append([]*column(nil), t.columns...)}
+		}
+		col := *t.columns[i]
+		col.name = renamed[i]
+		copied.columns[i] = &col
+	}
+	if copied == nil {
+		return t
+	}
+
+	// index columns refer to the renamed columns
+	copied.indexColumns = make(map[string][]*column, len(t.indexColumns))
+	for key, cols := range t.indexColumns {
+		indexCols := make([]*column, 0, len(cols))
+		for _, col := range cols {
+			indexCols = append(indexCols, copied.columns[col.idx])
+		}
+		copied.indexColumns[key] = indexCols
+	}
+	return copied
+}
+
+// evalColumnExpressions evaluates expressions in the order of columns, so an expression can see values computed before it.
+// values are assigned to a copy of row, and row itself is not changed.
+func evalColumnExpressions(schema, table string, columns []string, row []interface{}, exprs map[string]ColumnExpression) ([]interface{}, error) {
+	row = append(make([]interface{}, 0, len(columns)), row...)
+	for idx, column := range columns {
+		expr, ok := exprs[column]
+		if !ok {
+			continue
+		}
+		if idx > len(row) {
+			return nil, errors.NotValidf("column %s at position %d for row with %d values", column, idx, len(row))
+		}
+
+		value, err := expr(schema, table, columns, row)
 		if err != nil {
-			return nil, errors.Trace(err)
+			return nil, errors.Annotatef(err, "evaluate expression of column %s", column)
+		}
+		if idx == len(row) {
+			row = append(row, value)
+		} else {
+			row[idx] = value
 		}
 	}
-	return rows, nil
+	return row, nil
 }
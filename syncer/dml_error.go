@@ -0,0 +1,52 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syncer
+
+import (
+	"fmt"
+
+	"github.com/pingcap/errors"
+)
+
+// errors of generating DML statements, they are the causes of the errors returned by genInsertSQLs, genUpdateSQLs and genDeleteSQLs,
+// so callers can tell the kind of failure by `errors.Cause(err) == ErrXXX`, while the messages keep the details.
+var (
+	// ErrColumnCountMismatch means the values of a row mismatch the columns of the table in count,
+	// the cached structure of the table may be out of date, like changed by DDL not synced.
+	ErrColumnCountMismatch = errors.New("columns and data mismatch in count")
+	// ErrNoUsableIndex means no unique index or columns can be used to identify the row to update or delete.
+	ErrNoUsableIndex = errors.New("no usable index")
+	// ErrUnsupportedType means the value of a column can't be converted to the value of the type in the target.
+	ErrUnsupportedType = errors.New("unsupported type")
+)
+
+// dmlError is an error of generating DML statements, its cause is one of the errors above
+type dmlError struct {
+	cause error
+	msg   string
+}
+
+// newDMLError returns a dmlError of cause with the details, with the stack trace
+func newDMLError(cause error, format string, args ...interface{}) error {
+	return errors.WithStack(&dmlError{cause: cause, msg: fmt.Sprintf(format, args...)})
+}
+
+func (e *dmlError) Error() string {
+	return e.msg
+}
+
+// Cause implements the causer of github.com/pingcap/errors
+func (e *dmlError) Cause() error {
+	return e.cause
+}
@@ -0,0 +1,81 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syncer
+
+import (
+	. "github.com/pingcap/check"
+	"github.com/pingcap/errors"
+
+	"github.com/pingcap/dm/dm/config"
+)
+
+func (s *testSyncerSuite) TestDMLErrors(c *C) {
+	columns := []*column{
+		{idx: 0, name: "id", NotNull: true, tp: "int(11)"},
+		{idx: 1, name: "a", tp: "int(11)"},
+	}
+	pk := map[string][]*column{"primary": {columns[0]}}
+	row := []interface{}{int32(1), int32(10)}
+	short := []interface{}{int32(1)}
+	opts := &dmlOptions{keyGen: testDMLOptions.keyGen}
+
+	// columns mismatch the rows, the messages keep the details
+	var errs []error
+	_, _, _, err := genInsertSQLs("db", "tbl", [][]interface{}{short}, columns, pk, 1, config.ConflictReplace, opts)
+	errs = append(errs, err)
+	c.Assert(err, ErrorMatches, "insert columns and data mismatch in length: 2 \\(columns\\) vs 1 \\(data\\)")
+	_, _, _, err = genUpdateSQLs("db", "tbl", [][]interface{}{row, short}, columns, pk, nil, false, opts)
+	errs = append(errs, err)
+	c.Assert(err, ErrorMatches, "update data mismatch in length: 2 \\(columns\\) vs 1 \\(data\\)")
+	_, _, _, err = genUpdateSQLs("db", "tbl", [][]interface{}{short, short}, columns, pk, nil, false, opts)
+	errs = append(errs, err)
+	c.Assert(err, ErrorMatches, "update columns and data mismatch in length: 2 \\(columns\\) vs 1 \\(data\\)")
+	_, _, _, err = genDeleteSQLs("db", "tbl", [][]interface{}{short}, columns, nil, opts)
+	errs = append(errs, err)
+	c.Assert(err, ErrorMatches, "delete columns and data mismatch in length: 2 \\(columns\\) vs 1 \\(data\\)")
+	// deleted in batch
+	_, _, _, err = genDeleteSQLs("db", "tbl", [][]interface{}{row, short}, columns, pk, opts)
+	errs = append(errs, err)
+	for _, err = range errs {
+		c.Assert(errors.Cause(err), Equals, ErrColumnCountMismatch)
+		// the cause is kept when annotated by callers
+		c.Assert(errors.Cause(errors.Annotate(err, "gen sqls")), Equals, ErrColumnCountMismatch)
+	}
+
+	// no index or columns to identify the row, spatial and generated columns are not used to match rows
+	spatial := []*column{
+		{idx: 0, name: "geo", tp: "geometry", geometry: true},
+		{idx: 1, name: "g", tp: "int(11)", IsGenerated: true},
+	}
+	oldRow := []interface{}{[]byte("\x00\x00\x00\x00point-1"), int32(1)}
+	changedRow := []interface{}{[]byte("\x00\x00\x00\x00point-2"), int32(2)}
+	_, _, _, err = genUpdateSQLs("db", "tbl", [][]interface{}{oldRow, changedRow}, spatial, nil, nil, false, opts)
+	c.Assert(err, ErrorMatches, "no unique index or columns to identify the updated row of table `db`.`tbl`")
+	c.Assert(errors.Cause(err), Equals, ErrNoUsableIndex)
+	_, _, _, err = genUpdateSQLs("db", "tbl", [][]interface{}{oldRow, changedRow}, spatial, nil, nil, true, opts)
+	c.Assert(errors.Cause(err), Equals, ErrNoUsableIndex)
+	_, _, _, err = genDeleteSQLs("db", "tbl", [][]interface{}{oldRow}, spatial, nil, opts)
+	c.Assert(err, ErrorMatches, "no unique index or columns to identify the deleted row of table `db`.`tbl`")
+	c.Assert(errors.Cause(err), Equals, ErrNoUsableIndex)
+
+	// values failed to cast
+	opts.casts = map[string]CastFunc{"int": func(value interface{}) (interface{}, error) {
+		return nil, errors.NotValidf("value %v", value)
+	}}
+	_, _, _, err = genInsertSQLs("db", "tbl", [][]interface{}{row}, columns, pk, 1, config.ConflictReplace, opts)
+	c.Assert(err, ErrorMatches, "cast value 1 of column id \\(int\\(11\\)\\): value 1 not valid")
+	c.Assert(errors.Cause(err), Equals, ErrUnsupportedType)
+	_, _, _, err = genDeleteSQLs("db", "tbl", [][]interface{}{row}, columns, pk, opts)
+	c.Assert(errors.Cause(err), Equals, ErrUnsupportedType)
+}
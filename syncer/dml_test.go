@@ -14,12 +14,43 @@
 package syncer
 
 import (
+	"bytes"
+	"fmt"
 	"math"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
 
 	. "github.com/pingcap/check"
+	"github.com/pingcap/errors"
+	cm "github.com/pingcap/tidb-tools/pkg/column-mapping"
+
+	"github.com/pingcap/dm/dm/config"
+	"github.com/pingcap/dm/pkg/log"
+	sm "github.com/pingcap/dm/syncer/safe-mode"
 )
 
+var testDMLOptions = &dmlOptions{keyGen: joinKeyGenerator{}}
+
+// capturingLogger is a log.Logger recording entries like `[warn] message`
+type capturingLogger struct {
+	sync.Mutex
+	entries []string
+}
+
+func (l *capturingLogger) record(level, format string, v ...interface{}) {
+	l.Lock()
+	defer l.Unlock()
+	l.entries = append(l.entries, fmt.Sprintf("[%s] ", level)+fmt.Sprintf(format, v...))
+}
+
+func (l *capturingLogger) Debugf(format string, v ...interface{}) { l.record("debug", format, v...) }
+func (l *capturingLogger) Infof(format string, v ...interface{})  { l.record("info", format, v...) }
+func (l *capturingLogger) Warnf(format string, v ...interface{})  { l.record("warn", format, v...) }
+func (l *capturingLogger) Errorf(format string, v ...interface{}) { l.record("error", format, v...) }
+
 func (s *testSyncerSuite) TestCastUnsigned(c *C) {
 	// ref: https://dev.mysql.com/doc/refman/5.7/en/integer-types.html
 	cases := []struct {
@@ -36,11 +67,1401 @@ func (s *testSyncerSuite) TestCastUnsigned(c *C) {
 		{int32(-math.Exp2(23)), true, "mediumint(8) unsigned", uint32(math.Exp2(23))},
 		{int32(-math.Exp2(31)), false, "int(11)", int32(-math.Exp2(31))}, // INT
 		{int32(-math.Exp2(31)), true, "int(10) unsigned", uint32(math.Exp2(31))},
-		{int64(-math.Exp2(63)), false, "bigint(20)", int64(-math.Exp2(63))},                                 // BIGINT
-		{int64(-math.Exp2(63)), true, "bigint(20) unsigned", strconv.FormatUint(uint64(math.Exp2(63)), 10)}, // special case use string to represent uint64
+		{int64(-math.Exp2(63)), false, "bigint(20)", int64(-math.Exp2(63))}, // BIGINT
+		{int64(-math.Exp2(63)), true, "bigint(20) unsigned", uint64(math.Exp2(63))},
+		{int64(-1), true, "bigint(20) unsigned", uint64(math.MaxUint64)}, // max unsigned BIGINT
+		{uint64(math.MaxUint64), true, "bigint(20) unsigned", uint64(math.MaxUint64)},
+		{uint64(math.MaxUint64), false, "bigint(20) unsigned", uint64(math.MaxUint64)},
 	}
 	for _, cs := range cases {
 		obtained := castUnsigned(cs.data, cs.unsigned, cs.Type)
 		c.Assert(obtained, Equals, cs.expected)
 	}
+
+	// unsigned BIGINT is formatted as a number in keys
+	c.Assert(columnValue(int64(-1), true, "bigint(20) unsigned"), Equals, strconv.FormatUint(math.MaxUint64, 10))
+}
+
+func (s *testSyncerSuite) TestMediumIntUnsigned(c *C) {
+	cases := []struct {
+		data     interface{}
+		expected uint32
+	}{
+		{int32(-4692783), 12084433}, // 2^24 - 4692783
+		{int32(0), 0},
+		{int32(-1), 1<<24 - 1},
+		{int32(-1 << 23), 1 << 23},    // min signed MEDIUMINT
+		{int32(1<<23 - 1), 1<<23 - 1}, // max signed MEDIUMINT
+		{int(-4692783), 12084433},     // int on 32-bit builds
+		{int64(-4692783), 12084433},   // mis-sized by the upstream parser
+		{int16(-1), 1<<24 - 1},
+		{int8(-1), 1<<24 - 1},
+		{int64(1<<24 - 1), 1<<24 - 1}, // max unsigned MEDIUMINT
+	}
+	for _, cs := range cases {
+		c.Assert(castUnsigned(cs.data, true, "mediumint(8) unsigned"), Equals, cs.expected, Commentf("data %v", cs.data))
+	}
+
+	// signed MEDIUMINT is kept
+	c.Assert(castUnsigned(int32(-4692783), false, "mediumint(9)"), Equals, int32(-4692783))
+	c.Assert(columnValue(int32(-4692783), true, "mediumint(8) unsigned"), Equals, "12084433")
+}
+
+func (s *testSyncerSuite) TestGenInsertSQLsBatch(c *C) {
+	columns := []*column{
+		{idx: 0, name: "id", NotNull: true, tp: "int(11)"},
+		{idx: 1, name: "name", tp: "varchar(20)"},
+	}
+	indexColumns := map[string][]*column{"primary": {columns[0]}}
+	dataSeq := [][]interface{}{
+		{int32(1), "a"},
+		{int32(2), "b"},
+		{int32(3), "c"},
+		{int32(4), "d"},
+		{int32(5), "e"},
+	}
+
+	sqls, keys, values, err := genInsertSQLs("db", "tbl", dataSeq, columns, indexColumns, 2, config.ConflictReplace, testDMLOptions)
+	c.Assert(err, IsNil)
+	c.Assert(sqls, HasLen, 3)
+	c.Assert(keys, HasLen, 3)
+	c.Assert(values, HasLen, 3)
+
+	c.Assert(sqls[0], Equals, "REPLACE INTO `db`.`tbl` (`id`,`name`) VALUES (?,?),(?,?);")
+	c.Assert(sqls[1], Equals, "REPLACE INTO `db`.`tbl` (`id`,`name`) VALUES (?,?),(?,?);")
+	c.Assert(sqls[2], Equals, "REPLACE INTO `db`.`tbl` (`id`,`name`) VALUES (?,?);")
+	for i, sql := range sqls {
+		c.Assert(strings.Count(sql, "?"), Equals, len(values[i]))
+	}
+	c.Assert(values[0], DeepEquals, []interface{}{int32(1), "a", int32(2), "b"})
+	c.Assert(values[2], DeepEquals, []interface{}{int32(5), "e"})
+	c.Assert(keys[0], DeepEquals, []string{"1", "2"})
+	c.Assert(keys[1], DeepEquals, []string{"3", "4"})
+	c.Assert(keys[2], DeepEquals, []string{"5"})
+
+	// fall back to single-row statements if the batch is too large
+	origSize := maxDMLPacketSize
+	maxDMLPacketSize = 1
+	defer func() {
+		maxDMLPacketSize = origSize
+	}()
+	sqls, keys, values, err = genInsertSQLs("db", "tbl", dataSeq, columns, indexColumns, 2, config.ConflictReplace, testDMLOptions)
+	c.Assert(err, IsNil)
+	c.Assert(sqls, HasLen, 5)
+	c.Assert(keys, HasLen, 5)
+	c.Assert(values, HasLen, 5)
+	for i, sql := range sqls {
+		c.Assert(sql, Equals, "REPLACE INTO `db`.`tbl` (`id`,`name`) VALUES (?,?);")
+		c.Assert(values[i], DeepEquals, dataSeq[i])
+	}
+}
+
+func (s *testSyncerSuite) TestGenInsertSQLsPartitioned(c *C) {
+	columns := []*column{
+		{idx: 0, name: "id", NotNull: true, tp: "int(11)"},
+		{idx: 1, name: "name", tp: "varchar(20)"},
+	}
+	indexColumns := map[string][]*column{"primary": {columns[0]}}
+	dataSeq := [][]interface{}{
+		{int32(1), "a"},
+		{int32(2), "b"},
+		{int32(3), "c"},
+		{int32(4), "d"},
+		{int32(5), "e"},
+		{int32(6), "f"},
+	}
+	// `PARTITION BY HASH(id) PARTITIONS 2`
+	hash := func(columns []string, row []interface{}) int {
+		c.Assert(columns, DeepEquals, []string{"id", "name"})
+		return int(row[0].(int32) % 2)
+	}
+	opts := &dmlOptions{keyGen: testDMLOptions.keyGen, partitions: map[string]PartitionFunc{"`db`.`tbl`": hash}}
+
+	sqls, keys, values, err := genInsertSQLs("db", "tbl", dataSeq, columns, indexColumns, 2, config.ConflictReplace, opts)
+	c.Assert(err, IsNil)
+	c.Assert(sqls, HasLen, 3)
+	for _, sql := range sqls {
+		c.Assert(sql, Equals, "REPLACE INTO `db`.`tbl` (`id`,`name`) VALUES (?,?),(?,?);")
+	}
+	// rows of the same partition are coalesced in their order, and no row is changed
+	c.Assert(values, DeepEquals, [][]interface{}{
+		{int32(1), "a", int32(3), "c"},
+		{int32(5), "e", int32(2), "b"},
+		{int32(4), "d", int32(6), "f"},
+	})
+	c.Assert(keys, DeepEquals, [][]string{{"1", "3"}, {"5", "2"}, {"4", "6"}})
+
+	// not grouped for other tables, or without batching
+	sqls, _, values, err = genInsertSQLs("db", "tbl2", dataSeq, columns, indexColumns, 2, config.ConflictReplace, opts)
+	c.Assert(err, IsNil)
+	c.Assert(sqls, HasLen, 3)
+	c.Assert(values[0], DeepEquals, []interface{}{int32(1), "a", int32(2), "b"})
+	sqls, _, values, err = genInsertSQLs("db", "tbl", dataSeq, columns, indexColumns, 1, config.ConflictReplace, opts)
+	c.Assert(err, IsNil)
+	c.Assert(sqls, HasLen, len(dataSeq))
+	for i := range values {
+		c.Assert(values[i], DeepEquals, dataSeq[i])
+	}
+
+	// registered for the target table
+	RegisterPartitionFunc("db", "tbl", hash)
+	c.Assert(registeredPartitionFuncs(), HasLen, 1)
+	c.Assert(registeredPartitionFuncs()["`db`.`tbl`"], NotNil)
+	UnregisterPartitionFunc("db", "tbl")
+	c.Assert(registeredPartitionFuncs(), IsNil)
+}
+
+func (s *testSyncerSuite) TestGenInsertSQLsStrictNotNull(c *C) {
+	columns := []*column{
+		{idx: 0, name: "id", NotNull: true, tp: "int(11)"},
+		{idx: 1, name: "name", NotNull: true, tp: "varchar(20)"},
+		{idx: 2, name: "note", tp: "varchar(20)"},
+		{idx: 3, name: "gen", NotNull: true, tp: "int(11)", IsGenerated: true},
+	}
+	indexColumns := map[string][]*column{"primary": {columns[0]}}
+	dataSeq := [][]interface{}{
+		{int32(1), "a", nil, nil},
+		{int32(2), nil, "b", nil},
+	}
+
+	// NULL values are passed to the target if it's not strict
+	opts := &dmlOptions{keyGen: joinKeyGenerator{}}
+	sqls, _, _, err := genInsertSQLs("db", "tbl", dataSeq, columns, indexColumns, 1, config.ConflictReplace, opts)
+	c.Assert(err, IsNil)
+	c.Assert(sqls, HasLen, 2)
+
+	// NULL values of nullable and generated columns are accepted
+	opts.strictNotNull = true
+	_, _, _, err = genInsertSQLs("db", "tbl", dataSeq[:1], columns, indexColumns, 1, config.ConflictReplace, opts)
+	c.Assert(err, IsNil)
+	_, _, _, err = genInsertSQLs("db", "tbl", dataSeq, columns, indexColumns, 1, config.ConflictReplace, opts)
+	c.Assert(errors.Cause(err).Error(), Equals, "NULL value for NOT NULL column `name` of table `db`.`tbl` not valid")
+}
+
+func (s *testSyncerSuite) TestGenInsertSQLsMaxStatementSize(c *C) {
+	columns := []*column{
+		{idx: 0, name: "id", NotNull: true, tp: "int(11)"},
+		{idx: 1, name: "name", tp: "varchar(200)"},
+	}
+	indexColumns := map[string][]*column{"primary": {columns[0]}}
+	name := strings.Repeat("x", 100)
+	dataSeq := [][]interface{}{
+		{int32(1), name},
+		{int32(2), name},
+		{int32(3), name},
+		{int32(4), name},
+		{int32(5), name},
+	}
+
+	// every row is estimated as 105 bytes, at most 2 rows fit in one statement
+	opts := &dmlOptions{keyGen: joinKeyGenerator{}, maxStatementSize: 250}
+	sqls, keys, values, err := genInsertSQLs("db", "tbl", dataSeq, columns, indexColumns, 10, config.ConflictReplace, opts)
+	c.Assert(err, IsNil)
+	c.Assert(sqls, DeepEquals, []string{
+		"REPLACE INTO `db`.`tbl` (`id`,`name`) VALUES (?,?),(?,?);",
+		"REPLACE INTO `db`.`tbl` (`id`,`name`) VALUES (?,?),(?,?);",
+		"REPLACE INTO `db`.`tbl` (`id`,`name`) VALUES (?,?);",
+	})
+	c.Assert(values, DeepEquals, [][]interface{}{
+		{int32(1), name, int32(2), name},
+		{int32(3), name, int32(4), name},
+		{int32(5), name},
+	})
+	c.Assert(keys, DeepEquals, [][]string{{"1", "2"}, {"3", "4"}, {"5"}})
+
+	// a row larger than the limit is inserted by its own statement
+	opts.maxStatementSize = 100
+	sqls, _, values, err = genInsertSQLs("db", "tbl", dataSeq, columns, indexColumns, 10, config.ConflictReplace, opts)
+	c.Assert(err, IsNil)
+	c.Assert(sqls, HasLen, 5)
+	for i, sql := range sqls {
+		c.Assert(sql, Equals, "REPLACE INTO `db`.`tbl` (`id`,`name`) VALUES (?,?);")
+		c.Assert(values[i], DeepEquals, dataSeq[i])
+	}
+
+	// the default limit is 3/4 of max_allowed_packet of the target, and at most 3/4 of maxDMLPacketSize
+	c.Assert(defaultMaxStatementSize(1<<20), Equals, 3<<18)
+	c.Assert(defaultMaxStatementSize(64<<20), Equals, maxDMLPacketSize/4*3)
+	c.Assert(defaultMaxStatementSize(0), Equals, maxDMLPacketSize/4*3)
+}
+
+func (s *testSyncerSuite) TestOversizedRow(c *C) {
+	columns := []*column{
+		{idx: 0, name: "id", NotNull: true, tp: "int(11)"},
+		{idx: 1, name: "data", tp: "blob"},
+	}
+	indexColumns := map[string][]*column{"primary": {columns[0]}}
+	blob := bytes.Repeat([]byte{'x'}, 200)
+	dataSeq := [][]interface{}{
+		{int32(1), []byte("a")},
+		{int32(2), []byte("b")},
+		{int32(3), blob},
+		{int32(4), []byte("c")},
+		{int32(5), []byte("d")},
+	}
+
+	// the row with the BLOB over the limit is isolated onto its own statement
+	opts := &dmlOptions{keyGen: joinKeyGenerator{}, maxStatementSize: 100, maxPacketSize: 1000}
+	sqls, keys, values, err := genInsertSQLs("db", "tbl", dataSeq, columns, indexColumns, 10, config.ConflictReplace, opts)
+	c.Assert(err, IsNil)
+	c.Assert(sqls, DeepEquals, []string{
+		"REPLACE INTO `db`.`tbl` (`id`,`data`) VALUES (?,?),(?,?);",
+		"REPLACE INTO `db`.`tbl` (`id`,`data`) VALUES (?,?);",
+		"REPLACE INTO `db`.`tbl` (`id`,`data`) VALUES (?,?),(?,?);",
+	})
+	c.Assert(values[1], DeepEquals, []interface{}{int32(3), blob})
+	c.Assert(keys, DeepEquals, [][]string{{"1", "2"}, {"3"}, {"4", "5"}})
+
+	// so is it deleted by a batched DELETE
+	indexColumns = map[string][]*column{"primary": {columns[0], columns[1]}}
+	sqls, _, values, err = genDeleteSQLs("db", "tbl", dataSeq, columns, indexColumns, opts)
+	c.Assert(err, IsNil)
+	c.Assert(sqls, DeepEquals, []string{
+		"DELETE FROM `db`.`tbl` WHERE (`id`,`data`) IN ((?,?),(?,?));",
+		"DELETE FROM `db`.`tbl` WHERE (`id`,`data`) IN ((?,?));",
+		"DELETE FROM `db`.`tbl` WHERE (`id`,`data`) IN ((?,?),(?,?));",
+	})
+	c.Assert(values[1], DeepEquals, []interface{}{int32(3), blob})
+
+	// the row can't be sent even by its own statement, the column and the size of the value are named
+	opts.maxPacketSize = 150
+	_, _, _, err = genInsertSQLs("db", "tbl", dataSeq, columns, indexColumns, 10, config.ConflictReplace, opts)
+	c.Assert(err, ErrorMatches, ".*row of table `db`.`tbl` of about 205 bytes exceeding the packet size limit 150 of the target, with 200 bytes of column `data`.*")
+	_, _, _, err = genDeleteSQLs("db", "tbl", dataSeq, columns, indexColumns, opts)
+	c.Assert(err, ErrorMatches, ".*with 200 bytes of column `data`.*")
+	_, _, _, err = genUpdateSQLs("db", "tbl", [][]interface{}{dataSeq[0], {int32(1), blob}}, columns, nil, nil, false, opts)
+	c.Assert(err, ErrorMatches, ".*with 200 bytes of column `data`.*")
+	_, _, _, err = genUpdateSQLs("db", "tbl", [][]interface{}{dataSeq[0], {int32(1), blob}}, columns, nil, nil, true, opts)
+	c.Assert(err, ErrorMatches, ".*with 200 bytes of column `data`.*")
+
+	// the packet size limit is no more than the limit of the driver, and rows are not checked if it is unknown
+	c.Assert((&dmlOptions{maxPacketSize: 64 << 20}).packetSizeLimit(), Equals, maxDMLPacketSize)
+	c.Assert((&dmlOptions{}).packetSizeLimit(), Equals, 0)
+}
+
+func (s *testSyncerSuite) TestGenInsertSQLsOnDuplicate(c *C) {
+	columns := []*column{
+		{idx: 0, name: "id", NotNull: true, tp: "int(11)"},
+		{idx: 1, name: "a", tp: "varchar(20)"},
+		{idx: 2, name: "b", tp: "int(11)"},
+	}
+	indexColumns := map[string][]*column{"primary": {columns[0]}}
+	dataSeq := [][]interface{}{
+		{int32(1), "a", int32(10)},
+		{int32(2), "b", int32(20)},
+	}
+
+	replaceSQLs, replaceKeys, replaceValues, err := genInsertSQLs("db", "tbl", dataSeq, columns, indexColumns, 1, config.ConflictReplace, testDMLOptions)
+	c.Assert(err, IsNil)
+	sqls, keys, values, err := genInsertSQLs("db", "tbl", dataSeq, columns, indexColumns, 1, config.ConflictOnDuplicate, testDMLOptions)
+	c.Assert(err, IsNil)
+	c.Assert(sqls, HasLen, 2)
+	for _, sql := range sqls {
+		c.Assert(sql, Equals, "INSERT INTO `db`.`tbl` (`id`,`a`,`b`) VALUES (?,?,?) ON DUPLICATE KEY UPDATE `id`=VALUES(`id`),`a`=VALUES(`a`),`b`=VALUES(`b`);")
+	}
+	for _, sql := range replaceSQLs {
+		c.Assert(sql, Equals, "REPLACE INTO `db`.`tbl` (`id`,`a`,`b`) VALUES (?,?,?);")
+	}
+	c.Assert(keys, DeepEquals, replaceKeys)
+	c.Assert(values, DeepEquals, replaceValues)
+
+	// multi-row statement
+	sqls, _, values, err = genInsertSQLs("db", "tbl", dataSeq, columns, indexColumns, 2, config.ConflictOnDuplicate, testDMLOptions)
+	c.Assert(err, IsNil)
+	c.Assert(sqls, DeepEquals, []string{"INSERT INTO `db`.`tbl` (`id`,`a`,`b`) VALUES (?,?,?),(?,?,?) ON DUPLICATE KEY UPDATE `id`=VALUES(`id`),`a`=VALUES(`a`),`b`=VALUES(`b`);"})
+	c.Assert(values, DeepEquals, [][]interface{}{{int32(1), "a", int32(10), int32(2), "b", int32(20)}})
+}
+
+func (s *testSyncerSuite) TestGenInsertSQLsIgnore(c *C) {
+	columns := []*column{
+		{idx: 0, name: "id", NotNull: true, tp: "int(11)"},
+		{idx: 1, name: "a", tp: "varchar(20)"},
+	}
+	indexColumns := map[string][]*column{"primary": {columns[0]}}
+	dataSeq := [][]interface{}{{int32(1), "a"}, {int32(2), "b"}}
+
+	_, replaceKeys, replaceValues, err := genInsertSQLs("db", "tbl", dataSeq, columns, indexColumns, 2, config.ConflictReplace, testDMLOptions)
+	c.Assert(err, IsNil)
+	sqls, keys, values, err := genInsertSQLs("db", "tbl", dataSeq, columns, indexColumns, 2, config.ConflictIgnore, testDMLOptions)
+	c.Assert(err, IsNil)
+	c.Assert(sqls, DeepEquals, []string{"INSERT IGNORE INTO `db`.`tbl` (`id`,`a`) VALUES (?,?),(?,?);"})
+	c.Assert(keys, DeepEquals, replaceKeys)
+	c.Assert(values, DeepEquals, replaceValues)
+}
+
+func (s *testSyncerSuite) TestGenKeyListEscape(c *C) {
+	columns := []*column{
+		{idx: 0, name: "a", tp: "varchar(20)"},
+		{idx: 1, name: "b", tp: "varchar(20)"},
+	}
+
+	cases := []struct {
+		data1 []interface{}
+		data2 []interface{}
+	}{
+		{[]interface{}{"a,b", ""}, []interface{}{"a", "b"}},
+		{[]interface{}{"a", ",b"}, []interface{}{"a,", "b"}},
+		{[]interface{}{"a\\", ",b"}, []interface{}{"a\\,", "b"}},
+		{[]interface{}{[]byte("a,b"), []byte("")}, []interface{}{[]byte("a"), []byte("b")}},
+	}
+	for _, cs := range cases {
+		key1 := genKeyList(columns, cs.data1)
+		key2 := genKeyList(columns, cs.data2)
+		c.Assert(key1, Not(Equals), key2)
+	}
+
+	// values without separator are kept as what they are
+	c.Assert(genKeyList(columns, []interface{}{"a", "b"}), Equals, "a,b")
+	c.Assert(genKeyList(columns, []interface{}{"a,b", "c"}), Equals, "a\\,b,c")
+}
+
+func (s *testSyncerSuite) TestGenKeyListNull(c *C) {
+	columns := []*column{
+		{idx: 0, name: "a", tp: "varchar(20)"},
+		{idx: 1, name: "b", tp: "varbinary(20)", binary: true},
+	}
+
+	cases := []struct {
+		data1 []interface{}
+		data2 []interface{}
+	}{
+		{[]interface{}{nil, "b"}, []interface{}{"null", "b"}},
+		{[]interface{}{nil, "b"}, []interface{}{"NULL", "b"}},
+		{[]interface{}{nil, "b"}, []interface{}{"\\N", "b"}},
+		{[]interface{}{nil, "b"}, []interface{}{"", "b"}},
+		{[]interface{}{"a", nil}, []interface{}{"a", []byte("null")}},
+		{[]interface{}{"a", nil}, []interface{}{"a", []byte("\\N")}},
+		{[]interface{}{"a", nil}, []interface{}{"a", []byte("")}},
+		{[]interface{}{nil, nil}, []interface{}{"\\N,\\N", ""}},
+	}
+	for _, cs := range cases {
+		key1 := genKeyList(columns, cs.data1)
+		key2 := genKeyList(columns, cs.data2)
+		c.Assert(key1, Not(Equals), key2, Commentf("values %v and %v", cs.data1, cs.data2))
+	}
+	c.Assert(genKeyList(columns, []interface{}{nil, nil}), Equals, "\\N,\\N")
+	c.Assert(genKeyList(columns, []interface{}{"null", nil}), Equals, "null,\\N")
+	c.Assert(NewKeyGenerator(config.KeyStrategyJoin).GenKey(columns, []interface{}{nil, "b"}), Not(Equals), NewKeyGenerator(config.KeyStrategyJoin).GenKey(columns, []interface{}{"null", "b"}))
+}
+
+func (s *testSyncerSuite) TestGenInsertSQLsMissingColumns(c *C) {
+	columns := []*column{
+		{idx: 0, name: "id", tp: "int(11)"},
+		{idx: 1, name: "name", tp: "varchar(20)"},
+		{idx: 2, name: "age", tp: "int(11)", defaultValue: "18"},
+		{idx: 3, name: "note", tp: "varchar(20)"},
+		{idx: 4, name: "age2", tp: "int(11)", IsGenerated: true},
+	}
+	indexColumns := map[string][]*column{"primary": {columns[0]}}
+	// the row is written before `age`, `note` and `age2` are added upstream
+	data := [][]interface{}{{1, "a", 20, "x", nil}, {2, "b"}}
+
+	// rejected by default
+	opts := &dmlOptions{keyGen: joinKeyGenerator{}}
+	_, _, _, err := genInsertSQLs("db", "tbl", data, columns, indexColumns, 1, config.ConflictReplace, opts)
+	c.Assert(err, ErrorMatches, "insert columns and data mismatch in length: 5 \\(columns\\) vs 2 \\(data\\)")
+
+	opts.fillMissingColumns = true
+	sqls, keys, values, err := genInsertSQLs("db", "tbl", data, columns, indexColumns, 2, config.ConflictReplace, opts)
+	c.Assert(err, IsNil)
+	c.Assert(sqls, DeepEquals, []string{"REPLACE INTO `db`.`tbl` (`id`,`name`,`age`,`note`) VALUES (?,?,?,?),(?,?,?,?);"})
+	c.Assert(keys, DeepEquals, [][]string{{"1", "2"}})
+	c.Assert(values, DeepEquals, [][]interface{}{{1, "a", 20, "x", 2, "b", "18", nil}})
+
+	// rows longer than columns are always rejected
+	_, _, _, err = genInsertSQLs("db", "tbl", [][]interface{}{{1, "a", 20, "x", nil, 1}}, columns, indexColumns, 1, config.ConflictReplace, opts)
+	c.Assert(err, ErrorMatches, "insert columns and data mismatch in length: 5 \\(columns\\) vs 6 \\(data\\)")
+
+	// DEFAULT expressions can't be bound as values
+	columns[3].defaultValue, columns[3].defaultExpr = "CURRENT_TIMESTAMP", true
+	_, _, _, err = genInsertSQLs("db", "tbl", data, columns, indexColumns, 1, config.ConflictReplace, opts)
+	c.Assert(err, ErrorMatches, "fill column note missing in row with DEFAULT expression CURRENT_TIMESTAMP not supported")
+
+	c.Assert(isDefaultExpr("CURRENT_TIMESTAMP(3)"), IsTrue)
+	c.Assert(isDefaultExpr("b'1'"), IsTrue)
+	c.Assert(isDefaultExpr("current_timestamp"), IsTrue)
+	c.Assert(isDefaultExpr("18"), IsFalse)
+	c.Assert(isDefaultExpr("b"), IsFalse)
+}
+
+func (s *testSyncerSuite) TestGenUpdateSQLsChangedColumns(c *C) {
+	columns := []*column{
+		{idx: 0, name: "id", NotNull: true, tp: "int(11)"},
+		{idx: 1, name: "a", tp: "varchar(20)"},
+		{idx: 2, name: "b", tp: "int(11)"},
+		{idx: 3, name: "c", tp: "blob"},
+	}
+	indexColumns := map[string][]*column{"primary": {columns[0]}}
+	data := [][]interface{}{
+		{int32(1), "a", int32(10), []byte("c")},
+		{int32(1), "a", int32(11), []byte("c")},
+		// no column changed
+		{int32(2), "b", int32(20), []byte("d")},
+		{int32(2), "b", int32(20), []byte("d")},
+	}
+
+	sqls, keys, values, err := genUpdateSQLs("db", "tbl", data, columns, indexColumns, nil, false, testDMLOptions)
+	c.Assert(err, IsNil)
+	c.Assert(sqls, DeepEquals, []string{"UPDATE `db`.`tbl` SET `b` = ? WHERE `id` = ? LIMIT 1;"})
+	c.Assert(values, DeepEquals, [][]interface{}{{int32(11), int32(1)}})
+	c.Assert(keys, DeepEquals, [][]string{{"1", "1"}})
+}
+
+func (s *testSyncerSuite) TestGenUpdateSQLsMinimalRowImage(c *C) {
+	columns := []*column{
+		{idx: 0, name: "id", NotNull: true, tp: "int(11)"},
+		{idx: 1, name: "a", NotNull: true, tp: "int(11)"},
+		{idx: 2, name: "b", tp: "varchar(20)"},
+		{idx: 3, name: "c", NotNull: true, tp: "int(11)"},
+	}
+	pk := map[string][]*column{"primary": {columns[0]}}
+	c.Assert(newRowImages([]byte{0x0f}, []byte{0x0f}, len(columns)), IsNil)
+
+	// the before image has the primary key only, and the after image has the columns set,
+	// columns missing in images are NULL, and they are not written to the target.
+	cases := []struct {
+		after   byte
+		changed []interface{}
+		sql     string
+		values  []interface{}
+		keys    []string
+	}{
+		{0x02, []interface{}{nil, int32(20), nil, nil}, "UPDATE `db`.`tbl` SET `a` = ? WHERE `id` = ? LIMIT 1;", []interface{}{int32(20), int32(1)}, []string{"1", "1"}},
+		// set to NULL
+		{0x04, []interface{}{nil, nil, nil, nil}, "UPDATE `db`.`tbl` SET `b` = ? WHERE `id` = ? LIMIT 1;", []interface{}{nil, int32(1)}, []string{"1", "1"}},
+		// the primary key changed
+		{0x03, []interface{}{int32(2), int32(20), nil, nil}, "UPDATE `db`.`tbl` SET `id` = ?, `a` = ? WHERE `id` = ? LIMIT 1;", []interface{}{int32(2), int32(20), int32(1)}, []string{"1", "2"}},
+	}
+	for _, tc := range cases {
+		data := [][]interface{}{{int32(1), nil, nil, nil}, tc.changed}
+		images := newRowImages([]byte{0x01}, []byte{tc.after}, len(columns))
+		c.Assert(images, NotNil)
+		// no DELETE and REPLACE with partial rows in safe mode
+		for _, safeMode := range []bool{false, true} {
+			sqls, keys, values, err := genUpdateSQLs("db", "tbl", data, columns, pk, images, safeMode, testDMLOptions)
+			c.Assert(err, IsNil)
+			c.Assert(sqls, DeepEquals, []string{tc.sql})
+			c.Assert(values, DeepEquals, [][]interface{}{tc.values})
+			c.Assert(keys, DeepEquals, [][]string{tc.keys})
+		}
+	}
+
+	// the before image has all columns without primary key
+	data := [][]interface{}{{int32(1), int32(10), "x", int32(100)}, {nil, int32(20), nil, nil}}
+	sqls, _, values, err := genUpdateSQLs("db", "tbl", data, columns, nil, newRowImages([]byte{0x0f}, []byte{0x02}, len(columns)), false, testDMLOptions)
+	c.Assert(err, IsNil)
+	c.Assert(sqls, DeepEquals, []string{"UPDATE `db`.`tbl` SET `a` = ? WHERE `id` = ? AND `a` = ? AND `b` = ? AND `c` = ? LIMIT 1;"})
+	c.Assert(values, DeepEquals, [][]interface{}{{int32(20), int32(1), int32(10), "x", int32(100)}})
+
+	// the primary key missing in the before image isn't used to match the row
+	data = [][]interface{}{{nil, int32(10), nil, nil}, {nil, int32(20), nil, nil}}
+	sqls, _, values, err = genUpdateSQLs("db", "tbl", data, columns, pk, newRowImages([]byte{0x02}, []byte{0x02}, len(columns)), false, testDMLOptions)
+	c.Assert(err, IsNil)
+	c.Assert(sqls, DeepEquals, []string{"UPDATE `db`.`tbl` SET `a` = ? WHERE `a` = ? LIMIT 1;"})
+	c.Assert(values, DeepEquals, [][]interface{}{{int32(20), int32(10)}})
+}
+
+func (s *testSyncerSuite) TestGenUpdateSQLsWithoutIndex(c *C) {
+	// no primary key, and the only unique index is nullable
+	columns := []*column{
+		{idx: 0, name: "id", tp: "int(11)"},
+		{idx: 1, name: "a", tp: "varchar(20)"},
+	}
+	indexColumns := map[string][]*column{"uk": {columns[1]}}
+	data := [][]interface{}{
+		{int32(1), nil},
+		{int32(2), nil},
+		{int32(3), "x"},
+		{int32(4), "x"},
+		// the index is not usable again, though it's usable for the previous row
+		{int32(5), nil},
+		{int32(6), nil},
+	}
+	expectedValues := [][]interface{}{{int32(2), int32(1), nil}, {int32(4), "x"}, {int32(6), int32(5), nil}}
+
+	sqls, _, values, err := genUpdateSQLs("db", "tbl", data, columns, indexColumns, nil, false, testDMLOptions)
+	c.Assert(err, IsNil)
+	c.Assert(sqls, DeepEquals, []string{
+		"UPDATE `db`.`tbl` SET `id` = ? WHERE `id` = ? AND `a` IS ? LIMIT 1;",
+		"UPDATE `db`.`tbl` SET `id` = ? WHERE `a` = ? LIMIT 1;",
+		"UPDATE `db`.`tbl` SET `id` = ? WHERE `id` = ? AND `a` IS ? LIMIT 1;",
+	})
+	c.Assert(values, DeepEquals, expectedValues)
+
+	// update all duplicate rows matched by the full-column WHERE
+	opts := &dmlOptions{keyGen: joinKeyGenerator{}, updateAllDuplicates: true}
+	sqls, _, values, err = genUpdateSQLs("db", "tbl", data, columns, indexColumns, nil, false, opts)
+	c.Assert(err, IsNil)
+	c.Assert(sqls, DeepEquals, []string{
+		"UPDATE `db`.`tbl` SET `id` = ? WHERE `id` = ? AND `a` IS ?;",
+		"UPDATE `db`.`tbl` SET `id` = ? WHERE `a` = ? LIMIT 1;",
+		"UPDATE `db`.`tbl` SET `id` = ? WHERE `id` = ? AND `a` IS ?;",
+	})
+	c.Assert(values, DeepEquals, expectedValues)
+
+	// safe mode
+	sqls, _, values, err = genUpdateSQLs("db", "tbl", data[:2], columns, indexColumns, nil, true, testDMLOptions)
+	c.Assert(err, IsNil)
+	c.Assert(sqls, DeepEquals, []string{
+		"DELETE FROM `db`.`tbl` WHERE `id` = ? AND `a` IS ? LIMIT 1;",
+		"REPLACE INTO `db`.`tbl` (`id`,`a`) VALUES (?,?);",
+	})
+	c.Assert(values, DeepEquals, [][]interface{}{{int32(1), nil}, {int32(2), nil}})
+}
+
+func (s *testSyncerSuite) TestGenUpdateSQLsSafeModeWindow(c *C) {
+	columns := []*column{
+		{idx: 0, name: "id", NotNull: true, tp: "int(11)"},
+		{idx: 1, name: "a", tp: "varchar(20)"},
+	}
+	indexColumns := map[string][]*column{"primary": {columns[0]}}
+	data := [][]interface{}{{int32(1), "a"}, {int32(1), "b"}}
+
+	resumeAt := time.Unix(1557000000, 0)
+	safeMode := sm.NewSafeModeController(time.Minute)
+	safeMode.Resume(resumeAt)
+	cases := []struct {
+		eventTime time.Time
+		sqls      []string
+	}{
+		// replicated again after resumed
+		{resumeAt.Add(-time.Hour), []string{"DELETE FROM `db`.`tbl` WHERE `id` = ?;", "REPLACE INTO `db`.`tbl` (`id`,`a`) VALUES (?,?);"}},
+		{resumeAt.Add(59 * time.Second), []string{"DELETE FROM `db`.`tbl` WHERE `id` = ?;", "REPLACE INTO `db`.`tbl` (`id`,`a`) VALUES (?,?);"}},
+		// out of the window
+		{resumeAt.Add(time.Minute), []string{"UPDATE `db`.`tbl` SET `a` = ? WHERE `id` = ? LIMIT 1;"}},
+		{resumeAt.Add(time.Hour), []string{"UPDATE `db`.`tbl` SET `a` = ? WHERE `id` = ? LIMIT 1;"}},
+	}
+	for _, cs := range cases {
+		now := cs.eventTime.Add(time.Second) // replication lag
+		enable := safeMode.EnableFor(uint32(cs.eventTime.Unix()), now)
+		sqls, _, _, err := genUpdateSQLs("db", "tbl", data, columns, indexColumns, nil, enable, testDMLOptions)
+		c.Assert(err, IsNil)
+		c.Assert(sqls, DeepEquals, cs.sqls, Commentf("event at %v", cs.eventTime))
+	}
+}
+
+func (s *testSyncerSuite) TestGenDeleteSQLsBatch(c *C) {
+	columns := []*column{
+		{idx: 0, name: "id", NotNull: true, tp: "int(11)"},
+		{idx: 1, name: "a", NotNull: true, tp: "int(11)"},
+		{idx: 2, name: "b", tp: "varchar(20)"},
+	}
+	dataSeq := [][]interface{}{
+		{int32(1), int32(10), "a"},
+		{int32(2), int32(20), "b"},
+		{int32(3), int32(30), "c"},
+	}
+
+	// single column primary key
+	indexColumns := map[string][]*column{"primary": {columns[0]}}
+	sqls, keys, values, err := genDeleteSQLs("db", "tbl", dataSeq, columns, indexColumns, testDMLOptions)
+	c.Assert(err, IsNil)
+	c.Assert(sqls, DeepEquals, []string{"DELETE FROM `db`.`tbl` WHERE `id` IN (?,?,?);"})
+	c.Assert(values, DeepEquals, [][]interface{}{{int32(1), int32(2), int32(3)}})
+	// one entry for every row
+	c.Assert(keys, DeepEquals, [][]string{{"1"}, {"2"}, {"3"}})
+
+	// split if the statement is too large
+	origSize := maxDMLPacketSize
+	maxDMLPacketSize = 4
+	sqls, keys, values, rows, err := genDeleteSQLsWithRows("db", "tbl", dataSeq, columns, indexColumns, testDMLOptions)
+	maxDMLPacketSize = origSize
+	c.Assert(err, IsNil)
+	c.Assert(sqls, DeepEquals, []string{"DELETE FROM `db`.`tbl` WHERE `id` IN (?,?);", "DELETE FROM `db`.`tbl` WHERE `id` IN (?);"})
+	c.Assert(values, DeepEquals, [][]interface{}{{int32(1), int32(2)}, {int32(3)}})
+	c.Assert(keys, DeepEquals, [][]string{{"1"}, {"2"}, {"3"}})
+	c.Assert(rows, DeepEquals, []int{2, 1})
+
+	// multiple columns primary key
+	indexColumns = map[string][]*column{"primary": {columns[0], columns[1]}}
+	sqls, keys, values, err = genDeleteSQLs("db", "tbl", dataSeq, columns, indexColumns, testDMLOptions)
+	c.Assert(err, IsNil)
+	c.Assert(sqls, DeepEquals, []string{"DELETE FROM `db`.`tbl` WHERE (`id`,`a`) IN ((?,?),(?,?),(?,?));"})
+	c.Assert(values, DeepEquals, [][]interface{}{{int32(1), int32(10), int32(2), int32(20), int32(3), int32(30)}})
+	c.Assert(keys, DeepEquals, [][]string{{"1,10"}, {"2,20"}, {"3,30"}})
+
+	// no index, fall back to per-row statements
+	sqls, _, values, err = genDeleteSQLs("db", "tbl", dataSeq, columns, nil, testDMLOptions)
+	c.Assert(err, IsNil)
+	c.Assert(sqls, HasLen, 3)
+	for i, sql := range sqls {
+		c.Assert(sql, Equals, "DELETE FROM `db`.`tbl` WHERE `id` = ? AND `a` = ? AND `b` = ? LIMIT 1;")
+		c.Assert(values[i], DeepEquals, dataSeq[i])
+	}
+}
+
+func (s *testSyncerSuite) TestGenUpdateSQLsKeyChanged(c *C) {
+	columns := []*column{
+		{idx: 0, name: "id", NotNull: true, tp: "int(11)"},
+		{idx: 1, name: "a", tp: "int(11)"},
+	}
+	indexColumns := map[string][]*column{"primary": {columns[0]}}
+	data := [][]interface{}{
+		{int32(1), int32(10)}, {int32(2), int32(10)}, // primary key changed
+		{int32(3), int32(30)}, {int32(3), int32(31)}, // other columns changed
+	}
+
+	sqls, keys, values, err := genUpdateSQLs("db", "tbl", data, columns, indexColumns, nil, false, testDMLOptions)
+	c.Assert(err, IsNil)
+	c.Assert(sqls, DeepEquals, []string{
+		"DELETE FROM `db`.`tbl` WHERE `id` = ?;",
+		"REPLACE INTO `db`.`tbl` (`id`,`a`) VALUES (?,?);",
+		"UPDATE `db`.`tbl` SET `a` = ? WHERE `id` = ? LIMIT 1;",
+	})
+	c.Assert(values, DeepEquals, [][]interface{}{{int32(1)}, {int32(2), int32(10)}, {int32(31), int32(3)}})
+	c.Assert(keys, DeepEquals, [][]string{{"1", "2"}, {"1", "2"}, {"3", "3"}})
+
+	// not null unique key is used as the primary key
+	indexColumns = map[string][]*column{"uk": {columns[0]}}
+	sqls, _, _, err = genUpdateSQLs("db", "tbl", data[:2], columns, indexColumns, nil, false, testDMLOptions)
+	c.Assert(err, IsNil)
+	c.Assert(sqls, DeepEquals, []string{"DELETE FROM `db`.`tbl` WHERE `id` = ?;", "REPLACE INTO `db`.`tbl` (`id`,`a`) VALUES (?,?);"})
+
+	// rows identified by a nullable unique key or all columns are updated
+	for _, indexColumns = range []map[string][]*column{{"uk": {columns[1]}}, nil} {
+		data = [][]interface{}{{int32(1), int32(10)}, {int32(1), int32(11)}}
+		sqls, _, _, err = genUpdateSQLs("db", "tbl", data, columns, indexColumns, nil, false, testDMLOptions)
+		c.Assert(err, IsNil)
+		c.Assert(sqls, HasLen, 1)
+		c.Assert(sqls[0], Matches, "UPDATE `db`.`tbl` SET `a` = \\? WHERE .*")
+	}
+}
+
+func (s *testSyncerSuite) TestGenDeleteSQLLimit(c *C) {
+	columns := []*column{
+		{idx: 0, name: "id", NotNull: true, tp: "int(11)"},
+		{idx: 1, name: "a", tp: "int(11)"},
+		{idx: 2, name: "b", tp: "varchar(20)"},
+	}
+	cases := []struct {
+		indexColumns []*column
+		value        []interface{}
+		sql          string
+		args         []interface{}
+	}{
+		// primary key
+		{columns[:1], []interface{}{int32(1), int32(10), "a"}, "DELETE FROM `db`.`tbl` WHERE `id` = ?;", []interface{}{int32(1)}},
+		// unique key
+		{columns[1:], []interface{}{int32(1), int32(10), "a"}, "DELETE FROM `db`.`tbl` WHERE `a` = ? AND `b` = ?;", []interface{}{int32(10), "a"}},
+		// unique key with NULL matches duplicate rows
+		{columns[1:], []interface{}{int32(1), nil, "a"}, "DELETE FROM `db`.`tbl` WHERE `a` IS ? AND `b` = ? LIMIT 1;", []interface{}{nil, "a"}},
+		// no index
+		{nil, []interface{}{int32(1), int32(10), "a"}, "DELETE FROM `db`.`tbl` WHERE `id` = ? AND `a` = ? AND `b` = ? LIMIT 1;", []interface{}{int32(1), int32(10), "a"}},
+	}
+	for _, cs := range cases {
+		sql, args := genDeleteSQL("db", "tbl", cs.value, columns, cs.indexColumns, testDMLOptions)
+		c.Assert(sql, Equals, cs.sql)
+		c.Assert(args, DeepEquals, cs.args)
+	}
+}
+
+func (s *testSyncerSuite) TestJSONColumn(c *C) {
+	columns := []*column{
+		{idx: 0, name: "id", NotNull: true, tp: "int(11)"},
+		{idx: 1, name: "j", tp: "json"},
+	}
+	nested := `{"a": {"b": {"c": [1, 2, {"d": null}]}}}`
+	dataSeq := [][]interface{}{
+		{int32(1), nil},
+		{int32(2), []byte("{}")},
+		{int32(3), []byte(nested)},
+	}
+
+	_, _, values, err := genInsertSQLs("db", "tbl", dataSeq, columns, nil, 1, config.ConflictReplace, testDMLOptions)
+	c.Assert(err, IsNil)
+	c.Assert(values, DeepEquals, [][]interface{}{{int32(1), nil}, {int32(2), "{}"}, {int32(3), nested}})
+
+	// no index, JSON column is compared with a JSON document
+	sqls, _, values, err := genDeleteSQLs("db", "tbl", dataSeq, columns, nil, testDMLOptions)
+	c.Assert(err, IsNil)
+	c.Assert(sqls, DeepEquals, []string{
+		"DELETE FROM `db`.`tbl` WHERE `id` = ? AND `j` IS ? LIMIT 1;",
+		"DELETE FROM `db`.`tbl` WHERE `id` = ? AND `j` = CAST(? AS JSON) LIMIT 1;",
+		"DELETE FROM `db`.`tbl` WHERE `id` = ? AND `j` = CAST(? AS JSON) LIMIT 1;",
+	})
+	c.Assert(values, DeepEquals, [][]interface{}{{int32(1), nil}, {int32(2), "{}"}, {int32(3), nested}})
+
+	// non-JSON column of []byte is kept
+	c.Assert(castValue([]byte("{}"), &column{tp: "blob"}, nil, log.GlobalLogger()), DeepEquals, []byte("{}"))
+}
+
+func (s *testSyncerSuite) TestGeneratedColumn(c *C) {
+	columns := []*column{
+		{idx: 0, name: "id", NotNull: true, tp: "int(11)"},
+		{idx: 1, name: "a", tp: "int(11)"},
+		{idx: 2, name: "g", tp: "int(11)", IsGenerated: true},
+	}
+	indexColumns := map[string][]*column{"uk_g": {columns[2]}}
+
+	sqls, keys, values, err := genInsertSQLs("db", "tbl", [][]interface{}{{int32(1), int32(10), int32(11)}, {int32(2), int32(20), int32(21)}}, columns, indexColumns, 2, config.ConflictReplace, testDMLOptions)
+	c.Assert(err, IsNil)
+	c.Assert(sqls, DeepEquals, []string{"REPLACE INTO `db`.`tbl` (`id`,`a`) VALUES (?,?),(?,?);"})
+	c.Assert(values, DeepEquals, [][]interface{}{{int32(1), int32(10), int32(2), int32(20)}})
+	c.Assert(keys, DeepEquals, [][]string{{"11", "21"}})
+
+	sqls, _, _, err = genInsertSQLs("db", "tbl", [][]interface{}{{int32(1), int32(10), int32(11)}}, columns, indexColumns, 1, config.ConflictOnDuplicate, testDMLOptions)
+	c.Assert(err, IsNil)
+	c.Assert(sqls, DeepEquals, []string{"INSERT INTO `db`.`tbl` (`id`,`a`) VALUES (?,?) ON DUPLICATE KEY UPDATE `id`=VALUES(`id`),`a`=VALUES(`a`);"})
+
+	data := [][]interface{}{{int32(1), int32(10), int32(11)}, {int32(1), int32(20), int32(21)}}
+	sqls, _, values, err = genUpdateSQLs("db", "tbl", data, columns, indexColumns, nil, false, testDMLOptions)
+	c.Assert(err, IsNil)
+	c.Assert(sqls, DeepEquals, []string{"UPDATE `db`.`tbl` SET `a` = ? WHERE `g` = ? LIMIT 1;"})
+	c.Assert(values, DeepEquals, [][]interface{}{{int32(20), int32(11)}})
+
+	sqls, _, values, err = genUpdateSQLs("db", "tbl", data, columns, indexColumns, nil, true, testDMLOptions)
+	c.Assert(err, IsNil)
+	c.Assert(sqls, DeepEquals, []string{"DELETE FROM `db`.`tbl` WHERE `g` = ?;", "REPLACE INTO `db`.`tbl` (`id`,`a`) VALUES (?,?);"})
+	c.Assert(values, DeepEquals, [][]interface{}{{int32(11)}, {int32(1), int32(20)}})
+
+	// no index, generated column is not used in WHERE
+	sqls, _, values, err = genDeleteSQLs("db", "tbl", data[:1], columns, nil, testDMLOptions)
+	c.Assert(err, IsNil)
+	c.Assert(sqls, DeepEquals, []string{"DELETE FROM `db`.`tbl` WHERE `id` = ? AND `a` = ? LIMIT 1;"})
+	c.Assert(values, DeepEquals, [][]interface{}{{int32(1), int32(10)}})
+}
+
+func (s *testSyncerSuite) TestEnumSetColumn(c *C) {
+	c.Assert(parseEnumSetElems("enum('a','b''c','d,e')"), DeepEquals, []string{"a", "b'c", "d,e"})
+	c.Assert(parseEnumSetElems("set('x','y','z')"), DeepEquals, []string{"x", "y", "z"})
+	c.Assert(parseEnumSetElems("int(11)"), IsNil)
+
+	columns := []*column{
+		{idx: 0, name: "id", NotNull: true, tp: "int(11)"},
+		{idx: 1, name: "e", tp: "enum('a','b','c')", elems: []string{"a", "b", "c"}},
+		{idx: 2, name: "s", tp: "set('x','y','z')", elems: []string{"x", "y", "z"}},
+	}
+	indexColumns := map[string][]*column{"uk_e": {columns[1]}}
+
+	// ENUM index is 1-based, SET bits are joined with commas
+	sqls, keys, values, err := genInsertSQLs("db", "tbl", [][]interface{}{{int32(1), int64(2), int64(5)}}, columns, indexColumns, 1, config.ConflictReplace, testDMLOptions)
+	c.Assert(err, IsNil)
+	c.Assert(sqls, DeepEquals, []string{"REPLACE INTO `db`.`tbl` (`id`,`e`,`s`) VALUES (?,?,?);"})
+	c.Assert(values, DeepEquals, [][]interface{}{{int32(1), "b", "x,z"}})
+	c.Assert(keys, DeepEquals, [][]string{{"b"}})
+
+	// empty set and invalid enum index
+	sqls, _, values, err = genDeleteSQLs("db", "tbl", [][]interface{}{{int32(1), int64(0), int64(0)}}, columns, nil, testDMLOptions)
+	c.Assert(err, IsNil)
+	c.Assert(sqls, DeepEquals, []string{"DELETE FROM `db`.`tbl` WHERE `id` = ? AND `e` = ? AND `s` = ? LIMIT 1;"})
+	c.Assert(values, DeepEquals, [][]interface{}{{int32(1), "", ""}})
+
+	// string labels are kept
+	c.Assert(castValue("a", columns[1], nil, log.GlobalLogger()), Equals, "a")
+}
+
+func (s *testSyncerSuite) TestBitColumn(c *C) {
+	c.Assert(parseBitWidth("bit(1)"), Equals, 1)
+	c.Assert(parseBitWidth("BIT(64)"), Equals, 64)
+	c.Assert(parseBitWidth("bit"), Equals, 1)
+	c.Assert(parseBitWidth("bigint(20)"), Equals, 0)
+	c.Assert(parseBitWidth("binary(16)"), Equals, 0)
+
+	columns := []*column{
+		{idx: 0, name: "b1", tp: "bit(1)", bitWidth: 1},
+		{idx: 1, name: "b64", tp: "bit(64)", bitWidth: 64},
+		{idx: 2, name: "b10", tp: "bit(10)", bitWidth: 10},
+	}
+	indexColumns := map[string][]*column{"uk": {columns[1], columns[2]}}
+
+	// decoded from binlog as int64, BIT(64) with all bits set is negative
+	sqls, keys, values, err := genInsertSQLs("db", "tbl", [][]interface{}{{int64(1), int64(-1), int64(5)}}, columns, indexColumns, 1, config.ConflictReplace, testDMLOptions)
+	c.Assert(err, IsNil)
+	c.Assert(sqls, DeepEquals, []string{"REPLACE INTO `db`.`tbl` (`b1`,`b64`,`b10`) VALUES (?,?,?);"})
+	c.Assert(values, DeepEquals, [][]interface{}{{uint64(1), uint64(math.MaxUint64), uint64(5)}})
+	c.Assert(keys, DeepEquals, [][]string{{"18446744073709551615,5"}})
+
+	// raw bytes with leading zeros and bool, got the same keys as numbers
+	sqls, keys, values, err = genDeleteSQLs("db", "tbl", [][]interface{}{{true, []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, []byte{0x00, 0x05}}}, columns, indexColumns, testDMLOptions)
+	c.Assert(err, IsNil)
+	c.Assert(sqls, DeepEquals, []string{"DELETE FROM `db`.`tbl` WHERE `b64` = ? AND `b10` = ?;"})
+	c.Assert(values, DeepEquals, [][]interface{}{{uint64(math.MaxUint64), uint64(5)}})
+	c.Assert(keys, DeepEquals, [][]string{{"18446744073709551615,5"}})
+
+	c.Assert(castValue(false, columns[0], nil, log.GlobalLogger()), Equals, uint64(0))
+	c.Assert(castValue("\x01", columns[0], nil, log.GlobalLogger()), Equals, uint64(1))
+	c.Assert(castValue(int16(-1), columns[2], nil, log.GlobalLogger()), Equals, uint64(1023)) // only the width of column
+	c.Assert(castValue(nil, columns[2], nil, log.GlobalLogger()), IsNil)
+	logger := &capturingLogger{}
+	c.Assert(castValue(make([]byte, 9), columns[1], nil, logger), DeepEquals, make([]byte, 9))
+	c.Assert(logger.entries, DeepEquals, []string{"[warn] [syncer] BIT value 000000000000000000 of column b64 is longer than 8 bytes"})
+
+	// bit-value literals of the column width
+	c.Assert(RenderSQL("UPDATE `db`.`tbl` SET `b1` = ? WHERE `b64` = ? AND `b10` = ?;", []interface{}{int64(0), uint64(math.MaxUint64), []byte{0x00, 0x05}}, columns), Equals,
+		"UPDATE `db`.`tbl` SET `b1` = b'0' WHERE `b64` = b'"+strings.Repeat("1", 64)+"' AND `b10` = b'0000000101';")
+}
+
+func (s *testSyncerSuite) TestDecimalColumn(c *C) {
+	cases := []struct {
+		tp               string
+		precision, scale int
+	}{
+		{"decimal(20,2)", 20, 2},
+		{"decimal(65,30) unsigned", 65, 30},
+		{"decimal(10)", 10, 0},
+		{"decimal", 10, 0},
+		{"double", 0, 0},
+		{"int(11)", 0, 0},
+	}
+	for _, cs := range cases {
+		precision, scale := parseDecimal(cs.tp)
+		c.Assert(precision, Equals, cs.precision, Commentf("type %s", cs.tp))
+		c.Assert(scale, Equals, cs.scale, Commentf("type %s", cs.tp))
+	}
+
+	columns := []*column{
+		{idx: 0, name: "d", tp: "decimal(65,30)", precision: 65, scale: 30},
+		{idx: 1, name: "m", tp: "decimal(16,2)", precision: 16, scale: 2},
+		{idx: 2, name: "f", tp: "double"},
+	}
+	indexColumns := map[string][]*column{"uk": {columns[0], columns[1]}}
+
+	// high-precision decimals are kept byte-for-byte, and floats are formatted with the scale
+	high := "12345678901234567890123456789012345.123456789012345678901234567890"
+	sqls, keys, values, err := genInsertSQLs("db", "tbl", [][]interface{}{{high, float64(12345678901234.56), float64(0.1) + float64(0.2)}}, columns, indexColumns, 1, config.ConflictReplace, testDMLOptions)
+	c.Assert(err, IsNil)
+	c.Assert(sqls, DeepEquals, []string{"REPLACE INTO `db`.`tbl` (`d`,`m`,`f`) VALUES (?,?,?);"})
+	c.Assert(values, DeepEquals, [][]interface{}{{high, "12345678901234.56", float64(0.1) + float64(0.2)}})
+	c.Assert(keys, DeepEquals, [][]string{{high + ",12345678901234.56"}})
+
+	// the same keys whatever the value is decoded as
+	_, keys, _, err = genDeleteSQLs("db", "tbl", [][]interface{}{{[]byte(high), float32(1.5), float64(1)}}, columns, indexColumns, testDMLOptions)
+	c.Assert(err, IsNil)
+	c.Assert(keys, DeepEquals, [][]string{{high + ",1.50"}})
+
+	c.Assert(columnValue(float64(0.1)+float64(0.2), false, "decimal(16,2)"), Equals, "0.30")
+	c.Assert(columnValue(float64(0.1)+float64(0.2), false, "double"), Equals, "0.30000000000000004")
+	c.Assert(columnValue(high, false, "decimal(65,30)"), Equals, high)
+}
+
+func (s *testSyncerSuite) TestGenWhereIn(c *C) {
+	columns := []*column{
+		{idx: 0, name: "id", tp: "int(11)"},
+		{idx: 1, name: "a", tp: "int(11)"},
+		{idx: 2, name: "b", tp: "varchar(20)"},
+	}
+	whereColumns := []*column{columns[0], columns[2]}
+	dataSeq := [][]interface{}{
+		{int32(1), int32(10), "a"},
+		{int32(2), int32(20), "b"},
+		{int32(3), int32(30), "c"},
+	}
+
+	where, values, nullRows := genWhereIn(whereColumns, dataSeq)
+	c.Assert(where, Equals, "(`id`,`b`) IN ((?,?),(?,?),(?,?))")
+	c.Assert(values, DeepEquals, []interface{}{int32(1), "a", int32(2), "b", int32(3), "c"})
+	c.Assert(nullRows, HasLen, 0)
+
+	where, values, _ = genWhereIn(columns[:1], dataSeq)
+	c.Assert(where, Equals, "`id` IN (?,?,?)")
+	c.Assert(values, DeepEquals, []interface{}{int32(1), int32(2), int32(3)})
+
+	// rows with NULL are split out
+	dataSeq[1][2] = nil
+	where, values, nullRows = genWhereIn(whereColumns, dataSeq)
+	c.Assert(where, Equals, "(`id`,`b`) IN ((?,?),(?,?))")
+	c.Assert(values, DeepEquals, []interface{}{int32(1), "a", int32(3), "c"})
+	c.Assert(nullRows, DeepEquals, []int{1})
+
+	indexColumns := map[string][]*column{"uk": whereColumns}
+	sqls, keys, args, rows, err := genBatchDeleteSQLs("db", "tbl", dataSeq, columns, indexColumns, whereColumns, testDMLOptions)
+	c.Assert(err, IsNil)
+	c.Assert(sqls, DeepEquals, []string{
+		"DELETE FROM `db`.`tbl` WHERE `id` = ? AND `b` IS ? LIMIT 1;",
+		"DELETE FROM `db`.`tbl` WHERE (`id`,`b`) IN ((?,?),(?,?));",
+	})
+	c.Assert(args, DeepEquals, [][]interface{}{{int32(2), nil}, {int32(1), "a", int32(3), "c"}})
+	c.Assert(keys, DeepEquals, [][]string{{"2,\\N"}, {"1,a"}, {"3,c"}})
+	c.Assert(rows, DeepEquals, []int{1, 2})
+
+	// all rows with NULL
+	where, values, nullRows = genWhereIn(whereColumns, dataSeq[1:2])
+	c.Assert(where, Equals, "")
+	c.Assert(values, HasLen, 0)
+	c.Assert(nullRows, DeepEquals, []int{0})
+}
+
+func (s *testSyncerSuite) TestTimestampColumn(c *C) {
+	c.Assert(parseFsp("timestamp(3)"), Equals, 3)
+	c.Assert(parseFsp("timestamp"), Equals, 0)
+
+	columns := []*column{
+		{idx: 0, name: "id", NotNull: true, tp: "int(11)"},
+		{idx: 1, name: "ts", tp: "timestamp(3)", fsp: 3},
+		{idx: 2, name: "dt", tp: "datetime"},
+	}
+	dataSeq := [][]interface{}{
+		{int32(1), "2019-03-01 23:30:00.120", "2019-03-01 23:30:00"},
+		{int32(2), "0000-00-00 00:00:00.000", "0000-00-00 00:00:00"},
+		{int32(3), time.Date(2019, 3, 1, 23, 30, 0, 120000000, time.UTC), "2019-03-01 23:30:00"},
+	}
+
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	c.Assert(err, IsNil)
+	newYork, err := time.LoadLocation("America/New_York")
+	c.Assert(err, IsNil)
+
+	cases := []struct {
+		timezone *time.Location
+		values   [][]interface{}
+	}{
+		{
+			timezone: shanghai,
+			values: [][]interface{}{
+				{int32(1), "2019-03-02 07:30:00.120", "2019-03-01 23:30:00"},
+				{int32(2), "0000-00-00 00:00:00.000", "0000-00-00 00:00:00"},
+				{int32(3), "2019-03-02 07:30:00.120", "2019-03-01 23:30:00"},
+			},
+		},
+		{
+			timezone: newYork,
+			values: [][]interface{}{
+				{int32(1), "2019-03-01 18:30:00.120", "2019-03-01 23:30:00"},
+				{int32(2), "0000-00-00 00:00:00.000", "0000-00-00 00:00:00"},
+				{int32(3), "2019-03-01 18:30:00.120", "2019-03-01 23:30:00"},
+			},
+		},
+		{
+			// no conversion, time.Time is formatted in its time zone
+			timezone: nil,
+			values: [][]interface{}{
+				{int32(1), "2019-03-01 23:30:00.120", "2019-03-01 23:30:00"},
+				{int32(2), "0000-00-00 00:00:00.000", "0000-00-00 00:00:00"},
+				{int32(3), "2019-03-01 23:30:00.120", "2019-03-01 23:30:00"},
+			},
+		},
+	}
+	for _, cs := range cases {
+		opts := &dmlOptions{keyGen: joinKeyGenerator{}, timezone: cs.timezone}
+		_, _, values, err := genInsertSQLs("db", "tbl", dataSeq, columns, nil, 1, config.ConflictReplace, opts)
+		c.Assert(err, IsNil)
+		c.Assert(values, DeepEquals, cs.values)
+	}
+
+	// zero value of time.Time
+	c.Assert(castValue(time.Time{}, columns[1], shanghai, log.GlobalLogger()), Equals, "0000-00-00 00:00:00.000")
+
+	// invalid value is kept, and warned by the logger of options
+	logger := &capturingLogger{}
+	opts := &dmlOptions{keyGen: joinKeyGenerator{}, timezone: shanghai, logger: logger}
+	_, _, values, err := genInsertSQLs("db", "tbl", [][]interface{}{{int32(4), "invalid", "2019-03-01 23:30:00"}}, columns, nil, 1, config.ConflictReplace, opts)
+	c.Assert(err, IsNil)
+	c.Assert(values[0][1], Equals, "invalid")
+	c.Assert(logger.entries, HasLen, 1)
+	c.Assert(logger.entries[0], Matches, "\\[warn\\] \\[syncer\\] fail to parse TIMESTAMP value invalid of column ts.*")
+}
+
+func (s *testSyncerSuite) TestTimeValues(c *C) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	c.Assert(err, IsNil)
+	t := time.Date(2019, 3, 1, 23, 30, 5, 123456789, time.UTC)
+
+	cases := []struct {
+		value    interface{}
+		tp       string
+		expected string
+	}{
+		{t, "date", "2019-03-01"},
+		{time.Time{}, "date", "0000-00-00"},
+		{t, "time", "23:30:05"},
+		{t, "time(3)", "23:30:05.123"},
+		{t, "datetime", "2019-03-01 23:30:05"},
+		{t, "datetime(6)", "2019-03-01 23:30:05.123456"},
+		{t.In(shanghai), "datetime(2)", "2019-03-02 07:30:05.12"},
+		{time.Time{}, "datetime(3)", "0000-00-00 00:00:00.000"},
+		{t, "timestamp(4)", "2019-03-01 23:30:05.1234"},
+		{38*time.Hour + 5*time.Minute + 6*time.Second + 789*time.Millisecond, "time(3)", "38:05:06.789"},
+		{-(838*time.Hour + 59*time.Minute + 59*time.Second), "time", "-838:59:59"},
+		{1500 * time.Microsecond, "time(6)", "00:00:00.001500"},
+	}
+	for _, cs := range cases {
+		c.Assert(columnValue(cs.value, false, cs.tp), Equals, cs.expected, Commentf("%v %s", cs.value, cs.tp))
+		col := &column{name: "c", tp: cs.tp}
+		c.Assert(castValue(cs.value, col, nil, log.GlobalLogger()), Equals, cs.expected, Commentf("%v %s", cs.value, cs.tp))
+	}
+
+	// TIMESTAMP is converted to the target time zone
+	col := &column{name: "ts", tp: "timestamp(6)", fsp: 6}
+	c.Assert(castValue(t, col, shanghai, log.GlobalLogger()), Equals, "2019-03-02 07:30:05.123456")
+
+	// keys and literal values of rows
+	columns := []*column{
+		{idx: 0, name: "d", NotNull: true, tp: "date"},
+		{idx: 1, name: "dt", tp: "datetime(3)"},
+	}
+	pk := map[string][]*column{"primary": {columns[0]}}
+	data := [][]interface{}{{t, t}}
+	_, keys, values, err := genInsertSQLs("db", "tbl", data, columns, pk, 1, config.ConflictReplace, testDMLOptions)
+	c.Assert(err, IsNil)
+	c.Assert(keys, DeepEquals, [][]string{{"2019-03-01"}})
+	c.Assert(values, DeepEquals, [][]interface{}{{"2019-03-01", "2019-03-01 23:30:05.123"}})
+}
+
+func (s *testSyncerSuite) TestZeroDate(c *C) {
+	columns := []*column{
+		{idx: 0, name: "id", NotNull: true, tp: "int(11)"},
+		{idx: 1, name: "d", tp: "date"},
+		{idx: 2, name: "dt", tp: "datetime(3)"},
+		{idx: 3, name: "nd", NotNull: true, tp: "date"},
+		{idx: 4, name: "s", tp: "varchar(20)"},
+	}
+	dataSeq := [][]interface{}{
+		{int32(1), "0000-00-00", "0000-00-00 00:00:00.000", "0000-00-00", "0000-00-00"},
+		{int32(2), "2020-00-05", []byte("2020-01-00 10:00:00.000"), "2020-00-05", "2020-00-05"},
+		{int32(3), "2020-01-05", "2020-01-05 10:00:00.000", "2020-01-05", "2020-01-05"},
+		{int32(4), "0000-01-01", nil, "0000-01-01", nil},
+	}
+
+	// kept as they are
+	opts := &dmlOptions{keyGen: joinKeyGenerator{}}
+	_, _, values, err := genInsertSQLs("db", "tbl", dataSeq, columns, nil, 1, config.ConflictReplace, opts)
+	c.Assert(err, IsNil)
+	c.Assert(values, DeepEquals, dataSeq)
+
+	// converted to NULL for nullable DATE and DATETIME columns, and zero year is valid
+	opts.zeroDateToNull = true
+	sqls, _, values, err := genInsertSQLs("db", "tbl", dataSeq, columns, nil, 1, config.ConflictReplace, opts)
+	c.Assert(err, IsNil)
+	c.Assert(values, DeepEquals, [][]interface{}{
+		{int32(1), nil, nil, "0000-00-00", "0000-00-00"},
+		{int32(2), nil, nil, "2020-00-05", "2020-00-05"},
+		dataSeq[2],
+		dataSeq[3],
+	})
+	c.Assert(RenderSQL(sqls[0], values[0], columns), Equals, "REPLACE INTO `db`.`tbl` (`id`,`d`,`dt`,`nd`,`s`) VALUES (1,NULL,NULL,'0000-00-00','0000-00-00');")
+
+	c.Assert(isZeroDate("0000-00-00 00:00:00"), IsTrue)
+	c.Assert(isZeroDate("2020-01-01"), IsFalse)
+	c.Assert(isZeroDate("00:00:00"), IsFalse)
+	c.Assert(isZeroDate(""), IsFalse)
+}
+
+func (s *testSyncerSuite) TestRenderSQL(c *C) {
+	columns := []*column{
+		{idx: 0, name: "id", tp: "int(11)"},
+		{idx: 1, name: "u", tp: "int(10) unsigned", unsigned: true},
+		{idx: 2, name: "f", tp: "double"},
+		{idx: 3, name: "s", tp: "varchar(20)"},
+		{idx: 4, name: "b", tp: "blob"},
+		{idx: 5, name: "n", tp: "int(11)"},
+	}
+	sql := "REPLACE INTO `db`.`t?` (`id`,`u`,`f`,`s`,`b`,`n`) VALUES (?,?,?,?,?,?);"
+	values := []interface{}{int32(-1), int32(-1), 1.5, "it's a \\ \"test\"\n", []byte{0x00, 0xff, 'a'}, nil}
+	c.Assert(RenderSQL(sql, values, columns), Equals,
+		"REPLACE INTO `db`.`t?` (`id`,`u`,`f`,`s`,`b`,`n`) VALUES (-1,4294967295,1.5,'it\\'s a \\\\ \"test\"\\n',0x00ff61,NULL);")
+
+	// values without columns, and placeholders without values are kept
+	c.Assert(RenderSQL("UPDATE `db`.`tbl` SET `a` = ? WHERE `id` = ? AND `b` = ?;", []interface{}{true, "\x00\r\x1a"}, nil), Equals,
+		"UPDATE `db`.`tbl` SET `a` = 1 WHERE `id` = '\\0\\r\\Z' AND `b` = ?;")
+}
+
+func (s *testSyncerSuite) TestBinaryColumn(c *C) {
+	c.Assert(isBinaryType("varbinary(16)"), IsTrue)
+	c.Assert(isBinaryType("BINARY(4)"), IsTrue)
+	c.Assert(isBinaryType("mediumblob"), IsTrue)
+	c.Assert(isBinaryType("varchar(16)"), IsFalse)
+	c.Assert(isBinaryType("text"), IsFalse)
+	c.Assert(isTextType("mediumtext"), IsTrue)
+	c.Assert(isTextType("char(10)"), IsTrue)
+	c.Assert(isTextType("tinyblob"), IsFalse)
+
+	columns := []*column{
+		{idx: 0, name: "b", tp: "varbinary(16)", binary: true},
+		{idx: 1, name: "t", tp: "text"},
+	}
+	invalid := []byte{0xff, ',', 0xfe, '\\', 0x00}
+	c.Assert(utf8.Valid(invalid), IsFalse)
+
+	// rendered as hex whether it's decoded as string or bytes, and text is quoted
+	sql := "UPDATE `db`.`tbl` SET `t` = ? WHERE `b` = ?;"
+	c.Assert(RenderSQL(sql, []interface{}{[]byte("it's"), invalid}, []*column{columns[1], columns[0]}), Equals,
+		"UPDATE `db`.`tbl` SET `t` = 'it\\'s' WHERE `b` = 0xff2cfe5c00;")
+	c.Assert(RenderSQL(sql, []interface{}{"it's", string(invalid)}, []*column{columns[1], columns[0]}), Equals,
+		"UPDATE `db`.`tbl` SET `t` = 'it\\'s' WHERE `b` = 0xff2cfe5c00;")
+	c.Assert(literalValue([]byte{}, columns[0]), Equals, "''")
+
+	// keys of binary values are length-prefixed and lossless
+	key := genKeyList(columns, []interface{}{invalid, "a"})
+	c.Assert(key, Equals, "5:"+string(invalid)+",a")
+	c.Assert(genKeyList(columns, []interface{}{string(invalid), []byte("a")}), Equals, key)
+	cases := [][2][]interface{}{
+		{{[]byte("a,"), "b"}, {[]byte("a"), ",b"}},
+		{{[]byte{0xff}, "1:x"}, {[]byte{0xff, ','}, "x"}},
+		{{invalid[:2], ""}, {invalid[:1], ""}},
+	}
+	for _, cs := range cases {
+		c.Assert(genKeyList(columns, cs[0]), Not(Equals), genKeyList(columns, cs[1]), Commentf("values %v", cs))
+		c.Assert(hashKeyGenerator{}.GenKey(columns, cs[0]), Not(Equals), hashKeyGenerator{}.GenKey(columns, cs[1]))
+	}
+}
+
+func (s *testSyncerSuite) TestColumnPlaceholders(c *C) {
+	columns := []*column{
+		{idx: 0, name: "id", NotNull: true, tp: "int(11)"},
+		{idx: 1, name: "a", tp: "varchar(20)"},
+		{idx: 2, name: "ts", tp: "datetime", bindExpr: "CONVERT_TZ(?,'+00:00','+08:00')"},
+	}
+	c.Assert(columnPlaceholder(columns[0]), Equals, "?")
+	c.Assert(columnPlaceholder(columns[2]), Equals, "CONVERT_TZ(?,'+00:00','+08:00')")
+	c.Assert(genColumnPlaceholders(columns), Equals, "?,?,CONVERT_TZ(?,'+00:00','+08:00')")
+	c.Assert(defaultBindExpr(columns[1]), Equals, "")
+	c.Assert(defaultBindExpr(&column{name: "g", tp: "geometry", geometry: true}), Equals, geometryBindExpr)
+
+	indexColumns := map[string][]*column{"primary": {columns[0]}}
+	cache := newStatementCache()
+	opts := &dmlOptions{keyGen: joinKeyGenerator{}, stmtCache: cache}
+	rows := [][]interface{}{{int32(1), "a", "2019-01-01 00:00:00"}, {int32(2), "b", "2019-01-02 00:00:00"}}
+	sqls, _, _, err := genInsertSQLs("db", "tbl", rows, columns, indexColumns, 2, config.ConflictOnDuplicate, opts)
+	c.Assert(err, IsNil)
+	c.Assert(sqls, DeepEquals, []string{"INSERT INTO `db`.`tbl` (`id`,`a`,`ts`) VALUES (?,?,CONVERT_TZ(?,'+00:00','+08:00')),(?,?,CONVERT_TZ(?,'+00:00','+08:00')) " +
+		"ON DUPLICATE KEY UPDATE `id`=VALUES(`id`),`a`=VALUES(`a`),`ts`=VALUES(`ts`);"})
+
+	// the SET clause wraps the value, while the WHERE clause is matched by the primary key
+	sqls, _, values, err := genUpdateSQLs("db", "tbl", [][]interface{}{rows[0], {int32(1), "b", "2019-01-03 00:00:00"}}, columns, indexColumns, nil, false, opts)
+	c.Assert(err, IsNil)
+	c.Assert(sqls, DeepEquals, []string{"UPDATE `db`.`tbl` SET `a` = ?, `ts` = CONVERT_TZ(?,'+00:00','+08:00') WHERE `id` = ? LIMIT 1;"})
+	c.Assert(values, DeepEquals, [][]interface{}{{"b", "2019-01-03 00:00:00", int32(1)}})
+
+	// cached templates are invalidated if the bind expression changed
+	columns[2] = &column{idx: 2, name: "ts", tp: "datetime"}
+	sqls, _, _, err = genInsertSQLs("db", "tbl", rows[:1], columns, indexColumns, 1, config.ConflictOnDuplicate, opts)
+	c.Assert(err, IsNil)
+	c.Assert(sqls, DeepEquals, []string{"INSERT INTO `db`.`tbl` (`id`,`a`,`ts`) VALUES (?,?,?) ON DUPLICATE KEY UPDATE `id`=VALUES(`id`),`a`=VALUES(`a`),`ts`=VALUES(`ts`);"})
+}
+
+func (s *testSyncerSuite) TestGeometryColumn(c *C) {
+	c.Assert(isGeometryType("point"), IsTrue)
+	c.Assert(isGeometryType("GEOMETRY"), IsTrue)
+	c.Assert(isGeometryType("multipolygon"), IsTrue)
+	c.Assert(isGeometryType("pointer"), IsFalse)
+	c.Assert(isGeometryType("varbinary(16)"), IsFalse)
+
+	columns := []*column{
+		{idx: 0, name: "id", NotNull: true, tp: "int(11)"},
+		{idx: 1, name: "g", tp: "point", geometry: true, bindExpr: geometryBindExpr},
+	}
+	// POINT(1 2) in WKB (little-endian), and with SRID 0 ahead in the internal format decoded from binlog
+	wkb := []byte{0x01, 0x01, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xf0, 0x3f,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x40}
+	point := append([]byte{0x00, 0x00, 0x00, 0x00}, wkb...)
+	wkb2 := append([]byte{}, wkb...)
+	wkb2[5] = 0x01
+	point2 := append([]byte{0x00, 0x00, 0x00, 0x00}, wkb2...)
+
+	// unique index containing the spatial column is used neither in WHERE clauses nor in keys
+	indexColumns := findColumns(columns, map[string][]string{"uk": {"id", "g"}})
+	c.Assert(indexColumns, HasLen, 0)
+
+	sqls, keys, values, err := genInsertSQLs("db", "tbl", [][]interface{}{{int32(1), point}, {int32(2), nil}}, columns, indexColumns, 2, config.ConflictReplace, testDMLOptions)
+	c.Assert(err, IsNil)
+	c.Assert(sqls, DeepEquals, []string{"REPLACE INTO `db`.`tbl` (`id`,`g`) VALUES (?,ST_GeomFromWKB(?)),(?,ST_GeomFromWKB(?));"})
+	c.Assert(values, DeepEquals, [][]interface{}{{int32(1), wkb, int32(2), nil}})
+	c.Assert(keys[0], HasLen, 0)
+
+	sqls, _, values, err = genUpdateSQLs("db", "tbl", [][]interface{}{{int32(1), point}, {int32(1), point2}}, columns, indexColumns, nil, false, testDMLOptions)
+	c.Assert(err, IsNil)
+	c.Assert(sqls, DeepEquals, []string{"UPDATE `db`.`tbl` SET `g` = ST_GeomFromWKB(?) WHERE `id` = ? LIMIT 1;"})
+	c.Assert(values, DeepEquals, [][]interface{}{{wkb2, int32(1)}})
+
+	sqls, _, values, err = genDeleteSQLs("db", "tbl", [][]interface{}{{int32(1), point}}, columns, indexColumns, testDMLOptions)
+	c.Assert(err, IsNil)
+	c.Assert(sqls, DeepEquals, []string{"DELETE FROM `db`.`tbl` WHERE `id` = ? LIMIT 1;"})
+	c.Assert(values, DeepEquals, [][]interface{}{{int32(1)}})
+}
+
+func (s *testSyncerSuite) TestFindFitIndexOrdinal(c *C) {
+	columns := []*column{
+		{idx: 0, name: "a", NotNull: true, tp: "int(11)"},
+		{idx: 1, name: "b", NotNull: true, tp: "int(11)"},
+		{idx: 2, name: "c", NotNull: true, tp: "varchar(20)"},
+		{idx: 3, name: "d", tp: "int(11)"},
+	}
+
+	// composite primary key built in shuffled order, `c` is a prefix index column like `c(10)`
+	indexColumns := map[string][]*column{"primary": {columns[2], columns[0], columns[1]}}
+	cols := findFitIndex(indexColumns, "", log.GlobalLogger())
+	c.Assert(cols, DeepEquals, []*column{columns[0], columns[1], columns[2]})
+	// the map is not modified
+	c.Assert(indexColumns["primary"], DeepEquals, []*column{columns[2], columns[0], columns[1]})
+
+	// keys and WHERE clauses follow the ordinal order
+	_, keys, values, err := genDeleteSQLs("db", "tbl", [][]interface{}{{int32(1), int32(2), "abc", nil}}, columns, findColumns(columns, map[string][]string{"primary": {"c", "a", "b"}}), testDMLOptions)
+	c.Assert(err, IsNil)
+	c.Assert(keys, DeepEquals, [][]string{{"1,2,abc"}})
+	c.Assert(values, DeepEquals, [][]interface{}{{int32(1), int32(2), "abc"}})
+
+	// not null unique key
+	indexColumns = map[string][]*column{"uk": {columns[1], columns[0]}}
+	c.Assert(findFitIndex(indexColumns, "", log.GlobalLogger()), DeepEquals, []*column{columns[0], columns[1]})
+	c.Assert(findFitIndex(map[string][]*column{"uk": {columns[3]}}, "", log.GlobalLogger()), HasLen, 0)
+}
+
+func (s *testSyncerSuite) TestFindFitIndexFunctional(c *C) {
+	columns := []*column{
+		{idx: 0, name: "id", NotNull: true, tp: "int(11)"},
+		{idx: 1, name: "name", NotNull: true, tp: "varchar(20)"},
+		{idx: 2, name: "doc", tp: "json"},
+	}
+
+	// the only unique index is functional like `UNIQUE KEY uk ((lower(name)))`, whose key part has no column name
+	indexColumns := findColumns(columns, map[string][]string{"uk": {""}})
+	c.Assert(indexColumns, HasLen, 0)
+	// the rest columns of an index with a functional key part are not unique, like `UNIQUE KEY uk2 (id, (lower(name)))`
+	c.Assert(findColumns(columns, map[string][]string{"uk2": {"id", ""}}), HasLen, 0)
+
+	// falls back to match rows by all columns, every time
+	data := [][]interface{}{{int32(1), "a", nil}, {int32(1), "b", nil}}
+	for i := 0; i < 10; i++ {
+		c.Assert(findFitIndex(indexColumns, "", log.GlobalLogger()), HasLen, 0)
+		sqls, keys, _, err := genUpdateSQLs("db", "tbl", data, columns, indexColumns, nil, false, testDMLOptions)
+		c.Assert(err, IsNil)
+		c.Assert(sqls, DeepEquals, []string{"UPDATE `db`.`tbl` SET `name` = ? WHERE `id` = ? AND `name` = ? AND `doc` IS ? LIMIT 1;"})
+		c.Assert(keys, DeepEquals, [][]string{nil})
+		sqls, _, _, err = genDeleteSQLs("db", "tbl", data[:1], columns, indexColumns, testDMLOptions)
+		c.Assert(err, IsNil)
+		c.Assert(sqls, DeepEquals, []string{"DELETE FROM `db`.`tbl` WHERE `id` = ? AND `name` = ? AND `doc` IS ? LIMIT 1;"})
+	}
+
+	// a unique index of columns is preferred, and the first one by name is chosen among them
+	indexColumns = findColumns(columns, map[string][]string{"uk": {""}, "uk_name": {"name"}, "uk_id": {"id"}, "uk_mv": {"id", ""}})
+	c.Assert(indexColumns, HasLen, 2)
+	for i := 0; i < 10; i++ {
+		c.Assert(findFitIndex(indexColumns, "", log.GlobalLogger()), DeepEquals, []*column{columns[0]})
+		sqls, _, _, err := genDeleteSQLs("db", "tbl", data[:1], columns, indexColumns, testDMLOptions)
+		c.Assert(err, IsNil)
+		c.Assert(sqls, DeepEquals, []string{"DELETE FROM `db`.`tbl` WHERE `id` = ?;"})
+	}
+}
+
+func (s *testSyncerSuite) TestFindFitIndexEmptyColumns(c *C) {
+	columns := []*column{
+		{idx: 0, name: "a", NotNull: true, tp: "int(11)"},
+		{idx: 1, name: "b", tp: "int(11)"},
+	}
+	logger := &capturingLogger{}
+	c.Assert(findFitIndex(map[string][]*column{"primary": {}}, "", logger), HasLen, 0)
+	c.Assert(logger.entries, DeepEquals, []string{"[error] cols is empty"})
+
+	// logged by the logger of options, and the row is matched by all columns
+	logger.entries = nil
+	opts := &dmlOptions{keyGen: testDMLOptions.keyGen, logger: logger}
+	sqls, _, _, err := genDeleteSQLs("db", "tbl", [][]interface{}{{int32(1), int32(2)}}, columns, map[string][]*column{"primary": {}}, opts)
+	c.Assert(err, IsNil)
+	c.Assert(sqls, DeepEquals, []string{"DELETE FROM `db`.`tbl` WHERE `a` = ? AND `b` = ? LIMIT 1;"})
+	c.Assert(logger.entries, HasLen, 2)
+	c.Assert(logger.entries[0], Equals, "[error] cols is empty")
+	c.Assert(strings.HasPrefix(logger.entries[1], "[warn] [syncer] delete a row of `db`.`tbl` without unique key"), IsTrue)
+}
+
+func (s *testSyncerSuite) TestMappingDMLExpression(c *C) {
+	columns := []string{"id", "col1", "col1_shard"}
+	shardExpr := func(schema, table string, columns []string, row []interface{}) (interface{}, error) {
+		col1, ok := row[1].(string)
+		if !ok {
+			return nil, errors.NotValidf("col1 value %v", row[1])
+		}
+		shardID := strings.TrimPrefix(table, "t_")
+		return col1 + "_" + shardID, nil
+	}
+
+	syncer := &Syncer{}
+	syncer.RegisterColumnExpression("db", "t_01", "col1_shard", shardExpr)
+	syncer.RegisterColumnExpression("db", "t_02", "col1_shard", shardExpr)
+
+	_, rows, err := syncer.mappingDML("db", "t_01", columns, [][]interface{}{{int32(1), "a"}, {int32(2), "b"}})
+	c.Assert(err, IsNil)
+	c.Assert(rows, DeepEquals, [][]interface{}{{int32(1), "a", "a_01"}, {int32(2), "b", "b_01"}})
+
+	// rows of the event are not changed, even the values are assigned in place or appended into the spare capacity
+	data := [][]interface{}{append(make([]interface{}, 0, 3), int32(1), "a"), {int32(2), "b", "x"}}
+	_, rows, err = syncer.mappingDML("db", "t_01", columns, data)
+	c.Assert(err, IsNil)
+	c.Assert(rows, DeepEquals, [][]interface{}{{int32(1), "a", "a_01"}, {int32(2), "b", "b_01"}})
+	c.Assert(data, DeepEquals, [][]interface{}{{int32(1), "a"}, {int32(2), "b", "x"}})
+	c.Assert(data[0][:3][2], IsNil)
+	_, rows, err = syncer.mappingDML("db", "t_02", columns, [][]interface{}{{int32(3), "c"}})
+	c.Assert(err, IsNil)
+	c.Assert(rows, DeepEquals, [][]interface{}{{int32(3), "c", "c_02"}})
+
+	// no expression for other tables
+	_, rows, err = syncer.mappingDML("db", "t_03", columns, [][]interface{}{{int32(4), "d"}})
+	c.Assert(err, IsNil)
+	c.Assert(rows, DeepEquals, [][]interface{}{{int32(4), "d"}})
+
+	// evaluated after column mapping
+	syncer.columnMapping, err = cm.NewMapping(false, []*cm.Rule{
+		{PatternSchema: "db", PatternTable: "t_*", TargetColumn: "col1", Expression: cm.AddPrefix, Arguments: []string{"p_"}},
+	})
+	c.Assert(err, IsNil)
+	_, rows, err = syncer.mappingDML("db", "t_01", columns, [][]interface{}{{int32(1), "a"}})
+	c.Assert(err, IsNil)
+	c.Assert(rows, DeepEquals, [][]interface{}{{int32(1), "p_a", "p_a_01"}})
+	syncer.columnMapping = nil
+
+	// errors of expressions are returned
+	_, _, err = syncer.mappingDML("db", "t_01", columns, [][]interface{}{{int32(1), nil}})
+	c.Assert(err, ErrorMatches, ".*evaluate expression of column col1_shard.*col1 value <nil> not valid.*")
+
+	// the row is not long enough for the column
+	_, _, err = syncer.mappingDML("db", "t_01", []string{"id", "col1", "col2", "col1_shard"}, [][]interface{}{{int32(1), "a"}})
+	c.Assert(err, ErrorMatches, ".*column col1_shard at position 3 for row with 2 values not valid.*")
+}
+
+func (s *testSyncerSuite) TestMappingDMLRename(c *C) {
+	columns := []string{"id", "name", "age"}
+	tbl := &table{schema: "db", name: "t", columns: []*column{
+		{idx: 0, name: "id", NotNull: true, tp: "int(11)"},
+		{idx: 1, name: "name", tp: "varchar(20)"},
+		{idx: 2, name: "age", tp: "int(11)"},
+	}}
+	tbl.indexColumns = map[string][]*column{"primary": {tbl.columns[0]}}
+
+	syncer := &Syncer{}
+	syncer.RegisterColumnRename("db", "t_01", "name", "full_name")
+	// column mapping rules match the source name
+	var err error
+	syncer.columnMapping, err = cm.NewMapping(false, []*cm.Rule{
+		{PatternSchema: "db", PatternTable: "t_*", TargetColumn: "name", Expression: cm.AddPrefix, Arguments: []string{"p_"}},
+	})
+	c.Assert(err, IsNil)
+
+	renamed, rows, err := syncer.mappingDML("db", "t_01", columns, [][]interface{}{{int32(1), "a", int32(18)}})
+	c.Assert(err, IsNil)
+	c.Assert(renamed, DeepEquals, []string{"id", "full_name", "age"})
+	c.Assert(columns, DeepEquals, []string{"id", "name", "age"})
+	c.Assert(rows, DeepEquals, [][]interface{}{{int32(1), "p_a", int32(18)}})
+
+	// the INSERT uses the new column name, while the shared table is not modified
+	renamedTbl := renameTableColumns(tbl, columns, renamed)
+	sqls, _, args, err := genInsertSQLs(renamedTbl.schema, renamedTbl.name, rows, renamedTbl.columns, renamedTbl.indexColumns, 1, config.ConflictReplace, testDMLOptions)
+	c.Assert(err, IsNil)
+	c.Assert(sqls, DeepEquals, []string{"REPLACE INTO `db`.`t` (`id`,`full_name`,`age`) VALUES (?,?,?);"})
+	c.Assert(args, DeepEquals, [][]interface{}{{int32(1), "p_a", int32(18)}})
+	c.Assert(tbl.columns[1].name, Equals, "name")
+
+	// index columns refer to the renamed columns
+	syncer.RegisterColumnRename("db", "t_02", "id", "uid")
+	renamed, _, err = syncer.mappingDML("db", "t_02", columns, nil)
+	c.Assert(err, IsNil)
+	renamedTbl = renameTableColumns(tbl, columns, renamed)
+	c.Assert(renamedTbl.indexColumns["primary"], DeepEquals, []*column{renamedTbl.columns[0]})
+	c.Assert(renamedTbl.indexColumns["primary"][0].name, Equals, "uid")
+	c.Assert(tbl.indexColumns["primary"][0].name, Equals, "id")
+
+	// no rename for other tables
+	renamed, _, err = syncer.mappingDML("db", "t_03", columns, nil)
+	c.Assert(err, IsNil)
+	c.Assert(renamed, DeepEquals, columns)
+	c.Assert(renameTableColumns(tbl, columns, renamed), Equals, tbl)
+
+	// renamed to the name of another column
+	syncer.RegisterColumnRename("db", "t_04", "name", "AGE")
+	_, _, err = syncer.mappingDML("db", "t_04", columns, nil)
+	c.Assert(err, ErrorMatches, ".*columns name and age of table `db`.`t_04` both named age after renamed not valid")
+	// swapping names is fine
+	syncer.RegisterColumnRename("db", "t_04", "age", "name")
+	renamed, _, err = syncer.mappingDML("db", "t_04", columns, nil)
+	c.Assert(err, IsNil)
+	c.Assert(renamed, DeepEquals, []string{"id", "AGE", "name"})
 }
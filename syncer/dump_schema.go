@@ -0,0 +1,181 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syncer
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/parser"
+	"github.com/pingcap/parser/ast"
+	"github.com/pingcap/parser/charset"
+	"github.com/pingcap/parser/mysql"
+	"github.com/pingcap/tidb-tools/pkg/dbutil"
+	"github.com/pingcap/tidb/types"
+)
+
+// loadDumpTables parses CREATE TABLE statements in the schema files of the dump in dir,
+// and returns the structures of tables, keyed by `schema`.`table`, like getTable but without querying the target.
+func loadDumpTables(p *parser.Parser, dir string) (map[string]*table, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	tables := make(map[string]*table)
+	for _, f := range files {
+		schema, _, ok := parseDumpSchemaFileName(f.Name())
+		if !ok {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(dir, f.Name()))
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		tbl, err := parseCreateTable(p, schema, string(data))
+		if err != nil {
+			return nil, errors.Annotatef(err, "schema file %s", f.Name())
+		}
+		tables[dbutil.TableName(tbl.schema, tbl.name)] = tbl
+	}
+	return tables, nil
+}
+
+// parseCreateTable returns the structure of the table created by the CREATE TABLE statement in sqls, the same as getTableColumns and getTableIndex do.
+// other statements in sqls are ignored, like `SET NAMES` in files dumped by mydumper. the table is in schema if the statement doesn't specify its schema.
+func parseCreateTable(p *parser.Parser, schema string, sqls string) (*table, error) {
+	stmts, err := p.Parse(sqls, "", "")
+	if err != nil {
+		return nil, errors.Annotatef(err, "parse %-.100s", sqls)
+	}
+
+	var ct *ast.CreateTableStmt
+	for _, stmt := range stmts {
+		if s, ok := stmt.(*ast.CreateTableStmt); ok {
+			ct = s
+			break
+		}
+	}
+	if ct == nil {
+		return nil, errors.NotFoundf("CREATE TABLE statement in %-.100s", sqls)
+	}
+	if ct.ReferTable != nil || ct.Select != nil {
+		return nil, errors.NotSupportedf("CREATE TABLE LIKE or SELECT of table %s", ct.Table.Name.O)
+	}
+
+	tbl := &table{schema: schema, name: ct.Table.Name.O}
+	if ct.Table.Schema.O != "" {
+		tbl.schema = ct.Table.Schema.O
+	}
+
+	// the collation of the table is the default of string columns
+	var tableCollation string
+	for _, opt := range ct.Options {
+		if opt.Tp == ast.TableOptionCollate {
+			tableCollation = opt.StrValue
+		}
+	}
+
+	keys := make(map[string][]string) // key name -> column names, only PRIMARY and UNIQUE keys like getTableIndex
+	addKey := func(name string, columns []string) {
+		keys[strings.ToLower(name)] = columns
+	}
+
+	for idx, def := range ct.Cols {
+		column := &column{
+			idx:      idx,
+			name:     def.Name.Name.O,
+			tp:       def.Tp.InfoSchemaStr(),
+			unsigned: mysql.HasUnsignedFlag(def.Tp.Flag),
+		}
+		if types.IsTypeChar(def.Tp.Tp) || types.IsTypeBlob(def.Tp.Tp) || def.Tp.Tp == mysql.TypeEnum || def.Tp.Tp == mysql.TypeSet {
+			column.collation = def.Tp.Collate
+			if column.collation == "" && def.Tp.Charset != charset.CharsetBin {
+				column.collation = tableCollation
+			}
+		}
+
+		for _, opt := range def.Options {
+			switch opt.Tp {
+			case ast.ColumnOptionNotNull:
+				column.NotNull = true
+			case ast.ColumnOptionNull:
+				column.NotNull = false
+			case ast.ColumnOptionAutoIncrement:
+				column.autoIncrement = true
+			case ast.ColumnOptionGenerated:
+				column.IsGenerated = true
+			case ast.ColumnOptionDefaultValue:
+				column.defaultValue, column.defaultExpr = parseDefaultValue(opt.Expr)
+			case ast.ColumnOptionPrimaryKey:
+				addKey("primary", []string{column.name})
+			case ast.ColumnOptionUniqKey:
+				// the unique key is named after the column
+				addKey(column.name, []string{column.name})
+			}
+		}
+
+		initColumnType(column)
+		tbl.columns = append(tbl.columns, column)
+	}
+
+	for _, constraint := range ct.Constraints {
+		columns := make([]string, 0, len(constraint.Keys))
+		for _, key := range constraint.Keys {
+			columns = append(columns, key.Column.Name.O)
+		}
+		switch constraint.Tp {
+		case ast.ConstraintPrimaryKey:
+			addKey("primary", columns)
+		case ast.ConstraintUniq, ast.ConstraintUniqKey, ast.ConstraintUniqIndex:
+			name := constraint.Name
+			if name == "" && len(columns) > 0 {
+				// the same as MySQL, the unnamed unique key is named after its first column
+				name = columns[0]
+			}
+			addKey(name, columns)
+		}
+	}
+
+	// columns of PRIMARY KEY are NOT NULL implicitly
+	for _, name := range keys["primary"] {
+		if column := findColumn(tbl.columns, name); column != nil {
+			column.NotNull = true
+		}
+	}
+
+	tbl.indexColumns = findColumns(tbl.columns, keys)
+	return tbl, nil
+}
+
+// parseDefaultValue returns the DEFAULT value of a column in text like the `Default` of `SHOW FULL COLUMNS`,
+// and whether it's an expression like CURRENT_TIMESTAMP.
+func parseDefaultValue(expr ast.ExprNode) (interface{}, bool) {
+	switch e := expr.(type) {
+	case ast.ValueExpr:
+		if e.GetValue() == nil {
+			return nil, false
+		}
+		value := e.GetDatumString()
+		return value, isDefaultExpr(value)
+	case *ast.FuncCallExpr:
+		return e.FnName.O, true
+	default:
+		// like `-1`, formatted the same as DDL are synced
+		value := strings.TrimPrefix(defaultValueToSQL(&ast.ColumnOption{Expr: expr}), " DEFAULT ")
+		return value, isDefaultExpr(value)
+	}
+}
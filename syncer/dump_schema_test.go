@@ -0,0 +1,108 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syncer
+
+import (
+	"io/ioutil"
+	"path/filepath"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/parser"
+)
+
+func (s *testSyncerSuite) TestParseCreateTable(c *C) {
+	// the same as files dumped by mydumper
+	sqls := "/*!40101 SET NAMES binary*/;\n" +
+		"CREATE TABLE `t` (\n" +
+		"  `shard` int(10) unsigned NOT NULL,\n" +
+		"  `id` bigint(20) unsigned NOT NULL AUTO_INCREMENT,\n" +
+		"  `name` varchar(20) COLLATE utf8mb4_bin DEFAULT 'dm',\n" +
+		"  `email` varchar(64) NOT NULL,\n" +
+		"  `amount` decimal(10,2) DEFAULT NULL,\n" +
+		"  `created` timestamp(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),\n" +
+		"  `name_len` int(11) GENERATED ALWAYS AS (length(`name`)) VIRTUAL,\n" +
+		"  PRIMARY KEY (`id`,`shard`),\n" +
+		"  UNIQUE KEY `uk_email` (`email`),\n" +
+		"  KEY `idx_name` (`name`)\n" +
+		") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;\n"
+
+	tbl, err := parseCreateTable(parser.New(), "db", sqls)
+	c.Assert(err, IsNil)
+	c.Assert(tbl.schema, Equals, "db")
+	c.Assert(tbl.name, Equals, "t")
+	c.Assert(tbl.columns, HasLen, 7)
+
+	names := make([]string, 0, len(tbl.columns))
+	for i, col := range tbl.columns {
+		c.Assert(col.idx, Equals, i)
+		names = append(names, col.name)
+	}
+	c.Assert(names, DeepEquals, []string{"shard", "id", "name", "email", "amount", "created", "name_len"})
+
+	shard, id, name, email, amount, created, nameLen := tbl.columns[0], tbl.columns[1], tbl.columns[2], tbl.columns[3], tbl.columns[4], tbl.columns[5], tbl.columns[6]
+	c.Assert(shard.tp, Equals, "int(10) unsigned")
+	c.Assert(shard.unsigned, IsTrue)
+	c.Assert(shard.NotNull, IsTrue)
+	c.Assert(id.tp, Equals, "bigint(20) unsigned")
+	c.Assert(id.unsigned, IsTrue)
+	c.Assert(id.autoIncrement, IsTrue)
+	c.Assert(name.unsigned, IsFalse)
+	c.Assert(name.NotNull, IsFalse)
+	c.Assert(name.collation, Equals, "utf8mb4_bin")
+	c.Assert(name.defaultValue, Equals, "dm")
+	c.Assert(email.NotNull, IsTrue)
+	c.Assert(email.collation, Equals, "utf8mb4_general_ci")
+	c.Assert(amount.precision, Equals, 10)
+	c.Assert(amount.scale, Equals, 2)
+	c.Assert(amount.defaultValue, IsNil)
+	c.Assert(created.fsp, Equals, 3)
+	c.Assert(created.defaultExpr, IsTrue)
+	c.Assert(nameLen.IsGenerated, IsTrue)
+
+	// the composite PRIMARY KEY in ordinal positions like getTableIndex, and the UNIQUE KEY, but not the non-unique KEY
+	c.Assert(tbl.indexColumns, HasLen, 2)
+	c.Assert(tbl.indexColumns["primary"], DeepEquals, []*column{shard, id})
+	c.Assert(tbl.indexColumns["uk_email"], DeepEquals, []*column{email})
+
+	// the parsed structure drives the generators
+	sqlList, _, args, err := genDeleteSQLs(tbl.schema, tbl.name, [][]interface{}{{uint32(1), uint64(2), "a", "a@pingcap.com", nil, nil, int32(1)}}, tbl.columns, tbl.indexColumns, testDMLOptions)
+	c.Assert(err, IsNil)
+	c.Assert(sqlList, DeepEquals, []string{"DELETE FROM `db`.`t` WHERE `shard` = ? AND `id` = ?;"})
+	c.Assert(args, DeepEquals, [][]interface{}{{uint32(1), uint64(2)}})
+
+	// PRIMARY KEY and UNIQUE in column definitions, the schema in the statement
+	tbl, err = parseCreateTable(parser.New(), "db", "CREATE TABLE `db2`.`t2` (`a` tinyint unsigned PRIMARY KEY, `b` char(4) UNIQUE)")
+	c.Assert(err, IsNil)
+	c.Assert(tbl.schema, Equals, "db2")
+	c.Assert(tbl.columns[0].NotNull, IsTrue)
+	c.Assert(tbl.columns[0].unsigned, IsTrue)
+	c.Assert(tbl.indexColumns, DeepEquals, map[string][]*column{"primary": {tbl.columns[0]}, "b": {tbl.columns[1]}})
+
+	_, err = parseCreateTable(parser.New(), "db", "/*!40101 SET NAMES binary*/;")
+	c.Assert(errors.IsNotFound(err), IsTrue)
+	_, err = parseCreateTable(parser.New(), "db", "CREATE TABLE `t3` LIKE `t`")
+	c.Assert(err, ErrorMatches, ".*not supported")
+
+	// load from the dump directory
+	dir := c.MkDir()
+	c.Assert(ioutil.WriteFile(filepath.Join(dir, "db-schema-create.sql"), []byte("CREATE DATABASE `db`;"), 0644), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(dir, "db.t-schema.sql"), []byte(sqls), 0644), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(dir, "db.t.sql"), []byte("INSERT INTO `t` VALUES (1,2,'a','a@pingcap.com',NULL,NULL,1);"), 0644), IsNil)
+	tables, err := loadDumpTables(parser.New(), dir)
+	c.Assert(err, IsNil)
+	c.Assert(tables, HasLen, 1)
+	c.Assert(tables["`db`.`t`"].columns, HasLen, 7)
+	c.Assert(tables["`db`.`t`"].indexColumns["primary"], HasLen, 2)
+}
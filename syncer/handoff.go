@@ -0,0 +1,53 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syncer
+
+import (
+	"github.com/pingcap/errors"
+	"github.com/siddontang/go-mysql/mysql"
+
+	"github.com/pingcap/dm/dm/config"
+	"github.com/pingcap/dm/pkg/log"
+	"github.com/pingcap/dm/pkg/utils"
+)
+
+// checkHandoff verifies the handoff written by the loader before a fresh task of `all` mode replicates from pos loaded from the dump,
+// it fails if the loader didn't restore the dump completely, or the handoff is corrupted or for another task or position,
+// so the rows at the boundary of full and incremental data are neither synced twice nor missed.
+func (s *Syncer) checkHandoff(pos mysql.Position) error {
+	if s.cfg.Mode != config.ModeAll {
+		return nil
+	}
+
+	h, err := utils.ReadHandoff(s.cfg.Dir, s.cfg.Flavor)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return errors.Annotatef(err, "the loader didn't complete restoring the dump %s", s.cfg.Dir)
+		}
+		return errors.Annotatef(err, "invalid handoff from the loader")
+	}
+	if h.Task != s.cfg.Name {
+		return errors.Errorf("handoff from the loader is for task %s, not %s", h.Task, s.cfg.Name)
+	}
+	if h.BinLogName == "" {
+		// NOTE: the checkpoint of syncer doesn't support GTID yet
+		return errors.NotSupportedf("starting from GTID set %s in handoff from the loader without binlog position", h.BinlogGTID)
+	}
+	if h.Pos() != pos {
+		return errors.Errorf("binlog position %s in handoff from the loader mismatches %s in the dump", h.Pos(), pos)
+	}
+
+	log.Infof("[syncer] verified handoff from the loader, start from binlog position %s, GTID %s", pos, h.BinlogGTID)
+	return nil
+}
@@ -0,0 +1,77 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syncer
+
+import (
+	"io/ioutil"
+	"strings"
+
+	. "github.com/pingcap/check"
+	"github.com/siddontang/go-mysql/mysql"
+
+	"github.com/pingcap/dm/dm/config"
+	"github.com/pingcap/dm/pkg/utils"
+)
+
+func (s *testSyncerSuite) TestCheckHandoff(c *C) {
+	dir := c.MkDir()
+	syncer := &Syncer{cfg: &config.SubTaskConfig{Name: "test", Mode: config.ModeAll, Dir: dir, Flavor: mysql.MySQLFlavor}}
+	pos := mysql.Position{Name: "mysql-bin.000003", Pos: 1943}
+
+	// the loader didn't complete
+	err := syncer.checkHandoff(pos)
+	c.Assert(err, ErrorMatches, ".*the loader didn't complete restoring the dump.*")
+
+	h := &utils.Handoff{Task: "test", BinLogName: pos.Name, BinLogPos: pos.Pos, BinlogGTID: "97b5142f-e19c-11e8-808c-0242ac110005:1-13"}
+	c.Assert(utils.WriteHandoff(dir, h), IsNil)
+	c.Assert(syncer.checkHandoff(pos), IsNil)
+
+	// the position in the dump differs from the handoff
+	err = syncer.checkHandoff(mysql.Position{Name: pos.Name, Pos: 2044})
+	c.Assert(err, ErrorMatches, "binlog position \\(mysql-bin.000003, 1943\\) in handoff from the loader mismatches \\(mysql-bin.000003, 2044\\) in the dump")
+
+	// for another task
+	syncer.cfg.Name = "test2"
+	c.Assert(syncer.checkHandoff(pos), ErrorMatches, "handoff from the loader is for task test, not test2")
+	syncer.cfg.Name = "test"
+
+	// not checked in `incremental` mode
+	syncer.cfg.Mode = config.ModeIncrement
+	c.Assert(utils.RemoveHandoff(dir), IsNil)
+	c.Assert(syncer.checkHandoff(pos), IsNil)
+	syncer.cfg.Mode = config.ModeAll
+
+	// only the GTID set, or a malformed GTID set
+	c.Assert(utils.WriteHandoff(dir, &utils.Handoff{Task: "test", BinlogGTID: h.BinlogGTID}), IsNil)
+	c.Assert(syncer.checkHandoff(pos), ErrorMatches, "starting from GTID set .* without binlog position not supported")
+	c.Assert(utils.WriteHandoff(dir, &utils.Handoff{Task: "test", BinLogName: pos.Name, BinLogPos: pos.Pos, BinlogGTID: "mysql-bin.000003:1943"}), IsNil)
+	c.Assert(syncer.checkHandoff(pos), ErrorMatches, "invalid handoff from the loader: GTID set mysql-bin.000003:1943 in handoff .*")
+	c.Assert(utils.WriteHandoff(dir, &utils.Handoff{Task: "test"}), IsNil)
+	c.Assert(syncer.checkHandoff(pos), ErrorMatches, "invalid handoff from the loader: handoff .* without binlog position and GTID set not valid")
+
+	// corrupted handoffs
+	c.Assert(utils.WriteHandoff(dir, h), IsNil)
+	data, err := ioutil.ReadFile(utils.HandoffPath(dir))
+	c.Assert(err, IsNil)
+	for _, corrupted := range []string{
+		strings.Replace(string(data), "1943", "1944", 1),
+		strings.Replace(string(data), "1-13", "1-12", 1),
+		string(data[:len(data)/2]),
+		"",
+	} {
+		c.Assert(ioutil.WriteFile(utils.HandoffPath(dir), []byte(corrupted), 0644), IsNil)
+		err = syncer.checkHandoff(pos)
+		c.Assert(err, ErrorMatches, "invalid handoff from the loader.*", Commentf("handoff %s", corrupted))
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syncer
+
+import (
+	"strings"
+
+	"github.com/pingcap/errors"
+
+	"github.com/pingcap/dm/dm/config"
+	"github.com/pingcap/dm/pkg/utils"
+)
+
+// ignoredColumns resolves the columns ignored when detecting changes of rows of target tables,
+// see config.SyncerConfig.TableChangeIgnoredColumns
+type ignoredColumns struct {
+	rules *utils.TableRules // rules are *ignoredColumnsRule
+}
+
+// ignoredColumnsRule is a rule with the set of its column names in lower case
+type ignoredColumnsRule struct {
+	*config.TableChangeIgnoredColumns
+	columns map[string]struct{}
+}
+
+func newIgnoredColumns(caseSensitive bool, rules []*config.TableChangeIgnoredColumns) (*ignoredColumns, error) {
+	ic := &ignoredColumns{rules: utils.NewTableRules("rules of change ignored columns", caseSensitive)}
+	for _, rule := range rules {
+		r := &ignoredColumnsRule{TableChangeIgnoredColumns: rule, columns: make(map[string]struct{}, len(rule.Columns))}
+		for _, col := range rule.Columns {
+			// column names are case insensitive in MySQL
+			r.columns[strings.ToLower(col)] = struct{}{}
+		}
+		if err := ic.rules.Insert(rule.SchemaPattern, rule.TablePattern, r); err != nil {
+			return nil, errors.Annotatef(err, "table change ignored columns %+v", rule)
+		}
+	}
+	return ic, nil
+}
+
+// columns returns the names of the ignored columns of the table in lower case, nil if none.
+// see utils.TableRules for rules matching the table.
+func (ic *ignoredColumns) columns(schema, table string) (map[string]struct{}, error) {
+	rule, err := ic.rules.Match(schema, table)
+	if err != nil || rule == nil {
+		return nil, errors.Trace(err)
+	}
+	return rule.(*ignoredColumnsRule).columns, nil
+}
@@ -0,0 +1,89 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syncer
+
+import (
+	. "github.com/pingcap/check"
+
+	"github.com/pingcap/dm/dm/config"
+)
+
+func (s *testSyncerSuite) TestIgnoredColumns(c *C) {
+	ic, err := newIgnoredColumns(false, []*config.TableChangeIgnoredColumns{
+		{SchemaPattern: "db", TablePattern: "heartbeat", Columns: []string{"Updated_At"}},
+		{SchemaPattern: "db", Columns: []string{"version"}},
+	})
+	c.Assert(err, IsNil)
+
+	columns := []*column{
+		{idx: 0, name: "id", NotNull: true, tp: "int(11)"},
+		{idx: 1, name: "a", tp: "int(11)"},
+		{idx: 2, name: "updated_at", tp: "timestamp"},
+		{idx: 3, name: "g", tp: "int(11)", IsGenerated: true},
+	}
+	pk := map[string][]*column{"primary": {columns[0]}}
+	old := []interface{}{int32(1), int32(10), "2020-01-01 00:00:00", int32(11)}
+	onlyTimestamp := []interface{}{int32(1), int32(10), "2020-01-01 00:00:05", int32(12)}
+	both := []interface{}{int32(1), int32(20), "2020-01-01 00:00:05", int32(21)}
+
+	ignored, err := ic.columns("DB", "Heartbeat")
+	c.Assert(err, IsNil)
+	c.Assert(ignored, DeepEquals, map[string]struct{}{"updated_at": {}})
+	opts := &dmlOptions{keyGen: testDMLOptions.keyGen, changeIgnoredColumns: ignored}
+
+	// no statement for the row changed only in the ignored column, even in safe mode
+	for _, safeMode := range []bool{false, true} {
+		sqls, keys, values, err := genUpdateSQLs("db", "heartbeat", [][]interface{}{old, onlyTimestamp}, columns, pk, nil, safeMode, opts)
+		c.Assert(err, IsNil)
+		c.Assert(sqls, HasLen, 0)
+		c.Assert(keys, HasLen, 0)
+		c.Assert(values, HasLen, 0)
+	}
+
+	// the ignored column is still updated along with other columns
+	sqls, _, values, err := genUpdateSQLs("db", "heartbeat", [][]interface{}{old, onlyTimestamp, old, both}, columns, pk, nil, false, opts)
+	c.Assert(err, IsNil)
+	c.Assert(sqls, DeepEquals, []string{"UPDATE `db`.`heartbeat` SET `a` = ?, `updated_at` = ? WHERE `id` = ? LIMIT 1;"})
+	c.Assert(values, DeepEquals, [][]interface{}{{int32(20), "2020-01-01 00:00:05", int32(1)}})
+
+	// the ignored column is changed according to the partial images
+	images := &rowImages{before: []byte{0x01}, after: []byte{0x04}}
+	sqls, _, _, err = genUpdateSQLs("db", "heartbeat", [][]interface{}{{int32(1), nil, nil, nil}, {nil, nil, "2020-01-01 00:00:05", nil}}, columns, pk, images, false, opts)
+	c.Assert(err, IsNil)
+	c.Assert(sqls, HasLen, 0)
+
+	// the schema level rule, and no rule
+	ignored, err = ic.columns("db", "other")
+	c.Assert(err, IsNil)
+	c.Assert(ignored, DeepEquals, map[string]struct{}{"version": {}})
+	ignored, err = ic.columns("other", "heartbeat")
+	c.Assert(err, IsNil)
+	c.Assert(ignored, IsNil)
+	opts = &dmlOptions{keyGen: testDMLOptions.keyGen, changeIgnoredColumns: ignored}
+	sqls, _, _, err = genUpdateSQLs("db", "heartbeat", [][]interface{}{old, onlyTimestamp}, columns, pk, nil, false, opts)
+	c.Assert(err, IsNil)
+	c.Assert(sqls, DeepEquals, []string{"UPDATE `db`.`heartbeat` SET `updated_at` = ? WHERE `id` = ? LIMIT 1;"})
+
+	// ambiguous rules in the same level
+	ic, err = newIgnoredColumns(true, []*config.TableChangeIgnoredColumns{
+		{SchemaPattern: "db", TablePattern: "t*", Columns: []string{"a"}},
+		{SchemaPattern: "db", TablePattern: "tb*", Columns: []string{"b"}},
+	})
+	c.Assert(err, IsNil)
+	_, err = ic.columns("db", "tbl")
+	c.Assert(err, NotNil)
+	ignored, err = ic.columns("DB", "tbl")
+	c.Assert(err, IsNil)
+	c.Assert(ignored, IsNil)
+}
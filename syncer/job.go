@@ -64,7 +64,11 @@ type job struct {
 	sql          string
 	args         []interface{}
 	key          string
+	keys         []string        // keys of the rows changed by sql, reported when sql fails
+	fallback     *fallbackStmt   // executed if sql matches no row in the target, see Conn.handleMissingRow
+	deletes      conflictDeletes // executed if sql fails with a duplicate entry, see Conn.handleKeyConflict
 	retry        bool
+	txnPending   bool // more jobs of the same source transaction follow, see Syncer.commitTxn
 	pos          mysql.Position
 	currentPos   mysql.Position // exactly binlog position of current SQL
 	gtidSet      gtid.Set
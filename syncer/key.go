@@ -0,0 +1,97 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syncer
+
+import (
+	"encoding/binary"
+
+	"github.com/pingcap/dm/dm/config"
+)
+
+// KeyGenerator generates the key of a row used for conflict detection in causality
+type KeyGenerator interface {
+	// GenKey generates the key from the values of the index columns
+	GenKey(columns []*column, values []interface{}) string
+}
+
+// NewKeyGenerator creates a KeyGenerator for strategy, comma-joined keys are used by default
+func NewKeyGenerator(strategy string) KeyGenerator {
+	if strategy == config.KeyStrategyHash {
+		return hashKeyGenerator{}
+	}
+	return joinKeyGenerator{}
+}
+
+// joinKeyGenerator joins the escaped values with commas, keys are readable in logs
+type joinKeyGenerator struct{}
+
+// GenKey implements KeyGenerator.GenKey
+func (joinKeyGenerator) GenKey(columns []*column, values []interface{}) string {
+	return genKeyList(columns, values)
+}
+
+// FNV-1a 64-bit parameters
+const (
+	fnvOffset64 = 14695981039346656037
+	fnvPrime64  = 1099511628211
+)
+
+// hashKeyGenerator hashes the length-prefixed values into a fixed 8 bytes key,
+// it reduces the memory used by causality when the keys are wide.
+// different rows may have a same key in rare cases, which only leads to an unnecessary flush of jobs.
+type hashKeyGenerator struct{}
+
+// GenKey implements KeyGenerator.GenKey
+func (hashKeyGenerator) GenKey(columns []*column, values []interface{}) string {
+	var (
+		h      uint64 = fnvOffset64
+		prefix [binary.MaxVarintLen64 + 1]byte
+	)
+	for i, value := range values {
+		// distinguish NULL from the string "null"
+		if value == nil {
+			prefix[0] = 0
+			h = fnvBytes(h, prefix[:1])
+			continue
+		}
+		data := columnValue(value, columns[i].unsigned, columns[i].tp)
+		if !columns[i].binary {
+			data = foldCollationKey(data, columns[i].collation)
+		}
+		prefix[0] = 1
+		n := binary.PutUvarint(prefix[1:], uint64(len(data)))
+		h = fnvBytes(h, prefix[:n+1])
+		h = fnvString(h, data)
+	}
+
+	var key [8]byte
+	binary.BigEndian.PutUint64(key[:], h)
+	return string(key[:])
+}
+
+func fnvBytes(h uint64, data []byte) uint64 {
+	for _, b := range data {
+		h ^= uint64(b)
+		h *= fnvPrime64
+	}
+	return h
+}
+
+func fnvString(h uint64, data string) uint64 {
+	for i := 0; i < len(data); i++ {
+		h ^= uint64(data[i])
+		h *= fnvPrime64
+	}
+	return h
+}
@@ -0,0 +1,71 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syncer
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/pingcap/check"
+
+	"github.com/pingcap/dm/dm/config"
+)
+
+func (s *testSyncerSuite) TestKeyGenerator(c *C) {
+	columns := []*column{
+		{idx: 0, name: "a", tp: "varchar(20)"},
+		{idx: 1, name: "b", tp: "varchar(20)"},
+	}
+
+	join := NewKeyGenerator(config.KeyStrategyJoin)
+	c.Assert(join.GenKey(columns, []interface{}{"a,b", "c"}), Equals, genKeyList(columns, []interface{}{"a,b", "c"}))
+	c.Assert(NewKeyGenerator(""), Equals, join)
+
+	hash := NewKeyGenerator(config.KeyStrategyHash)
+	key := hash.GenKey(columns, []interface{}{"a,b", "c"})
+	c.Assert(key, HasLen, 8)
+	c.Assert(hash.GenKey(columns, []interface{}{"a,b", "c"}), Equals, key)
+	c.Assert(hash.GenKey(columns, []interface{}{"a", "b,c"}), Not(Equals), key)
+	c.Assert(hash.GenKey(columns, []interface{}{"ab", ""}), Not(Equals), hash.GenKey(columns, []interface{}{"a", "b"}))
+	c.Assert(hash.GenKey(columns, []interface{}{nil, "c"}), Not(Equals), hash.GenKey(columns, []interface{}{"null", "c"}))
+
+	// keys of statements are generated by the KeyGenerator
+	indexColumns := map[string][]*column{"uk": columns}
+	_, keys, _, err := genInsertSQLs("db", "tbl", [][]interface{}{{"a,b", "c"}}, columns, indexColumns, 1, config.ConflictReplace, &dmlOptions{keyGen: hash})
+	c.Assert(err, IsNil)
+	c.Assert(keys, DeepEquals, [][]string{{key}})
+}
+
+func benchmarkKeyGenerator(b *testing.B, keyGen KeyGenerator) {
+	columns := make([]*column, 0, 8)
+	values := make([]interface{}, 0, 8)
+	for i := 0; i < 4; i++ {
+		columns = append(columns, &column{idx: len(columns), tp: "varchar(255)"}, &column{idx: len(columns) + 1, tp: "bigint(20)"})
+		values = append(values, strings.Repeat("x", 64), int64(1234567890)*int64(i+1))
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		keyGen.GenKey(columns, values)
+	}
+}
+
+func BenchmarkJoinKeyGenerator(b *testing.B) {
+	benchmarkKeyGenerator(b, NewKeyGenerator(config.KeyStrategyJoin))
+}
+
+func BenchmarkHashKeyGenerator(b *testing.B) {
+	benchmarkKeyGenerator(b, NewKeyGenerator(config.KeyStrategyHash))
+}
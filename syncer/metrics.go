@@ -92,6 +92,24 @@ var (
 			Buckets:   prometheus.ExponentialBuckets(0.0005, 2, 18),
 		}, []string{"task"})
 
+	// statements of tables not in metrics-tables are counted with empty schema and table
+	generatedStatementsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "dm",
+			Subsystem: "syncer",
+			Name:      "generated_statements_total",
+			Help:      "total number of generated DML statements",
+		}, []string{"type", "task", "schema", "table"})
+
+	// UPDATE and DELETE statements matching no row in the target, counted if upsert-on-missing is set
+	missingRowsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "dm",
+			Subsystem: "syncer",
+			Name:      "missing_rows_total",
+			Help:      "total number of DML statements matching no row in the target",
+		}, []string{"type", "task"})
+
 	// FIXME: should I move it to dm-worker?
 	cpuUsageGauge = prometheus.NewGauge(
 		prometheus.GaugeOpts{
@@ -138,6 +156,8 @@ func RegisterMetrics(registry *prometheus.Registry) {
 	registry.MustRegister(binlogPosGauge)
 	registry.MustRegister(binlogFileGauge)
 	registry.MustRegister(txnHistogram)
+	registry.MustRegister(generatedStatementsTotal)
+	registry.MustRegister(missingRowsTotal)
 	registry.MustRegister(cpuUsageGauge)
 	registry.MustRegister(syncerExitWithErrorCounter)
 	registry.MustRegister(replicationLagGauge)
@@ -159,6 +179,18 @@ func (s *Syncer) runBackgroundJob(ctx context.Context) {
 	}
 }
 
+// addGeneratedStatements counts the DML statements generated for rows of the target table,
+// only tables in metrics-tables are labeled by their names to bound the cardinality.
+func (s *Syncer) addGeneratedStatements(tp, schema, table string, count int) {
+	if count == 0 {
+		return
+	}
+	if _, ok := s.metricsTables[schema+"."+table]; !ok {
+		schema, table = "", ""
+	}
+	generatedStatementsTotal.WithLabelValues(tp, s.cfg.Name, schema, table).Add(float64(count))
+}
+
 // Note: handle error inside the function with returning it.
 func (s *Syncer) collectMetrics() {
 	// CPU usage metric
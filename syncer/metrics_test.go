@@ -0,0 +1,66 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syncer
+
+import (
+	. "github.com/pingcap/check"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/pingcap/dm/dm/config"
+)
+
+func (s *testSyncerSuite) TestGeneratedStatementsMetrics(c *C) {
+	cfg := &config.SubTaskConfig{Name: "test-generated-statements"}
+	cfg.MetricsTables = []string{"db.tbl"}
+	syncer := NewSyncer(cfg)
+
+	columns := []*column{
+		{idx: 0, name: "id", NotNull: true, tp: "int(11)"},
+		{idx: 1, name: "a", tp: "int(11)"},
+	}
+	indexColumns := map[string][]*column{"primary": {columns[0]}}
+	rows := [][]interface{}{{int32(1), int32(10)}, {int32(2), int32(20)}, {int32(3), int32(30)}}
+	updated := [][]interface{}{rows[0], {int32(1), int32(11)}, rows[1], {int32(2), int32(21)}}
+	counter := func(tp, schema, table string) float64 {
+		return testutil.ToFloat64(generatedStatementsTotal.WithLabelValues(tp, cfg.Name, schema, table))
+	}
+
+	for _, table := range []string{"tbl", "other"} {
+		sqls, _, _, err := genInsertSQLs("db", table, rows, columns, indexColumns, 1, config.ConflictReplace, testDMLOptions)
+		c.Assert(err, IsNil)
+		syncer.addGeneratedStatements("insert", "db", table, len(sqls))
+		sqls, _, _, err = genUpdateSQLs("db", table, updated, columns, indexColumns, nil, false, testDMLOptions)
+		c.Assert(err, IsNil)
+		syncer.addGeneratedStatements("update", "db", table, len(sqls))
+		sqls, _, _, err = genUpdateSQLs("db", table, updated, columns, indexColumns, nil, true, testDMLOptions)
+		c.Assert(err, IsNil)
+		syncer.addGeneratedStatements("safe_mode_update", "db", table, len(sqls))
+		sqls, _, _, err = genDeleteSQLs("db", table, rows[:1], columns, indexColumns, testDMLOptions)
+		c.Assert(err, IsNil)
+		syncer.addGeneratedStatements("delete", "db", table, len(sqls))
+	}
+
+	// tables in metrics-tables are labeled by their names
+	c.Assert(counter("insert", "db", "tbl"), Equals, 3.0)
+	c.Assert(counter("update", "db", "tbl"), Equals, 2.0)
+	c.Assert(counter("safe_mode_update", "db", "tbl"), Equals, 4.0) // DELETE and REPLACE for 2 rows
+	c.Assert(counter("delete", "db", "tbl"), Equals, 1.0)
+	// other tables are counted together
+	c.Assert(counter("insert", "", ""), Equals, 3.0)
+	c.Assert(counter("safe_mode_update", "", ""), Equals, 4.0)
+	c.Assert(counter("insert", "db", "other"), Equals, 0.0)
+
+	syncer.addGeneratedStatements("insert", "db", "tbl", 2)
+	c.Assert(counter("insert", "db", "tbl"), Equals, 5.0)
+}
@@ -17,35 +17,17 @@ import (
 	"time"
 
 	"github.com/pingcap/dm/pkg/log"
-	"github.com/pingcap/errors"
-	"golang.org/x/net/context"
 
-	"github.com/pingcap/dm/dm/pb"
-	"github.com/pingcap/dm/dm/unit"
 	sm "github.com/pingcap/dm/syncer/safe-mode"
 )
 
-func (s *Syncer) enableSafeModeInitializationPhase(ctx context.Context, safeMode *sm.SafeMode) {
+func (s *Syncer) enableSafeModeInitializationPhase(safeMode *sm.SafeModeController) {
 	safeMode.Reset() // in initialization phase, reset first
-	safeMode.Add(1)  // try to enable
+	safeMode.Resume(time.Now())
+	log.Infof("[syncer] enable safe-mode for events in %v after resumed", s.safeModeDuration)
 
 	if s.cfg.SafeMode {
 		safeMode.Add(1) // add 1 but should no corresponding -1
 		log.Info("[syncer] enable safe-mode by config")
 	}
-
-	go func() {
-		defer func() {
-			err := safeMode.Add(-1) // try to disable after 5 minutes
-			if err != nil {
-				// send error to the fatal chan to interrupt the process
-				s.runFatalChan <- unit.NewProcessError(pb.ErrorType_UnknownError, errors.ErrorStack(err))
-			}
-		}()
-
-		select {
-		case <-ctx.Done():
-		case <-time.After(5 * time.Minute):
-		}
-	}()
 }
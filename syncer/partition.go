@@ -0,0 +1,91 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syncer
+
+import (
+	"sync"
+
+	"github.com/pingcap/tidb-tools/pkg/dbutil"
+)
+
+// PartitionFunc returns the partition of a row inserted into a partitioned target table,
+// row is the values (casted) of columns in order, including generated columns.
+// rows with the same unique key must be in the same partition, which is required by MySQL for partitioning columns too.
+type PartitionFunc func(columns []string, row []interface{}) int
+
+var (
+	partitionFuncsLock sync.RWMutex
+	partitionFuncs     = make(map[string]PartitionFunc)
+)
+
+// RegisterPartitionFunc registers fn to compute partitions of rows inserted into the target table,
+// rows of an event are grouped by their partitions before they are coalesced into multi-row INSERT statements (see insert-batch),
+// so a statement touches as few partitions as possible. it changes the order of rows in different partitions only, not the rows.
+// it should be called before syncers are created, and fn replaces the previous one registered for the table.
+func RegisterPartitionFunc(schema, table string, fn PartitionFunc) {
+	partitionFuncsLock.Lock()
+	defer partitionFuncsLock.Unlock()
+	partitionFuncs[dbutil.TableName(schema, table)] = fn
+}
+
+// UnregisterPartitionFunc removes the partition function registered for the target table
+func UnregisterPartitionFunc(schema, table string) {
+	partitionFuncsLock.Lock()
+	defer partitionFuncsLock.Unlock()
+	delete(partitionFuncs, dbutil.TableName(schema, table))
+}
+
+// registeredPartitionFuncs returns a copy of all registered partition functions, nil if none registered
+func registeredPartitionFuncs() map[string]PartitionFunc {
+	partitionFuncsLock.RLock()
+	defer partitionFuncsLock.RUnlock()
+	if len(partitionFuncs) == 0 {
+		return nil
+	}
+	funcs := make(map[string]PartitionFunc, len(partitionFuncs))
+	for table, fn := range partitionFuncs {
+		funcs[table] = fn
+	}
+	return funcs
+}
+
+// groupByPartition returns the indexes of rows grouped by their partitions computed by fn,
+// groups are in the order of their first rows, and rows in a group keep their order.
+func groupByPartition(fn PartitionFunc, columns []*column, rows [][]interface{}) []int {
+	names := make([]string, 0, len(columns))
+	for _, col := range columns {
+		names = append(names, col.name)
+	}
+
+	var (
+		groups  [][]int
+		indexes = make(map[int]int) // partition -> index of its group
+	)
+	for i, row := range rows {
+		p := fn(names, row)
+		g, ok := indexes[p]
+		if !ok {
+			g = len(groups)
+			indexes[p] = g
+			groups = append(groups, nil)
+		}
+		groups[g] = append(groups[g], i)
+	}
+
+	order := make([]int, 0, len(rows))
+	for _, group := range groups {
+		order = append(order, group...)
+	}
+	return order
+}
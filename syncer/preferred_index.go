@@ -0,0 +1,48 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syncer
+
+import (
+	"strings"
+
+	"github.com/pingcap/errors"
+
+	"github.com/pingcap/dm/dm/config"
+	"github.com/pingcap/dm/pkg/utils"
+)
+
+// preferredIndexes resolves the preferred unique indexes of target tables, see config.SyncerConfig.TablePreferredIndexes
+type preferredIndexes struct {
+	rules *utils.TableRules
+}
+
+func newPreferredIndexes(caseSensitive bool, rules []*config.TablePreferredIndex) (*preferredIndexes, error) {
+	p := &preferredIndexes{rules: utils.NewTableRules("preferred indexes", caseSensitive)}
+	for _, rule := range rules {
+		if err := p.rules.Insert(rule.SchemaPattern, rule.TablePattern, rule); err != nil {
+			return nil, errors.Annotatef(err, "table preferred index %+v", rule)
+		}
+	}
+	return p, nil
+}
+
+// index returns the name of the preferred index of the table in lower case like key names of table.indexColumns, empty if none.
+// see utils.TableRules for rules matching the table.
+func (p *preferredIndexes) index(schema, table string) (string, error) {
+	rule, err := p.rules.Match(schema, table)
+	if err != nil || rule == nil {
+		return "", errors.Trace(err)
+	}
+	return strings.ToLower(rule.(*config.TablePreferredIndex).Index), nil
+}
@@ -0,0 +1,103 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syncer
+
+import (
+	. "github.com/pingcap/check"
+
+	"github.com/pingcap/dm/dm/config"
+	"github.com/pingcap/dm/pkg/log"
+)
+
+func (s *testSyncerSuite) TestPreferredIndexes(c *C) {
+	p, err := newPreferredIndexes(false, []*config.TablePreferredIndex{
+		{SchemaPattern: "db", TablePattern: "users", Index: "uk_bc"},
+		{SchemaPattern: "db", TablePattern: "orders", Index: "UK_A"},
+		{SchemaPattern: "db", Index: "uk_missing"},
+	})
+	c.Assert(err, IsNil)
+
+	columns := []*column{
+		{idx: 0, name: "id", NotNull: true, tp: "int(11)"},
+		{idx: 1, name: "a", NotNull: true, tp: "int(11)"},
+		{idx: 2, name: "b", tp: "int(11)"},
+		{idx: 3, name: "c", tp: "int(11)"},
+	}
+	indexColumns := map[string][]*column{
+		"primary": {columns[0]},
+		"uk_a":    {columns[1]},
+		"uk_bc":   {columns[3], columns[2]},
+	}
+	update := func(opts *dmlOptions, old []interface{}) string {
+		changed := append([]interface{}{}, old...)
+		changed[3] = int32(99)
+		sqls, _, _, err := genUpdateSQLs("db", "tbl", [][]interface{}{old, changed}, columns, indexColumns, nil, false, opts)
+		c.Assert(err, IsNil)
+		c.Assert(sqls, HasLen, 1)
+		return sqls[0]
+	}
+	del := func(opts *dmlOptions, rows ...[]interface{}) []string {
+		sqls, _, _, err := genDeleteSQLs("db", "tbl", rows, columns, indexColumns, opts)
+		c.Assert(err, IsNil)
+		return sqls
+	}
+	row := []interface{}{int32(1), int32(2), int32(3), int32(4)}
+	nullRow := []interface{}{int32(5), int32(6), nil, int32(8)}
+
+	// the not null preferred index is used for all rows, rather than the primary key
+	index, err := p.index("DB", "Orders")
+	c.Assert(err, IsNil)
+	c.Assert(index, Equals, "uk_a")
+	opts := &dmlOptions{keyGen: testDMLOptions.keyGen, preferredIndex: index}
+	c.Assert(findFitIndex(indexColumns, index, log.GlobalLogger()), DeepEquals, []*column{columns[1]})
+	c.Assert(update(opts, row), Equals, "UPDATE `db`.`tbl` SET `c` = ? WHERE `a` = ? LIMIT 1;")
+	c.Assert(del(opts, row, nullRow), DeepEquals, []string{"DELETE FROM `db`.`tbl` WHERE `a` IN (?,?);"})
+
+	// the nullable preferred index is used if it has no NULL in the row, or else the primary key is used
+	index, err = p.index("db", "users")
+	c.Assert(err, IsNil)
+	c.Assert(index, Equals, "uk_bc")
+	opts = &dmlOptions{keyGen: testDMLOptions.keyGen, preferredIndex: index}
+	c.Assert(findFitIndex(indexColumns, index, log.GlobalLogger()), DeepEquals, []*column{columns[0]})
+	c.Assert(update(opts, row), Equals, "UPDATE `db`.`tbl` SET `c` = ? WHERE `b` = ? AND `c` = ? LIMIT 1;")
+	c.Assert(update(opts, nullRow), Equals, "UPDATE `db`.`tbl` SET `c` = ? WHERE `id` = ? LIMIT 1;")
+	c.Assert(del(opts, row, nullRow), DeepEquals, []string{
+		"DELETE FROM `db`.`tbl` WHERE `b` = ? AND `c` = ?;",
+		"DELETE FROM `db`.`tbl` WHERE `id` = ?;",
+	})
+
+	// the preferred index doesn't exist, and no preferred index
+	for _, table := range []string{"tbl", "other"} {
+		index, err = p.index("db", table)
+		c.Assert(err, IsNil)
+		opts = &dmlOptions{keyGen: testDMLOptions.keyGen, preferredIndex: index}
+		c.Assert(update(opts, row), Equals, "UPDATE `db`.`tbl` SET `c` = ? WHERE `id` = ? LIMIT 1;")
+		c.Assert(del(opts, row, nullRow), DeepEquals, []string{"DELETE FROM `db`.`tbl` WHERE `id` IN (?,?);"})
+	}
+	index, err = p.index("other", "users")
+	c.Assert(err, IsNil)
+	c.Assert(index, Equals, "")
+
+	// ambiguous rules in the same level
+	p, err = newPreferredIndexes(true, []*config.TablePreferredIndex{
+		{SchemaPattern: "db", TablePattern: "t*", Index: "uk_a"},
+		{SchemaPattern: "db", TablePattern: "tb*", Index: "uk_bc"},
+	})
+	c.Assert(err, IsNil)
+	_, err = p.index("db", "tbl")
+	c.Assert(err, NotNil)
+	index, err = p.index("DB", "tbl")
+	c.Assert(err, IsNil)
+	c.Assert(index, Equals, "")
+}
@@ -0,0 +1,86 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syncer
+
+import (
+	"reflect"
+)
+
+// rowImages holds the columns present in the before and after images of UPDATE rows events,
+// they are partial if the upstream uses `binlog_row_image=MINIMAL`, then the before image has the primary key only
+// (all columns if no primary key) and the after image has the columns set by the statement.
+// columns missing in an image are NULL in rows, their values must not be written to the target or used to match rows.
+type rowImages struct {
+	before []byte // bitmap of columns present in the before image, like RowsEvent.ColumnBitmap1
+	after  []byte // bitmap of columns present in the after image, like RowsEvent.ColumnBitmap2
+}
+
+// newRowImages creates a rowImages from the bitmaps of count columns, it returns nil if both images are full
+func newRowImages(before, after []byte, count int) *rowImages {
+	for i := 0; i < count; i++ {
+		if !isBitSet(before, i) || !isBitSet(after, i) {
+			return &rowImages{before: before, after: after}
+		}
+	}
+	return nil
+}
+
+func isBitSet(bitmap []byte, i int) bool {
+	return i>>3 < len(bitmap) && bitmap[i>>3]&(1<<(uint(i)&7)) > 0
+}
+
+// inBefore returns whether all of columns are present in the before image
+func (im *rowImages) inBefore(columns []*column) bool {
+	for _, col := range columns {
+		if !isBitSet(im.before, col.idx) {
+			return false
+		}
+	}
+	return true
+}
+
+// fillAfter fills the columns missing in the after image with the values in the before image,
+// they are not changed, so keys of the changed row are generated correctly
+func (im *rowImages) fillAfter(oldValues, changedValues []interface{}) {
+	for i := range changedValues {
+		if !isBitSet(im.after, i) {
+			changedValues[i] = oldValues[i]
+		}
+	}
+}
+
+// isChanged returns whether the i-th column should be set by the UPDATE statement,
+// a column present in the after image is set if its old value is unknown.
+func (im *rowImages) isChanged(i int, oldValues, changedValues []interface{}) bool {
+	if !isBitSet(im.after, i) {
+		return false
+	}
+	if !isBitSet(im.before, i) {
+		return true
+	}
+	return !reflect.DeepEqual(oldValues[i], changedValues[i])
+}
+
+// filterBefore filters out the columns missing in the before image and the corresponding values
+func (im *rowImages) filterBefore(columns []*column, values []interface{}) ([]*column, []interface{}) {
+	cols := make([]*column, 0, len(columns))
+	vals := make([]interface{}, 0, len(values))
+	for i, col := range columns {
+		if isBitSet(im.before, col.idx) {
+			cols = append(cols, col)
+			vals = append(vals, values[i])
+		}
+	}
+	return cols, vals
+}
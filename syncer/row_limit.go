@@ -0,0 +1,50 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syncer
+
+import (
+	"github.com/pingcap/errors"
+
+	"github.com/pingcap/dm/dm/config"
+	"github.com/pingcap/dm/pkg/utils"
+)
+
+// rowLimits resolves the row limits of target tables, see config.SyncerConfig.RowLimit,
+// tables not matched by any rule use the default limit.
+type rowLimits struct {
+	defaultLimit int
+	rules        *utils.TableRules
+}
+
+func newRowLimits(caseSensitive bool, defaultLimit int, rules []*config.TableRowLimit) (*rowLimits, error) {
+	l := &rowLimits{defaultLimit: defaultLimit, rules: utils.NewTableRules("row limits", caseSensitive)}
+	for _, rule := range rules {
+		if err := l.rules.Insert(rule.SchemaPattern, rule.TablePattern, rule); err != nil {
+			return nil, errors.Annotatef(err, "table row limit %+v", rule)
+		}
+	}
+	return l, nil
+}
+
+// limit returns the row limit of the table, see utils.TableRules for rules matching the table
+func (l *rowLimits) limit(schema, table string) (int, error) {
+	rule, err := l.rules.Match(schema, table)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	if rule == nil {
+		return l.defaultLimit, nil
+	}
+	return rule.(*config.TableRowLimit).Limit, nil
+}
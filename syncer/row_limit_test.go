@@ -0,0 +1,90 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syncer
+
+import (
+	. "github.com/pingcap/check"
+
+	"github.com/pingcap/dm/dm/config"
+)
+
+func (s *testSyncerSuite) TestRowLimits(c *C) {
+	rl, err := newRowLimits(false, 1, []*config.TableRowLimit{
+		{SchemaPattern: "db", TablePattern: "log_*", Limit: config.NoRowLimit},
+		{SchemaPattern: "db", TablePattern: "users", Limit: 3},
+		{SchemaPattern: "stats*", Limit: 10},
+		{SchemaPattern: "stats_1", TablePattern: "t", Limit: 2},
+	})
+	c.Assert(err, IsNil)
+
+	columns := []*column{
+		{idx: 0, name: "id", NotNull: true, tp: "int(11)"},
+		{idx: 1, name: "a", tp: "int(11)"},
+	}
+	pk := map[string][]*column{"primary": {columns[0]}}
+	data := [][]interface{}{{int32(1), int32(10)}, {int32(1), int32(20)}}
+
+	cases := []struct {
+		schema, table string
+		limit         int
+		clause        string
+	}{
+		{"db", "users", 3, " LIMIT 3"},
+		{"db", "log_202001", config.NoRowLimit, ""},
+		{"DB", "LOG_1", config.NoRowLimit, ""},
+		{"stats_2", "t", 10, " LIMIT 10"},
+		{"stats_1", "t", 2, " LIMIT 2"},
+		{"other", "t", 1, " LIMIT 1"},
+	}
+	for _, tc := range cases {
+		limit, err := rl.limit(tc.schema, tc.table)
+		c.Assert(err, IsNil)
+		c.Assert(limit, Equals, tc.limit)
+		opts := &dmlOptions{keyGen: testDMLOptions.keyGen, rowLimit: limit}
+
+		// the LIMIT clause is used if no unique index identifies the row
+		sqls, _, _, err := genUpdateSQLs(tc.schema, tc.table, data, columns, nil, nil, false, opts)
+		c.Assert(err, IsNil)
+		c.Assert(sqls, DeepEquals, []string{"UPDATE `" + tc.schema + "`.`" + tc.table + "` SET `a` = ? WHERE `id` = ? AND `a` = ?" + tc.clause + ";"})
+		sqls, _, _, err = genDeleteSQLs(tc.schema, tc.table, data[:1], columns, nil, opts)
+		c.Assert(err, IsNil)
+		c.Assert(sqls, DeepEquals, []string{"DELETE FROM `" + tc.schema + "`.`" + tc.table + "` WHERE `id` = ? AND `a` = ?" + tc.clause + ";"})
+
+		// at most one row is matched by the unique index
+		sqls, _, _, err = genUpdateSQLs(tc.schema, tc.table, data, columns, pk, nil, false, opts)
+		c.Assert(err, IsNil)
+		c.Assert(sqls, DeepEquals, []string{"UPDATE `" + tc.schema + "`.`" + tc.table + "` SET `a` = ? WHERE `id` = ? LIMIT 1;"})
+		sqls, _, _, err = genDeleteSQLs(tc.schema, tc.table, data[:1], columns, pk, opts)
+		c.Assert(err, IsNil)
+		c.Assert(sqls, DeepEquals, []string{"DELETE FROM `" + tc.schema + "`.`" + tc.table + "` WHERE `id` = ?;"})
+	}
+
+	// update-all-duplicates overrides the limit of UPDATE statements
+	opts := &dmlOptions{keyGen: testDMLOptions.keyGen, rowLimit: 3, updateAllDuplicates: true}
+	sqls, _, _, err := genUpdateSQLs("db", "tbl", data, columns, nil, nil, false, opts)
+	c.Assert(err, IsNil)
+	c.Assert(sqls, DeepEquals, []string{"UPDATE `db`.`tbl` SET `a` = ? WHERE `id` = ? AND `a` = ?;"})
+
+	// ambiguous rules in the same level
+	rl, err = newRowLimits(true, 1, []*config.TableRowLimit{
+		{SchemaPattern: "db", TablePattern: "t*", Limit: 2},
+		{SchemaPattern: "db", TablePattern: "tb*", Limit: 3},
+	})
+	c.Assert(err, IsNil)
+	_, err = rl.limit("db", "tbl")
+	c.Assert(err, NotNil)
+	limit, err := rl.limit("DB", "tbl")
+	c.Assert(err, IsNil)
+	c.Assert(limit, Equals, 1)
+}
@@ -0,0 +1,65 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mode
+
+import (
+	"sync"
+	"time"
+)
+
+// SafeModeController reports whether safe-mode is enabled for binlog events.
+// events before the last saved checkpoint may be replicated again after the syncer resumed,
+// so safe-mode is enabled for events happening before the window (duration after the last resume) ends.
+// it's also enabled whenever the count of the embedded SafeMode is not 0, like enabled by config or for sharding re-syncing.
+type SafeModeController struct {
+	*SafeMode
+
+	mu       sync.RWMutex
+	duration time.Duration
+	resumeAt time.Time // zero means not resumed
+}
+
+// NewSafeModeController creates a new SafeModeController with the window duration
+func NewSafeModeController(duration time.Duration) *SafeModeController {
+	return &SafeModeController{
+		SafeMode: NewSafeMode(),
+		duration: duration,
+	}
+}
+
+// Resume records the time when the syncer resumed, safe-mode is enabled for events before now + duration
+func (c *SafeModeController) Resume(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.resumeAt = now
+}
+
+// EnableFor returns whether safe-mode is enabled for the event with timestamp (seconds since epoch, in binlog event header).
+// events without timestamp (like fake events) are regarded as happening now.
+func (c *SafeModeController) EnableFor(timestamp uint32, now time.Time) bool {
+	if c.Enable() {
+		return true
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.resumeAt.IsZero() {
+		return false
+	}
+	eventTime := now
+	if timestamp > 0 {
+		eventTime = time.Unix(int64(timestamp), 0)
+	}
+	return eventTime.Before(c.resumeAt.Add(c.duration))
+}
@@ -15,6 +15,7 @@ package mode
 
 import (
 	"testing"
+	"time"
 
 	. "github.com/pingcap/check"
 )
@@ -79,3 +80,31 @@ func (t *testModeSuite) TestMode(c *C) {
 	err = m.Add(-1)
 	c.Assert(err, NotNil)
 }
+
+func (t *testModeSuite) TestController(c *C) {
+	m := NewSafeModeController(5 * time.Minute)
+	now := time.Unix(1557000000, 0)
+	ts := func(d time.Duration) uint32 { return uint32(now.Add(d).Unix()) }
+	c.Assert(m.EnableFor(ts(0), now), IsFalse) // not resumed
+
+	m.Resume(now)
+	c.Assert(m.EnableFor(ts(-time.Hour), now), IsTrue) // replicated again
+	c.Assert(m.EnableFor(ts(4*time.Minute), now), IsTrue)
+	c.Assert(m.EnableFor(ts(5*time.Minute), now), IsFalse)
+	c.Assert(m.EnableFor(ts(time.Hour), now), IsFalse)
+	// events without timestamp happen now
+	c.Assert(m.EnableFor(0, now.Add(time.Minute)), IsTrue)
+	c.Assert(m.EnableFor(0, now.Add(10*time.Minute)), IsFalse)
+
+	// enabled out of the window by the count
+	c.Assert(m.IncrForTable("schema", "table"), IsNil)
+	c.Assert(m.EnableFor(ts(time.Hour), now), IsTrue)
+	c.Assert(m.DescForTable("schema", "table"), IsNil)
+	c.Assert(m.EnableFor(ts(time.Hour), now), IsFalse)
+
+	// no window
+	m = NewSafeModeController(0)
+	m.Resume(now)
+	c.Assert(m.EnableFor(ts(0), now), IsFalse)
+	c.Assert(m.EnableFor(ts(-time.Second), now), IsTrue)
+}
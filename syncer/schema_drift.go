@@ -0,0 +1,171 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syncer
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/parser"
+	"github.com/siddontang/go-mysql/replication"
+
+	"github.com/pingcap/dm/dm/config"
+	"github.com/pingcap/dm/pkg/log"
+)
+
+// display widths of integer types don't affect values, and MySQL 8.0 doesn't show them
+var intDisplayWidthRegexp = regexp.MustCompile(`^(tinyint|smallint|mediumint|int|bigint)\(\d+\)`)
+
+// schemaDiff is the difference between the columns of a table declared in the dump and the columns of its target table
+type schemaDiff struct {
+	missing      []string // columns in the dump missing in the target
+	mismatched   []string // columns with different types, like "`c` varchar(20) vs varchar(10)"
+	extraNotNull []string // NOT NULL columns only in the target without DEFAULT values, which rows of the source can't fill
+}
+
+func (d *schemaDiff) String() string {
+	var parts []string
+	if len(d.missing) > 0 {
+		parts = append(parts, "missing columns "+strings.Join(d.missing, ", "))
+	}
+	if len(d.mismatched) > 0 {
+		parts = append(parts, "mismatched types (dump vs target) "+strings.Join(d.mismatched, ", "))
+	}
+	if len(d.extraNotNull) > 0 {
+		parts = append(parts, "extra NOT NULL columns without DEFAULT "+strings.Join(d.extraNotNull, ", "))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// normalizeColumnType returns the type of a column comparable between the dump and the target
+func normalizeColumnType(tp string) string {
+	return intDisplayWidthRegexp.ReplaceAllString(strings.ToLower(strings.TrimSpace(tp)), "$1")
+}
+
+// parseDumpColumns returns the columns declared by the CREATE TABLE statement in a schema file of the dump
+func parseDumpColumns(p *parser.Parser, schemaSQL string) ([]*column, error) {
+	tbl, err := parseCreateTable(p, "", schemaSQL)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return tbl.columns, nil
+}
+
+// diffColumns compares the columns of a table declared in the dump with the columns of its target table by names (case insensitive) and types,
+// it returns nil if no differences.
+func diffColumns(dumped, target []*column) *schemaDiff {
+	targetColumns := make(map[string]*column, len(target))
+	for _, col := range target {
+		targetColumns[strings.ToLower(col.name)] = col
+	}
+
+	diff := &schemaDiff{}
+	dumpedColumns := make(map[string]struct{}, len(dumped))
+	for _, col := range dumped {
+		name := strings.ToLower(col.name)
+		dumpedColumns[name] = struct{}{}
+		tc, ok := targetColumns[name]
+		if !ok {
+			diff.missing = append(diff.missing, fmt.Sprintf("`%s`", col.name))
+			continue
+		}
+		if normalizeColumnType(col.tp) != normalizeColumnType(tc.tp) {
+			diff.mismatched = append(diff.mismatched, fmt.Sprintf("`%s` %s vs %s", col.name, col.tp, tc.tp))
+		}
+	}
+	for _, col := range target {
+		if _, ok := dumpedColumns[strings.ToLower(col.name)]; ok {
+			continue
+		}
+		if col.NotNull && col.defaultValue == nil && !col.defaultExpr && !col.IsGenerated && !col.autoIncrement {
+			diff.extraNotNull = append(diff.extraNotNull, fmt.Sprintf("`%s`", col.name))
+		}
+	}
+
+	if len(diff.missing) == 0 && len(diff.mismatched) == 0 && len(diff.extraNotNull) == 0 {
+		return nil
+	}
+	return diff
+}
+
+// parseDumpSchemaFileName returns the schema and table of a table schema file of the dump like `{db}.{table}-schema.sql`
+func parseDumpSchemaFileName(name string) (string, string, bool) {
+	if !strings.HasSuffix(name, "-schema.sql") {
+		return "", "", false
+	}
+	fields := strings.Split(strings.TrimSuffix(name, "-schema.sql"), ".")
+	if len(fields) != 2 {
+		return "", "", false
+	}
+	return fields[0], fields[1], true
+}
+
+// checkSchemaDrift compares the columns of tables declared in the dump with the columns of their target tables
+// before a fresh task of `all` mode replicates from the position of the dump, so a target table drifting from the source
+// is reported with all the differences at once, rather than failing on a row of it later. tables whose inserts are skipped aren't checked.
+func (s *Syncer) checkSchemaDrift(p *parser.Parser) error {
+	if s.cfg.Mode != config.ModeAll {
+		return nil
+	}
+	files, err := ioutil.ReadDir(s.cfg.Dir)
+	if os.IsNotExist(err) {
+		log.Warnf("[syncer] dump directory %s not found, skip checking schemas of target tables", s.cfg.Dir)
+		return nil
+	} else if err != nil {
+		return errors.Annotatef(err, "read dump directory %s", s.cfg.Dir)
+	}
+
+	var drifts []string
+	for _, f := range files {
+		schema, table, ok := parseDumpSchemaFileName(f.Name())
+		if !ok {
+			continue
+		}
+		skip, err := s.skipDMLEvent(schema, table, replication.WRITE_ROWS_EVENTv2)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if skip {
+			continue
+		}
+
+		data, err := ioutil.ReadFile(filepath.Join(s.cfg.Dir, f.Name()))
+		if err != nil {
+			return errors.Trace(err)
+		}
+		dumped, err := parseDumpColumns(p, string(data))
+		if err != nil {
+			return errors.Annotatef(err, "schema file %s", f.Name())
+		}
+		targetSchema, targetTable := s.renameShardingSchema(schema, table)
+		target, _, err := s.getTable(targetSchema, targetTable)
+		if err != nil {
+			return errors.Annotatef(err, "get columns of target table `%s`.`%s`", targetSchema, targetTable)
+		}
+		if diff := diffColumns(dumped, target.columns); diff != nil {
+			drifts = append(drifts, fmt.Sprintf("`%s`.`%s` -> `%s`.`%s`: %s", schema, table, targetSchema, targetTable, diff))
+		}
+	}
+	if len(drifts) > 0 {
+		sort.Strings(drifts)
+		return errors.Errorf("schemas of target tables drift from the dump:\n%s", strings.Join(drifts, "\n"))
+	}
+	return nil
+}
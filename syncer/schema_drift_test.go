@@ -0,0 +1,92 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syncer
+
+import (
+	. "github.com/pingcap/check"
+	"github.com/pingcap/parser"
+)
+
+func (s *testSyncerSuite) TestSchemaDrift(c *C) {
+	schemaSQL := "/*!40101 SET NAMES binary*/;\n" +
+		"CREATE TABLE `t1` (\n" +
+		"  `id` int(11) NOT NULL AUTO_INCREMENT,\n" +
+		"  `name` varchar(20) DEFAULT NULL,\n" +
+		"  `price` decimal(10,2) unsigned NOT NULL,\n" +
+		"  `note` text,\n" +
+		"  `created_at` datetime(3) NOT NULL,\n" +
+		"  PRIMARY KEY (`id`)\n" +
+		") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;\n"
+	dumped, err := parseDumpColumns(parser.New(), schemaSQL)
+	c.Assert(err, IsNil)
+	c.Assert(dumped, HasLen, 5)
+	c.Assert(dumped[0].tp, Equals, "int(11)")
+	c.Assert(dumped[0].NotNull, IsTrue)
+	c.Assert(dumped[2].tp, Equals, "decimal(10,2) unsigned")
+	c.Assert(dumped[3].NotNull, IsFalse)
+
+	// the same columns, display widths of integer types are ignored
+	target := []*column{
+		{idx: 0, name: "ID", tp: "int", NotNull: true, autoIncrement: true},
+		{idx: 1, name: "name", tp: "varchar(20)"},
+		{idx: 2, name: "price", tp: "decimal(10,2) unsigned", NotNull: true},
+		{idx: 3, name: "note", tp: "text"},
+		{idx: 4, name: "created_at", tp: "datetime(3)", NotNull: true},
+		// extra columns filled by the target
+		{idx: 5, name: "seq", tp: "bigint(20)", NotNull: true, autoIncrement: true},
+		{idx: 6, name: "updated_at", tp: "timestamp", NotNull: true, defaultValue: "CURRENT_TIMESTAMP", defaultExpr: true},
+		{idx: 7, name: "v", tp: "int(11)", NotNull: true, IsGenerated: true},
+		{idx: 8, name: "extra", tp: "int(11)"},
+	}
+	c.Assert(diffColumns(dumped, target), IsNil)
+
+	// a deliberately mismatched target
+	target = []*column{
+		{idx: 0, name: "id", tp: "bigint(20)", NotNull: true},
+		{idx: 1, name: "name", tp: "varchar(10)"},
+		{idx: 2, name: "price", tp: "decimal(10,2)", NotNull: true},
+		{idx: 3, name: "tenant", tp: "int(11)", NotNull: true},
+		{idx: 4, name: "status", tp: "tinyint(4)", NotNull: true, defaultValue: "0"},
+	}
+	diff := diffColumns(dumped, target)
+	c.Assert(diff, NotNil)
+	c.Assert(diff.missing, DeepEquals, []string{"`note`", "`created_at`"})
+	c.Assert(diff.mismatched, DeepEquals, []string{
+		"`id` int(11) vs bigint(20)",
+		"`name` varchar(20) vs varchar(10)",
+		"`price` decimal(10,2) unsigned vs decimal(10,2)",
+	})
+	c.Assert(diff.extraNotNull, DeepEquals, []string{"`tenant`"})
+	c.Assert(diff.String(), Equals, "missing columns `note`, `created_at`; "+
+		"mismatched types (dump vs target) `id` int(11) vs bigint(20), `name` varchar(20) vs varchar(10), `price` decimal(10,2) unsigned vs decimal(10,2); "+
+		"extra NOT NULL columns without DEFAULT `tenant`")
+
+	// not a CREATE TABLE statement
+	_, err = parseDumpColumns(parser.New(), "CREATE DATABASE `db`;")
+	c.Assert(err, NotNil)
+
+	for name, expected := range map[string][]string{
+		"db.t1-schema.sql":          {"db", "t1"},
+		"db-schema-create.sql":      nil,
+		"db.t1-schema-view.sql":     nil,
+		"db.t1-schema-triggers.sql": nil,
+		"db.t1.sql":                 nil,
+	} {
+		schema, table, ok := parseDumpSchemaFileName(name)
+		c.Assert(ok, Equals, expected != nil, Commentf("file %s", name))
+		if ok {
+			c.Assert([]string{schema, table}, DeepEquals, expected)
+		}
+	}
+}
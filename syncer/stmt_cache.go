@@ -0,0 +1,197 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syncer
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+)
+
+// stmtKey identifies a kind of statements of a table, like INSERT statements resolving conflicts by `replace`
+type stmtKey struct {
+	schema string
+	table  string
+	mode   string
+}
+
+// stmtTemplate is the template of statements like `head row,row,...tail`, values are bound to placeholders of rows
+type stmtTemplate struct {
+	fingerprint uint64 // fingerprint of the columns which the template is generated for
+	head        string
+	row         string
+	tail        string
+	single      string // statement of one row
+}
+
+func newStmtTemplate(head, row, tail string) *stmtTemplate {
+	return &stmtTemplate{head: head, row: row, tail: tail, single: head + row + tail}
+}
+
+// sql returns the statement of rows
+func (t *stmtTemplate) sql(rows int) string {
+	if rows == 1 {
+		return t.single
+	}
+	sql := make([]byte, 0, len(t.head)+rows*(len(t.row)+1)+len(t.tail))
+	sql = append(sql, t.head...)
+	for i := 0; i < rows; i++ {
+		if i > 0 {
+			sql = append(sql, ',')
+		}
+		sql = append(sql, t.row...)
+	}
+	sql = append(sql, t.tail...)
+	return string(sql)
+}
+
+// statementCache caches templates of statements whose shape is decided by the table, columns and mode,
+// so they are not formatted again for every row event.
+// a template is generated again if the columns of the table changed, like a column added by online DDL.
+// a nil statementCache caches nothing.
+type statementCache struct {
+	sync.RWMutex
+	templates map[stmtKey]*stmtTemplate
+}
+
+func newStatementCache() *statementCache {
+	return &statementCache{templates: make(map[stmtKey]*stmtTemplate)}
+}
+
+// get returns the template of mode for columns of the table, gen is called if it's not cached or the columns changed
+func (c *statementCache) get(schema, table, mode string, columns []*column, gen func() *stmtTemplate) *stmtTemplate {
+	if c == nil {
+		return gen()
+	}
+
+	key := stmtKey{schema: schema, table: table, mode: mode}
+	fingerprint := columnsFingerprint(columns)
+	c.RLock()
+	t, ok := c.templates[key]
+	c.RUnlock()
+	if ok && t.fingerprint == fingerprint {
+		return t
+	}
+
+	t = gen()
+	t.fingerprint = fingerprint
+	c.Lock()
+	c.templates[key] = t
+	c.Unlock()
+	return t
+}
+
+// clear removes all cached templates
+func (c *statementCache) clear() {
+	if c == nil {
+		return
+	}
+	c.Lock()
+	defer c.Unlock()
+	c.templates = make(map[stmtKey]*stmtTemplate)
+}
+
+// kinds of clauses generated for columns
+const (
+	clauseColumnList   = iota // see genColumnList
+	clausePlaceholders        // see genColumnPlaceholders
+	clauseKVs                 // see genKVs
+)
+
+// max count of clauses cached, the cache is cleared when it's full, as subsets of columns updated may be many
+const maxCachedClauses = 4096
+
+// bufferPool pools buffers to generate clauses
+var bufferPool = sync.Pool{New: func() interface{} { return new(bytes.Buffer) }}
+
+// clauseKey identifies a kind of clauses generated for columns by the fingerprint of the columns
+type clauseKey struct {
+	kind        int
+	fingerprint uint64
+}
+
+// clauseCache caches clauses like column lists generated for columns, they rarely change between events.
+// clauses depend on names and bind expressions of columns only, so they are shared between tables.
+type clauseCache struct {
+	sync.RWMutex
+	clauses map[clauseKey]string
+}
+
+var clauses = &clauseCache{clauses: make(map[clauseKey]string)}
+
+// get returns the clause of kind for columns, write is called with a pooled buffer to generate it if it's not cached
+func (c *clauseCache) get(kind int, columns []*column, write func(buf *bytes.Buffer, columns []*column)) string {
+	key := clauseKey{kind: kind, fingerprint: columnsFingerprint(columns)}
+	c.RLock()
+	clause, ok := c.clauses[key]
+	c.RUnlock()
+	if ok {
+		return clause
+	}
+
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	write(buf, columns)
+	clause = buf.String()
+	bufferPool.Put(buf)
+
+	c.Lock()
+	if len(c.clauses) >= maxCachedClauses {
+		c.clauses = make(map[clauseKey]string)
+	}
+	c.clauses[key] = clause
+	c.Unlock()
+	return clause
+}
+
+// columnsFingerprint returns FNV-1a of the names, kinds and bind expressions of columns in order
+func columnsFingerprint(columns []*column) uint64 {
+	const (
+		offset64 = 14695981039346656037
+		prime64  = 1099511628211
+	)
+	hash := uint64(offset64)
+	write := func(s string, sep byte) {
+		for i := 0; i < len(s); i++ {
+			hash ^= uint64(s[i])
+			hash *= prime64
+		}
+		hash ^= uint64(sep)
+		hash *= prime64
+	}
+	for _, col := range columns {
+		// separates names, and tells generated columns apart
+		sep := byte(0)
+		if col.IsGenerated {
+			sep = 1
+		}
+		write(col.name, sep)
+		// placeholders differ with bind expressions, which never contain NUL
+		write(col.bindExpr, 0)
+	}
+	return hash
+}
+
+// insertTemplate returns the template of INSERT statements of the table resolving conflicts by strategy,
+// generated columns are not inserted.
+func insertTemplate(cache *statementCache, schema, table string, columns []*column, strategy string) *stmtTemplate {
+	return cache.get(schema, table, strategy, columns, func() *stmtTemplate {
+		insertColumns, _ := filterGeneratedColumns(columns, nil)
+		head, tail := genInsertHeadTail(strategy, insertColumns)
+		return newStmtTemplate(
+			fmt.Sprintf("%s `%s`.`%s` (%s) VALUES ", head, schema, table, genColumnList(insertColumns)),
+			fmt.Sprintf("(%s)", genColumnPlaceholders(insertColumns)),
+			tail+";")
+	})
+}
@@ -0,0 +1,231 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syncer
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	. "github.com/pingcap/check"
+
+	"github.com/pingcap/dm/dm/config"
+)
+
+func (s *testSyncerSuite) TestStatementCache(c *C) {
+	columns := []*column{
+		{idx: 0, name: "id", NotNull: true, tp: "int(11)"},
+		{idx: 1, name: "a", tp: "int(11)"},
+	}
+	indexColumns := map[string][]*column{"primary": {columns[0]}}
+	cache := newStatementCache()
+	opts := &dmlOptions{keyGen: joinKeyGenerator{}, stmtCache: cache}
+	rows := [][]interface{}{{int32(1), int32(10)}, {int32(2), int32(20)}}
+
+	sqls, _, _, err := genInsertSQLs("db", "tbl", rows, columns, indexColumns, 2, config.ConflictReplace, opts)
+	c.Assert(err, IsNil)
+	c.Assert(sqls, DeepEquals, []string{"REPLACE INTO `db`.`tbl` (`id`,`a`) VALUES (?,?),(?,?);"})
+	sqls, _, _, err = genInsertSQLs("db", "tbl", rows[:1], columns, indexColumns, 1, config.ConflictIgnore, opts)
+	c.Assert(err, IsNil)
+	c.Assert(sqls, DeepEquals, []string{"INSERT IGNORE INTO `db`.`tbl` (`id`,`a`) VALUES (?,?);"})
+	c.Assert(cache.templates, HasLen, 2)
+
+	// the same template is used for the same table, columns and mode
+	tmpl := cache.templates[stmtKey{schema: "db", table: "tbl", mode: config.ConflictReplace}]
+	c.Assert(tmpl, NotNil)
+	c.Assert(insertTemplate(cache, "db", "tbl", columns, config.ConflictReplace), Equals, tmpl)
+	// REPLACE statements of safe mode share the template
+	sqls, _, _, err = genUpdateSQLs("db", "tbl", [][]interface{}{rows[0], rows[1]}, columns, indexColumns, nil, true, opts)
+	c.Assert(err, IsNil)
+	c.Assert(sqls, DeepEquals, []string{"DELETE FROM `db`.`tbl` WHERE `id` = ?;", "REPLACE INTO `db`.`tbl` (`id`,`a`) VALUES (?,?);"})
+	c.Assert(cache.templates, HasLen, 2)
+
+	// invalidated after a column added, or the columns changed
+	columns = append(columns, &column{idx: 2, name: "b", tp: "int(11)"})
+	rows = [][]interface{}{{int32(1), int32(10), int32(100)}}
+	sqls, _, _, err = genInsertSQLs("db", "tbl", rows, columns, indexColumns, 1, config.ConflictReplace, opts)
+	c.Assert(err, IsNil)
+	c.Assert(sqls, DeepEquals, []string{"REPLACE INTO `db`.`tbl` (`id`,`a`,`b`) VALUES (?,?,?);"})
+	c.Assert(insertTemplate(cache, "db", "tbl", columns, config.ConflictReplace), Not(Equals), tmpl)
+	c.Assert(cache.templates, HasLen, 2)
+
+	columns[2] = &column{idx: 2, name: "c", tp: "int(11)"}
+	sqls, _, _, err = genInsertSQLs("db", "tbl", rows, columns, indexColumns, 1, config.ConflictReplace, opts)
+	c.Assert(err, IsNil)
+	c.Assert(sqls, DeepEquals, []string{"REPLACE INTO `db`.`tbl` (`id`,`a`,`c`) VALUES (?,?,?);"})
+	columns[2] = &column{idx: 2, name: "c", tp: "int(11)", IsGenerated: true}
+	sqls, _, _, err = genInsertSQLs("db", "tbl", rows, columns, indexColumns, 1, config.ConflictReplace, opts)
+	c.Assert(err, IsNil)
+	c.Assert(sqls, DeepEquals, []string{"REPLACE INTO `db`.`tbl` (`id`,`a`) VALUES (?,?);"})
+
+	// not shared between tables
+	sqls, _, _, err = genInsertSQLs("db", "tbl2", rows, columns, indexColumns, 1, config.ConflictReplace, opts)
+	c.Assert(err, IsNil)
+	c.Assert(sqls, DeepEquals, []string{"REPLACE INTO `db`.`tbl2` (`id`,`a`) VALUES (?,?);"})
+	c.Assert(cache.templates, HasLen, 3)
+
+	cache.clear()
+	c.Assert(cache.templates, HasLen, 0)
+	// nil cache generates templates every time
+	var nilCache *statementCache
+	c.Assert(insertTemplate(nilCache, "db", "tbl", columns, config.ConflictReplace).single, Equals, "REPLACE INTO `db`.`tbl` (`id`,`a`) VALUES (?,?);")
+	nilCache.clear()
+}
+
+func benchmarkGenInsertSQLs(b *testing.B, cache *statementCache) {
+	columns := []*column{
+		{idx: 0, name: "id", NotNull: true, tp: "int(11)"},
+		{idx: 1, name: "name", tp: "varchar(20)"},
+		{idx: 2, name: "age", tp: "int(11)"},
+		{idx: 3, name: "created_at", tp: "datetime"},
+	}
+	indexColumns := map[string][]*column{"primary": {columns[0]}}
+	rows := [][]interface{}{{int32(1), "a", int32(10), "2019-01-01 00:00:00"}}
+	opts := &dmlOptions{keyGen: joinKeyGenerator{}, stmtCache: cache}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, _, err := genInsertSQLs("db", "tbl", rows, columns, indexColumns, 1, config.ConflictReplace, opts); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGenInsertSQLs(b *testing.B) {
+	benchmarkGenInsertSQLs(b, nil)
+}
+
+func BenchmarkGenInsertSQLsCached(b *testing.B) {
+	benchmarkGenInsertSQLs(b, newStatementCache())
+}
+
+// the implementations before clauses are cached, which generate the same clauses
+func legacyGenColumnList(columns []*column) string {
+	var columnList []byte
+	for i, column := range columns {
+		name := fmt.Sprintf("`%s`", column.name)
+		columnList = append(columnList, []byte(name)...)
+
+		if i != len(columns)-1 {
+			columnList = append(columnList, ',')
+		}
+	}
+
+	return string(columnList)
+}
+
+func legacyGenColumnPlaceholders(columns []*column) string {
+	values := make([]string, len(columns))
+	for i, col := range columns {
+		values[i] = columnPlaceholder(col)
+	}
+	return strings.Join(values, ",")
+}
+
+func legacyGenKVs(columns []*column) string {
+	var kvs bytes.Buffer
+	for i := range columns {
+		if i == len(columns)-1 {
+			fmt.Fprintf(&kvs, "`%s` = %s", columns[i].name, columnPlaceholder(columns[i]))
+		} else {
+			fmt.Fprintf(&kvs, "`%s` = %s, ", columns[i].name, columnPlaceholder(columns[i]))
+		}
+	}
+
+	return kvs.String()
+}
+
+func (s *testSyncerSuite) TestClauseCache(c *C) {
+	columns := []*column{
+		{idx: 0, name: "id", NotNull: true, tp: "int(11)"},
+		{idx: 1, name: "a", tp: "int(11)"},
+		{idx: 2, name: "g", tp: "geometry", geometry: true, bindExpr: geometryBindExpr},
+		{idx: 3, name: "ts", tp: "timestamp", bindExpr: "CONVERT_TZ(?,'+00:00','+08:00')"},
+	}
+	columnSets := [][]*column{nil, columns[:1], columns[:2], columns, columns[1:], {columns[3], columns[0]}}
+	for i := 0; i < 2; i++ {
+		// generated, and then cached
+		for _, cols := range columnSets {
+			c.Assert(genColumnList(cols), Equals, legacyGenColumnList(cols))
+			c.Assert(genColumnPlaceholders(cols), Equals, legacyGenColumnPlaceholders(cols))
+			c.Assert(genKVs(cols), Equals, legacyGenKVs(cols))
+		}
+	}
+	c.Assert(genColumnList(columns), Equals, "`id`,`a`,`g`,`ts`")
+	c.Assert(genColumnPlaceholders(columns), Equals, "?,?,ST_GeomFromWKB(?),CONVERT_TZ(?,'+00:00','+08:00')")
+	c.Assert(genKVs(columns), Equals, "`id` = ?, `a` = ?, `g` = ST_GeomFromWKB(?), `ts` = CONVERT_TZ(?,'+00:00','+08:00')")
+
+	// columns with the same names but different bind expressions
+	plain := []*column{{idx: 0, name: "g", tp: "int(11)"}}
+	c.Assert(genKVs(plain), Equals, "`g` = ?")
+	c.Assert(genKVs(columns[2:3]), Equals, "`g` = ST_GeomFromWKB(?)")
+
+	// the cache is cleared when it's full
+	cache := &clauseCache{clauses: make(map[clauseKey]string)}
+	for i := 0; i <= maxCachedClauses; i++ {
+		cols := []*column{{name: fmt.Sprintf("c%d", i)}}
+		c.Assert(cache.get(clauseColumnList, cols, writeColumnList), Equals, fmt.Sprintf("`c%d`", i))
+	}
+	c.Assert(cache.clauses, HasLen, 1)
+}
+
+// wideColumns returns columns of a wide table
+func wideColumns(count int) []*column {
+	columns := make([]*column, 0, count)
+	for i := 0; i < count; i++ {
+		columns = append(columns, &column{idx: i, name: fmt.Sprintf("column_%d", i), tp: "int(11)"})
+	}
+	return columns
+}
+
+func benchmarkGenClauses(b *testing.B, genColumnList, genColumnPlaceholders, genKVs func([]*column) string) {
+	columns := wideColumns(50)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		genColumnList(columns)
+		genColumnPlaceholders(columns)
+		genKVs(columns)
+	}
+}
+
+func BenchmarkGenClauses(b *testing.B) {
+	benchmarkGenClauses(b, genColumnList, genColumnPlaceholders, genKVs)
+}
+
+func BenchmarkGenClausesLegacy(b *testing.B) {
+	benchmarkGenClauses(b, legacyGenColumnList, legacyGenColumnPlaceholders, legacyGenKVs)
+}
+
+func BenchmarkGenUpdateSQLs(b *testing.B) {
+	columns := wideColumns(50)
+	oldRow := make([]interface{}, len(columns))
+	changedRow := make([]interface{}, len(columns))
+	for i := range columns {
+		oldRow[i] = int32(i)
+		changedRow[i] = int32(i + 1)
+	}
+	columns[0].NotNull = true
+	indexColumns := map[string][]*column{"primary": {columns[0]}}
+	changedRow[0] = oldRow[0]
+	opts := &dmlOptions{keyGen: joinKeyGenerator{}}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, _, err := genUpdateSQLs("db", "tbl", [][]interface{}{oldRow, changedRow}, columns, indexColumns, nil, false, opts); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
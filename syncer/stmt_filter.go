@@ -0,0 +1,58 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syncer
+
+import (
+	"sync"
+)
+
+// Statement is a DML statement generated for a row event, with the tables it's replicated from and to
+type Statement struct {
+	Type         string // `insert`, `update` or `delete`
+	SourceSchema string
+	SourceTable  string
+	TargetSchema string
+	TargetTable  string
+	SQL          string
+	Args         []interface{} // values bound to the placeholders of SQL
+	Keys         []string      // keys of the rows changed by SQL, used for conflict detection
+}
+
+// StatementFilter rewrites or drops DML statements after they are generated and before they are executed.
+type StatementFilter interface {
+	// Filter returns the statement to execute, which can be stmt itself modified, or nil to drop it.
+	// an error returned stops the syncer.
+	Filter(stmt *Statement) (*Statement, error)
+}
+
+var (
+	stmtFilterLock sync.RWMutex
+	stmtFilter     StatementFilter
+)
+
+// RegisterStatementFilter registers f to filter DML statements of syncers, nil removes the filter registered.
+// it should be called before syncers are created, and f replaces the previous one registered.
+// f may be called by multiple syncers concurrently.
+func RegisterStatementFilter(f StatementFilter) {
+	stmtFilterLock.Lock()
+	defer stmtFilterLock.Unlock()
+	stmtFilter = f
+}
+
+// registeredStatementFilter returns the statement filter registered, nil if none registered
+func registeredStatementFilter() StatementFilter {
+	stmtFilterLock.RLock()
+	defer stmtFilterLock.RUnlock()
+	return stmtFilter
+}
@@ -0,0 +1,93 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syncer
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/siddontang/go-mysql/mysql"
+	"golang.org/x/net/context"
+
+	"github.com/pingcap/dm/dm/config"
+	"github.com/pingcap/dm/pkg/utils"
+)
+
+// testStatementFilter drops deletes of one table and rewrites inserts to `INSERT IGNORE`
+type testStatementFilter struct {
+	schema string
+	table  string
+}
+
+func (f *testStatementFilter) Filter(stmt *Statement) (*Statement, error) {
+	if stmt.Type == "delete" && stmt.TargetSchema == f.schema && stmt.TargetTable == f.table {
+		return nil, nil
+	}
+	if stmt.Type == "insert" {
+		stmt.SQL = strings.Replace(stmt.SQL, "INSERT INTO", "INSERT IGNORE INTO", 1)
+	}
+	return stmt, nil
+}
+
+func (t *testDBSuite) TestStatementFilter(c *C) {
+	RegisterStatementFilter(&testStatementFilter{schema: "db", table: "tbl2"})
+	cfg := &config.SubTaskConfig{Name: "test-statement-filter", WorkerCount: 1, Batch: 100, MaxRetry: 1}
+	s := NewSyncer(cfg)
+	RegisterStatementFilter(nil)
+	c.Assert(s.stmtFilter, NotNil)
+	c.Assert(NewSyncer(cfg).stmtFilter, IsNil)
+
+	file := filepath.Join(c.MkDir(), "dry-run.sql")
+	w, err := utils.NewSQLWriter(file)
+	c.Assert(err, IsNil)
+	s.toDBs = append(s.toDBs, &Conn{cfg: cfg, sqlWriter: w})
+	if len(queueBucketMapping) == 0 {
+		queueBucketMapping = append(queueBucketMapping, queueBucketName(0))
+	}
+	// no checkpoint flushed when dispatching jobs
+	s.checkpoint.(*RemoteCheckPoint).globalPointSaveTime = time.Now()
+	s.newJobChans(cfg.WorkerCount + 1)
+	s.wg.Add(1)
+	go s.sync(context.Background(), queueBucketMapping[0], s.toDBs[0], s.jobs[0])
+
+	pos := mysql.Position{Name: "mysql-bin.000001", Pos: 4}
+	dml := func(tp opType, table, sql string, keys ...string) {
+		pos.Pos += 10
+		c.Assert(s.commitJob(tp, "db", table, "db", table, sql, nil, keys, nil, nil, true, pos, pos, nil), IsNil)
+	}
+	dml(insert, "tbl1", "INSERT INTO `db`.`tbl1` VALUES (1)", "1")
+	dml(del, "tbl2", "DELETE FROM `db`.`tbl2` WHERE `id` = 1", "1")
+	dml(del, "tbl1", "DELETE FROM `db`.`tbl1` WHERE `id` = 2", "2")
+	dml(del, "tbl2", "DELETE FROM `db`.`tbl2` WHERE `id` = 3", "3")
+	c.Assert(s.addJob(newXIDJob(pos, pos, nil)), IsNil)
+	// the transaction with dropped statements only still advances the checkpoint
+	dml(del, "tbl2", "DELETE FROM `db`.`tbl2` WHERE `id` = 4", "4")
+	c.Assert(s.addJob(newXIDJob(pos, pos, nil)), IsNil)
+	c.Assert(s.checkpoint.GlobalPoint(), DeepEquals, pos)
+
+	s.jobWg.Wait()
+	s.closeJobChans()
+	s.wg.Wait()
+	c.Assert(w.Close(), IsNil)
+
+	data, err := ioutil.ReadFile(file)
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "BEGIN;\n"+
+		"INSERT IGNORE INTO `db`.`tbl1` VALUES (1);\n"+
+		"DELETE FROM `db`.`tbl1` WHERE `id` = 2;\n"+
+		"COMMIT;\n")
+}
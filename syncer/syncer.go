@@ -102,16 +102,48 @@ type Syncer struct {
 	toDBs  []*Conn
 	ddlDB  *Conn
 
+	sqlWriter *utils.SQLWriter // only used in dry-run mode
+
 	jobs       []chan *job
 	jobsClosed sync2.AtomicBool
 
-	c *causality
+	c      *causality
+	keyGen KeyGenerator
+	casts  map[string]CastFunc // cast functions registered when created
+
+	partitions map[string]PartitionFunc // partition functions registered when created
+
+	stmtFilter StatementFilter // statement filter registered when created, nil if none
+
+	logger log.Logger // the global logger by default, see SetLogger
+
+	stmtCache *statementCache // templates of DML statements
+
+	conflictStrategies *conflictStrategies // conflict strategies of target tables
+	rowLimits          *rowLimits          // row limits of UPDATE and DELETE statements of target tables
+	preferredIndexes   *preferredIndexes   // unique indexes preferred to identify rows of target tables
+	ignoredColumns     *ignoredColumns     // columns ignored when detecting changes of rows of target tables
+	maxStatementSize   int                 // estimated size limit of batched DML statements, see initMaxStatementSize
+	maxPacketSize      int                 // `max_allowed_packet` of the target, see initMaxStatementSize
+
+	// DML jobs of the source transaction not ended yet and keys of them, only used if cfg.KeepTransaction is set
+	txnJobs []*job
+	txnKeys []string
+
+	metricsTables map[string]struct{} // `schema.table` of target tables labeled in metrics of generated statements
+
+	safeModeDuration time.Duration // safe-mode is enabled for events in the duration after resumed
 
 	tableRouter   *router.Table
 	binlogFilter  *bf.BinlogEvent
 	columnMapping *cm.Mapping
 	bwList        *filter.Filter
 
+	// source table ID -> target column -> expression, evaluated after column mapping
+	columnExprs map[string]map[string]ColumnExpression
+	// source table ID -> column -> renamed column in statements, applied after column expressions
+	columnRenames map[string]map[string]string
+
 	closed sync2.AtomicBool
 
 	start    time.Time
@@ -166,6 +198,20 @@ func NewSyncer(cfg *config.SubTaskConfig) *Syncer {
 	syncer.tables = make(map[string]*table)
 	syncer.cacheColumns = make(map[string][]string)
 	syncer.c = newCausality()
+	syncer.keyGen = NewKeyGenerator(cfg.KeyStrategy)
+	syncer.logger = log.GlobalLogger()
+	syncer.casts = registeredCastFuncs()
+	syncer.partitions = registeredPartitionFuncs()
+	syncer.stmtFilter = registeredStatementFilter()
+	syncer.stmtCache = newStatementCache()
+	syncer.conflictStrategies, _ = newConflictStrategies(cfg.CaseSensitive, cfg.ConflictStrategy, nil)
+	syncer.rowLimits, _ = newRowLimits(cfg.CaseSensitive, cfg.RowLimit, nil)
+	syncer.preferredIndexes, _ = newPreferredIndexes(cfg.CaseSensitive, nil)
+	syncer.ignoredColumns, _ = newIgnoredColumns(cfg.CaseSensitive, nil)
+	syncer.metricsTables = make(map[string]struct{}, len(cfg.MetricsTables))
+	for _, table := range cfg.MetricsTables {
+		syncer.metricsTables[table] = struct{}{}
+	}
 	syncer.tableRouter, _ = router.NewTableRouter(cfg.CaseSensitive, []*router.TableRule{})
 	syncer.done = make(chan struct{})
 	syncer.bwList = filter.New(cfg.CaseSensitive, cfg.BWList)
@@ -182,7 +228,7 @@ func NewSyncer(cfg *config.SubTaskConfig) *Syncer {
 		Password:                syncer.cfg.From.Password,
 		UseDecimal:              true,
 		VerifyChecksum:          true,
-		TimestampStringLocation: syncer.timezone,
+		TimestampStringLocation: time.UTC, // TIMESTAMP values are converted to syncer.timezone when generating DMLs
 	}
 
 	syncer.binlogType = toBinlogType(cfg.BinlogType)
@@ -223,6 +269,12 @@ func (s *Syncer) closeJobChans() {
 	s.jobsClosed.Set(true)
 }
 
+// SetLogger sets the logger of the syncer, like a logger with fields of the task,
+// it should be called before the syncer is initialized.
+func (s *Syncer) SetLogger(logger log.Logger) {
+	s.logger = logger
+}
+
 // Type implements Unit.Type
 func (s *Syncer) Type() pb.UnitType {
 	return pb.UnitType_Sync
@@ -242,6 +294,36 @@ func (s *Syncer) Init() error {
 		return errors.Trace(err)
 	}
 
+	s.safeModeDuration, err = config.ParseSafeModeDuration(s.cfg.SafeModeDuration)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	s.conflictStrategies, err = newConflictStrategies(s.cfg.CaseSensitive, s.cfg.ConflictStrategy, s.cfg.TableConflictStrategies)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	s.rowLimits, err = newRowLimits(s.cfg.CaseSensitive, s.cfg.RowLimit, s.cfg.TableRowLimits)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	s.preferredIndexes, err = newPreferredIndexes(s.cfg.CaseSensitive, s.cfg.TablePreferredIndexes)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	s.ignoredColumns, err = newIgnoredColumns(s.cfg.CaseSensitive, s.cfg.TableChangeIgnoredColumns)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	err = s.initMaxStatementSize()
+	if err != nil {
+		return errors.Trace(err)
+	}
+
 	if len(s.cfg.ColumnMappingRules) > 0 {
 		s.columnMapping, err = cm.NewMapping(s.cfg.CaseSensitive, s.cfg.ColumnMappingRules)
 		if err != nil {
@@ -250,6 +332,9 @@ func (s *Syncer) Init() error {
 	}
 
 	if s.cfg.OnlineDDLScheme != "" {
+		if s.cfg.DryRun {
+			return errors.NotSupportedf("online ddl scheme (%s) in dry-run mode", s.cfg.OnlineDDLScheme)
+		}
 		fn, ok := OnlineDDLSchemes[s.cfg.OnlineDDLScheme]
 		if !ok {
 			return errors.NotSupportedf("online ddl scheme (%s)", s.cfg.OnlineDDLScheme)
@@ -323,6 +408,28 @@ func (s *Syncer) Init() error {
 	return nil
 }
 
+// initMaxStatementSize sets the estimated size limit of batched DML statements to cfg.MaxStatementSize,
+// or a safe fraction of `max_allowed_packet` of the target if it's not specified.
+// `max_allowed_packet` is kept to reject rows which can't be sent even by their own statements, see checkRowSize.
+func (s *Syncer) initMaxStatementSize() error {
+	value, err := utils.GetGlobalVariable(s.ddlDB.db, "max_allowed_packet")
+	if err != nil {
+		return errors.Annotate(err, "get max_allowed_packet of the target")
+	}
+	maxAllowedPacket, err := strconv.Atoi(value)
+	if err != nil {
+		return errors.Annotatef(err, "parse max_allowed_packet %s of the target", value)
+	}
+	s.maxPacketSize = maxAllowedPacket
+	if s.cfg.MaxStatementSize > 0 {
+		s.maxStatementSize = s.cfg.MaxStatementSize
+		return nil
+	}
+	s.maxStatementSize = defaultMaxStatementSize(maxAllowedPacket)
+	log.Infof("[syncer] max_allowed_packet of the target is %d, max estimated size of batched statements is %d", maxAllowedPacket, s.maxStatementSize)
+	return nil
+}
+
 // initShardingGroups initializes sharding groups according to source MySQL, filter rules and router rules
 // NOTE: now we don't support modify router rules after task has started
 func (s *Syncer) initShardingGroups() error {
@@ -392,7 +499,7 @@ func (s *Syncer) Process(ctx context.Context, pr chan pb.ProcessResult) {
 	} else if s.binlogType == LocalBinlog {
 		s.localReader = streamer.NewBinlogReader(&streamer.BinlogReaderConfig{
 			RelayDir: s.cfg.RelayDir,
-			Timezone: s.timezone,
+			Timezone: time.UTC,
 		})
 	}
 	// create new done chan
@@ -497,9 +604,21 @@ func (s *Syncer) clearTables(schema, table string) {
 	delete(s.cacheColumns, key)
 }
 
+// genDMLError annotates the error of generating DML statements of tp for the table, keeping its cause (see ErrColumnCountMismatch).
+// the cached structure of the table is cleared if its columns mismatch the rows, it may be changed by DDL not synced,
+// like executed in the target directly, then the structure is fetched again when the task is resumed, rather than failing again.
+func (s *Syncer) genDMLError(err error, tp, schema, table string) error {
+	if errors.Cause(err) == ErrColumnCountMismatch {
+		log.Warnf("[syncer] columns of table `%s`.`%s` mismatch the rows, clear its structure to fetch again: %v", schema, table, err)
+		s.clearTables(schema, table)
+	}
+	return errors.Annotatef(err, "gen %s sqls failed, schema: %s, table: %s", tp, schema, table)
+}
+
 func (s *Syncer) clearAllTables() {
 	s.tables = make(map[string]*table)
 	s.cacheColumns = make(map[string][]string)
+	s.stmtCache.clear()
 }
 
 func (s *Syncer) getTableFromDB(db *Conn, schema string, name string) (*table, error) {
@@ -544,6 +663,8 @@ func (s *Syncer) getTable(schema string, table string) (*table, []string, error)
 	for _, c := range t.columns {
 		columns = append(columns, c.name)
 	}
+	// column mapping matches the names of the source, while statements are generated with the names normalized
+	normalizeIdentifiers(t, s.cfg.IdentifierCase)
 
 	s.tables[key] = t
 	s.cacheColumns[key] = columns
@@ -593,6 +714,9 @@ func (s *Syncer) checkWait(job *job) bool {
 func (s *Syncer) addJob(job *job) error {
 	switch job.tp {
 	case xid:
+		if err := s.commitTxn(); err != nil {
+			return errors.Trace(err)
+		}
 		s.saveGlobalPoint(job.pos)
 		return nil
 	case flush:
@@ -606,6 +730,10 @@ func (s *Syncer) addJob(job *job) error {
 		finishedJobsTotal.WithLabelValues("flush", s.cfg.Name, adminQueueName).Inc()
 		return errors.Trace(s.flushCheckPoints())
 	case ddl:
+		// DMLs of non-transactional tables are not ended by XID events
+		if err := s.commitTxn(); err != nil {
+			return errors.Trace(err)
+		}
 		s.jobWg.Wait()
 		addedJobsTotal.WithLabelValues("ddl", s.cfg.Name, adminQueueName).Inc()
 		s.jobWg.Add(1)
@@ -617,7 +745,8 @@ func (s *Syncer) addJob(job *job) error {
 		s.jobs[idx] <- job
 	}
 
-	wait := s.checkWait(job)
+	// never wait in the middle of a source transaction, the worker doesn't execute a part of it
+	wait := !job.txnPending && s.checkWait(job)
 	if wait {
 		s.jobWg.Wait()
 		s.c.reset()
@@ -706,6 +835,7 @@ func (s *Syncer) sync(ctx context.Context, queueBucket string, db *Conn, jobChan
 	count := s.cfg.Batch
 	jobs := make([]*job, 0, count)
 	tpCnt := make(map[opType]int64)
+	inTxn := false // jobs of a source transaction are received partly, see Syncer.commitTxn
 
 	clearF := func() {
 		for i := 0; i < idx; i++ {
@@ -790,9 +920,11 @@ func (s *Syncer) sync(ctx context.Context, queueBucket string, db *Conn, jobChan
 			} else if sqlJob.tp != flush && len(sqlJob.sql) > 0 {
 				jobs = append(jobs, sqlJob)
 				tpCnt[sqlJob.tp]++
+				inTxn = sqlJob.txnPending
 			}
 
-			if idx >= count || sqlJob.tp == flush {
+			// every source transaction is executed in its own transaction if cfg.KeepTransaction is set
+			if (!inTxn && (idx >= count || s.cfg.KeepTransaction)) || sqlJob.tp == flush {
 				err = executeSQLs()
 				if err != nil {
 					fatalF(err, pb.ErrorType_ExecSQL)
@@ -802,7 +934,7 @@ func (s *Syncer) sync(ctx context.Context, queueBucket string, db *Conn, jobChan
 			}
 
 		default:
-			if len(jobs) > 0 {
+			if len(jobs) > 0 && !inTxn {
 				err = executeSQLs()
 				if err != nil {
 					fatalF(err, pb.ErrorType_ExecSQL)
@@ -836,6 +968,14 @@ func (s *Syncer) Run(ctx context.Context) (err error) {
 		if err != nil {
 			return errors.Trace(err)
 		}
+		err = s.checkHandoff(s.checkpoint.GlobalPoint())
+		if err != nil {
+			return errors.Trace(err)
+		}
+		err = s.checkSchemaDrift(parser2)
+		if err != nil {
+			return errors.Trace(err)
+		}
 	}
 
 	// currentPos is the pos for current received event (End_log_pos in `show binlog events` for mysql)
@@ -905,8 +1045,8 @@ func (s *Syncer) Run(ctx context.Context) (err error) {
 	// but there are no ways to make `update` idempotent,
 	// if we start syncer at an early position, database must bear a period of inconsistent state,
 	// it's eventual consistency.
-	safeMode := sm.NewSafeMode()
-	s.enableSafeModeInitializationPhase(ctx, safeMode)
+	safeMode := sm.NewSafeModeController(s.safeModeDuration)
+	s.enableSafeModeInitializationPhase(safeMode)
 
 	// syncing progress with sharding DDL group
 	// 1. use the global streamer to sync regular binlog events
@@ -964,7 +1104,7 @@ func (s *Syncer) Run(ctx context.Context) (err error) {
 			} else if s.binlogType == LocalBinlog {
 				shardingReader = streamer.NewBinlogReader(&streamer.BinlogReaderConfig{
 					RelayDir: s.cfg.RelayDir,
-					Timezone: s.timezone,
+					Timezone: time.UTC,
 				})
 				shardingStreamer, err = s.getBinlogStreamer(shardingReader, shardingReSync.currPos)
 			}
@@ -1138,16 +1278,20 @@ func (s *Syncer) Run(ctx context.Context) (err error) {
 			if err != nil {
 				return errors.Trace(err)
 			}
-			rows, err := s.mappingDML(originSchema, originTable, columns, ev.Rows)
+			renamed, rows, err := s.mappingDML(originSchema, originTable, columns, ev.Rows)
 			if err != nil {
 				return errors.Trace(err)
 			}
+			table = renameTableColumns(table, columns, renamed)
 
 			var (
-				applied bool
-				sqls    []string
-				keys    [][]string
-				args    [][]interface{}
+				applied   bool
+				sqls      []string
+				keys      [][]string
+				args      [][]interface{}
+				fallbacks []*fallbackStmt   // fallbacks of UPDATE statements, see genUpdateSQLsWithFallbacks
+				deletes   []conflictDeletes // conflict deletes of INSERT statements, see genInsertSQLsWithConflictDeletes
+				stmtRows  []int             // rows deleted by DELETE statements, see genDeleteSQLsWithRows
 			)
 
 			// for RowsEvent, one event may have multi SQLs and multi keys, (eg. INSERT INTO t1 VALUES (11, 12), (21, 22) )
@@ -1158,35 +1302,67 @@ func (s *Syncer) Run(ctx context.Context) (err error) {
 				return errors.Trace(err)
 			}
 
+			rowLimit, err := s.rowLimits.limit(table.schema, table.name)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			preferredIndex, err := s.preferredIndexes.index(table.schema, table.name)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			changeIgnoredColumns, err := s.ignoredColumns.columns(table.schema, table.name)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			opts := &dmlOptions{keyGen: s.keyGen, timezone: s.timezone, updateAllDuplicates: s.cfg.UpdateAllDuplicates, rowLimit: rowLimit, preferredIndex: preferredIndex, changeIgnoredColumns: changeIgnoredColumns, logger: s.logger, fillMissingColumns: s.cfg.FillMissingColumns, zeroDateToNull: s.cfg.ZeroDateToNull, strictNotNull: s.cfg.StrictNotNull, maxStatementSize: s.maxStatementSize, maxPacketSize: s.maxPacketSize, upsertOnMissing: s.cfg.UpsertOnMissing, deleteOnKeyConflict: s.cfg.DeleteOnKeyConflict, casts: s.casts, partitions: s.partitions, stmtCache: s.stmtCache}
 			switch e.Header.EventType {
 			case replication.WRITE_ROWS_EVENTv0, replication.WRITE_ROWS_EVENTv1, replication.WRITE_ROWS_EVENTv2:
 				if !applied {
-					sqls, keys, args, err = genInsertSQLs(table.schema, table.name, rows, table.columns, table.indexColumns)
+					var strategy string
+					strategy, err = s.conflictStrategies.strategy(table.schema, table.name)
 					if err != nil {
-						return errors.Errorf("gen insert sqls failed: %v, schema: %s, table: %s", errors.Trace(err), table.schema, table.name)
+						return errors.Trace(err)
 					}
+					sqls, keys, args, deletes, err = genInsertSQLsWithConflictDeletes(table.schema, table.name, rows, table.columns, table.indexColumns, s.cfg.InsertBatch, strategy, opts)
+					if err != nil {
+						return s.genDMLError(err, "insert", schemaName, tableName)
+					}
+					s.addGeneratedStatements("insert", table.schema, table.name, len(sqls))
 				}
 				binlogEvent.WithLabelValues("write_rows", s.cfg.Name).Observe(time.Since(startTime).Seconds())
 
 				for i := range sqls {
 					var arg []interface{}
 					var key []string
+					var conflictDelete conflictDeletes
 					if args != nil {
 						arg = args[i]
 					}
 					if keys != nil {
 						key = keys[i]
 					}
-					err = s.commitJob(insert, string(ev.Table.Schema), string(ev.Table.Table), table.schema, table.name, sqls[i], arg, key, true, lastPos, currentPos, nil)
+					if deletes != nil {
+						conflictDelete = deletes[i]
+					}
+					err = s.commitJob(insert, string(ev.Table.Schema), string(ev.Table.Table), table.schema, table.name, sqls[i], arg, key, nil, conflictDelete, true, lastPos, currentPos, nil)
 					if err != nil {
 						return errors.Trace(err)
 					}
 				}
 			case replication.UPDATE_ROWS_EVENTv0, replication.UPDATE_ROWS_EVENTv1, replication.UPDATE_ROWS_EVENTv2:
 				if !applied {
-					sqls, keys, args, err = genUpdateSQLs(table.schema, table.name, rows, table.columns, table.indexColumns, safeMode.Enable())
+					enableSafeMode := safeMode.EnableFor(e.Header.Timestamp, time.Now())
+					// the images are partial if the upstream uses `binlog_row_image=MINIMAL`
+					images := newRowImages(ev.ColumnBitmap1, ev.ColumnBitmap2, int(ev.ColumnCount))
+					sqls, keys, args, fallbacks, err = genUpdateSQLsWithFallbacks(table.schema, table.name, rows, table.columns, table.indexColumns, images, enableSafeMode, opts)
 					if err != nil {
-						return errors.Errorf("gen update sqls failed: %v, schema: %s, table: %s", err, table.schema, table.name)
+						return s.genDMLError(err, "update", schemaName, tableName)
+					}
+					if enableSafeMode {
+						// every row is updated by a DELETE and a REPLACE statement
+						s.addGeneratedStatements("safe_mode_update", table.schema, table.name, len(sqls))
+					} else {
+						s.addGeneratedStatements("update", table.schema, table.name, len(sqls))
 					}
 				}
 				binlogEvent.WithLabelValues("update_rows", s.cfg.Name).Observe(time.Since(startTime).Seconds())
@@ -1194,24 +1370,29 @@ func (s *Syncer) Run(ctx context.Context) (err error) {
 				for i := range sqls {
 					var arg []interface{}
 					var key []string
+					var fallback *fallbackStmt
 					if args != nil {
 						arg = args[i]
 					}
 					if keys != nil {
 						key = keys[i]
 					}
+					if fallbacks != nil {
+						fallback = fallbacks[i]
+					}
 
-					err = s.commitJob(update, string(ev.Table.Schema), string(ev.Table.Table), table.schema, table.name, sqls[i], arg, key, true, lastPos, currentPos, nil)
+					err = s.commitJob(update, string(ev.Table.Schema), string(ev.Table.Table), table.schema, table.name, sqls[i], arg, key, fallback, nil, true, lastPos, currentPos, nil)
 					if err != nil {
 						return errors.Trace(err)
 					}
 				}
 			case replication.DELETE_ROWS_EVENTv0, replication.DELETE_ROWS_EVENTv1, replication.DELETE_ROWS_EVENTv2:
 				if !applied {
-					sqls, keys, args, err = genDeleteSQLs(table.schema, table.name, rows, table.columns, table.indexColumns)
+					sqls, keys, args, stmtRows, err = genDeleteSQLsWithRows(table.schema, table.name, rows, table.columns, table.indexColumns, opts)
 					if err != nil {
-						return errors.Errorf("gen delete sqls failed: %v, schema: %s, table: %s", err, table.schema, table.name)
+						return s.genDMLError(err, "delete", schemaName, tableName)
 					}
+					s.addGeneratedStatements("delete", table.schema, table.name, len(sqls))
 				}
 				binlogEvent.WithLabelValues("delete_rows", s.cfg.Name).Observe(time.Since(startTime).Seconds())
 
@@ -1222,10 +1403,14 @@ func (s *Syncer) Run(ctx context.Context) (err error) {
 						arg = args[i]
 					}
 					if keys != nil {
-						key = keys[i]
+						// keys of all rows deleted by the statement
+						for _, ks := range keys[:stmtRows[i]] {
+							key = append(key, ks...)
+						}
+						keys = keys[stmtRows[i]:]
 					}
 
-					err = s.commitJob(del, string(ev.Table.Schema), string(ev.Table.Table), table.schema, table.name, sqls[i], arg, key, true, lastPos, currentPos, nil)
+					err = s.commitJob(del, string(ev.Table.Schema), string(ev.Table.Table), table.schema, table.name, sqls[i], arg, key, nil, nil, true, lastPos, currentPos, nil)
 					if err != nil {
 						return errors.Trace(err)
 					}
@@ -1255,6 +1440,12 @@ func (s *Syncer) Run(ctx context.Context) (err error) {
 			}
 			if !parseResult.isDDL {
 				// skipped sql maybe not a DDL (like `BEGIN`)
+				// a transaction of non-transactional tables is ended by `COMMIT` rather than a XID event
+				if strings.EqualFold(sql, "COMMIT") {
+					if err = s.commitTxn(); err != nil {
+						return errors.Trace(err)
+					}
+				}
 				continue
 			}
 
@@ -1546,6 +1737,9 @@ func (s *Syncer) Run(ctx context.Context) (err error) {
 				lastPos = shardingReSync.currPos
 				if shardingReSync.currPos.Compare(shardingReSync.latestPos) >= 0 {
 					log.Infof("[syncer] sharding group %v re-syncing completed", shardingReSync)
+					if err = s.commitTxn(); err != nil {
+						return errors.Trace(err)
+					}
 					closeShardingSyncer()
 					continue
 				}
@@ -1565,16 +1759,78 @@ func (s *Syncer) Run(ctx context.Context) (err error) {
 	}
 }
 
-func (s *Syncer) commitJob(tp opType, sourceSchema, sourceTable, targetSchema, targetTable, sql string, args []interface{}, keys []string, retry bool, pos, cmdPos mysql.Position, gs gtid.Set) error {
+func (s *Syncer) commitJob(tp opType, sourceSchema, sourceTable, targetSchema, targetTable, sql string, args []interface{}, keys []string, fallback *fallbackStmt, deletes conflictDeletes, retry bool, pos, cmdPos mysql.Position, gs gtid.Set) error {
+	if s.stmtFilter != nil {
+		stmt, err := s.stmtFilter.Filter(&Statement{
+			Type:         tp.String(),
+			SourceSchema: sourceSchema,
+			SourceTable:  sourceTable,
+			TargetSchema: targetSchema,
+			TargetTable:  targetTable,
+			SQL:          sql,
+			Args:         args,
+			Keys:         keys,
+		})
+		if err != nil {
+			return errors.Annotatef(err, "filter statement %s", sql)
+		}
+		if stmt == nil {
+			// the checkpoint still advances with the following jobs, like the XID job ending the transaction
+			log.Debugf("[syncer] statement %s of %s.%s at %s dropped by the statement filter", sql, targetSchema, targetTable, cmdPos)
+			return nil
+		}
+		if stmt.SQL != sql {
+			// the fallback and conflict deletes may not fit the statement rewritten
+			fallback, deletes = nil, nil
+		}
+		sql, args, keys = stmt.SQL, stmt.Args, stmt.Keys
+	}
+	if s.cfg.KeepTransaction {
+		// dispatched after the source transaction ended
+		job := newJob(tp, sourceSchema, sourceTable, targetSchema, targetTable, sql, args, "", pos, cmdPos, gs)
+		job.keys = keys
+		job.fallback = fallback
+		job.deletes = deletes
+		s.txnJobs = append(s.txnJobs, job)
+		s.txnKeys = append(s.txnKeys, keys...)
+		return nil
+	}
 	key, err := s.resolveCasuality(keys)
 	if err != nil {
 		return errors.Errorf("resolve karam error %v", err)
 	}
 	job := newJob(tp, sourceSchema, sourceTable, targetSchema, targetTable, sql, args, key, pos, cmdPos, gs)
+	job.keys = keys
+	job.fallback = fallback
+	job.deletes = deletes
 	err = s.addJob(job)
 	return errors.Trace(err)
 }
 
+// commitTxn dispatches DML jobs of the ended source transaction to one worker with the causality key of all their keys,
+// the worker executes them in one transaction. jobs of a transaction not ended are dropped if the syncer stops,
+// they are replicated again from the checkpoint, which is not beyond the transaction.
+func (s *Syncer) commitTxn() error {
+	if len(s.txnJobs) == 0 {
+		return nil
+	}
+	jobs, keys := s.txnJobs, s.txnKeys
+	s.txnJobs, s.txnKeys = nil, nil
+
+	key, err := s.resolveCasuality(keys)
+	if err != nil {
+		return errors.Errorf("resolve karam error %v", err)
+	}
+	for i, job := range jobs {
+		job.key = key
+		job.txnPending = i < len(jobs)-1
+		if err = s.addJob(job); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
 func (s *Syncer) resolveCasuality(keys []string) (string, error) {
 	if s.cfg.DisableCausality {
 		if len(keys) > 0 {
@@ -1624,7 +1880,7 @@ func (s *Syncer) printStatus(ctx context.Context) {
 	for {
 		select {
 		case <-ctx.Done():
-			log.Infof("print status exits, err:%s", ctx.Err())
+			s.logger.Infof("print status exits, err:%s", ctx.Err())
 			return
 		case <-timer.C:
 			now := time.Now()
@@ -1648,12 +1904,12 @@ func (s *Syncer) printStatus(ctx context.Context) {
 				remainingSize, err2 := countBinaryLogsSize(currentPos, s.fromDB.db)
 				if err2 != nil {
 					// log the error, but still handle the rest operation
-					log.Errorf("[syncer] count remaining binlog size err %v", errors.ErrorStack(err2))
+					s.logger.Errorf("[syncer] count remaining binlog size err %v", errors.ErrorStack(err2))
 				} else {
 					bytesPerSec := (totalBinlogSize - lastBinlogSize) / seconds
 					if bytesPerSec > 0 {
 						remainingSeconds := remainingSize / bytesPerSec
-						log.Infof("totalBinlogSize %d, lastBinlogSize %d, seconds %d,  bytesPerSec %d, remainingSize %d, remaining seconds %d", totalBinlogSize, lastBinlogSize, seconds, bytesPerSec, remainingSize, remainingSeconds)
+						s.logger.Infof("totalBinlogSize %d, lastBinlogSize %d, seconds %d,  bytesPerSec %d, remainingSize %d, remaining seconds %d", totalBinlogSize, lastBinlogSize, seconds, bytesPerSec, remainingSize, remainingSeconds)
 						remainingTimeGauge.WithLabelValues(s.cfg.Name).Set(float64(remainingSeconds))
 					}
 				}
@@ -1661,18 +1917,18 @@ func (s *Syncer) printStatus(ctx context.Context) {
 
 			latestMasterPos, latestmasterGTIDSet, err = s.getMasterStatus()
 			if err != nil {
-				log.Errorf("[syncer] get master status error %s", err)
+				s.logger.Errorf("[syncer] get master status error %s", err)
 			} else {
 				binlogPosGauge.WithLabelValues("master", s.cfg.Name).Set(float64(latestMasterPos.Pos))
 				index, err := streamer.GetBinlogFileIndex(latestMasterPos.Name)
 				if err != nil {
-					log.Errorf("[syncer] parse binlog file err %v", err)
+					s.logger.Errorf("[syncer] parse binlog file err %v", err)
 				} else {
 					binlogFileGauge.WithLabelValues("master", s.cfg.Name).Set(index)
 				}
 			}
 
-			log.Infof("[syncer]total events = %d, total tps = %d, recent tps = %d, master-binlog = %v, master-binlog-gtid=%v, syncer-binlog=%s",
+			s.logger.Infof("[syncer]total events = %d, total tps = %d, recent tps = %d, master-binlog = %v, master-binlog-gtid=%v, syncer-binlog=%s",
 				total, totalTps, tps, latestMasterPos, latestmasterGTIDSet, s.checkpoint)
 
 			s.lastCount.Set(total)
@@ -1729,12 +1985,32 @@ func (s *Syncer) createDBs() error {
 	if err != nil {
 		return errors.Trace(err)
 	}
+	slowThreshold, err := config.ParseSlowStatementThreshold(s.cfg.SlowStatementThreshold)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for _, db := range s.toDBs {
+		db.slowThreshold, db.logger = slowThreshold, s.logger
+	}
 	// db for ddl
 	s.ddlDB, err = createDB(s.cfg, s.cfg.To, maxDDLConnectionTimeout)
 	if err != nil {
 		return errors.Trace(err)
 	}
 
+	if s.cfg.DryRun {
+		// still query table structures from downstream, but write DMLs and DDLs rather than executing them
+		s.sqlWriter, err = utils.NewSQLWriter(s.cfg.DryRunFile)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		for _, db := range s.toDBs {
+			db.sqlWriter = s.sqlWriter
+		}
+		s.ddlDB.sqlWriter = s.sqlWriter
+		log.Info("[syncer] in dry-run mode, sqls will not be executed in downstream")
+	}
+
 	return nil
 }
 
@@ -1842,6 +2118,11 @@ func (s *Syncer) Close() {
 	closeDBs(s.fromDB)
 	closeDBs(s.toDBs...)
 	closeDBs(s.ddlDB)
+	if s.sqlWriter != nil {
+		if err := s.sqlWriter.Close(); err != nil {
+			log.Errorf("[syncer] close dry-run sql writer error %v", err)
+		}
+	}
 
 	s.checkpoint.Close()
 